@@ -20,10 +20,49 @@ func Initialize(databaseURL string) (*gorm.DB, error) {
 		&models.Shop{},
 		&models.ShopUser{},
 		&models.Item{},
+		&models.ItemBarcode{},
+		&models.CustomerGroup{},
+		&models.PriceList{},
+		&models.PriceListItem{},
 		&models.Customer{},
+		&models.LoyaltyProgram{},
+		&models.LoyaltyTier{},
+		&models.LoyaltyTransaction{},
 		&models.Bill{},
 		&models.BillItem{},
 		&models.Payment{},
+		&models.Promotion{},
+		&models.BillPromotion{},
+		&models.Cart{},
+		&models.CartItem{},
+		&models.RegisterSession{},
+		&models.CashMovement{},
+		&models.Quotation{},
+		&models.QuotationItem{},
+		&models.RecurringBill{},
+		&models.RecurringBillItem{},
+		&models.RecurringBillRun{},
+		&models.Expense{},
+		&models.ExpenseAttachment{},
+		&models.RecurringExpense{},
+		&models.EWayBill{},
+		&models.EWayBillVehicleUpdate{},
+		&models.LedgerMapping{},
+		&models.AccountingExport{},
+		&models.AccountingExportDocument{},
+		&models.BankStatement{},
+		&models.BankStatementLine{},
+		&models.PaymentLink{},
+		&models.PaymentWebhookEvent{},
+		&models.EmailSettings{},
+		&models.EmailTemplate{},
+		&models.EmailDelivery{},
+		&models.DunningStep{},
+		&models.PaymentReminder{},
+		&models.LateFeePolicy{},
+		&models.LateFeeCharge{},
+		&models.NotificationTemplate{},
+		&models.NotificationDelivery{},
 		&models.AuditLog{},
 	)
 	if err != nil {
@@ -0,0 +1,191 @@
+package handlers
+
+import (
+	"billboard/backend/models"
+	"billboard/backend/services"
+	"fmt"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+type AccountingHandler struct {
+	accountingService *services.AccountingService
+}
+
+func NewAccountingHandler(accountingService *services.AccountingService) *AccountingHandler {
+	return &AccountingHandler{accountingService: accountingService}
+}
+
+// GetLedgerMappings returns the ledger names used for the shop's journal entries
+func (h *AccountingHandler) GetLedgerMappings(c *gin.Context) {
+	shopIDStr := c.Param("shopId")
+	shopID, err := uuid.Parse(shopIDStr)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid shop ID"})
+		return
+	}
+
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	mappings, err := h.accountingService.GetLedgerMappings(shopID, userID.(uuid.UUID))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"data": mappings})
+}
+
+// UpdateLedgerMappings sets the ledger names used for the shop's journal entries
+func (h *AccountingHandler) UpdateLedgerMappings(c *gin.Context) {
+	shopIDStr := c.Param("shopId")
+	shopID, err := uuid.Parse(shopIDStr)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid shop ID"})
+		return
+	}
+
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	var req models.LedgerMappingRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	mappings, err := h.accountingService.UpdateLedgerMappings(shopID, userID.(uuid.UUID), req)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"data": mappings})
+}
+
+// GetJournal previews the journal entries for a date range without exporting them
+func (h *AccountingHandler) GetJournal(c *gin.Context) {
+	shopIDStr := c.Param("shopId")
+	shopID, err := uuid.Parse(shopIDStr)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid shop ID"})
+		return
+	}
+
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	includeExported := c.Query("include_exported") == "true"
+
+	journal, err := h.accountingService.GetJournal(shopID, userID.(uuid.UUID), c.Query("from"), c.Query("to"), includeExported)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"data": journal})
+}
+
+// ExportJournal downloads the journal entries for a date range as Tally XML or CSV and records the export
+func (h *AccountingHandler) ExportJournal(c *gin.Context) {
+	shopIDStr := c.Param("shopId")
+	shopID, err := uuid.Parse(shopIDStr)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid shop ID"})
+		return
+	}
+
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	var req models.AccountingExportRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	export, content, err := h.accountingService.ExportJournal(shopID, userID.(uuid.UUID), req)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	extension, contentType := "csv", "text/csv"
+	if export.Format == "tally" {
+		extension, contentType = "xml", "application/xml"
+	}
+	c.Header("X-Export-ID", export.ID.String())
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=journal-%s-%s.%s",
+		export.FromDate.Format("2006-01-02"), export.ToDate.Format("2006-01-02"), extension))
+	c.Data(http.StatusOK, contentType, content)
+}
+
+// GetExports lists the shop's accounting exports
+func (h *AccountingHandler) GetExports(c *gin.Context) {
+	shopIDStr := c.Param("shopId")
+	shopID, err := uuid.Parse(shopIDStr)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid shop ID"})
+		return
+	}
+
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	exports, err := h.accountingService.GetExports(shopID, userID.(uuid.UUID))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"data": exports})
+}
+
+// DeleteExport forgets an export so its documents are exported again
+func (h *AccountingHandler) DeleteExport(c *gin.Context) {
+	shopIDStr := c.Param("shopId")
+	shopID, err := uuid.Parse(shopIDStr)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid shop ID"})
+		return
+	}
+
+	exportIDStr := c.Param("exportId")
+	exportID, err := uuid.Parse(exportIDStr)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid export ID"})
+		return
+	}
+
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	err = h.accountingService.DeleteExport(exportID, shopID, userID.(uuid.UUID))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Export deleted successfully"})
+}
@@ -3,6 +3,7 @@ package handlers
 import (
 	"billboard/backend/models"
 	"billboard/backend/services"
+	"fmt"
 	"net/http"
 
 	"github.com/gin-gonic/gin"
@@ -11,11 +12,15 @@ import (
 
 type BillHandler struct {
 	billService *services.BillService
+	pdfService  *services.PDFService
+	ublService  *services.UBLService
 }
 
-func NewBillHandler(billService *services.BillService) *BillHandler {
+func NewBillHandler(billService *services.BillService, pdfService *services.PDFService, ublService *services.UBLService) *BillHandler {
 	return &BillHandler{
 		billService: billService,
+		pdfService:  pdfService,
+		ublService:  ublService,
 	}
 }
 
@@ -190,6 +195,68 @@ func (h *BillHandler) DeleteBill(c *gin.Context) {
 	c.JSON(http.StatusOK, gin.H{"message": "Bill deleted successfully"})
 }
 
+// CancelBill cancels a bill
+func (h *BillHandler) CancelBill(c *gin.Context) {
+	shopIDStr := c.Param("shopId")
+	shopID, err := uuid.Parse(shopIDStr)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid shop ID"})
+		return
+	}
+
+	billIDStr := c.Param("billId")
+	billID, err := uuid.Parse(billIDStr)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid bill ID"})
+		return
+	}
+
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	bill, err := h.billService.CancelBill(billID, shopID, userID.(uuid.UUID))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"data": bill})
+}
+
+// IssueBill issues a draft bill to its customer
+func (h *BillHandler) IssueBill(c *gin.Context) {
+	shopIDStr := c.Param("shopId")
+	shopID, err := uuid.Parse(shopIDStr)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid shop ID"})
+		return
+	}
+
+	billIDStr := c.Param("billId")
+	billID, err := uuid.Parse(billIDStr)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid bill ID"})
+		return
+	}
+
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	bill, err := h.billService.IssueBill(billID, shopID, userID.(uuid.UUID))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"data": bill})
+}
+
 // AddPayment adds a payment to a bill
 func (h *BillHandler) AddPayment(c *gin.Context) {
 	shopIDStr := c.Param("shopId")
@@ -274,16 +341,57 @@ func (h *BillHandler) GeneratePDF(c *gin.Context) {
 	}
 
 	// Get the bill
-	bill, err := h.billService.GetBill(billID, shopID, userID.(uuid.UUID))
+	bill, err := h.billService.GetBillDocument(billID, shopID, userID.(uuid.UUID))
 	if err != nil {
 		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
 		return
 	}
 
-	// TODO: Implement PDF generation
-	// For now, return a placeholder response
-	c.JSON(http.StatusOK, gin.H{
-		"message": "PDF generation not implemented yet",
-		"bill":    bill,
-	})
+	content, err := h.pdfService.RenderBill(*bill)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.Header("Content-Disposition", fmt.Sprintf("inline; filename=%s.pdf", bill.BillNumber))
+	c.Data(http.StatusOK, "application/pdf", content)
+}
+
+// GenerateUBL generates a Peppol BIS Billing 3.0 UBL invoice for a bill
+func (h *BillHandler) GenerateUBL(c *gin.Context) {
+	shopIDStr := c.Param("shopId")
+	shopID, err := uuid.Parse(shopIDStr)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid shop ID"})
+		return
+	}
+
+	billIDStr := c.Param("billId")
+	billID, err := uuid.Parse(billIDStr)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid bill ID"})
+		return
+	}
+
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	// Get the bill
+	bill, err := h.billService.GetBillDocument(billID, shopID, userID.(uuid.UUID))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+
+	content, err := h.ublService.RenderBill(*bill)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%s.xml", bill.BillNumber))
+	c.Data(http.StatusOK, "application/xml", content)
 }
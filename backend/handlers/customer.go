@@ -48,6 +48,11 @@ func (h *CustomerHandler) GetCustomers(c *gin.Context) {
 	if city := c.Query("city"); city != "" {
 		filters["city"] = city
 	}
+	if groupIDStr := c.Query("group_id"); groupIDStr != "" {
+		if groupID, err := uuid.Parse(groupIDStr); err == nil {
+			filters["group_id"] = groupID
+		}
+	}
 
 	customers, err := h.customerService.GetCustomers(shopID, userID.(uuid.UUID), filters)
 	if err != nil {
@@ -210,3 +215,38 @@ func (h *CustomerHandler) GetCustomerStats(c *gin.Context) {
 
 	c.JSON(http.StatusOK, gin.H{"data": stats})
 }
+
+// GetStatement returns a customer's statement for a date range
+func (h *CustomerHandler) GetStatement(c *gin.Context) {
+	shopIDStr := c.Param("shopId")
+	shopID, err := uuid.Parse(shopIDStr)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid shop ID"})
+		return
+	}
+
+	customerIDStr := c.Param("customerId")
+	customerID, err := uuid.Parse(customerIDStr)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid customer ID"})
+		return
+	}
+
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	statement, err := h.customerService.GetStatement(customerID, shopID, userID.(uuid.UUID), c.Query("from"), c.Query("to"))
+	if err != nil {
+		if err.Error() == "customer not found" {
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"data": statement})
+}
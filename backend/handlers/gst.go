@@ -0,0 +1,98 @@
+package handlers
+
+import (
+	"billboard/backend/services"
+	"fmt"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+type GSTHandler struct {
+	gstService *services.GSTService
+}
+
+func NewGSTHandler(gstService *services.GSTService) *GSTHandler {
+	return &GSTHandler{gstService: gstService}
+}
+
+// GetGSTR1 returns the GSTR-1 sections for a return period. format=json downloads the offline tool
+// JSON and format=csv downloads a zip of the section CSVs.
+func (h *GSTHandler) GetGSTR1(c *gin.Context) {
+	shopIDStr := c.Param("shopId")
+	shopID, err := uuid.Parse(shopIDStr)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid shop ID"})
+		return
+	}
+
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	ret, err := h.gstService.GetGSTR1(shopID, userID.(uuid.UUID), c.Query("period"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	switch c.Query("format") {
+	case "json":
+		c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=GSTR1-%s-%s.json", ret.GSTIN, ret.Fp))
+		c.JSON(http.StatusOK, ret)
+	case "csv":
+		data, err := h.gstService.ExportGSTR1CSV(shopID, ret)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+
+		c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=GSTR1-%s-%s.zip", ret.GSTIN, ret.Fp))
+		c.Data(http.StatusOK, "application/zip", data)
+	default:
+		c.JSON(http.StatusOK, gin.H{"data": ret})
+	}
+}
+
+// GetGSTR3B returns the GSTR-3B liability summary for a return period. format=json downloads the
+// offline tool JSON and format=csv downloads it as CSV.
+func (h *GSTHandler) GetGSTR3B(c *gin.Context) {
+	shopIDStr := c.Param("shopId")
+	shopID, err := uuid.Parse(shopIDStr)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid shop ID"})
+		return
+	}
+
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	ret, err := h.gstService.GetGSTR3B(shopID, userID.(uuid.UUID), c.Query("period"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	switch c.Query("format") {
+	case "json":
+		c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=GSTR3B-%s-%s.json", ret.GSTIN, ret.RetPeriod))
+		c.JSON(http.StatusOK, ret)
+	case "csv":
+		data, err := h.gstService.ExportGSTR3BCSV(ret)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+
+		c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=GSTR3B-%s-%s.csv", ret.GSTIN, ret.RetPeriod))
+		c.Data(http.StatusOK, "text/csv", data)
+	default:
+		c.JSON(http.StatusOK, gin.H{"data": ret})
+	}
+}
@@ -0,0 +1,59 @@
+package handlers
+
+import (
+	"billboard/backend/models"
+	"billboard/backend/services"
+	"fmt"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+type LabelHandler struct {
+	labelService *services.LabelService
+}
+
+func NewLabelHandler(labelService *services.LabelService) *LabelHandler {
+	return &LabelHandler{labelService: labelService}
+}
+
+// GetLabelSizes lists the supported label sheet and roll sizes
+func (h *LabelHandler) GetLabelSizes(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{"data": h.labelService.GetLabelSizes()})
+}
+
+// GenerateLabels renders a printable label sheet for the selected items
+func (h *LabelHandler) GenerateLabels(c *gin.Context) {
+	shopIDStr := c.Param("shopId")
+	shopID, err := uuid.Parse(shopIDStr)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid shop ID"})
+		return
+	}
+
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	var req models.LabelRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	content, contentType, err := h.labelService.GenerateLabels(shopID, userID.(uuid.UUID), req)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	extension := "pdf"
+	if contentType == "image/png" {
+		extension = "png"
+	}
+	c.Header("Content-Disposition", fmt.Sprintf("inline; filename=labels.%s", extension))
+	c.Data(http.StatusOK, contentType, content)
+}
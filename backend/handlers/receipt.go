@@ -0,0 +1,112 @@
+package handlers
+
+import (
+	"billboard/backend/services"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+type ReceiptHandler struct {
+	receiptService *services.ReceiptService
+}
+
+func NewReceiptHandler(receiptService *services.ReceiptService) *ReceiptHandler {
+	return &ReceiptHandler{receiptService: receiptService}
+}
+
+// GetReceipt returns a bill as an ESC/POS byte stream for a thermal printer
+func (h *ReceiptHandler) GetReceipt(c *gin.Context) {
+	shopIDStr := c.Param("shopId")
+	shopID, err := uuid.Parse(shopIDStr)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid shop ID"})
+		return
+	}
+
+	billIDStr := c.Param("billId")
+	billID, err := uuid.Parse(billIDStr)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid bill ID"})
+		return
+	}
+
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	opts := services.ReceiptOptions{
+		Code:   c.Query("code"),
+		Footer: c.Query("footer"),
+	}
+	if width := c.Query("width"); width != "" {
+		opts.PaperWidth, err = strconv.Atoi(width)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid paper width"})
+			return
+		}
+	}
+
+	receipt, err := h.receiptService.RenderBillReceipt(billID, shopID, userID.(uuid.UUID), opts)
+	if err != nil {
+		if err.Error() == "bill not found" {
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=receipt-%s.bin", billID))
+	c.Data(http.StatusOK, "application/octet-stream", receipt)
+}
+
+// GetUPIQR returns a PNG QR code that pays a bill's balance with any UPI app
+func (h *ReceiptHandler) GetUPIQR(c *gin.Context) {
+	shopIDStr := c.Param("shopId")
+	shopID, err := uuid.Parse(shopIDStr)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid shop ID"})
+		return
+	}
+
+	billIDStr := c.Param("billId")
+	billID, err := uuid.Parse(billIDStr)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid bill ID"})
+		return
+	}
+
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	size := 0
+	if value := c.Query("size"); value != "" {
+		size, err = strconv.Atoi(value)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid size"})
+			return
+		}
+	}
+
+	png, err := h.receiptService.RenderBillUPIQR(billID, shopID, userID.(uuid.UUID), size)
+	if err != nil {
+		if err.Error() == "bill not found" {
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.Header("Cache-Control", "no-store")
+	c.Data(http.StatusOK, "image/png", png)
+}
@@ -3,6 +3,7 @@ package main
 import (
 	"log"
 	"os"
+	"time"
 
 	"billboard/backend/config"
 	"billboard/backend/database"
@@ -54,6 +55,71 @@ func main() {
 	customerService := services.NewCustomerService(db)
 	shopService := services.NewShopService(db)
 	pdfService := services.NewPDFService()
+	ublService := services.NewUBLService()
+	receiptService := services.NewReceiptService(db)
+	labelService := services.NewLabelService(db)
+	posService := services.NewPOSService(db)
+	cartService := services.NewCartService(db, time.Duration(cfg.CartExpiryMinutes)*time.Minute)
+	registerService := services.NewRegisterService(db)
+	quotationService := services.NewQuotationService(db)
+	recurringBillService := services.NewRecurringBillService(db)
+	promotionService := services.NewPromotionService(db)
+	priceListService := services.NewPriceListService(db)
+	loyaltyService := services.NewLoyaltyService(db)
+	expenseService := services.NewExpenseService(db)
+	reportService := services.NewReportService(db)
+	gstService := services.NewGSTService(db)
+	var irpClient services.IRPClient
+	if cfg.EInvoiceProvider == "mock" {
+		irpClient = services.NewMockIRPClient()
+	}
+	eInvoiceService := services.NewEInvoiceService(db, irpClient)
+	eWayBillService := services.NewEWayBillService(db)
+	accountingService := services.NewAccountingService(db)
+	bankReconciliationService := services.NewBankReconciliationService(db)
+	var paymentGateway services.PaymentGateway
+	if cfg.PaymentGateway != "" && cfg.PaymentWebhookSecret == "" {
+		log.Fatal("PAYMENT_WEBHOOK_SECRET must be set to use the payment gateway")
+	}
+	switch cfg.PaymentGateway {
+	case "":
+		// Payment links are disabled
+	case "fake":
+		paymentGateway = services.NewFakePaymentGateway(cfg.PaymentWebhookSecret)
+	case "razorpay":
+		paymentGateway = services.NewRazorpayGateway(cfg.RazorpayKeyID, cfg.RazorpayKeySecret, cfg.PaymentWebhookSecret)
+	default:
+		log.Fatal("Unknown payment gateway: ", cfg.PaymentGateway)
+	}
+	paymentLinkService := services.NewPaymentLinkService(db, paymentGateway)
+	emailService := services.NewEmailService(db, pdfService, services.SMTPConfig{
+		Host:     cfg.SMTPHost,
+		Port:     cfg.SMTPPort,
+		Username: cfg.SMTPUsername,
+		Password: cfg.SMTPPassword,
+		Security: cfg.SMTPSecurity,
+		From:     cfg.SMTPFrom,
+	})
+	var smsNotifier services.Notifier = services.NewLogNotifier("sms")
+	if cfg.SMSProvider == "http" {
+		smsNotifier = services.NewHTTPSMSNotifier(cfg.SMSGatewayURL, cfg.SMSGatewayAPIKey, cfg.SMSSenderID, cfg.SMSWebhookSecret)
+	}
+	var whatsAppNotifier services.Notifier = services.NewLogNotifier("whatsapp")
+	if cfg.WhatsAppProvider == "cloud" {
+		whatsAppNotifier = services.NewWhatsAppNotifier(cfg.WhatsAppAPIURL, cfg.WhatsAppPhoneNumberID, cfg.WhatsAppAccessToken, cfg.WhatsAppAppSecret, cfg.WhatsAppVerifyToken)
+	}
+	notificationService := services.NewNotificationService(db, smsNotifier, whatsAppNotifier, cfg.NotificationCountryCode)
+	dunningService := services.NewDunningService(db, emailService, notificationService)
+	lateFeeService := services.NewLateFeeService(db)
+
+	// Start background workers
+	cartService.StartExpiryWorker(time.Minute)
+	recurringBillService.StartGenerator(time.Hour)
+	expenseService.StartGenerator(time.Hour)
+	emailService.StartWorker(time.Minute)
+	notificationService.StartWorker(time.Minute)
+	dunningService.StartScheduler(time.Hour)
+	lateFeeService.StartScheduler(6 * time.Hour)
 
 	// Initialize Gin router
 	router := gin.Default()
@@ -74,12 +140,35 @@ func main() {
 
 	// Initialize routes
 	routes.SetupRoutes(router, &services.Services{
-		Auth:     authService,
-		Bill:     billService,
-		Item:     itemService,
-		Customer: customerService,
-		Shop:     shopService,
-		PDF:      pdfService,
+		Auth:               authService,
+		Bill:               billService,
+		Item:               itemService,
+		Customer:           customerService,
+		Shop:               shopService,
+		PDF:                pdfService,
+		Receipt:            receiptService,
+		Label:              labelService,
+		POS:                posService,
+		Cart:               cartService,
+		Register:           registerService,
+		Quotation:          quotationService,
+		Recurring:          recurringBillService,
+		Promotion:          promotionService,
+		PriceList:          priceListService,
+		Loyalty:            loyaltyService,
+		Expense:            expenseService,
+		Report:             reportService,
+		GST:                gstService,
+		EInvoice:           eInvoiceService,
+		EWayBill:           eWayBillService,
+		UBL:                ublService,
+		Accounting:         accountingService,
+		BankReconciliation: bankReconciliationService,
+		PaymentLink:        paymentLinkService,
+		Email:              emailService,
+		Notification:       notificationService,
+		Dunning:            dunningService,
+		LateFee:            lateFeeService,
 	})
 
 	// Start server
@@ -0,0 +1,148 @@
+package models
+
+import (
+	"encoding/xml"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// LedgerMapping maps an account used in exported journal entries to the ledger name in the
+// shop's accounting software. Accounts without a mapping use their default ledger name.
+type LedgerMapping struct {
+	ID         uuid.UUID `json:"id" gorm:"type:uuid;primary_key;default:gen_random_uuid()"`
+	ShopID     uuid.UUID `json:"shop_id" gorm:"type:uuid;not null;uniqueIndex:idx_ledger_mapping_account"`
+	Account    string    `json:"account" gorm:"not null;uniqueIndex:idx_ledger_mapping_account"` // sales, output_cgst, cash, expense:<category>, ...
+	LedgerName string    `json:"ledger_name" gorm:"not null"`
+	CreatedAt  time.Time `json:"created_at"`
+	UpdatedAt  time.Time `json:"updated_at"`
+}
+
+// AccountingExport records one export of journal entries to accounting software
+type AccountingExport struct {
+	ID           uuid.UUID `json:"id" gorm:"type:uuid;primary_key;default:gen_random_uuid()"`
+	ShopID       uuid.UUID `json:"shop_id" gorm:"type:uuid;not null;index"`
+	Format       string    `json:"format" gorm:"not null"` // tally, csv
+	FromDate     time.Time `json:"from_date" gorm:"type:date;not null"`
+	ToDate       time.Time `json:"to_date" gorm:"type:date;not null"`
+	EntryCount   int       `json:"entry_count" gorm:"not null;default:0"`
+	BillCount    int       `json:"bill_count" gorm:"not null;default:0"`
+	PaymentCount int       `json:"payment_count" gorm:"not null;default:0"`
+	ExpenseCount int       `json:"expense_count" gorm:"not null;default:0"`
+	CreatedBy    string    `json:"created_by" gorm:"not null"`
+	CreatedAt    time.Time `json:"created_at"`
+}
+
+// AccountingExportDocument records a document that has been exported, so later exports of an
+// overlapping date range leave it out
+type AccountingExportDocument struct {
+	ID           uuid.UUID `json:"id" gorm:"type:uuid;primary_key;default:gen_random_uuid()"`
+	ExportID     uuid.UUID `json:"export_id" gorm:"type:uuid;not null;index"`
+	ShopID       uuid.UUID `json:"shop_id" gorm:"type:uuid;not null;uniqueIndex:idx_accounting_export_document"`
+	DocumentType string    `json:"document_type" gorm:"not null;uniqueIndex:idx_accounting_export_document"` // bill, bill_cancellation, payment, late_fee, late_fee_waiver, expense
+	DocumentID   uuid.UUID `json:"document_id" gorm:"type:uuid;not null;uniqueIndex:idx_accounting_export_document"`
+	CreatedAt    time.Time `json:"created_at"`
+}
+
+// LedgerMappingRequest represents the request payload for updating ledger names. An empty
+// ledger name resets the account to its default.
+type LedgerMappingRequest struct {
+	Mappings map[string]string `json:"mappings" binding:"required"`
+}
+
+// AccountingExportRequest represents the request payload for exporting journal entries
+type AccountingExportRequest struct {
+	From            string `json:"from"` // YYYY-MM-DD, defaults to the start of the month
+	To              string `json:"to"`   // YYYY-MM-DD, defaults to today
+	Format          string `json:"format" binding:"required,oneof=tally csv"`
+	IncludeExported bool   `json:"include_exported"` // export documents again even if already exported
+}
+
+// LedgerMappingResponse represents the ledger used for one account
+type LedgerMappingResponse struct {
+	Account     string `json:"account"`
+	Description string `json:"description"`
+	LedgerName  string `json:"ledger_name"`
+	IsDefault   bool   `json:"is_default"`
+}
+
+// JournalEntry represents one balanced double-entry journal voucher
+type JournalEntry struct {
+	Date          time.Time     `json:"date"`
+	VoucherType   string        `json:"voucher_type"` // Sales, Receipt, Payment
+	VoucherNumber string        `json:"voucher_number"`
+	PartyLedger   string        `json:"party_ledger"`
+	Narration     string        `json:"narration"`
+	DocumentType  string        `json:"document_type"` // bill, bill_cancellation, payment, late_fee, late_fee_waiver, expense
+	DocumentID    uuid.UUID     `json:"document_id"`
+	Lines         []JournalLine `json:"lines"`
+}
+
+// JournalLine represents a debit or credit to one ledger
+type JournalLine struct {
+	Ledger string  `json:"ledger"`
+	Debit  float64 `json:"debit"`
+	Credit float64 `json:"credit"`
+}
+
+// JournalResponse represents the journal entries for a date range
+type JournalResponse struct {
+	From            time.Time      `json:"from"`
+	To              time.Time      `json:"to"`
+	Entries         []JournalEntry `json:"entries"`
+	TotalDebit      float64        `json:"total_debit"`
+	TotalCredit     float64        `json:"total_credit"`
+	AlreadyExported int            `json:"already_exported"` // documents left out because they were exported before
+}
+
+// AccountingExportResponse represents the response payload for an export
+type AccountingExportResponse struct {
+	ID           uuid.UUID `json:"id"`
+	Format       string    `json:"format"`
+	FromDate     time.Time `json:"from_date"`
+	ToDate       time.Time `json:"to_date"`
+	EntryCount   int       `json:"entry_count"`
+	BillCount    int       `json:"bill_count"`
+	PaymentCount int       `json:"payment_count"`
+	ExpenseCount int       `json:"expense_count"`
+	CreatedBy    string    `json:"created_by"`
+	CreatedAt    time.Time `json:"created_at"`
+}
+
+// The types below follow Tally's XML import format, so their element names are Tally's.
+
+// TallyEnvelope represents a Tally import request
+type TallyEnvelope struct {
+	XMLName      xml.Name       `xml:"ENVELOPE"`
+	TallyRequest string         `xml:"HEADER>TALLYREQUEST"`
+	ReportName   string         `xml:"BODY>IMPORTDATA>REQUESTDESC>REPORTNAME"`
+	Company      string         `xml:"BODY>IMPORTDATA>REQUESTDESC>STATICVARIABLES>SVCURRENTCOMPANY,omitempty"`
+	Messages     []TallyMessage `xml:"BODY>IMPORTDATA>REQUESTDATA>TALLYMESSAGE"`
+}
+
+// TallyMessage wraps one voucher
+type TallyMessage struct {
+	UDF     string       `xml:"xmlns:UDF,attr"`
+	Voucher TallyVoucher `xml:"VOUCHER"`
+}
+
+// TallyVoucher represents a voucher. Tally records debits as negative amounts that are deemed
+// positive, and credits as positive amounts.
+type TallyVoucher struct {
+	RemoteID        string             `xml:"REMOTEID,attr"`
+	VchType         string             `xml:"VCHTYPE,attr"`
+	Action          string             `xml:"ACTION,attr"`
+	Date            string             `xml:"DATE"` // YYYYMMDD
+	VoucherTypeName string             `xml:"VOUCHERTYPENAME"`
+	VoucherNumber   string             `xml:"VOUCHERNUMBER"`
+	PartyLedgerName string             `xml:"PARTYLEDGERNAME,omitempty"`
+	Narration       string             `xml:"NARRATION,omitempty"`
+	LedgerEntries   []TallyLedgerEntry `xml:"ALLLEDGERENTRIES.LIST"`
+}
+
+// TallyLedgerEntry represents one ledger posting of a voucher
+type TallyLedgerEntry struct {
+	LedgerName       string `xml:"LEDGERNAME"`
+	IsDeemedPositive string `xml:"ISDEEMEDPOSITIVE"` // Yes for debits, No for credits
+	Amount           string `xml:"AMOUNT"`
+}
@@ -0,0 +1,110 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// BankStatement represents a bank statement file imported for reconciliation
+type BankStatement struct {
+	ID             uuid.UUID  `json:"id" gorm:"type:uuid;primary_key;default:gen_random_uuid()"`
+	ShopID         uuid.UUID  `json:"shop_id" gorm:"type:uuid;not null;index"`
+	FileName       string     `json:"file_name" gorm:"not null"`
+	Format         string     `json:"format" gorm:"not null"` // csv, ofx, mt940
+	AccountNumber  string     `json:"account_number"`
+	PeriodStart    *time.Time `json:"period_start" gorm:"type:date"`
+	PeriodEnd      *time.Time `json:"period_end" gorm:"type:date"`
+	LineCount      int        `json:"line_count" gorm:"not null;default:0"`
+	DuplicateCount int        `json:"duplicate_count" gorm:"not null;default:0"` // lines skipped because an earlier statement had them
+	CreatedBy      string     `json:"created_by" gorm:"not null"`
+	CreatedAt      time.Time  `json:"created_at"`
+
+	// Relationships
+	Lines []BankStatementLine `json:"lines,omitempty" gorm:"foreignKey:StatementID"`
+}
+
+// BankStatementLine represents one transaction on a bank statement. Credits are positive and
+// debits negative. A credit is reconciled by matching it to a bill, which records a payment.
+type BankStatementLine struct {
+	ID              uuid.UUID  `json:"id" gorm:"type:uuid;primary_key;default:gen_random_uuid()"`
+	StatementID     uuid.UUID  `json:"statement_id" gorm:"type:uuid;not null;index"`
+	ShopID          uuid.UUID  `json:"shop_id" gorm:"type:uuid;not null;index;uniqueIndex:idx_bank_statement_line_fingerprint"`
+	TransactionDate time.Time  `json:"transaction_date" gorm:"type:date;not null;index"`
+	Amount          float64    `json:"amount" gorm:"not null"`
+	Description     string     `json:"description"`
+	Reference       string     `json:"reference"`
+	Fingerprint     string     `json:"-" gorm:"not null;uniqueIndex:idx_bank_statement_line_fingerprint"` // identifies the transaction across overlapping statements
+	Status          string     `json:"status" gorm:"not null;default:'unreconciled';index"`               // unreconciled, reconciled, ignored
+	BillID          *uuid.UUID `json:"bill_id" gorm:"type:uuid"`
+	PaymentID       *uuid.UUID `json:"payment_id" gorm:"type:uuid"`
+	ReconciledBy    string     `json:"reconciled_by"`
+	ReconciledAt    *time.Time `json:"reconciled_at"`
+	CreatedAt       time.Time  `json:"created_at"`
+	UpdatedAt       time.Time  `json:"updated_at"`
+}
+
+// BankCSVMapping names the columns of a CSV bank statement. Columns are given by their header
+// name or by their 1-based position. Either an amount column or credit and debit columns are
+// needed.
+type BankCSVMapping struct {
+	Date        string `json:"date" binding:"required"`
+	Amount      string `json:"amount"`
+	Credit      string `json:"credit"`
+	Debit       string `json:"debit"`
+	Description string `json:"description"`
+	Reference   string `json:"reference"`
+	DateFormat  string `json:"date_format"` // Go layout, defaults to trying common formats
+	SkipRows    int    `json:"skip_rows"`   // rows before the header, such as the bank's title lines
+}
+
+// BankMatchRequest represents the request payload for reconciling a statement line with a bill
+type BankMatchRequest struct {
+	BillID        uuid.UUID `json:"bill_id" binding:"required"`
+	PaymentMethod string    `json:"payment_method" binding:"omitempty,oneof=bank_transfer upi card check other"` // defaults to upi for UPI credits, else bank_transfer
+	Notes         string    `json:"notes"`
+}
+
+// BankStatementResponse represents the response payload for an imported statement
+type BankStatementResponse struct {
+	ID                uuid.UUID                   `json:"id"`
+	FileName          string                      `json:"file_name"`
+	Format            string                      `json:"format"`
+	AccountNumber     string                      `json:"account_number"`
+	PeriodStart       *time.Time                  `json:"period_start"`
+	PeriodEnd         *time.Time                  `json:"period_end"`
+	LineCount         int                         `json:"line_count"`
+	DuplicateCount    int                         `json:"duplicate_count"`
+	UnreconciledCount int                         `json:"unreconciled_count"`
+	Lines             []BankStatementLineResponse `json:"lines,omitempty"`
+	CreatedBy         string                      `json:"created_by"`
+	CreatedAt         time.Time                   `json:"created_at"`
+}
+
+// BankStatementLineResponse represents the response payload for a statement line
+type BankStatementLineResponse struct {
+	ID              uuid.UUID  `json:"id"`
+	StatementID     uuid.UUID  `json:"statement_id"`
+	TransactionDate time.Time  `json:"transaction_date"`
+	Amount          float64    `json:"amount"`
+	Description     string     `json:"description"`
+	Reference       string     `json:"reference"`
+	Status          string     `json:"status"`
+	BillID          *uuid.UUID `json:"bill_id"`
+	BillNumber      string     `json:"bill_number,omitempty"`
+	PaymentID       *uuid.UUID `json:"payment_id"`
+	ReconciledBy    string     `json:"reconciled_by"`
+	ReconciledAt    *time.Time `json:"reconciled_at"`
+}
+
+// BankMatchSuggestion represents an open bill a statement line could pay, best matches first
+type BankMatchSuggestion struct {
+	BillID       uuid.UUID `json:"bill_id"`
+	BillNumber   string    `json:"bill_number"`
+	BillDate     time.Time `json:"bill_date"`
+	CustomerName string    `json:"customer_name"`
+	TotalAmount  float64   `json:"total_amount"`
+	Balance      float64   `json:"balance"`
+	Score        int       `json:"score"`   // 0-100
+	Reasons      []string  `json:"reasons"` // why the bill was suggested
+}
@@ -9,47 +9,73 @@ import (
 
 // Bill represents a bill/invoice in the system
 type Bill struct {
-	ID            uuid.UUID      `json:"id" gorm:"type:uuid;primary_key;default:gen_random_uuid()"`
-	ShopID        uuid.UUID      `json:"shop_id" gorm:"not null"`
-	CustomerID    *uuid.UUID     `json:"customer_id" gorm:"type:uuid"`
-	BillNumber    string         `json:"bill_number" gorm:"not null;unique"`
-	BillDate      time.Time      `json:"bill_date" gorm:"not null"`
-	DueDate       *time.Time     `json:"due_date"`
-	SubTotal      float64        `json:"sub_total" gorm:"column:subtotal;not null;default:0"`
-	TaxAmount     float64        `json:"tax_amount" gorm:"not null;default:0"`
-	Discount      float64        `json:"discount" gorm:"column:discount_amount;not null;default:0"`
-	TotalAmount   float64        `json:"total_amount" gorm:"not null;default:0"`
-	PaidAmount    float64        `json:"paid_amount" gorm:"not null;default:0"`
-	PendingAmount float64        `json:"pending_amount" gorm:"not null;default:0"`
-	Balance       float64        `json:"balance" gorm:"not null;default:0"`
-	Status        string         `json:"status" gorm:"not null;default:'draft'"` // draft, sent, paid, overdue, cancelled
-	Notes         string         `json:"notes"`
-	Terms         string         `json:"terms" gorm:"column:payment_terms"`
-	CreatedBy     string         `json:"created_by" gorm:"not null"`
-	PdfURL        string         `json:"pdf_url" gorm:"column:pdf_url"`
-	CreatedAt     time.Time      `json:"created_at"`
-	UpdatedAt     time.Time      `json:"updated_at"`
-	DeletedAt     gorm.DeletedAt `json:"deleted_at" gorm:"index"`
+	ID                uuid.UUID      `json:"id" gorm:"type:uuid;primary_key;default:gen_random_uuid()"`
+	ShopID            uuid.UUID      `json:"shop_id" gorm:"not null"`
+	CustomerID        *uuid.UUID     `json:"customer_id" gorm:"type:uuid"`
+	BillNumber        string         `json:"bill_number" gorm:"not null;unique"`
+	BillDate          time.Time      `json:"bill_date" gorm:"not null"`
+	DueDate           *time.Time     `json:"due_date"`
+	SubTotal          float64        `json:"sub_total" gorm:"column:subtotal;not null;default:0"`
+	TaxAmount         float64        `json:"tax_amount" gorm:"not null;default:0"`
+	Discount          float64        `json:"discount" gorm:"column:discount_amount;not null;default:0"`
+	LineDiscount      float64        `json:"line_discount" gorm:"not null;default:0"`
+	PromotionDiscount float64        `json:"promotion_discount" gorm:"not null;default:0"`
+	TaxableValue      float64        `json:"taxable_value" gorm:"not null;default:0"`
+	TotalAmount       float64        `json:"total_amount" gorm:"not null;default:0"`
+	LateFeeAmount     float64        `json:"late_fee_amount" gorm:"not null;default:0"` // late fees charged after the due date, included in the balance
+	PaidAmount        float64        `json:"paid_amount" gorm:"not null;default:0"`
+	PendingAmount     float64        `json:"pending_amount" gorm:"not null;default:0"`
+	Balance           float64        `json:"balance" gorm:"not null;default:0"`
+	Status            string         `json:"status" gorm:"not null;default:'draft'"` // draft, sent, paid, overdue, cancelled
+	CancelledAt       *time.Time     `json:"cancelled_at"`
+	Notes             string         `json:"notes"`
+	Terms             string         `json:"terms" gorm:"column:payment_terms"`
+	CreatedBy         string         `json:"created_by" gorm:"not null"`
+	PdfURL            string         `json:"pdf_url" gorm:"column:pdf_url"`
+	IRN               string         `json:"irn" gorm:"column:irn;index"`
+	IRNStatus         string         `json:"irn_status" gorm:"column:irn_status"` // generated, cancelled; empty before registration
+	IRNAckNo          string         `json:"irn_ack_no" gorm:"column:irn_ack_no"`
+	IRNAckDate        *time.Time     `json:"irn_ack_date" gorm:"column:irn_ack_date"`
+	IRNSignedQR       string         `json:"irn_signed_qr" gorm:"column:irn_signed_qr;type:text"`
+	IRNCancelledAt    *time.Time     `json:"irn_cancelled_at" gorm:"column:irn_cancelled_at"`
+	NoReminders       bool           `json:"no_reminders" gorm:"not null;default:false"` // left out of payment reminders
+	CreatedAt         time.Time      `json:"created_at"`
+	UpdatedAt         time.Time      `json:"updated_at"`
+	DeletedAt         gorm.DeletedAt `json:"deleted_at" gorm:"index"`
 
 	// Relationships
-	Shop     Shop       `json:"shop,omitempty" gorm:"foreignKey:ShopID"`
-	Customer *Customer  `json:"customer,omitempty" gorm:"foreignKey:CustomerID"`
-	Items    []BillItem `json:"items,omitempty" gorm:"foreignKey:BillID"`
-	Payments []Payment  `json:"payments,omitempty" gorm:"foreignKey:BillID"`
+	Shop       Shop            `json:"shop,omitempty" gorm:"foreignKey:ShopID"`
+	Customer   *Customer       `json:"customer,omitempty" gorm:"foreignKey:CustomerID"`
+	Items      []BillItem      `json:"items,omitempty" gorm:"foreignKey:BillID"`
+	Payments   []Payment       `json:"payments,omitempty" gorm:"foreignKey:BillID"`
+	Promotions []BillPromotion `json:"promotions,omitempty" gorm:"foreignKey:BillID"`
 }
 
 // BillItem represents an item in a bill
 type BillItem struct {
-	ID          uuid.UUID `json:"id" gorm:"type:uuid;primary_key;default:gen_random_uuid()"`
-	BillID      uuid.UUID `json:"bill_id" gorm:"not null"`
-	ItemID      uuid.UUID `json:"item_id" gorm:"not null"`
-	ItemName    string    `json:"item_name" gorm:"not null"`
-	Description string    `json:"description"`
-	Quantity    int       `json:"quantity" gorm:"not null"`
-	UnitPrice   float64   `json:"unit_price" gorm:"not null"`
-	TotalPrice  float64   `json:"total_price" gorm:"not null"`
-	CreatedAt   time.Time `json:"created_at"`
-	UpdatedAt   time.Time `json:"updated_at"`
+	ID                uuid.UUID  `json:"id" gorm:"type:uuid;primary_key;default:gen_random_uuid()"`
+	BillID            uuid.UUID  `json:"bill_id" gorm:"not null"`
+	ItemID            uuid.UUID  `json:"item_id" gorm:"not null"`
+	ItemName          string     `json:"item_name" gorm:"not null"`
+	Description       string     `json:"description"`
+	HSNCode           string     `json:"hsn_code"`
+	Unit              string     `json:"unit"`
+	Quantity          int        `json:"quantity" gorm:"not null"`
+	UnitPrice         float64    `json:"unit_price" gorm:"not null"`
+	TotalPrice        float64    `json:"total_price" gorm:"not null"`
+	UnitCost          float64    `json:"unit_cost" gorm:"not null;default:0"` // item cost price at the time of sale
+	PriceListID       *uuid.UUID `json:"price_list_id" gorm:"type:uuid"`
+	DiscountType      string     `json:"discount_type"` // percentage, amount
+	DiscountValue     float64    `json:"discount_value" gorm:"not null;default:0"`
+	DiscountAmount    float64    `json:"discount_amount" gorm:"not null;default:0"`
+	PromotionID       *uuid.UUID `json:"promotion_id" gorm:"type:uuid"`
+	PromotionDiscount float64    `json:"promotion_discount" gorm:"not null;default:0"`
+	TaxInclusive      bool       `json:"tax_inclusive" gorm:"not null;default:false"`
+	TaxRate           float64    `json:"tax_rate" gorm:"not null;default:0"`
+	TaxableValue      float64    `json:"taxable_value" gorm:"not null;default:0"`
+	TaxAmount         float64    `json:"tax_amount" gorm:"not null;default:0"`
+	CreatedAt         time.Time  `json:"created_at"`
+	UpdatedAt         time.Time  `json:"updated_at"`
 
 	// Relationships
 	Bill Bill `json:"bill,omitempty" gorm:"foreignKey:BillID"`
@@ -58,16 +84,18 @@ type BillItem struct {
 
 // Payment represents a payment for a bill
 type Payment struct {
-	ID            uuid.UUID `json:"id" gorm:"type:uuid;primary_key;default:gen_random_uuid()"`
-	BillID        uuid.UUID `json:"bill_id" gorm:"not null"`
-	Amount        float64   `json:"amount" gorm:"not null"`
-	PaymentDate   time.Time `json:"payment_date" gorm:"not null"`
-	PaymentMethod string    `json:"payment_method" gorm:"not null"` // cash, card, bank_transfer, check, other
-	Reference     string    `json:"reference"`
-	Notes         string    `json:"notes"`
-	CreatedBy     string    `json:"created_by" gorm:"not null"`
-	CreatedAt     time.Time `json:"created_at"`
-	UpdatedAt     time.Time `json:"updated_at"`
+	ID                uuid.UUID  `json:"id" gorm:"type:uuid;primary_key;default:gen_random_uuid()"`
+	BillID            uuid.UUID  `json:"bill_id" gorm:"not null"`
+	Amount            float64    `json:"amount" gorm:"not null"`
+	PaymentDate       time.Time  `json:"payment_date" gorm:"not null"`
+	PaymentMethod     string     `json:"payment_method" gorm:"not null"` // cash, card, bank_transfer, check, loyalty_points, other
+	Reference         string     `json:"reference"`
+	Notes             string     `json:"notes"`
+	TenderedAmount    float64    `json:"tendered_amount" gorm:"not null;default:0"` // cash handed over, when more than the amount
+	RegisterSessionID *uuid.UUID `json:"register_session_id" gorm:"type:uuid;index"`
+	CreatedBy         string     `json:"created_by" gorm:"not null"`
+	CreatedAt         time.Time  `json:"created_at"`
+	UpdatedAt         time.Time  `json:"updated_at"`
 
 	// Relationships
 	Bill Bill `json:"bill,omitempty" gorm:"foreignKey:BillID"`
@@ -75,82 +103,113 @@ type Payment struct {
 
 // BillRequest represents the request payload for creating/updating a bill
 type BillRequest struct {
-	CustomerID *uuid.UUID        `json:"customer_id"`
-	BillDate   string            `json:"bill_date" binding:"required"`
-	DueDate    *string           `json:"due_date"`
-	Items      []BillItemRequest `json:"items" binding:"required"`
-	Discount   float64           `json:"discount"`
-	TaxRate    float64           `json:"tax_rate"`
-	Notes      string            `json:"notes"`
-	Terms      string            `json:"terms"`
+	CustomerID  *uuid.UUID        `json:"customer_id"`
+	BillDate    string            `json:"bill_date" binding:"required"`
+	DueDate     *string           `json:"due_date"`
+	Items       []BillItemRequest `json:"items" binding:"required"`
+	Discount    float64           `json:"discount"`
+	TaxRate     float64           `json:"tax_rate"`
+	Notes       string            `json:"notes"`
+	Terms       string            `json:"terms"`
+	CouponCodes []string          `json:"coupon_codes"`
 }
 
 // BillItemRequest represents an item in a bill request
 type BillItemRequest struct {
-	ItemID      uuid.UUID `json:"item_id" binding:"required"`
-	Quantity    int       `json:"quantity" binding:"required,min=1"`
-	UnitPrice   float64   `json:"unit_price" binding:"required,min=0"`
-	Description string    `json:"description"`
+	ItemID       uuid.UUID `json:"item_id" binding:"required"`
+	Quantity     int       `json:"quantity" binding:"required,min=1"`
+	UnitPrice    *float64  `json:"unit_price" binding:"omitempty,min=0"` // omit to use the customer's price list or the item price; 0 is a free line
+	Description  string    `json:"description"`
+	Discount     float64   `json:"discount" binding:"min=0"`
+	DiscountType string    `json:"discount_type" binding:"omitempty,oneof=percentage amount"`
+	TaxInclusive *bool     `json:"tax_inclusive"` // omit to use the item's or the shop's setting
 }
 
 // PaymentRequest represents a payment request
 type PaymentRequest struct {
-	Amount        float64 `json:"amount" binding:"required,min=0"`
-	PaymentDate   string  `json:"payment_date" binding:"required"`
-	PaymentMethod string  `json:"payment_method" binding:"required"`
-	Reference     string  `json:"reference"`
-	Notes         string  `json:"notes"`
+	Amount         float64 `json:"amount" binding:"required,min=0"`
+	PaymentDate    string  `json:"payment_date" binding:"required"`
+	PaymentMethod  string  `json:"payment_method" binding:"required"`
+	Reference      string  `json:"reference"`
+	Notes          string  `json:"notes"`
+	TenderedAmount float64 `json:"tendered_amount"`
 }
 
 // BillResponse represents the response payload for bill data
 type BillResponse struct {
-	ID          uuid.UUID          `json:"id"`
-	ShopID      uuid.UUID          `json:"shop_id"`
-	CustomerID  *uuid.UUID         `json:"customer_id"`
-	BillNumber  string             `json:"bill_number"`
-	BillDate    time.Time          `json:"bill_date"`
-	DueDate     *time.Time         `json:"due_date"`
-	SubTotal    float64            `json:"sub_total"`
-	TaxAmount   float64            `json:"tax_amount"`
-	Discount    float64            `json:"discount"`
-	TotalAmount float64            `json:"total_amount"`
-	PaidAmount  float64            `json:"paid_amount"`
-	Balance     float64            `json:"balance"`
-	Status      string             `json:"status"`
-	Notes       string             `json:"notes"`
-	Terms       string             `json:"terms"`
-	Customer    *CustomerResponse  `json:"customer,omitempty"`
-	Items       []BillItemResponse `json:"items"`
-	Payments    []PaymentResponse  `json:"payments"`
-	CreatedAt   time.Time          `json:"created_at"`
-	UpdatedAt   time.Time          `json:"updated_at"`
+	ID                uuid.UUID               `json:"id"`
+	ShopID            uuid.UUID               `json:"shop_id"`
+	CustomerID        *uuid.UUID              `json:"customer_id"`
+	BillNumber        string                  `json:"bill_number"`
+	BillDate          time.Time               `json:"bill_date"`
+	DueDate           *time.Time              `json:"due_date"`
+	SubTotal          float64                 `json:"sub_total"`
+	TaxAmount         float64                 `json:"tax_amount"`
+	Discount          float64                 `json:"discount"`
+	LineDiscount      float64                 `json:"line_discount"`
+	PromotionDiscount float64                 `json:"promotion_discount"`
+	TaxableValue      float64                 `json:"taxable_value"`
+	TotalAmount       float64                 `json:"total_amount"`
+	LateFeeAmount     float64                 `json:"late_fee_amount"`
+	PaidAmount        float64                 `json:"paid_amount"`
+	Balance           float64                 `json:"balance"`
+	Status            string                  `json:"status"`
+	CancelledAt       *time.Time              `json:"cancelled_at"`
+	Notes             string                  `json:"notes"`
+	Terms             string                  `json:"terms"`
+	IRN               string                  `json:"irn"`
+	IRNStatus         string                  `json:"irn_status"`
+	IRNAckNo          string                  `json:"irn_ack_no"`
+	IRNAckDate        *time.Time              `json:"irn_ack_date"`
+	NoReminders       bool                    `json:"no_reminders"`
+	Customer          *CustomerResponse       `json:"customer,omitempty"`
+	Items             []BillItemResponse      `json:"items"`
+	Payments          []PaymentResponse       `json:"payments"`
+	Promotions        []BillPromotionResponse `json:"promotions"`
+	CreatedAt         time.Time               `json:"created_at"`
+	UpdatedAt         time.Time               `json:"updated_at"`
 }
 
 // BillItemResponse represents the response payload for bill item data
 type BillItemResponse struct {
-	ID          uuid.UUID `json:"id"`
-	BillID      uuid.UUID `json:"bill_id"`
-	ItemID      uuid.UUID `json:"item_id"`
-	ItemName    string    `json:"item_name"`
-	Description string    `json:"description"`
-	Quantity    int       `json:"quantity"`
-	UnitPrice   float64   `json:"unit_price"`
-	TotalPrice  float64   `json:"total_price"`
-	CreatedAt   time.Time `json:"created_at"`
-	UpdatedAt   time.Time `json:"updated_at"`
+	ID                uuid.UUID  `json:"id"`
+	BillID            uuid.UUID  `json:"bill_id"`
+	ItemID            uuid.UUID  `json:"item_id"`
+	ItemName          string     `json:"item_name"`
+	Description       string     `json:"description"`
+	HSNCode           string     `json:"hsn_code"`
+	Unit              string     `json:"unit"`
+	Quantity          int        `json:"quantity"`
+	UnitPrice         float64    `json:"unit_price"`
+	TotalPrice        float64    `json:"total_price"`
+	UnitCost          float64    `json:"unit_cost"`
+	PriceListID       *uuid.UUID `json:"price_list_id"`
+	DiscountType      string     `json:"discount_type"`
+	DiscountValue     float64    `json:"discount_value"`
+	DiscountAmount    float64    `json:"discount_amount"`
+	PromotionID       *uuid.UUID `json:"promotion_id"`
+	PromotionDiscount float64    `json:"promotion_discount"`
+	TaxInclusive      bool       `json:"tax_inclusive"`
+	TaxRate           float64    `json:"tax_rate"`
+	TaxableValue      float64    `json:"taxable_value"`
+	TaxAmount         float64    `json:"tax_amount"`
+	CreatedAt         time.Time  `json:"created_at"`
+	UpdatedAt         time.Time  `json:"updated_at"`
 }
 
 // PaymentResponse represents the response payload for payment data
 type PaymentResponse struct {
-	ID            uuid.UUID `json:"id"`
-	BillID        uuid.UUID `json:"bill_id"`
-	Amount        float64   `json:"amount"`
-	PaymentDate   time.Time `json:"payment_date"`
-	PaymentMethod string    `json:"payment_method"`
-	Reference     string    `json:"reference"`
-	Notes         string    `json:"notes"`
-	CreatedAt     time.Time `json:"created_at"`
-	UpdatedAt     time.Time `json:"updated_at"`
+	ID                uuid.UUID  `json:"id"`
+	BillID            uuid.UUID  `json:"bill_id"`
+	Amount            float64    `json:"amount"`
+	PaymentDate       time.Time  `json:"payment_date"`
+	PaymentMethod     string     `json:"payment_method"`
+	Reference         string     `json:"reference"`
+	Notes             string     `json:"notes"`
+	TenderedAmount    float64    `json:"tendered_amount"`
+	RegisterSessionID *uuid.UUID `json:"register_session_id"`
+	CreatedAt         time.Time  `json:"created_at"`
+	UpdatedAt         time.Time  `json:"updated_at"`
 }
 
 // BillStats represents bill statistics
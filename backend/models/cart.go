@@ -0,0 +1,105 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Cart represents a held sale that has no bill number and only reserves stock
+type Cart struct {
+	ID         uuid.UUID  `json:"id" gorm:"type:uuid;primary_key;default:gen_random_uuid()"`
+	ShopID     uuid.UUID  `json:"shop_id" gorm:"type:uuid;not null;index"`
+	CustomerID *uuid.UUID `json:"customer_id" gorm:"type:uuid"`
+	Name       string     `json:"name"`
+	Status     string     `json:"status" gorm:"not null;default:'open';index"` // open, checked_out, expired, cancelled
+	Discount   float64    `json:"discount" gorm:"not null;default:0"`
+	TaxRate    float64    `json:"tax_rate" gorm:"not null;default:0"`
+	Notes      string     `json:"notes"`
+	Terms      string     `json:"terms"`
+	ExpiresAt  time.Time  `json:"expires_at" gorm:"not null;index"`
+	BillID     *uuid.UUID `json:"bill_id" gorm:"type:uuid"`
+	CreatedBy  string     `json:"created_by" gorm:"not null"`
+	UpdatedBy  string     `json:"updated_by"`
+	CreatedAt  time.Time  `json:"created_at"`
+	UpdatedAt  time.Time  `json:"updated_at"`
+
+	// Relationships
+	Customer *Customer  `json:"customer,omitempty" gorm:"foreignKey:CustomerID"`
+	Items    []CartItem `json:"items,omitempty" gorm:"foreignKey:CartID"`
+}
+
+// CartItem represents an item in a held cart
+type CartItem struct {
+	ID           uuid.UUID `json:"id" gorm:"type:uuid;primary_key;default:gen_random_uuid()"`
+	CartID       uuid.UUID `json:"cart_id" gorm:"type:uuid;not null;index"`
+	ItemID       uuid.UUID `json:"item_id" gorm:"type:uuid;not null;index"`
+	ItemName     string    `json:"item_name" gorm:"not null"`
+	Description  string    `json:"description"`
+	Quantity     int       `json:"quantity" gorm:"not null"`
+	UnitPrice    float64   `json:"unit_price" gorm:"not null"`
+	Discount     float64   `json:"discount" gorm:"not null;default:0"`
+	DiscountType string    `json:"discount_type"` // percentage, amount
+	TaxInclusive *bool     `json:"tax_inclusive"` // nil uses the item's or the shop's setting
+	CreatedAt    time.Time `json:"created_at"`
+	UpdatedAt    time.Time `json:"updated_at"`
+}
+
+// CartRequest represents the request payload for creating/updating a cart
+type CartRequest struct {
+	CustomerID       *uuid.UUID        `json:"customer_id"`
+	Name             string            `json:"name"`
+	Items            []BillItemRequest `json:"items"`
+	Discount         float64           `json:"discount"`
+	TaxRate          float64           `json:"tax_rate"`
+	Notes            string            `json:"notes"`
+	Terms            string            `json:"terms"`
+	ExpiresInMinutes int               `json:"expires_in_minutes"`
+}
+
+// CartCheckoutRequest represents the request payload for converting a cart into a bill
+type CartCheckoutRequest struct {
+	BillDate    string   `json:"bill_date"`
+	DueDate     *string  `json:"due_date"`
+	CouponCodes []string `json:"coupon_codes"`
+}
+
+// CartResponse represents the response payload for cart data
+type CartResponse struct {
+	ID                uuid.UUID          `json:"id"`
+	ShopID            uuid.UUID          `json:"shop_id"`
+	CustomerID        *uuid.UUID         `json:"customer_id"`
+	Name              string             `json:"name"`
+	Status            string             `json:"status"`
+	SubTotal          float64            `json:"sub_total"`
+	TaxAmount         float64            `json:"tax_amount"`
+	Discount          float64            `json:"discount"`
+	LineDiscount      float64            `json:"line_discount"`
+	PromotionDiscount float64            `json:"promotion_discount"`
+	TaxRate           float64            `json:"tax_rate"`
+	Total             float64            `json:"total"`
+	Notes             string             `json:"notes"`
+	Terms             string             `json:"terms"`
+	ExpiresAt         time.Time          `json:"expires_at"`
+	BillID            *uuid.UUID         `json:"bill_id"`
+	CreatedBy         string             `json:"created_by"`
+	UpdatedBy         string             `json:"updated_by"`
+	Customer          *CustomerResponse  `json:"customer,omitempty"`
+	Items             []CartItemResponse `json:"items"`
+	CreatedAt         time.Time          `json:"created_at"`
+	UpdatedAt         time.Time          `json:"updated_at"`
+}
+
+// CartItemResponse represents the response payload for cart item data
+type CartItemResponse struct {
+	ID           uuid.UUID `json:"id"`
+	ItemID       uuid.UUID `json:"item_id"`
+	ItemName     string    `json:"item_name"`
+	Description  string    `json:"description"`
+	Quantity     int       `json:"quantity"`
+	UnitPrice    float64   `json:"unit_price"`
+	TotalPrice   float64   `json:"total_price"`
+	Discount     float64   `json:"discount"`
+	DiscountType string    `json:"discount_type"`
+	TaxInclusive *bool     `json:"tax_inclusive"`
+}
@@ -9,54 +9,93 @@ import (
 
 // Customer represents a customer in the system
 type Customer struct {
-	ID         uuid.UUID      `json:"id" gorm:"type:uuid;primary_key;default:gen_random_uuid()"`
-	ShopID     uuid.UUID      `json:"shop_id" gorm:"type:uuid;not null"`
-	Name       string         `json:"name" gorm:"not null"`
-	Email      string         `json:"email"`
-	Phone      string         `json:"phone"`
-	Address    string         `json:"address"`
-	City       string         `json:"city"`
-	State      string         `json:"state"`
-	Country    string         `json:"country"`
-	PostalCode string         `json:"postal_code"`
-	TaxNumber  string         `json:"tax_number"`
-	Notes      string         `json:"notes"`
-	IsActive   bool           `json:"is_active" gorm:"default:true"`
-	CreatedAt  time.Time      `json:"created_at"`
-	UpdatedAt  time.Time      `json:"updated_at"`
-	DeletedAt  gorm.DeletedAt `json:"deleted_at" gorm:"index"`
+	ID             uuid.UUID      `json:"id" gorm:"type:uuid;primary_key;default:gen_random_uuid()"`
+	ShopID         uuid.UUID      `json:"shop_id" gorm:"type:uuid;not null"`
+	Name           string         `json:"name" gorm:"not null"`
+	Email          string         `json:"email"`
+	Phone          string         `json:"phone"`
+	Address        string         `json:"address"`
+	City           string         `json:"city"`
+	State          string         `json:"state"`
+	Country        string         `json:"country"`
+	PostalCode     string         `json:"postal_code"`
+	TaxNumber      string         `json:"tax_number"`
+	Notes          string         `json:"notes"`
+	GroupID        *uuid.UUID     `json:"group_id" gorm:"type:uuid;index"`
+	LoyaltyPoints  int            `json:"loyalty_points" gorm:"not null;default:0"`
+	LifetimePoints int            `json:"lifetime_points" gorm:"not null;default:0"`  // points ever earned, used for tiers
+	NoReminders    bool           `json:"no_reminders" gorm:"not null;default:false"` // left out of payment reminders
+	IsActive       bool           `json:"is_active" gorm:"default:true"`
+	CreatedAt      time.Time      `json:"created_at"`
+	UpdatedAt      time.Time      `json:"updated_at"`
+	DeletedAt      gorm.DeletedAt `json:"deleted_at" gorm:"index"`
+
+	// Relationships
+	Group *CustomerGroup `json:"group,omitempty" gorm:"foreignKey:GroupID"`
 }
 
 // CustomerRequest represents the request payload for creating/updating a customer
 type CustomerRequest struct {
-	Name       string `json:"name" binding:"required"`
-	Email      string `json:"email"`
-	Phone      string `json:"phone"`
-	Address    string `json:"address"`
-	City       string `json:"city"`
-	State      string `json:"state"`
-	Country    string `json:"country"`
-	PostalCode string `json:"postal_code"`
-	TaxNumber  string `json:"tax_number"`
-	Notes      string `json:"notes"`
-	IsActive   bool   `json:"is_active"`
+	Name        string     `json:"name" binding:"required"`
+	Email       string     `json:"email"`
+	Phone       string     `json:"phone"`
+	Address     string     `json:"address"`
+	City        string     `json:"city"`
+	State       string     `json:"state"`
+	Country     string     `json:"country"`
+	PostalCode  string     `json:"postal_code"`
+	TaxNumber   string     `json:"tax_number"`
+	Notes       string     `json:"notes"`
+	GroupID     *uuid.UUID `json:"group_id"`
+	NoReminders bool       `json:"no_reminders"`
+	IsActive    bool       `json:"is_active"`
 }
 
 // CustomerResponse represents the response payload for customer data
 type CustomerResponse struct {
-	ID         uuid.UUID `json:"id"`
-	ShopID     uuid.UUID `json:"shop_id"`
-	Name       string    `json:"name"`
-	Email      string    `json:"email"`
-	Phone      string    `json:"phone"`
-	Address    string    `json:"address"`
-	City       string    `json:"city"`
-	State      string    `json:"state"`
-	Country    string    `json:"country"`
-	PostalCode string    `json:"postal_code"`
-	TaxNumber  string    `json:"tax_number"`
-	Notes      string    `json:"notes"`
-	IsActive   bool      `json:"is_active"`
-	CreatedAt  time.Time `json:"created_at"`
-	UpdatedAt  time.Time `json:"updated_at"`
+	ID            uuid.UUID  `json:"id"`
+	ShopID        uuid.UUID  `json:"shop_id"`
+	Name          string     `json:"name"`
+	Email         string     `json:"email"`
+	Phone         string     `json:"phone"`
+	Address       string     `json:"address"`
+	City          string     `json:"city"`
+	State         string     `json:"state"`
+	Country       string     `json:"country"`
+	PostalCode    string     `json:"postal_code"`
+	TaxNumber     string     `json:"tax_number"`
+	Notes         string     `json:"notes"`
+	GroupID       *uuid.UUID `json:"group_id"`
+	GroupName     string     `json:"group_name,omitempty"`
+	LoyaltyPoints int        `json:"loyalty_points"`
+	NoReminders   bool       `json:"no_reminders"`
+	IsActive      bool       `json:"is_active"`
+	CreatedAt     time.Time  `json:"created_at"`
+	UpdatedAt     time.Time  `json:"updated_at"`
+}
+
+// CustomerStatementLine is one document on a customer statement
+type CustomerStatementLine struct {
+	Date        time.Time  `json:"date"`
+	Type        string     `json:"type"` // bill, payment, late_fee, late_fee_waiver
+	Reference   string     `json:"reference"`
+	Description string     `json:"description"`
+	BillID      *uuid.UUID `json:"bill_id"`
+	Debit       float64    `json:"debit"`
+	Credit      float64    `json:"credit"`
+	Balance     float64    `json:"balance"` // running balance owed by the customer
+}
+
+// CustomerStatement represents a customer's account over a date range: bills and late fees
+// charged, and payments and waivers credited
+type CustomerStatement struct {
+	Customer       CustomerResponse        `json:"customer"`
+	From           time.Time               `json:"from"`
+	To             time.Time               `json:"to"`
+	OpeningBalance float64                 `json:"opening_balance"`
+	Lines          []CustomerStatementLine `json:"lines"`
+	TotalDebit     float64                 `json:"total_debit"`
+	TotalCredit    float64                 `json:"total_credit"`
+	LateFees       float64                 `json:"late_fees"` // late fees charged in the range, less waivers
+	ClosingBalance float64                 `json:"closing_balance"`
 }
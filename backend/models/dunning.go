@@ -0,0 +1,104 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// DunningStep is one step of a shop's payment reminder schedule, such as a reminder three days
+// before the due date or a week after it. Each open bill gets every step at most once.
+type DunningStep struct {
+	ID            uuid.UUID `json:"id" gorm:"type:uuid;primary_key;default:gen_random_uuid()"`
+	ShopID        uuid.UUID `json:"shop_id" gorm:"type:uuid;not null;index"`
+	Name          string    `json:"name" gorm:"not null"`
+	DaysFromDue   int       `json:"days_from_due" gorm:"not null"` // negative before the due date, positive after it
+	Channel       string    `json:"channel" gorm:"not null"`       // email, sms, whatsapp, webhook
+	Subject       string    `json:"subject"`                       // email subject template, defaults to the shop's reminder template
+	Body          string    `json:"body" gorm:"type:text"`         // message template, defaults to the shop's reminder template for the channel
+	WebhookURL    string    `json:"webhook_url"`
+	WebhookSecret string    `json:"-"` // signs webhook bodies when set
+	IsActive      bool      `json:"is_active" gorm:"not null;default:true"`
+	CreatedBy     string    `json:"created_by" gorm:"not null"`
+	CreatedAt     time.Time `json:"created_at"`
+	UpdatedAt     time.Time `json:"updated_at"`
+}
+
+// PaymentReminder records a dunning step taken for a bill. The unique index on the bill and
+// step means a reminder is never sent twice, even by concurrent runs.
+type PaymentReminder struct {
+	ID                     uuid.UUID  `json:"id" gorm:"type:uuid;primary_key;default:gen_random_uuid()"`
+	ShopID                 uuid.UUID  `json:"shop_id" gorm:"type:uuid;not null;index"`
+	BillID                 uuid.UUID  `json:"bill_id" gorm:"type:uuid;not null;uniqueIndex:idx_payment_reminder_step"`
+	StepID                 uuid.UUID  `json:"step_id" gorm:"type:uuid;not null;uniqueIndex:idx_payment_reminder_step"`
+	StepName               string     `json:"step_name" gorm:"not null"`
+	DaysFromDue            int        `json:"days_from_due" gorm:"not null"`
+	Channel                string     `json:"channel" gorm:"not null"`
+	Recipient              string     `json:"recipient"`
+	Balance                float64    `json:"balance" gorm:"not null"`      // bill balance when the reminder was sent
+	Status                 string     `json:"status" gorm:"not null;index"` // sending, sent, queued, failed, skipped
+	Error                  string     `json:"error"`
+	EmailDeliveryID        *uuid.UUID `json:"email_delivery_id" gorm:"type:uuid"`
+	NotificationDeliveryID *uuid.UUID `json:"notification_delivery_id" gorm:"type:uuid"`
+	CreatedAt              time.Time  `json:"created_at"`
+	UpdatedAt              time.Time  `json:"updated_at"`
+}
+
+// DunningStepRequest represents the request payload for creating/updating a dunning step
+type DunningStepRequest struct {
+	Name          string  `json:"name" binding:"required"`
+	DaysFromDue   int     `json:"days_from_due" binding:"min=-90,max=365"`
+	Channel       string  `json:"channel" binding:"required,oneof=email sms whatsapp webhook"`
+	Subject       string  `json:"subject"`
+	Body          string  `json:"body"`
+	WebhookURL    string  `json:"webhook_url" binding:"omitempty,url"`
+	WebhookSecret *string `json:"webhook_secret"` // omitted to keep the stored secret
+	IsActive      *bool   `json:"is_active"`      // defaults to true
+}
+
+// DunningStepResponse represents the response payload for a dunning step
+type DunningStepResponse struct {
+	ID               uuid.UUID `json:"id"`
+	Name             string    `json:"name"`
+	DaysFromDue      int       `json:"days_from_due"`
+	Channel          string    `json:"channel"`
+	Subject          string    `json:"subject"`
+	Body             string    `json:"body"`
+	WebhookURL       string    `json:"webhook_url"`
+	WebhookSecretSet bool      `json:"webhook_secret_set"`
+	IsActive         bool      `json:"is_active"`
+	CreatedBy        string    `json:"created_by"`
+	CreatedAt        time.Time `json:"created_at"`
+	UpdatedAt        time.Time `json:"updated_at"`
+}
+
+// ReminderExclusionRequest represents the request payload for leaving a bill out of reminders
+type ReminderExclusionRequest struct {
+	Excluded *bool `json:"excluded" binding:"required"`
+}
+
+// PaymentReminderResponse represents the response payload for a reminder sent for a bill
+type PaymentReminderResponse struct {
+	ID                     uuid.UUID  `json:"id"`
+	BillID                 uuid.UUID  `json:"bill_id"`
+	BillNumber             string     `json:"bill_number,omitempty"`
+	StepID                 uuid.UUID  `json:"step_id"`
+	StepName               string     `json:"step_name"`
+	DaysFromDue            int        `json:"days_from_due"`
+	Channel                string     `json:"channel"`
+	Recipient              string     `json:"recipient"`
+	Balance                float64    `json:"balance"`
+	Status                 string     `json:"status"`
+	Error                  string     `json:"error"`
+	EmailDeliveryID        *uuid.UUID `json:"email_delivery_id"`
+	NotificationDeliveryID *uuid.UUID `json:"notification_delivery_id"`
+	CreatedAt              time.Time  `json:"created_at"`
+}
+
+// DunningRunResult summarizes a run of the reminder schedule
+type DunningRunResult struct {
+	BillsChecked int `json:"bills_checked"`
+	Sent         int `json:"sent"`
+	Failed       int `json:"failed"`
+	Skipped      int `json:"skipped"`
+}
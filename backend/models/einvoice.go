@@ -0,0 +1,97 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// The e-invoice types below follow the IRP's INV-01 JSON schema, so their field names are the
+// schema's rather than this API's usual style.
+
+// EInvoice represents an invoice in the INV-01 schema registered with the Invoice Registration Portal
+type EInvoice struct {
+	Version    string              `json:"Version"`
+	TranDtls   EInvoiceTransaction `json:"TranDtls"`
+	DocDtls    EInvoiceDocument    `json:"DocDtls"`
+	SellerDtls EInvoiceParty       `json:"SellerDtls"`
+	BuyerDtls  EInvoiceParty       `json:"BuyerDtls"`
+	ItemList   []EInvoiceItem      `json:"ItemList"`
+	ValDtls    EInvoiceValues      `json:"ValDtls"`
+}
+
+// EInvoiceTransaction represents the transaction details of an e-invoice
+type EInvoiceTransaction struct {
+	TaxSch      string `json:"TaxSch"`
+	SupTyp      string `json:"SupTyp"`
+	RegRev      string `json:"RegRev"`
+	IgstOnIntra string `json:"IgstOnIntra"`
+}
+
+// EInvoiceDocument represents the document number, type and date of an e-invoice
+type EInvoiceDocument struct {
+	Typ string `json:"Typ"`
+	No  string `json:"No"`
+	Dt  string `json:"Dt"` // DD/MM/YYYY
+}
+
+// EInvoiceParty represents the seller or buyer of an e-invoice
+type EInvoiceParty struct {
+	Gstin string `json:"Gstin"`
+	LglNm string `json:"LglNm"`
+	Pos   string `json:"Pos,omitempty"` // buyer only
+	Addr1 string `json:"Addr1"`
+	Loc   string `json:"Loc"`
+	Pin   int    `json:"Pin"`
+	Stcd  string `json:"Stcd"`
+	Ph    string `json:"Ph,omitempty"`
+	Em    string `json:"Em,omitempty"`
+}
+
+// EInvoiceItem represents one line of an e-invoice
+type EInvoiceItem struct {
+	SlNo       string  `json:"SlNo"`
+	PrdDesc    string  `json:"PrdDesc"`
+	IsServc    string  `json:"IsServc"`
+	HsnCd      string  `json:"HsnCd"`
+	Qty        float64 `json:"Qty"`
+	Unit       string  `json:"Unit"`
+	UnitPrice  float64 `json:"UnitPrice"` // before tax
+	TotAmt     float64 `json:"TotAmt"`
+	Discount   float64 `json:"Discount"`
+	AssAmt     float64 `json:"AssAmt"`
+	GstRt      float64 `json:"GstRt"`
+	IgstAmt    float64 `json:"IgstAmt"`
+	CgstAmt    float64 `json:"CgstAmt"`
+	SgstAmt    float64 `json:"SgstAmt"`
+	TotItemVal float64 `json:"TotItemVal"`
+}
+
+// EInvoiceValues represents the invoice totals of an e-invoice
+type EInvoiceValues struct {
+	AssVal    float64 `json:"AssVal"`
+	CgstVal   float64 `json:"CgstVal"`
+	SgstVal   float64 `json:"SgstVal"`
+	IgstVal   float64 `json:"IgstVal"`
+	Discount  float64 `json:"Discount"`
+	RndOffAmt float64 `json:"RndOffAmt"`
+	TotInvVal float64 `json:"TotInvVal"`
+}
+
+// EInvoiceCancelRequest represents the request payload for cancelling an e-invoice
+type EInvoiceCancelRequest struct {
+	Reason string `json:"reason" binding:"required,oneof=duplicate data_entry_mistake order_cancelled other"`
+	Remark string `json:"remark" binding:"required,max=100"`
+}
+
+// EInvoiceResponse represents the registration details of a bill's e-invoice
+type EInvoiceResponse struct {
+	BillID       uuid.UUID  `json:"bill_id"`
+	Status       string     `json:"status"` // not_generated, generated, cancelled
+	IRN          string     `json:"irn"`
+	AckNo        string     `json:"ack_no"`
+	AckDate      *time.Time `json:"ack_date"`
+	SignedQRCode string     `json:"signed_qr_code"`
+	CancelBy     *time.Time `json:"cancel_by"` // end of the cancellation window
+	CancelledAt  *time.Time `json:"cancelled_at"`
+}
@@ -0,0 +1,133 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// EmailSettings holds a shop's own SMTP server. Shops without one send through the server
+// configured for the whole installation.
+type EmailSettings struct {
+	ID          uuid.UUID `json:"id" gorm:"type:uuid;primary_key;default:gen_random_uuid()"`
+	ShopID      uuid.UUID `json:"shop_id" gorm:"type:uuid;not null;uniqueIndex"`
+	Host        string    `json:"host"` // empty to use the installation's server
+	Port        int       `json:"port" gorm:"not null;default:587"`
+	Username    string    `json:"username"`
+	Password    string    `json:"-"`
+	Security    string    `json:"security" gorm:"not null;default:'starttls'"` // none, starttls, tls
+	FromAddress string    `json:"from_address"`
+	FromName    string    `json:"from_name"`
+	ReplyTo     string    `json:"reply_to"`
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
+
+// EmailTemplate overrides the built-in subject and body of an email a shop sends. Both are Go
+// text templates filled with the bill and shop being emailed.
+type EmailTemplate struct {
+	ID        uuid.UUID `json:"id" gorm:"type:uuid;primary_key;default:gen_random_uuid()"`
+	ShopID    uuid.UUID `json:"shop_id" gorm:"type:uuid;not null;uniqueIndex:idx_email_template_event"`
+	Event     string    `json:"event" gorm:"not null;uniqueIndex:idx_email_template_event"` // invoice, reminder
+	Subject   string    `json:"subject" gorm:"not null"`
+	Body      string    `json:"body" gorm:"type:text;not null"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// EmailDelivery records an email sent, or being sent, by a shop. Deliveries that fail for a
+// reason that may pass, such as the server being unreachable, are retried with a backoff.
+type EmailDelivery struct {
+	ID                 uuid.UUID  `json:"id" gorm:"type:uuid;primary_key;default:gen_random_uuid()"`
+	ShopID             uuid.UUID  `json:"shop_id" gorm:"type:uuid;not null;index"`
+	BillID             *uuid.UUID `json:"bill_id" gorm:"type:uuid;index"`
+	RecurringBillRunID *uuid.UUID `json:"recurring_bill_run_id" gorm:"type:uuid;uniqueIndex"` // set for the invoice email of an auto-emailed recurring bill
+	Event              string     `json:"event" gorm:"not null"`                              // invoice, test
+	ToAddresses        string     `json:"to_addresses" gorm:"not null"`                       // comma separated
+	CCAddresses        string     `json:"cc_addresses"`
+	Subject            string     `json:"subject" gorm:"not null"`
+	Body               string     `json:"body" gorm:"type:text;not null"`
+	AttachPDF          bool       `json:"attach_pdf" gorm:"not null;default:false"`
+	Status             string     `json:"status" gorm:"not null;default:'queued';index"` // queued, sent, failed
+	Attempts           int        `json:"attempts" gorm:"not null;default:0"`
+	NextAttemptAt      *time.Time `json:"next_attempt_at" gorm:"index"`
+	LastError          string     `json:"last_error"`
+	MessageID          string     `json:"message_id"`
+	SentAt             *time.Time `json:"sent_at"`
+	CreatedBy          string     `json:"created_by" gorm:"not null"`
+	CreatedAt          time.Time  `json:"created_at"`
+	UpdatedAt          time.Time  `json:"updated_at"`
+}
+
+// EmailSettingsRequest represents the request payload for updating a shop's SMTP server
+type EmailSettingsRequest struct {
+	Host        string  `json:"host"`
+	Port        int     `json:"port" binding:"omitempty,min=1,max=65535"`
+	Username    string  `json:"username"`
+	Password    *string `json:"password"` // omitted to keep the stored password
+	Security    string  `json:"security" binding:"omitempty,oneof=none starttls tls"`
+	FromAddress string  `json:"from_address" binding:"omitempty,email"`
+	FromName    string  `json:"from_name"`
+	ReplyTo     string  `json:"reply_to" binding:"omitempty,email"`
+}
+
+// EmailSettingsResponse represents the response payload for a shop's SMTP server
+type EmailSettingsResponse struct {
+	Host          string `json:"host"`
+	Port          int    `json:"port"`
+	Username      string `json:"username"`
+	PasswordSet   bool   `json:"password_set"`
+	Security      string `json:"security"`
+	FromAddress   string `json:"from_address"`
+	FromName      string `json:"from_name"`
+	ReplyTo       string `json:"reply_to"`
+	DefaultServer bool   `json:"default_server"` // the shop sends through the installation's server
+}
+
+// EmailTemplateRequest represents the request payload for customizing an email template
+type EmailTemplateRequest struct {
+	Subject string `json:"subject" binding:"required"`
+	Body    string `json:"body" binding:"required"`
+}
+
+// EmailTemplateResponse represents the response payload for an email template
+type EmailTemplateResponse struct {
+	Event     string `json:"event"`
+	Subject   string `json:"subject"`
+	Body      string `json:"body"`
+	IsDefault bool   `json:"is_default"` // the shop has not customized it
+}
+
+// SendBillEmailRequest represents the request payload for emailing a bill. The subject and
+// body default to the shop's invoice template.
+type SendBillEmailRequest struct {
+	To        []string `json:"to" binding:"omitempty,dive,email"` // defaults to the customer's email
+	CC        []string `json:"cc" binding:"omitempty,dive,email"`
+	Subject   string   `json:"subject"`
+	Body      string   `json:"body"`
+	AttachPDF *bool    `json:"attach_pdf"` // defaults to true
+}
+
+// TestEmailRequest represents the request payload for checking a shop's email settings
+type TestEmailRequest struct {
+	To string `json:"to" binding:"required,email"`
+}
+
+// EmailDeliveryResponse represents the response payload for a sent email
+type EmailDeliveryResponse struct {
+	ID            uuid.UUID  `json:"id"`
+	BillID        *uuid.UUID `json:"bill_id"`
+	Event         string     `json:"event"`
+	To            []string   `json:"to"`
+	CC            []string   `json:"cc"`
+	Subject       string     `json:"subject"`
+	AttachPDF     bool       `json:"attach_pdf"`
+	Status        string     `json:"status"`
+	Attempts      int        `json:"attempts"`
+	NextAttemptAt *time.Time `json:"next_attempt_at"`
+	LastError     string     `json:"last_error"`
+	MessageID     string     `json:"message_id"`
+	SentAt        *time.Time `json:"sent_at"`
+	CreatedBy     string     `json:"created_by"`
+	CreatedAt     time.Time  `json:"created_at"`
+}
@@ -0,0 +1,210 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// EWayBill represents the transport details for moving the goods of a bill. It is prepared here,
+// uploaded to the e-way bill portal, and the number the portal assigns is recorded back.
+type EWayBill struct {
+	ID              uuid.UUID      `json:"id" gorm:"type:uuid;primary_key;default:gen_random_uuid()"`
+	ShopID          uuid.UUID      `json:"shop_id" gorm:"type:uuid;not null;index"`
+	BillID          uuid.UUID      `json:"bill_id" gorm:"type:uuid;not null;index"`
+	SubSupplyType   string         `json:"sub_supply_type" gorm:"not null;default:'supply'"` // supply, export, job_work, sales_return, others
+	TransportMode   string         `json:"transport_mode" gorm:"not null"`                   // road, rail, air, ship
+	Distance        int            `json:"distance" gorm:"not null;default:0"`               // approximate distance in km
+	TransporterID   string         `json:"transporter_id"`                                   // transporter GSTIN or TRANSIN
+	TransporterName string         `json:"transporter_name"`
+	TransDocNo      string         `json:"trans_doc_no"` // LR, RR, airway bill or bill of lading number
+	TransDocDate    *time.Time     `json:"trans_doc_date" gorm:"type:date"`
+	VehicleNumber   string         `json:"vehicle_number"`
+	VehicleType     string         `json:"vehicle_type" gorm:"not null;default:'regular'"` // regular, over_dimensional
+	FromPincode     string         `json:"from_pincode" gorm:"not null"`
+	ToPincode       string         `json:"to_pincode" gorm:"not null"`
+	Status          string         `json:"status" gorm:"not null;default:'draft';index"` // draft, active, cancelled
+	EWayBillNumber  string         `json:"eway_bill_number" gorm:"column:eway_bill_number;index"`
+	GeneratedAt     *time.Time     `json:"generated_at"`
+	ValidUntil      *time.Time     `json:"valid_until" gorm:"index"`
+	CancelledAt     *time.Time     `json:"cancelled_at"`
+	CancelReason    string         `json:"cancel_reason"`
+	CreatedBy       string         `json:"created_by" gorm:"not null"`
+	CreatedAt       time.Time      `json:"created_at"`
+	UpdatedAt       time.Time      `json:"updated_at"`
+	DeletedAt       gorm.DeletedAt `json:"deleted_at" gorm:"index"`
+
+	// Relationships
+	Bill           Bill                    `json:"bill,omitempty" gorm:"foreignKey:BillID"`
+	VehicleUpdates []EWayBillVehicleUpdate `json:"vehicle_updates,omitempty" gorm:"foreignKey:EWayBillID"`
+}
+
+// EWayBillVehicleUpdate represents a change of vehicle while the goods are in transit
+type EWayBillVehicleUpdate struct {
+	ID            uuid.UUID  `json:"id" gorm:"type:uuid;primary_key;default:gen_random_uuid()"`
+	EWayBillID    uuid.UUID  `json:"eway_bill_id" gorm:"type:uuid;not null;index"`
+	VehicleNumber string     `json:"vehicle_number" gorm:"not null"`
+	TransportMode string     `json:"transport_mode" gorm:"not null"`
+	TransDocNo    string     `json:"trans_doc_no"`
+	TransDocDate  *time.Time `json:"trans_doc_date" gorm:"type:date"`
+	FromPlace     string     `json:"from_place" gorm:"not null"`
+	FromState     string     `json:"from_state" gorm:"not null"` // GST state code
+	Reason        string     `json:"reason" gorm:"not null"`     // breakdown, transshipment, first_time, others
+	Remarks       string     `json:"remarks"`
+	CreatedBy     string     `json:"created_by" gorm:"not null"`
+	CreatedAt     time.Time  `json:"created_at"`
+}
+
+// EWayBillRequest represents the request payload for creating/updating an e-way bill
+type EWayBillRequest struct {
+	BillID          uuid.UUID `json:"bill_id" binding:"required"`
+	SubSupplyType   string    `json:"sub_supply_type" binding:"omitempty,oneof=supply export job_work sales_return others"`
+	TransportMode   string    `json:"transport_mode" binding:"required,oneof=road rail air ship"`
+	Distance        int       `json:"distance" binding:"min=0,max=4000"`
+	TransporterID   string    `json:"transporter_id"`
+	TransporterName string    `json:"transporter_name"`
+	TransDocNo      string    `json:"trans_doc_no"`
+	TransDocDate    *string   `json:"trans_doc_date"`
+	VehicleNumber   string    `json:"vehicle_number"`
+	VehicleType     string    `json:"vehicle_type" binding:"omitempty,oneof=regular over_dimensional"`
+	FromPincode     string    `json:"from_pincode"` // defaults to the PIN code in the shop address
+	ToPincode       string    `json:"to_pincode"`   // defaults to the customer's postal code
+}
+
+// EWayBillGeneratedRequest records the number the portal assigned to an uploaded e-way bill
+type EWayBillGeneratedRequest struct {
+	EWayBillNumber string  `json:"eway_bill_number" binding:"required,len=12,numeric"`
+	GeneratedAt    *string `json:"generated_at"` // RFC 3339, defaults to now
+}
+
+// EWayBillVehicleRequest represents a change of vehicle for an active e-way bill
+type EWayBillVehicleRequest struct {
+	VehicleNumber string  `json:"vehicle_number" binding:"required"`
+	TransportMode string  `json:"transport_mode" binding:"omitempty,oneof=road rail air ship"`
+	TransDocNo    string  `json:"trans_doc_no"`
+	TransDocDate  *string `json:"trans_doc_date"`
+	FromPlace     string  `json:"from_place" binding:"required"`
+	FromState     string  `json:"from_state" binding:"required"` // state name or GST state code
+	Reason        string  `json:"reason" binding:"required,oneof=breakdown transshipment first_time others"`
+	Remarks       string  `json:"remarks"`
+}
+
+// EWayBillCancelRequest represents the request payload for cancelling an e-way bill
+type EWayBillCancelRequest struct {
+	Reason string `json:"reason" binding:"required,oneof=duplicate order_cancelled data_entry_mistake others"`
+}
+
+// EWayBillResponse represents the response payload for e-way bill data
+type EWayBillResponse struct {
+	ID              uuid.UUID                       `json:"id"`
+	ShopID          uuid.UUID                       `json:"shop_id"`
+	BillID          uuid.UUID                       `json:"bill_id"`
+	BillNumber      string                          `json:"bill_number"`
+	SubSupplyType   string                          `json:"sub_supply_type"`
+	TransportMode   string                          `json:"transport_mode"`
+	Distance        int                             `json:"distance"`
+	TransporterID   string                          `json:"transporter_id"`
+	TransporterName string                          `json:"transporter_name"`
+	TransDocNo      string                          `json:"trans_doc_no"`
+	TransDocDate    *time.Time                      `json:"trans_doc_date"`
+	VehicleNumber   string                          `json:"vehicle_number"`
+	VehicleType     string                          `json:"vehicle_type"`
+	FromPincode     string                          `json:"from_pincode"`
+	ToPincode       string                          `json:"to_pincode"`
+	Status          string                          `json:"status"` // draft, active, expired, cancelled
+	EWayBillNumber  string                          `json:"eway_bill_number"`
+	GeneratedAt     *time.Time                      `json:"generated_at"`
+	ValidUntil      *time.Time                      `json:"valid_until"`
+	CancelledAt     *time.Time                      `json:"cancelled_at"`
+	CancelReason    string                          `json:"cancel_reason"`
+	VehicleUpdates  []EWayBillVehicleUpdateResponse `json:"vehicle_updates"`
+	CreatedBy       string                          `json:"created_by"`
+	CreatedAt       time.Time                       `json:"created_at"`
+	UpdatedAt       time.Time                       `json:"updated_at"`
+}
+
+// EWayBillVehicleUpdateResponse represents one change of vehicle
+type EWayBillVehicleUpdateResponse struct {
+	ID            uuid.UUID  `json:"id"`
+	VehicleNumber string     `json:"vehicle_number"`
+	TransportMode string     `json:"transport_mode"`
+	TransDocNo    string     `json:"trans_doc_no"`
+	TransDocDate  *time.Time `json:"trans_doc_date"`
+	FromPlace     string     `json:"from_place"`
+	FromState     string     `json:"from_state"`
+	Reason        string     `json:"reason"`
+	Remarks       string     `json:"remarks"`
+	CreatedBy     string     `json:"created_by"`
+	CreatedAt     time.Time  `json:"created_at"`
+}
+
+// The types below follow the e-way bill portal's bulk upload JSON schema, so their field names
+// are the portal's rather than this API's usual style.
+
+// EWayBillBulkUpload represents a bulk upload file for the e-way bill portal
+type EWayBillBulkUpload struct {
+	Version   string            `json:"version"`
+	BillLists []EWayBillPayload `json:"billLists"`
+}
+
+// EWayBillPayload represents one e-way bill in the bulk upload schema
+type EWayBillPayload struct {
+	UserGstin           string                `json:"userGstin"`
+	SupplyType          string                `json:"supplyType"`
+	SubSupplyType       int                   `json:"subSupplyType"`
+	SubSupplyDesc       string                `json:"subSupplyDesc"`
+	DocType             string                `json:"docType"`
+	DocNo               string                `json:"docNo"`
+	DocDate             string                `json:"docDate"` // DD/MM/YYYY
+	TransType           int                   `json:"transType"`
+	FromGstin           string                `json:"fromGstin"`
+	FromTrdName         string                `json:"fromTrdName"`
+	FromAddr1           string                `json:"fromAddr1"`
+	FromAddr2           string                `json:"fromAddr2"`
+	FromPlace           string                `json:"fromPlace"`
+	FromPincode         int                   `json:"fromPincode"`
+	FromStateCode       int                   `json:"fromStateCode"`
+	ActualFromStateCode int                   `json:"actualFromStateCode"`
+	ToGstin             string                `json:"toGstin"`
+	ToTrdName           string                `json:"toTrdName"`
+	ToAddr1             string                `json:"toAddr1"`
+	ToAddr2             string                `json:"toAddr2"`
+	ToPlace             string                `json:"toPlace"`
+	ToPincode           int                   `json:"toPincode"`
+	ToStateCode         int                   `json:"toStateCode"`
+	ActualToStateCode   int                   `json:"actualToStateCode"`
+	TotalValue          float64               `json:"totalValue"`
+	CgstValue           float64               `json:"cgstValue"`
+	SgstValue           float64               `json:"sgstValue"`
+	IgstValue           float64               `json:"igstValue"`
+	CessValue           float64               `json:"cessValue"`
+	TotNonAdvolVal      float64               `json:"TotNonAdvolVal"`
+	OthValue            float64               `json:"OthValue"`
+	TotInvValue         float64               `json:"totInvValue"`
+	TransMode           int                   `json:"transMode"`
+	TransDistance       int                   `json:"transDistance"`
+	TransporterName     string                `json:"transporterName"`
+	TransporterID       string                `json:"transporterId"`
+	TransDocNo          string                `json:"transDocNo"`
+	TransDocDate        string                `json:"transDocDate"`
+	VehicleNo           string                `json:"vehicleNo"`
+	VehicleType         string                `json:"vehicleType"`
+	ItemList            []EWayBillPayloadItem `json:"itemList"`
+}
+
+// EWayBillPayloadItem represents one item of an e-way bill in the bulk upload schema
+type EWayBillPayloadItem struct {
+	ItemNo        int     `json:"itemNo"`
+	ProductName   string  `json:"productName"`
+	ProductDesc   string  `json:"productDesc"`
+	HsnCode       int     `json:"hsnCode"`
+	Quantity      float64 `json:"quantity"`
+	QtyUnit       string  `json:"qtyUnit"`
+	TaxableAmount float64 `json:"taxableAmount"`
+	SgstRate      float64 `json:"sgstRate"`
+	CgstRate      float64 `json:"cgstRate"`
+	IgstRate      float64 `json:"igstRate"`
+	CessRate      float64 `json:"cessRate"`
+	CessNonAdvol  float64 `json:"cessNonAdvol"`
+}
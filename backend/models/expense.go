@@ -0,0 +1,172 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// Expense represents money a shop spent, such as rent, salaries or utilities.
+// Expenses generated from a recurring expense are unique per period.
+type Expense struct {
+	ID                 uuid.UUID      `json:"id" gorm:"type:uuid;primary_key;default:gen_random_uuid()"`
+	ShopID             uuid.UUID      `json:"shop_id" gorm:"type:uuid;not null;index"`
+	Category           string         `json:"category" gorm:"not null;index"`
+	Description        string         `json:"description"`
+	Vendor             string         `json:"vendor"`
+	ExpenseDate        time.Time      `json:"expense_date" gorm:"type:date;not null;index;uniqueIndex:idx_recurring_expense_period"`
+	Amount             float64        `json:"amount" gorm:"not null"` // before tax
+	TaxAmount          float64        `json:"tax_amount" gorm:"not null;default:0"`
+	TotalAmount        float64        `json:"total_amount" gorm:"not null"`
+	PaymentMode        string         `json:"payment_mode" gorm:"not null"` // cash, card, bank_transfer, upi, check, other
+	Reference          string         `json:"reference"`
+	Notes              string         `json:"notes"`
+	RecurringExpenseID *uuid.UUID     `json:"recurring_expense_id" gorm:"type:uuid;uniqueIndex:idx_recurring_expense_period"`
+	CreatedBy          string         `json:"created_by" gorm:"not null"`
+	CreatedAt          time.Time      `json:"created_at"`
+	UpdatedAt          time.Time      `json:"updated_at"`
+	DeletedAt          gorm.DeletedAt `json:"deleted_at" gorm:"index"`
+
+	// Relationships
+	Attachments []ExpenseAttachment `json:"attachments,omitempty" gorm:"foreignKey:ExpenseID"`
+}
+
+// ExpenseAttachment represents a receipt or invoice file kept with an expense
+type ExpenseAttachment struct {
+	ID          uuid.UUID `json:"id" gorm:"type:uuid;primary_key;default:gen_random_uuid()"`
+	ExpenseID   uuid.UUID `json:"expense_id" gorm:"type:uuid;not null;index"`
+	FileName    string    `json:"file_name" gorm:"not null"`
+	ContentType string    `json:"content_type" gorm:"not null"`
+	Size        int64     `json:"size" gorm:"not null"`
+	Data        []byte    `json:"-" gorm:"not null"`
+	CreatedBy   string    `json:"created_by" gorm:"not null"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+// RecurringExpense represents an expense that is recorded automatically on a schedule
+type RecurringExpense struct {
+	ID          uuid.UUID      `json:"id" gorm:"type:uuid;primary_key;default:gen_random_uuid()"`
+	ShopID      uuid.UUID      `json:"shop_id" gorm:"type:uuid;not null;index"`
+	Category    string         `json:"category" gorm:"not null"`
+	Description string         `json:"description"`
+	Vendor      string         `json:"vendor"`
+	Amount      float64        `json:"amount" gorm:"not null"`
+	TaxAmount   float64        `json:"tax_amount" gorm:"not null;default:0"`
+	PaymentMode string         `json:"payment_mode" gorm:"not null"`
+	Frequency   string         `json:"frequency" gorm:"not null"` // weekly, monthly, quarterly, yearly
+	StartDate   time.Time      `json:"start_date" gorm:"not null"`
+	EndDate     *time.Time     `json:"end_date"`
+	Status      string         `json:"status" gorm:"not null;default:'active';index"` // active, paused, ended
+	NextRunDate *time.Time     `json:"next_run_date" gorm:"index"`
+	LastRunDate *time.Time     `json:"last_run_date"`
+	CreatedBy   string         `json:"created_by" gorm:"not null"`
+	CreatedAt   time.Time      `json:"created_at"`
+	UpdatedAt   time.Time      `json:"updated_at"`
+	DeletedAt   gorm.DeletedAt `json:"deleted_at" gorm:"index"`
+}
+
+// ExpenseRequest represents the request payload for creating/updating an expense
+type ExpenseRequest struct {
+	Category    string  `json:"category" binding:"required"`
+	Description string  `json:"description"`
+	Vendor      string  `json:"vendor"`
+	ExpenseDate string  `json:"expense_date" binding:"required"`
+	Amount      float64 `json:"amount" binding:"required,gt=0"`
+	TaxAmount   float64 `json:"tax_amount" binding:"min=0"`
+	PaymentMode string  `json:"payment_mode" binding:"required,oneof=cash card bank_transfer upi check other"`
+	Reference   string  `json:"reference"`
+	Notes       string  `json:"notes"`
+}
+
+// RecurringExpenseRequest represents the request payload for creating/updating a recurring expense
+type RecurringExpenseRequest struct {
+	Category    string  `json:"category" binding:"required"`
+	Description string  `json:"description"`
+	Vendor      string  `json:"vendor"`
+	Amount      float64 `json:"amount" binding:"required,gt=0"`
+	TaxAmount   float64 `json:"tax_amount" binding:"min=0"`
+	PaymentMode string  `json:"payment_mode" binding:"required,oneof=cash card bank_transfer upi check other"`
+	Frequency   string  `json:"frequency" binding:"required,oneof=weekly monthly quarterly yearly"`
+	StartDate   string  `json:"start_date" binding:"required"`
+	EndDate     *string `json:"end_date"`
+	Status      string  `json:"status" binding:"omitempty,oneof=active paused"`
+}
+
+// ExpenseResponse represents the response payload for expense data
+type ExpenseResponse struct {
+	ID                 uuid.UUID                   `json:"id"`
+	ShopID             uuid.UUID                   `json:"shop_id"`
+	Category           string                      `json:"category"`
+	Description        string                      `json:"description"`
+	Vendor             string                      `json:"vendor"`
+	ExpenseDate        time.Time                   `json:"expense_date"`
+	Amount             float64                     `json:"amount"`
+	TaxAmount          float64                     `json:"tax_amount"`
+	TotalAmount        float64                     `json:"total_amount"`
+	PaymentMode        string                      `json:"payment_mode"`
+	Reference          string                      `json:"reference"`
+	Notes              string                      `json:"notes"`
+	RecurringExpenseID *uuid.UUID                  `json:"recurring_expense_id"`
+	Attachments        []ExpenseAttachmentResponse `json:"attachments"`
+	CreatedBy          string                      `json:"created_by"`
+	CreatedAt          time.Time                   `json:"created_at"`
+	UpdatedAt          time.Time                   `json:"updated_at"`
+}
+
+// ExpenseAttachmentResponse represents the details of an expense attachment without its content
+type ExpenseAttachmentResponse struct {
+	ID          uuid.UUID `json:"id"`
+	FileName    string    `json:"file_name"`
+	ContentType string    `json:"content_type"`
+	Size        int64     `json:"size"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+// RecurringExpenseResponse represents the response payload for recurring expense data
+type RecurringExpenseResponse struct {
+	ID          uuid.UUID  `json:"id"`
+	ShopID      uuid.UUID  `json:"shop_id"`
+	Category    string     `json:"category"`
+	Description string     `json:"description"`
+	Vendor      string     `json:"vendor"`
+	Amount      float64    `json:"amount"`
+	TaxAmount   float64    `json:"tax_amount"`
+	PaymentMode string     `json:"payment_mode"`
+	Frequency   string     `json:"frequency"`
+	StartDate   time.Time  `json:"start_date"`
+	EndDate     *time.Time `json:"end_date"`
+	Status      string     `json:"status"`
+	NextRunDate *time.Time `json:"next_run_date"`
+	LastRunDate *time.Time `json:"last_run_date"`
+	CreatedAt   time.Time  `json:"created_at"`
+	UpdatedAt   time.Time  `json:"updated_at"`
+}
+
+// ProfitAndLossReport represents the profit and loss of a shop over a date range
+type ProfitAndLossReport struct {
+	From             time.Time              `json:"from"`
+	To               time.Time              `json:"to"`
+	BillCount        int64                  `json:"bill_count"`
+	GrossSales       float64                `json:"gross_sales"`
+	Discounts        float64                `json:"discounts"`
+	NetSales         float64                `json:"net_sales"` // excluding tax
+	TaxCollected     float64                `json:"tax_collected"`
+	CostOfGoodsSold  float64                `json:"cost_of_goods_sold"`
+	GrossProfit      float64                `json:"gross_profit"`
+	GrossMargin      float64                `json:"gross_margin"` // percent of net sales
+	Expenses         []ExpenseCategoryTotal `json:"expenses"`
+	TotalExpenses    float64                `json:"total_expenses"` // excluding tax
+	ExpenseTax       float64                `json:"expense_tax"`
+	NetProfit        float64                `json:"net_profit"`
+	NetMargin        float64                `json:"net_margin"`        // percent of net sales
+	UncostedQuantity int64                  `json:"uncosted_quantity"` // units sold without a cost price
+}
+
+// ExpenseCategoryTotal represents the expenses of one category in a report
+type ExpenseCategoryTotal struct {
+	Category  string  `json:"category"`
+	Count     int64   `json:"count"`
+	Amount    float64 `json:"amount"`
+	TaxAmount float64 `json:"tax_amount"`
+}
@@ -0,0 +1,183 @@
+package models
+
+// The GST return types below follow the JSON schema accepted by the GST offline tool, so their
+// field names are the portal's short names rather than this API's usual style.
+
+// GSTR1Return represents the outward supplies of a shop for one return period
+type GSTR1Return struct {
+	GSTIN string          `json:"gstin"`
+	Fp    string          `json:"fp"` // return period as MMYYYY
+	B2B   []GSTR1B2B      `json:"b2b"`
+	B2CL  []GSTR1B2CL     `json:"b2cl"`
+	B2CS  []GSTR1B2CS     `json:"b2cs"`
+	CDNR  []GSTR1CDNR     `json:"cdnr"`
+	CDNUR []GSTR1CDNUR    `json:"cdnur"`
+	HSN   GSTR1HSNSummary `json:"hsn"`
+}
+
+// GSTR1B2B represents the invoices issued to one registered customer
+type GSTR1B2B struct {
+	Ctin     string         `json:"ctin"` // customer GSTIN
+	Invoices []GSTR1Invoice `json:"inv"`
+}
+
+// GSTR1B2CL represents large invoices to unregistered customers in one other state
+type GSTR1B2CL struct {
+	Pos      string         `json:"pos"`
+	Invoices []GSTR1Invoice `json:"inv"`
+}
+
+// GSTR1Invoice represents one invoice in the B2B or B2CL section
+type GSTR1Invoice struct {
+	Inum   string          `json:"inum"`
+	Idt    string          `json:"idt"` // invoice date as DD-MM-YYYY
+	Val    float64         `json:"val"`
+	Pos    string          `json:"pos,omitempty"`
+	Rchrg  string          `json:"rchrg,omitempty"`
+	InvTyp string          `json:"inv_typ,omitempty"`
+	Items  []GSTR1RateItem `json:"itms"`
+}
+
+// GSTR1RateItem represents the taxable value and tax of an invoice at one tax rate
+type GSTR1RateItem struct {
+	Num    int            `json:"num"`
+	Detail GSTR1ItemTotal `json:"itm_det"`
+}
+
+// GSTR1ItemTotal represents the taxable value and the tax split at one rate. Intra-state
+// supplies carry central and state tax, inter-state supplies integrated tax.
+type GSTR1ItemTotal struct {
+	Rt    float64 `json:"rt"`
+	Txval float64 `json:"txval"`
+	Iamt  float64 `json:"iamt,omitempty"`
+	Camt  float64 `json:"camt,omitempty"`
+	Samt  float64 `json:"samt,omitempty"`
+	Csamt float64 `json:"csamt"`
+}
+
+// GSTR1B2CS represents supplies to unregistered customers summed by place of supply and rate
+type GSTR1B2CS struct {
+	SplyTy string  `json:"sply_ty"` // INTRA or INTER
+	Pos    string  `json:"pos"`
+	Typ    string  `json:"typ"`
+	Rt     float64 `json:"rt"`
+	Txval  float64 `json:"txval"`
+	Iamt   float64 `json:"iamt,omitempty"`
+	Camt   float64 `json:"camt,omitempty"`
+	Samt   float64 `json:"samt,omitempty"`
+	Csamt  float64 `json:"csamt"`
+}
+
+// GSTR1CDNR represents the credit and debit notes issued to one registered customer
+type GSTR1CDNR struct {
+	Ctin  string      `json:"ctin"`
+	Notes []GSTR1Note `json:"nt"`
+}
+
+// GSTR1CDNUR represents a credit or debit note issued to an unregistered customer
+type GSTR1CDNUR struct {
+	GSTR1Note
+	Typ string `json:"typ"`
+}
+
+// GSTR1Note represents one credit or debit note
+type GSTR1Note struct {
+	NtNum string          `json:"nt_num"`
+	NtDt  string          `json:"nt_dt"`
+	NtTy  string          `json:"ntty"` // C for credit, D for debit
+	Val   float64         `json:"val"`
+	Pos   string          `json:"pos"`
+	Items []GSTR1RateItem `json:"itms"`
+}
+
+// GSTR1HSNSummary represents the HSN-wise summary of outward supplies
+type GSTR1HSNSummary struct {
+	Data []GSTR1HSNRow `json:"data"`
+}
+
+// GSTR1HSNRow represents the supplies of one HSN code at one tax rate
+type GSTR1HSNRow struct {
+	Num   int     `json:"num"`
+	HsnSc string  `json:"hsn_sc"`
+	Desc  string  `json:"desc"`
+	Uqc   string  `json:"uqc"`
+	Qty   float64 `json:"qty"`
+	Rt    float64 `json:"rt"`
+	Val   float64 `json:"val"`
+	Txval float64 `json:"txval"`
+	Iamt  float64 `json:"iamt"`
+	Camt  float64 `json:"camt"`
+	Samt  float64 `json:"samt"`
+	Csamt float64 `json:"csamt"`
+}
+
+// GSTR3BReturn represents the summary return of a shop for one return period
+type GSTR3BReturn struct {
+	GSTIN     string             `json:"gstin"`
+	RetPeriod string             `json:"ret_period"` // MMYYYY
+	SupDetail GSTR3BSupplies     `json:"sup_details"`
+	InterSup  GSTR3BInterState   `json:"inter_sup"`
+	ITCElg    GSTR3BITC          `json:"itc_elg"`
+	InwardSup GSTR3BInwardSupply `json:"inward_sup"`
+}
+
+// GSTR3BSupplies represents table 3.1, outward supplies and inward supplies under reverse charge
+type GSTR3BSupplies struct {
+	OsupDet     GSTR3BTax `json:"osup_det"`      // (a) taxable outward supplies
+	OsupZero    GSTR3BTax `json:"osup_zero"`     // (b) zero rated supplies
+	OsupNilExmp GSTR3BTax `json:"osup_nil_exmp"` // (c) nil rated and exempted supplies
+	IsupRev     GSTR3BTax `json:"isup_rev"`      // (d) inward supplies under reverse charge
+	OsupNongst  GSTR3BTax `json:"osup_nongst"`   // (e) non-GST outward supplies
+}
+
+// GSTR3BTax represents a taxable value and the tax on it
+type GSTR3BTax struct {
+	Txval float64 `json:"txval"`
+	Iamt  float64 `json:"iamt"`
+	Camt  float64 `json:"camt"`
+	Samt  float64 `json:"samt"`
+	Csamt float64 `json:"csamt"`
+}
+
+// GSTR3BInterState represents table 3.2, inter-state supplies to unregistered persons and others
+type GSTR3BInterState struct {
+	UnregDetails []GSTR3BPlaceOfSupply `json:"unreg_details"`
+	CompDetails  []GSTR3BPlaceOfSupply `json:"comp_details"`
+	UinDetails   []GSTR3BPlaceOfSupply `json:"uin_details"`
+}
+
+// GSTR3BPlaceOfSupply represents inter-state supplies to one state
+type GSTR3BPlaceOfSupply struct {
+	Pos   string  `json:"pos"`
+	Txval float64 `json:"txval"`
+	Iamt  float64 `json:"iamt"`
+}
+
+// GSTR3BITC represents table 4, eligible input tax credit
+type GSTR3BITC struct {
+	ITCAvl   []GSTR3BITCLine `json:"itc_avl"`
+	ITCRev   []GSTR3BITCLine `json:"itc_rev"`
+	ITCNet   GSTR3BTax       `json:"itc_net"`
+	ITCInelg []GSTR3BITCLine `json:"itc_inelg"`
+}
+
+// GSTR3BITCLine represents one row of input tax credit
+type GSTR3BITCLine struct {
+	Ty    string  `json:"ty"`
+	Iamt  float64 `json:"iamt"`
+	Camt  float64 `json:"camt"`
+	Samt  float64 `json:"samt"`
+	Csamt float64 `json:"csamt"`
+}
+
+// GSTR3BInwardSupply represents table 5, exempt, nil rated and non-GST inward supplies
+type GSTR3BInwardSupply struct {
+	IsupDetails []GSTR3BInwardLine `json:"isup_details"`
+}
+
+// GSTR3BInwardLine represents inward supplies of one kind split by inter and intra state
+type GSTR3BInwardLine struct {
+	Ty    string  `json:"ty"`
+	Inter float64 `json:"inter"`
+	Intra float64 `json:"intra"`
+}
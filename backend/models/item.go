@@ -8,23 +8,26 @@ import (
 )
 
 type Item struct {
-	ID          uuid.UUID      `json:"id" gorm:"type:uuid;primary_key;default:gen_random_uuid()"`
-	ShopID      uuid.UUID      `json:"shop_id" gorm:"not null"`
-	Name        string         `json:"name" gorm:"not null"`
-	Description string         `json:"description"`
-	SKU         string         `json:"sku"`
-	Price       float64        `json:"price" gorm:"not null"`
-	CostPrice   float64        `json:"cost_price"`
-	TaxRate     float64        `json:"tax_rate" gorm:"default:0"`
-	Category    string         `json:"category"`
-	Quantity    float64        `json:"quantity" gorm:"default:0"`
-	MinQuantity float64        `json:"min_quantity" gorm:"default:0"`
-	Unit        string         `json:"unit" gorm:"default:'PCS'"`
-	Barcode     string         `json:"barcode"`
-	IsActive    bool           `json:"is_active" gorm:"default:true"`
-	CreatedAt   time.Time      `json:"created_at"`
-	UpdatedAt   time.Time      `json:"updated_at"`
-	DeletedAt   gorm.DeletedAt `json:"-" gorm:"index"`
+	ID           uuid.UUID      `json:"id" gorm:"type:uuid;primary_key;default:gen_random_uuid()"`
+	ShopID       uuid.UUID      `json:"shop_id" gorm:"not null;uniqueIndex:idx_items_shop_barcode,where:barcode <> '' AND deleted_at IS NULL"`
+	Name         string         `json:"name" gorm:"not null"`
+	Description  string         `json:"description"`
+	SKU          string         `json:"sku"`
+	Price        float64        `json:"price" gorm:"not null"`
+	CostPrice    float64        `json:"cost_price"`
+	TaxRate      float64        `json:"tax_rate" gorm:"default:0"`
+	TaxInclusive *bool          `json:"tax_inclusive"` // price includes tax; nil follows the shop setting
+	HSNCode      string         `json:"hsn_code"`      // HSN or SAC code reported in GST returns
+	Category     string         `json:"category"`
+	Quantity     float64        `json:"quantity" gorm:"default:0"`
+	MinQuantity  float64        `json:"min_quantity" gorm:"default:0"`
+	Unit         string         `json:"unit" gorm:"default:'PCS'"`
+	Barcode      string         `json:"barcode" gorm:"index;uniqueIndex:idx_items_shop_barcode"`
+	BarcodeType  string         `json:"barcode_type"` // ean13, code128
+	IsActive     bool           `json:"is_active" gorm:"default:true"`
+	CreatedAt    time.Time      `json:"created_at"`
+	UpdatedAt    time.Time      `json:"updated_at"`
+	DeletedAt    gorm.DeletedAt `json:"-" gorm:"index"`
 
 	// Relationships
 	Shop Shop `json:"shop,omitempty" gorm:"foreignKey:ShopID"`
@@ -32,39 +35,109 @@ type Item struct {
 
 // ItemRequest represents the request payload for creating/updating items
 type ItemRequest struct {
-	Name        string  `json:"name" binding:"required"`
-	Description string  `json:"description"`
-	SKU         string  `json:"sku"`
-	Price       float64 `json:"price" binding:"required"`
-	CostPrice   float64 `json:"cost_price"`
-	TaxRate     float64 `json:"tax_rate"`
-	Category    string  `json:"category"`
-	Quantity    float64 `json:"quantity"`
-	MinQuantity float64 `json:"min_quantity"`
-	Unit        string  `json:"unit"`
-	Barcode     string  `json:"barcode"`
-	IsActive    bool    `json:"is_active"`
+	Name         string  `json:"name" binding:"required"`
+	Description  string  `json:"description"`
+	SKU          string  `json:"sku"`
+	Price        float64 `json:"price" binding:"required"`
+	CostPrice    float64 `json:"cost_price"`
+	TaxRate      float64 `json:"tax_rate"`
+	TaxInclusive *bool   `json:"tax_inclusive"`
+	HSNCode      string  `json:"hsn_code"`
+	Category     string  `json:"category"`
+	Quantity     float64 `json:"quantity"`
+	MinQuantity  float64 `json:"min_quantity"`
+	Unit         string  `json:"unit"`
+	Barcode      string  `json:"barcode"`
+	BarcodeType  string  `json:"barcode_type"`
+	IsActive     bool    `json:"is_active"`
 }
 
 // ItemResponse represents the response payload for items
 type ItemResponse struct {
-	ID          uuid.UUID `json:"id"`
-	ShopID      uuid.UUID `json:"shop_id"`
-	Name        string    `json:"name"`
-	Description string    `json:"description"`
-	SKU         string    `json:"sku"`
-	Price       float64   `json:"price"`
-	CostPrice   float64   `json:"cost_price"`
-	TaxRate     float64   `json:"tax_rate"`
-	Category    string    `json:"category"`
-	Quantity    float64   `json:"quantity"`
-	MinQuantity float64   `json:"min_quantity"`
-	Unit        string    `json:"unit"`
-	Barcode     string    `json:"barcode"`
-	IsActive    bool      `json:"is_active"`
-	IsLowStock  bool      `json:"is_low_stock"`
-	CreatedAt   time.Time `json:"created_at"`
-	UpdatedAt   time.Time `json:"updated_at"`
+	ID           uuid.UUID `json:"id"`
+	ShopID       uuid.UUID `json:"shop_id"`
+	Name         string    `json:"name"`
+	Description  string    `json:"description"`
+	SKU          string    `json:"sku"`
+	Price        float64   `json:"price"`
+	CostPrice    float64   `json:"cost_price"`
+	TaxRate      float64   `json:"tax_rate"`
+	TaxInclusive *bool     `json:"tax_inclusive"`
+	HSNCode      string    `json:"hsn_code"`
+	Category     string    `json:"category"`
+	Quantity     float64   `json:"quantity"`
+	MinQuantity  float64   `json:"min_quantity"`
+	Unit         string    `json:"unit"`
+	Barcode      string    `json:"barcode"`
+	BarcodeType  string    `json:"barcode_type"`
+	IsActive     bool      `json:"is_active"`
+	IsLowStock   bool      `json:"is_low_stock"`
+	CreatedAt    time.Time `json:"created_at"`
+	UpdatedAt    time.Time `json:"updated_at"`
+}
+
+// ItemBarcode represents an additional barcode for an item, such as a variant
+// or a pack sold in an alternate unit
+type ItemBarcode struct {
+	ID           uuid.UUID `json:"id" gorm:"type:uuid;primary_key;default:gen_random_uuid()"`
+	ShopID       uuid.UUID `json:"shop_id" gorm:"type:uuid;not null;index;uniqueIndex:idx_item_barcodes_shop_barcode"`
+	ItemID       uuid.UUID `json:"item_id" gorm:"type:uuid;not null;index"`
+	Barcode      string    `json:"barcode" gorm:"not null;index;uniqueIndex:idx_item_barcodes_shop_barcode"`
+	BarcodeType  string    `json:"barcode_type"`
+	Variant      string    `json:"variant"`
+	Unit         string    `json:"unit"`
+	UnitQuantity int       `json:"unit_quantity" gorm:"not null;default:1"` // base units per scan
+	Price        float64   `json:"price"`                                   // price per scan, 0 uses the item price
+	CreatedAt    time.Time `json:"created_at"`
+	UpdatedAt    time.Time `json:"updated_at"`
+
+	// Relationships
+	Item Item `json:"item,omitempty" gorm:"foreignKey:ItemID"`
+}
+
+// ItemBarcodeRequest represents the request payload for adding an item barcode
+type ItemBarcodeRequest struct {
+	Barcode      string  `json:"barcode" binding:"required"`
+	BarcodeType  string  `json:"barcode_type"`
+	Variant      string  `json:"variant"`
+	Unit         string  `json:"unit"`
+	UnitQuantity int     `json:"unit_quantity"`
+	Price        float64 `json:"price"`
+}
+
+// ItemBarcodeResponse represents the response payload for item barcodes
+type ItemBarcodeResponse struct {
+	ID           uuid.UUID `json:"id"`
+	ItemID       uuid.UUID `json:"item_id"`
+	Barcode      string    `json:"barcode"`
+	BarcodeType  string    `json:"barcode_type"`
+	Variant      string    `json:"variant"`
+	Unit         string    `json:"unit"`
+	UnitQuantity int       `json:"unit_quantity"`
+	Price        float64   `json:"price"`
+	CreatedAt    time.Time `json:"created_at"`
+	UpdatedAt    time.Time `json:"updated_at"`
+}
+
+// ItemLookupResponse represents the result of an exact barcode or SKU lookup
+type ItemLookupResponse struct {
+	Item         ItemResponse `json:"item"`
+	Code         string       `json:"code"`
+	MatchedBy    string       `json:"matched_by"` // barcode, sku, alternate_barcode
+	BarcodeID    *uuid.UUID   `json:"barcode_id,omitempty"`
+	Variant      string       `json:"variant"`
+	Unit         string       `json:"unit"`
+	UnitQuantity int          `json:"unit_quantity"`
+	UnitPrice    float64      `json:"unit_price"` // price per base unit
+	ScanPrice    float64      `json:"scan_price"` // price per scan
+}
+
+// LabelRequest represents the request payload for printing item labels
+type LabelRequest struct {
+	ItemIDs   []uuid.UUID `json:"item_ids" binding:"required"`
+	Copies    int         `json:"copies" binding:"min=0,max=1000"` // per item, defaults to 1
+	LabelSize string      `json:"label_size"`
+	Format    string      `json:"format"` // pdf, png
 }
 
 type AuditLog struct {
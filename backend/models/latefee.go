@@ -0,0 +1,139 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// LateFeePolicy says what a shop charges on bills paid after their due date: a flat fee once
+// the grace period is over, or simple interest on the overdue amount. A policy for a customer
+// replaces the shop's policy for that customer's bills.
+type LateFeePolicy struct {
+	ID            uuid.UUID  `json:"id" gorm:"type:uuid;primary_key;default:gen_random_uuid()"`
+	ShopID        uuid.UUID  `json:"shop_id" gorm:"type:uuid;not null;index"`
+	CustomerID    *uuid.UUID `json:"customer_id" gorm:"type:uuid;index"` // empty for the shop's policy
+	Name          string     `json:"name" gorm:"not null"`
+	FeeType       string     `json:"fee_type" gorm:"not null"`              // flat, interest
+	FlatAmount    float64    `json:"flat_amount" gorm:"not null;default:0"` // charged once per bill
+	AnnualRate    float64    `json:"annual_rate" gorm:"not null;default:0"` // percent per annum, charged per day
+	GraceDays     int        `json:"grace_days" gorm:"not null;default:0"`
+	MaxAmount     float64    `json:"max_amount" gorm:"not null;default:0"`  // cap on the fees of a bill, 0 for none
+	MaxPercent    float64    `json:"max_percent" gorm:"not null;default:0"` // cap as a percent of the bill total, 0 for none
+	EffectiveFrom time.Time  `json:"effective_from" gorm:"type:date;not null"`
+	IsActive      bool       `json:"is_active" gorm:"not null;default:true"`
+	CreatedBy     string     `json:"created_by" gorm:"not null"`
+	CreatedAt     time.Time  `json:"created_at"`
+	UpdatedAt     time.Time  `json:"updated_at"`
+
+	// Relationships
+	Customer *Customer `json:"customer,omitempty" gorm:"foreignKey:CustomerID"`
+}
+
+// LateFeeCharge is a debit note raising a bill's balance by a late fee. It keeps the policy
+// terms and the figures it was worked out from, and the unique index on the bill, type and
+// period start means a period is never charged twice, even by concurrent runs.
+type LateFeeCharge struct {
+	ID          uuid.UUID  `json:"id" gorm:"type:uuid;primary_key;default:gen_random_uuid()"`
+	ShopID      uuid.UUID  `json:"shop_id" gorm:"type:uuid;not null;index"`
+	BillID      uuid.UUID  `json:"bill_id" gorm:"type:uuid;not null;uniqueIndex:idx_late_fee_charge_period"`
+	CustomerID  *uuid.UUID `json:"customer_id" gorm:"type:uuid;index"`
+	PolicyID    uuid.UUID  `json:"policy_id" gorm:"type:uuid;not null;index"`
+	NoteNumber  string     `json:"note_number" gorm:"not null;unique"`
+	FeeType     string     `json:"fee_type" gorm:"not null;uniqueIndex:idx_late_fee_charge_period"` // flat, interest
+	ChargeDate  time.Time  `json:"charge_date" gorm:"type:date;not null;index"`
+	PeriodStart time.Time  `json:"period_start" gorm:"type:date;not null;uniqueIndex:idx_late_fee_charge_period"`
+	PeriodEnd   time.Time  `json:"period_end" gorm:"type:date;not null"`
+	Days        int        `json:"days" gorm:"not null;default:0"`
+	BaseAmount  float64    `json:"base_amount" gorm:"not null;default:0"` // average overdue amount over the period
+	Rate        float64    `json:"rate" gorm:"not null;default:0"`        // annual rate for interest
+	Amount      float64    `json:"amount" gorm:"not null"`
+	Capped      bool       `json:"capped" gorm:"not null;default:false"`          // cut down to the policy's cap
+	Status      string     `json:"status" gorm:"not null;default:'posted';index"` // posted, waived
+	WaiveReason string     `json:"waive_reason"`
+	WaivedBy    string     `json:"waived_by"`
+	WaivedAt    *time.Time `json:"waived_at"`
+	CreatedBy   string     `json:"created_by" gorm:"not null"`
+	CreatedAt   time.Time  `json:"created_at"`
+	UpdatedAt   time.Time  `json:"updated_at"`
+
+	// Relationships
+	Bill Bill `json:"bill,omitempty" gorm:"foreignKey:BillID"`
+}
+
+// LateFeePolicyRequest represents the request payload for creating/updating a late fee policy
+type LateFeePolicyRequest struct {
+	CustomerID    *uuid.UUID `json:"customer_id"`
+	Name          string     `json:"name" binding:"required"`
+	FeeType       string     `json:"fee_type" binding:"required,oneof=flat interest"`
+	FlatAmount    float64    `json:"flat_amount" binding:"min=0"`
+	AnnualRate    float64    `json:"annual_rate" binding:"min=0,max=100"`
+	GraceDays     int        `json:"grace_days" binding:"min=0,max=365"`
+	MaxAmount     float64    `json:"max_amount" binding:"min=0"`
+	MaxPercent    float64    `json:"max_percent" binding:"min=0,max=100"`
+	EffectiveFrom string     `json:"effective_from"` // defaults to today
+	IsActive      *bool      `json:"is_active"`      // defaults to true
+}
+
+// LateFeePolicyResponse represents the response payload for a late fee policy
+type LateFeePolicyResponse struct {
+	ID            uuid.UUID  `json:"id"`
+	CustomerID    *uuid.UUID `json:"customer_id"`
+	CustomerName  string     `json:"customer_name,omitempty"`
+	Name          string     `json:"name"`
+	FeeType       string     `json:"fee_type"`
+	FlatAmount    float64    `json:"flat_amount"`
+	AnnualRate    float64    `json:"annual_rate"`
+	GraceDays     int        `json:"grace_days"`
+	MaxAmount     float64    `json:"max_amount"`
+	MaxPercent    float64    `json:"max_percent"`
+	EffectiveFrom time.Time  `json:"effective_from"`
+	IsActive      bool       `json:"is_active"`
+	CreatedBy     string     `json:"created_by"`
+	CreatedAt     time.Time  `json:"created_at"`
+	UpdatedAt     time.Time  `json:"updated_at"`
+}
+
+// LateFeeRunRequest represents the request payload for charging late fees now. Interest is
+// charged up to the end of the last month unless another date is given.
+type LateFeeRunRequest struct {
+	Through string `json:"through"`
+}
+
+// WaiveLateFeeRequest represents the request payload for waiving a late fee
+type WaiveLateFeeRequest struct {
+	Reason string `json:"reason" binding:"required"`
+}
+
+// LateFeeChargeResponse represents the response payload for a late fee charged on a bill
+type LateFeeChargeResponse struct {
+	ID          uuid.UUID  `json:"id"`
+	BillID      uuid.UUID  `json:"bill_id"`
+	BillNumber  string     `json:"bill_number,omitempty"`
+	CustomerID  *uuid.UUID `json:"customer_id"`
+	PolicyID    uuid.UUID  `json:"policy_id"`
+	NoteNumber  string     `json:"note_number"`
+	FeeType     string     `json:"fee_type"`
+	ChargeDate  time.Time  `json:"charge_date"`
+	PeriodStart time.Time  `json:"period_start"`
+	PeriodEnd   time.Time  `json:"period_end"`
+	Days        int        `json:"days"`
+	BaseAmount  float64    `json:"base_amount"`
+	Rate        float64    `json:"rate"`
+	Amount      float64    `json:"amount"`
+	Capped      bool       `json:"capped"`
+	Status      string     `json:"status"`
+	WaiveReason string     `json:"waive_reason"`
+	WaivedBy    string     `json:"waived_by"`
+	WaivedAt    *time.Time `json:"waived_at"`
+	CreatedBy   string     `json:"created_by"`
+	CreatedAt   time.Time  `json:"created_at"`
+}
+
+// LateFeeRunResult summarizes a run of the late fee job
+type LateFeeRunResult struct {
+	Through      time.Time `json:"through"`
+	BillsChecked int       `json:"bills_checked"`
+	Charged      int       `json:"charged"`
+	TotalAmount  float64   `json:"total_amount"`
+}
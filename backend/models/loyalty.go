@@ -0,0 +1,124 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// LoyaltyProgram represents a shop's rules for earning and redeeming loyalty points
+type LoyaltyProgram struct {
+	ID              uuid.UUID `json:"id" gorm:"type:uuid;primary_key;default:gen_random_uuid()"`
+	ShopID          uuid.UUID `json:"shop_id" gorm:"type:uuid;not null;uniqueIndex"`
+	IsActive        bool      `json:"is_active" gorm:"default:true"`
+	PointsPerUnit   float64   `json:"points_per_unit" gorm:"not null;default:0"`   // points earned per currency unit paid
+	PointValue      float64   `json:"point_value" gorm:"not null;default:0"`       // currency value of one point when redeemed
+	MinRedeemPoints int       `json:"min_redeem_points" gorm:"not null;default:0"` // smallest number of points that can be redeemed at once
+	ExpiryDays      int       `json:"expiry_days" gorm:"not null;default:0"`       // 0 means points never expire
+	CreatedAt       time.Time `json:"created_at"`
+	UpdatedAt       time.Time `json:"updated_at"`
+
+	// Relationships
+	Tiers []LoyaltyTier `json:"tiers,omitempty" gorm:"foreignKey:ProgramID"`
+}
+
+// LoyaltyTier represents a customer tier reached by lifetime points that earns points faster
+type LoyaltyTier struct {
+	ID         uuid.UUID `json:"id" gorm:"type:uuid;primary_key;default:gen_random_uuid()"`
+	ProgramID  uuid.UUID `json:"program_id" gorm:"type:uuid;not null;index"`
+	Name       string    `json:"name" gorm:"not null"`
+	MinPoints  int       `json:"min_points" gorm:"not null;default:0"`
+	Multiplier float64   `json:"multiplier" gorm:"not null;default:1"`
+	CreatedAt  time.Time `json:"created_at"`
+	UpdatedAt  time.Time `json:"updated_at"`
+}
+
+// LoyaltyTransaction represents an entry in a customer's points ledger. Earned and restored points
+// are kept as lots whose Remaining points are used up oldest first and expire at ExpiresAt.
+type LoyaltyTransaction struct {
+	ID          uuid.UUID  `json:"id" gorm:"type:uuid;primary_key;default:gen_random_uuid()"`
+	ShopID      uuid.UUID  `json:"shop_id" gorm:"type:uuid;not null;index"`
+	CustomerID  uuid.UUID  `json:"customer_id" gorm:"type:uuid;not null;index"`
+	BillID      *uuid.UUID `json:"bill_id" gorm:"type:uuid;index"`
+	PaymentID   *uuid.UUID `json:"payment_id" gorm:"type:uuid"`
+	Type        string     `json:"type" gorm:"not null"`   // earn, redeem, expire, reverse, adjust
+	Points      int        `json:"points" gorm:"not null"` // positive when added, negative when taken away
+	Remaining   int        `json:"remaining" gorm:"not null;default:0"`
+	ExpiresAt   *time.Time `json:"expires_at"`
+	Description string     `json:"description"`
+	CreatedBy   string     `json:"created_by" gorm:"not null"`
+	CreatedAt   time.Time  `json:"created_at"`
+}
+
+// LoyaltyProgramRequest represents the request payload for configuring a loyalty program
+type LoyaltyProgramRequest struct {
+	IsActive        *bool                `json:"is_active"`
+	PointsPerUnit   float64              `json:"points_per_unit" binding:"min=0"`
+	PointValue      float64              `json:"point_value" binding:"min=0"`
+	MinRedeemPoints int                  `json:"min_redeem_points" binding:"min=0"`
+	ExpiryDays      int                  `json:"expiry_days" binding:"min=0"`
+	Tiers           []LoyaltyTierRequest `json:"tiers" binding:"dive"`
+}
+
+// LoyaltyTierRequest represents one tier in a loyalty program request
+type LoyaltyTierRequest struct {
+	Name       string  `json:"name" binding:"required"`
+	MinPoints  int     `json:"min_points" binding:"min=0"`
+	Multiplier float64 `json:"multiplier" binding:"min=0"`
+}
+
+// LoyaltyAdjustmentRequest represents a manual correction to a customer's points
+type LoyaltyAdjustmentRequest struct {
+	Points      int    `json:"points" binding:"required"`
+	Description string `json:"description" binding:"required"`
+}
+
+// LoyaltyProgramResponse represents the response payload for loyalty program data
+type LoyaltyProgramResponse struct {
+	ID              uuid.UUID             `json:"id"`
+	ShopID          uuid.UUID             `json:"shop_id"`
+	IsActive        bool                  `json:"is_active"`
+	PointsPerUnit   float64               `json:"points_per_unit"`
+	PointValue      float64               `json:"point_value"`
+	MinRedeemPoints int                   `json:"min_redeem_points"`
+	ExpiryDays      int                   `json:"expiry_days"`
+	Tiers           []LoyaltyTierResponse `json:"tiers"`
+	CreatedAt       time.Time             `json:"created_at"`
+	UpdatedAt       time.Time             `json:"updated_at"`
+}
+
+// LoyaltyTierResponse represents one tier of a loyalty program
+type LoyaltyTierResponse struct {
+	ID         uuid.UUID `json:"id"`
+	Name       string    `json:"name"`
+	MinPoints  int       `json:"min_points"`
+	Multiplier float64   `json:"multiplier"`
+}
+
+// LoyaltyTransactionResponse represents an entry in a customer's points ledger
+type LoyaltyTransactionResponse struct {
+	ID          uuid.UUID  `json:"id"`
+	BillID      *uuid.UUID `json:"bill_id"`
+	PaymentID   *uuid.UUID `json:"payment_id"`
+	Type        string     `json:"type"`
+	Points      int        `json:"points"`
+	Remaining   int        `json:"remaining"`
+	ExpiresAt   *time.Time `json:"expires_at"`
+	Description string     `json:"description"`
+	CreatedBy   string     `json:"created_by"`
+	CreatedAt   time.Time  `json:"created_at"`
+}
+
+// CustomerLoyaltyResponse represents a customer's points balance, tier and ledger
+type CustomerLoyaltyResponse struct {
+	CustomerID     uuid.UUID                    `json:"customer_id"`
+	Points         int                          `json:"points"`
+	LifetimePoints int                          `json:"lifetime_points"`
+	PointsValue    float64                      `json:"points_value"`
+	Tier           string                       `json:"tier,omitempty"`
+	NextTier       string                       `json:"next_tier,omitempty"`
+	PointsToNext   int                          `json:"points_to_next_tier,omitempty"`
+	ExpiringPoints int                          `json:"expiring_points"` // points expiring in the next 30 days
+	NextExpiryDate *time.Time                   `json:"next_expiry_date"`
+	Transactions   []LoyaltyTransactionResponse `json:"transactions"`
+}
@@ -0,0 +1,119 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// NotificationTemplate overrides the built-in text message a shop sends a customer by SMS or
+// WhatsApp for an event, and says whether it is sent automatically when the event happens
+type NotificationTemplate struct {
+	ID               uuid.UUID  `json:"id" gorm:"type:uuid;primary_key;default:gen_random_uuid()"`
+	ShopID           uuid.UUID  `json:"shop_id" gorm:"type:uuid;not null;uniqueIndex:idx_notification_template_event"`
+	Channel          string     `json:"channel" gorm:"not null;uniqueIndex:idx_notification_template_event"` // sms, whatsapp
+	Event            string     `json:"event" gorm:"not null;uniqueIndex:idx_notification_template_event"`   // bill_issued, payment_received, reminder
+	Body             string     `json:"body" gorm:"type:text;not null"`                                      // Go text template
+	ProviderTemplate string     `json:"provider_template"`                                                   // DLT template ID for SMS, approved template name for WhatsApp
+	Language         string     `json:"language"`                                                            // WhatsApp template language code
+	Params           string     `json:"params" gorm:"type:text"`                                             // WhatsApp template parameters, one Go text template per line
+	AutoSend         bool       `json:"auto_send" gorm:"not null;default:false"`
+	AutoSendSince    *time.Time `json:"auto_send_since"` // events before automatic sending was turned on are left alone
+	CreatedAt        time.Time  `json:"created_at"`
+	UpdatedAt        time.Time  `json:"updated_at"`
+}
+
+// NotificationDelivery records an SMS or WhatsApp message sent, or being sent, by a shop.
+// Messages that fail for a reason that may pass are retried with a backoff, and the provider's
+// delivery reports move a sent message on to delivered or read.
+type NotificationDelivery struct {
+	ID                uuid.UUID  `json:"id" gorm:"type:uuid;primary_key;default:gen_random_uuid()"`
+	ShopID            uuid.UUID  `json:"shop_id" gorm:"type:uuid;not null;index"`
+	BillID            *uuid.UUID `json:"bill_id" gorm:"type:uuid;index"`
+	PaymentID         *uuid.UUID `json:"payment_id" gorm:"type:uuid"`
+	CustomerID        *uuid.UUID `json:"customer_id" gorm:"type:uuid;index"`
+	AutoKey           *string    `json:"-" gorm:"uniqueIndex"`    // set for automatic messages, so an event is only notified once per channel
+	Event             string     `json:"event" gorm:"not null"`   // bill_issued, payment_received, reminder, test
+	Channel           string     `json:"channel" gorm:"not null"` // sms, whatsapp
+	Provider          string     `json:"provider" gorm:"not null;uniqueIndex:idx_notification_provider_message"`
+	Recipient         string     `json:"recipient" gorm:"not null"`
+	Body              string     `json:"body" gorm:"type:text;not null"`
+	ProviderTemplate  string     `json:"provider_template"`
+	Language          string     `json:"language"`
+	Params            string     `json:"params" gorm:"type:text"`                       // rendered WhatsApp template parameters, one per line
+	Status            string     `json:"status" gorm:"not null;default:'queued';index"` // queued, sent, delivered, read, failed
+	Attempts          int        `json:"attempts" gorm:"not null;default:0"`
+	NextAttemptAt     *time.Time `json:"next_attempt_at" gorm:"index"`
+	LastError         string     `json:"last_error"`
+	ProviderMessageID *string    `json:"provider_message_id" gorm:"uniqueIndex:idx_notification_provider_message"`
+	SentAt            *time.Time `json:"sent_at"`
+	DeliveredAt       *time.Time `json:"delivered_at"`
+	ReadAt            *time.Time `json:"read_at"`
+	CreatedBy         string     `json:"created_by" gorm:"not null"`
+	CreatedAt         time.Time  `json:"created_at"`
+	UpdatedAt         time.Time  `json:"updated_at"`
+}
+
+// NotificationTemplateRequest represents the request payload for customizing a notification
+// template
+type NotificationTemplateRequest struct {
+	Body             string `json:"body" binding:"required"`
+	ProviderTemplate string `json:"provider_template"`
+	Language         string `json:"language"`
+	Params           string `json:"params"`
+	AutoSend         *bool  `json:"auto_send"` // omitted to keep the current setting
+}
+
+// NotificationTemplateResponse represents the response payload for a notification template
+type NotificationTemplateResponse struct {
+	Channel          string     `json:"channel"`
+	Event            string     `json:"event"`
+	Body             string     `json:"body"`
+	ProviderTemplate string     `json:"provider_template"`
+	Language         string     `json:"language"`
+	Params           string     `json:"params"`
+	AutoSend         bool       `json:"auto_send"`
+	AutoSendSince    *time.Time `json:"auto_send_since"`
+	IsDefault        bool       `json:"is_default"` // the shop has not customized it
+	Provider         string     `json:"provider"`   // provider the channel sends through
+}
+
+// SendBillNotificationRequest represents the request payload for messaging a customer about a
+// bill. The message defaults to the shop's template for the event.
+type SendBillNotificationRequest struct {
+	Channel string `json:"channel" binding:"required,oneof=sms whatsapp"`
+	Event   string `json:"event" binding:"required,oneof=bill_issued payment_received reminder"`
+	Phone   string `json:"phone"` // defaults to the customer's phone
+	Message string `json:"message"`
+}
+
+// TestNotificationRequest represents the request payload for checking a notification channel
+type TestNotificationRequest struct {
+	Channel string `json:"channel" binding:"required,oneof=sms whatsapp"`
+	Phone   string `json:"phone" binding:"required"`
+}
+
+// NotificationDeliveryResponse represents the response payload for a sent SMS or WhatsApp
+// message
+type NotificationDeliveryResponse struct {
+	ID                uuid.UUID  `json:"id"`
+	BillID            *uuid.UUID `json:"bill_id"`
+	PaymentID         *uuid.UUID `json:"payment_id"`
+	CustomerID        *uuid.UUID `json:"customer_id"`
+	Event             string     `json:"event"`
+	Channel           string     `json:"channel"`
+	Provider          string     `json:"provider"`
+	Recipient         string     `json:"recipient"`
+	Body              string     `json:"body"`
+	Automatic         bool       `json:"automatic"`
+	Status            string     `json:"status"`
+	Attempts          int        `json:"attempts"`
+	NextAttemptAt     *time.Time `json:"next_attempt_at"`
+	LastError         string     `json:"last_error"`
+	ProviderMessageID *string    `json:"provider_message_id"`
+	SentAt            *time.Time `json:"sent_at"`
+	DeliveredAt       *time.Time `json:"delivered_at"`
+	ReadAt            *time.Time `json:"read_at"`
+	CreatedBy         string     `json:"created_by"`
+	CreatedAt         time.Time  `json:"created_at"`
+}
@@ -0,0 +1,61 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// PaymentLink represents a hosted payment page a customer can use to pay a bill's balance online
+type PaymentLink struct {
+	ID                uuid.UUID  `json:"id" gorm:"type:uuid;primary_key;default:gen_random_uuid()"`
+	ShopID            uuid.UUID  `json:"shop_id" gorm:"type:uuid;not null;index"`
+	BillID            uuid.UUID  `json:"bill_id" gorm:"type:uuid;not null;index"`
+	Provider          string     `json:"provider" gorm:"not null;uniqueIndex:idx_payment_link_provider"`
+	ProviderLinkID    string     `json:"provider_link_id" gorm:"not null;uniqueIndex:idx_payment_link_provider"`
+	URL               string     `json:"url" gorm:"not null"`
+	Amount            float64    `json:"amount" gorm:"not null"`
+	Status            string     `json:"status" gorm:"not null;default:'created';index"` // created, paid, expired, cancelled
+	ExpiresAt         *time.Time `json:"expires_at"`
+	PaidAt            *time.Time `json:"paid_at"`
+	ProviderPaymentID string     `json:"provider_payment_id"`
+	PaymentID         *uuid.UUID `json:"payment_id" gorm:"type:uuid"`
+	CreatedBy         string     `json:"created_by" gorm:"not null"`
+	CreatedAt         time.Time  `json:"created_at"`
+	UpdatedAt         time.Time  `json:"updated_at"`
+}
+
+// PaymentWebhookEvent records a webhook event received from a payment provider, so events the
+// provider delivers more than once are only acted on once
+type PaymentWebhookEvent struct {
+	ID             uuid.UUID `json:"id" gorm:"type:uuid;primary_key;default:gen_random_uuid()"`
+	Provider       string    `json:"provider" gorm:"not null;uniqueIndex:idx_payment_webhook_event"`
+	EventID        string    `json:"event_id" gorm:"not null;uniqueIndex:idx_payment_webhook_event"`
+	EventType      string    `json:"event_type" gorm:"not null"`
+	ProviderLinkID string    `json:"provider_link_id" gorm:"index"`
+	Status         string    `json:"status" gorm:"not null;default:'received'"` // received, processed, ignored, failed
+	Error          string    `json:"error"`
+	CreatedAt      time.Time `json:"created_at"`
+	UpdatedAt      time.Time `json:"updated_at"`
+}
+
+// PaymentLinkRequest represents the request payload for creating a payment link
+type PaymentLinkRequest struct {
+	ExpiresInHours int `json:"expires_in_hours" binding:"omitempty,min=1,max=2160"` // defaults to 7 days
+}
+
+// PaymentLinkResponse represents the response payload for a payment link
+type PaymentLinkResponse struct {
+	ID                uuid.UUID  `json:"id"`
+	BillID            uuid.UUID  `json:"bill_id"`
+	Provider          string     `json:"provider"`
+	URL               string     `json:"url"`
+	Amount            float64    `json:"amount"`
+	Status            string     `json:"status"`
+	ExpiresAt         *time.Time `json:"expires_at"`
+	PaidAt            *time.Time `json:"paid_at"`
+	ProviderPaymentID string     `json:"provider_payment_id"`
+	PaymentID         *uuid.UUID `json:"payment_id"`
+	CreatedBy         string     `json:"created_by"`
+	CreatedAt         time.Time  `json:"created_at"`
+}
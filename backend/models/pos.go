@@ -0,0 +1,35 @@
+package models
+
+import "github.com/google/uuid"
+
+// POSSaleRequest represents the request payload for a point-of-sale quick sale
+type POSSaleRequest struct {
+	CustomerID  *uuid.UUID       `json:"customer_id"`
+	Scans       []POSScanRequest `json:"scans" binding:"required,min=1,dive"`
+	Discount    float64          `json:"discount"`
+	TaxRate     float64          `json:"tax_rate"`
+	Notes       string           `json:"notes"`
+	CouponCodes []string         `json:"coupon_codes"`
+	Tender      POSTenderRequest `json:"tender" binding:"required"`
+}
+
+// POSScanRequest represents a scanned barcode or SKU
+type POSScanRequest struct {
+	Code     string `json:"code" binding:"required"`
+	Quantity int    `json:"quantity"`
+}
+
+// POSTenderRequest represents the tender handed over by the customer
+type POSTenderRequest struct {
+	Method    string  `json:"method" binding:"required"` // cash, card, upi, bank_transfer, other
+	Amount    float64 `json:"amount" binding:"min=0"`
+	Reference string  `json:"reference"`
+}
+
+// POSSaleResponse represents the result of a quick sale
+type POSSaleResponse struct {
+	Bill           BillResponse    `json:"bill"`
+	Payment        PaymentResponse `json:"payment"`
+	AmountTendered float64         `json:"amount_tendered"`
+	ChangeDue      float64         `json:"change_due"`
+}
@@ -0,0 +1,158 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// CustomerGroup represents a class of customers, such as retail or wholesale, that share a price list
+type CustomerGroup struct {
+	ID          uuid.UUID      `json:"id" gorm:"type:uuid;primary_key;default:gen_random_uuid()"`
+	ShopID      uuid.UUID      `json:"shop_id" gorm:"type:uuid;not null;index"`
+	Name        string         `json:"name" gorm:"not null"`
+	Description string         `json:"description"`
+	PriceListID *uuid.UUID     `json:"price_list_id" gorm:"type:uuid"`
+	IsActive    bool           `json:"is_active" gorm:"default:true"`
+	CreatedAt   time.Time      `json:"created_at"`
+	UpdatedAt   time.Time      `json:"updated_at"`
+	DeletedAt   gorm.DeletedAt `json:"-" gorm:"index"`
+
+	// Relationships
+	PriceList *PriceList `json:"price_list,omitempty" gorm:"foreignKey:PriceListID"`
+}
+
+// PriceList represents a set of item prices that override the catalogue price
+type PriceList struct {
+	ID              uuid.UUID      `json:"id" gorm:"type:uuid;primary_key;default:gen_random_uuid()"`
+	ShopID          uuid.UUID      `json:"shop_id" gorm:"type:uuid;not null;index"`
+	Name            string         `json:"name" gorm:"not null"`
+	Description     string         `json:"description"`
+	DefaultDiscount float64        `json:"default_discount" gorm:"not null;default:0"` // percent off items not on the list
+	StartsAt        *time.Time     `json:"starts_at"`
+	EndsAt          *time.Time     `json:"ends_at"`
+	IsActive        bool           `json:"is_active" gorm:"default:true"`
+	CreatedAt       time.Time      `json:"created_at"`
+	UpdatedAt       time.Time      `json:"updated_at"`
+	DeletedAt       gorm.DeletedAt `json:"-" gorm:"index"`
+
+	// Relationships
+	Items []PriceListItem `json:"items,omitempty" gorm:"foreignKey:PriceListID"`
+}
+
+// PriceListItem represents the price of an item on a price list from a minimum quantity upwards
+type PriceListItem struct {
+	ID          uuid.UUID `json:"id" gorm:"type:uuid;primary_key;default:gen_random_uuid()"`
+	PriceListID uuid.UUID `json:"price_list_id" gorm:"type:uuid;not null;index"`
+	ItemID      uuid.UUID `json:"item_id" gorm:"type:uuid;not null;index"`
+	MinQuantity int       `json:"min_quantity" gorm:"not null;default:1"`
+	PriceType   string    `json:"price_type" gorm:"not null"` // fixed, percentage
+	Value       float64   `json:"value" gorm:"not null"`      // unit price, or percent off the item price
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+
+	// Relationships
+	Item Item `json:"item,omitempty" gorm:"foreignKey:ItemID"`
+}
+
+// CustomerGroupRequest represents the request payload for creating/updating a customer group
+type CustomerGroupRequest struct {
+	Name        string     `json:"name" binding:"required"`
+	Description string     `json:"description"`
+	PriceListID *uuid.UUID `json:"price_list_id"`
+	IsActive    *bool      `json:"is_active"`
+}
+
+// CustomerGroupResponse represents the response payload for customer group data
+type CustomerGroupResponse struct {
+	ID            uuid.UUID  `json:"id"`
+	ShopID        uuid.UUID  `json:"shop_id"`
+	Name          string     `json:"name"`
+	Description   string     `json:"description"`
+	PriceListID   *uuid.UUID `json:"price_list_id"`
+	PriceListName string     `json:"price_list_name,omitempty"`
+	CustomerCount int64      `json:"customer_count"`
+	IsActive      bool       `json:"is_active"`
+	CreatedAt     time.Time  `json:"created_at"`
+	UpdatedAt     time.Time  `json:"updated_at"`
+}
+
+// PriceListRequest represents the request payload for creating/updating a price list
+type PriceListRequest struct {
+	Name            string                 `json:"name" binding:"required"`
+	Description     string                 `json:"description"`
+	DefaultDiscount float64                `json:"default_discount" binding:"min=0,max=100"`
+	StartsAt        *string                `json:"starts_at"`
+	EndsAt          *string                `json:"ends_at"`
+	IsActive        *bool                  `json:"is_active"`
+	Items           []PriceListItemRequest `json:"items" binding:"dive"`
+}
+
+// PriceListItemRequest represents one item price on a price list request
+type PriceListItemRequest struct {
+	ItemID      uuid.UUID `json:"item_id" binding:"required"`
+	MinQuantity int       `json:"min_quantity" binding:"min=0"`
+	PriceType   string    `json:"price_type" binding:"required,oneof=fixed percentage"`
+	Value       float64   `json:"value" binding:"min=0"`
+}
+
+// PriceListResponse represents the response payload for price list data
+type PriceListResponse struct {
+	ID              uuid.UUID               `json:"id"`
+	ShopID          uuid.UUID               `json:"shop_id"`
+	Name            string                  `json:"name"`
+	Description     string                  `json:"description"`
+	DefaultDiscount float64                 `json:"default_discount"`
+	StartsAt        *time.Time              `json:"starts_at"`
+	EndsAt          *time.Time              `json:"ends_at"`
+	IsActive        bool                    `json:"is_active"`
+	Items           []PriceListItemResponse `json:"items"`
+	CreatedAt       time.Time               `json:"created_at"`
+	UpdatedAt       time.Time               `json:"updated_at"`
+}
+
+// PriceListItemResponse represents one item price on a price list
+type PriceListItemResponse struct {
+	ID          uuid.UUID `json:"id"`
+	ItemID      uuid.UUID `json:"item_id"`
+	ItemName    string    `json:"item_name"`
+	ItemPrice   float64   `json:"item_price"`
+	MinQuantity int       `json:"min_quantity"`
+	PriceType   string    `json:"price_type"`
+	Value       float64   `json:"value"`
+}
+
+// PriceResolutionRequest represents a request to resolve the prices a customer would pay
+type PriceResolutionRequest struct {
+	CustomerID      *uuid.UUID                   `json:"customer_id"`
+	CustomerGroupID *uuid.UUID                   `json:"customer_group_id"`
+	Date            string                       `json:"date"`
+	Items           []PriceResolutionItemRequest `json:"items" binding:"required,min=1,dive"`
+}
+
+// PriceResolutionItemRequest represents one item to resolve a price for
+type PriceResolutionItemRequest struct {
+	ItemID   uuid.UUID `json:"item_id" binding:"required"`
+	Quantity int       `json:"quantity" binding:"min=0"`
+}
+
+// PriceResolution represents the resolved prices for a customer
+type PriceResolution struct {
+	CustomerGroupID *uuid.UUID            `json:"customer_group_id"`
+	PriceListID     *uuid.UUID            `json:"price_list_id"`
+	PriceListName   string                `json:"price_list_name,omitempty"`
+	Items           []ResolvedPriceResult `json:"items"`
+}
+
+// ResolvedPriceResult represents the price of one item for a customer and quantity
+type ResolvedPriceResult struct {
+	ItemID      uuid.UUID `json:"item_id"`
+	ItemName    string    `json:"item_name"`
+	Quantity    int       `json:"quantity"`
+	BasePrice   float64   `json:"base_price"`
+	UnitPrice   float64   `json:"unit_price"`
+	TotalPrice  float64   `json:"total_price"`
+	Source      string    `json:"source"` // item, price_list, default_discount
+	MinQuantity int       `json:"min_quantity,omitempty"`
+}
@@ -0,0 +1,112 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// Promotion represents a discount rule that is applied automatically or unlocked by a coupon code
+type Promotion struct {
+	ID           uuid.UUID      `json:"id" gorm:"type:uuid;primary_key;default:gen_random_uuid()"`
+	ShopID       uuid.UUID      `json:"shop_id" gorm:"type:uuid;not null;index"`
+	Name         string         `json:"name" gorm:"not null"`
+	Description  string         `json:"description"`
+	Type         string         `json:"type" gorm:"not null"`  // percentage, fixed, buy_x_get_y
+	Scope        string         `json:"scope" gorm:"not null"` // item, category, bill
+	ItemID       *uuid.UUID     `json:"item_id" gorm:"type:uuid"`
+	Category     string         `json:"category"`
+	Value        float64        `json:"value" gorm:"not null;default:0"` // percent, fixed amount per unit/bill, or percent off the free units
+	BuyQuantity  int            `json:"buy_quantity" gorm:"not null;default:0"`
+	GetQuantity  int            `json:"get_quantity" gorm:"not null;default:0"`
+	MinBillValue float64        `json:"min_bill_value" gorm:"not null;default:0"`
+	StartsAt     *time.Time     `json:"starts_at"`
+	EndsAt       *time.Time     `json:"ends_at"`
+	CouponCode   string         `json:"coupon_code" gorm:"index"`              // empty for automatic promotions
+	UsageLimit   int            `json:"usage_limit" gorm:"not null;default:0"` // 0 means unlimited
+	UsageCount   int            `json:"usage_count" gorm:"not null;default:0"`
+	IsActive     bool           `json:"is_active" gorm:"default:true"`
+	CreatedAt    time.Time      `json:"created_at"`
+	UpdatedAt    time.Time      `json:"updated_at"`
+	DeletedAt    gorm.DeletedAt `json:"-" gorm:"index"`
+}
+
+// BillPromotion records a promotion applied to a bill and the discount it gave
+type BillPromotion struct {
+	ID            uuid.UUID `json:"id" gorm:"type:uuid;primary_key;default:gen_random_uuid()"`
+	BillID        uuid.UUID `json:"bill_id" gorm:"type:uuid;not null;index"`
+	PromotionID   uuid.UUID `json:"promotion_id" gorm:"type:uuid;not null;index"`
+	PromotionName string    `json:"promotion_name" gorm:"not null"`
+	CouponCode    string    `json:"coupon_code"`
+	Scope         string    `json:"scope" gorm:"not null"`
+	Amount        float64   `json:"amount" gorm:"not null"`
+	CreatedAt     time.Time `json:"created_at"`
+}
+
+// PromotionRequest represents the request payload for creating/updating a promotion
+type PromotionRequest struct {
+	Name         string     `json:"name" binding:"required"`
+	Description  string     `json:"description"`
+	Type         string     `json:"type" binding:"required,oneof=percentage fixed buy_x_get_y"`
+	Scope        string     `json:"scope" binding:"required,oneof=item category bill"`
+	ItemID       *uuid.UUID `json:"item_id"`
+	Category     string     `json:"category"`
+	Value        float64    `json:"value" binding:"min=0"`
+	BuyQuantity  int        `json:"buy_quantity" binding:"min=0"`
+	GetQuantity  int        `json:"get_quantity" binding:"min=0"`
+	MinBillValue float64    `json:"min_bill_value" binding:"min=0"`
+	StartsAt     *string    `json:"starts_at"`
+	EndsAt       *string    `json:"ends_at"`
+	CouponCode   string     `json:"coupon_code"`
+	UsageLimit   int        `json:"usage_limit" binding:"min=0"`
+	IsActive     *bool      `json:"is_active"`
+}
+
+// PromotionResponse represents the response payload for promotion data
+type PromotionResponse struct {
+	ID           uuid.UUID  `json:"id"`
+	ShopID       uuid.UUID  `json:"shop_id"`
+	Name         string     `json:"name"`
+	Description  string     `json:"description"`
+	Type         string     `json:"type"`
+	Scope        string     `json:"scope"`
+	ItemID       *uuid.UUID `json:"item_id"`
+	Category     string     `json:"category"`
+	Value        float64    `json:"value"`
+	BuyQuantity  int        `json:"buy_quantity"`
+	GetQuantity  int        `json:"get_quantity"`
+	MinBillValue float64    `json:"min_bill_value"`
+	StartsAt     *time.Time `json:"starts_at"`
+	EndsAt       *time.Time `json:"ends_at"`
+	CouponCode   string     `json:"coupon_code"`
+	UsageLimit   int        `json:"usage_limit"`
+	UsageCount   int        `json:"usage_count"`
+	IsActive     bool       `json:"is_active"`
+	CreatedAt    time.Time  `json:"created_at"`
+	UpdatedAt    time.Time  `json:"updated_at"`
+}
+
+// BillPromotionResponse represents a promotion applied to a bill
+type BillPromotionResponse struct {
+	PromotionID   uuid.UUID `json:"promotion_id"`
+	PromotionName string    `json:"promotion_name"`
+	CouponCode    string    `json:"coupon_code,omitempty"`
+	Scope         string    `json:"scope"`
+	Amount        float64   `json:"amount"`
+}
+
+// PromotionEvaluation represents the promotions that would apply to a bill request
+type PromotionEvaluation struct {
+	SubTotal          float64                 `json:"sub_total"`
+	PromotionDiscount float64                 `json:"promotion_discount"`
+	Lines             []PromotionLineResult   `json:"lines"`
+	Promotions        []BillPromotionResponse `json:"promotions"`
+}
+
+// PromotionLineResult represents the promotion discount given on one bill line
+type PromotionLineResult struct {
+	ItemID            uuid.UUID  `json:"item_id"`
+	PromotionID       *uuid.UUID `json:"promotion_id"`
+	PromotionDiscount float64    `json:"promotion_discount"`
+}
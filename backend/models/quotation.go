@@ -0,0 +1,130 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// Quotation represents a price quotation/estimate that can be converted into a bill
+type Quotation struct {
+	ID                uuid.UUID      `json:"id" gorm:"type:uuid;primary_key;default:gen_random_uuid()"`
+	ShopID            uuid.UUID      `json:"shop_id" gorm:"type:uuid;not null;index"`
+	CustomerID        *uuid.UUID     `json:"customer_id" gorm:"type:uuid"`
+	QuotationNumber   string         `json:"quotation_number" gorm:"not null;unique"`
+	QuotationDate     time.Time      `json:"quotation_date" gorm:"not null"`
+	ValidUntil        time.Time      `json:"valid_until" gorm:"not null"`
+	SubTotal          float64        `json:"sub_total" gorm:"not null;default:0"`
+	TaxRate           float64        `json:"tax_rate" gorm:"not null;default:0"`
+	TaxAmount         float64        `json:"tax_amount" gorm:"not null;default:0"`
+	Discount          float64        `json:"discount" gorm:"not null;default:0"`
+	LineDiscount      float64        `json:"line_discount" gorm:"not null;default:0"`
+	PromotionDiscount float64        `json:"promotion_discount" gorm:"not null;default:0"`
+	TaxableValue      float64        `json:"taxable_value" gorm:"not null;default:0"`
+	TotalAmount       float64        `json:"total_amount" gorm:"not null;default:0"`
+	Status            string         `json:"status" gorm:"not null;default:'draft'"` // draft, sent, accepted, rejected, expired, converted
+	Notes             string         `json:"notes"`
+	Terms             string         `json:"terms"`
+	BillID            *uuid.UUID     `json:"bill_id" gorm:"type:uuid"`
+	CreatedBy         string         `json:"created_by" gorm:"not null"`
+	CreatedAt         time.Time      `json:"created_at"`
+	UpdatedAt         time.Time      `json:"updated_at"`
+	DeletedAt         gorm.DeletedAt `json:"deleted_at" gorm:"index"`
+
+	// Relationships
+	Shop     Shop            `json:"shop,omitempty" gorm:"foreignKey:ShopID"`
+	Customer *Customer       `json:"customer,omitempty" gorm:"foreignKey:CustomerID"`
+	Items    []QuotationItem `json:"items,omitempty" gorm:"foreignKey:QuotationID"`
+}
+
+// QuotationItem represents an item in a quotation
+type QuotationItem struct {
+	ID                uuid.UUID `json:"id" gorm:"type:uuid;primary_key;default:gen_random_uuid()"`
+	QuotationID       uuid.UUID `json:"quotation_id" gorm:"type:uuid;not null;index"`
+	ItemID            uuid.UUID `json:"item_id" gorm:"type:uuid;not null"`
+	ItemName          string    `json:"item_name" gorm:"not null"`
+	Description       string    `json:"description"`
+	Quantity          int       `json:"quantity" gorm:"not null"`
+	UnitPrice         float64   `json:"unit_price" gorm:"not null"`
+	TotalPrice        float64   `json:"total_price" gorm:"not null"`
+	Discount          float64   `json:"discount" gorm:"not null;default:0"`
+	DiscountType      string    `json:"discount_type"` // percentage, amount
+	DiscountAmount    float64   `json:"discount_amount" gorm:"not null;default:0"`
+	PromotionDiscount float64   `json:"promotion_discount" gorm:"not null;default:0"`
+	TaxInclusive      *bool     `json:"tax_inclusive"` // nil uses the item's or the shop's setting
+	TaxRate           float64   `json:"tax_rate" gorm:"not null;default:0"`
+	TaxableValue      float64   `json:"taxable_value" gorm:"not null;default:0"`
+	TaxAmount         float64   `json:"tax_amount" gorm:"not null;default:0"`
+	CreatedAt         time.Time `json:"created_at"`
+	UpdatedAt         time.Time `json:"updated_at"`
+}
+
+// QuotationRequest represents the request payload for creating/updating a quotation
+type QuotationRequest struct {
+	CustomerID    *uuid.UUID        `json:"customer_id"`
+	QuotationDate string            `json:"quotation_date" binding:"required"`
+	ValidUntil    string            `json:"valid_until" binding:"required"`
+	Items         []BillItemRequest `json:"items" binding:"required"`
+	Discount      float64           `json:"discount"`
+	TaxRate       float64           `json:"tax_rate"`
+	Notes         string            `json:"notes"`
+	Terms         string            `json:"terms"`
+}
+
+// QuotationStatusRequest represents the request payload for changing a quotation's status
+type QuotationStatusRequest struct {
+	Status string `json:"status" binding:"required,oneof=draft sent accepted rejected"`
+}
+
+// QuotationConvertRequest represents the request payload for converting a quotation into a bill
+type QuotationConvertRequest struct {
+	BillDate string  `json:"bill_date"`
+	DueDate  *string `json:"due_date"`
+}
+
+// QuotationResponse represents the response payload for quotation data
+type QuotationResponse struct {
+	ID                uuid.UUID               `json:"id"`
+	ShopID            uuid.UUID               `json:"shop_id"`
+	CustomerID        *uuid.UUID              `json:"customer_id"`
+	QuotationNumber   string                  `json:"quotation_number"`
+	QuotationDate     time.Time               `json:"quotation_date"`
+	ValidUntil        time.Time               `json:"valid_until"`
+	SubTotal          float64                 `json:"sub_total"`
+	TaxRate           float64                 `json:"tax_rate"`
+	TaxAmount         float64                 `json:"tax_amount"`
+	Discount          float64                 `json:"discount"`
+	LineDiscount      float64                 `json:"line_discount"`
+	PromotionDiscount float64                 `json:"promotion_discount"`
+	TaxableValue      float64                 `json:"taxable_value"`
+	TotalAmount       float64                 `json:"total_amount"`
+	Status            string                  `json:"status"`
+	Notes             string                  `json:"notes"`
+	Terms             string                  `json:"terms"`
+	BillID            *uuid.UUID              `json:"bill_id"`
+	Customer          *CustomerResponse       `json:"customer,omitempty"`
+	Items             []QuotationItemResponse `json:"items"`
+	CreatedAt         time.Time               `json:"created_at"`
+	UpdatedAt         time.Time               `json:"updated_at"`
+}
+
+// QuotationItemResponse represents the response payload for quotation item data
+type QuotationItemResponse struct {
+	ID                uuid.UUID `json:"id"`
+	QuotationID       uuid.UUID `json:"quotation_id"`
+	ItemID            uuid.UUID `json:"item_id"`
+	ItemName          string    `json:"item_name"`
+	Description       string    `json:"description"`
+	Quantity          int       `json:"quantity"`
+	UnitPrice         float64   `json:"unit_price"`
+	TotalPrice        float64   `json:"total_price"`
+	Discount          float64   `json:"discount"`
+	DiscountType      string    `json:"discount_type"`
+	DiscountAmount    float64   `json:"discount_amount"`
+	PromotionDiscount float64   `json:"promotion_discount"`
+	TaxInclusive      *bool     `json:"tax_inclusive"`
+	TaxRate           float64   `json:"tax_rate"`
+	TaxableValue      float64   `json:"taxable_value"`
+	TaxAmount         float64   `json:"tax_amount"`
+}
@@ -0,0 +1,145 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// RecurringBill represents a template that generates the same bill on a schedule
+type RecurringBill struct {
+	ID          uuid.UUID      `json:"id" gorm:"type:uuid;primary_key;default:gen_random_uuid()"`
+	ShopID      uuid.UUID      `json:"shop_id" gorm:"type:uuid;not null;index"`
+	CustomerID  *uuid.UUID     `json:"customer_id" gorm:"type:uuid"`
+	Name        string         `json:"name" gorm:"not null"`
+	Frequency   string         `json:"frequency" gorm:"not null"` // weekly, monthly, quarterly, yearly
+	StartDate   time.Time      `json:"start_date" gorm:"not null"`
+	EndDate     *time.Time     `json:"end_date"`
+	DayOfMonth  int            `json:"day_of_month" gorm:"not null;default:1"`
+	DueInDays   int            `json:"due_in_days" gorm:"not null;default:0"`
+	AutoIssue   bool           `json:"auto_issue" gorm:"not null;default:false"`
+	AutoEmail   bool           `json:"auto_email" gorm:"not null;default:false"`
+	Status      string         `json:"status" gorm:"not null;default:'active';index"` // active, paused, ended
+	NextRunDate *time.Time     `json:"next_run_date" gorm:"index"`
+	LastRunDate *time.Time     `json:"last_run_date"`
+	Discount    float64        `json:"discount" gorm:"not null;default:0"`
+	TaxRate     float64        `json:"tax_rate" gorm:"not null;default:0"`
+	Notes       string         `json:"notes"`
+	Terms       string         `json:"terms"`
+	CreatedBy   string         `json:"created_by" gorm:"not null"`
+	CreatedAt   time.Time      `json:"created_at"`
+	UpdatedAt   time.Time      `json:"updated_at"`
+	DeletedAt   gorm.DeletedAt `json:"deleted_at" gorm:"index"`
+
+	// Relationships
+	Customer *Customer           `json:"customer,omitempty" gorm:"foreignKey:CustomerID"`
+	Items    []RecurringBillItem `json:"items,omitempty" gorm:"foreignKey:RecurringBillID"`
+}
+
+// RecurringBillItem represents a line of a recurring bill template
+type RecurringBillItem struct {
+	ID              uuid.UUID `json:"id" gorm:"type:uuid;primary_key;default:gen_random_uuid()"`
+	RecurringBillID uuid.UUID `json:"recurring_bill_id" gorm:"type:uuid;not null;index"`
+	ItemID          uuid.UUID `json:"item_id" gorm:"type:uuid;not null"`
+	ItemName        string    `json:"item_name" gorm:"not null"`
+	Description     string    `json:"description"`
+	Quantity        int       `json:"quantity" gorm:"not null"`
+	UnitPrice       float64   `json:"unit_price" gorm:"not null"`
+	Discount        float64   `json:"discount" gorm:"not null;default:0"`
+	DiscountType    string    `json:"discount_type"` // percentage, amount
+	TaxInclusive    *bool     `json:"tax_inclusive"` // nil uses the item's or the shop's setting
+	CreatedAt       time.Time `json:"created_at"`
+	UpdatedAt       time.Time `json:"updated_at"`
+}
+
+// RecurringBillRun records the bill generated for one period of a template.
+// The unique period index makes generation idempotent.
+type RecurringBillRun struct {
+	ID              uuid.UUID  `json:"id" gorm:"type:uuid;primary_key;default:gen_random_uuid()"`
+	RecurringBillID uuid.UUID  `json:"recurring_bill_id" gorm:"type:uuid;not null;uniqueIndex:idx_recurring_bill_period"`
+	PeriodDate      time.Time  `json:"period_date" gorm:"type:date;not null;uniqueIndex:idx_recurring_bill_period"`
+	BillID          *uuid.UUID `json:"bill_id" gorm:"type:uuid"`
+	EmailStatus     string     `json:"email_status" gorm:"not null;default:'not_required'"` // not_required, pending, sent, failed
+	CreatedAt       time.Time  `json:"created_at"`
+	UpdatedAt       time.Time  `json:"updated_at"`
+}
+
+// RecurringBillRequest represents the request payload for creating/updating a recurring bill template
+type RecurringBillRequest struct {
+	CustomerID *uuid.UUID        `json:"customer_id"`
+	Name       string            `json:"name" binding:"required"`
+	Frequency  string            `json:"frequency" binding:"required,oneof=weekly monthly quarterly yearly"`
+	StartDate  string            `json:"start_date" binding:"required"`
+	EndDate    *string           `json:"end_date"`
+	DayOfMonth int               `json:"day_of_month" binding:"min=0,max=31"`
+	DueInDays  int               `json:"due_in_days" binding:"min=0"`
+	AutoIssue  bool              `json:"auto_issue"`
+	AutoEmail  bool              `json:"auto_email"`
+	Items      []BillItemRequest `json:"items" binding:"required,min=1,dive"`
+	Discount   float64           `json:"discount"`
+	TaxRate    float64           `json:"tax_rate"`
+	Notes      string            `json:"notes"`
+	Terms      string            `json:"terms"`
+}
+
+// RecurringBillResponse represents the response payload for recurring bill template data
+type RecurringBillResponse struct {
+	ID          uuid.UUID                   `json:"id"`
+	ShopID      uuid.UUID                   `json:"shop_id"`
+	CustomerID  *uuid.UUID                  `json:"customer_id"`
+	Name        string                      `json:"name"`
+	Frequency   string                      `json:"frequency"`
+	StartDate   time.Time                   `json:"start_date"`
+	EndDate     *time.Time                  `json:"end_date"`
+	DayOfMonth  int                         `json:"day_of_month"`
+	DueInDays   int                         `json:"due_in_days"`
+	AutoIssue   bool                        `json:"auto_issue"`
+	AutoEmail   bool                        `json:"auto_email"`
+	Status      string                      `json:"status"`
+	NextRunDate *time.Time                  `json:"next_run_date"`
+	LastRunDate *time.Time                  `json:"last_run_date"`
+	Discount    float64                     `json:"discount"`
+	TaxRate     float64                     `json:"tax_rate"`
+	Notes       string                      `json:"notes"`
+	Terms       string                      `json:"terms"`
+	Customer    *CustomerResponse           `json:"customer,omitempty"`
+	Items       []RecurringBillItemResponse `json:"items"`
+	CreatedAt   time.Time                   `json:"created_at"`
+	UpdatedAt   time.Time                   `json:"updated_at"`
+}
+
+// RecurringBillItemResponse represents the response payload for a recurring bill line
+type RecurringBillItemResponse struct {
+	ID           uuid.UUID `json:"id"`
+	ItemID       uuid.UUID `json:"item_id"`
+	ItemName     string    `json:"item_name"`
+	Description  string    `json:"description"`
+	Quantity     int       `json:"quantity"`
+	UnitPrice    float64   `json:"unit_price"`
+	TotalPrice   float64   `json:"total_price"`
+	Discount     float64   `json:"discount"`
+	DiscountType string    `json:"discount_type"`
+	TaxInclusive *bool     `json:"tax_inclusive"`
+}
+
+// RecurringBillRunResponse represents the response payload for a generated period
+type RecurringBillRunResponse struct {
+	ID          uuid.UUID  `json:"id"`
+	PeriodDate  time.Time  `json:"period_date"`
+	BillID      *uuid.UUID `json:"bill_id"`
+	EmailStatus string     `json:"email_status"`
+	CreatedAt   time.Time  `json:"created_at"`
+}
+
+// RecurringBillPreview represents an upcoming run of a recurring bill template
+type RecurringBillPreview struct {
+	BillDate          time.Time  `json:"bill_date"`
+	DueDate           *time.Time `json:"due_date"`
+	Status            string     `json:"status"` // bill status the run will create: draft or sent
+	SubTotal          float64    `json:"sub_total"`
+	LineDiscount      float64    `json:"line_discount"`
+	PromotionDiscount float64    `json:"promotion_discount"`
+	TaxAmount         float64    `json:"tax_amount"`
+	TotalAmount       float64    `json:"total_amount"`
+}
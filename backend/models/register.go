@@ -0,0 +1,128 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// RegisterSession represents a cash register shift from opening float to closing count
+type RegisterSession struct {
+	ID           uuid.UUID  `json:"id" gorm:"type:uuid;primary_key;default:gen_random_uuid()"`
+	ShopID       uuid.UUID  `json:"shop_id" gorm:"type:uuid;not null;index"`
+	RegisterName string     `json:"register_name" gorm:"not null"`
+	Status       string     `json:"status" gorm:"not null;default:'open';index"` // open, closed
+	OpeningFloat float64    `json:"opening_float" gorm:"not null;default:0"`
+	ExpectedCash float64    `json:"expected_cash" gorm:"not null;default:0"`
+	CountedCash  *float64   `json:"counted_cash"`
+	Variance     float64    `json:"variance" gorm:"not null;default:0"`
+	OpenedBy     string     `json:"opened_by" gorm:"not null"`
+	OpenedAt     time.Time  `json:"opened_at" gorm:"not null"`
+	ClosedBy     string     `json:"closed_by"`
+	ClosedAt     *time.Time `json:"closed_at"`
+	Notes        string     `json:"notes"`
+	CreatedAt    time.Time  `json:"created_at"`
+	UpdatedAt    time.Time  `json:"updated_at"`
+
+	// Relationships
+	Movements []CashMovement `json:"movements,omitempty" gorm:"foreignKey:SessionID"`
+}
+
+// CashMovement represents cash put into or taken out of a register outside of sales
+type CashMovement struct {
+	ID            uuid.UUID  `json:"id" gorm:"type:uuid;primary_key;default:gen_random_uuid()"`
+	SessionID     uuid.UUID  `json:"session_id" gorm:"type:uuid;not null;index"`
+	ShopID        uuid.UUID  `json:"shop_id" gorm:"type:uuid;not null"`
+	Type          string     `json:"type" gorm:"not null"` // cash_in, cash_out, refund
+	Amount        float64    `json:"amount" gorm:"not null"`
+	PaymentMethod string     `json:"payment_method" gorm:"not null;default:'cash'"`
+	BillID        *uuid.UUID `json:"bill_id" gorm:"type:uuid"`
+	Reason        string     `json:"reason"`
+	CreatedBy     string     `json:"created_by" gorm:"not null"`
+	CreatedAt     time.Time  `json:"created_at"`
+	UpdatedAt     time.Time  `json:"updated_at"`
+}
+
+// OpenRegisterRequest represents the request payload for opening a register session
+type OpenRegisterRequest struct {
+	RegisterName string  `json:"register_name" binding:"required"`
+	OpeningFloat float64 `json:"opening_float" binding:"min=0"`
+	Notes        string  `json:"notes"`
+}
+
+// CloseRegisterRequest represents the request payload for closing a register session
+type CloseRegisterRequest struct {
+	CountedCash float64 `json:"counted_cash" binding:"min=0"`
+	Notes       string  `json:"notes"`
+}
+
+// CashMovementRequest represents the request payload for recording a cash movement
+type CashMovementRequest struct {
+	Type          string     `json:"type" binding:"required,oneof=cash_in cash_out refund"`
+	Amount        float64    `json:"amount" binding:"required,gt=0"`
+	PaymentMethod string     `json:"payment_method"`
+	BillID        *uuid.UUID `json:"bill_id"`
+	Reason        string     `json:"reason"`
+}
+
+// RegisterSessionResponse represents the response payload for register session data
+type RegisterSessionResponse struct {
+	ID           uuid.UUID              `json:"id"`
+	ShopID       uuid.UUID              `json:"shop_id"`
+	RegisterName string                 `json:"register_name"`
+	Status       string                 `json:"status"`
+	OpeningFloat float64                `json:"opening_float"`
+	ExpectedCash float64                `json:"expected_cash"`
+	CountedCash  *float64               `json:"counted_cash"`
+	Variance     float64                `json:"variance"`
+	OpenedBy     string                 `json:"opened_by"`
+	OpenedAt     time.Time              `json:"opened_at"`
+	ClosedBy     string                 `json:"closed_by"`
+	ClosedAt     *time.Time             `json:"closed_at"`
+	Notes        string                 `json:"notes"`
+	Movements    []CashMovementResponse `json:"movements"`
+	CreatedAt    time.Time              `json:"created_at"`
+	UpdatedAt    time.Time              `json:"updated_at"`
+}
+
+// CashMovementResponse represents the response payload for cash movement data
+type CashMovementResponse struct {
+	ID            uuid.UUID  `json:"id"`
+	SessionID     uuid.UUID  `json:"session_id"`
+	Type          string     `json:"type"`
+	Amount        float64    `json:"amount"`
+	PaymentMethod string     `json:"payment_method"`
+	BillID        *uuid.UUID `json:"bill_id"`
+	Reason        string     `json:"reason"`
+	CreatedBy     string     `json:"created_by"`
+	CreatedAt     time.Time  `json:"created_at"`
+}
+
+// RegisterReport represents an X (mid-shift) or Z (closing) report for a register session
+type RegisterReport struct {
+	ReportType      string                  `json:"report_type"` // X, Z
+	ShopName        string                  `json:"shop_name"`
+	Session         RegisterSessionResponse `json:"session"`
+	SalesByMethod   []PaymentMethodTotal    `json:"sales_by_method"`
+	TotalSales      float64                 `json:"total_sales"`
+	PaymentCount    int                     `json:"payment_count"`
+	PointsRedeemed  float64                 `json:"points_redeemed"` // paid with loyalty points, not money, so left out of the sales
+	RefundsByMethod []PaymentMethodTotal    `json:"refunds_by_method"`
+	TotalRefunds    float64                 `json:"total_refunds"`
+	CashSales       float64                 `json:"cash_sales"`
+	CashRefunds     float64                 `json:"cash_refunds"`
+	CashIn          float64                 `json:"cash_in"`
+	CashOut         float64                 `json:"cash_out"`
+	OpeningFloat    float64                 `json:"opening_float"`
+	ExpectedCash    float64                 `json:"expected_cash"`
+	CountedCash     *float64                `json:"counted_cash"`
+	Variance        float64                 `json:"variance"`
+	GeneratedAt     time.Time               `json:"generated_at"`
+}
+
+// PaymentMethodTotal represents the count and amount of payments for a payment method
+type PaymentMethodTotal struct {
+	PaymentMethod string  `json:"payment_method"`
+	Count         int     `json:"count"`
+	Amount        float64 `json:"amount"`
+}
@@ -0,0 +1,33 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// ProfitabilityReport represents revenue, cost and gross margin grouped by item, category, customer or staff member
+type ProfitabilityReport struct {
+	From    time.Time          `json:"from"`
+	To      time.Time          `json:"to"`
+	GroupBy string             `json:"group_by"` // item, category, customer, staff
+	SortBy  string             `json:"sort_by"`
+	Order   string             `json:"order"`
+	Rows    []ProfitabilityRow `json:"rows"`
+	Totals  ProfitabilityRow   `json:"totals"`
+}
+
+// ProfitabilityRow represents the profitability of one group. Revenue excludes tax and is net of
+// line discounts, promotions and the bill discount; cost uses the cost price captured at sale.
+type ProfitabilityRow struct {
+	Key              *uuid.UUID `json:"key,omitempty"`
+	Name             string     `json:"name"`
+	Category         string     `json:"category,omitempty"`
+	BillCount        int64      `json:"bill_count"`
+	Quantity         int64      `json:"quantity"`
+	Revenue          float64    `json:"revenue"`
+	Cost             float64    `json:"cost"`
+	GrossMargin      float64    `json:"gross_margin"`
+	MarginPercent    float64    `json:"margin_percent"`
+	UncostedQuantity int64      `json:"uncosted_quantity"` // units sold without a cost price
+}
@@ -0,0 +1,184 @@
+package models
+
+import "encoding/xml"
+
+// The types below serialise a UBL 2.1 invoice following Peppol BIS Billing 3.0. Element names
+// carry their cbc (basic) or cac (aggregate) component prefixes.
+
+// UBLInvoice represents a UBL 2.1 Invoice document
+type UBLInvoice struct {
+	XMLName                 xml.Name         `xml:"Invoice"`
+	Xmlns                   string           `xml:"xmlns,attr"`
+	XmlnsCac                string           `xml:"xmlns:cac,attr"`
+	XmlnsCbc                string           `xml:"xmlns:cbc,attr"`
+	CustomizationID         string           `xml:"cbc:CustomizationID"`
+	ProfileID               string           `xml:"cbc:ProfileID"`
+	ID                      string           `xml:"cbc:ID"`
+	IssueDate               string           `xml:"cbc:IssueDate"`
+	DueDate                 string           `xml:"cbc:DueDate,omitempty"`
+	InvoiceTypeCode         string           `xml:"cbc:InvoiceTypeCode"`
+	Note                    string           `xml:"cbc:Note,omitempty"`
+	DocumentCurrencyCode    string           `xml:"cbc:DocumentCurrencyCode"`
+	BuyerReference          string           `xml:"cbc:BuyerReference"`
+	AccountingSupplierParty UBLPartyWrapper  `xml:"cac:AccountingSupplierParty"`
+	AccountingCustomerParty UBLPartyWrapper  `xml:"cac:AccountingCustomerParty"`
+	PaymentTerms            *UBLPaymentTerms `xml:"cac:PaymentTerms,omitempty"`
+	TaxTotal                UBLTaxTotal      `xml:"cac:TaxTotal"`
+	LegalMonetaryTotal      UBLMonetaryTotal `xml:"cac:LegalMonetaryTotal"`
+	InvoiceLines            []UBLInvoiceLine `xml:"cac:InvoiceLine"`
+}
+
+// UBLPartyWrapper wraps the party of a supplier or customer
+type UBLPartyWrapper struct {
+	Party UBLParty `xml:"cac:Party"`
+}
+
+// UBLParty represents a seller or buyer
+type UBLParty struct {
+	EndpointID       UBLIdentifier      `xml:"cbc:EndpointID"`
+	PartyName        *UBLPartyName      `xml:"cac:PartyName,omitempty"`
+	PostalAddress    UBLAddress         `xml:"cac:PostalAddress"`
+	PartyTaxScheme   *UBLPartyTaxScheme `xml:"cac:PartyTaxScheme,omitempty"`
+	PartyLegalEntity UBLLegalEntity     `xml:"cac:PartyLegalEntity"`
+	Contact          *UBLContact        `xml:"cac:Contact,omitempty"`
+}
+
+// UBLIdentifier represents an identifier with its scheme
+type UBLIdentifier struct {
+	SchemeID string `xml:"schemeID,attr,omitempty"`
+	Value    string `xml:",chardata"`
+}
+
+// UBLPartyName represents the trading name of a party
+type UBLPartyName struct {
+	Name string `xml:"cbc:Name"`
+}
+
+// UBLAddress represents a postal address
+type UBLAddress struct {
+	StreetName string     `xml:"cbc:StreetName,omitempty"`
+	CityName   string     `xml:"cbc:CityName,omitempty"`
+	PostalZone string     `xml:"cbc:PostalZone,omitempty"`
+	Country    UBLCountry `xml:"cac:Country"`
+}
+
+// UBLCountry represents a country by its ISO 3166-1 alpha-2 code
+type UBLCountry struct {
+	IdentificationCode string `xml:"cbc:IdentificationCode"`
+}
+
+// UBLPartyTaxScheme represents the tax registration of a party
+type UBLPartyTaxScheme struct {
+	CompanyID string       `xml:"cbc:CompanyID"`
+	TaxScheme UBLTaxScheme `xml:"cac:TaxScheme"`
+}
+
+// UBLTaxScheme identifies the tax scheme, always VAT in Peppol
+type UBLTaxScheme struct {
+	ID string `xml:"cbc:ID"`
+}
+
+// UBLLegalEntity represents the legal registration of a party
+type UBLLegalEntity struct {
+	RegistrationName string `xml:"cbc:RegistrationName"`
+}
+
+// UBLContact represents a party's contact details
+type UBLContact struct {
+	Telephone      string `xml:"cbc:Telephone,omitempty"`
+	ElectronicMail string `xml:"cbc:ElectronicMail,omitempty"`
+}
+
+// UBLPaymentTerms represents the payment terms as free text
+type UBLPaymentTerms struct {
+	Note string `xml:"cbc:Note"`
+}
+
+// UBLAmount represents an amount in a currency
+type UBLAmount struct {
+	CurrencyID string `xml:"currencyID,attr"`
+	Value      string `xml:",chardata"`
+}
+
+// UBLQuantity represents a quantity in a UN/ECE recommendation 20 unit
+type UBLQuantity struct {
+	UnitCode string `xml:"unitCode,attr"`
+	Value    string `xml:",chardata"`
+}
+
+// UBLTaxTotal represents the tax of the invoice and its breakdown by category
+type UBLTaxTotal struct {
+	TaxAmount    UBLAmount        `xml:"cbc:TaxAmount"`
+	TaxSubtotals []UBLTaxSubtotal `xml:"cac:TaxSubtotal"`
+}
+
+// UBLTaxSubtotal represents the taxable amount and tax of one tax category and rate
+type UBLTaxSubtotal struct {
+	TaxableAmount UBLAmount      `xml:"cbc:TaxableAmount"`
+	TaxAmount     UBLAmount      `xml:"cbc:TaxAmount"`
+	TaxCategory   UBLTaxCategory `xml:"cac:TaxCategory"`
+}
+
+// UBLTaxCategory represents a tax category such as S (standard) or Z (zero rated)
+type UBLTaxCategory struct {
+	ID        string       `xml:"cbc:ID"`
+	Percent   string       `xml:"cbc:Percent"`
+	TaxScheme UBLTaxScheme `xml:"cac:TaxScheme"`
+}
+
+// UBLMonetaryTotal represents the invoice totals
+type UBLMonetaryTotal struct {
+	LineExtensionAmount   UBLAmount  `xml:"cbc:LineExtensionAmount"`
+	TaxExclusiveAmount    UBLAmount  `xml:"cbc:TaxExclusiveAmount"`
+	TaxInclusiveAmount    UBLAmount  `xml:"cbc:TaxInclusiveAmount"`
+	PrepaidAmount         *UBLAmount `xml:"cbc:PrepaidAmount,omitempty"`
+	PayableRoundingAmount *UBLAmount `xml:"cbc:PayableRoundingAmount,omitempty"`
+	PayableAmount         UBLAmount  `xml:"cbc:PayableAmount"`
+}
+
+// UBLInvoiceLine represents one line of an invoice
+type UBLInvoiceLine struct {
+	ID                  string               `xml:"cbc:ID"`
+	InvoicedQuantity    UBLQuantity          `xml:"cbc:InvoicedQuantity"`
+	LineExtensionAmount UBLAmount            `xml:"cbc:LineExtensionAmount"`
+	AllowanceCharges    []UBLAllowanceCharge `xml:"cac:AllowanceCharge"`
+	Item                UBLItem              `xml:"cac:Item"`
+	Price               UBLPrice             `xml:"cac:Price"`
+}
+
+// UBLAllowanceCharge represents a discount (allowance) or charge on a line
+type UBLAllowanceCharge struct {
+	ChargeIndicator       bool      `xml:"cbc:ChargeIndicator"`
+	AllowanceChargeReason string    `xml:"cbc:AllowanceChargeReason"`
+	Amount                UBLAmount `xml:"cbc:Amount"`
+}
+
+// UBLItem represents the item sold on a line
+type UBLItem struct {
+	Description               string                      `xml:"cbc:Description,omitempty"`
+	Name                      string                      `xml:"cbc:Name"`
+	SellersItemIdentification *UBLItemIdentification      `xml:"cac:SellersItemIdentification,omitempty"`
+	CommodityClassification   *UBLCommodityClassification `xml:"cac:CommodityClassification,omitempty"`
+	ClassifiedTaxCategory     UBLTaxCategory              `xml:"cac:ClassifiedTaxCategory"`
+}
+
+// UBLItemIdentification represents the seller's identifier for an item
+type UBLItemIdentification struct {
+	ID string `xml:"cbc:ID"`
+}
+
+// UBLCommodityClassification represents an item classification such as its HS code
+type UBLCommodityClassification struct {
+	ItemClassificationCode UBLClassificationCode `xml:"cbc:ItemClassificationCode"`
+}
+
+// UBLClassificationCode represents a classification code with its code list
+type UBLClassificationCode struct {
+	ListID string `xml:"listID,attr"`
+	Value  string `xml:",chardata"`
+}
+
+// UBLPrice represents the net unit price of an item
+type UBLPrice struct {
+	PriceAmount UBLAmount `xml:"cbc:PriceAmount"`
+}
@@ -24,18 +24,22 @@ type User struct {
 }
 
 type Shop struct {
-	ID        uuid.UUID      `json:"id" gorm:"type:uuid;primary_key;default:gen_random_uuid()"`
-	Name      string         `json:"name" gorm:"not null"`
-	Address   string         `json:"address"`
-	Phone     string         `json:"phone"`
-	Email     string         `json:"email"`
-	GSTNumber string         `json:"gst_number"`
-	LogoURL   string         `json:"logo_url"`
-	Settings  string         `json:"settings" gorm:"type:jsonb"`
-	IsActive  bool           `json:"is_active" gorm:"default:true"`
-	CreatedAt time.Time      `json:"created_at"`
-	UpdatedAt time.Time      `json:"updated_at"`
-	DeletedAt gorm.DeletedAt `json:"-" gorm:"index"`
+	ID               uuid.UUID      `json:"id" gorm:"type:uuid;primary_key;default:gen_random_uuid()"`
+	Name             string         `json:"name" gorm:"not null"`
+	Address          string         `json:"address"`
+	Phone            string         `json:"phone"`
+	Email            string         `json:"email"`
+	GSTNumber        string         `json:"gst_number"`
+	LogoURL          string         `json:"logo_url"`
+	Settings         string         `json:"settings" gorm:"type:jsonb"`
+	ReceiptFooter    string         `json:"receipt_footer"`
+	PricesIncludeTax bool           `json:"prices_include_tax" gorm:"not null;default:false"` // item prices are tax-inclusive unless an item says otherwise
+	UPIID            string         `json:"upi_id" gorm:"column:upi_id"`                      // VPA customers pay to, such as shopname@okhdfcbank
+	UPIPayeeName     string         `json:"upi_payee_name" gorm:"column:upi_payee_name"`      // name shown in the payer's app, defaults to the shop name
+	IsActive         bool           `json:"is_active" gorm:"default:true"`
+	CreatedAt        time.Time      `json:"created_at"`
+	UpdatedAt        time.Time      `json:"updated_at"`
+	DeletedAt        gorm.DeletedAt `json:"-" gorm:"index"`
 
 	// Relationships
 	ShopUsers []ShopUser `json:"shop_users,omitempty" gorm:"foreignKey:ShopID"`
@@ -62,27 +66,35 @@ type ShopUser struct {
 
 // ShopRequest represents the request payload for creating/updating a shop
 type ShopRequest struct {
-	Name      string `json:"name" binding:"required"`
-	Address   string `json:"address"`
-	Phone     string `json:"phone"`
-	Email     string `json:"email"`
-	GSTNumber string `json:"gst_number"`
-	LogoURL   string `json:"logo_url"`
-	Settings  string `json:"settings"`
-	IsActive  bool   `json:"is_active"`
+	Name             string `json:"name" binding:"required"`
+	Address          string `json:"address"`
+	Phone            string `json:"phone"`
+	Email            string `json:"email"`
+	GSTNumber        string `json:"gst_number"`
+	LogoURL          string `json:"logo_url"`
+	Settings         string `json:"settings"`
+	ReceiptFooter    string `json:"receipt_footer"`
+	PricesIncludeTax bool   `json:"prices_include_tax"`
+	UPIID            string `json:"upi_id"`
+	UPIPayeeName     string `json:"upi_payee_name"`
+	IsActive         bool   `json:"is_active"`
 }
 
 // ShopResponse represents the response payload for shop data
 type ShopResponse struct {
-	ID        uuid.UUID `json:"id"`
-	Name      string    `json:"name"`
-	Address   string    `json:"address"`
-	Phone     string    `json:"phone"`
-	Email     string    `json:"email"`
-	GSTNumber string    `json:"gst_number"`
-	LogoURL   string    `json:"logo_url"`
-	Settings  string    `json:"settings"`
-	IsActive  bool      `json:"is_active"`
-	CreatedAt time.Time `json:"created_at"`
-	UpdatedAt time.Time `json:"updated_at"`
+	ID               uuid.UUID `json:"id"`
+	Name             string    `json:"name"`
+	Address          string    `json:"address"`
+	Phone            string    `json:"phone"`
+	Email            string    `json:"email"`
+	GSTNumber        string    `json:"gst_number"`
+	LogoURL          string    `json:"logo_url"`
+	Settings         string    `json:"settings"`
+	ReceiptFooter    string    `json:"receipt_footer"`
+	PricesIncludeTax bool      `json:"prices_include_tax"`
+	UPIID            string    `json:"upi_id"`
+	UPIPayeeName     string    `json:"upi_payee_name"`
+	IsActive         bool      `json:"is_active"`
+	CreatedAt        time.Time `json:"created_at"`
+	UpdatedAt        time.Time `json:"updated_at"`
 }
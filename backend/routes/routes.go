@@ -12,9 +12,31 @@ func SetupRoutes(router *gin.Engine, services *services.Services) {
 	// Initialize handlers
 	authHandler := handlers.NewAuthHandler(services.Auth)
 	shopHandler := handlers.NewShopHandler(services.Shop)
-	billHandler := handlers.NewBillHandler(services.Bill)
+	billHandler := handlers.NewBillHandler(services.Bill, services.PDF, services.UBL)
 	itemHandler := handlers.NewItemHandler(services.Item)
 	customerHandler := handlers.NewCustomerHandler(services.Customer)
+	labelHandler := handlers.NewLabelHandler(services.Label)
+	posHandler := handlers.NewPOSHandler(services.POS)
+	cartHandler := handlers.NewCartHandler(services.Cart)
+	registerHandler := handlers.NewRegisterHandler(services.Register, services.PDF)
+	receiptHandler := handlers.NewReceiptHandler(services.Receipt)
+	quotationHandler := handlers.NewQuotationHandler(services.Quotation, services.PDF)
+	recurringBillHandler := handlers.NewRecurringBillHandler(services.Recurring)
+	promotionHandler := handlers.NewPromotionHandler(services.Promotion)
+	priceListHandler := handlers.NewPriceListHandler(services.PriceList)
+	loyaltyHandler := handlers.NewLoyaltyHandler(services.Loyalty)
+	expenseHandler := handlers.NewExpenseHandler(services.Expense)
+	reportHandler := handlers.NewReportHandler(services.Report)
+	gstHandler := handlers.NewGSTHandler(services.GST)
+	eInvoiceHandler := handlers.NewEInvoiceHandler(services.EInvoice)
+	eWayBillHandler := handlers.NewEWayBillHandler(services.EWayBill)
+	accountingHandler := handlers.NewAccountingHandler(services.Accounting)
+	bankStatementHandler := handlers.NewBankStatementHandler(services.BankReconciliation)
+	paymentLinkHandler := handlers.NewPaymentLinkHandler(services.PaymentLink)
+	emailHandler := handlers.NewEmailHandler(services.Email)
+	notificationHandler := handlers.NewNotificationHandler(services.Notification)
+	dunningHandler := handlers.NewDunningHandler(services.Dunning)
+	lateFeeHandler := handlers.NewLateFeeHandler(services.LateFee)
 
 	// API v1 routes
 	v1 := router.Group("/api/v1")
@@ -28,6 +50,13 @@ func SetupRoutes(router *gin.Engine, services *services.Services) {
 			auth.POST("/logout", middleware.AuthMiddleware(services.Auth), authHandler.Logout)
 		}
 
+		// Payment provider webhooks, verified by their signature
+		v1.POST("/webhooks/payments/:provider", paymentLinkHandler.HandleWebhook)
+
+		// SMS and WhatsApp delivery reports, verified by their signature
+		v1.GET("/webhooks/notifications/:provider", notificationHandler.VerifyWebhook)
+		v1.POST("/webhooks/notifications/:provider", notificationHandler.HandleDeliveryReport)
+
 		// Protected routes
 		protected := v1.Group("/")
 		protected.Use(middleware.AuthMiddleware(services.Auth))
@@ -55,10 +84,17 @@ func SetupRoutes(router *gin.Engine, services *services.Services) {
 					items.POST("/bulk", itemHandler.BulkCreateItems)
 					items.GET("/categories", itemHandler.GetCategories)
 					items.GET("/low-stock", itemHandler.GetLowStockItems)
+					items.GET("/lookup", itemHandler.LookupItem)
+					items.POST("/barcodes/generate", itemHandler.GenerateMissingBarcodes)
+					items.GET("/labels/sizes", labelHandler.GetLabelSizes)
+					items.POST("/labels", labelHandler.GenerateLabels)
 					items.GET("/:id", itemHandler.GetItem)
 					items.PUT("/:id", itemHandler.UpdateItem)
 					items.PUT("/:id/quantity", itemHandler.UpdateItemQuantity)
 					items.DELETE("/:id", itemHandler.DeleteItem)
+					items.GET("/:id/barcodes", itemHandler.GetItemBarcodes)
+					items.POST("/:id/barcodes", itemHandler.AddItemBarcode)
+					items.DELETE("/:id/barcodes/:barcodeId", itemHandler.DeleteItemBarcode)
 				}
 
 				// Customers
@@ -70,6 +106,9 @@ func SetupRoutes(router *gin.Engine, services *services.Services) {
 					customers.GET("/:customerId", customerHandler.GetCustomer)
 					customers.PUT("/:customerId", customerHandler.UpdateCustomer)
 					customers.DELETE("/:customerId", customerHandler.DeleteCustomer)
+					customers.GET("/:customerId/statement", customerHandler.GetStatement)
+					customers.GET("/:customerId/loyalty", loyaltyHandler.GetCustomerLoyalty)
+					customers.POST("/:customerId/loyalty/adjust", loyaltyHandler.AdjustPoints)
 				}
 
 				// Bills
@@ -82,7 +121,145 @@ func SetupRoutes(router *gin.Engine, services *services.Services) {
 					bills.PUT("/:billId", billHandler.UpdateBill)
 					bills.DELETE("/:billId", billHandler.DeleteBill)
 					bills.POST("/:billId/pdf", billHandler.GeneratePDF)
+					bills.POST("/:billId/ubl", billHandler.GenerateUBL)
+					bills.GET("/:billId/payment-links", paymentLinkHandler.GetPaymentLinks)
+					bills.POST("/:billId/payment-links", paymentLinkHandler.CreatePaymentLink)
+					bills.POST("/:billId/payment-links/:linkId/cancel", paymentLinkHandler.CancelPaymentLink)
+					bills.POST("/:billId/send", emailHandler.SendBill)
+					bills.GET("/:billId/emails", emailHandler.GetBillDeliveries)
+					bills.POST("/:billId/notify", notificationHandler.SendBill)
+					bills.GET("/:billId/notifications", notificationHandler.GetBillDeliveries)
+					bills.GET("/:billId/reminders", dunningHandler.GetBillReminders)
+					bills.PUT("/:billId/reminders", dunningHandler.SetBillExclusion)
+					bills.GET("/:billId/late-fees", lateFeeHandler.GetBillCharges)
+					bills.GET("/:billId/receipt", receiptHandler.GetReceipt)
+					bills.GET("/:billId/upi-qr", receiptHandler.GetUPIQR)
 					bills.POST("/:billId/payments", billHandler.AddPayment)
+					bills.POST("/:billId/issue", billHandler.IssueBill)
+					bills.POST("/:billId/cancel", billHandler.CancelBill)
+					bills.GET("/:billId/einvoice", eInvoiceHandler.GetEInvoice)
+					bills.GET("/:billId/einvoice/payload", eInvoiceHandler.GetPayload)
+					bills.POST("/:billId/einvoice", eInvoiceHandler.Generate)
+					bills.POST("/:billId/einvoice/cancel", eInvoiceHandler.Cancel)
+				}
+
+				// Point of sale
+				pos := shopRoutes.Group("/pos")
+				{
+					pos.POST("/sales", posHandler.QuickSale)
+				}
+
+				// Held carts
+				carts := shopRoutes.Group("/carts")
+				{
+					carts.GET("", cartHandler.GetCarts)
+					carts.POST("", cartHandler.CreateCart)
+					carts.GET("/:cartId", cartHandler.GetCart)
+					carts.PUT("/:cartId", cartHandler.UpdateCart)
+					carts.DELETE("/:cartId", cartHandler.CancelCart)
+					carts.POST("/:cartId/checkout", cartHandler.CheckoutCart)
+				}
+
+				// Cash register sessions
+				registers := shopRoutes.Group("/register-sessions")
+				{
+					registers.GET("", registerHandler.GetSessions)
+					registers.POST("", registerHandler.OpenSession)
+					registers.GET("/current", registerHandler.GetCurrentSession)
+					registers.GET("/:sessionId", registerHandler.GetSession)
+					registers.POST("/:sessionId/movements", registerHandler.AddMovement)
+					registers.POST("/:sessionId/close", registerHandler.CloseSession)
+					registers.GET("/:sessionId/report", registerHandler.GetReport)
+				}
+
+				// Quotations
+				quotations := shopRoutes.Group("/quotations")
+				{
+					quotations.GET("", quotationHandler.GetQuotations)
+					quotations.POST("", quotationHandler.CreateQuotation)
+					quotations.GET("/:quotationId", quotationHandler.GetQuotation)
+					quotations.PUT("/:quotationId", quotationHandler.UpdateQuotation)
+					quotations.DELETE("/:quotationId", quotationHandler.DeleteQuotation)
+					quotations.PUT("/:quotationId/status", quotationHandler.UpdateQuotationStatus)
+					quotations.POST("/:quotationId/convert", quotationHandler.ConvertToBill)
+					quotations.GET("/:quotationId/pdf", quotationHandler.GeneratePDF)
+				}
+
+				// Recurring bill templates
+				recurringBills := shopRoutes.Group("/recurring-bills")
+				{
+					recurringBills.GET("", recurringBillHandler.GetRecurringBills)
+					recurringBills.POST("", recurringBillHandler.CreateRecurringBill)
+					recurringBills.GET("/:recurringBillId", recurringBillHandler.GetRecurringBill)
+					recurringBills.PUT("/:recurringBillId", recurringBillHandler.UpdateRecurringBill)
+					recurringBills.DELETE("/:recurringBillId", recurringBillHandler.DeleteRecurringBill)
+					recurringBills.POST("/:recurringBillId/pause", recurringBillHandler.PauseRecurringBill)
+					recurringBills.POST("/:recurringBillId/resume", recurringBillHandler.ResumeRecurringBill)
+					recurringBills.GET("/:recurringBillId/preview", recurringBillHandler.PreviewRecurringBill)
+					recurringBills.GET("/:recurringBillId/runs", recurringBillHandler.GetRecurringBillRuns)
+				}
+
+				// Promotions and coupons
+				promotions := shopRoutes.Group("/promotions")
+				{
+					promotions.GET("", promotionHandler.GetPromotions)
+					promotions.POST("", promotionHandler.CreatePromotion)
+					promotions.POST("/evaluate", promotionHandler.EvaluatePromotions)
+					promotions.GET("/:promotionId", promotionHandler.GetPromotion)
+					promotions.PUT("/:promotionId", promotionHandler.UpdatePromotion)
+					promotions.DELETE("/:promotionId", promotionHandler.DeletePromotion)
+				}
+
+				// Loyalty program
+				loyalty := shopRoutes.Group("/loyalty")
+				{
+					loyalty.GET("/program", loyaltyHandler.GetProgram)
+					loyalty.PUT("/program", loyaltyHandler.UpdateProgram)
+				}
+
+				// Customer groups
+				customerGroups := shopRoutes.Group("/customer-groups")
+				{
+					customerGroups.GET("", priceListHandler.GetCustomerGroups)
+					customerGroups.POST("", priceListHandler.CreateCustomerGroup)
+					customerGroups.GET("/:groupId", priceListHandler.GetCustomerGroup)
+					customerGroups.PUT("/:groupId", priceListHandler.UpdateCustomerGroup)
+					customerGroups.DELETE("/:groupId", priceListHandler.DeleteCustomerGroup)
+				}
+
+				// Price lists
+				priceLists := shopRoutes.Group("/price-lists")
+				{
+					priceLists.GET("", priceListHandler.GetPriceLists)
+					priceLists.POST("", priceListHandler.CreatePriceList)
+					priceLists.POST("/resolve", priceListHandler.ResolvePrices)
+					priceLists.GET("/:priceListId", priceListHandler.GetPriceList)
+					priceLists.PUT("/:priceListId", priceListHandler.UpdatePriceList)
+					priceLists.DELETE("/:priceListId", priceListHandler.DeletePriceList)
+				}
+
+				// Expenses
+				expenses := shopRoutes.Group("/expenses")
+				{
+					expenses.GET("", expenseHandler.GetExpenses)
+					expenses.POST("", expenseHandler.CreateExpense)
+					expenses.GET("/categories", expenseHandler.GetExpenseCategories)
+					expenses.GET("/:expenseId", expenseHandler.GetExpense)
+					expenses.PUT("/:expenseId", expenseHandler.UpdateExpense)
+					expenses.DELETE("/:expenseId", expenseHandler.DeleteExpense)
+					expenses.POST("/:expenseId/attachments", expenseHandler.UploadAttachment)
+					expenses.GET("/:expenseId/attachments/:attachmentId", expenseHandler.DownloadAttachment)
+					expenses.DELETE("/:expenseId/attachments/:attachmentId", expenseHandler.DeleteAttachment)
+				}
+
+				// Recurring expenses
+				recurringExpenses := shopRoutes.Group("/recurring-expenses")
+				{
+					recurringExpenses.GET("", expenseHandler.GetRecurringExpenses)
+					recurringExpenses.POST("", expenseHandler.CreateRecurringExpense)
+					recurringExpenses.GET("/:recurringExpenseId", expenseHandler.GetRecurringExpense)
+					recurringExpenses.PUT("/:recurringExpenseId", expenseHandler.UpdateRecurringExpense)
+					recurringExpenses.DELETE("/:recurringExpenseId", expenseHandler.DeleteRecurringExpense)
 				}
 
 				// Analytics
@@ -91,6 +268,104 @@ func SetupRoutes(router *gin.Engine, services *services.Services) {
 					analytics.GET("/dashboard", shopHandler.GetDashboard)
 					analytics.GET("/sales", shopHandler.GetSalesAnalytics)
 					analytics.GET("/pending-amounts", shopHandler.GetPendingAmounts)
+					analytics.GET("/profit-loss", expenseHandler.GetProfitAndLoss)
+					analytics.GET("/profitability", reportHandler.GetProfitability)
+				}
+
+				// E-way bills
+				eWayBills := shopRoutes.Group("/eway-bills")
+				{
+					eWayBills.GET("", eWayBillHandler.GetEWayBills)
+					eWayBills.POST("", eWayBillHandler.CreateEWayBill)
+					eWayBills.GET("/export", eWayBillHandler.ExportBulkJSON)
+					eWayBills.GET("/:ewayBillId", eWayBillHandler.GetEWayBill)
+					eWayBills.PUT("/:ewayBillId", eWayBillHandler.UpdateEWayBill)
+					eWayBills.DELETE("/:ewayBillId", eWayBillHandler.DeleteEWayBill)
+					eWayBills.POST("/:ewayBillId/generated", eWayBillHandler.MarkGenerated)
+					eWayBills.POST("/:ewayBillId/vehicle", eWayBillHandler.UpdateVehicle)
+					eWayBills.POST("/:ewayBillId/cancel", eWayBillHandler.CancelEWayBill)
+				}
+
+				// GST returns
+				gst := shopRoutes.Group("/gst")
+				{
+					gst.GET("/gstr1", gstHandler.GetGSTR1)
+					gst.GET("/gstr3b", gstHandler.GetGSTR3B)
+				}
+
+				// Accounting export
+				accounting := shopRoutes.Group("/accounting")
+				{
+					accounting.GET("/ledgers", accountingHandler.GetLedgerMappings)
+					accounting.PUT("/ledgers", accountingHandler.UpdateLedgerMappings)
+					accounting.GET("/journal", accountingHandler.GetJournal)
+					accounting.GET("/exports", accountingHandler.GetExports)
+					accounting.POST("/exports", accountingHandler.ExportJournal)
+					accounting.DELETE("/exports/:exportId", accountingHandler.DeleteExport)
+				}
+
+				// Outbound email
+				email := shopRoutes.Group("/email")
+				{
+					email.GET("/settings", emailHandler.GetSettings)
+					email.PUT("/settings", emailHandler.UpdateSettings)
+					email.POST("/test", emailHandler.SendTestEmail)
+					email.GET("/templates", emailHandler.GetTemplates)
+					email.PUT("/templates/:event", emailHandler.UpdateTemplate)
+					email.DELETE("/templates/:event", emailHandler.ResetTemplate)
+					email.GET("/deliveries", emailHandler.GetDeliveries)
+					email.POST("/deliveries/:deliveryId/retry", emailHandler.RetryDelivery)
+				}
+
+				// SMS and WhatsApp notifications
+				notifications := shopRoutes.Group("/notifications")
+				{
+					notifications.GET("/templates", notificationHandler.GetTemplates)
+					notifications.PUT("/templates/:channel/:event", notificationHandler.UpdateTemplate)
+					notifications.DELETE("/templates/:channel/:event", notificationHandler.ResetTemplate)
+					notifications.POST("/test", notificationHandler.SendTestMessage)
+					notifications.GET("/deliveries", notificationHandler.GetDeliveries)
+					notifications.POST("/deliveries/:deliveryId/retry", notificationHandler.RetryDelivery)
+				}
+
+				// Payment reminders
+				dunning := shopRoutes.Group("/dunning")
+				{
+					dunning.GET("/steps", dunningHandler.GetSteps)
+					dunning.POST("/steps", dunningHandler.CreateStep)
+					dunning.PUT("/steps/:stepId", dunningHandler.UpdateStep)
+					dunning.DELETE("/steps/:stepId", dunningHandler.DeleteStep)
+					dunning.POST("/run", dunningHandler.RunReminders)
+					dunning.GET("/reminders", dunningHandler.GetReminders)
+				}
+
+				// Late fees
+				lateFees := shopRoutes.Group("/late-fees")
+				{
+					lateFees.GET("/policies", lateFeeHandler.GetPolicies)
+					lateFees.POST("/policies", lateFeeHandler.CreatePolicy)
+					lateFees.PUT("/policies/:policyId", lateFeeHandler.UpdatePolicy)
+					lateFees.DELETE("/policies/:policyId", lateFeeHandler.DeletePolicy)
+					lateFees.POST("/run", lateFeeHandler.RunLateFees)
+					lateFees.GET("/charges", lateFeeHandler.GetCharges)
+					lateFees.POST("/charges/:chargeId/waive", lateFeeHandler.WaiveCharge)
+				}
+
+				// Bank reconciliation
+				bankStatements := shopRoutes.Group("/bank-statements")
+				{
+					bankStatements.GET("", bankStatementHandler.GetStatements)
+					bankStatements.POST("", bankStatementHandler.ImportStatement)
+					bankStatements.GET("/:statementId", bankStatementHandler.GetStatement)
+					bankStatements.DELETE("/:statementId", bankStatementHandler.DeleteStatement)
+				}
+				bankLines := shopRoutes.Group("/bank-statement-lines")
+				{
+					bankLines.GET("", bankStatementHandler.GetLines)
+					bankLines.GET("/:lineId/suggestions", bankStatementHandler.SuggestMatches)
+					bankLines.POST("/:lineId/match", bankStatementHandler.MatchLine)
+					bankLines.POST("/:lineId/ignore", bankStatementHandler.IgnoreLine)
+					bankLines.POST("/:lineId/restore", bankStatementHandler.RestoreLine)
 				}
 			}
 		}
@@ -0,0 +1,814 @@
+package services
+
+import (
+	"billboard/backend/models"
+	"bytes"
+	"encoding/csv"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// AccountingService turns bills, payments and expenses into double-entry journal entries for
+// accounting software and keeps track of what has been exported
+type AccountingService struct {
+	db *gorm.DB
+}
+
+// NewAccountingService creates a new AccountingService instance
+func NewAccountingService(db *gorm.DB) *AccountingService {
+	return &AccountingService{db: db}
+}
+
+// ledgerAccount is an account used in journal entries with its default ledger name
+type ledgerAccount struct {
+	Account     string
+	Description string
+	Default     string
+}
+
+// ledgerAccounts are the accounts journal entries post to, besides customer and expense ledgers
+var ledgerAccounts = []ledgerAccount{
+	{"sales", "Taxable value of sales", "Sales"},
+	{"output_cgst", "Central tax collected on sales", "Output CGST"},
+	{"output_sgst", "State tax collected on sales", "Output SGST"},
+	{"output_igst", "Integrated tax collected on sales", "Output IGST"},
+	{"input_tax", "Tax paid on expenses", "Input GST"},
+	{"discount", "Bill discounts given after tax", "Discount Allowed"},
+	{"round_off", "Rounding differences", "Round Off"},
+	{"late_fee", "Late fees and interest charged on overdue bills", "Interest Received"},
+	{"walk_in_customer", "Party for bills without a customer", "Cash Customer"},
+	{"cash", "Cash payments", "Cash"},
+	{"card", "Card payments", "Card Receipts"},
+	{"upi", "UPI payments", "UPI Receipts"},
+	{"bank_transfer", "Bank transfers", "Bank"},
+	{"check", "Cheques", "Bank"},
+	{"loyalty_points", "Payments made with loyalty points", "Loyalty Redemption"},
+	{"other", "Other payment methods", "Suspense"},
+}
+
+// expenseLedgerPrefix prefixes expense categories used as mapping accounts
+const expenseLedgerPrefix = "expense:"
+
+// journalRoundOffTolerance is the largest difference between a voucher's debits and credits
+// that is posted to round off
+const journalRoundOffTolerance = 1.0
+
+// GetLedgerMappings returns the ledger used for each account, including one per expense category
+func (s *AccountingService) GetLedgerMappings(shopID, userID uuid.UUID) ([]models.LedgerMappingResponse, error) {
+	// Check if user has access to the shop
+	var shopUser models.ShopUser
+	if err := s.db.Where("shop_id = ? AND user_id = ? AND is_active = ?", shopID, userID, true).First(&shopUser).Error; err != nil {
+		return nil, errors.New("access denied to shop")
+	}
+
+	ledgers, err := s.loadLedgers(shopID)
+	if err != nil {
+		return nil, err
+	}
+
+	responses := make([]models.LedgerMappingResponse, 0, len(ledgerAccounts))
+	for _, account := range ledgerAccounts {
+		name, mapped := ledgers.mapped[account.Account]
+		if !mapped {
+			name = account.Default
+		}
+		responses = append(responses, models.LedgerMappingResponse{
+			Account:     account.Account,
+			Description: account.Description,
+			LedgerName:  name,
+			IsDefault:   !mapped,
+		})
+	}
+
+	// Expense categories the shop has used or mapped
+	var categories []string
+	if err := s.db.Model(&models.Expense{}).Where("shop_id = ?", shopID).Distinct().Pluck("category", &categories).Error; err != nil {
+		return nil, err
+	}
+	seen := map[string]bool{}
+	for _, category := range categories {
+		seen[expenseLedgerPrefix+category] = true
+	}
+	for account := range ledgers.mapped {
+		if strings.HasPrefix(account, expenseLedgerPrefix) {
+			seen[account] = true
+		}
+	}
+	expenseAccounts := make([]string, 0, len(seen))
+	for account := range seen {
+		expenseAccounts = append(expenseAccounts, account)
+	}
+	sort.Strings(expenseAccounts)
+	for _, account := range expenseAccounts {
+		name, mapped := ledgers.mapped[account]
+		if !mapped {
+			name = strings.TrimPrefix(account, expenseLedgerPrefix)
+		}
+		responses = append(responses, models.LedgerMappingResponse{
+			Account:     account,
+			Description: "Expenses in the " + strings.TrimPrefix(account, expenseLedgerPrefix) + " category",
+			LedgerName:  name,
+			IsDefault:   !mapped,
+		})
+	}
+
+	return responses, nil
+}
+
+// UpdateLedgerMappings sets the ledger names of accounts. An empty name resets the account to
+// its default ledger.
+func (s *AccountingService) UpdateLedgerMappings(shopID, userID uuid.UUID, req models.LedgerMappingRequest) ([]models.LedgerMappingResponse, error) {
+	// Check if user has access to the shop
+	var shopUser models.ShopUser
+	if err := s.db.Where("shop_id = ? AND user_id = ? AND is_active = ?", shopID, userID, true).First(&shopUser).Error; err != nil {
+		return nil, errors.New("access denied to shop")
+	}
+
+	known := map[string]bool{}
+	for _, account := range ledgerAccounts {
+		known[account.Account] = true
+	}
+	for account := range req.Mappings {
+		if !known[account] && (!strings.HasPrefix(account, expenseLedgerPrefix) || strings.TrimSpace(strings.TrimPrefix(account, expenseLedgerPrefix)) == "") {
+			return nil, fmt.Errorf("unknown account %q", account)
+		}
+	}
+
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		for account, name := range req.Mappings {
+			name = strings.TrimSpace(name)
+			if name == "" {
+				if err := tx.Where("shop_id = ? AND account = ?", shopID, account).Delete(&models.LedgerMapping{}).Error; err != nil {
+					return err
+				}
+				continue
+			}
+			var mapping models.LedgerMapping
+			err := tx.Where("shop_id = ? AND account = ?", shopID, account).First(&mapping).Error
+			if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+				return err
+			}
+			mapping.ShopID = shopID
+			mapping.Account = account
+			mapping.LedgerName = name
+			if err := tx.Save(&mapping).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return s.GetLedgerMappings(shopID, userID)
+}
+
+// GetJournal returns the journal entries for a date range without recording an export.
+// Documents exported before are left out unless includeExported is set.
+func (s *AccountingService) GetJournal(shopID, userID uuid.UUID, from, to string, includeExported bool) (*models.JournalResponse, error) {
+	// Check if user has access to the shop
+	var shopUser models.ShopUser
+	if err := s.db.Where("shop_id = ? AND user_id = ? AND is_active = ?", shopID, userID, true).First(&shopUser).Error; err != nil {
+		return nil, errors.New("access denied to shop")
+	}
+
+	fromDate, toDate, err := reportDateRange(from, to)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.buildJournal(shopID, fromDate, toDate, includeExported)
+}
+
+// ExportJournal builds the journal entries for a date range in Tally XML or CSV and records the
+// exported documents so later exports leave them out. It returns the export and the file.
+func (s *AccountingService) ExportJournal(shopID, userID uuid.UUID, req models.AccountingExportRequest) (*models.AccountingExportResponse, []byte, error) {
+	// Check if user has access to the shop
+	var shopUser models.ShopUser
+	if err := s.db.Where("shop_id = ? AND user_id = ? AND is_active = ?", shopID, userID, true).First(&shopUser).Error; err != nil {
+		return nil, nil, errors.New("access denied to shop")
+	}
+
+	fromDate, toDate, err := reportDateRange(req.From, req.To)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	journal, err := s.buildJournal(shopID, fromDate, toDate, req.IncludeExported)
+	if err != nil {
+		return nil, nil, err
+	}
+	if len(journal.Entries) == 0 {
+		return nil, nil, errors.New("nothing to export for this date range")
+	}
+
+	var content []byte
+	switch req.Format {
+	case "tally":
+		var shop models.Shop
+		if err := s.db.Select("id", "name").Where("id = ?", shopID).First(&shop).Error; err != nil {
+			return nil, nil, errors.New("shop not found")
+		}
+		content, err = journalTallyXML(shop.Name, journal.Entries)
+	default:
+		content, err = journalCSV(journal.Entries)
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+
+	export := models.AccountingExport{
+		ShopID:     shopID,
+		Format:     req.Format,
+		FromDate:   fromDate,
+		ToDate:     toDate,
+		EntryCount: len(journal.Entries),
+		CreatedBy:  userID.String(),
+	}
+	for _, entry := range journal.Entries {
+		switch entry.DocumentType {
+		case "bill":
+			export.BillCount++
+		case "payment":
+			export.PaymentCount++
+		case "expense":
+			export.ExpenseCount++
+		}
+	}
+
+	err = s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&export).Error; err != nil {
+			return err
+		}
+		// Documents exported again keep pointing at the export that first included them
+		var existing []models.AccountingExportDocument
+		if err := tx.Select("document_type", "document_id").Where("shop_id = ?", shopID).Find(&existing).Error; err != nil {
+			return err
+		}
+		recorded := make(map[string]bool, len(existing))
+		for _, document := range existing {
+			recorded[document.DocumentType+":"+document.DocumentID.String()] = true
+		}
+
+		documents := make([]models.AccountingExportDocument, 0, len(journal.Entries))
+		for _, entry := range journal.Entries {
+			if recorded[entry.DocumentType+":"+entry.DocumentID.String()] {
+				continue
+			}
+			documents = append(documents, models.AccountingExportDocument{
+				ExportID:     export.ID,
+				ShopID:       shopID,
+				DocumentType: entry.DocumentType,
+				DocumentID:   entry.DocumentID,
+			})
+		}
+		if len(documents) == 0 {
+			return nil
+		}
+		return tx.CreateInBatches(&documents, 500).Error
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return accountingExportToResponse(export), content, nil
+}
+
+// GetExports lists the shop's accounting exports, most recent first
+func (s *AccountingService) GetExports(shopID, userID uuid.UUID) ([]models.AccountingExportResponse, error) {
+	// Check if user has access to the shop
+	var shopUser models.ShopUser
+	if err := s.db.Where("shop_id = ? AND user_id = ? AND is_active = ?", shopID, userID, true).First(&shopUser).Error; err != nil {
+		return nil, errors.New("access denied to shop")
+	}
+
+	var exports []models.AccountingExport
+	if err := s.db.Where("shop_id = ?", shopID).Order("created_at DESC").Find(&exports).Error; err != nil {
+		return nil, err
+	}
+
+	responses := make([]models.AccountingExportResponse, len(exports))
+	for i, export := range exports {
+		responses[i] = *accountingExportToResponse(export)
+	}
+	return responses, nil
+}
+
+// DeleteExport forgets an export, so its documents are included in the next export again. Use
+// it when an export was never imported into the accounting software.
+func (s *AccountingService) DeleteExport(exportID, shopID, userID uuid.UUID) error {
+	// Check if user has access to the shop
+	var shopUser models.ShopUser
+	if err := s.db.Where("shop_id = ? AND user_id = ? AND is_active = ?", shopID, userID, true).First(&shopUser).Error; err != nil {
+		return errors.New("access denied to shop")
+	}
+
+	var export models.AccountingExport
+	if err := s.db.Where("id = ? AND shop_id = ?", exportID, shopID).First(&export).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return errors.New("export not found")
+		}
+		return err
+	}
+
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("export_id = ?", export.ID).Delete(&models.AccountingExportDocument{}).Error; err != nil {
+			return err
+		}
+		return tx.Delete(&export).Error
+	})
+}
+
+// shopLedgers resolves account names to the shop's ledger names
+type shopLedgers struct {
+	mapped map[string]string
+}
+
+// name returns the ledger for an account, falling back to its default
+func (l shopLedgers) name(account string) string {
+	if name, ok := l.mapped[account]; ok {
+		return name
+	}
+	if strings.HasPrefix(account, expenseLedgerPrefix) {
+		return strings.TrimPrefix(account, expenseLedgerPrefix)
+	}
+	for _, known := range ledgerAccounts {
+		if known.Account == account {
+			return known.Default
+		}
+	}
+	return l.name("other")
+}
+
+// loadLedgers loads the shop's ledger mappings
+func (s *AccountingService) loadLedgers(shopID uuid.UUID) (shopLedgers, error) {
+	var mappings []models.LedgerMapping
+	if err := s.db.Where("shop_id = ?", shopID).Find(&mappings).Error; err != nil {
+		return shopLedgers{}, err
+	}
+	ledgers := shopLedgers{mapped: make(map[string]string, len(mappings))}
+	for _, mapping := range mappings {
+		ledgers.mapped[mapping.Account] = mapping.LedgerName
+	}
+	return ledgers, nil
+}
+
+// buildJournal builds the journal entries for the bills, bill cancellations, payments, late fees
+// and expenses of a date range, in date order
+func (s *AccountingService) buildJournal(shopID uuid.UUID, fromDate, toDate time.Time, includeExported bool) (*models.JournalResponse, error) {
+	end := toDate.AddDate(0, 0, 1)
+
+	ledgers, err := s.loadLedgers(shopID)
+	if err != nil {
+		return nil, err
+	}
+
+	var shop models.Shop
+	if err := s.db.Select("id", "gst_number").Where("id = ?", shopID).First(&shop).Error; err != nil {
+		return nil, errors.New("shop not found")
+	}
+	shopState := ""
+	if gstin := strings.ToUpper(strings.TrimSpace(shop.GSTNumber)); validGSTIN(gstin) {
+		shopState = gstin[:2]
+	}
+
+	exported := map[string]bool{}
+	if !includeExported {
+		var documents []models.AccountingExportDocument
+		if err := s.db.Select("document_type", "document_id").Where("shop_id = ?", shopID).Find(&documents).Error; err != nil {
+			return nil, err
+		}
+		for _, document := range documents {
+			exported[document.DocumentType+":"+document.DocumentID.String()] = true
+		}
+	}
+
+	journal := &models.JournalResponse{From: fromDate, To: toDate, Entries: []models.JournalEntry{}}
+	add := func(entry models.JournalEntry) {
+		if exported[entry.DocumentType+":"+entry.DocumentID.String()] {
+			journal.AlreadyExported++
+			return
+		}
+		journal.Entries = append(journal.Entries, entry)
+	}
+
+	// Sales. Drafts can still be edited, so only issued bills are posted.
+	var bills []models.Bill
+	if err := s.db.Preload("Customer").Preload("Items").
+		Where("shop_id = ? AND status NOT IN ? AND bill_date >= ? AND bill_date < ?", shopID, []string{"draft", "cancelled"}, fromDate, end).
+		Order("bill_date ASC, bill_number ASC").
+		Find(&bills).Error; err != nil {
+		return nil, err
+	}
+	for _, bill := range bills {
+		entry, err := billJournalEntry(bill, shopState, ledgers)
+		if err != nil {
+			return nil, err
+		}
+		add(entry)
+	}
+
+	// Cancellations of bills already exported, which the accounting software still has as sales.
+	// Bills cancelled before the cancellation date was kept use their last update.
+	var cancelled []models.Bill
+	if err := s.db.Preload("Customer").Preload("Items").
+		Where("shop_id = ? AND status = ? AND COALESCE(cancelled_at, updated_at) >= ? AND COALESCE(cancelled_at, updated_at) < ?", shopID, "cancelled", fromDate, end).
+		Where("id IN (?)", s.db.Model(&models.AccountingExportDocument{}).Select("document_id").Where("shop_id = ? AND document_type = ?", shopID, "bill")).
+		Order("bill_number ASC").
+		Find(&cancelled).Error; err != nil {
+		return nil, err
+	}
+	for _, bill := range cancelled {
+		entry, err := billCancellationJournalEntry(bill, shopState, ledgers)
+		if err != nil {
+			return nil, err
+		}
+		add(entry)
+	}
+
+	// Receipts
+	var payments []models.Payment
+	if err := s.db.Preload("Bill").Preload("Bill.Customer").
+		Joins("JOIN bills ON bills.id = payments.bill_id").
+		Where("bills.shop_id = ? AND bills.deleted_at IS NULL AND bills.status <> ? AND payments.payment_date >= ? AND payments.payment_date < ?",
+			shopID, "cancelled", fromDate, end).
+		Order("payments.payment_date ASC, payments.created_at ASC").
+		Find(&payments).Error; err != nil {
+		return nil, err
+	}
+	for _, payment := range payments {
+		add(paymentJournalEntry(payment, ledgers))
+	}
+
+	// Late fees, and the waivers of late fees charged before
+	var charges []models.LateFeeCharge
+	if err := s.db.Preload("Bill").Preload("Bill.Customer").Preload("Bill.Items").
+		Where("shop_id = ? AND ((charge_date >= ? AND charge_date < ?) OR (status = ? AND waived_at >= ? AND waived_at < ?))",
+			shopID, fromDate, end, "waived", fromDate, end).
+		Order("charge_date ASC, note_number ASC").
+		Find(&charges).Error; err != nil {
+		return nil, err
+	}
+	for _, charge := range charges {
+		if !charge.ChargeDate.Before(fromDate) && charge.ChargeDate.Before(end) {
+			add(lateFeeJournalEntry(charge, shopState, ledgers))
+		}
+		if charge.Status == "waived" && charge.WaivedAt != nil && !charge.WaivedAt.Before(fromDate) && charge.WaivedAt.Before(end) {
+			add(lateFeeWaiverJournalEntry(charge, shopState, ledgers))
+		}
+	}
+
+	// Expenses
+	var expenses []models.Expense
+	if err := s.db.Where("shop_id = ? AND expense_date >= ? AND expense_date <= ?", shopID, fromDate, toDate).
+		Order("expense_date ASC, created_at ASC").
+		Find(&expenses).Error; err != nil {
+		return nil, err
+	}
+	for _, expense := range expenses {
+		entry, err := expenseJournalEntry(expense, ledgers)
+		if err != nil {
+			return nil, err
+		}
+		add(entry)
+	}
+
+	sort.SliceStable(journal.Entries, func(i, j int) bool {
+		return journal.Entries[i].Date.Before(journal.Entries[j].Date)
+	})
+	for _, entry := range journal.Entries {
+		for _, line := range entry.Lines {
+			journal.TotalDebit += line.Debit
+			journal.TotalCredit += line.Credit
+		}
+	}
+	journal.TotalDebit = roundAmount(journal.TotalDebit)
+	journal.TotalCredit = roundAmount(journal.TotalCredit)
+
+	return journal, nil
+}
+
+// billPartyLedger returns the party ledger a bill is posted to
+func billPartyLedger(bill models.Bill, ledgers shopLedgers) string {
+	if bill.Customer != nil && strings.TrimSpace(bill.Customer.Name) != "" {
+		return strings.TrimSpace(bill.Customer.Name)
+	}
+	return ledgers.name("walk_in_customer")
+}
+
+// billJournalEntry posts a bill as a sales voucher: the customer is debited with the bill total
+// and sales and output tax are credited. Bill discounts and rounding balance the voucher.
+func billJournalEntry(bill models.Bill, shopState string, ledgers shopLedgers) (models.JournalEntry, error) {
+	party := billPartyLedger(bill, ledgers)
+
+	interState := false
+	if shopState != "" && bill.Customer != nil {
+		if gstin := strings.ToUpper(strings.TrimSpace(bill.Customer.TaxNumber)); validGSTIN(gstin) {
+			interState = gstin[:2] != shopState
+		} else if code, ok := gstStateCodes[strings.ToLower(strings.TrimSpace(bill.Customer.State))]; ok {
+			interState = code != shopState
+		}
+	}
+
+	var taxable, cgst, sgst, igst float64
+	for _, line := range bill.Items {
+		supply := gstSplitLine(line, interState)
+		taxable += supply.Taxable
+		cgst += supply.CGST
+		sgst += supply.SGST
+		igst += supply.IGST
+	}
+	// Bills from before tax was kept per line only carry the tax on the bill
+	if cgst == 0 && sgst == 0 && igst == 0 && bill.TaxAmount != 0 {
+		if interState {
+			igst = bill.TaxAmount
+		} else {
+			cgst = roundAmount(bill.TaxAmount / 2)
+			sgst = roundAmount(bill.TaxAmount - cgst)
+		}
+	}
+
+	entry := models.JournalEntry{
+		Date:          bill.BillDate,
+		VoucherType:   "Sales",
+		VoucherNumber: bill.BillNumber,
+		PartyLedger:   party,
+		Narration:     "Bill " + bill.BillNumber,
+		DocumentType:  "bill",
+		DocumentID:    bill.ID,
+	}
+	entry.Lines = appendJournalLine(entry.Lines, party, bill.TotalAmount)
+	entry.Lines = appendJournalLine(entry.Lines, ledgers.name("discount"), bill.Discount)
+	entry.Lines = appendJournalLine(entry.Lines, ledgers.name("sales"), -taxable)
+	entry.Lines = appendJournalLine(entry.Lines, ledgers.name("output_cgst"), -cgst)
+	entry.Lines = appendJournalLine(entry.Lines, ledgers.name("output_sgst"), -sgst)
+	entry.Lines = appendJournalLine(entry.Lines, ledgers.name("output_igst"), -igst)
+	return balanceJournalEntry(entry, ledgers)
+}
+
+// billCancellationJournalEntry reverses the sales voucher of a cancelled bill with a credit note
+// on the day it was cancelled
+func billCancellationJournalEntry(bill models.Bill, shopState string, ledgers shopLedgers) (models.JournalEntry, error) {
+	entry, err := billJournalEntry(bill, shopState, ledgers)
+	if err != nil {
+		return entry, err
+	}
+
+	cancelledAt := bill.UpdatedAt
+	if bill.CancelledAt != nil {
+		cancelledAt = *bill.CancelledAt
+	}
+	entry.Date = lateFeeDate(cancelledAt)
+	entry.VoucherType = "Credit Note"
+	entry.Narration = "Bill " + bill.BillNumber + " cancelled"
+	entry.DocumentType = "bill_cancellation"
+	for i := range entry.Lines {
+		entry.Lines[i].Debit, entry.Lines[i].Credit = entry.Lines[i].Credit, entry.Lines[i].Debit
+	}
+	return entry, nil
+}
+
+// paymentJournalEntry posts a payment as a receipt voucher: the ledger of the payment method is
+// debited and the customer credited
+func paymentJournalEntry(payment models.Payment, ledgers shopLedgers) models.JournalEntry {
+	party := billPartyLedger(payment.Bill, ledgers)
+	narration := "Payment for bill " + payment.Bill.BillNumber
+	if payment.Reference != "" {
+		narration += ", ref " + payment.Reference
+	}
+
+	entry := models.JournalEntry{
+		Date:          payment.PaymentDate,
+		VoucherType:   "Receipt",
+		VoucherNumber: payment.Bill.BillNumber,
+		PartyLedger:   party,
+		Narration:     narration,
+		DocumentType:  "payment",
+		DocumentID:    payment.ID,
+	}
+	entry.Lines = appendJournalLine(entry.Lines, ledgers.name(payment.PaymentMethod), payment.Amount)
+	entry.Lines = appendJournalLine(entry.Lines, party, -payment.Amount)
+	return entry
+}
+
+// lateFeeJournalEntry posts a late fee as a debit note: the customer is debited, and late fee
+// income and the output tax included in the fee are credited
+func lateFeeJournalEntry(charge models.LateFeeCharge, shopState string, ledgers shopLedgers) models.JournalEntry {
+	party := billPartyLedger(charge.Bill, ledgers)
+
+	entry := models.JournalEntry{
+		Date:          charge.ChargeDate,
+		VoucherType:   "Debit Note",
+		VoucherNumber: charge.NoteNumber,
+		PartyLedger:   party,
+		Narration:     lateFeeChargeDescription(charge),
+		DocumentType:  "late_fee",
+		DocumentID:    charge.ID,
+	}
+	entry.Lines = appendJournalLine(entry.Lines, party, charge.Amount)
+	entry.Lines = appendLateFeeLines(entry.Lines, charge, shopState, ledgers, -1)
+	return entry
+}
+
+// lateFeeWaiverJournalEntry reverses a waived late fee with a credit note on the day it was
+// waived
+func lateFeeWaiverJournalEntry(charge models.LateFeeCharge, shopState string, ledgers shopLedgers) models.JournalEntry {
+	party := billPartyLedger(charge.Bill, ledgers)
+	narration := "Late fee " + charge.NoteNumber + " waived"
+	if charge.WaiveReason != "" {
+		narration += ": " + charge.WaiveReason
+	}
+
+	entry := models.JournalEntry{
+		Date:          lateFeeDate(*charge.WaivedAt),
+		VoucherType:   "Credit Note",
+		VoucherNumber: charge.NoteNumber,
+		PartyLedger:   party,
+		Narration:     narration,
+		DocumentType:  "late_fee_waiver",
+		DocumentID:    charge.ID,
+	}
+	entry.Lines = appendLateFeeLines(entry.Lines, charge, shopState, ledgers, 1)
+	entry.Lines = appendJournalLine(entry.Lines, party, -charge.Amount)
+	return entry
+}
+
+// appendLateFeeLines posts a late fee to late fee income and output tax, split over the tax rates
+// of its bill the way GST returns report it. Sign is -1 to credit the ledgers and 1 to debit them.
+func appendLateFeeLines(lines []models.JournalLine, charge models.LateFeeCharge, shopState string, ledgers shopLedgers, sign float64) []models.JournalLine {
+	b := classifyGSTBill(charge.Bill, shopState)
+	if shopState == "" {
+		b.InterState = false
+	}
+	var taxable, cgst, sgst, igst float64
+	for _, supply := range gstLateFeeSupplies(charge.Amount, b) {
+		taxable += supply.Taxable
+		cgst += supply.CGST
+		sgst += supply.SGST
+		igst += supply.IGST
+	}
+	lines = appendJournalLine(lines, ledgers.name("late_fee"), sign*taxable)
+	lines = appendJournalLine(lines, ledgers.name("output_cgst"), sign*cgst)
+	lines = appendJournalLine(lines, ledgers.name("output_sgst"), sign*sgst)
+	return appendJournalLine(lines, ledgers.name("output_igst"), sign*igst)
+}
+
+// expenseJournalEntry posts an expense as a payment voucher: the expense category and input tax
+// are debited and the ledger of the payment mode credited
+func expenseJournalEntry(expense models.Expense, ledgers shopLedgers) (models.JournalEntry, error) {
+	narration := expense.Category
+	if expense.Description != "" {
+		narration += ": " + expense.Description
+	}
+	if expense.Vendor != "" {
+		narration += " (" + expense.Vendor + ")"
+	}
+
+	entry := models.JournalEntry{
+		Date:          expense.ExpenseDate,
+		VoucherType:   "Payment",
+		VoucherNumber: expense.Reference,
+		Narration:     narration,
+		DocumentType:  "expense",
+		DocumentID:    expense.ID,
+	}
+	entry.Lines = appendJournalLine(entry.Lines, ledgers.name(expenseLedgerPrefix+expense.Category), expense.Amount)
+	entry.Lines = appendJournalLine(entry.Lines, ledgers.name("input_tax"), expense.TaxAmount)
+	entry.Lines = appendJournalLine(entry.Lines, ledgers.name(expense.PaymentMode), -expense.TotalAmount)
+	return balanceJournalEntry(entry, ledgers)
+}
+
+// appendJournalLine adds a posting to a ledger, debiting positive amounts and crediting negative
+// ones. Zero amounts are skipped and postings to a ledger already on the entry are combined.
+func appendJournalLine(lines []models.JournalLine, ledger string, amount float64) []models.JournalLine {
+	amount = roundAmount(amount)
+	if amount == 0 {
+		return lines
+	}
+	for i := range lines {
+		if lines[i].Ledger == ledger {
+			net := roundAmount(lines[i].Debit - lines[i].Credit + amount)
+			lines[i].Debit, lines[i].Credit = 0, 0
+			if net > 0 {
+				lines[i].Debit = net
+			} else {
+				lines[i].Credit = -net
+			}
+			return lines
+		}
+	}
+	line := models.JournalLine{Ledger: ledger}
+	if amount > 0 {
+		line.Debit = amount
+	} else {
+		line.Credit = -amount
+	}
+	return append(lines, line)
+}
+
+// balanceJournalEntry posts the rounding difference between debits and credits to the round off
+// ledger. Larger differences mean the document's totals are wrong and are refused rather than
+// hidden in round off.
+func balanceJournalEntry(entry models.JournalEntry, ledgers shopLedgers) (models.JournalEntry, error) {
+	var difference float64
+	for _, line := range entry.Lines {
+		difference += line.Debit - line.Credit
+	}
+	difference = roundAmount(difference)
+	if math.Abs(difference) > journalRoundOffTolerance {
+		return entry, fmt.Errorf("%s voucher %s does not balance: debits and credits differ by %.2f",
+			strings.ToLower(entry.VoucherType), entry.VoucherNumber, difference)
+	}
+	entry.Lines = appendJournalLine(entry.Lines, ledgers.name("round_off"), -difference)
+	return entry, nil
+}
+
+// journalTallyXML renders journal entries as Tally vouchers for import
+func journalTallyXML(company string, entries []models.JournalEntry) ([]byte, error) {
+	envelope := models.TallyEnvelope{
+		TallyRequest: "Import Data",
+		ReportName:   "Vouchers",
+		Company:      company,
+	}
+	for _, entry := range entries {
+		voucher := models.TallyVoucher{
+			RemoteID:        entry.DocumentType + "-" + entry.DocumentID.String(),
+			VchType:         entry.VoucherType,
+			Action:          "Create",
+			Date:            entry.Date.Format("20060102"),
+			VoucherTypeName: entry.VoucherType,
+			VoucherNumber:   entry.VoucherNumber,
+			PartyLedgerName: entry.PartyLedger,
+			Narration:       entry.Narration,
+		}
+		for _, line := range entry.Lines {
+			ledgerEntry := models.TallyLedgerEntry{LedgerName: line.Ledger}
+			if line.Debit > 0 {
+				ledgerEntry.IsDeemedPositive = "Yes"
+				ledgerEntry.Amount = fmt.Sprintf("%.2f", -line.Debit)
+			} else {
+				ledgerEntry.IsDeemedPositive = "No"
+				ledgerEntry.Amount = fmt.Sprintf("%.2f", line.Credit)
+			}
+			voucher.LedgerEntries = append(voucher.LedgerEntries, ledgerEntry)
+		}
+		envelope.Messages = append(envelope.Messages, models.TallyMessage{UDF: "TallyUDF", Voucher: voucher})
+	}
+
+	content, err := xml.MarshalIndent(envelope, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return append([]byte(xml.Header), content...), nil
+}
+
+// journalCSV renders journal entries as a CSV journal with one row per ledger posting
+func journalCSV(entries []models.JournalEntry) ([]byte, error) {
+	var buf bytes.Buffer
+	writer := csv.NewWriter(&buf)
+	records := [][]string{{"Date", "Voucher Type", "Voucher Number", "Document Type", "Document ID", "Ledger", "Debit", "Credit", "Narration"}}
+	for _, entry := range entries {
+		for _, line := range entry.Lines {
+			debit, credit := "", ""
+			if line.Debit > 0 {
+				debit = fmt.Sprintf("%.2f", line.Debit)
+			}
+			if line.Credit > 0 {
+				credit = fmt.Sprintf("%.2f", line.Credit)
+			}
+			records = append(records, []string{
+				entry.Date.Format("2006-01-02"), entry.VoucherType, entry.VoucherNumber, entry.DocumentType,
+				entry.DocumentID.String(), line.Ledger, debit, credit, entry.Narration,
+			})
+		}
+	}
+	if err := writer.WriteAll(records); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// accountingExportToResponse converts an accounting export to its response
+func accountingExportToResponse(export models.AccountingExport) *models.AccountingExportResponse {
+	return &models.AccountingExportResponse{
+		ID:           export.ID,
+		Format:       export.Format,
+		FromDate:     export.FromDate,
+		ToDate:       export.ToDate,
+		EntryCount:   export.EntryCount,
+		BillCount:    export.BillCount,
+		PaymentCount: export.PaymentCount,
+		ExpenseCount: export.ExpenseCount,
+		CreatedBy:    export.CreatedBy,
+		CreatedAt:    export.CreatedAt,
+	}
+}
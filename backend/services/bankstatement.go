@@ -0,0 +1,964 @@
+package services
+
+import (
+	"billboard/backend/models"
+	"bytes"
+	"crypto/sha256"
+	"encoding/csv"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// maxStatementSize is the largest bank statement file accepted, in bytes
+const maxStatementSize = 5 << 20
+
+// BankReconciliationService imports bank statements and reconciles their credits with bills
+type BankReconciliationService struct {
+	db *gorm.DB
+}
+
+// NewBankReconciliationService creates a new BankReconciliationService instance
+func NewBankReconciliationService(db *gorm.DB) *BankReconciliationService {
+	return &BankReconciliationService{db: db}
+}
+
+// bankTransaction is a transaction read from a statement file
+type bankTransaction struct {
+	Date        time.Time
+	Amount      float64
+	Description string
+	Reference   string
+}
+
+// parsedStatement is the content of a statement file
+type parsedStatement struct {
+	AccountNumber string
+	Transactions  []bankTransaction
+}
+
+// ImportStatement reads a CSV, OFX or MT940 statement and stores its transactions. Transactions
+// already imported from an earlier, overlapping statement are skipped. An empty format is
+// detected from the file.
+func (s *BankReconciliationService) ImportStatement(shopID, userID uuid.UUID, fileName, format string, data []byte, mapping *models.BankCSVMapping) (*models.BankStatementResponse, error) {
+	// Check if user has access to the shop
+	var shopUser models.ShopUser
+	if err := s.db.Where("shop_id = ? AND user_id = ? AND is_active = ?", shopID, userID, true).First(&shopUser).Error; err != nil {
+		return nil, errors.New("access denied to shop")
+	}
+
+	if len(data) == 0 {
+		return nil, errors.New("statement file is empty")
+	}
+	if len(data) > maxStatementSize {
+		return nil, errors.New("statement file must be 5 MB or smaller")
+	}
+
+	if format == "" {
+		format = detectStatementFormat(fileName, data)
+	}
+
+	var parsed *parsedStatement
+	var err error
+	switch format {
+	case "csv":
+		if mapping == nil {
+			return nil, errors.New("CSV statements need a column mapping")
+		}
+		parsed, err = parseCSVStatement(data, *mapping)
+	case "ofx":
+		parsed, err = parseOFXStatement(data)
+	case "mt940":
+		parsed, err = parseMT940Statement(data)
+	default:
+		return nil, errors.New("statement format must be csv, ofx or mt940")
+	}
+	if err != nil {
+		return nil, err
+	}
+	if len(parsed.Transactions) == 0 {
+		return nil, errors.New("no transactions found in the statement")
+	}
+
+	statement := models.BankStatement{
+		ShopID:        shopID,
+		FileName:      fileName,
+		Format:        format,
+		AccountNumber: parsed.AccountNumber,
+		CreatedBy:     userID.String(),
+	}
+
+	// Identical transactions in one file are told apart by their position among themselves, so
+	// importing the same file again gives the same fingerprints
+	occurrences := map[string]int{}
+	lines := make([]models.BankStatementLine, 0, len(parsed.Transactions))
+	for _, txn := range parsed.Transactions {
+		key := fmt.Sprintf("%s|%s|%.2f|%s|%s", parsed.AccountNumber, txn.Date.Format("2006-01-02"), txn.Amount,
+			strings.ToUpper(strings.TrimSpace(txn.Reference)), strings.ToUpper(strings.TrimSpace(txn.Description)))
+		occurrences[key]++
+		sum := sha256.Sum256([]byte(fmt.Sprintf("%s|%d", key, occurrences[key])))
+
+		lines = append(lines, models.BankStatementLine{
+			ShopID:          shopID,
+			TransactionDate: txn.Date,
+			Amount:          roundAmount(txn.Amount),
+			Description:     strings.TrimSpace(txn.Description),
+			Reference:       strings.TrimSpace(txn.Reference),
+			Fingerprint:     hex.EncodeToString(sum[:]),
+			Status:          "unreconciled",
+		})
+
+		date := txn.Date
+		if statement.PeriodStart == nil || date.Before(*statement.PeriodStart) {
+			statement.PeriodStart = &date
+		}
+		if statement.PeriodEnd == nil || date.After(*statement.PeriodEnd) {
+			statement.PeriodEnd = &date
+		}
+	}
+
+	err = s.db.Transaction(func(tx *gorm.DB) error {
+		fingerprints := make([]string, len(lines))
+		for i, line := range lines {
+			fingerprints[i] = line.Fingerprint
+		}
+		var existing []string
+		if err := tx.Model(&models.BankStatementLine{}).
+			Where("shop_id = ? AND fingerprint IN ?", shopID, fingerprints).
+			Pluck("fingerprint", &existing).Error; err != nil {
+			return err
+		}
+		seen := make(map[string]bool, len(existing))
+		for _, fingerprint := range existing {
+			seen[fingerprint] = true
+		}
+
+		fresh := make([]models.BankStatementLine, 0, len(lines))
+		for _, line := range lines {
+			if seen[line.Fingerprint] {
+				statement.DuplicateCount++
+				continue
+			}
+			fresh = append(fresh, line)
+		}
+		statement.LineCount = len(fresh)
+
+		if err := tx.Create(&statement).Error; err != nil {
+			return err
+		}
+		if len(fresh) == 0 {
+			return nil
+		}
+		for i := range fresh {
+			fresh[i].StatementID = statement.ID
+		}
+		return tx.CreateInBatches(&fresh, 500).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return s.GetStatement(statement.ID, shopID, userID)
+}
+
+// GetStatements lists the shop's imported statements, most recent first
+func (s *BankReconciliationService) GetStatements(shopID, userID uuid.UUID) ([]models.BankStatementResponse, error) {
+	// Check if user has access to the shop
+	var shopUser models.ShopUser
+	if err := s.db.Where("shop_id = ? AND user_id = ? AND is_active = ?", shopID, userID, true).First(&shopUser).Error; err != nil {
+		return nil, errors.New("access denied to shop")
+	}
+
+	var statements []models.BankStatement
+	if err := s.db.Where("shop_id = ?", shopID).Order("created_at DESC").Find(&statements).Error; err != nil {
+		return nil, err
+	}
+
+	var counts []struct {
+		StatementID uuid.UUID
+		Count       int
+	}
+	if err := s.db.Model(&models.BankStatementLine{}).
+		Select("statement_id, COUNT(*) AS count").
+		Where("shop_id = ? AND status = ?", shopID, "unreconciled").
+		Group("statement_id").
+		Scan(&counts).Error; err != nil {
+		return nil, err
+	}
+	unreconciled := make(map[uuid.UUID]int, len(counts))
+	for _, count := range counts {
+		unreconciled[count.StatementID] = count.Count
+	}
+
+	responses := make([]models.BankStatementResponse, len(statements))
+	for i, statement := range statements {
+		responses[i] = bankStatementToResponse(statement)
+		responses[i].UnreconciledCount = unreconciled[statement.ID]
+	}
+	return responses, nil
+}
+
+// GetStatement returns an imported statement with its lines
+func (s *BankReconciliationService) GetStatement(statementID, shopID, userID uuid.UUID) (*models.BankStatementResponse, error) {
+	// Check if user has access to the shop
+	var shopUser models.ShopUser
+	if err := s.db.Where("shop_id = ? AND user_id = ? AND is_active = ?", shopID, userID, true).First(&shopUser).Error; err != nil {
+		return nil, errors.New("access denied to shop")
+	}
+
+	var statement models.BankStatement
+	if err := s.db.Preload("Lines", func(db *gorm.DB) *gorm.DB {
+		return db.Order("transaction_date ASC, created_at ASC")
+	}).Where("id = ? AND shop_id = ?", statementID, shopID).First(&statement).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("bank statement not found")
+		}
+		return nil, err
+	}
+
+	lines, err := s.linesToResponse(statement.Lines)
+	if err != nil {
+		return nil, err
+	}
+
+	response := bankStatementToResponse(statement)
+	response.Lines = lines
+	for _, line := range statement.Lines {
+		if line.Status == "unreconciled" {
+			response.UnreconciledCount++
+		}
+	}
+	return &response, nil
+}
+
+// DeleteStatement removes an imported statement and its lines. Statements with reconciled lines
+// are kept, since their payments refer to them.
+func (s *BankReconciliationService) DeleteStatement(statementID, shopID, userID uuid.UUID) error {
+	// Check if user has access to the shop
+	var shopUser models.ShopUser
+	if err := s.db.Where("shop_id = ? AND user_id = ? AND is_active = ?", shopID, userID, true).First(&shopUser).Error; err != nil {
+		return errors.New("access denied to shop")
+	}
+
+	var statement models.BankStatement
+	if err := s.db.Where("id = ? AND shop_id = ?", statementID, shopID).First(&statement).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return errors.New("bank statement not found")
+		}
+		return err
+	}
+
+	var reconciled int64
+	if err := s.db.Model(&models.BankStatementLine{}).Where("statement_id = ? AND status = ?", statement.ID, "reconciled").Count(&reconciled).Error; err != nil {
+		return err
+	}
+	if reconciled > 0 {
+		return errors.New("cannot delete a statement with reconciled lines")
+	}
+
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("statement_id = ?", statement.ID).Delete(&models.BankStatementLine{}).Error; err != nil {
+			return err
+		}
+		return tx.Delete(&statement).Error
+	})
+}
+
+// GetLines lists statement lines across statements, filtered by status and statement. With no
+// status filter, unreconciled lines are listed.
+func (s *BankReconciliationService) GetLines(shopID, userID uuid.UUID, filters map[string]interface{}) ([]models.BankStatementLineResponse, error) {
+	// Check if user has access to the shop
+	var shopUser models.ShopUser
+	if err := s.db.Where("shop_id = ? AND user_id = ? AND is_active = ?", shopID, userID, true).First(&shopUser).Error; err != nil {
+		return nil, errors.New("access denied to shop")
+	}
+
+	query := s.db.Where("shop_id = ?", shopID)
+	status, ok := filters["status"].(string)
+	if !ok || status == "" {
+		status = "unreconciled"
+	}
+	if status != "all" {
+		query = query.Where("status = ?", status)
+	}
+	if statementID, ok := filters["statement_id"].(uuid.UUID); ok {
+		query = query.Where("statement_id = ?", statementID)
+	}
+	if credits, ok := filters["credits_only"].(bool); ok && credits {
+		query = query.Where("amount > 0")
+	}
+
+	var lines []models.BankStatementLine
+	if err := query.Order("transaction_date DESC, created_at ASC").Find(&lines).Error; err != nil {
+		return nil, err
+	}
+
+	return s.linesToResponse(lines)
+}
+
+// SuggestMatches ranks the shop's open bills by how likely a credit is to be their payment,
+// looking at the amount, bill numbers in the reference and the customer's name and phone
+func (s *BankReconciliationService) SuggestMatches(lineID, shopID, userID uuid.UUID) ([]models.BankMatchSuggestion, error) {
+	// Check if user has access to the shop
+	var shopUser models.ShopUser
+	if err := s.db.Where("shop_id = ? AND user_id = ? AND is_active = ?", shopID, userID, true).First(&shopUser).Error; err != nil {
+		return nil, errors.New("access denied to shop")
+	}
+
+	line, err := s.findLine(lineID, shopID)
+	if err != nil {
+		return nil, err
+	}
+	if line.Amount <= 0 {
+		return nil, errors.New("only credits can be matched to bills")
+	}
+
+	var bills []models.Bill
+	if err := s.db.Preload("Customer").
+		Where("shop_id = ? AND status <> ? AND balance > 0", shopID, "cancelled").
+		Order("bill_date ASC").
+		Find(&bills).Error; err != nil {
+		return nil, err
+	}
+
+	suggestions := []models.BankMatchSuggestion{}
+	for _, bill := range bills {
+		suggestion := scoreBankMatch(*line, bill)
+		if suggestion.Score >= 20 {
+			suggestions = append(suggestions, suggestion)
+		}
+	}
+	sort.SliceStable(suggestions, func(i, j int) bool {
+		return suggestions[i].Score > suggestions[j].Score
+	})
+	if len(suggestions) > 10 {
+		suggestions = suggestions[:10]
+	}
+
+	return suggestions, nil
+}
+
+// MatchLine reconciles a credit with a bill by recording it as a payment on the bill, the way
+// payments taken at the counter are recorded
+func (s *BankReconciliationService) MatchLine(lineID, shopID, userID uuid.UUID, req models.BankMatchRequest) (*models.BankStatementLineResponse, error) {
+	// Check if user has access to the shop
+	var shopUser models.ShopUser
+	if err := s.db.Where("shop_id = ? AND user_id = ? AND is_active = ?", shopID, userID, true).First(&shopUser).Error; err != nil {
+		return nil, errors.New("access denied to shop")
+	}
+
+	line, err := s.findLine(lineID, shopID)
+	if err != nil {
+		return nil, err
+	}
+	if line.Amount <= 0 {
+		return nil, errors.New("only credits can be matched to bills")
+	}
+
+	method := req.PaymentMethod
+	if method == "" {
+		method = "bank_transfer"
+		if strings.Contains(strings.ToUpper(line.Description+" "+line.Reference), "UPI") {
+			method = "upi"
+		}
+	}
+	reference := line.Reference
+	if reference == "" {
+		reference = truncateRunes(line.Description, 100)
+	}
+
+	err = s.db.Transaction(func(tx *gorm.DB) error {
+		// Claim the line first so two users cannot record the same credit twice
+		now := time.Now()
+		result := tx.Model(&models.BankStatementLine{}).
+			Where("id = ? AND status = ?", line.ID, "unreconciled").
+			Updates(map[string]interface{}{"status": "reconciled", "reconciled_by": userID.String(), "reconciled_at": now, "updated_at": now})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return errors.New("statement line is not unreconciled")
+		}
+
+		// Bank credits never pass through a till, so they stay out of register sessions
+		payment, err := (&BillService{db: tx}).recordPayment(req.BillID, shopID, userID, models.PaymentRequest{
+			Amount:        line.Amount,
+			PaymentDate:   line.TransactionDate.Format("2006-01-02"),
+			PaymentMethod: method,
+			Reference:     reference,
+			Notes:         req.Notes,
+		}, false)
+		if err != nil {
+			return err
+		}
+
+		return tx.Model(&models.BankStatementLine{}).Where("id = ?", line.ID).
+			Updates(map[string]interface{}{"bill_id": req.BillID, "payment_id": payment.ID}).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return s.getLineResponse(line.ID, shopID)
+}
+
+// IgnoreLine marks a line that is not a customer payment, such as bank charges or interest, so
+// it no longer shows as unreconciled
+func (s *BankReconciliationService) IgnoreLine(lineID, shopID, userID uuid.UUID) (*models.BankStatementLineResponse, error) {
+	// Check if user has access to the shop
+	var shopUser models.ShopUser
+	if err := s.db.Where("shop_id = ? AND user_id = ? AND is_active = ?", shopID, userID, true).First(&shopUser).Error; err != nil {
+		return nil, errors.New("access denied to shop")
+	}
+
+	line, err := s.findLine(lineID, shopID)
+	if err != nil {
+		return nil, err
+	}
+	if line.Status != "unreconciled" {
+		return nil, errors.New("only unreconciled lines can be ignored")
+	}
+
+	now := time.Now()
+	if err := s.db.Model(line).Updates(map[string]interface{}{"status": "ignored", "reconciled_by": userID.String(), "reconciled_at": now}).Error; err != nil {
+		return nil, err
+	}
+
+	return s.getLineResponse(line.ID, shopID)
+}
+
+// RestoreLine returns an ignored line to the unreconciled lines
+func (s *BankReconciliationService) RestoreLine(lineID, shopID, userID uuid.UUID) (*models.BankStatementLineResponse, error) {
+	// Check if user has access to the shop
+	var shopUser models.ShopUser
+	if err := s.db.Where("shop_id = ? AND user_id = ? AND is_active = ?", shopID, userID, true).First(&shopUser).Error; err != nil {
+		return nil, errors.New("access denied to shop")
+	}
+
+	line, err := s.findLine(lineID, shopID)
+	if err != nil {
+		return nil, err
+	}
+	if line.Status != "ignored" {
+		return nil, errors.New("only ignored lines can be restored")
+	}
+
+	if err := s.db.Model(line).Updates(map[string]interface{}{"status": "unreconciled", "reconciled_by": "", "reconciled_at": nil}).Error; err != nil {
+		return nil, err
+	}
+
+	return s.getLineResponse(line.ID, shopID)
+}
+
+// findLine loads a statement line of the shop
+func (s *BankReconciliationService) findLine(lineID, shopID uuid.UUID) (*models.BankStatementLine, error) {
+	var line models.BankStatementLine
+	if err := s.db.Where("id = ? AND shop_id = ?", lineID, shopID).First(&line).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("statement line not found")
+		}
+		return nil, err
+	}
+	return &line, nil
+}
+
+// getLineResponse loads a statement line as a response
+func (s *BankReconciliationService) getLineResponse(lineID, shopID uuid.UUID) (*models.BankStatementLineResponse, error) {
+	line, err := s.findLine(lineID, shopID)
+	if err != nil {
+		return nil, err
+	}
+	responses, err := s.linesToResponse([]models.BankStatementLine{*line})
+	if err != nil {
+		return nil, err
+	}
+	return &responses[0], nil
+}
+
+// linesToResponse converts statement lines to responses with the numbers of their bills
+func (s *BankReconciliationService) linesToResponse(lines []models.BankStatementLine) ([]models.BankStatementLineResponse, error) {
+	var billIDs []uuid.UUID
+	for _, line := range lines {
+		if line.BillID != nil {
+			billIDs = append(billIDs, *line.BillID)
+		}
+	}
+	billNumbers := map[uuid.UUID]string{}
+	if len(billIDs) > 0 {
+		var bills []models.Bill
+		if err := s.db.Select("id", "bill_number").Where("id IN ?", billIDs).Find(&bills).Error; err != nil {
+			return nil, err
+		}
+		for _, bill := range bills {
+			billNumbers[bill.ID] = bill.BillNumber
+		}
+	}
+
+	responses := make([]models.BankStatementLineResponse, len(lines))
+	for i, line := range lines {
+		responses[i] = models.BankStatementLineResponse{
+			ID:              line.ID,
+			StatementID:     line.StatementID,
+			TransactionDate: line.TransactionDate,
+			Amount:          line.Amount,
+			Description:     line.Description,
+			Reference:       line.Reference,
+			Status:          line.Status,
+			BillID:          line.BillID,
+			PaymentID:       line.PaymentID,
+			ReconciledBy:    line.ReconciledBy,
+			ReconciledAt:    line.ReconciledAt,
+		}
+		if line.BillID != nil {
+			responses[i].BillNumber = billNumbers[*line.BillID]
+		}
+	}
+	return responses, nil
+}
+
+// bankStatementToResponse converts a statement to its response, without lines
+func bankStatementToResponse(statement models.BankStatement) models.BankStatementResponse {
+	return models.BankStatementResponse{
+		ID:             statement.ID,
+		FileName:       statement.FileName,
+		Format:         statement.Format,
+		AccountNumber:  statement.AccountNumber,
+		PeriodStart:    statement.PeriodStart,
+		PeriodEnd:      statement.PeriodEnd,
+		LineCount:      statement.LineCount,
+		DuplicateCount: statement.DuplicateCount,
+		CreatedBy:      statement.CreatedBy,
+		CreatedAt:      statement.CreatedAt,
+	}
+}
+
+// nonAlphanumeric matches runs of characters that are not letters or digits
+var nonAlphanumeric = regexp.MustCompile(`[^A-Z0-9]+`)
+
+// scoreBankMatch scores how likely a credit is to be the payment of a bill
+func scoreBankMatch(line models.BankStatementLine, bill models.Bill) models.BankMatchSuggestion {
+	suggestion := models.BankMatchSuggestion{
+		BillID:      bill.ID,
+		BillNumber:  bill.BillNumber,
+		BillDate:    bill.BillDate,
+		TotalAmount: bill.TotalAmount,
+		Balance:     bill.Balance,
+		Reasons:     []string{},
+	}
+	if bill.Customer != nil {
+		suggestion.CustomerName = bill.Customer.Name
+	}
+
+	text := strings.ToUpper(line.Description + " " + line.Reference)
+	// Compare bill numbers without separators, since banks often drop them from references
+	compact := nonAlphanumeric.ReplaceAllString(text, "")
+	words := " " + nonAlphanumeric.ReplaceAllString(text, " ") + " "
+
+	score := 0
+	if billNumber := nonAlphanumeric.ReplaceAllString(strings.ToUpper(bill.BillNumber), ""); billNumber != "" && strings.Contains(compact, billNumber) {
+		score += 50
+		suggestion.Reasons = append(suggestion.Reasons, "bill number in the reference")
+	}
+
+	amount := roundAmount(line.Amount)
+	switch {
+	case amount == roundAmount(bill.Balance):
+		score += 35
+		suggestion.Reasons = append(suggestion.Reasons, "amount equals the bill balance")
+	case amount == roundAmount(bill.TotalAmount):
+		score += 20
+		suggestion.Reasons = append(suggestion.Reasons, "amount equals the bill total")
+	case amount < bill.Balance:
+		score += 5
+		suggestion.Reasons = append(suggestion.Reasons, "amount is less than the bill balance")
+	}
+
+	if bill.Customer != nil {
+		var tokens, found int
+		for _, token := range strings.Fields(nonAlphanumeric.ReplaceAllString(strings.ToUpper(bill.Customer.Name), " ")) {
+			if len(token) < 3 {
+				continue
+			}
+			tokens++
+			if strings.Contains(words, " "+token+" ") {
+				found++
+			}
+		}
+		if tokens > 0 && found == tokens {
+			score += 25
+			suggestion.Reasons = append(suggestion.Reasons, "customer name in the description")
+		} else if found > 0 {
+			score += 10
+			suggestion.Reasons = append(suggestion.Reasons, "part of the customer name in the description")
+		}
+
+		// UPI handles are often the payer's phone number
+		phone := nonDigits.ReplaceAllString(bill.Customer.Phone, "")
+		if len(phone) >= 10 && strings.Contains(compact, phone[len(phone)-10:]) {
+			score += 15
+			suggestion.Reasons = append(suggestion.Reasons, "customer phone in the description")
+		}
+	}
+
+	// Payments rarely come before the bill
+	if line.TransactionDate.Before(bill.BillDate.Truncate(24 * time.Hour)) {
+		score -= 15
+	}
+
+	if score > 100 {
+		score = 100
+	}
+	if score < 0 {
+		score = 0
+	}
+	suggestion.Score = score
+	return suggestion
+}
+
+// nonDigits matches runs of characters that are not digits
+var nonDigits = regexp.MustCompile(`\D+`)
+
+// detectStatementFormat guesses a statement's format from its name and content
+func detectStatementFormat(fileName string, data []byte) string {
+	name := strings.ToLower(fileName)
+	head := strings.ToUpper(string(data[:min(len(data), 2048)]))
+	switch {
+	case strings.HasSuffix(name, ".ofx") || strings.HasSuffix(name, ".qfx") || strings.Contains(head, "<OFX>") || strings.Contains(head, "OFXHEADER"):
+		return "ofx"
+	case strings.HasSuffix(name, ".sta") || strings.HasSuffix(name, ".mt940") || (strings.Contains(head, ":20:") && strings.Contains(head, ":61:")):
+		return "mt940"
+	case strings.HasSuffix(name, ".csv"):
+		return "csv"
+	}
+	return ""
+}
+
+// parseCSVStatement reads a CSV statement using a column mapping
+func parseCSVStatement(data []byte, mapping models.BankCSVMapping) (*parsedStatement, error) {
+	reader := csv.NewReader(bytes.NewReader(bytes.TrimPrefix(data, []byte("\xef\xbb\xbf"))))
+	reader.FieldsPerRecord = -1
+	reader.LazyQuotes = true
+	reader.TrimLeadingSpace = true
+	records, err := reader.ReadAll()
+	if err != nil {
+		return nil, fmt.Errorf("invalid CSV: %v", err)
+	}
+	if mapping.SkipRows < 0 || mapping.SkipRows >= len(records) {
+		return nil, errors.New("CSV has no header row")
+	}
+	records = records[mapping.SkipRows:]
+	header := records[0]
+
+	column := func(name string) (int, error) {
+		name = strings.TrimSpace(name)
+		if name == "" {
+			return -1, nil
+		}
+		if position, err := strconv.Atoi(name); err == nil {
+			if position < 1 || position > len(header) {
+				return -1, fmt.Errorf("column %d is not in the CSV", position)
+			}
+			return position - 1, nil
+		}
+		for i, heading := range header {
+			if strings.EqualFold(strings.TrimSpace(heading), name) {
+				return i, nil
+			}
+		}
+		return -1, fmt.Errorf("column %q is not in the CSV", name)
+	}
+
+	dateCol, err := column(mapping.Date)
+	if err != nil {
+		return nil, err
+	}
+	if dateCol < 0 {
+		return nil, errors.New("date column is required")
+	}
+	amountCol, err := column(mapping.Amount)
+	if err != nil {
+		return nil, err
+	}
+	creditCol, err := column(mapping.Credit)
+	if err != nil {
+		return nil, err
+	}
+	debitCol, err := column(mapping.Debit)
+	if err != nil {
+		return nil, err
+	}
+	if amountCol < 0 && creditCol < 0 {
+		return nil, errors.New("an amount column or a credit column is required")
+	}
+	descriptionCol, err := column(mapping.Description)
+	if err != nil {
+		return nil, err
+	}
+	referenceCol, err := column(mapping.Reference)
+	if err != nil {
+		return nil, err
+	}
+
+	field := func(record []string, col int) string {
+		if col < 0 || col >= len(record) {
+			return ""
+		}
+		return strings.TrimSpace(record[col])
+	}
+
+	statement := &parsedStatement{}
+	for i, record := range records[1:] {
+		row := mapping.SkipRows + i + 2
+		dateText := field(record, dateCol)
+		if dateText == "" {
+			// Banks end their exports with blank or summary rows
+			continue
+		}
+		date, err := parseStatementDate(dateText, mapping.DateFormat)
+		if err != nil {
+			return nil, fmt.Errorf("row %d: %v", row, err)
+		}
+
+		var amount float64
+		if amountCol >= 0 {
+			if amount, err = parseStatementAmount(field(record, amountCol)); err != nil {
+				return nil, fmt.Errorf("row %d: %v", row, err)
+			}
+		} else {
+			credit, err := parseStatementAmount(field(record, creditCol))
+			if err != nil {
+				return nil, fmt.Errorf("row %d: %v", row, err)
+			}
+			debit, err := parseStatementAmount(field(record, debitCol))
+			if err != nil {
+				return nil, fmt.Errorf("row %d: %v", row, err)
+			}
+			amount = credit - debit
+		}
+		if amount == 0 {
+			continue
+		}
+
+		statement.Transactions = append(statement.Transactions, bankTransaction{
+			Date:        date,
+			Amount:      amount,
+			Description: field(record, descriptionCol),
+			Reference:   field(record, referenceCol),
+		})
+	}
+
+	return statement, nil
+}
+
+// statementDateFormats are the date formats tried when a CSV mapping gives none. Day-first
+// formats come before month-first ones, as Indian banks write dates that way.
+var statementDateFormats = []string{
+	"2006-01-02", "02/01/2006", "02-01-2006", "02.01.2006", "02/01/06", "02-01-06",
+	"02-Jan-2006", "02 Jan 2006", "2 Jan 2006", "02-Jan-06", "Jan 2, 2006", "2006-01-02 15:04:05",
+	"02/01/2006 15:04:05", "02/01/2006 15:04",
+}
+
+// parseStatementDate parses a statement date with the given layout or the common formats
+func parseStatementDate(text, layout string) (time.Time, error) {
+	if layout != "" {
+		date, err := time.Parse(layout, text)
+		if err != nil {
+			return time.Time{}, fmt.Errorf("date %q does not match the format %q", text, layout)
+		}
+		return date.Truncate(24 * time.Hour), nil
+	}
+	for _, format := range statementDateFormats {
+		if date, err := time.Parse(format, text); err == nil {
+			return date.Truncate(24 * time.Hour), nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("unrecognised date %q", text)
+}
+
+// parseStatementAmount parses a statement amount, allowing thousands separators, currency
+// symbols, Cr/Dr suffixes and brackets for negative amounts. Blank amounts are zero.
+func parseStatementAmount(text string) (float64, error) {
+	original := text
+	text = strings.ToUpper(strings.TrimSpace(text))
+	if text == "" || text == "-" {
+		return 0, nil
+	}
+
+	sign := 1.0
+	switch {
+	case strings.HasSuffix(text, "DR"):
+		sign = -1
+		text = strings.TrimSuffix(text, "DR")
+	case strings.HasSuffix(text, "CR"):
+		text = strings.TrimSuffix(text, "CR")
+	}
+	if strings.HasPrefix(text, "(") && strings.HasSuffix(text, ")") {
+		sign = -sign
+		text = strings.Trim(text, "()")
+	}
+	text = strings.NewReplacer(",", "", "₹", "", "INR", "", "RS.", "", " ", "").Replace(text)
+	text = strings.TrimSuffix(text, ".")
+
+	amount, err := strconv.ParseFloat(text, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid amount %q", original)
+	}
+	return sign * amount, nil
+}
+
+// ofxValue returns the value of an OFX element, which in SGML files has no closing tag
+func ofxValue(block, tag string) string {
+	pattern := regexp.MustCompile(`(?is)<` + tag + `>([^<\r\n]*)`)
+	if match := pattern.FindStringSubmatch(block); match != nil {
+		return strings.TrimSpace(match[1])
+	}
+	return ""
+}
+
+// parseOFXStatement reads the transactions of an OFX or QFX statement
+func parseOFXStatement(data []byte) (*parsedStatement, error) {
+	content := string(data)
+	if !strings.Contains(strings.ToUpper(content), "<OFX>") {
+		return nil, errors.New("file is not an OFX statement")
+	}
+
+	statement := &parsedStatement{AccountNumber: ofxValue(content, "ACCTID")}
+
+	// Transactions are closed explicitly in XML files; SGML files run on to the next one
+	body := content
+	for {
+		start := strings.Index(strings.ToUpper(body), "<STMTTRN>")
+		if start < 0 {
+			break
+		}
+		body = body[start+len("<STMTTRN>"):]
+		end := len(body)
+		upper := strings.ToUpper(body)
+		for _, marker := range []string{"</STMTTRN>", "<STMTTRN>", "</BANKTRANLIST>"} {
+			if i := strings.Index(upper, marker); i >= 0 && i < end {
+				end = i
+			}
+		}
+		block := body[:end]
+
+		posted := ofxValue(block, "DTPOSTED")
+		if len(posted) < 8 {
+			return nil, errors.New("OFX transaction without a posting date")
+		}
+		date, err := time.Parse("20060102", posted[:8])
+		if err != nil {
+			return nil, fmt.Errorf("invalid OFX date %q", posted)
+		}
+		amount, err := strconv.ParseFloat(strings.ReplaceAll(ofxValue(block, "TRNAMT"), ",", "."), 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid OFX amount %q", ofxValue(block, "TRNAMT"))
+		}
+
+		description := strings.TrimSpace(ofxValue(block, "NAME") + " " + ofxValue(block, "MEMO"))
+		reference := ofxValue(block, "REFNUM")
+		if reference == "" {
+			reference = ofxValue(block, "CHECKNUM")
+		}
+		if reference == "" {
+			reference = ofxValue(block, "FITID")
+		}
+
+		statement.Transactions = append(statement.Transactions, bankTransaction{
+			Date:        date,
+			Amount:      amount,
+			Description: description,
+			Reference:   reference,
+		})
+		body = body[end:]
+	}
+
+	return statement, nil
+}
+
+// mt940Line matches the statement line field (:61:) of an MT940 statement: value date, optional
+// entry date, debit/credit mark, optional funds code, amount, transaction type, the account
+// owner's reference and the bank's reference
+var mt940Line = regexp.MustCompile(`^(\d{6})(\d{4})?(RC|RD|C|D)([A-Z])?(\d+,\d{0,2})([NSF][A-Z0-9]{3})([^/\n]{0,16})(?://([^\n]{0,16}))?`)
+
+// parseMT940Statement reads the transactions of a SWIFT MT940 statement
+func parseMT940Statement(data []byte) (*parsedStatement, error) {
+	// Gather the fields, joining continuation lines to the field they belong to
+	type field struct{ Tag, Value string }
+	var fields []field
+	for _, raw := range strings.Split(strings.ReplaceAll(string(data), "\r\n", "\n"), "\n") {
+		line := strings.TrimRight(raw, "\r ")
+		if len(line) > 3 && line[0] == ':' {
+			if end := strings.Index(line[1:], ":"); end > 0 {
+				fields = append(fields, field{Tag: line[1 : end+1], Value: line[end+2:]})
+				continue
+			}
+		}
+		if line == "-" || line == "" || strings.HasPrefix(line, "{") {
+			continue
+		}
+		if len(fields) > 0 {
+			fields[len(fields)-1].Value += "\n" + line
+		}
+	}
+	if len(fields) == 0 {
+		return nil, errors.New("file is not an MT940 statement")
+	}
+
+	statement := &parsedStatement{}
+	for _, f := range fields {
+		switch f.Tag {
+		case "25":
+			statement.AccountNumber = strings.TrimSpace(f.Value)
+		case "61":
+			match := mt940Line.FindStringSubmatch(f.Value)
+			if match == nil {
+				return nil, fmt.Errorf("invalid MT940 statement line %q", strings.SplitN(f.Value, "\n", 2)[0])
+			}
+			date, err := time.Parse("060102", match[1])
+			if err != nil {
+				return nil, fmt.Errorf("invalid MT940 date %q", match[1])
+			}
+			amount, err := strconv.ParseFloat(strings.Replace(match[5], ",", ".", 1), 64)
+			if err != nil {
+				return nil, fmt.Errorf("invalid MT940 amount %q", match[5])
+			}
+			// Debits and reversed credits take money out of the account
+			if match[3] == "D" || match[3] == "RC" {
+				amount = -amount
+			}
+
+			reference := strings.TrimSpace(match[7])
+			if reference == "" || reference == "NONREF" {
+				reference = strings.TrimSpace(match[8])
+			}
+			description := ""
+			if parts := strings.SplitN(f.Value, "\n", 2); len(parts) == 2 {
+				description = strings.TrimSpace(parts[1])
+			}
+
+			statement.Transactions = append(statement.Transactions, bankTransaction{
+				Date:        date,
+				Amount:      amount,
+				Description: description,
+				Reference:   reference,
+			})
+		case "86":
+			// Information to account owner describes the statement line before it
+			if n := len(statement.Transactions); n > 0 {
+				info := strings.Join(strings.Fields(strings.ReplaceAll(f.Value, "\n", "")), " ")
+				txn := &statement.Transactions[n-1]
+				txn.Description = strings.TrimSpace(txn.Description + " " + info)
+			}
+		}
+	}
+
+	return statement, nil
+}
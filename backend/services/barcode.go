@@ -0,0 +1,358 @@
+package services
+
+import (
+	"billboard/backend/models"
+	"errors"
+	"fmt"
+	"math/rand"
+	"strings"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+const (
+	BarcodeTypeEAN13   = "ean13"
+	BarcodeTypeCode128 = "code128"
+
+	// In-store EAN-13 numbers use the 20-29 prefix range reserved for
+	// restricted circulation, so generated codes never clash with GTINs.
+	generatedBarcodePrefix = "20"
+)
+
+// ean13CheckDigit calculates the EAN-13 check digit for the first 12 digits
+func ean13CheckDigit(code string) (int, error) {
+	if len(code) != 12 {
+		return 0, errors.New("EAN-13 check digit requires 12 digits")
+	}
+
+	sum := 0
+	for i, r := range code {
+		if r < '0' || r > '9' {
+			return 0, errors.New("EAN-13 barcodes may only contain digits")
+		}
+		digit := int(r - '0')
+		if i%2 == 1 {
+			digit *= 3
+		}
+		sum += digit
+	}
+
+	return (10 - sum%10) % 10, nil
+}
+
+// detectBarcodeType guesses the symbology of a barcode when none is given
+func detectBarcodeType(code string) string {
+	if len(code) == 13 && strings.Trim(code, "0123456789") == "" {
+		return BarcodeTypeEAN13
+	}
+	return BarcodeTypeCode128
+}
+
+// validateBarcode checks that a barcode is well formed for its symbology
+func validateBarcode(code, barcodeType string) error {
+	switch barcodeType {
+	case BarcodeTypeEAN13:
+		if len(code) != 13 {
+			return errors.New("EAN-13 barcode must be 13 digits")
+		}
+		checkDigit, err := ean13CheckDigit(code[:12])
+		if err != nil {
+			return err
+		}
+		if int(code[12]-'0') != checkDigit {
+			return fmt.Errorf("invalid EAN-13 check digit, expected %d", checkDigit)
+		}
+	case BarcodeTypeCode128:
+		if code == "" || len(code) > 48 {
+			return errors.New("Code128 barcode must be between 1 and 48 characters")
+		}
+		for _, r := range code {
+			if r < 32 || r > 126 {
+				return errors.New("Code128 barcode may only contain printable ASCII characters")
+			}
+		}
+	default:
+		return errors.New("unsupported barcode type")
+	}
+
+	return nil
+}
+
+// prepareBarcode normalizes, validates and checks uniqueness of an item barcode.
+// Items without a barcode are assigned a generated in-store EAN-13.
+func (s *ItemService) prepareBarcode(tx *gorm.DB, shopID uuid.UUID, itemID *uuid.UUID, code, barcodeType string) (string, string, error) {
+	code = strings.TrimSpace(code)
+	barcodeType = strings.ToLower(strings.TrimSpace(barcodeType))
+
+	if code == "" {
+		generated, err := s.generateBarcode(tx, shopID)
+		if err != nil {
+			return "", "", err
+		}
+		return generated, BarcodeTypeEAN13, nil
+	}
+
+	if barcodeType == "" {
+		barcodeType = detectBarcodeType(code)
+	}
+
+	if err := validateBarcode(code, barcodeType); err != nil {
+		return "", "", err
+	}
+
+	inUse, err := barcodeInUse(tx, shopID, code, itemID)
+	if err != nil {
+		return "", "", err
+	}
+	if inUse {
+		return "", "", errors.New("barcode already exists")
+	}
+
+	return code, barcodeType, nil
+}
+
+// barcodeError reports a barcode taken by a concurrent request, which the unique index on the
+// shop's barcodes rejects after barcodeInUse passed, as the same error barcodeInUse leads to
+func barcodeError(err error) error {
+	if isUniqueViolation(err) {
+		return errors.New("barcode already exists")
+	}
+	return err
+}
+
+// barcodeInUse reports whether a code is already used as a primary or alternate
+// barcode in the shop, ignoring the primary barcode of excludeItemID
+func barcodeInUse(tx *gorm.DB, shopID uuid.UUID, code string, excludeItemID *uuid.UUID) (bool, error) {
+	query := tx.Model(&models.Item{}).Where("shop_id = ? AND barcode = ?", shopID, code)
+	if excludeItemID != nil {
+		query = query.Where("id != ?", *excludeItemID)
+	}
+	var count int64
+	if err := query.Count(&count).Error; err != nil {
+		return false, err
+	}
+	if count > 0 {
+		return true, nil
+	}
+
+	if err := tx.Model(&models.ItemBarcode{}).Where("shop_id = ? AND barcode = ?", shopID, code).Count(&count).Error; err != nil {
+		return false, err
+	}
+	return count > 0, nil
+}
+
+// generateBarcode generates an EAN-13 barcode that is unique within the shop
+func (s *ItemService) generateBarcode(tx *gorm.DB, shopID uuid.UUID) (string, error) {
+	for attempt := 0; attempt < 10; attempt++ {
+		body := fmt.Sprintf("%s%010d", generatedBarcodePrefix, rand.Int63n(10000000000))
+		checkDigit, err := ean13CheckDigit(body)
+		if err != nil {
+			return "", err
+		}
+		code := fmt.Sprintf("%s%d", body, checkDigit)
+
+		inUse, err := barcodeInUse(tx, shopID, code, nil)
+		if err != nil {
+			return "", err
+		}
+		if !inUse {
+			return code, nil
+		}
+	}
+
+	return "", errors.New("failed to generate a unique barcode")
+}
+
+// GenerateMissingBarcodes assigns generated barcodes to all items of a shop that have none
+func (s *ItemService) GenerateMissingBarcodes(shopID uuid.UUID) ([]models.ItemResponse, error) {
+	var items []models.Item
+	if err := s.db.Where("shop_id = ? AND (barcode IS NULL OR barcode = '')", shopID).Find(&items).Error; err != nil {
+		return nil, err
+	}
+
+	var responses []models.ItemResponse
+	for _, item := range items {
+		code, err := s.generateBarcode(s.db, shopID)
+		if err != nil {
+			return nil, err
+		}
+
+		item.Barcode = code
+		item.BarcodeType = BarcodeTypeEAN13
+		if err := s.db.Model(&item).Updates(map[string]interface{}{
+			"barcode":      item.Barcode,
+			"barcode_type": item.BarcodeType,
+		}).Error; err != nil {
+			return nil, err
+		}
+
+		responses = append(responses, s.itemToResponse(item))
+	}
+
+	return responses, nil
+}
+
+// GetItemBarcodes retrieves the alternate barcodes of an item
+func (s *ItemService) GetItemBarcodes(shopID, itemID uuid.UUID) ([]models.ItemBarcodeResponse, error) {
+	var barcodes []models.ItemBarcode
+	if err := s.db.Where("shop_id = ? AND item_id = ?", shopID, itemID).Order("created_at").Find(&barcodes).Error; err != nil {
+		return nil, err
+	}
+
+	var responses []models.ItemBarcodeResponse
+	for _, barcode := range barcodes {
+		responses = append(responses, s.itemBarcodeToResponse(barcode))
+	}
+
+	return responses, nil
+}
+
+// AddItemBarcode adds an alternate barcode for a variant or pack of an item
+func (s *ItemService) AddItemBarcode(shopID, itemID uuid.UUID, req models.ItemBarcodeRequest) (*models.ItemBarcodeResponse, error) {
+	var item models.Item
+	if err := s.db.Where("shop_id = ? AND id = ?", shopID, itemID).First(&item).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("item not found")
+		}
+		return nil, err
+	}
+
+	code := strings.TrimSpace(req.Barcode)
+	barcodeType := strings.ToLower(strings.TrimSpace(req.BarcodeType))
+	if barcodeType == "" {
+		barcodeType = detectBarcodeType(code)
+	}
+	if err := validateBarcode(code, barcodeType); err != nil {
+		return nil, err
+	}
+
+	inUse, err := barcodeInUse(s.db, shopID, code, nil)
+	if err != nil {
+		return nil, err
+	}
+	if inUse {
+		return nil, errors.New("barcode already exists")
+	}
+
+	if req.UnitQuantity <= 0 {
+		req.UnitQuantity = 1
+	}
+	if req.Price < 0 {
+		return nil, errors.New("price cannot be negative")
+	}
+	if req.Unit == "" {
+		req.Unit = item.Unit
+	}
+
+	barcode := models.ItemBarcode{
+		ShopID:       shopID,
+		ItemID:       itemID,
+		Barcode:      code,
+		BarcodeType:  barcodeType,
+		Variant:      req.Variant,
+		Unit:         req.Unit,
+		UnitQuantity: req.UnitQuantity,
+		Price:        req.Price,
+	}
+
+	if err := s.db.Create(&barcode).Error; err != nil {
+		return nil, barcodeError(err)
+	}
+
+	response := s.itemBarcodeToResponse(barcode)
+	return &response, nil
+}
+
+// DeleteItemBarcode removes an alternate barcode from an item
+func (s *ItemService) DeleteItemBarcode(shopID, itemID, barcodeID uuid.UUID) error {
+	result := s.db.Where("id = ? AND shop_id = ? AND item_id = ?", barcodeID, shopID, itemID).Delete(&models.ItemBarcode{})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return errors.New("barcode not found")
+	}
+	return nil
+}
+
+// LookupItem finds an active item by exact barcode, SKU or alternate barcode
+func (s *ItemService) LookupItem(shopID uuid.UUID, code string) (*models.ItemLookupResponse, error) {
+	code = strings.TrimSpace(code)
+	if code == "" {
+		return nil, errors.New("code is required")
+	}
+
+	var item models.Item
+	err := s.db.Where("shop_id = ? AND is_active = ? AND barcode = ?", shopID, true, code).First(&item).Error
+	if err == nil {
+		return s.lookupResponse(item, code, "barcode", nil), nil
+	}
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, err
+	}
+
+	err = s.db.Where("shop_id = ? AND is_active = ? AND sku = ?", shopID, true, code).First(&item).Error
+	if err == nil {
+		return s.lookupResponse(item, code, "sku", nil), nil
+	}
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, err
+	}
+
+	var barcode models.ItemBarcode
+	if err := s.db.Preload("Item").
+		Joins("JOIN items ON items.id = item_barcodes.item_id AND items.deleted_at IS NULL AND items.is_active = ?", true).
+		Where("item_barcodes.shop_id = ? AND item_barcodes.barcode = ?", shopID, code).
+		First(&barcode).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("item not found")
+		}
+		return nil, err
+	}
+
+	return s.lookupResponse(barcode.Item, code, "alternate_barcode", &barcode), nil
+}
+
+// lookupResponse builds a lookup result, applying the pack size and price of an alternate barcode
+func (s *ItemService) lookupResponse(item models.Item, code, matchedBy string, barcode *models.ItemBarcode) *models.ItemLookupResponse {
+	response := &models.ItemLookupResponse{
+		Item:         s.itemToResponse(item),
+		Code:         code,
+		MatchedBy:    matchedBy,
+		Unit:         item.Unit,
+		UnitQuantity: 1,
+		UnitPrice:    item.Price,
+		ScanPrice:    item.Price,
+	}
+
+	if barcode != nil {
+		response.BarcodeID = &barcode.ID
+		response.Variant = barcode.Variant
+		response.Unit = barcode.Unit
+		response.UnitQuantity = barcode.UnitQuantity
+		response.ScanPrice = item.Price * float64(barcode.UnitQuantity)
+		if barcode.Price > 0 {
+			response.ScanPrice = barcode.Price
+			response.UnitPrice = barcode.Price / float64(barcode.UnitQuantity)
+		}
+	}
+
+	return response
+}
+
+// itemBarcodeToResponse converts ItemBarcode model to ItemBarcodeResponse
+func (s *ItemService) itemBarcodeToResponse(barcode models.ItemBarcode) models.ItemBarcodeResponse {
+	return models.ItemBarcodeResponse{
+		ID:           barcode.ID,
+		ItemID:       barcode.ItemID,
+		Barcode:      barcode.Barcode,
+		BarcodeType:  barcode.BarcodeType,
+		Variant:      barcode.Variant,
+		Unit:         barcode.Unit,
+		UnitQuantity: barcode.UnitQuantity,
+		Price:        barcode.Price,
+		CreatedAt:    barcode.CreatedAt,
+		UpdatedAt:    barcode.UpdatedAt,
+	}
+}
@@ -4,10 +4,12 @@ import (
 	"billboard/backend/models"
 	"errors"
 	"fmt"
+	"math"
 	"time"
 
 	"github.com/google/uuid"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 type BillService struct {
@@ -20,6 +22,12 @@ func NewBillService(db *gorm.DB) *BillService {
 
 // CreateBill creates a new bill
 func (s *BillService) CreateBill(shopID, userID uuid.UUID, req models.BillRequest) (*models.BillResponse, error) {
+	return s.createBill(shopID, userID, req, nil)
+}
+
+// createBill creates a bill, deducting its stock. Stock held by open carts other than the one
+// being checked out, if any, cannot be sold.
+func (s *BillService) createBill(shopID, userID uuid.UUID, req models.BillRequest, checkoutCartID *uuid.UUID) (*models.BillResponse, error) {
 	// Check if user has access to the shop
 	var shopUser models.ShopUser
 	if err := s.db.Where("shop_id = ? AND user_id = ? AND is_active = ?", shopID, userID, true).First(&shopUser).Error; err != nil {
@@ -48,77 +56,292 @@ func (s *BillService) CreateBill(shopID, userID uuid.UUID, req models.BillReques
 		return nil, err
 	}
 
-	// Calculate totals
-	subTotal := 0.0
-	for _, item := range req.Items {
-		subTotal += float64(item.Quantity) * item.UnitPrice
+	var bill models.Bill
+	err = s.db.Transaction(func(tx *gorm.DB) error {
+		// Calculate totals, applying promotions and coupons
+		calc, err := calculateBill(tx, shopID, billDate, req)
+		if err != nil {
+			return err
+		}
+
+		// Create bill
+		bill = models.Bill{
+			ShopID:            shopID,
+			CustomerID:        req.CustomerID,
+			BillNumber:        billNumber,
+			BillDate:          billDate,
+			DueDate:           dueDate,
+			SubTotal:          calc.SubTotal,
+			TaxAmount:         calc.TaxAmount,
+			Discount:          req.Discount,
+			LineDiscount:      calc.LineDiscount,
+			PromotionDiscount: calc.PromotionDiscount,
+			TaxableValue:      calc.TaxableValue,
+			TotalAmount:       calc.TotalAmount,
+			PaidAmount:        0,
+			PendingAmount:     calc.TotalAmount, // Initially pending amount equals total amount
+			Balance:           calc.TotalAmount,
+			Status:            "draft",
+			Notes:             req.Notes,
+			Terms:             req.Terms,
+			CreatedBy:         userID.String(), // Set the user who created the bill
+		}
+
+		if err := tx.Create(&bill).Error; err != nil {
+			return err
+		}
+
+		// Create bill items and record applied promotions
+		if err := createBillLines(tx, bill.ID, req.Items, calc); err != nil {
+			return err
+		}
+
+		// Stock reserved by held carts is not for sale. The items are locked so that a cart
+		// cannot reserve them between the check and the deduction.
+		itemIDs := make([]uuid.UUID, len(calc.Items))
+		for i, item := range calc.Items {
+			itemIDs[i] = item.ID
+		}
+		stock, err := lockStockItems(tx, itemIDs)
+		if err != nil {
+			return err
+		}
+		requested := make(map[uuid.UUID]int)
+		for i, itemReq := range req.Items {
+			item := stock[calc.Items[i].ID]
+			requested[item.ID] += itemReq.Quantity
+			reserved, err := reservedQuantity(tx, item.ID, checkoutCartID)
+			if err != nil {
+				return err
+			}
+			if available := item.Quantity - reserved; reserved > 0 && float64(requested[item.ID]) > available {
+				return fmt.Errorf("insufficient stock for %s: %.2f available, %.2f held in carts", item.Name, math.Max(0, available), reserved)
+			}
+		}
+
+		// Update item quantity
+		for _, itemReq := range req.Items {
+			if err := tx.Model(&models.Item{}).Where("id = ?", itemReq.ItemID).
+				Update("quantity", gorm.Expr("quantity - ?", itemReq.Quantity)).Error; err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// Fetch the complete bill with relationships
+	return s.getBillWithRelations(bill.ID, shopID)
+}
+
+// billCalculation holds the catalogue items, promotions and totals worked out for a bill request
+type billCalculation struct {
+	Items             []models.Item
+	Lines             []billLineCalculation
+	Promotions        *promotionResult
+	SubTotal          float64
+	LineDiscount      float64
+	PromotionDiscount float64
+	TaxableValue      float64
+	TaxAmount         float64
+	TotalAmount       float64
+}
+
+// billLineCalculation holds the price, discounts and tax worked out for one bill line
+type billLineCalculation struct {
+	UnitPrice         float64
+	PriceListID       *uuid.UUID
+	DiscountType      string
+	DiscountValue     float64
+	DiscountAmount    float64
+	PromotionDiscount float64
+	TaxInclusive      bool
+	TaxRate           float64
+	TaxableValue      float64
+	TaxAmount         float64
+}
+
+// calculateBill works out the totals of a bill request. Lines without a unit price are priced
+// from the customer's price list, or the item price. Line discounts come off first, then
+// promotions, and tax is calculated per line on what remains, at the item's tax rate or, for
+// items without one, the bill's rate. Lines priced tax-inclusive, by the line, the item or the
+// shop in that order, have their taxable value back-calculated. The cashier's bill discount is
+// taken off the total as before.
+func calculateBill(tx *gorm.DB, shopID uuid.UUID, billDate time.Time, req models.BillRequest) (*billCalculation, error) {
+	items, err := loadBillItems(tx, shopID, req.Items)
+	if err != nil {
+		return nil, err
+	}
+
+	var shop models.Shop
+	if err := tx.Select("id", "prices_include_tax").Where("id = ?", shopID).First(&shop).Error; err != nil {
+		return nil, errors.New("shop not found")
 	}
 
-	taxAmount := subTotal * (req.TaxRate / 100)
-	totalAmount := subTotal + taxAmount - req.Discount
+	_, priceList, err := loadPriceList(tx, shopID, req.CustomerID, nil, billDate)
+	if err != nil {
+		return nil, err
+	}
 
-	// Ensure totalAmount is not negative
-	if totalAmount < 0 {
-		totalAmount = 0
-	}
-
-	// Create bill
-	bill := models.Bill{
-		ShopID:        shopID,
-		CustomerID:    req.CustomerID,
-		BillNumber:    billNumber,
-		BillDate:      billDate,
-		DueDate:       dueDate,
-		SubTotal:      subTotal,
-		TaxAmount:     taxAmount,
-		Discount:      req.Discount,
-		TotalAmount:   totalAmount,
-		PaidAmount:    0,
-		PendingAmount: totalAmount, // Initially pending amount equals total amount
-		Balance:       totalAmount,
-		Status:        "draft",
-		Notes:         req.Notes,
-		Terms:         req.Terms,
-		CreatedBy:     userID.String(), // Set the user who created the bill
-	}
-
-	// Debug logging
-	fmt.Printf("Creating bill with SubTotal: %f, TaxAmount: %f, TotalAmount: %f\n", subTotal, taxAmount, totalAmount)
-
-	if err := s.db.Create(&bill).Error; err != nil {
+	calc := &billCalculation{Items: items, Lines: make([]billLineCalculation, len(req.Items))}
+
+	// Prices and line discounts
+	netAmounts := make([]float64, len(req.Items))
+	for i, itemReq := range req.Items {
+		line := &calc.Lines[i]
+		if itemReq.UnitPrice != nil {
+			line.UnitPrice = *itemReq.UnitPrice
+		} else {
+			price := resolveItemPrice(priceList, items[i], itemReq.Quantity)
+			line.UnitPrice = price.UnitPrice
+			if price.Source != "item" {
+				line.PriceListID = &priceList.ID
+			}
+		}
+
+		gross := float64(itemReq.Quantity) * line.UnitPrice
+		calc.SubTotal += gross
+
+		if itemReq.Discount > 0 {
+			line.DiscountType = itemReq.DiscountType
+			if line.DiscountType == "" {
+				line.DiscountType = "amount"
+			}
+			line.DiscountValue = itemReq.Discount
+			line.DiscountAmount = itemReq.Discount
+			if line.DiscountType == "percentage" {
+				if itemReq.Discount > 100 {
+					return nil, errors.New("line discount percentage must not exceed 100")
+				}
+				line.DiscountAmount = roundAmount(gross * itemReq.Discount / 100)
+			}
+			if line.DiscountAmount > gross {
+				return nil, errors.New("line discount cannot exceed the line amount")
+			}
+		}
+
+		netAmounts[i] = gross - line.DiscountAmount
+		calc.LineDiscount += line.DiscountAmount
+	}
+
+	// Promotions and coupons
+	promotions, err := evaluatePromotions(tx, shopID, billDate, promotionLines(req.Items, items, netAmounts), req.CouponCodes)
+	if err != nil {
 		return nil, err
 	}
+	calc.Promotions = promotions
+	calc.PromotionDiscount = promotions.Total
+
+	// Spread the bill promotion over the lines in proportion to their value so tax stays per line
+	billPromotion := promotions.Total
+	remaining := 0.0
+	for i := range req.Items {
+		billPromotion -= promotions.LineDiscounts[i]
+		remaining += netAmounts[i] - promotions.LineDiscounts[i]
+	}
+
+	// Taxable value and tax per line
+	for i := range req.Items {
+		line := &calc.Lines[i]
+		line.PromotionDiscount = promotions.LineDiscounts[i]
+
+		amount := netAmounts[i] - line.PromotionDiscount
+		if billPromotion > 0 && remaining > 0 {
+			amount -= billPromotion * amount / remaining
+		}
+
+		line.TaxInclusive = shop.PricesIncludeTax
+		if req.Items[i].TaxInclusive != nil {
+			line.TaxInclusive = *req.Items[i].TaxInclusive
+		} else if items[i].TaxInclusive != nil {
+			line.TaxInclusive = *items[i].TaxInclusive
+		}
+
+		line.TaxRate = req.TaxRate
+		if items[i].TaxRate > 0 {
+			line.TaxRate = items[i].TaxRate
+		}
+		if line.TaxInclusive {
+			line.TaxableValue = roundAmount(amount / (1 + line.TaxRate/100))
+			line.TaxAmount = roundAmount(amount - line.TaxableValue)
+		} else {
+			line.TaxableValue = roundAmount(amount)
+			line.TaxAmount = roundAmount(amount * line.TaxRate / 100)
+		}
+
+		calc.TaxableValue += line.TaxableValue
+		calc.TaxAmount += line.TaxAmount
+	}
+
+	calc.SubTotal = roundAmount(calc.SubTotal)
+	calc.LineDiscount = roundAmount(calc.LineDiscount)
+	calc.TaxableValue = roundAmount(calc.TaxableValue)
+	calc.TaxAmount = roundAmount(calc.TaxAmount)
+	calc.TotalAmount = roundAmount(calc.TaxableValue + calc.TaxAmount - req.Discount)
+
+	// Ensure totalAmount is not negative
+	if calc.TotalAmount < 0 {
+		calc.TotalAmount = 0
+	}
 
-	// Create bill items
-	for _, itemReq := range req.Items {
-		// Get item details
-		var item models.Item
-		if err := s.db.Where("id = ? AND shop_id = ?", itemReq.ItemID, shopID).First(&item).Error; err != nil {
+	return calc, nil
+}
+
+// loadBillItems loads the catalogue item of every bill line
+func loadBillItems(tx *gorm.DB, shopID uuid.UUID, reqs []models.BillItemRequest) ([]models.Item, error) {
+	items := make([]models.Item, len(reqs))
+	for i, itemReq := range reqs {
+		if err := tx.Where("id = ? AND shop_id = ?", itemReq.ItemID, shopID).First(&items[i]).Error; err != nil {
 			return nil, errors.New("item not found")
 		}
+	}
+	return items, nil
+}
 
+// createBillLines creates the items of a bill and records the promotions applied to it
+func createBillLines(tx *gorm.DB, billID uuid.UUID, reqs []models.BillItemRequest, calc *billCalculation) error {
+	for i, itemReq := range reqs {
 		billItem := models.BillItem{
-			BillID:      bill.ID,
-			ItemID:      itemReq.ItemID,
-			ItemName:    item.Name,
-			Description: itemReq.Description,
-			Quantity:    itemReq.Quantity,
-			UnitPrice:   itemReq.UnitPrice,
-			TotalPrice:  float64(itemReq.Quantity) * itemReq.UnitPrice,
+			BillID:            billID,
+			ItemID:            itemReq.ItemID,
+			ItemName:          calc.Items[i].Name,
+			Description:       itemReq.Description,
+			HSNCode:           calc.Items[i].HSNCode,
+			Unit:              calc.Items[i].Unit,
+			Quantity:          itemReq.Quantity,
+			UnitPrice:         calc.Lines[i].UnitPrice,
+			TotalPrice:        float64(itemReq.Quantity) * calc.Lines[i].UnitPrice,
+			UnitCost:          calc.Items[i].CostPrice,
+			PriceListID:       calc.Lines[i].PriceListID,
+			DiscountType:      calc.Lines[i].DiscountType,
+			DiscountValue:     calc.Lines[i].DiscountValue,
+			DiscountAmount:    calc.Lines[i].DiscountAmount,
+			PromotionDiscount: calc.Lines[i].PromotionDiscount,
+			TaxInclusive:      calc.Lines[i].TaxInclusive,
+			TaxRate:           calc.Lines[i].TaxRate,
+			TaxableValue:      calc.Lines[i].TaxableValue,
+			TaxAmount:         calc.Lines[i].TaxAmount,
+		}
+		if promotion := calc.Promotions.LinePromotions[i]; promotion != nil {
+			billItem.PromotionID = &promotion.ID
 		}
 
-		if err := s.db.Create(&billItem).Error; err != nil {
-			return nil, err
+		if err := tx.Create(&billItem).Error; err != nil {
+			return err
 		}
+	}
 
-		// Update item quantity
-		if err := s.db.Model(&item).Update("quantity", int(item.Quantity)-itemReq.Quantity).Error; err != nil {
-			return nil, err
+	for _, applied := range calc.Promotions.Applied {
+		applied.BillID = billID
+		if err := tx.Create(&applied).Error; err != nil {
+			return err
 		}
 	}
 
-	// Fetch the complete bill with relationships
-	return s.getBillWithRelations(bill.ID, shopID)
+	return redeemCoupons(tx, calc.Promotions.Applied)
 }
 
 // GetBills retrieves all bills for a shop
@@ -188,6 +411,26 @@ func (s *BillService) GetBill(billID, shopID, userID uuid.UUID) (*models.BillRes
 	return s.getBillWithRelations(bill.ID, shopID)
 }
 
+// GetBillDocument retrieves a bill with its shop, customer, items and payments for rendering
+func (s *BillService) GetBillDocument(billID, shopID, userID uuid.UUID) (*models.Bill, error) {
+	// Check if user has access to the shop
+	var shopUser models.ShopUser
+	if err := s.db.Where("shop_id = ? AND user_id = ? AND is_active = ?", shopID, userID, true).First(&shopUser).Error; err != nil {
+		return nil, errors.New("access denied to shop")
+	}
+
+	var bill models.Bill
+	if err := s.db.Preload("Shop").Preload("Customer").Preload("Items.Item").Preload("Payments").Preload("Promotions").
+		Where("id = ? AND shop_id = ? AND deleted_at IS NULL", billID, shopID).First(&bill).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("bill not found")
+		}
+		return nil, err
+	}
+
+	return &bill, nil
+}
+
 // UpdateBill updates an existing bill
 func (s *BillService) UpdateBill(billID, shopID, userID uuid.UUID, req models.BillRequest) (*models.BillResponse, error) {
 	// Check if user has access to the shop
@@ -225,62 +468,50 @@ func (s *BillService) UpdateBill(billID, shopID, userID uuid.UUID, req models.Bi
 		dueDate = &parsedDueDate
 	}
 
-	// Calculate totals
-	subTotal := 0.0
-	for _, item := range req.Items {
-		subTotal += float64(item.Quantity) * item.UnitPrice
-	}
-
-	taxAmount := subTotal * (req.TaxRate / 100)
-	totalAmount := subTotal + taxAmount - req.Discount
-
-	// Update bill
-	newBalance := totalAmount - bill.PaidAmount
-	updates := map[string]interface{}{
-		"customer_id":    req.CustomerID,
-		"bill_date":      billDate,
-		"due_date":       dueDate,
-		"sub_total":      subTotal,
-		"tax_amount":     taxAmount,
-		"discount":       req.Discount,
-		"total_amount":   totalAmount,
-		"pending_amount": newBalance, // Update pending amount
-		"balance":        newBalance,
-		"notes":          req.Notes,
-		"terms":          req.Terms,
-		"updated_at":     time.Now(),
-	}
-
-	if err := s.db.Model(&models.Bill{}).Where("id = ?", billID).Updates(updates).Error; err != nil {
-		return nil, err
-	}
+	err = s.db.Transaction(func(tx *gorm.DB) error {
+		// Give back the promotions and coupons of the previous version before recalculating
+		if err := releaseBillPromotions(tx, billID); err != nil {
+			return err
+		}
 
-	// Delete existing bill items
-	if err := s.db.Where("bill_id = ?", billID).Delete(&models.BillItem{}).Error; err != nil {
-		return nil, err
-	}
+		calc, err := calculateBill(tx, shopID, billDate, req)
+		if err != nil {
+			return err
+		}
 
-	// Create new bill items
-	for _, itemReq := range req.Items {
-		// Get item details
-		var item models.Item
-		if err := s.db.Where("id = ? AND shop_id = ?", itemReq.ItemID, shopID).First(&item).Error; err != nil {
-			return nil, errors.New("item not found")
+		// Update bill
+		newBalance := calc.TotalAmount - bill.PaidAmount
+		updates := map[string]interface{}{
+			"customer_id":        req.CustomerID,
+			"bill_date":          billDate,
+			"due_date":           dueDate,
+			"subtotal":           calc.SubTotal,
+			"tax_amount":         calc.TaxAmount,
+			"discount_amount":    req.Discount,
+			"line_discount":      calc.LineDiscount,
+			"promotion_discount": calc.PromotionDiscount,
+			"taxable_value":      calc.TaxableValue,
+			"total_amount":       calc.TotalAmount,
+			"pending_amount":     newBalance, // Update pending amount
+			"balance":            newBalance,
+			"notes":              req.Notes,
+			"payment_terms":      req.Terms,
+			"updated_at":         time.Now(),
 		}
 
-		billItem := models.BillItem{
-			BillID:      bill.ID,
-			ItemID:      itemReq.ItemID,
-			ItemName:    item.Name,
-			Description: itemReq.Description,
-			Quantity:    itemReq.Quantity,
-			UnitPrice:   itemReq.UnitPrice,
-			TotalPrice:  float64(itemReq.Quantity) * itemReq.UnitPrice,
+		if err := tx.Model(&models.Bill{}).Where("id = ?", billID).Updates(updates).Error; err != nil {
+			return err
 		}
 
-		if err := s.db.Create(&billItem).Error; err != nil {
-			return nil, err
+		// Replace bill items
+		if err := tx.Where("bill_id = ?", billID).Delete(&models.BillItem{}).Error; err != nil {
+			return err
 		}
+
+		return createBillLines(tx, bill.ID, req.Items, calc)
+	})
+	if err != nil {
+		return nil, err
 	}
 
 	// Fetch the updated bill with relationships
@@ -308,15 +539,72 @@ func (s *BillService) DeleteBill(billID, shopID, userID uuid.UUID) error {
 		return errors.New("only draft bills can be deleted")
 	}
 
-	if err := s.db.Delete(&bill).Error; err != nil {
-		return err
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		if err := releaseBillPromotions(tx, bill.ID); err != nil {
+			return err
+		}
+		return tx.Delete(&bill).Error
+	})
+}
+
+// CancelBill cancels a bill, returning its stock, coupons and loyalty points. Payments already
+// taken stay on record and are refunded through the register.
+func (s *BillService) CancelBill(billID, shopID, userID uuid.UUID) (*models.BillResponse, error) {
+	// Check if user has access to the shop
+	var shopUser models.ShopUser
+	if err := s.db.Where("shop_id = ? AND user_id = ? AND is_active = ?", shopID, userID, true).First(&shopUser).Error; err != nil {
+		return nil, errors.New("access denied to shop")
 	}
 
-	return nil
+	var bill models.Bill
+	if err := s.db.Preload("Items").Where("id = ? AND shop_id = ? AND deleted_at IS NULL", billID, shopID).First(&bill).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("bill not found")
+		}
+		return nil, err
+	}
+
+	if bill.Status == "cancelled" {
+		return nil, errors.New("bill is already cancelled")
+	}
+	if bill.IRNStatus == "generated" {
+		return nil, errors.New("bill has an active e-invoice; cancel the e-invoice first")
+	}
+
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		// Return stock
+		for _, item := range bill.Items {
+			if err := tx.Model(&models.Item{}).Where("id = ?", item.ItemID).
+				Update("quantity", gorm.Expr("quantity + ?", item.Quantity)).Error; err != nil {
+				return err
+			}
+		}
+
+		if err := returnBillCoupons(tx, bill.ID); err != nil {
+			return err
+		}
+
+		if err := reverseBillLoyalty(tx, bill, userID); err != nil {
+			return err
+		}
+
+		now := time.Now()
+		return tx.Model(&models.Bill{}).Where("id = ?", bill.ID).Updates(map[string]interface{}{
+			"status":       "cancelled",
+			"cancelled_at": now,
+			"updated_at":   now,
+		}).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return s.getBillWithRelations(bill.ID, shopID)
 }
 
-// AddPayment adds a payment to a bill
-func (s *BillService) AddPayment(billID, shopID, userID uuid.UUID, req models.PaymentRequest) (*models.PaymentResponse, error) {
+// IssueBill issues a draft bill to its customer. Issued bills can no longer be edited or
+// deleted, only cancelled.
+func (s *BillService) IssueBill(billID, shopID, userID uuid.UUID) (*models.BillResponse, error) {
 	// Check if user has access to the shop
 	var shopUser models.ShopUser
 	if err := s.db.Where("shop_id = ? AND user_id = ? AND is_active = ?", shopID, userID, true).First(&shopUser).Error; err != nil {
@@ -331,55 +619,176 @@ func (s *BillService) AddPayment(billID, shopID, userID uuid.UUID, req models.Pa
 		return nil, err
 	}
 
+	if bill.Status != "draft" {
+		return nil, errors.New("only draft bills can be issued")
+	}
+	if err := issueBill(s.db, &bill); err != nil {
+		return nil, err
+	}
+
+	return s.getBillWithRelations(bill.ID, shopID)
+}
+
+// issueBill moves a draft bill on to sent, or overdue when its due date has passed, once it has
+// gone out to the customer. Bills already issued are left alone.
+func issueBill(db *gorm.DB, bill *models.Bill) error {
+	if bill.Status != "draft" {
+		return nil
+	}
+
+	status := "sent"
+	if bill.DueDate != nil && time.Now().After(*bill.DueDate) {
+		status = "overdue"
+	}
+	if err := db.Model(&models.Bill{}).Where("id = ? AND status = ?", bill.ID, "draft").Updates(map[string]interface{}{
+		"status":     status,
+		"updated_at": time.Now(),
+	}).Error; err != nil {
+		return err
+	}
+	bill.Status = status
+	return nil
+}
+
+// AddPayment adds a payment to a bill
+func (s *BillService) AddPayment(billID, shopID, userID uuid.UUID, req models.PaymentRequest) (*models.PaymentResponse, error) {
+	// Check if user has access to the shop
+	var shopUser models.ShopUser
+	if err := s.db.Where("shop_id = ? AND user_id = ? AND is_active = ?", shopID, userID, true).First(&shopUser).Error; err != nil {
+		return nil, errors.New("access denied to shop")
+	}
+
+	return s.recordPayment(billID, shopID, userID, req, true)
+}
+
+// recordPayment records a payment on a bill and updates its balance and status. Payments taken
+// at the counter are attached to the cashier's open register session; payments that arrive
+// online are not. The bill row is locked while the payment is recorded, since counter, bank and
+// online payments for a bill can arrive at the same time.
+func (s *BillService) recordPayment(billID, shopID, userID uuid.UUID, req models.PaymentRequest, atRegister bool) (*models.PaymentResponse, error) {
 	// Parse payment date
 	paymentDate, err := time.Parse("2006-01-02", req.PaymentDate)
 	if err != nil {
 		return nil, errors.New("invalid payment date format")
 	}
 
+	// Attach the payment to the cashier's open register session, if any
+	var session models.RegisterSession
+	var sessionID *uuid.UUID
+	if atRegister {
+		if err := s.db.Where("shop_id = ? AND opened_by = ? AND status = ?", shopID, userID.String(), "open").First(&session).Error; err == nil {
+			sessionID = &session.ID
+		}
+	}
+
 	// Create payment
 	payment := models.Payment{
-		BillID:        billID,
-		Amount:        req.Amount,
-		PaymentDate:   paymentDate,
-		PaymentMethod: req.PaymentMethod,
-		Reference:     req.Reference,
-		Notes:         req.Notes,
-		CreatedBy:     userID.String(), // Set the user who created the payment
-	}
+		BillID:            billID,
+		Amount:            req.Amount,
+		PaymentDate:       paymentDate,
+		PaymentMethod:     req.PaymentMethod,
+		Reference:         req.Reference,
+		Notes:             req.Notes,
+		TenderedAmount:    req.TenderedAmount,
+		RegisterSessionID: sessionID,
+		CreatedBy:         userID.String(), // Set the user who created the payment
+	}
+
+	err = s.db.Transaction(func(tx *gorm.DB) error {
+		var bill models.Bill
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("id = ? AND shop_id = ? AND deleted_at IS NULL", billID, shopID).First(&bill).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return errors.New("bill not found")
+			}
+			return err
+		}
+
+		if bill.Status == "cancelled" {
+			return errors.New("cannot add a payment to a cancelled bill")
+		}
+
+		if req.PaymentMethod == "loyalty_points" && roundAmount(req.Amount) > roundAmount(bill.Balance) {
+			return errors.New("loyalty points cannot pay more than the bill balance")
+		}
+
+		// Update bill paid amount and balance
+		newPaidAmount := bill.PaidAmount + req.Amount
+		newBalance := bill.TotalAmount + bill.LateFeeAmount - newPaidAmount
+
+		// Update bill status based on balance
+		newStatus := bill.Status
+		if newBalance <= 0 {
+			newStatus = "paid"
+		} else if bill.DueDate != nil && time.Now().After(*bill.DueDate) {
+			newStatus = "overdue"
+		} else {
+			newStatus = "sent"
+		}
+
+		if err := tx.Create(&payment).Error; err != nil {
+			return err
+		}
 
-	if err := s.db.Create(&payment).Error; err != nil {
+		// Earn loyalty points, or redeem them when paying with points
+		if err := applyPaymentLoyalty(tx, bill, payment, userID); err != nil {
+			return err
+		}
+
+		return tx.Model(&models.Bill{}).Where("id = ?", billID).Updates(map[string]interface{}{
+			"paid_amount":    newPaidAmount,
+			"pending_amount": newBalance, // Pending amount is the remaining balance
+			"balance":        newBalance,
+			"status":         newStatus,
+			"updated_at":     time.Now(),
+		}).Error
+	})
+	if err != nil {
 		return nil, err
 	}
 
-	// Update bill paid amount and balance
-	newPaidAmount := bill.PaidAmount + req.Amount
-	newBalance := bill.TotalAmount - newPaidAmount
+	response := s.paymentToResponse(payment)
+	return &response, nil
+}
 
-	// Update bill status based on balance
+// refundBillPayment takes an amount refunded to the customer off what was paid on a bill,
+// reopening its balance unless the bill is cancelled, and returns the bill as it was before
+func refundBillPayment(tx *gorm.DB, billID uuid.UUID, amount float64) (*models.Bill, error) {
+	var bill models.Bill
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("id = ? AND deleted_at IS NULL", billID).First(&bill).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("bill not found")
+		}
+		return nil, err
+	}
+	if roundAmount(amount) > roundAmount(bill.PaidAmount) {
+		return nil, fmt.Errorf("refund cannot be more than the %.2f paid on the bill", bill.PaidAmount)
+	}
+
+	newPaidAmount := roundAmount(bill.PaidAmount - amount)
+	newBalance := roundAmount(bill.TotalAmount + bill.LateFeeAmount - newPaidAmount)
 	newStatus := bill.Status
-	if newBalance <= 0 {
-		newStatus = "paid"
-	} else if bill.DueDate != nil && time.Now().After(*bill.DueDate) {
-		newStatus = "overdue"
-	} else {
-		newStatus = "sent"
+	if bill.Status != "cancelled" && bill.Status != "draft" {
+		if newBalance <= 0 {
+			newStatus = "paid"
+		} else if bill.DueDate != nil && time.Now().After(*bill.DueDate) {
+			newStatus = "overdue"
+		} else {
+			newStatus = "sent"
+		}
 	}
 
-	updates := map[string]interface{}{
+	if err := tx.Model(&models.Bill{}).Where("id = ?", bill.ID).Updates(map[string]interface{}{
 		"paid_amount":    newPaidAmount,
-		"pending_amount": newBalance, // Pending amount is the remaining balance
+		"pending_amount": newBalance,
 		"balance":        newBalance,
 		"status":         newStatus,
 		"updated_at":     time.Now(),
-	}
-
-	if err := s.db.Model(&models.Bill{}).Where("id = ?", billID).Updates(updates).Error; err != nil {
+	}).Error; err != nil {
 		return nil, err
 	}
-
-	response := s.paymentToResponse(payment)
-	return &response, nil
+	return &bill, nil
 }
 
 // GetBillStats retrieves bill statistics for a shop
@@ -443,7 +852,7 @@ func (s *BillService) generateBillNumber(shopID uuid.UUID) (string, error) {
 // getBillWithRelations fetches a bill with all its relationships
 func (s *BillService) getBillWithRelations(billID, shopID uuid.UUID) (*models.BillResponse, error) {
 	var bill models.Bill
-	if err := s.db.Preload("Customer").Preload("Items").Preload("Payments").Where("id = ? AND shop_id = ?", billID, shopID).First(&bill).Error; err != nil {
+	if err := s.db.Preload("Customer").Preload("Items").Preload("Payments").Preload("Promotions").Where("id = ? AND shop_id = ?", billID, shopID).First(&bill).Error; err != nil {
 		return nil, err
 	}
 
@@ -469,78 +878,111 @@ func (s *BillService) billToResponse(bill models.Bill) models.BillResponse {
 		payments = append(payments, s.paymentToResponse(payment))
 	}
 
+	promotions := []models.BillPromotionResponse{}
+	for _, promotion := range bill.Promotions {
+		promotions = append(promotions, billPromotionToResponse(promotion))
+	}
+
 	return models.BillResponse{
-		ID:          bill.ID,
-		ShopID:      bill.ShopID,
-		CustomerID:  bill.CustomerID,
-		BillNumber:  bill.BillNumber,
-		BillDate:    bill.BillDate,
-		DueDate:     bill.DueDate,
-		SubTotal:    bill.SubTotal,
-		TaxAmount:   bill.TaxAmount,
-		Discount:    bill.Discount,
-		TotalAmount: bill.TotalAmount,
-		PaidAmount:  bill.PaidAmount,
-		Balance:     bill.Balance,
-		Status:      bill.Status,
-		Notes:       bill.Notes,
-		Terms:       bill.Terms,
-		Customer:    customer,
-		Items:       items,
-		Payments:    payments,
-		CreatedAt:   bill.CreatedAt,
-		UpdatedAt:   bill.UpdatedAt,
+		ID:                bill.ID,
+		ShopID:            bill.ShopID,
+		CustomerID:        bill.CustomerID,
+		BillNumber:        bill.BillNumber,
+		BillDate:          bill.BillDate,
+		DueDate:           bill.DueDate,
+		SubTotal:          bill.SubTotal,
+		TaxAmount:         bill.TaxAmount,
+		Discount:          bill.Discount,
+		LineDiscount:      bill.LineDiscount,
+		PromotionDiscount: bill.PromotionDiscount,
+		TaxableValue:      bill.TaxableValue,
+		TotalAmount:       bill.TotalAmount,
+		LateFeeAmount:     bill.LateFeeAmount,
+		PaidAmount:        bill.PaidAmount,
+		Balance:           bill.Balance,
+		Status:            bill.Status,
+		CancelledAt:       bill.CancelledAt,
+		Notes:             bill.Notes,
+		Terms:             bill.Terms,
+		IRN:               bill.IRN,
+		IRNStatus:         bill.IRNStatus,
+		IRNAckNo:          bill.IRNAckNo,
+		IRNAckDate:        bill.IRNAckDate,
+		NoReminders:       bill.NoReminders,
+		Customer:          customer,
+		Items:             items,
+		Payments:          payments,
+		Promotions:        promotions,
+		CreatedAt:         bill.CreatedAt,
+		UpdatedAt:         bill.UpdatedAt,
 	}
 }
 
 // billItemToResponse converts a BillItem model to BillItemResponse
 func (s *BillService) billItemToResponse(item models.BillItem) models.BillItemResponse {
 	return models.BillItemResponse{
-		ID:          item.ID,
-		BillID:      item.BillID,
-		ItemID:      item.ItemID,
-		ItemName:    item.ItemName,
-		Description: item.Description,
-		Quantity:    item.Quantity,
-		UnitPrice:   item.UnitPrice,
-		TotalPrice:  item.TotalPrice,
-		CreatedAt:   item.CreatedAt,
-		UpdatedAt:   item.UpdatedAt,
+		ID:                item.ID,
+		BillID:            item.BillID,
+		ItemID:            item.ItemID,
+		ItemName:          item.ItemName,
+		Description:       item.Description,
+		HSNCode:           item.HSNCode,
+		Unit:              item.Unit,
+		Quantity:          item.Quantity,
+		UnitPrice:         item.UnitPrice,
+		TotalPrice:        item.TotalPrice,
+		UnitCost:          item.UnitCost,
+		PriceListID:       item.PriceListID,
+		DiscountType:      item.DiscountType,
+		DiscountValue:     item.DiscountValue,
+		DiscountAmount:    item.DiscountAmount,
+		PromotionID:       item.PromotionID,
+		PromotionDiscount: item.PromotionDiscount,
+		TaxInclusive:      item.TaxInclusive,
+		TaxRate:           item.TaxRate,
+		TaxableValue:      item.TaxableValue,
+		TaxAmount:         item.TaxAmount,
+		CreatedAt:         item.CreatedAt,
+		UpdatedAt:         item.UpdatedAt,
 	}
 }
 
 // paymentToResponse converts a Payment model to PaymentResponse
 func (s *BillService) paymentToResponse(payment models.Payment) models.PaymentResponse {
 	return models.PaymentResponse{
-		ID:            payment.ID,
-		BillID:        payment.BillID,
-		Amount:        payment.Amount,
-		PaymentDate:   payment.PaymentDate,
-		PaymentMethod: payment.PaymentMethod,
-		Reference:     payment.Reference,
-		Notes:         payment.Notes,
-		CreatedAt:     payment.CreatedAt,
-		UpdatedAt:     payment.UpdatedAt,
+		ID:                payment.ID,
+		BillID:            payment.BillID,
+		Amount:            payment.Amount,
+		PaymentDate:       payment.PaymentDate,
+		PaymentMethod:     payment.PaymentMethod,
+		Reference:         payment.Reference,
+		Notes:             payment.Notes,
+		TenderedAmount:    payment.TenderedAmount,
+		RegisterSessionID: payment.RegisterSessionID,
+		CreatedAt:         payment.CreatedAt,
+		UpdatedAt:         payment.UpdatedAt,
 	}
 }
 
 // customerToResponse converts a Customer model to CustomerResponse (helper method)
 func (s *BillService) customerToResponse(customer models.Customer) models.CustomerResponse {
 	return models.CustomerResponse{
-		ID:         customer.ID,
-		ShopID:     customer.ShopID,
-		Name:       customer.Name,
-		Email:      customer.Email,
-		Phone:      customer.Phone,
-		Address:    customer.Address,
-		City:       customer.City,
-		State:      customer.State,
-		Country:    customer.Country,
-		PostalCode: customer.PostalCode,
-		TaxNumber:  customer.TaxNumber,
-		Notes:      customer.Notes,
-		IsActive:   customer.IsActive,
-		CreatedAt:  customer.CreatedAt,
-		UpdatedAt:  customer.UpdatedAt,
+		ID:            customer.ID,
+		ShopID:        customer.ShopID,
+		Name:          customer.Name,
+		Email:         customer.Email,
+		Phone:         customer.Phone,
+		Address:       customer.Address,
+		City:          customer.City,
+		State:         customer.State,
+		Country:       customer.Country,
+		PostalCode:    customer.PostalCode,
+		TaxNumber:     customer.TaxNumber,
+		Notes:         customer.Notes,
+		GroupID:       customer.GroupID,
+		LoyaltyPoints: customer.LoyaltyPoints,
+		IsActive:      customer.IsActive,
+		CreatedAt:     customer.CreatedAt,
+		UpdatedAt:     customer.UpdatedAt,
 	}
 }
@@ -0,0 +1,468 @@
+package services
+
+import (
+	"billboard/backend/models"
+	"errors"
+	"fmt"
+	"log"
+	"math"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// CartService handles held sales that reserve stock until they are checked out
+type CartService struct {
+	db     *gorm.DB
+	expiry time.Duration
+}
+
+// NewCartService creates a new CartService instance
+func NewCartService(db *gorm.DB, expiry time.Duration) *CartService {
+	return &CartService{db: db, expiry: expiry}
+}
+
+// CreateCart creates a new held cart
+func (s *CartService) CreateCart(shopID, userID uuid.UUID, req models.CartRequest) (*models.CartResponse, error) {
+	// Check if user has access to the shop
+	var shopUser models.ShopUser
+	if err := s.db.Where("shop_id = ? AND user_id = ? AND is_active = ?", shopID, userID, true).First(&shopUser).Error; err != nil {
+		return nil, errors.New("access denied to shop")
+	}
+
+	cart := models.Cart{
+		ShopID:     shopID,
+		CustomerID: req.CustomerID,
+		Name:       req.Name,
+		Status:     "open",
+		Discount:   req.Discount,
+		TaxRate:    req.TaxRate,
+		Notes:      req.Notes,
+		Terms:      req.Terms,
+		ExpiresAt:  s.expiresAt(req.ExpiresInMinutes),
+		CreatedBy:  userID.String(),
+		UpdatedBy:  userID.String(),
+	}
+
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&cart).Error; err != nil {
+			return err
+		}
+		return s.replaceCartItems(tx, shopID, cart.ID, req)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return s.getCartWithRelations(cart.ID, shopID)
+}
+
+// GetCarts retrieves all open carts of a shop, so that any terminal can resume them
+func (s *CartService) GetCarts(shopID, userID uuid.UUID) ([]models.CartResponse, error) {
+	// Check if user has access to the shop
+	var shopUser models.ShopUser
+	if err := s.db.Where("shop_id = ? AND user_id = ? AND is_active = ?", shopID, userID, true).First(&shopUser).Error; err != nil {
+		return nil, errors.New("access denied to shop")
+	}
+
+	var carts []models.Cart
+	if err := s.db.Preload("Customer").Preload("Items").
+		Where("shop_id = ? AND status = ? AND expires_at > ?", shopID, "open", time.Now()).
+		Order("created_at DESC").Find(&carts).Error; err != nil {
+		return nil, err
+	}
+
+	var responses []models.CartResponse
+	for _, cart := range carts {
+		responses = append(responses, s.cartToResponse(cart))
+	}
+
+	return responses, nil
+}
+
+// GetCart retrieves a specific cart
+func (s *CartService) GetCart(cartID, shopID, userID uuid.UUID) (*models.CartResponse, error) {
+	// Check if user has access to the shop
+	var shopUser models.ShopUser
+	if err := s.db.Where("shop_id = ? AND user_id = ? AND is_active = ?", shopID, userID, true).First(&shopUser).Error; err != nil {
+		return nil, errors.New("access denied to shop")
+	}
+
+	return s.getCartWithRelations(cartID, shopID)
+}
+
+// UpdateCart replaces the contents of an open cart and extends its expiry
+func (s *CartService) UpdateCart(cartID, shopID, userID uuid.UUID, req models.CartRequest) (*models.CartResponse, error) {
+	// Check if user has access to the shop
+	var shopUser models.ShopUser
+	if err := s.db.Where("shop_id = ? AND user_id = ? AND is_active = ?", shopID, userID, true).First(&shopUser).Error; err != nil {
+		return nil, errors.New("access denied to shop")
+	}
+
+	updates := map[string]interface{}{
+		"customer_id": req.CustomerID,
+		"name":        req.Name,
+		"discount":    req.Discount,
+		"tax_rate":    req.TaxRate,
+		"notes":       req.Notes,
+		"terms":       req.Terms,
+		"expires_at":  s.expiresAt(req.ExpiresInMinutes),
+		"updated_by":  userID.String(),
+		"updated_at":  time.Now(),
+	}
+
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		// Lock the cart so it cannot be checked out while its lines are replaced
+		if _, err := s.getOpenCart(tx.Clauses(clause.Locking{Strength: "UPDATE"}), cartID, shopID); err != nil {
+			return err
+		}
+		if err := tx.Model(&models.Cart{}).Where("id = ?", cartID).Updates(updates).Error; err != nil {
+			return err
+		}
+		return s.replaceCartItems(tx, shopID, cartID, req)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return s.getCartWithRelations(cartID, shopID)
+}
+
+// CancelCart cancels an open cart and releases its reserved stock
+func (s *CartService) CancelCart(cartID, shopID, userID uuid.UUID) error {
+	// Check if user has access to the shop
+	var shopUser models.ShopUser
+	if err := s.db.Where("shop_id = ? AND user_id = ? AND is_active = ?", shopID, userID, true).First(&shopUser).Error; err != nil {
+		return errors.New("access denied to shop")
+	}
+
+	if _, err := s.getOpenCart(s.db, cartID, shopID); err != nil {
+		return err
+	}
+
+	return s.db.Model(&models.Cart{}).Where("id = ?", cartID).Updates(map[string]interface{}{
+		"status":     "cancelled",
+		"updated_by": userID.String(),
+		"updated_at": time.Now(),
+	}).Error
+}
+
+// CheckoutCart converts an open cart into a bill, which assigns the bill number and deducts stock
+func (s *CartService) CheckoutCart(cartID, shopID, userID uuid.UUID, req models.CartCheckoutRequest) (*models.BillResponse, error) {
+	// Check if user has access to the shop
+	var shopUser models.ShopUser
+	if err := s.db.Where("shop_id = ? AND user_id = ? AND is_active = ?", shopID, userID, true).First(&shopUser).Error; err != nil {
+		return nil, errors.New("access denied to shop")
+	}
+
+	if req.BillDate == "" {
+		req.BillDate = time.Now().Format("2006-01-02")
+	}
+
+	var billID uuid.UUID
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		// The lock makes a second checkout of the same cart wait, then find it checked out
+		cart, err := s.getOpenCart(tx.Clauses(clause.Locking{Strength: "UPDATE"}).Preload("Items"), cartID, shopID)
+		if err != nil {
+			return err
+		}
+		if len(cart.Items) == 0 {
+			return errors.New("cart is empty")
+		}
+
+		billReq := cartBillRequest(*cart)
+		billReq.BillDate = req.BillDate
+		billReq.DueDate = req.DueDate
+		billReq.CouponCodes = req.CouponCodes
+
+		bill, err := (&BillService{db: tx}).createBill(shopID, userID, billReq, &cartID)
+		if err != nil {
+			return err
+		}
+		billID = bill.ID
+
+		result := tx.Model(&models.Cart{}).Where("id = ? AND status = ?", cartID, "open").Updates(map[string]interface{}{
+			"status":     "checked_out",
+			"bill_id":    bill.ID,
+			"updated_by": userID.String(),
+			"updated_at": time.Now(),
+		})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return errors.New("cart is already checked out")
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return (&BillService{db: s.db}).getBillWithRelations(billID, shopID)
+}
+
+// ExpireCarts marks open carts past their expiry as expired, releasing their reservations
+func (s *CartService) ExpireCarts() (int64, error) {
+	result := s.db.Model(&models.Cart{}).
+		Where("status = ? AND expires_at <= ?", "open", time.Now()).
+		Updates(map[string]interface{}{"status": "expired", "updated_at": time.Now()})
+	return result.RowsAffected, result.Error
+}
+
+// StartExpiryWorker periodically expires stale carts in the background
+func (s *CartService) StartExpiryWorker(interval time.Duration) {
+	if s.db == nil {
+		return
+	}
+
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for range ticker.C {
+			if count, err := s.ExpireCarts(); err != nil {
+				log.Printf("Failed to expire carts: %v", err)
+			} else if count > 0 {
+				log.Printf("Expired %d held carts", count)
+			}
+		}
+	}()
+}
+
+// lockStockItems locks the rows of the given items for the rest of the transaction, in a fixed
+// order so that concurrent holds and sales of the same items cannot deadlock, and returns them
+// with their current quantities
+func lockStockItems(tx *gorm.DB, itemIDs []uuid.UUID) (map[uuid.UUID]models.Item, error) {
+	var items []models.Item
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("id IN ?", itemIDs).Order("id").Find(&items).Error; err != nil {
+		return nil, err
+	}
+
+	locked := make(map[uuid.UUID]models.Item, len(items))
+	for _, item := range items {
+		locked[item.ID] = item
+	}
+	return locked, nil
+}
+
+// reservedQuantity returns the quantity of an item reserved by open carts, ignoring excludeCartID
+func reservedQuantity(tx *gorm.DB, itemID uuid.UUID, excludeCartID *uuid.UUID) (float64, error) {
+	query := tx.Model(&models.CartItem{}).
+		Joins("JOIN carts ON carts.id = cart_items.cart_id").
+		Where("cart_items.item_id = ? AND carts.status = ? AND carts.expires_at > ?", itemID, "open", time.Now())
+	if excludeCartID != nil {
+		query = query.Where("carts.id != ?", *excludeCartID)
+	}
+
+	var reserved float64
+	if err := query.Select("COALESCE(SUM(cart_items.quantity), 0)").Scan(&reserved).Error; err != nil {
+		return 0, err
+	}
+	return reserved, nil
+}
+
+// replaceCartItems replaces the lines of a cart, checking stock net of other carts' reservations
+// and the lines the way checkout will. Lines without a unit price are priced from the
+// customer's price list, or the item price, so the cart holds the price the customer was shown.
+func (s *CartService) replaceCartItems(tx *gorm.DB, shopID, cartID uuid.UUID, req models.CartRequest) error {
+	if err := tx.Where("cart_id = ?", cartID).Delete(&models.CartItem{}).Error; err != nil {
+		return err
+	}
+
+	for _, itemReq := range req.Items {
+		if itemReq.Quantity <= 0 {
+			return errors.New("quantity must be greater than 0")
+		}
+	}
+	calc, err := calculateBill(tx, shopID, time.Now(), models.BillRequest{
+		CustomerID: req.CustomerID,
+		Items:      req.Items,
+		Discount:   req.Discount,
+		TaxRate:    req.TaxRate,
+	})
+	if err != nil {
+		return err
+	}
+
+	// Holds and sales of the same items wait for each other, so that together they cannot
+	// reserve more stock than there is
+	itemIDs := make([]uuid.UUID, len(calc.Items))
+	for i, item := range calc.Items {
+		itemIDs[i] = item.ID
+	}
+	stock, err := lockStockItems(tx, itemIDs)
+	if err != nil {
+		return err
+	}
+
+	requested := make(map[uuid.UUID]int)
+	for i, itemReq := range req.Items {
+		item := stock[calc.Items[i].ID]
+
+		reserved, err := reservedQuantity(tx, item.ID, &cartID)
+		if err != nil {
+			return err
+		}
+		requested[item.ID] += itemReq.Quantity
+		if available := item.Quantity - reserved; float64(requested[item.ID]) > available {
+			return fmt.Errorf("insufficient stock for %s: %.2f available", item.Name, available)
+		}
+
+		cartItem := models.CartItem{
+			CartID:       cartID,
+			ItemID:       item.ID,
+			ItemName:     item.Name,
+			Description:  itemReq.Description,
+			Quantity:     itemReq.Quantity,
+			UnitPrice:    calc.Lines[i].UnitPrice,
+			Discount:     itemReq.Discount,
+			DiscountType: calc.Lines[i].DiscountType,
+			TaxInclusive: itemReq.TaxInclusive,
+		}
+		if err := tx.Create(&cartItem).Error; err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// getOpenCart loads a cart and checks that it can still be changed
+func (s *CartService) getOpenCart(tx *gorm.DB, cartID, shopID uuid.UUID) (*models.Cart, error) {
+	var cart models.Cart
+	if err := tx.Where("id = ? AND shop_id = ?", cartID, shopID).First(&cart).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("cart not found")
+		}
+		return nil, err
+	}
+
+	if cart.Status == "open" && !cart.ExpiresAt.After(time.Now()) {
+		return nil, errors.New("cart has expired")
+	}
+	if cart.Status != "open" {
+		return nil, fmt.Errorf("cart is %s", cart.Status)
+	}
+
+	return &cart, nil
+}
+
+// expiresAt calculates the expiry time of a cart from an optional override in minutes
+func (s *CartService) expiresAt(minutes int) time.Time {
+	expiry := s.expiry
+	if minutes > 0 {
+		expiry = time.Duration(minutes) * time.Minute
+	}
+	return time.Now().Add(expiry)
+}
+
+// getCartWithRelations fetches a cart with its customer and items
+func (s *CartService) getCartWithRelations(cartID, shopID uuid.UUID) (*models.CartResponse, error) {
+	var cart models.Cart
+	if err := s.db.Preload("Customer").Preload("Items").Where("id = ? AND shop_id = ?", cartID, shopID).First(&cart).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("cart not found")
+		}
+		return nil, err
+	}
+
+	response := s.cartToResponse(cart)
+	return &response, nil
+}
+
+// cartBillRequest builds the bill request a cart checks out as, less its dates and coupons
+func cartBillRequest(cart models.Cart) models.BillRequest {
+	billReq := models.BillRequest{
+		CustomerID: cart.CustomerID,
+		Discount:   cart.Discount,
+		TaxRate:    cart.TaxRate,
+		Notes:      cart.Notes,
+		Terms:      cart.Terms,
+	}
+	for _, item := range cart.Items {
+		unitPrice := item.UnitPrice // stored lines hold the price already resolved
+		billReq.Items = append(billReq.Items, models.BillItemRequest{
+			ItemID:       item.ItemID,
+			Quantity:     item.Quantity,
+			UnitPrice:    &unitPrice,
+			Description:  item.Description,
+			Discount:     item.Discount,
+			DiscountType: item.DiscountType,
+			TaxInclusive: item.TaxInclusive,
+		})
+	}
+	return billReq
+}
+
+// cartToResponse converts a Cart model to CartResponse, with totals worked out as they would be
+// if the cart were checked out today. Carts whose lines can no longer be priced, such as ones
+// holding a deleted item, only show their line total.
+func (s *CartService) cartToResponse(cart models.Cart) models.CartResponse {
+	var customer *models.CustomerResponse
+	if cart.Customer != nil {
+		customerResp := (&BillService{}).customerToResponse(*cart.Customer)
+		customer = &customerResp
+	}
+
+	status := cart.Status
+	if status == "open" && !cart.ExpiresAt.After(time.Now()) {
+		status = "expired"
+	}
+
+	calc := &billCalculation{}
+	if len(cart.Items) > 0 {
+		if priced, err := calculateBill(s.db, cart.ShopID, time.Now(), cartBillRequest(cart)); err == nil {
+			calc = priced
+		} else {
+			for _, item := range cart.Items {
+				calc.SubTotal += float64(item.Quantity) * item.UnitPrice
+			}
+			calc.TotalAmount = math.Max(0, calc.SubTotal-cart.Discount)
+		}
+	}
+
+	var items []models.CartItemResponse
+	for _, item := range cart.Items {
+		items = append(items, models.CartItemResponse{
+			ID:           item.ID,
+			ItemID:       item.ItemID,
+			ItemName:     item.ItemName,
+			Description:  item.Description,
+			Quantity:     item.Quantity,
+			UnitPrice:    item.UnitPrice,
+			TotalPrice:   float64(item.Quantity) * item.UnitPrice,
+			Discount:     item.Discount,
+			DiscountType: item.DiscountType,
+			TaxInclusive: item.TaxInclusive,
+		})
+	}
+
+	return models.CartResponse{
+		ID:                cart.ID,
+		ShopID:            cart.ShopID,
+		CustomerID:        cart.CustomerID,
+		Name:              cart.Name,
+		Status:            status,
+		SubTotal:          calc.SubTotal,
+		TaxAmount:         calc.TaxAmount,
+		Discount:          cart.Discount,
+		LineDiscount:      calc.LineDiscount,
+		PromotionDiscount: calc.PromotionDiscount,
+		TaxRate:           cart.TaxRate,
+		Total:             calc.TotalAmount,
+		Notes:             cart.Notes,
+		Terms:             cart.Terms,
+		ExpiresAt:         cart.ExpiresAt,
+		BillID:            cart.BillID,
+		CreatedBy:         cart.CreatedBy,
+		UpdatedBy:         cart.UpdatedBy,
+		Customer:          customer,
+		Items:             items,
+		CreatedAt:         cart.CreatedAt,
+		UpdatedAt:         cart.UpdatedAt,
+	}
+}
@@ -3,6 +3,7 @@ package services
 import (
 	"billboard/backend/models"
 	"errors"
+	"sort"
 	"time"
 
 	"github.com/google/uuid"
@@ -46,26 +47,37 @@ func (s *CustomerService) CreateCustomer(shopID, userID uuid.UUID, req models.Cu
 		}
 	}
 
+	if err := s.validateCustomerGroup(shopID, req.GroupID); err != nil {
+		return nil, err
+	}
+
 	// Create customer
 	customer := models.Customer{
-		ShopID:     shopID,
-		Name:       req.Name,
-		Email:      req.Email,
-		Phone:      req.Phone,
-		Address:    req.Address,
-		City:       req.City,
-		State:      req.State,
-		Country:    req.Country,
-		PostalCode: req.PostalCode,
-		TaxNumber:  req.TaxNumber,
-		Notes:      req.Notes,
-		IsActive:   req.IsActive,
+		ShopID:      shopID,
+		Name:        req.Name,
+		Email:       req.Email,
+		Phone:       req.Phone,
+		Address:     req.Address,
+		City:        req.City,
+		State:       req.State,
+		Country:     req.Country,
+		PostalCode:  req.PostalCode,
+		TaxNumber:   req.TaxNumber,
+		Notes:       req.Notes,
+		GroupID:     req.GroupID,
+		NoReminders: req.NoReminders,
+		IsActive:    req.IsActive,
 	}
 
 	if err := s.db.Create(&customer).Error; err != nil {
 		return nil, err
 	}
 
+	// Fetch with group
+	if err := s.db.Preload("Group").First(&customer, "id = ?", customer.ID).Error; err != nil {
+		return nil, err
+	}
+
 	response := s.customerToResponse(customer)
 	return &response, nil
 }
@@ -79,7 +91,7 @@ func (s *CustomerService) GetCustomers(shopID, userID uuid.UUID, filters map[str
 	}
 
 	var customers []models.Customer
-	query := s.db.Where("shop_id = ? AND deleted_at IS NULL", shopID)
+	query := s.db.Preload("Group").Where("shop_id = ? AND deleted_at IS NULL", shopID)
 
 	// Apply filters
 	if search, ok := filters["search"].(string); ok && search != "" {
@@ -94,6 +106,10 @@ func (s *CustomerService) GetCustomers(shopID, userID uuid.UUID, filters map[str
 		query = query.Where("city ILIKE ?", "%"+city+"%")
 	}
 
+	if groupID, ok := filters["group_id"].(uuid.UUID); ok {
+		query = query.Where("group_id = ?", groupID)
+	}
+
 	if err := query.Order("created_at DESC").Find(&customers).Error; err != nil {
 		return nil, err
 	}
@@ -115,7 +131,7 @@ func (s *CustomerService) GetCustomer(customerID, shopID, userID uuid.UUID) (*mo
 	}
 
 	var customer models.Customer
-	if err := s.db.Where("id = ? AND shop_id = ? AND deleted_at IS NULL", customerID, shopID).First(&customer).Error; err != nil {
+	if err := s.db.Preload("Group").Where("id = ? AND shop_id = ? AND deleted_at IS NULL", customerID, shopID).First(&customer).Error; err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
 			return nil, errors.New("customer not found")
 		}
@@ -158,20 +174,26 @@ func (s *CustomerService) UpdateCustomer(customerID, shopID, userID uuid.UUID, r
 		}
 	}
 
+	if err := s.validateCustomerGroup(shopID, req.GroupID); err != nil {
+		return nil, err
+	}
+
 	// Update customer
 	updates := map[string]interface{}{
-		"name":        req.Name,
-		"email":       req.Email,
-		"phone":       req.Phone,
-		"address":     req.Address,
-		"city":        req.City,
-		"state":       req.State,
-		"country":     req.Country,
-		"postal_code": req.PostalCode,
-		"tax_number":  req.TaxNumber,
-		"notes":       req.Notes,
-		"is_active":   req.IsActive,
-		"updated_at":  time.Now(),
+		"name":         req.Name,
+		"email":        req.Email,
+		"phone":        req.Phone,
+		"address":      req.Address,
+		"city":         req.City,
+		"state":        req.State,
+		"country":      req.Country,
+		"postal_code":  req.PostalCode,
+		"tax_number":   req.TaxNumber,
+		"notes":        req.Notes,
+		"group_id":     req.GroupID,
+		"no_reminders": req.NoReminders,
+		"is_active":    req.IsActive,
+		"updated_at":   time.Now(),
 	}
 
 	if err := s.db.Model(&models.Customer{}).Where("id = ?", customerID).Updates(updates).Error; err != nil {
@@ -179,7 +201,7 @@ func (s *CustomerService) UpdateCustomer(customerID, shopID, userID uuid.UUID, r
 	}
 
 	// Fetch updated customer
-	if err := s.db.First(&customer, "id = ?", customerID).Error; err != nil {
+	if err := s.db.Preload("Group").First(&customer, "id = ?", customerID).Error; err != nil {
 		return nil, err
 	}
 
@@ -239,23 +261,181 @@ func (s *CustomerService) GetCustomerStats(shopID, userID uuid.UUID) (map[string
 	}, nil
 }
 
+// GetStatement builds a customer's statement for a date range: their bills and late fees are
+// debited, payments and waived late fees credited, with the balance brought forward from
+// before the range
+func (s *CustomerService) GetStatement(customerID, shopID, userID uuid.UUID, from, to string) (*models.CustomerStatement, error) {
+	// Check if user has access to the shop
+	var shopUser models.ShopUser
+	if err := s.db.Where("shop_id = ? AND user_id = ? AND is_active = ?", shopID, userID, true).First(&shopUser).Error; err != nil {
+		return nil, errors.New("access denied to shop")
+	}
+
+	var customer models.Customer
+	if err := s.db.Preload("Group").Where("id = ? AND shop_id = ? AND deleted_at IS NULL", customerID, shopID).First(&customer).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("customer not found")
+		}
+		return nil, err
+	}
+
+	fromDate, toDate, err := reportDateRange(from, to)
+	if err != nil {
+		return nil, err
+	}
+	end := toDate.AddDate(0, 0, 1)
+
+	var bills []models.Bill
+	if err := s.db.Preload("Payments").
+		Where("shop_id = ? AND customer_id = ? AND deleted_at IS NULL AND status <> ? AND bill_date < ?",
+			shopID, customerID, "cancelled", end).
+		Find(&bills).Error; err != nil {
+		return nil, err
+	}
+	billIDs := make([]uuid.UUID, 0, len(bills))
+	for _, bill := range bills {
+		billIDs = append(billIDs, bill.ID)
+	}
+	var charges []models.LateFeeCharge
+	if len(billIDs) > 0 {
+		if err := s.db.Preload("Bill", func(db *gorm.DB) *gorm.DB { return db.Select("id", "bill_number") }).
+			Where("bill_id IN ? AND charge_date < ?", billIDs, end).
+			Find(&charges).Error; err != nil {
+			return nil, err
+		}
+	}
+
+	// Every document up to the end of the range, in the order it is listed on a day
+	var lines []models.CustomerStatementLine
+	for _, bill := range bills {
+		billID := bill.ID
+		lines = append(lines, models.CustomerStatementLine{
+			Date:        bill.BillDate,
+			Type:        "bill",
+			Reference:   bill.BillNumber,
+			Description: "Bill " + bill.BillNumber,
+			BillID:      &billID,
+			Debit:       bill.TotalAmount,
+		})
+		for _, payment := range bill.Payments {
+			if !payment.PaymentDate.Before(end) {
+				continue
+			}
+			description := "Payment for bill " + bill.BillNumber
+			if payment.Reference != "" {
+				description += ", ref " + payment.Reference
+			}
+			lines = append(lines, models.CustomerStatementLine{
+				Date:        payment.PaymentDate,
+				Type:        "payment",
+				Reference:   bill.BillNumber,
+				Description: description,
+				BillID:      &billID,
+				Credit:      payment.Amount,
+			})
+		}
+	}
+	for _, charge := range charges {
+		billID := charge.BillID
+		lines = append(lines, models.CustomerStatementLine{
+			Date:        charge.ChargeDate,
+			Type:        "late_fee",
+			Reference:   charge.NoteNumber,
+			Description: lateFeeChargeDescription(charge),
+			BillID:      &billID,
+			Debit:       charge.Amount,
+		})
+		if charge.Status == "waived" && charge.WaivedAt != nil && charge.WaivedAt.Before(end) {
+			lines = append(lines, models.CustomerStatementLine{
+				Date:        lateFeeDate(*charge.WaivedAt),
+				Type:        "late_fee_waiver",
+				Reference:   charge.NoteNumber,
+				Description: "Late fee " + charge.NoteNumber + " waived",
+				BillID:      &billID,
+				Credit:      charge.Amount,
+			})
+		}
+	}
+	order := map[string]int{"bill": 0, "late_fee": 1, "late_fee_waiver": 2, "payment": 3}
+	sort.SliceStable(lines, func(i, j int) bool {
+		if !lines[i].Date.Equal(lines[j].Date) {
+			return lines[i].Date.Before(lines[j].Date)
+		}
+		return order[lines[i].Type] < order[lines[j].Type]
+	})
+
+	statement := &models.CustomerStatement{
+		Customer: s.customerToResponse(customer),
+		From:     fromDate,
+		To:       toDate,
+		Lines:    []models.CustomerStatementLine{},
+	}
+	balance := 0.0
+	for _, line := range lines {
+		balance = roundAmount(balance + line.Debit - line.Credit)
+		if line.Date.Before(fromDate) {
+			statement.OpeningBalance = balance
+			continue
+		}
+		line.Balance = balance
+		statement.Lines = append(statement.Lines, line)
+		statement.TotalDebit += line.Debit
+		statement.TotalCredit += line.Credit
+		switch line.Type {
+		case "late_fee":
+			statement.LateFees += line.Debit
+		case "late_fee_waiver":
+			statement.LateFees -= line.Credit
+		}
+	}
+	statement.TotalDebit = roundAmount(statement.TotalDebit)
+	statement.TotalCredit = roundAmount(statement.TotalCredit)
+	statement.LateFees = roundAmount(statement.LateFees)
+	statement.ClosingBalance = balance
+
+	return statement, nil
+}
+
+// validateCustomerGroup checks that a customer group belongs to the shop
+func (s *CustomerService) validateCustomerGroup(shopID uuid.UUID, groupID *uuid.UUID) error {
+	if groupID == nil {
+		return nil
+	}
+
+	var count int64
+	if err := s.db.Model(&models.CustomerGroup{}).Where("id = ? AND shop_id = ?", *groupID, shopID).Count(&count).Error; err != nil {
+		return err
+	}
+	if count == 0 {
+		return errors.New("customer group not found")
+	}
+	return nil
+}
+
 // customerToResponse converts a Customer model to CustomerResponse
 func (s *CustomerService) customerToResponse(customer models.Customer) models.CustomerResponse {
-	return models.CustomerResponse{
-		ID:         customer.ID,
-		ShopID:     customer.ShopID,
-		Name:       customer.Name,
-		Email:      customer.Email,
-		Phone:      customer.Phone,
-		Address:    customer.Address,
-		City:       customer.City,
-		State:      customer.State,
-		Country:    customer.Country,
-		PostalCode: customer.PostalCode,
-		TaxNumber:  customer.TaxNumber,
-		Notes:      customer.Notes,
-		IsActive:   customer.IsActive,
-		CreatedAt:  customer.CreatedAt,
-		UpdatedAt:  customer.UpdatedAt,
+	response := models.CustomerResponse{
+		ID:            customer.ID,
+		ShopID:        customer.ShopID,
+		Name:          customer.Name,
+		Email:         customer.Email,
+		Phone:         customer.Phone,
+		Address:       customer.Address,
+		City:          customer.City,
+		State:         customer.State,
+		Country:       customer.Country,
+		PostalCode:    customer.PostalCode,
+		TaxNumber:     customer.TaxNumber,
+		Notes:         customer.Notes,
+		GroupID:       customer.GroupID,
+		LoyaltyPoints: customer.LoyaltyPoints,
+		NoReminders:   customer.NoReminders,
+		IsActive:      customer.IsActive,
+		CreatedAt:     customer.CreatedAt,
+		UpdatedAt:     customer.UpdatedAt,
+	}
+	if customer.Group != nil {
+		response.GroupName = customer.Group.Name
 	}
+	return response
 }
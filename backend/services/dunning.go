@@ -0,0 +1,610 @@
+package services
+
+import (
+	"billboard/backend/models"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// DunningService sends payment reminders for open bills on each shop's reminder schedule
+type DunningService struct {
+	db                  *gorm.DB
+	emailService        *EmailService
+	notificationService *NotificationService
+	client              *http.Client
+}
+
+// NewDunningService creates a new DunningService instance
+func NewDunningService(db *gorm.DB, emailService *EmailService, notificationService *NotificationService) *DunningService {
+	return &DunningService{db: db, emailService: emailService, notificationService: notificationService, client: &http.Client{Timeout: 15 * time.Second}}
+}
+
+// GetSteps lists a shop's reminder schedule in the order the steps are taken
+func (s *DunningService) GetSteps(shopID, userID uuid.UUID) ([]models.DunningStepResponse, error) {
+	// Check if user has access to the shop
+	var shopUser models.ShopUser
+	if err := s.db.Where("shop_id = ? AND user_id = ? AND is_active = ?", shopID, userID, true).First(&shopUser).Error; err != nil {
+		return nil, errors.New("access denied to shop")
+	}
+
+	var steps []models.DunningStep
+	if err := s.db.Where("shop_id = ?", shopID).Order("days_from_due, created_at").Find(&steps).Error; err != nil {
+		return nil, err
+	}
+
+	responses := make([]models.DunningStepResponse, len(steps))
+	for i, step := range steps {
+		responses[i] = dunningStepToResponse(step)
+	}
+	return responses, nil
+}
+
+// CreateStep adds a step to a shop's reminder schedule
+func (s *DunningService) CreateStep(shopID, userID uuid.UUID, req models.DunningStepRequest) (*models.DunningStepResponse, error) {
+	// Check if user has access to the shop
+	var shopUser models.ShopUser
+	if err := s.db.Where("shop_id = ? AND user_id = ? AND is_active = ?", shopID, userID, true).First(&shopUser).Error; err != nil {
+		return nil, errors.New("access denied to shop")
+	}
+
+	if err := s.checkChannel(req.Channel); err != nil {
+		return nil, err
+	}
+	step := models.DunningStep{ShopID: shopID, CreatedBy: userID.String()}
+	if err := applyDunningStepRequest(&step, req); err != nil {
+		return nil, err
+	}
+	if err := s.db.Create(&step).Error; err != nil {
+		return nil, err
+	}
+
+	response := dunningStepToResponse(step)
+	return &response, nil
+}
+
+// UpdateStep changes a step of a shop's reminder schedule. Bills that already had the step
+// are not reminded again.
+func (s *DunningService) UpdateStep(stepID, shopID, userID uuid.UUID, req models.DunningStepRequest) (*models.DunningStepResponse, error) {
+	// Check if user has access to the shop
+	var shopUser models.ShopUser
+	if err := s.db.Where("shop_id = ? AND user_id = ? AND is_active = ?", shopID, userID, true).First(&shopUser).Error; err != nil {
+		return nil, errors.New("access denied to shop")
+	}
+
+	var step models.DunningStep
+	if err := s.db.Where("id = ? AND shop_id = ?", stepID, shopID).First(&step).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("dunning step not found")
+		}
+		return nil, err
+	}
+	if err := s.checkChannel(req.Channel); err != nil {
+		return nil, err
+	}
+	if err := applyDunningStepRequest(&step, req); err != nil {
+		return nil, err
+	}
+	if err := s.db.Save(&step).Error; err != nil {
+		return nil, err
+	}
+
+	response := dunningStepToResponse(step)
+	return &response, nil
+}
+
+// DeleteStep removes a step from a shop's reminder schedule. Reminders already sent for it
+// stay in the bills' history.
+func (s *DunningService) DeleteStep(stepID, shopID, userID uuid.UUID) error {
+	// Check if user has access to the shop
+	var shopUser models.ShopUser
+	if err := s.db.Where("shop_id = ? AND user_id = ? AND is_active = ?", shopID, userID, true).First(&shopUser).Error; err != nil {
+		return errors.New("access denied to shop")
+	}
+
+	result := s.db.Where("id = ? AND shop_id = ?", stepID, shopID).Delete(&models.DunningStep{})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return errors.New("dunning step not found")
+	}
+	return nil
+}
+
+// GetReminders lists a shop's reminders, optionally by status, most recent first
+func (s *DunningService) GetReminders(shopID, userID uuid.UUID, status string) ([]models.PaymentReminderResponse, error) {
+	// Check if user has access to the shop
+	var shopUser models.ShopUser
+	if err := s.db.Where("shop_id = ? AND user_id = ? AND is_active = ?", shopID, userID, true).First(&shopUser).Error; err != nil {
+		return nil, errors.New("access denied to shop")
+	}
+
+	query := s.db.Where("shop_id = ?", shopID)
+	if status != "" {
+		query = query.Where("status = ?", status)
+	}
+	var reminders []models.PaymentReminder
+	if err := query.Order("created_at DESC").Limit(200).Find(&reminders).Error; err != nil {
+		return nil, err
+	}
+	return s.remindersToResponse(reminders)
+}
+
+// GetBillReminders lists the reminders sent for a bill, most recent first
+func (s *DunningService) GetBillReminders(billID, shopID, userID uuid.UUID) ([]models.PaymentReminderResponse, error) {
+	// Check if user has access to the shop
+	var shopUser models.ShopUser
+	if err := s.db.Where("shop_id = ? AND user_id = ? AND is_active = ?", shopID, userID, true).First(&shopUser).Error; err != nil {
+		return nil, errors.New("access denied to shop")
+	}
+
+	var reminders []models.PaymentReminder
+	if err := s.db.Where("bill_id = ? AND shop_id = ?", billID, shopID).Order("created_at DESC").Find(&reminders).Error; err != nil {
+		return nil, err
+	}
+	return s.remindersToResponse(reminders)
+}
+
+// SetBillExclusion leaves a bill out of payment reminders, or puts it back on the schedule
+func (s *DunningService) SetBillExclusion(billID, shopID, userID uuid.UUID, excluded bool) error {
+	// Check if user has access to the shop
+	var shopUser models.ShopUser
+	if err := s.db.Where("shop_id = ? AND user_id = ? AND is_active = ?", shopID, userID, true).First(&shopUser).Error; err != nil {
+		return errors.New("access denied to shop")
+	}
+
+	result := s.db.Model(&models.Bill{}).Where("id = ? AND shop_id = ? AND deleted_at IS NULL", billID, shopID).
+		Updates(map[string]interface{}{"no_reminders": excluded, "updated_at": time.Now()})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return errors.New("bill not found")
+	}
+	return nil
+}
+
+// RunShopReminders takes the due steps of a shop's schedule straight away
+func (s *DunningService) RunShopReminders(shopID, userID uuid.UUID) (*models.DunningRunResult, error) {
+	// Check if user has access to the shop
+	var shopUser models.ShopUser
+	if err := s.db.Where("shop_id = ? AND user_id = ? AND is_active = ?", shopID, userID, true).First(&shopUser).Error; err != nil {
+		return nil, errors.New("access denied to shop")
+	}
+
+	result, err := s.runShop(shopID, recurringBillToday())
+	if err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+// SendDueReminders takes the due steps of every shop's schedule and returns how many
+// reminders were sent
+func (s *DunningService) SendDueReminders() (int, error) {
+	var shopIDs []uuid.UUID
+	if err := s.db.Model(&models.DunningStep{}).Where("is_active = ?", true).Distinct("shop_id").Pluck("shop_id", &shopIDs).Error; err != nil {
+		return 0, err
+	}
+
+	today := recurringBillToday()
+	sent := 0
+	for _, shopID := range shopIDs {
+		result, err := s.runShop(shopID, today)
+		if err != nil {
+			log.Printf("Failed to send payment reminders for shop %s: %v", shopID, err)
+			continue
+		}
+		sent += result.Sent
+	}
+	return sent, nil
+}
+
+// StartScheduler periodically sends due payment reminders in the background
+func (s *DunningService) StartScheduler(interval time.Duration) {
+	if s.db == nil {
+		return
+	}
+
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for range ticker.C {
+			if count, err := s.SendDueReminders(); err != nil {
+				log.Printf("Failed to send payment reminders: %v", err)
+			} else if count > 0 {
+				log.Printf("Sent %d payment reminders", count)
+			}
+		}
+	}()
+}
+
+// runShop evaluates a shop's open bills against its schedule. When several steps have come
+// due for a bill since it was last looked at, only the latest is sent and the earlier ones
+// are recorded as skipped, so a customer never gets a burst of reminders.
+func (s *DunningService) runShop(shopID uuid.UUID, today time.Time) (models.DunningRunResult, error) {
+	var result models.DunningRunResult
+
+	var steps []models.DunningStep
+	if err := s.db.Where("shop_id = ? AND is_active = ?", shopID, true).Order("days_from_due, created_at").Find(&steps).Error; err != nil {
+		return result, err
+	}
+	if len(steps) == 0 {
+		return result, nil
+	}
+
+	// Bills whose earliest step has come due; excluded bills and customers are left out
+	var bills []models.Bill
+	if err := s.db.Preload("Shop").Preload("Customer").
+		Where("shop_id = ? AND deleted_at IS NULL AND status <> ? AND balance > 0 AND due_date IS NOT NULL AND no_reminders = ?",
+			shopID, "cancelled", false).
+		Where("due_date < ?", today.AddDate(0, 0, 1-steps[0].DaysFromDue)).
+		Where("customer_id IS NULL OR customer_id NOT IN (?)",
+			s.db.Model(&models.Customer{}).Select("id").Where("shop_id = ? AND no_reminders = ?", shopID, true)).
+		Find(&bills).Error; err != nil {
+		return result, err
+	}
+	if len(bills) == 0 {
+		return result, nil
+	}
+
+	billIDs := make([]uuid.UUID, len(bills))
+	for i, bill := range bills {
+		billIDs[i] = bill.ID
+	}
+	var taken []models.PaymentReminder
+	if err := s.db.Select("bill_id", "step_id").Where("bill_id IN ?", billIDs).Find(&taken).Error; err != nil {
+		return result, err
+	}
+	done := map[uuid.UUID]map[uuid.UUID]bool{}
+	for _, reminder := range taken {
+		if done[reminder.BillID] == nil {
+			done[reminder.BillID] = map[uuid.UUID]bool{}
+		}
+		done[reminder.BillID][reminder.StepID] = true
+	}
+
+	for _, bill := range bills {
+		result.BillsChecked++
+		due := time.Date(bill.DueDate.Year(), bill.DueDate.Month(), bill.DueDate.Day(), 0, 0, 0, 0, time.UTC)
+
+		var pending []models.DunningStep
+		for _, step := range steps {
+			if !done[bill.ID][step.ID] && !due.AddDate(0, 0, step.DaysFromDue).After(today) {
+				pending = append(pending, step)
+			}
+		}
+		if len(pending) == 0 {
+			continue
+		}
+
+		latest := pending[len(pending)-1]
+		for _, step := range pending[:len(pending)-1] {
+			if s.claim(bill, step, "skipped", "superseded by the "+latest.Name+" reminder") != nil {
+				result.Skipped++
+			}
+		}
+
+		reminder := s.claim(bill, latest, "sending", "")
+		if reminder == nil {
+			continue
+		}
+		s.send(reminder, bill, latest)
+		switch reminder.Status {
+		case "sent", "queued":
+			result.Sent++
+		case "skipped":
+			result.Skipped++
+		default:
+			result.Failed++
+		}
+	}
+
+	return result, nil
+}
+
+// claim records a step taken for a bill. It returns nil when the bill already had the step,
+// such as when another run got to it first.
+func (s *DunningService) claim(bill models.Bill, step models.DunningStep, status, reason string) *models.PaymentReminder {
+	reminder := models.PaymentReminder{
+		ShopID:      bill.ShopID,
+		BillID:      bill.ID,
+		StepID:      step.ID,
+		StepName:    step.Name,
+		DaysFromDue: step.DaysFromDue,
+		Channel:     step.Channel,
+		Balance:     bill.Balance,
+		Status:      status,
+		Error:       reason,
+	}
+	// The unique bill and step index rejects a step already taken
+	if err := s.db.Create(&reminder).Error; err != nil {
+		return nil
+	}
+	return &reminder
+}
+
+// send delivers a claimed reminder through its step's channel and records the outcome
+func (s *DunningService) send(reminder *models.PaymentReminder, bill models.Bill, step models.DunningStep) {
+	var err error
+	switch step.Channel {
+	case "email":
+		err = s.sendEmail(reminder, bill, step)
+	case "sms", "whatsapp":
+		err = s.sendNotification(reminder, bill, step)
+	case "webhook":
+		err = s.sendWebhook(reminder, bill, step)
+	default:
+		reminder.Status = "skipped"
+		reminder.Error = "unknown reminder channel " + step.Channel
+	}
+	if err != nil {
+		reminder.Status = "failed"
+		reminder.Error = err.Error()
+	}
+
+	if err := s.db.Model(&models.PaymentReminder{}).Where("id = ?", reminder.ID).Updates(map[string]interface{}{
+		"status":                   reminder.Status,
+		"error":                    reminder.Error,
+		"recipient":                reminder.Recipient,
+		"email_delivery_id":        reminder.EmailDeliveryID,
+		"notification_delivery_id": reminder.NotificationDeliveryID,
+		"updated_at":               time.Now(),
+	}).Error; err != nil {
+		log.Printf("Failed to record payment reminder %s: %v", reminder.ID, err)
+	}
+}
+
+// sendEmail emails a reminder to the bill's customer. The delivery is retried by the email
+// queue when the server is unavailable.
+func (s *DunningService) sendEmail(reminder *models.PaymentReminder, bill models.Bill, step models.DunningStep) error {
+	if bill.Customer == nil || bill.Customer.Email == "" {
+		reminder.Status = "skipped"
+		reminder.Error = "customer has no email address"
+		return nil
+	}
+	reminder.Recipient = bill.Customer.Email
+
+	tmpl, err := s.stepTemplate(bill.ShopID, step)
+	if err != nil {
+		return err
+	}
+	delivery, err := s.emailService.sendBillEmail(bill, "reminder", tmpl, []string{bill.Customer.Email}, nil, true, step.CreatedBy)
+	if err != nil {
+		return err
+	}
+	reminder.EmailDeliveryID = &delivery.ID
+	reminder.Status = delivery.Status
+	reminder.Error = delivery.LastError
+	return nil
+}
+
+// sendNotification texts a reminder to the bill's customer by SMS or WhatsApp, with the step's
+// message or the shop's reminder template. The message is retried by the notification queue
+// when the provider is unavailable.
+func (s *DunningService) sendNotification(reminder *models.PaymentReminder, bill models.Bill, step models.DunningStep) error {
+	if bill.Customer == nil || bill.Customer.Phone == "" {
+		reminder.Status = "skipped"
+		reminder.Error = "customer has no phone number"
+		return nil
+	}
+	reminder.Recipient = bill.Customer.Phone
+
+	delivery, err := s.notificationService.sendBillNotification(bill, nil, "reminder", step.Channel, bill.Customer.Phone, step.Body, step.CreatedBy, nil)
+	if err != nil {
+		return err
+	}
+	reminder.Recipient = delivery.Recipient
+	reminder.NotificationDeliveryID = &delivery.ID
+	reminder.Status = delivery.Status
+	reminder.Error = delivery.LastError
+	return nil
+}
+
+// sendWebhook posts a reminder to the step's webhook, signed with an X-Signature HMAC-SHA256
+// of the body when the step has a secret
+func (s *DunningService) sendWebhook(reminder *models.PaymentReminder, bill models.Bill, step models.DunningStep) error {
+	if step.WebhookURL == "" {
+		return errors.New("dunning step has no webhook URL")
+	}
+	reminder.Recipient = step.WebhookURL
+
+	tmpl, err := s.stepTemplate(bill.ShopID, step)
+	if err != nil {
+		return err
+	}
+	data := billEmailDataFor(bill)
+	_, message, err := renderEmailTemplate(tmpl.Subject, tmpl.Body, data)
+	if err != nil {
+		return err
+	}
+
+	type webhookCustomer struct {
+		ID    uuid.UUID `json:"id"`
+		Name  string    `json:"name"`
+		Email string    `json:"email"`
+		Phone string    `json:"phone"`
+	}
+	payload := struct {
+		Event      string           `json:"event"`
+		ReminderID uuid.UUID        `json:"reminder_id"`
+		ShopID     uuid.UUID        `json:"shop_id"`
+		StepName   string           `json:"step_name"`
+		BillID     uuid.UUID        `json:"bill_id"`
+		BillNumber string           `json:"bill_number"`
+		BillDate   time.Time        `json:"bill_date"`
+		DueDate    *time.Time       `json:"due_date"`
+		Total      float64          `json:"total_amount"`
+		Balance    float64          `json:"balance"`
+		DaysLate   int              `json:"days_overdue"`
+		Customer   *webhookCustomer `json:"customer"`
+		Message    string           `json:"message"`
+	}{
+		Event:      "payment_reminder",
+		ReminderID: reminder.ID,
+		ShopID:     bill.ShopID,
+		StepName:   step.Name,
+		BillID:     bill.ID,
+		BillNumber: bill.BillNumber,
+		BillDate:   bill.BillDate,
+		DueDate:    bill.DueDate,
+		Total:      bill.TotalAmount,
+		Balance:    bill.Balance,
+		DaysLate:   data.DaysOverdue,
+		Message:    message,
+	}
+	if bill.Customer != nil {
+		payload.Customer = &webhookCustomer{ID: bill.Customer.ID, Name: bill.Customer.Name, Email: bill.Customer.Email, Phone: bill.Customer.Phone}
+	}
+
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequest(http.MethodPost, step.WebhookURL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if step.WebhookSecret != "" {
+		req.Header.Set("X-Signature", hmacSHA256Hex(body, step.WebhookSecret))
+	}
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("webhook unavailable: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("webhook returned status %d", resp.StatusCode)
+	}
+	reminder.Status = "sent"
+	return nil
+}
+
+// stepTemplate returns a step's own subject and body, falling back to the shop's reminder
+// template for either
+func (s *DunningService) stepTemplate(shopID uuid.UUID, step models.DunningStep) (models.EmailTemplateRequest, error) {
+	tmpl, err := s.emailService.shopTemplate(shopID, "reminder")
+	if err != nil {
+		return tmpl, err
+	}
+	if step.Subject != "" {
+		tmpl.Subject = step.Subject
+	}
+	if step.Body != "" {
+		tmpl.Body = step.Body
+	}
+	return tmpl, nil
+}
+
+// remindersToResponse converts reminders to their responses with their bill numbers
+func (s *DunningService) remindersToResponse(reminders []models.PaymentReminder) ([]models.PaymentReminderResponse, error) {
+	billIDs := make([]uuid.UUID, 0, len(reminders))
+	for _, reminder := range reminders {
+		billIDs = append(billIDs, reminder.BillID)
+	}
+	numbers := map[uuid.UUID]string{}
+	if len(billIDs) > 0 {
+		var bills []models.Bill
+		if err := s.db.Select("id", "bill_number").Where("id IN ?", billIDs).Find(&bills).Error; err != nil {
+			return nil, err
+		}
+		for _, bill := range bills {
+			numbers[bill.ID] = bill.BillNumber
+		}
+	}
+
+	responses := make([]models.PaymentReminderResponse, len(reminders))
+	for i, reminder := range reminders {
+		responses[i] = models.PaymentReminderResponse{
+			ID:                     reminder.ID,
+			BillID:                 reminder.BillID,
+			BillNumber:             numbers[reminder.BillID],
+			StepID:                 reminder.StepID,
+			StepName:               reminder.StepName,
+			DaysFromDue:            reminder.DaysFromDue,
+			Channel:                reminder.Channel,
+			Recipient:              reminder.Recipient,
+			Balance:                reminder.Balance,
+			Status:                 reminder.Status,
+			Error:                  reminder.Error,
+			EmailDeliveryID:        reminder.EmailDeliveryID,
+			NotificationDeliveryID: reminder.NotificationDeliveryID,
+			CreatedAt:              reminder.CreatedAt,
+		}
+	}
+	return responses, nil
+}
+
+// checkChannel refuses SMS and WhatsApp steps when no provider is configured to deliver them
+func (s *DunningService) checkChannel(channel string) error {
+	if (channel == "sms" || channel == "whatsapp") && !s.notificationService.channelAvailable(channel) {
+		return fmt.Errorf("%s notifications are not available", notificationChannelName(channel))
+	}
+	return nil
+}
+
+// applyDunningStepRequest validates a step request and copies it onto the step. Templates
+// are checked by filling them with sample data.
+func applyDunningStepRequest(step *models.DunningStep, req models.DunningStepRequest) error {
+	if req.Channel == "webhook" && req.WebhookURL == "" {
+		return errors.New("webhook steps need a webhook URL")
+	}
+	if (req.Channel == "sms" || req.Channel == "whatsapp") && req.Body != "" {
+		tmpl := models.NotificationTemplate{Channel: req.Channel, Event: "reminder", Body: req.Body}
+		if _, _, err := renderNotificationTemplate(tmpl, sampleBillNotificationData); err != nil {
+			return err
+		}
+	} else if req.Subject != "" || req.Body != "" {
+		subject, body := req.Subject, req.Body
+		if subject == "" {
+			subject = defaultEmailTemplates["reminder"].Subject
+		}
+		if body == "" {
+			body = defaultEmailTemplates["reminder"].Body
+		}
+		if _, _, err := renderEmailTemplate(subject, body, sampleBillEmailData); err != nil {
+			return err
+		}
+	}
+
+	step.Name = req.Name
+	step.DaysFromDue = req.DaysFromDue
+	step.Channel = req.Channel
+	step.Subject = req.Subject
+	step.Body = req.Body
+	step.WebhookURL = req.WebhookURL
+	if req.WebhookSecret != nil {
+		step.WebhookSecret = *req.WebhookSecret
+	}
+	step.IsActive = req.IsActive == nil || *req.IsActive
+	return nil
+}
+
+// dunningStepToResponse converts a dunning step to its response, leaving out the webhook secret
+func dunningStepToResponse(step models.DunningStep) models.DunningStepResponse {
+	return models.DunningStepResponse{
+		ID:               step.ID,
+		Name:             step.Name,
+		DaysFromDue:      step.DaysFromDue,
+		Channel:          step.Channel,
+		Subject:          step.Subject,
+		Body:             step.Body,
+		WebhookURL:       step.WebhookURL,
+		WebhookSecretSet: step.WebhookSecret != "",
+		IsActive:         step.IsActive,
+		CreatedBy:        step.CreatedBy,
+		CreatedAt:        step.CreatedAt,
+		UpdatedAt:        step.UpdatedAt,
+	}
+}
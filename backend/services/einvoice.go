@@ -0,0 +1,403 @@
+package services
+
+import (
+	"billboard/backend/models"
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// EInvoiceService registers bills as e-invoices with the Invoice Registration Portal
+type EInvoiceService struct {
+	db  *gorm.DB
+	irp IRPClient
+}
+
+// NewEInvoiceService creates a new EInvoiceService instance. A nil IRP client leaves
+// e-invoicing disabled; payloads can still be built but not registered.
+func NewEInvoiceService(db *gorm.DB, irp IRPClient) *EInvoiceService {
+	return &EInvoiceService{db: db, irp: irp}
+}
+
+// eInvoiceCancelWindow is how long after registration the IRP allows an e-invoice to be cancelled
+const eInvoiceCancelWindow = 24 * time.Hour
+
+// eInvoiceCancelReasons maps cancellation reasons to the IRP's reason codes
+var eInvoiceCancelReasons = map[string]string{
+	"duplicate":          "1",
+	"data_entry_mistake": "2",
+	"order_cancelled":    "3",
+	"other":              "4",
+}
+
+// errEInvoicingDisabled is returned when no IRP has been configured
+var errEInvoicingDisabled = errors.New("e-invoicing is not configured; set EINVOICE_PROVIDER to enable it")
+
+var pinCodePattern = regexp.MustCompile(`\b[1-9][0-9]{5}\b`)
+
+// GetPayload builds the INV-01 e-invoice JSON for a bill without registering it
+func (s *EInvoiceService) GetPayload(billID, shopID, userID uuid.UUID) (*models.EInvoice, error) {
+	// Check if user has access to the shop
+	var shopUser models.ShopUser
+	if err := s.db.Where("shop_id = ? AND user_id = ? AND is_active = ?", shopID, userID, true).First(&shopUser).Error; err != nil {
+		return nil, errors.New("access denied to shop")
+	}
+
+	bill, err := s.loadBill(billID, shopID)
+	if err != nil {
+		return nil, err
+	}
+
+	return buildEInvoice(*bill)
+}
+
+// GetEInvoice returns the registration details of a bill's e-invoice
+func (s *EInvoiceService) GetEInvoice(billID, shopID, userID uuid.UUID) (*models.EInvoiceResponse, error) {
+	// Check if user has access to the shop
+	var shopUser models.ShopUser
+	if err := s.db.Where("shop_id = ? AND user_id = ? AND is_active = ?", shopID, userID, true).First(&shopUser).Error; err != nil {
+		return nil, errors.New("access denied to shop")
+	}
+
+	var bill models.Bill
+	if err := s.db.Where("id = ? AND shop_id = ?", billID, shopID).First(&bill).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("bill not found")
+		}
+		return nil, err
+	}
+
+	response := eInvoiceToResponse(bill)
+	return &response, nil
+}
+
+// Generate registers a bill with the IRP and stores the IRN, acknowledgement and signed QR code.
+// A registered draft bill is issued, since its amounts can no longer change.
+func (s *EInvoiceService) Generate(billID, shopID, userID uuid.UUID) (*models.EInvoiceResponse, error) {
+	// Check if user has access to the shop
+	var shopUser models.ShopUser
+	if err := s.db.Where("shop_id = ? AND user_id = ? AND is_active = ?", shopID, userID, true).First(&shopUser).Error; err != nil {
+		return nil, errors.New("access denied to shop")
+	}
+
+	if s.irp == nil {
+		return nil, errEInvoicingDisabled
+	}
+
+	bill, err := s.loadBill(billID, shopID)
+	if err != nil {
+		return nil, err
+	}
+
+	switch {
+	case bill.Status == "cancelled":
+		return nil, errors.New("cancelled bills cannot be e-invoiced")
+	case bill.IRNStatus == "generated":
+		return nil, errors.New("bill already has an e-invoice")
+	case bill.IRNStatus == "cancelled":
+		return nil, errors.New("e-invoice was cancelled; the invoice number cannot be registered again")
+	}
+
+	invoice, err := buildEInvoice(*bill)
+	if err != nil {
+		return nil, err
+	}
+
+	registration, err := s.irp.GenerateIRN(invoice.SellerDtls.Gstin, *invoice)
+	if err != nil {
+		return nil, fmt.Errorf("IRP rejected the invoice: %v", err)
+	}
+
+	bill.IRN = registration.IRN
+	bill.IRNStatus = "generated"
+	bill.IRNAckNo = registration.AckNo
+	bill.IRNAckDate = &registration.AckDate
+	bill.IRNSignedQR = registration.SignedQRCode
+	if err := s.db.Model(&models.Bill{}).Where("id = ?", bill.ID).Updates(map[string]interface{}{
+		"irn":           bill.IRN,
+		"irn_status":    bill.IRNStatus,
+		"irn_ack_no":    bill.IRNAckNo,
+		"irn_ack_date":  bill.IRNAckDate,
+		"irn_signed_qr": bill.IRNSignedQR,
+		"updated_at":    time.Now(),
+	}).Error; err != nil {
+		return nil, err
+	}
+	if err := issueBill(s.db, bill); err != nil {
+		return nil, err
+	}
+
+	response := eInvoiceToResponse(*bill)
+	return &response, nil
+}
+
+// Cancel cancels a bill's e-invoice with the IRP. The IRP only allows this within 24 hours of
+// registration; after that the invoice has to be reversed with a credit note.
+func (s *EInvoiceService) Cancel(billID, shopID, userID uuid.UUID, req models.EInvoiceCancelRequest) (*models.EInvoiceResponse, error) {
+	// Check if user has access to the shop
+	var shopUser models.ShopUser
+	if err := s.db.Where("shop_id = ? AND user_id = ? AND is_active = ?", shopID, userID, true).First(&shopUser).Error; err != nil {
+		return nil, errors.New("access denied to shop")
+	}
+
+	if s.irp == nil {
+		return nil, errEInvoicingDisabled
+	}
+
+	var bill models.Bill
+	if err := s.db.Preload("Shop").Where("id = ? AND shop_id = ?", billID, shopID).First(&bill).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("bill not found")
+		}
+		return nil, err
+	}
+
+	if bill.IRNStatus != "generated" {
+		return nil, errors.New("bill has no active e-invoice")
+	}
+	if bill.IRNAckDate != nil && time.Since(*bill.IRNAckDate) > eInvoiceCancelWindow {
+		return nil, errors.New("e-invoices can only be cancelled within 24 hours of registration")
+	}
+
+	gstin := strings.ToUpper(strings.TrimSpace(bill.Shop.GSTNumber))
+	cancellation, err := s.irp.CancelIRN(gstin, bill.IRN, eInvoiceCancelReasons[req.Reason], req.Remark)
+	if err != nil {
+		return nil, fmt.Errorf("IRP rejected the cancellation: %v", err)
+	}
+
+	bill.IRNStatus = "cancelled"
+	bill.IRNCancelledAt = &cancellation.CancelDate
+	if err := s.db.Model(&models.Bill{}).Where("id = ?", bill.ID).Updates(map[string]interface{}{
+		"irn_status":       bill.IRNStatus,
+		"irn_cancelled_at": bill.IRNCancelledAt,
+		"updated_at":       time.Now(),
+	}).Error; err != nil {
+		return nil, err
+	}
+
+	response := eInvoiceToResponse(bill)
+	return &response, nil
+}
+
+// loadBill loads a bill with the shop, customer and lines needed for its e-invoice
+func (s *EInvoiceService) loadBill(billID, shopID uuid.UUID) (*models.Bill, error) {
+	var bill models.Bill
+	if err := s.db.Preload("Shop").Preload("Customer").Preload("Items").Preload("Items.Item").
+		Where("id = ? AND shop_id = ?", billID, shopID).First(&bill).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("bill not found")
+		}
+		return nil, err
+	}
+	return &bill, nil
+}
+
+// buildEInvoice maps a bill, its lines and customer to the INV-01 schema. Only B2B invoices,
+// where both the shop and the customer have a GSTIN, can be e-invoiced.
+func buildEInvoice(bill models.Bill) (*models.EInvoice, error) {
+	sellerGSTIN := strings.ToUpper(strings.TrimSpace(bill.Shop.GSTNumber))
+	if !validGSTIN(sellerGSTIN) {
+		return nil, errors.New("shop GST number must be a valid GSTIN to e-invoice")
+	}
+	if bill.Customer == nil {
+		return nil, errors.New("e-invoices need a customer with a GSTIN")
+	}
+	buyerGSTIN := strings.ToUpper(strings.TrimSpace(bill.Customer.TaxNumber))
+	if !validGSTIN(buyerGSTIN) {
+		return nil, errors.New("customer tax number must be a valid GSTIN to e-invoice")
+	}
+	if len(bill.BillNumber) > 16 {
+		return nil, errors.New("bill number must be at most 16 characters to e-invoice")
+	}
+
+	sellerAddress, sellerLocation, sellerPin := splitShopAddress(bill.Shop.Address)
+	if sellerPin == 0 {
+		return nil, errors.New("shop address must include a 6 digit PIN code to e-invoice")
+	}
+	buyerPin, _ := strconv.Atoi(strings.TrimSpace(bill.Customer.PostalCode))
+	if buyerPin < 100000 || buyerPin > 999999 {
+		return nil, errors.New("customer postal code must be a 6 digit PIN code to e-invoice")
+	}
+	buyerLocation := bill.Customer.City
+	if buyerLocation == "" {
+		buyerLocation = bill.Customer.State
+	}
+	buyerAddress := bill.Customer.Address
+	if buyerAddress == "" {
+		buyerAddress = buyerLocation
+	}
+
+	sellerState, buyerState := sellerGSTIN[:2], buyerGSTIN[:2]
+	interState := sellerState != buyerState
+
+	invoice := &models.EInvoice{
+		Version: "1.1",
+		TranDtls: models.EInvoiceTransaction{
+			TaxSch:      "GST",
+			SupTyp:      "B2B",
+			RegRev:      "N",
+			IgstOnIntra: "N",
+		},
+		DocDtls: models.EInvoiceDocument{
+			Typ: "INV",
+			No:  bill.BillNumber,
+			Dt:  bill.BillDate.Format("02/01/2006"),
+		},
+		SellerDtls: models.EInvoiceParty{
+			Gstin: sellerGSTIN,
+			LglNm: bill.Shop.Name,
+			Addr1: truncateRunes(sellerAddress, 100),
+			Loc:   truncateRunes(sellerLocation, 50),
+			Pin:   sellerPin,
+			Stcd:  sellerState,
+			Ph:    eInvoicePhone(bill.Shop.Phone),
+			Em:    bill.Shop.Email,
+		},
+		BuyerDtls: models.EInvoiceParty{
+			Gstin: buyerGSTIN,
+			LglNm: bill.Customer.Name,
+			Pos:   buyerState,
+			Addr1: truncateRunes(buyerAddress, 100),
+			Loc:   truncateRunes(buyerLocation, 50),
+			Pin:   buyerPin,
+			Stcd:  buyerState,
+			Ph:    eInvoicePhone(bill.Customer.Phone),
+			Em:    bill.Customer.Email,
+		},
+		ItemList: []models.EInvoiceItem{},
+	}
+
+	for i, line := range bill.Items {
+		code := line.HSNCode
+		if code == "" {
+			code = line.Item.HSNCode
+		}
+		if code == "" {
+			return nil, fmt.Errorf("item %s has no HSN code", line.ItemName)
+		}
+		unit := line.Unit
+		if unit == "" {
+			unit = line.Item.Unit
+		}
+
+		supply := gstSplitLine(line, interState)
+		unitPrice := line.UnitPrice
+		if line.TaxInclusive {
+			unitPrice = line.UnitPrice / (1 + line.TaxRate/100)
+		}
+		total := roundAmount(unitPrice * float64(line.Quantity))
+
+		isService := "N"
+		if strings.HasPrefix(code, "99") {
+			isService = "Y"
+		}
+
+		invoice.ItemList = append(invoice.ItemList, models.EInvoiceItem{
+			SlNo:       strconv.Itoa(i + 1),
+			PrdDesc:    line.ItemName,
+			IsServc:    isService,
+			HsnCd:      code,
+			Qty:        float64(line.Quantity),
+			Unit:       gstUnitCode(unit),
+			UnitPrice:  roundAmount(unitPrice),
+			TotAmt:     total,
+			Discount:   roundAmount(total - supply.Taxable),
+			AssAmt:     supply.Taxable,
+			GstRt:      supply.Rate,
+			IgstAmt:    supply.IGST,
+			CgstAmt:    supply.CGST,
+			SgstAmt:    supply.SGST,
+			TotItemVal: roundAmount(supply.Taxable + supply.IGST + supply.CGST + supply.SGST),
+		})
+
+		invoice.ValDtls.AssVal += supply.Taxable
+		invoice.ValDtls.IgstVal += supply.IGST
+		invoice.ValDtls.CgstVal += supply.CGST
+		invoice.ValDtls.SgstVal += supply.SGST
+	}
+
+	values := &invoice.ValDtls
+	values.AssVal = roundAmount(values.AssVal)
+	values.IgstVal = roundAmount(values.IgstVal)
+	values.CgstVal = roundAmount(values.CgstVal)
+	values.SgstVal = roundAmount(values.SgstVal)
+	values.Discount = roundAmount(bill.Discount)
+	values.TotInvVal = roundAmount(bill.TotalAmount)
+	values.RndOffAmt = roundAmount(values.TotInvVal - (values.AssVal + values.IgstVal + values.CgstVal + values.SgstVal - values.Discount))
+
+	return invoice, nil
+}
+
+// splitShopAddress splits a free-form shop address into the street, locality and PIN code.
+// The locality is the last comma separated part once the PIN code is taken out.
+func splitShopAddress(address string) (string, string, int) {
+	pin := 0
+	if match := pinCodePattern.FindString(address); match != "" {
+		pin, _ = strconv.Atoi(match)
+		address = strings.Replace(address, match, "", 1)
+	}
+
+	var parts []string
+	for _, part := range strings.Split(address, ",") {
+		if part = strings.Trim(strings.TrimSpace(part), "-"); part != "" {
+			parts = append(parts, strings.TrimSpace(part))
+		}
+	}
+	if len(parts) == 0 {
+		return "", "", pin
+	}
+	if len(parts) == 1 {
+		return parts[0], parts[0], pin
+	}
+	return strings.Join(parts[:len(parts)-1], ", "), parts[len(parts)-1], pin
+}
+
+// eInvoicePhone keeps only the digits of a phone number, as the IRP expects
+func eInvoicePhone(phone string) string {
+	var digits strings.Builder
+	for _, r := range phone {
+		if r >= '0' && r <= '9' {
+			digits.WriteRune(r)
+		}
+	}
+	if digits.Len() < 6 {
+		return ""
+	}
+	return truncateRunes(digits.String(), 12)
+}
+
+// truncateRunes shortens text to at most n characters
+func truncateRunes(text string, n int) string {
+	runes := []rune(strings.TrimSpace(text))
+	if len(runes) > n {
+		return string(runes[:n])
+	}
+	return string(runes)
+}
+
+// eInvoiceToResponse converts a bill's e-invoice fields to an EInvoiceResponse
+func eInvoiceToResponse(bill models.Bill) models.EInvoiceResponse {
+	response := models.EInvoiceResponse{
+		BillID:       bill.ID,
+		Status:       bill.IRNStatus,
+		IRN:          bill.IRN,
+		AckNo:        bill.IRNAckNo,
+		AckDate:      bill.IRNAckDate,
+		SignedQRCode: bill.IRNSignedQR,
+		CancelledAt:  bill.IRNCancelledAt,
+	}
+	if response.Status == "" {
+		response.Status = "not_generated"
+	}
+	if bill.IRNStatus == "generated" && bill.IRNAckDate != nil {
+		cancelBy := bill.IRNAckDate.Add(eInvoiceCancelWindow)
+		response.CancelBy = &cancelBy
+	}
+	return response
+}
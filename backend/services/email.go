@@ -0,0 +1,810 @@
+package services
+
+import (
+	"billboard/backend/models"
+	"bytes"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// emailRetryDelays is how long to wait before retrying a delivery after each transient failure.
+// A delivery is given up once they run out.
+var emailRetryDelays = []time.Duration{time.Minute, 5 * time.Minute, 30 * time.Minute, 2 * time.Hour, 6 * time.Hour}
+
+// emailClaimTimeout is how long a delivery being sent is hidden from the retry worker, so a
+// crash mid-send leaves it to be retried rather than stuck
+const emailClaimTimeout = 10 * time.Minute
+
+// defaultEmailTemplates are the subjects and bodies used until a shop customizes them
+var defaultEmailTemplates = map[string]models.EmailTemplateRequest{
+	"invoice": {
+		Subject: "Invoice {{.BillNumber}} from {{.ShopName}}",
+		Body: `Dear {{if .CustomerName}}{{.CustomerName}}{{else}}customer{{end}},
+
+Please find attached invoice {{.BillNumber}} dated {{.BillDate}} for {{.TotalAmount}}.
+{{- if .DueDate}} Payment is due by {{.DueDate}}.{{end}}
+{{if ne .Balance "0.00"}}
+Balance due: {{.Balance}}
+{{- if .UPIID}}
+You can pay by UPI to {{.UPIID}}.{{end}}
+{{end}}
+Thank you for your business.
+
+{{.ShopName}}
+{{- if .ShopPhone}}
+{{.ShopPhone}}{{end}}
+`,
+	},
+	"reminder": {
+		Subject: "{{if gt .DaysOverdue 0}}Overdue: invoice {{.BillNumber}}{{else}}Reminder: invoice {{.BillNumber}} is due {{if eq .DaysUntilDue 0}}today{{else}}on {{.DueDate}}{{end}}{{end}}",
+		Body: `Dear {{if .CustomerName}}{{.CustomerName}}{{else}}customer{{end}},
+
+This is a reminder that {{.Balance}} is outstanding on invoice {{.BillNumber}} dated {{.BillDate}}, which
+{{- if gt .DaysOverdue 0}} was due on {{.DueDate}}, {{.DaysOverdue}} days ago.
+{{- else if eq .DaysUntilDue 0}} is due today.
+{{- else}} is due on {{.DueDate}}.{{end}}
+{{- if .UPIID}}
+
+You can pay by UPI to {{.UPIID}}.{{end}}
+
+If you have already paid, please ignore this message.
+
+{{.ShopName}}
+{{- if .ShopPhone}}
+{{.ShopPhone}}{{end}}
+`,
+	},
+}
+
+// emailTemplateEvents lists the emails whose template a shop can customize, in display order
+var emailTemplateEvents = []string{"invoice", "reminder"}
+
+// billEmailData is what email templates are filled with
+type billEmailData struct {
+	ShopName     string
+	ShopEmail    string
+	ShopPhone    string
+	CustomerName string
+	BillNumber   string
+	BillDate     string
+	DueDate      string
+	TotalAmount  string
+	PaidAmount   string
+	Balance      string
+	UPIID        string
+	DaysOverdue  int // days past the due date, 0 until then
+	DaysUntilDue int // days left until the due date, 0 once it has passed
+}
+
+// sampleBillEmailData fills templates when checking them before they are saved
+var sampleBillEmailData = billEmailData{
+	ShopName:     "Sample Shop",
+	CustomerName: "Sample Customer",
+	BillNumber:   "INV-0001",
+	BillDate:     "01 Jan 2024",
+	DueDate:      "31 Jan 2024",
+	TotalAmount:  "100.00",
+	PaidAmount:   "0.00",
+	Balance:      "100.00",
+	DaysOverdue:  7,
+}
+
+// EmailService sends bills and other shop email through SMTP and keeps a log of every delivery
+type EmailService struct {
+	db          *gorm.DB
+	pdfService  *PDFService
+	defaultSMTP SMTPConfig
+	newMailer   func(SMTPConfig) Mailer
+}
+
+// NewEmailService creates a new EmailService instance. Shops without their own SMTP server
+// send through defaultSMTP.
+func NewEmailService(db *gorm.DB, pdfService *PDFService, defaultSMTP SMTPConfig) *EmailService {
+	return &EmailService{
+		db:          db,
+		pdfService:  pdfService,
+		defaultSMTP: defaultSMTP,
+		newMailer:   func(config SMTPConfig) Mailer { return NewSMTPMailer(config) },
+	}
+}
+
+// GetSettings returns a shop's SMTP server settings
+func (s *EmailService) GetSettings(shopID, userID uuid.UUID) (*models.EmailSettingsResponse, error) {
+	// Check if user has access to the shop
+	var shopUser models.ShopUser
+	if err := s.db.Where("shop_id = ? AND user_id = ? AND is_active = ?", shopID, userID, true).First(&shopUser).Error; err != nil {
+		return nil, errors.New("access denied to shop")
+	}
+
+	settings, err := s.shopSettings(shopID)
+	if err != nil {
+		return nil, err
+	}
+	response := emailSettingsToResponse(settings)
+	return &response, nil
+}
+
+// UpdateSettings replaces a shop's SMTP server settings. An empty host sends through the
+// installation's server.
+func (s *EmailService) UpdateSettings(shopID, userID uuid.UUID, req models.EmailSettingsRequest) (*models.EmailSettingsResponse, error) {
+	// Check if user has access to the shop
+	var shopUser models.ShopUser
+	if err := s.db.Where("shop_id = ? AND user_id = ? AND is_active = ?", shopID, userID, true).First(&shopUser).Error; err != nil {
+		return nil, errors.New("access denied to shop")
+	}
+
+	settings, err := s.shopSettings(shopID)
+	if err != nil {
+		return nil, err
+	}
+
+	settings.Host = strings.TrimSpace(req.Host)
+	settings.Port = req.Port
+	if settings.Port == 0 {
+		settings.Port = 587
+	}
+	settings.Username = req.Username
+	if req.Password != nil {
+		settings.Password = *req.Password
+	}
+	settings.Security = req.Security
+	if settings.Security == "" {
+		settings.Security = "starttls"
+	}
+	settings.FromAddress = req.FromAddress
+	settings.FromName = req.FromName
+	settings.ReplyTo = req.ReplyTo
+	if settings.Host != "" && settings.FromAddress == "" {
+		return nil, errors.New("a from address is needed with your own SMTP server")
+	}
+
+	if err := s.db.Save(&settings).Error; err != nil {
+		return nil, err
+	}
+	response := emailSettingsToResponse(settings)
+	return &response, nil
+}
+
+// GetTemplates returns the templates of every email a shop sends, customized or built in
+func (s *EmailService) GetTemplates(shopID, userID uuid.UUID) ([]models.EmailTemplateResponse, error) {
+	// Check if user has access to the shop
+	var shopUser models.ShopUser
+	if err := s.db.Where("shop_id = ? AND user_id = ? AND is_active = ?", shopID, userID, true).First(&shopUser).Error; err != nil {
+		return nil, errors.New("access denied to shop")
+	}
+
+	var custom []models.EmailTemplate
+	if err := s.db.Where("shop_id = ?", shopID).Find(&custom).Error; err != nil {
+		return nil, err
+	}
+	byEvent := map[string]models.EmailTemplate{}
+	for _, tmpl := range custom {
+		byEvent[tmpl.Event] = tmpl
+	}
+
+	responses := make([]models.EmailTemplateResponse, 0, len(emailTemplateEvents))
+	for _, event := range emailTemplateEvents {
+		if tmpl, ok := byEvent[event]; ok {
+			responses = append(responses, models.EmailTemplateResponse{Event: event, Subject: tmpl.Subject, Body: tmpl.Body})
+			continue
+		}
+		builtIn := defaultEmailTemplates[event]
+		responses = append(responses, models.EmailTemplateResponse{Event: event, Subject: builtIn.Subject, Body: builtIn.Body, IsDefault: true})
+	}
+	return responses, nil
+}
+
+// UpdateTemplate customizes the template of an email. The template is checked by filling it
+// with sample data.
+func (s *EmailService) UpdateTemplate(shopID, userID uuid.UUID, event string, req models.EmailTemplateRequest) (*models.EmailTemplateResponse, error) {
+	// Check if user has access to the shop
+	var shopUser models.ShopUser
+	if err := s.db.Where("shop_id = ? AND user_id = ? AND is_active = ?", shopID, userID, true).First(&shopUser).Error; err != nil {
+		return nil, errors.New("access denied to shop")
+	}
+
+	if _, ok := defaultEmailTemplates[event]; !ok {
+		return nil, errors.New("unknown email template")
+	}
+	if _, _, err := renderEmailTemplate(req.Subject, req.Body, sampleBillEmailData); err != nil {
+		return nil, err
+	}
+
+	var tmpl models.EmailTemplate
+	if err := s.db.Where("shop_id = ? AND event = ?", shopID, event).Find(&tmpl).Error; err != nil {
+		return nil, err
+	}
+	tmpl.ShopID = shopID
+	tmpl.Event = event
+	tmpl.Subject = req.Subject
+	tmpl.Body = req.Body
+	if err := s.db.Save(&tmpl).Error; err != nil {
+		return nil, err
+	}
+
+	return &models.EmailTemplateResponse{Event: event, Subject: tmpl.Subject, Body: tmpl.Body}, nil
+}
+
+// ResetTemplate discards a shop's customized template, going back to the built-in one
+func (s *EmailService) ResetTemplate(shopID, userID uuid.UUID, event string) error {
+	// Check if user has access to the shop
+	var shopUser models.ShopUser
+	if err := s.db.Where("shop_id = ? AND user_id = ? AND is_active = ?", shopID, userID, true).First(&shopUser).Error; err != nil {
+		return errors.New("access denied to shop")
+	}
+
+	if _, ok := defaultEmailTemplates[event]; !ok {
+		return errors.New("unknown email template")
+	}
+	return s.db.Where("shop_id = ? AND event = ?", shopID, event).Delete(&models.EmailTemplate{}).Error
+}
+
+// SendTestEmail sends a short message through the shop's settings to check they work
+func (s *EmailService) SendTestEmail(shopID, userID uuid.UUID, req models.TestEmailRequest) (*models.EmailDeliveryResponse, error) {
+	// Check if user has access to the shop
+	var shopUser models.ShopUser
+	if err := s.db.Preload("Shop").Where("shop_id = ? AND user_id = ? AND is_active = ?", shopID, userID, true).First(&shopUser).Error; err != nil {
+		return nil, errors.New("access denied to shop")
+	}
+
+	delivery := models.EmailDelivery{
+		ShopID:      shopID,
+		Event:       "test",
+		ToAddresses: req.To,
+		Subject:     "Test email from " + shopUser.Shop.Name,
+		Body:        "This is a test email. If you can read it, email from " + shopUser.Shop.Name + " is set up correctly.\n",
+		CreatedBy:   userID.String(),
+	}
+	if err := s.createAndSend(&delivery); err != nil {
+		return nil, err
+	}
+	response := emailDeliveryToResponse(delivery)
+	return &response, nil
+}
+
+// SendBill emails a bill, with its PDF attached unless asked otherwise. The delivery is logged
+// on the bill; a transient failure leaves it queued for retry. Emailing a draft bill issues it.
+func (s *EmailService) SendBill(billID, shopID, userID uuid.UUID, req models.SendBillEmailRequest) (*models.EmailDeliveryResponse, error) {
+	// Check if user has access to the shop
+	var shopUser models.ShopUser
+	if err := s.db.Where("shop_id = ? AND user_id = ? AND is_active = ?", shopID, userID, true).First(&shopUser).Error; err != nil {
+		return nil, errors.New("access denied to shop")
+	}
+
+	bill, err := s.loadBill(billID, shopID)
+	if err != nil {
+		return nil, err
+	}
+	if bill.Status == "cancelled" {
+		return nil, errors.New("cannot email a cancelled bill")
+	}
+
+	to := req.To
+	if len(to) == 0 {
+		if bill.Customer == nil || bill.Customer.Email == "" {
+			return nil, errors.New("customer has no email address")
+		}
+		to = []string{bill.Customer.Email}
+	}
+
+	tmpl, err := s.shopTemplate(shopID, "invoice")
+	if err != nil {
+		return nil, err
+	}
+	if req.Subject != "" {
+		tmpl.Subject = req.Subject
+	}
+	if req.Body != "" {
+		tmpl.Body = req.Body
+	}
+	delivery, err := s.sendBillEmail(*bill, "invoice", tmpl, to, req.CC, req.AttachPDF == nil || *req.AttachPDF, userID.String())
+	if err != nil {
+		return nil, err
+	}
+	if delivery.Status != "failed" {
+		if err := issueBill(s.db, bill); err != nil {
+			return nil, err
+		}
+	}
+	response := emailDeliveryToResponse(*delivery)
+	return &response, nil
+}
+
+// GetBillDeliveries lists the emails sent for a bill, most recent first
+func (s *EmailService) GetBillDeliveries(billID, shopID, userID uuid.UUID) ([]models.EmailDeliveryResponse, error) {
+	// Check if user has access to the shop
+	var shopUser models.ShopUser
+	if err := s.db.Where("shop_id = ? AND user_id = ? AND is_active = ?", shopID, userID, true).First(&shopUser).Error; err != nil {
+		return nil, errors.New("access denied to shop")
+	}
+
+	var deliveries []models.EmailDelivery
+	if err := s.db.Where("bill_id = ? AND shop_id = ?", billID, shopID).Order("created_at DESC").Find(&deliveries).Error; err != nil {
+		return nil, err
+	}
+	return emailDeliveriesToResponse(deliveries), nil
+}
+
+// GetDeliveries lists a shop's emails, optionally by status, most recent first
+func (s *EmailService) GetDeliveries(shopID, userID uuid.UUID, status string) ([]models.EmailDeliveryResponse, error) {
+	// Check if user has access to the shop
+	var shopUser models.ShopUser
+	if err := s.db.Where("shop_id = ? AND user_id = ? AND is_active = ?", shopID, userID, true).First(&shopUser).Error; err != nil {
+		return nil, errors.New("access denied to shop")
+	}
+
+	query := s.db.Where("shop_id = ?", shopID)
+	if status != "" {
+		query = query.Where("status = ?", status)
+	}
+	var deliveries []models.EmailDelivery
+	if err := query.Order("created_at DESC").Limit(200).Find(&deliveries).Error; err != nil {
+		return nil, err
+	}
+	return emailDeliveriesToResponse(deliveries), nil
+}
+
+// RetryDelivery sends a failed or queued email again straight away
+func (s *EmailService) RetryDelivery(deliveryID, shopID, userID uuid.UUID) (*models.EmailDeliveryResponse, error) {
+	// Check if user has access to the shop
+	var shopUser models.ShopUser
+	if err := s.db.Where("shop_id = ? AND user_id = ? AND is_active = ?", shopID, userID, true).First(&shopUser).Error; err != nil {
+		return nil, errors.New("access denied to shop")
+	}
+
+	var delivery models.EmailDelivery
+	if err := s.db.Where("id = ? AND shop_id = ?", deliveryID, shopID).First(&delivery).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("email not found")
+		}
+		return nil, err
+	}
+	if delivery.Status == "sent" {
+		return nil, errors.New("email has already been sent")
+	}
+
+	// Claim the delivery so the retry worker does not send it at the same time
+	now := time.Now()
+	claimUntil := now.Add(emailClaimTimeout)
+	claim := s.db.Model(&models.EmailDelivery{}).
+		Where("id = ? AND (status = ? OR (status = ? AND next_attempt_at <= ?))", delivery.ID, "failed", "queued", now).
+		Updates(map[string]interface{}{"status": "queued", "next_attempt_at": claimUntil, "updated_at": now})
+	if claim.Error != nil {
+		return nil, claim.Error
+	}
+	if claim.RowsAffected == 0 {
+		return nil, errors.New("email is being sent")
+	}
+	delivery.Status = "queued"
+	delivery.NextAttemptAt = &claimUntil
+
+	s.attempt(&delivery)
+	response := emailDeliveryToResponse(delivery)
+	return &response, nil
+}
+
+// ProcessQueue queues the invoice emails of auto-emailed recurring bills and retries the
+// deliveries that are due. It returns how many emails were sent.
+func (s *EmailService) ProcessQueue() (int, error) {
+	if err := s.queueRecurringBillEmails(); err != nil {
+		return 0, err
+	}
+
+	var due []models.EmailDelivery
+	if err := s.db.Where("status = ? AND next_attempt_at <= ?", "queued", time.Now()).
+		Order("next_attempt_at").Limit(100).Find(&due).Error; err != nil {
+		return 0, err
+	}
+
+	sent := 0
+	for i := range due {
+		delivery := &due[i]
+		now := time.Now()
+		claimUntil := now.Add(emailClaimTimeout)
+		claim := s.db.Model(&models.EmailDelivery{}).
+			Where("id = ? AND status = ? AND next_attempt_at <= ?", delivery.ID, "queued", now).
+			Update("next_attempt_at", claimUntil)
+		if claim.Error != nil {
+			return sent, claim.Error
+		}
+		if claim.RowsAffected == 0 {
+			continue
+		}
+		delivery.NextAttemptAt = &claimUntil
+
+		s.attempt(delivery)
+		if delivery.Status == "sent" {
+			sent++
+		}
+	}
+	return sent, nil
+}
+
+// StartWorker periodically sends queued email in the background
+func (s *EmailService) StartWorker(interval time.Duration) {
+	if s.db == nil {
+		return
+	}
+
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for range ticker.C {
+			if count, err := s.ProcessQueue(); err != nil {
+				log.Printf("Failed to process email queue: %v", err)
+			} else if count > 0 {
+				log.Printf("Sent %d queued emails", count)
+			}
+		}
+	}()
+}
+
+// queueRecurringBillEmails creates the invoice email of every recurring bill run waiting for one
+func (s *EmailService) queueRecurringBillEmails() error {
+	var runs []models.RecurringBillRun
+	if err := s.db.Where("email_status = ? AND bill_id IS NOT NULL", "pending").
+		Order("created_at").Limit(100).Find(&runs).Error; err != nil {
+		return err
+	}
+
+	for _, run := range runs {
+		var template models.RecurringBill
+		if err := s.db.Unscoped().Where("id = ?", run.RecurringBillID).First(&template).Error; err != nil {
+			log.Printf("Failed to load recurring bill %s for emailing: %v", run.RecurringBillID, err)
+			continue
+		}
+
+		bill, err := s.loadBill(*run.BillID, template.ShopID)
+		if err != nil || bill.Customer == nil || bill.Customer.Email == "" {
+			if err == nil {
+				err = errors.New("customer has no email address")
+			}
+			log.Printf("Cannot email recurring bill run %s: %v", run.ID, err)
+			s.db.Model(&models.RecurringBillRun{}).Where("id = ?", run.ID).
+				Updates(map[string]interface{}{"email_status": "failed", "updated_at": time.Now()})
+			continue
+		}
+
+		subject, body, err := s.renderBillEmail(*bill, "invoice")
+		if err != nil {
+			log.Printf("Cannot email recurring bill run %s: %v", run.ID, err)
+			s.db.Model(&models.RecurringBillRun{}).Where("id = ?", run.ID).
+				Updates(map[string]interface{}{"email_status": "failed", "updated_at": time.Now()})
+			continue
+		}
+
+		runID := run.ID
+		delivery := models.EmailDelivery{
+			ShopID:             template.ShopID,
+			BillID:             &bill.ID,
+			RecurringBillRunID: &runID,
+			Event:              "invoice",
+			ToAddresses:        bill.Customer.Email,
+			Subject:            subject,
+			Body:               body,
+			AttachPDF:          true,
+			CreatedBy:          template.CreatedBy,
+		}
+		// The unique run index rejects a delivery another worker already created for the run
+		if err := s.createAndSend(&delivery); err != nil {
+			continue
+		}
+	}
+	return nil
+}
+
+// sendBillEmail fills a template with a bill and sends it, logging the delivery on the bill
+func (s *EmailService) sendBillEmail(bill models.Bill, event string, tmpl models.EmailTemplateRequest, to, cc []string, attachPDF bool, createdBy string) (*models.EmailDelivery, error) {
+	subject, body, err := renderEmailTemplate(tmpl.Subject, tmpl.Body, billEmailDataFor(bill))
+	if err != nil {
+		return nil, err
+	}
+
+	delivery := models.EmailDelivery{
+		ShopID:      bill.ShopID,
+		BillID:      &bill.ID,
+		Event:       event,
+		ToAddresses: strings.Join(to, ","),
+		CCAddresses: strings.Join(cc, ","),
+		Subject:     subject,
+		Body:        body,
+		AttachPDF:   attachPDF,
+		CreatedBy:   createdBy,
+	}
+	if err := s.createAndSend(&delivery); err != nil {
+		return nil, err
+	}
+	return &delivery, nil
+}
+
+// createAndSend logs a new delivery and makes its first attempt
+func (s *EmailService) createAndSend(delivery *models.EmailDelivery) error {
+	claimUntil := time.Now().Add(emailClaimTimeout)
+	delivery.Status = "queued"
+	delivery.NextAttemptAt = &claimUntil
+	if err := s.db.Create(delivery).Error; err != nil {
+		return err
+	}
+	s.attempt(delivery)
+	return nil
+}
+
+// attempt sends a claimed delivery once and records the outcome: sent, queued for a retry
+// after a transient failure, or failed
+func (s *EmailService) attempt(delivery *models.EmailDelivery) {
+	msg, mailer, err := s.buildMessage(*delivery)
+	var messageID string
+	if err == nil {
+		messageID, err = mailer.Send(*msg)
+	}
+
+	now := time.Now()
+	delivery.Attempts++
+	delivery.LastError = ""
+	switch {
+	case err == nil:
+		delivery.Status = "sent"
+		delivery.SentAt = &now
+		delivery.MessageID = messageID
+		delivery.NextAttemptAt = nil
+	case transientMailError(err) && delivery.Attempts <= len(emailRetryDelays):
+		next := now.Add(emailRetryDelays[delivery.Attempts-1])
+		delivery.Status = "queued"
+		delivery.NextAttemptAt = &next
+		delivery.LastError = err.Error()
+	default:
+		delivery.Status = "failed"
+		delivery.NextAttemptAt = nil
+		delivery.LastError = err.Error()
+	}
+
+	if err := s.db.Model(&models.EmailDelivery{}).Where("id = ?", delivery.ID).Updates(map[string]interface{}{
+		"status":          delivery.Status,
+		"attempts":        delivery.Attempts,
+		"next_attempt_at": delivery.NextAttemptAt,
+		"last_error":      delivery.LastError,
+		"message_id":      delivery.MessageID,
+		"sent_at":         delivery.SentAt,
+		"updated_at":      now,
+	}).Error; err != nil {
+		log.Printf("Failed to record email delivery %s: %v", delivery.ID, err)
+	}
+
+	if delivery.RecurringBillRunID != nil && delivery.Status != "queued" {
+		s.db.Model(&models.RecurringBillRun{}).Where("id = ?", *delivery.RecurringBillRunID).
+			Updates(map[string]interface{}{"email_status": delivery.Status, "updated_at": now})
+	}
+}
+
+// buildMessage assembles a delivery into a message for the shop's mail server, rendering the
+// bill's PDF afresh when it is attached
+func (s *EmailService) buildMessage(delivery models.EmailDelivery) (*EmailMessage, Mailer, error) {
+	var shop models.Shop
+	if err := s.db.Where("id = ?", delivery.ShopID).First(&shop).Error; err != nil {
+		return nil, nil, err
+	}
+	settings, err := s.shopSettings(delivery.ShopID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	config := s.defaultSMTP
+	if settings.Host != "" {
+		config = SMTPConfig{
+			Host:     settings.Host,
+			Port:     settings.Port,
+			Username: settings.Username,
+			Password: settings.Password,
+			Security: settings.Security,
+			From:     settings.FromAddress,
+		}
+	}
+	if config.From == "" {
+		config.From = shop.Email
+	}
+	if config.From == "" {
+		return nil, nil, errors.New("no sender address configured")
+	}
+
+	msg := &EmailMessage{
+		From:     config.From,
+		FromName: settings.FromName,
+		ReplyTo:  settings.ReplyTo,
+		To:       splitAddresses(delivery.ToAddresses),
+		CC:       splitAddresses(delivery.CCAddresses),
+		Subject:  delivery.Subject,
+		Body:     delivery.Body,
+	}
+	if msg.FromName == "" {
+		msg.FromName = shop.Name
+	}
+	if msg.ReplyTo == "" && shop.Email != config.From {
+		msg.ReplyTo = shop.Email
+	}
+
+	if delivery.AttachPDF && delivery.BillID != nil {
+		bill, err := s.loadBill(*delivery.BillID, delivery.ShopID)
+		if err != nil {
+			return nil, nil, err
+		}
+		content, err := s.pdfService.RenderBill(*bill)
+		if err != nil {
+			return nil, nil, err
+		}
+		msg.Attachments = append(msg.Attachments, EmailAttachment{
+			FileName:    bill.BillNumber + ".pdf",
+			ContentType: "application/pdf",
+			Content:     content,
+		})
+	}
+
+	return msg, s.newMailer(config), nil
+}
+
+// shopTemplate returns a shop's template for an event, or the built-in one
+func (s *EmailService) shopTemplate(shopID uuid.UUID, event string) (models.EmailTemplateRequest, error) {
+	var custom models.EmailTemplate
+	if err := s.db.Where("shop_id = ? AND event = ?", shopID, event).Find(&custom).Error; err != nil {
+		return models.EmailTemplateRequest{}, err
+	}
+	if custom.ID != uuid.Nil {
+		return models.EmailTemplateRequest{Subject: custom.Subject, Body: custom.Body}, nil
+	}
+	return defaultEmailTemplates[event], nil
+}
+
+// renderBillEmail fills the shop's template for an event with a bill
+func (s *EmailService) renderBillEmail(bill models.Bill, event string) (string, string, error) {
+	tmpl, err := s.shopTemplate(bill.ShopID, event)
+	if err != nil {
+		return "", "", err
+	}
+	return renderEmailTemplate(tmpl.Subject, tmpl.Body, billEmailDataFor(bill))
+}
+
+// shopSettings returns a shop's email settings, or unsaved defaults when it has none
+func (s *EmailService) shopSettings(shopID uuid.UUID) (models.EmailSettings, error) {
+	var settings models.EmailSettings
+	if err := s.db.Where("shop_id = ?", shopID).Find(&settings).Error; err != nil {
+		return settings, err
+	}
+	if settings.ID == uuid.Nil {
+		settings = models.EmailSettings{ShopID: shopID, Port: 587, Security: "starttls"}
+	}
+	return settings, nil
+}
+
+// loadBill loads a bill with everything its PDF and email templates need
+func (s *EmailService) loadBill(billID, shopID uuid.UUID) (*models.Bill, error) {
+	var bill models.Bill
+	if err := s.db.Preload("Shop").Preload("Customer").Preload("Items.Item").Preload("Payments").Preload("Promotions").
+		Where("id = ? AND shop_id = ? AND deleted_at IS NULL", billID, shopID).First(&bill).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("bill not found")
+		}
+		return nil, err
+	}
+	return &bill, nil
+}
+
+// billEmailDataFor collects the template data of a bill
+func billEmailDataFor(bill models.Bill) billEmailData {
+	data := billEmailData{
+		ShopName:    bill.Shop.Name,
+		ShopEmail:   bill.Shop.Email,
+		ShopPhone:   bill.Shop.Phone,
+		BillNumber:  bill.BillNumber,
+		BillDate:    bill.BillDate.Format("02 Jan 2006"),
+		TotalAmount: formatAmount(bill.TotalAmount),
+		PaidAmount:  formatAmount(bill.PaidAmount),
+		Balance:     formatAmount(bill.Balance),
+		UPIID:       bill.Shop.UPIID,
+	}
+	if bill.Customer != nil {
+		data.CustomerName = bill.Customer.Name
+	}
+	if bill.DueDate != nil {
+		data.DueDate = bill.DueDate.Format("02 Jan 2006")
+		due := time.Date(bill.DueDate.Year(), bill.DueDate.Month(), bill.DueDate.Day(), 0, 0, 0, 0, time.UTC)
+		days := int(recurringBillToday().Sub(due).Hours() / 24)
+		if days > 0 {
+			data.DaysOverdue = days
+		} else {
+			data.DaysUntilDue = -days
+		}
+	}
+	return data
+}
+
+// renderEmailTemplate fills a subject and body template. Subjects are kept to one line.
+func renderEmailTemplate(subject, body string, data interface{}) (string, string, error) {
+	render := func(name, text string) (string, error) {
+		tmpl, err := template.New(name).Parse(text)
+		if err != nil {
+			return "", fmt.Errorf("invalid %s template: %v", name, err)
+		}
+		var buf bytes.Buffer
+		if err := tmpl.Execute(&buf, data); err != nil {
+			return "", fmt.Errorf("invalid %s template: %v", name, err)
+		}
+		return buf.String(), nil
+	}
+
+	renderedSubject, err := render("subject", subject)
+	if err != nil {
+		return "", "", err
+	}
+	renderedBody, err := render("body", body)
+	if err != nil {
+		return "", "", err
+	}
+	renderedSubject = strings.Join(strings.Fields(renderedSubject), " ")
+	if renderedSubject == "" {
+		return "", "", errors.New("email subject is empty")
+	}
+	return renderedSubject, renderedBody, nil
+}
+
+// splitAddresses splits a comma separated address list
+func splitAddresses(addresses string) []string {
+	var list []string
+	for _, address := range strings.Split(addresses, ",") {
+		if address = strings.TrimSpace(address); address != "" {
+			list = append(list, address)
+		}
+	}
+	return list
+}
+
+// emailSettingsToResponse converts email settings to their response, leaving out the password
+func emailSettingsToResponse(settings models.EmailSettings) models.EmailSettingsResponse {
+	return models.EmailSettingsResponse{
+		Host:          settings.Host,
+		Port:          settings.Port,
+		Username:      settings.Username,
+		PasswordSet:   settings.Password != "",
+		Security:      settings.Security,
+		FromAddress:   settings.FromAddress,
+		FromName:      settings.FromName,
+		ReplyTo:       settings.ReplyTo,
+		DefaultServer: settings.Host == "",
+	}
+}
+
+// emailDeliveriesToResponse converts deliveries to their responses
+func emailDeliveriesToResponse(deliveries []models.EmailDelivery) []models.EmailDeliveryResponse {
+	responses := make([]models.EmailDeliveryResponse, len(deliveries))
+	for i, delivery := range deliveries {
+		responses[i] = emailDeliveryToResponse(delivery)
+	}
+	return responses
+}
+
+// emailDeliveryToResponse converts a delivery to its response
+func emailDeliveryToResponse(delivery models.EmailDelivery) models.EmailDeliveryResponse {
+	return models.EmailDeliveryResponse{
+		ID:            delivery.ID,
+		BillID:        delivery.BillID,
+		Event:         delivery.Event,
+		To:            splitAddresses(delivery.ToAddresses),
+		CC:            splitAddresses(delivery.CCAddresses),
+		Subject:       delivery.Subject,
+		AttachPDF:     delivery.AttachPDF,
+		Status:        delivery.Status,
+		Attempts:      delivery.Attempts,
+		NextAttemptAt: delivery.NextAttemptAt,
+		LastError:     delivery.LastError,
+		MessageID:     delivery.MessageID,
+		SentAt:        delivery.SentAt,
+		CreatedBy:     delivery.CreatedBy,
+		CreatedAt:     delivery.CreatedAt,
+	}
+}
@@ -0,0 +1,743 @@
+package services
+
+import (
+	"billboard/backend/models"
+	"errors"
+	"fmt"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// EWayBillService prepares e-way bills for the goods on a shop's bills
+type EWayBillService struct {
+	db *gorm.DB
+}
+
+// NewEWayBillService creates a new EWayBillService instance
+func NewEWayBillService(db *gorm.DB) *EWayBillService {
+	return &EWayBillService{db: db}
+}
+
+// eWayBillCancelWindow is how long after generation the portal allows an e-way bill to be cancelled
+const eWayBillCancelWindow = 24 * time.Hour
+
+var (
+	// eWayBillSubSupplyTypes maps sub supply types to the portal's codes
+	eWayBillSubSupplyTypes = map[string]int{"supply": 1, "export": 3, "job_work": 4, "sales_return": 7, "others": 8}
+	// eWayBillTransportModes maps transport modes to the portal's codes
+	eWayBillTransportModes = map[string]int{"road": 1, "rail": 2, "air": 3, "ship": 4}
+
+	vehicleNumberPattern   = regexp.MustCompile(`^[A-Z]{2}[0-9]{1,2}[A-Z]{0,3}[0-9]{4}$`)
+	tempVehicleNumber      = regexp.MustCompile(`^(TM|TR)[A-Z0-9]{6,13}$`)
+	eWayBillDocNoPattern   = regexp.MustCompile(`^[A-Za-z0-9/-]{1,16}$`)
+	eWayBillTransDocNumber = regexp.MustCompile(`^[A-Za-z0-9/-]{0,15}$`)
+)
+
+// CreateEWayBill prepares an e-way bill for a bill's goods. A draft bill is issued, since the
+// goods are about to move on it.
+func (s *EWayBillService) CreateEWayBill(shopID, userID uuid.UUID, req models.EWayBillRequest) (*models.EWayBillResponse, error) {
+	// Check if user has access to the shop
+	var shopUser models.ShopUser
+	if err := s.db.Where("shop_id = ? AND user_id = ? AND is_active = ?", shopID, userID, true).First(&shopUser).Error; err != nil {
+		return nil, errors.New("access denied to shop")
+	}
+
+	var existing int64
+	if err := s.db.Model(&models.EWayBill{}).
+		Where("shop_id = ? AND bill_id = ? AND status <> ?", shopID, req.BillID, "cancelled").
+		Count(&existing).Error; err != nil {
+		return nil, err
+	}
+	if existing > 0 {
+		return nil, errors.New("bill already has an e-way bill")
+	}
+
+	ewb := models.EWayBill{
+		ShopID:    shopID,
+		BillID:    req.BillID,
+		Status:    "draft",
+		CreatedBy: userID.String(),
+	}
+	if err := applyEWayBillRequest(&ewb, req); err != nil {
+		return nil, err
+	}
+
+	bill, err := s.loadBill(shopID, req.BillID)
+	if err != nil {
+		return nil, err
+	}
+	if bill.Status == "cancelled" {
+		return nil, errors.New("e-way bills cannot be prepared for cancelled bills")
+	}
+	if err := fillEWayBillPincodes(&ewb, *bill); err != nil {
+		return nil, err
+	}
+	if err := checkEWayBill(ewb, *bill); err != nil {
+		return nil, err
+	}
+
+	if err := s.db.Create(&ewb).Error; err != nil {
+		return nil, err
+	}
+	if err := issueBill(s.db, bill); err != nil {
+		return nil, err
+	}
+
+	return s.GetEWayBill(ewb.ID, shopID, userID)
+}
+
+// GetEWayBills retrieves the e-way bills of a shop. Status may be draft, active, expired or
+// cancelled, and expiring_hours lists active e-way bills that expire within that many hours.
+func (s *EWayBillService) GetEWayBills(shopID, userID uuid.UUID, filters map[string]interface{}) ([]models.EWayBillResponse, error) {
+	// Check if user has access to the shop
+	var shopUser models.ShopUser
+	if err := s.db.Where("shop_id = ? AND user_id = ? AND is_active = ?", shopID, userID, true).First(&shopUser).Error; err != nil {
+		return nil, errors.New("access denied to shop")
+	}
+
+	now := time.Now()
+	query := s.db.Preload("Bill").Preload("VehicleUpdates", func(db *gorm.DB) *gorm.DB {
+		return db.Order("created_at ASC")
+	}).Where("shop_id = ?", shopID)
+
+	if status, ok := filters["status"].(string); ok && status != "" {
+		switch status {
+		case "active":
+			query = query.Where("status = ? AND (valid_until IS NULL OR valid_until >= ?)", "active", now)
+		case "expired":
+			query = query.Where("status = ? AND valid_until < ?", "active", now)
+		default:
+			query = query.Where("status = ?", status)
+		}
+	}
+
+	if billID, ok := filters["bill_id"].(string); ok && billID != "" {
+		query = query.Where("bill_id = ?", billID)
+	}
+
+	if hours, ok := filters["expiring_hours"].(string); ok && hours != "" {
+		h, err := strconv.Atoi(hours)
+		if err != nil || h <= 0 {
+			return nil, errors.New("expiring hours must be a positive number")
+		}
+		query = query.Where("status = ? AND valid_until >= ? AND valid_until < ?", "active", now, now.Add(time.Duration(h)*time.Hour))
+	}
+
+	var ewbs []models.EWayBill
+	if err := query.Order("created_at DESC").Find(&ewbs).Error; err != nil {
+		return nil, err
+	}
+
+	responses := []models.EWayBillResponse{}
+	for _, ewb := range ewbs {
+		responses = append(responses, eWayBillToResponse(ewb))
+	}
+
+	return responses, nil
+}
+
+// GetEWayBill retrieves a specific e-way bill
+func (s *EWayBillService) GetEWayBill(ewbID, shopID, userID uuid.UUID) (*models.EWayBillResponse, error) {
+	// Check if user has access to the shop
+	var shopUser models.ShopUser
+	if err := s.db.Where("shop_id = ? AND user_id = ? AND is_active = ?", shopID, userID, true).First(&shopUser).Error; err != nil {
+		return nil, errors.New("access denied to shop")
+	}
+
+	ewb, err := s.loadEWayBill(ewbID, shopID)
+	if err != nil {
+		return nil, err
+	}
+
+	response := eWayBillToResponse(*ewb)
+	return &response, nil
+}
+
+// UpdateEWayBill updates the transport details of an e-way bill that hasn't been generated yet
+func (s *EWayBillService) UpdateEWayBill(ewbID, shopID, userID uuid.UUID, req models.EWayBillRequest) (*models.EWayBillResponse, error) {
+	// Check if user has access to the shop
+	var shopUser models.ShopUser
+	if err := s.db.Where("shop_id = ? AND user_id = ? AND is_active = ?", shopID, userID, true).First(&shopUser).Error; err != nil {
+		return nil, errors.New("access denied to shop")
+	}
+
+	ewb, err := s.loadEWayBill(ewbID, shopID)
+	if err != nil {
+		return nil, err
+	}
+	if ewb.Status != "draft" {
+		return nil, errors.New("only draft e-way bills can be updated; use a vehicle update instead")
+	}
+	if req.BillID != ewb.BillID {
+		return nil, errors.New("the bill of an e-way bill cannot be changed")
+	}
+
+	if err := applyEWayBillRequest(ewb, req); err != nil {
+		return nil, err
+	}
+
+	bill, err := s.loadBill(shopID, ewb.BillID)
+	if err != nil {
+		return nil, err
+	}
+	if err := fillEWayBillPincodes(ewb, *bill); err != nil {
+		return nil, err
+	}
+	if err := checkEWayBill(*ewb, *bill); err != nil {
+		return nil, err
+	}
+
+	if err := s.db.Omit("Bill", "VehicleUpdates").Save(ewb).Error; err != nil {
+		return nil, err
+	}
+
+	return s.GetEWayBill(ewb.ID, shopID, userID)
+}
+
+// DeleteEWayBill deletes an e-way bill that hasn't been generated yet
+func (s *EWayBillService) DeleteEWayBill(ewbID, shopID, userID uuid.UUID) error {
+	// Check if user has access to the shop
+	var shopUser models.ShopUser
+	if err := s.db.Where("shop_id = ? AND user_id = ? AND is_active = ?", shopID, userID, true).First(&shopUser).Error; err != nil {
+		return errors.New("access denied to shop")
+	}
+
+	ewb, err := s.loadEWayBill(ewbID, shopID)
+	if err != nil {
+		return err
+	}
+	if ewb.Status != "draft" {
+		return errors.New("only draft e-way bills can be deleted")
+	}
+
+	return s.db.Delete(&models.EWayBill{}, "id = ?", ewb.ID).Error
+}
+
+// MarkGenerated records the number the portal assigned to an uploaded e-way bill and works out
+// how long it stays valid
+func (s *EWayBillService) MarkGenerated(ewbID, shopID, userID uuid.UUID, req models.EWayBillGeneratedRequest) (*models.EWayBillResponse, error) {
+	// Check if user has access to the shop
+	var shopUser models.ShopUser
+	if err := s.db.Where("shop_id = ? AND user_id = ? AND is_active = ?", shopID, userID, true).First(&shopUser).Error; err != nil {
+		return nil, errors.New("access denied to shop")
+	}
+
+	ewb, err := s.loadEWayBill(ewbID, shopID)
+	if err != nil {
+		return nil, err
+	}
+	if ewb.Status != "draft" {
+		return nil, errors.New("e-way bill has already been generated")
+	}
+
+	generatedAt := time.Now()
+	if req.GeneratedAt != nil && *req.GeneratedAt != "" {
+		generatedAt, err = time.Parse(time.RFC3339, *req.GeneratedAt)
+		if err != nil {
+			return nil, errors.New("invalid generated at format")
+		}
+	}
+
+	var validUntil *time.Time
+	if ewb.VehicleNumber != "" || ewb.TransDocNo != "" {
+		until := eWayBillValidUntil(generatedAt, ewb.Distance, ewb.VehicleType)
+		validUntil = &until
+	}
+
+	if err := s.db.Model(&models.EWayBill{}).Where("id = ?", ewb.ID).Updates(map[string]interface{}{
+		"status":           "active",
+		"eway_bill_number": req.EWayBillNumber,
+		"generated_at":     generatedAt,
+		"valid_until":      validUntil,
+		"updated_at":       time.Now(),
+	}).Error; err != nil {
+		return nil, err
+	}
+
+	return s.GetEWayBill(ewb.ID, shopID, userID)
+}
+
+// UpdateVehicle records a change of vehicle for goods in transit. Entering the first vehicle of
+// an e-way bill generated without one starts its validity.
+func (s *EWayBillService) UpdateVehicle(ewbID, shopID, userID uuid.UUID, req models.EWayBillVehicleRequest) (*models.EWayBillResponse, error) {
+	// Check if user has access to the shop
+	var shopUser models.ShopUser
+	if err := s.db.Where("shop_id = ? AND user_id = ? AND is_active = ?", shopID, userID, true).First(&shopUser).Error; err != nil {
+		return nil, errors.New("access denied to shop")
+	}
+
+	ewb, err := s.loadEWayBill(ewbID, shopID)
+	if err != nil {
+		return nil, err
+	}
+	if ewb.Status != "active" {
+		return nil, errors.New("only active e-way bills can have their vehicle updated")
+	}
+	if ewb.ValidUntil != nil && ewb.ValidUntil.Before(time.Now()) {
+		return nil, errors.New("e-way bill has expired")
+	}
+
+	vehicleNumber := normaliseVehicleNumber(req.VehicleNumber)
+	if !vehicleNumberPattern.MatchString(vehicleNumber) && !tempVehicleNumber.MatchString(vehicleNumber) {
+		return nil, errors.New("invalid vehicle number")
+	}
+	if !eWayBillTransDocNumber.MatchString(req.TransDocNo) {
+		return nil, errors.New("transport document number must be at most 15 letters, digits, / or -")
+	}
+	fromState := strings.TrimSpace(req.FromState)
+	if code, ok := gstStateCodes[strings.ToLower(fromState)]; ok {
+		fromState = code
+	}
+	if _, err := strconv.Atoi(fromState); err != nil || len(fromState) != 2 {
+		return nil, errors.New("from state must be a state name or GST state code")
+	}
+
+	transportMode := req.TransportMode
+	if transportMode == "" {
+		transportMode = ewb.TransportMode
+	}
+
+	update := models.EWayBillVehicleUpdate{
+		EWayBillID:    ewb.ID,
+		VehicleNumber: vehicleNumber,
+		TransportMode: transportMode,
+		TransDocNo:    req.TransDocNo,
+		FromPlace:     req.FromPlace,
+		FromState:     fromState,
+		Reason:        req.Reason,
+		Remarks:       req.Remarks,
+		CreatedBy:     userID.String(),
+	}
+	if req.TransDocDate != nil && *req.TransDocDate != "" {
+		date, err := time.Parse("2006-01-02", *req.TransDocDate)
+		if err != nil {
+			return nil, errors.New("invalid transport document date format")
+		}
+		update.TransDocDate = &date
+	}
+
+	err = s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&update).Error; err != nil {
+			return err
+		}
+
+		updates := map[string]interface{}{
+			"vehicle_number": update.VehicleNumber,
+			"transport_mode": update.TransportMode,
+			"trans_doc_no":   update.TransDocNo,
+			"trans_doc_date": update.TransDocDate,
+			"updated_at":     time.Now(),
+		}
+		if ewb.ValidUntil == nil {
+			updates["valid_until"] = eWayBillValidUntil(time.Now(), ewb.Distance, ewb.VehicleType)
+		}
+		return tx.Model(&models.EWayBill{}).Where("id = ?", ewb.ID).Updates(updates).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return s.GetEWayBill(ewb.ID, shopID, userID)
+}
+
+// CancelEWayBill cancels a generated e-way bill. The portal only allows this within 24 hours of
+// generation.
+func (s *EWayBillService) CancelEWayBill(ewbID, shopID, userID uuid.UUID, req models.EWayBillCancelRequest) (*models.EWayBillResponse, error) {
+	// Check if user has access to the shop
+	var shopUser models.ShopUser
+	if err := s.db.Where("shop_id = ? AND user_id = ? AND is_active = ?", shopID, userID, true).First(&shopUser).Error; err != nil {
+		return nil, errors.New("access denied to shop")
+	}
+
+	ewb, err := s.loadEWayBill(ewbID, shopID)
+	if err != nil {
+		return nil, err
+	}
+	if ewb.Status != "active" {
+		return nil, errors.New("only active e-way bills can be cancelled")
+	}
+	if ewb.GeneratedAt != nil && time.Since(*ewb.GeneratedAt) > eWayBillCancelWindow {
+		return nil, errors.New("e-way bills can only be cancelled within 24 hours of generation")
+	}
+
+	if err := s.db.Model(&models.EWayBill{}).Where("id = ?", ewb.ID).Updates(map[string]interface{}{
+		"status":        "cancelled",
+		"cancelled_at":  time.Now(),
+		"cancel_reason": req.Reason,
+		"updated_at":    time.Now(),
+	}).Error; err != nil {
+		return nil, err
+	}
+
+	return s.GetEWayBill(ewb.ID, shopID, userID)
+}
+
+// ExportBulkJSON builds the portal's bulk upload file for the given e-way bills, or for all
+// draft e-way bills when none are given
+func (s *EWayBillService) ExportBulkJSON(shopID, userID uuid.UUID, ids []uuid.UUID) (*models.EWayBillBulkUpload, error) {
+	// Check if user has access to the shop
+	var shopUser models.ShopUser
+	if err := s.db.Where("shop_id = ? AND user_id = ? AND is_active = ?", shopID, userID, true).First(&shopUser).Error; err != nil {
+		return nil, errors.New("access denied to shop")
+	}
+
+	query := s.db.Where("shop_id = ?", shopID)
+	if len(ids) > 0 {
+		query = query.Where("id IN ?", ids)
+	} else {
+		query = query.Where("status = ?", "draft")
+	}
+
+	var ewbs []models.EWayBill
+	if err := query.Order("created_at ASC").Find(&ewbs).Error; err != nil {
+		return nil, err
+	}
+	if len(ids) > 0 && len(ewbs) != len(ids) {
+		return nil, errors.New("one or more e-way bills not found")
+	}
+
+	upload := &models.EWayBillBulkUpload{Version: "1.0.0621", BillLists: []models.EWayBillPayload{}}
+	for _, ewb := range ewbs {
+		bill, err := s.loadBill(shopID, ewb.BillID)
+		if err != nil {
+			return nil, err
+		}
+		payload, err := buildEWayBillPayload(ewb, *bill)
+		if err != nil {
+			return nil, fmt.Errorf("bill %s: %v", bill.BillNumber, err)
+		}
+		if problems := validateEWayBillPayload(*payload); len(problems) > 0 {
+			return nil, fmt.Errorf("bill %s: %s", bill.BillNumber, strings.Join(problems, "; "))
+		}
+		upload.BillLists = append(upload.BillLists, *payload)
+	}
+
+	return upload, nil
+}
+
+// loadEWayBill loads an e-way bill of the shop with its bill and vehicle updates
+func (s *EWayBillService) loadEWayBill(ewbID, shopID uuid.UUID) (*models.EWayBill, error) {
+	var ewb models.EWayBill
+	if err := s.db.Preload("Bill").Preload("VehicleUpdates", func(db *gorm.DB) *gorm.DB {
+		return db.Order("created_at ASC")
+	}).Where("id = ? AND shop_id = ?", ewbID, shopID).First(&ewb).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("e-way bill not found")
+		}
+		return nil, err
+	}
+	return &ewb, nil
+}
+
+// loadBill loads a bill with the shop, customer and lines needed for its e-way bill
+func (s *EWayBillService) loadBill(shopID, billID uuid.UUID) (*models.Bill, error) {
+	var bill models.Bill
+	if err := s.db.Preload("Shop").Preload("Customer").Preload("Items").Preload("Items.Item").
+		Where("id = ? AND shop_id = ?", billID, shopID).First(&bill).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("bill not found")
+		}
+		return nil, err
+	}
+	return &bill, nil
+}
+
+// applyEWayBillRequest copies the transport details of a request onto an e-way bill
+func applyEWayBillRequest(ewb *models.EWayBill, req models.EWayBillRequest) error {
+	ewb.SubSupplyType = req.SubSupplyType
+	if ewb.SubSupplyType == "" {
+		ewb.SubSupplyType = "supply"
+	}
+	ewb.TransportMode = req.TransportMode
+	ewb.Distance = req.Distance
+	ewb.TransporterID = strings.ToUpper(strings.TrimSpace(req.TransporterID))
+	ewb.TransporterName = strings.TrimSpace(req.TransporterName)
+	ewb.TransDocNo = strings.TrimSpace(req.TransDocNo)
+	ewb.VehicleNumber = normaliseVehicleNumber(req.VehicleNumber)
+	ewb.VehicleType = req.VehicleType
+	if ewb.VehicleType == "" {
+		ewb.VehicleType = "regular"
+	}
+	ewb.FromPincode = strings.TrimSpace(req.FromPincode)
+	ewb.ToPincode = strings.TrimSpace(req.ToPincode)
+
+	ewb.TransDocDate = nil
+	if req.TransDocDate != nil && *req.TransDocDate != "" {
+		date, err := time.Parse("2006-01-02", *req.TransDocDate)
+		if err != nil {
+			return errors.New("invalid transport document date format")
+		}
+		ewb.TransDocDate = &date
+	}
+	return nil
+}
+
+// fillEWayBillPincodes defaults the PIN codes to the shop address and the customer's postal code
+func fillEWayBillPincodes(ewb *models.EWayBill, bill models.Bill) error {
+	if ewb.FromPincode == "" {
+		if _, _, pin := splitShopAddress(bill.Shop.Address); pin != 0 {
+			ewb.FromPincode = strconv.Itoa(pin)
+		}
+	}
+	if ewb.ToPincode == "" && bill.Customer != nil {
+		ewb.ToPincode = strings.TrimSpace(bill.Customer.PostalCode)
+	}
+	if ewb.FromPincode == "" {
+		return errors.New("from PIN code is required when the shop address has none")
+	}
+	if ewb.ToPincode == "" {
+		return errors.New("to PIN code is required when the customer has no postal code")
+	}
+	return nil
+}
+
+// checkEWayBill builds the portal payload for an e-way bill and rejects it if it breaks the schema
+func checkEWayBill(ewb models.EWayBill, bill models.Bill) error {
+	payload, err := buildEWayBillPayload(ewb, bill)
+	if err != nil {
+		return err
+	}
+	if problems := validateEWayBillPayload(*payload); len(problems) > 0 {
+		return fmt.Errorf("invalid e-way bill: %s", strings.Join(problems, "; "))
+	}
+	return nil
+}
+
+// buildEWayBillPayload maps an e-way bill and its bill to the portal's bulk upload schema
+func buildEWayBillPayload(ewb models.EWayBill, bill models.Bill) (*models.EWayBillPayload, error) {
+	fromGSTIN := strings.ToUpper(strings.TrimSpace(bill.Shop.GSTNumber))
+	if !validGSTIN(fromGSTIN) {
+		return nil, errors.New("shop GST number must be a valid GSTIN to prepare e-way bills")
+	}
+	fromState, _ := strconv.Atoi(fromGSTIN[:2])
+	fromAddress, fromPlace, _ := splitShopAddress(bill.Shop.Address)
+	fromPincode, _ := strconv.Atoi(ewb.FromPincode)
+
+	toGSTIN, toName, toAddress, toPlace, toState := "URP", "", "", "", fromState
+	if bill.Customer != nil {
+		toName = bill.Customer.Name
+		toAddress = bill.Customer.Address
+		toPlace = bill.Customer.City
+		if gstin := strings.ToUpper(strings.TrimSpace(bill.Customer.TaxNumber)); validGSTIN(gstin) {
+			toGSTIN = gstin
+			toState, _ = strconv.Atoi(gstin[:2])
+		} else if code, ok := gstStateCodes[strings.ToLower(strings.TrimSpace(bill.Customer.State))]; ok {
+			toState, _ = strconv.Atoi(code)
+		}
+	}
+	if ewb.SubSupplyType == "export" {
+		toState = 99
+	}
+	toPincode, _ := strconv.Atoi(ewb.ToPincode)
+	interState := toState != fromState
+
+	payload := &models.EWayBillPayload{
+		UserGstin:           fromGSTIN,
+		SupplyType:          "O",
+		SubSupplyType:       eWayBillSubSupplyTypes[ewb.SubSupplyType],
+		DocType:             "INV",
+		DocNo:               bill.BillNumber,
+		DocDate:             bill.BillDate.Format("02/01/2006"),
+		TransType:           1,
+		FromGstin:           fromGSTIN,
+		FromTrdName:         bill.Shop.Name,
+		FromAddr1:           truncateRunes(fromAddress, 120),
+		FromPlace:           truncateRunes(fromPlace, 50),
+		FromPincode:         fromPincode,
+		FromStateCode:       fromState,
+		ActualFromStateCode: fromState,
+		ToGstin:             toGSTIN,
+		ToTrdName:           toName,
+		ToAddr1:             truncateRunes(toAddress, 120),
+		ToPlace:             truncateRunes(toPlace, 50),
+		ToPincode:           toPincode,
+		ToStateCode:         toState,
+		ActualToStateCode:   toState,
+		TotInvValue:         roundAmount(bill.TotalAmount),
+		TransMode:           eWayBillTransportModes[ewb.TransportMode],
+		TransDistance:       ewb.Distance,
+		TransporterName:     ewb.TransporterName,
+		TransporterID:       ewb.TransporterID,
+		TransDocNo:          ewb.TransDocNo,
+		VehicleNo:           ewb.VehicleNumber,
+		VehicleType:         "R",
+		ItemList:            []models.EWayBillPayloadItem{},
+	}
+	if ewb.SubSupplyType == "others" {
+		payload.SubSupplyDesc = "Others"
+	}
+	if ewb.TransDocDate != nil {
+		payload.TransDocDate = ewb.TransDocDate.Format("02/01/2006")
+	}
+	if ewb.VehicleType == "over_dimensional" {
+		payload.VehicleType = "O"
+	}
+
+	for i, line := range bill.Items {
+		code := line.HSNCode
+		if code == "" {
+			code = line.Item.HSNCode
+		}
+		hsn, _ := strconv.Atoi(code)
+		unit := line.Unit
+		if unit == "" {
+			unit = line.Item.Unit
+		}
+		supply := gstSplitLine(line, interState)
+
+		item := models.EWayBillPayloadItem{
+			ItemNo:        i + 1,
+			ProductName:   line.ItemName,
+			ProductDesc:   line.Description,
+			HsnCode:       hsn,
+			Quantity:      float64(line.Quantity),
+			QtyUnit:       gstUnitCode(unit),
+			TaxableAmount: supply.Taxable,
+		}
+		if interState {
+			item.IgstRate = supply.Rate
+		} else {
+			item.CgstRate = supply.Rate / 2
+			item.SgstRate = supply.Rate / 2
+		}
+		payload.ItemList = append(payload.ItemList, item)
+
+		payload.TotalValue += supply.Taxable
+		payload.IgstValue += supply.IGST
+		payload.CgstValue += supply.CGST
+		payload.SgstValue += supply.SGST
+	}
+	payload.TotalValue = roundAmount(payload.TotalValue)
+	payload.IgstValue = roundAmount(payload.IgstValue)
+	payload.CgstValue = roundAmount(payload.CgstValue)
+	payload.SgstValue = roundAmount(payload.SgstValue)
+	payload.OthValue = roundAmount(payload.TotInvValue - (payload.TotalValue + payload.IgstValue + payload.CgstValue + payload.SgstValue))
+
+	return payload, nil
+}
+
+// validateEWayBillPayload checks a payload against the constraints of the portal's JSON schema
+// and returns every problem found
+func validateEWayBillPayload(p models.EWayBillPayload) []string {
+	var problems []string
+	check := func(ok bool, problem string) {
+		if !ok {
+			problems = append(problems, problem)
+		}
+	}
+	validState := func(code int) bool { return (code >= 1 && code <= 38) || code == 97 || code == 99 }
+	validPincode := func(pin int) bool { return pin >= 100000 && pin <= 999999 }
+
+	check(eWayBillDocNoPattern.MatchString(p.DocNo), "document number must be 1 to 16 letters, digits, / or -")
+	check(p.SubSupplyType != 0, "invalid sub supply type")
+	check(p.ToGstin == "URP" || validGSTIN(p.ToGstin), "invalid recipient GSTIN")
+	check(validPincode(p.FromPincode), "from PIN code must be 6 digits")
+	check(validPincode(p.ToPincode) || p.ToStateCode == 99, "to PIN code must be 6 digits")
+	check(validState(p.FromStateCode), "invalid from state code")
+	check(validState(p.ToStateCode), "invalid to state code")
+	check(p.FromPlace != "", "from place is required")
+	check(p.TransMode >= 1 && p.TransMode <= 4, "invalid transport mode")
+	check(p.TransDistance >= 0 && p.TransDistance <= 4000, "distance must be between 0 and 4000 km")
+	check(p.TransporterID == "" || validGSTIN(p.TransporterID), "transporter ID must be a GSTIN or TRANSIN")
+	check(len(p.TransporterName) <= 100, "transporter name must be at most 100 characters")
+	check(eWayBillTransDocNumber.MatchString(p.TransDocNo), "transport document number must be at most 15 letters, digits, / or -")
+	check(p.VehicleNo == "" || vehicleNumberPattern.MatchString(p.VehicleNo) || tempVehicleNumber.MatchString(p.VehicleNo), "invalid vehicle number")
+	check(p.VehicleNo == "" || p.TransMode == 1, "vehicle number is only used for road transport")
+
+	// Part B needs a vehicle for road transport, or the transport document for rail, air and ship.
+	// A transporter ID alone lets the transporter fill Part B later.
+	switch {
+	case p.TransMode == 1:
+		check(p.VehicleNo != "" || p.TransporterID != "", "vehicle number or transporter ID is required for road transport")
+	case p.TransMode > 1:
+		check(p.TransDocNo != "" && p.TransDocDate != "", "transport document number and date are required for rail, air and ship")
+	}
+
+	check(len(p.ItemList) > 0, "at least one item is required")
+	for _, item := range p.ItemList {
+		check(item.HsnCode > 0, fmt.Sprintf("item %s needs a numeric HSN code", item.ProductName))
+		check(item.TaxableAmount >= 0, fmt.Sprintf("item %s has a negative taxable amount", item.ProductName))
+	}
+	check(p.TotInvValue >= p.TotalValue, "invoice value cannot be less than the taxable value")
+
+	return problems
+}
+
+// eWayBillValidUntil works out when an e-way bill expires. It is valid for a day per 200 km, or
+// per 20 km for over dimensional cargo, and each day runs to midnight of the following day.
+func eWayBillValidUntil(from time.Time, distance int, vehicleType string) time.Time {
+	perDay := 200.0
+	if vehicleType == "over_dimensional" {
+		perDay = 20.0
+	}
+	days := int(math.Ceil(float64(distance) / perDay))
+	if days < 1 {
+		days = 1
+	}
+	start := time.Date(from.Year(), from.Month(), from.Day(), 0, 0, 0, 0, from.Location())
+	return start.AddDate(0, 0, days+1).Add(-time.Second)
+}
+
+// normaliseVehicleNumber upper-cases a vehicle number and drops spaces and dashes
+func normaliseVehicleNumber(number string) string {
+	return strings.NewReplacer(" ", "", "-", "").Replace(strings.ToUpper(strings.TrimSpace(number)))
+}
+
+// eWayBillToResponse converts an EWayBill model to EWayBillResponse
+func eWayBillToResponse(ewb models.EWayBill) models.EWayBillResponse {
+	status := ewb.Status
+	if status == "active" && ewb.ValidUntil != nil && ewb.ValidUntil.Before(time.Now()) {
+		status = "expired"
+	}
+
+	updates := []models.EWayBillVehicleUpdateResponse{}
+	for _, update := range ewb.VehicleUpdates {
+		updates = append(updates, models.EWayBillVehicleUpdateResponse{
+			ID:            update.ID,
+			VehicleNumber: update.VehicleNumber,
+			TransportMode: update.TransportMode,
+			TransDocNo:    update.TransDocNo,
+			TransDocDate:  update.TransDocDate,
+			FromPlace:     update.FromPlace,
+			FromState:     update.FromState,
+			Reason:        update.Reason,
+			Remarks:       update.Remarks,
+			CreatedBy:     update.CreatedBy,
+			CreatedAt:     update.CreatedAt,
+		})
+	}
+
+	return models.EWayBillResponse{
+		ID:              ewb.ID,
+		ShopID:          ewb.ShopID,
+		BillID:          ewb.BillID,
+		BillNumber:      ewb.Bill.BillNumber,
+		SubSupplyType:   ewb.SubSupplyType,
+		TransportMode:   ewb.TransportMode,
+		Distance:        ewb.Distance,
+		TransporterID:   ewb.TransporterID,
+		TransporterName: ewb.TransporterName,
+		TransDocNo:      ewb.TransDocNo,
+		TransDocDate:    ewb.TransDocDate,
+		VehicleNumber:   ewb.VehicleNumber,
+		VehicleType:     ewb.VehicleType,
+		FromPincode:     ewb.FromPincode,
+		ToPincode:       ewb.ToPincode,
+		Status:          status,
+		EWayBillNumber:  ewb.EWayBillNumber,
+		GeneratedAt:     ewb.GeneratedAt,
+		ValidUntil:      ewb.ValidUntil,
+		CancelledAt:     ewb.CancelledAt,
+		CancelReason:    ewb.CancelReason,
+		VehicleUpdates:  updates,
+		CreatedBy:       ewb.CreatedBy,
+		CreatedAt:       ewb.CreatedAt,
+		UpdatedAt:       ewb.UpdatedAt,
+	}
+}
@@ -0,0 +1,783 @@
+package services
+
+import (
+	"billboard/backend/models"
+	"errors"
+	"log"
+	"mime"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// maxAttachmentSize is the largest expense attachment accepted, in bytes
+const maxAttachmentSize = 5 << 20
+
+// attachmentContentTypes are the file types attachments are served as. Anything else, such as
+// HTML or SVG that a browser would run, is served as a plain download.
+var attachmentContentTypes = map[string]bool{
+	"application/pdf": true,
+	"image/jpeg":      true,
+	"image/png":       true,
+	"image/gif":       true,
+	"image/webp":      true,
+}
+
+// defaultExpenseCategories are offered for every shop alongside the categories it has used
+var defaultExpenseCategories = []string{"Rent", "Salary", "Utilities", "Supplies", "Transport", "Maintenance", "Marketing", "Other"}
+
+// ExpenseService manages expenses, recurring expenses and the profit and loss report
+type ExpenseService struct {
+	db *gorm.DB
+}
+
+// NewExpenseService creates a new ExpenseService instance
+func NewExpenseService(db *gorm.DB) *ExpenseService {
+	return &ExpenseService{db: db}
+}
+
+// CreateExpense records a new expense
+func (s *ExpenseService) CreateExpense(shopID, userID uuid.UUID, req models.ExpenseRequest) (*models.ExpenseResponse, error) {
+	// Check if user has access to the shop
+	var shopUser models.ShopUser
+	if err := s.db.Where("shop_id = ? AND user_id = ? AND is_active = ?", shopID, userID, true).First(&shopUser).Error; err != nil {
+		return nil, errors.New("access denied to shop")
+	}
+
+	expense := models.Expense{ShopID: shopID, CreatedBy: userID.String()}
+	if err := applyExpenseRequest(&expense, req); err != nil {
+		return nil, err
+	}
+
+	if err := s.db.Create(&expense).Error; err != nil {
+		return nil, err
+	}
+
+	return s.getExpenseResponse(expense.ID, shopID)
+}
+
+// GetExpenses retrieves the expenses of a shop
+func (s *ExpenseService) GetExpenses(shopID, userID uuid.UUID, filters map[string]interface{}) ([]models.ExpenseResponse, error) {
+	// Check if user has access to the shop
+	var shopUser models.ShopUser
+	if err := s.db.Where("shop_id = ? AND user_id = ? AND is_active = ?", shopID, userID, true).First(&shopUser).Error; err != nil {
+		return nil, errors.New("access denied to shop")
+	}
+
+	query := s.db.Preload("Attachments", func(db *gorm.DB) *gorm.DB {
+		return db.Omit("data")
+	}).Where("shop_id = ?", shopID)
+
+	if category, ok := filters["category"].(string); ok && category != "" {
+		query = query.Where("category = ?", category)
+	}
+
+	if paymentMode, ok := filters["payment_mode"].(string); ok && paymentMode != "" {
+		query = query.Where("payment_mode = ?", paymentMode)
+	}
+
+	if search, ok := filters["search"].(string); ok && search != "" {
+		query = query.Where("description ILIKE ? OR vendor ILIKE ? OR reference ILIKE ?", "%"+search+"%", "%"+search+"%", "%"+search+"%")
+	}
+
+	if from, ok := filters["from"].(string); ok && from != "" {
+		fromDate, err := time.Parse("2006-01-02", from)
+		if err != nil {
+			return nil, errors.New("invalid from date format")
+		}
+		query = query.Where("expense_date >= ?", fromDate)
+	}
+
+	if to, ok := filters["to"].(string); ok && to != "" {
+		toDate, err := time.Parse("2006-01-02", to)
+		if err != nil {
+			return nil, errors.New("invalid to date format")
+		}
+		query = query.Where("expense_date <= ?", toDate)
+	}
+
+	var expenses []models.Expense
+	if err := query.Order("expense_date DESC, created_at DESC").Find(&expenses).Error; err != nil {
+		return nil, err
+	}
+
+	var responses []models.ExpenseResponse
+	for _, expense := range expenses {
+		responses = append(responses, s.expenseToResponse(expense))
+	}
+
+	return responses, nil
+}
+
+// GetExpense retrieves a specific expense
+func (s *ExpenseService) GetExpense(expenseID, shopID, userID uuid.UUID) (*models.ExpenseResponse, error) {
+	// Check if user has access to the shop
+	var shopUser models.ShopUser
+	if err := s.db.Where("shop_id = ? AND user_id = ? AND is_active = ?", shopID, userID, true).First(&shopUser).Error; err != nil {
+		return nil, errors.New("access denied to shop")
+	}
+
+	return s.getExpenseResponse(expenseID, shopID)
+}
+
+// UpdateExpense updates an expense
+func (s *ExpenseService) UpdateExpense(expenseID, shopID, userID uuid.UUID, req models.ExpenseRequest) (*models.ExpenseResponse, error) {
+	// Check if user has access to the shop
+	var shopUser models.ShopUser
+	if err := s.db.Where("shop_id = ? AND user_id = ? AND is_active = ?", shopID, userID, true).First(&shopUser).Error; err != nil {
+		return nil, errors.New("access denied to shop")
+	}
+
+	expense, err := s.getExpense(expenseID, shopID)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := applyExpenseRequest(expense, req); err != nil {
+		return nil, err
+	}
+
+	if err := s.db.Omit("Attachments").Save(expense).Error; err != nil {
+		return nil, err
+	}
+
+	return s.getExpenseResponse(expenseID, shopID)
+}
+
+// DeleteExpense soft deletes an expense
+func (s *ExpenseService) DeleteExpense(expenseID, shopID, userID uuid.UUID) error {
+	// Check if user has access to the shop
+	var shopUser models.ShopUser
+	if err := s.db.Where("shop_id = ? AND user_id = ? AND is_active = ?", shopID, userID, true).First(&shopUser).Error; err != nil {
+		return errors.New("access denied to shop")
+	}
+
+	expense, err := s.getExpense(expenseID, shopID)
+	if err != nil {
+		return err
+	}
+
+	return s.db.Delete(expense).Error
+}
+
+// GetExpenseCategories returns the default expense categories and any others the shop has used
+func (s *ExpenseService) GetExpenseCategories(shopID, userID uuid.UUID) ([]string, error) {
+	// Check if user has access to the shop
+	var shopUser models.ShopUser
+	if err := s.db.Where("shop_id = ? AND user_id = ? AND is_active = ?", shopID, userID, true).First(&shopUser).Error; err != nil {
+		return nil, errors.New("access denied to shop")
+	}
+
+	var used []string
+	if err := s.db.Model(&models.Expense{}).Where("shop_id = ?", shopID).Distinct().Pluck("category", &used).Error; err != nil {
+		return nil, err
+	}
+
+	categories := append([]string{}, defaultExpenseCategories...)
+	seen := map[string]bool{}
+	for _, category := range categories {
+		seen[strings.ToLower(category)] = true
+	}
+	var extra []string
+	for _, category := range used {
+		if !seen[strings.ToLower(category)] {
+			seen[strings.ToLower(category)] = true
+			extra = append(extra, category)
+		}
+	}
+	sort.Strings(extra)
+
+	return append(categories, extra...), nil
+}
+
+// AddAttachment stores a receipt or invoice file with an expense. Its type is read from the file
+// itself rather than taken from the uploader.
+func (s *ExpenseService) AddAttachment(expenseID, shopID, userID uuid.UUID, fileName string, data []byte) (*models.ExpenseAttachmentResponse, error) {
+	// Check if user has access to the shop
+	var shopUser models.ShopUser
+	if err := s.db.Where("shop_id = ? AND user_id = ? AND is_active = ?", shopID, userID, true).First(&shopUser).Error; err != nil {
+		return nil, errors.New("access denied to shop")
+	}
+
+	if _, err := s.getExpense(expenseID, shopID); err != nil {
+		return nil, err
+	}
+
+	if len(data) == 0 {
+		return nil, errors.New("attachment is empty")
+	}
+	if len(data) > maxAttachmentSize {
+		return nil, errors.New("attachment must not be larger than 5 MB")
+	}
+
+	attachment := models.ExpenseAttachment{
+		ExpenseID:   expenseID,
+		FileName:    fileName,
+		ContentType: attachmentContentType(data),
+		Size:        int64(len(data)),
+		Data:        data,
+		CreatedBy:   userID.String(),
+	}
+	if err := s.db.Create(&attachment).Error; err != nil {
+		return nil, err
+	}
+
+	response := attachmentToResponse(attachment)
+	return &response, nil
+}
+
+// GetAttachment retrieves an expense attachment with its content
+func (s *ExpenseService) GetAttachment(attachmentID, expenseID, shopID, userID uuid.UUID) (*models.ExpenseAttachment, error) {
+	// Check if user has access to the shop
+	var shopUser models.ShopUser
+	if err := s.db.Where("shop_id = ? AND user_id = ? AND is_active = ?", shopID, userID, true).First(&shopUser).Error; err != nil {
+		return nil, errors.New("access denied to shop")
+	}
+
+	if _, err := s.getExpense(expenseID, shopID); err != nil {
+		return nil, err
+	}
+
+	var attachment models.ExpenseAttachment
+	if err := s.db.Where("id = ? AND expense_id = ?", attachmentID, expenseID).First(&attachment).Error; err != nil {
+		return nil, errors.New("attachment not found")
+	}
+	// Attachments stored before their type was sniffed carry whatever the uploader sent
+	attachment.ContentType = attachmentContentType(attachment.Data)
+
+	return &attachment, nil
+}
+
+// DeleteAttachment removes an attachment from an expense
+func (s *ExpenseService) DeleteAttachment(attachmentID, expenseID, shopID, userID uuid.UUID) error {
+	attachment, err := s.GetAttachment(attachmentID, expenseID, shopID, userID)
+	if err != nil {
+		return err
+	}
+
+	return s.db.Delete(attachment).Error
+}
+
+// CreateRecurringExpense creates an expense that is recorded automatically on a schedule
+func (s *ExpenseService) CreateRecurringExpense(shopID, userID uuid.UUID, req models.RecurringExpenseRequest) (*models.RecurringExpenseResponse, error) {
+	// Check if user has access to the shop
+	var shopUser models.ShopUser
+	if err := s.db.Where("shop_id = ? AND user_id = ? AND is_active = ?", shopID, userID, true).First(&shopUser).Error; err != nil {
+		return nil, errors.New("access denied to shop")
+	}
+
+	recurring := models.RecurringExpense{ShopID: shopID, Status: "active", CreatedBy: userID.String()}
+	if err := applyRecurringExpenseRequest(&recurring, req); err != nil {
+		return nil, err
+	}
+
+	if err := s.db.Create(&recurring).Error; err != nil {
+		return nil, err
+	}
+
+	response := s.recurringExpenseToResponse(recurring)
+	return &response, nil
+}
+
+// GetRecurringExpenses retrieves the recurring expenses of a shop
+func (s *ExpenseService) GetRecurringExpenses(shopID, userID uuid.UUID) ([]models.RecurringExpenseResponse, error) {
+	// Check if user has access to the shop
+	var shopUser models.ShopUser
+	if err := s.db.Where("shop_id = ? AND user_id = ? AND is_active = ?", shopID, userID, true).First(&shopUser).Error; err != nil {
+		return nil, errors.New("access denied to shop")
+	}
+
+	var recurring []models.RecurringExpense
+	if err := s.db.Where("shop_id = ?", shopID).Order("created_at DESC").Find(&recurring).Error; err != nil {
+		return nil, err
+	}
+
+	var responses []models.RecurringExpenseResponse
+	for _, expense := range recurring {
+		responses = append(responses, s.recurringExpenseToResponse(expense))
+	}
+
+	return responses, nil
+}
+
+// GetRecurringExpense retrieves a specific recurring expense
+func (s *ExpenseService) GetRecurringExpense(recurringExpenseID, shopID, userID uuid.UUID) (*models.RecurringExpenseResponse, error) {
+	// Check if user has access to the shop
+	var shopUser models.ShopUser
+	if err := s.db.Where("shop_id = ? AND user_id = ? AND is_active = ?", shopID, userID, true).First(&shopUser).Error; err != nil {
+		return nil, errors.New("access denied to shop")
+	}
+
+	recurring, err := s.getRecurringExpense(recurringExpenseID, shopID)
+	if err != nil {
+		return nil, err
+	}
+
+	response := s.recurringExpenseToResponse(*recurring)
+	return &response, nil
+}
+
+// UpdateRecurringExpense updates a recurring expense; expenses already recorded are not changed
+func (s *ExpenseService) UpdateRecurringExpense(recurringExpenseID, shopID, userID uuid.UUID, req models.RecurringExpenseRequest) (*models.RecurringExpenseResponse, error) {
+	// Check if user has access to the shop
+	var shopUser models.ShopUser
+	if err := s.db.Where("shop_id = ? AND user_id = ? AND is_active = ?", shopID, userID, true).First(&shopUser).Error; err != nil {
+		return nil, errors.New("access denied to shop")
+	}
+
+	recurring, err := s.getRecurringExpense(recurringExpenseID, shopID)
+	if err != nil {
+		return nil, err
+	}
+	if recurring.Status == "ended" {
+		return nil, errors.New("recurring expense has ended")
+	}
+
+	if err := applyRecurringExpenseRequest(recurring, req); err != nil {
+		return nil, err
+	}
+
+	if err := s.db.Save(recurring).Error; err != nil {
+		return nil, err
+	}
+
+	response := s.recurringExpenseToResponse(*recurring)
+	return &response, nil
+}
+
+// DeleteRecurringExpense soft deletes a recurring expense; expenses already recorded are kept
+func (s *ExpenseService) DeleteRecurringExpense(recurringExpenseID, shopID, userID uuid.UUID) error {
+	// Check if user has access to the shop
+	var shopUser models.ShopUser
+	if err := s.db.Where("shop_id = ? AND user_id = ? AND is_active = ?", shopID, userID, true).First(&shopUser).Error; err != nil {
+		return errors.New("access denied to shop")
+	}
+
+	recurring, err := s.getRecurringExpense(recurringExpenseID, shopID)
+	if err != nil {
+		return err
+	}
+
+	return s.db.Delete(recurring).Error
+}
+
+// GenerateDueExpenses records the expenses of all active recurring expenses that are due, catching up
+// missed periods. Each period is recorded at most once, so running it repeatedly is safe.
+func (s *ExpenseService) GenerateDueExpenses() (int, error) {
+	today := recurringBillToday()
+
+	var recurring []models.RecurringExpense
+	if err := s.db.Where("status = ? AND next_run_date <= ?", "active", today).Find(&recurring).Error; err != nil {
+		return 0, err
+	}
+
+	generated := 0
+	for _, template := range recurring {
+		for template.NextRunDate != nil && !template.NextRunDate.After(today) {
+			created, err := s.generateExpense(&template, *template.NextRunDate)
+			if err != nil {
+				log.Printf("Failed to record recurring expense %s for %s: %v", template.ID, template.NextRunDate.Format("2006-01-02"), err)
+				break
+			}
+			if created {
+				generated++
+			}
+		}
+	}
+
+	return generated, nil
+}
+
+// StartGenerator periodically records due recurring expenses in the background
+func (s *ExpenseService) StartGenerator(interval time.Duration) {
+	if s.db == nil {
+		return
+	}
+
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for range ticker.C {
+			if count, err := s.GenerateDueExpenses(); err != nil {
+				log.Printf("Failed to record recurring expenses: %v", err)
+			} else if count > 0 {
+				log.Printf("Recorded %d recurring expenses", count)
+			}
+		}
+	}()
+}
+
+// GetProfitAndLoss reports net sales, cost of goods sold and expenses for a date range.
+// Every bill but a cancelled one is a sale, paid or not, since its stock has gone; cost of
+// goods uses the cost price captured on each bill line.
+func (s *ExpenseService) GetProfitAndLoss(shopID, userID uuid.UUID, from, to string) (*models.ProfitAndLossReport, error) {
+	// Check if user has access to the shop
+	var shopUser models.ShopUser
+	if err := s.db.Where("shop_id = ? AND user_id = ? AND is_active = ?", shopID, userID, true).First(&shopUser).Error; err != nil {
+		return nil, errors.New("access denied to shop")
+	}
+
+	fromDate, toDate, err := reportDateRange(from, to)
+	if err != nil {
+		return nil, err
+	}
+	end := toDate.AddDate(0, 0, 1)
+
+	report := &models.ProfitAndLossReport{From: fromDate, To: toDate, Expenses: []models.ExpenseCategoryTotal{}}
+
+	// Sales
+	var sales struct {
+		BillCount    int64
+		GrossSales   float64
+		Discounts    float64
+		TaxCollected float64
+		TotalAmount  float64
+	}
+	if err := s.db.Model(&models.Bill{}).
+		Select("COUNT(*) AS bill_count, COALESCE(SUM(subtotal), 0) AS gross_sales, "+
+			"COALESCE(SUM(line_discount + promotion_discount + discount_amount), 0) AS discounts, "+
+			"COALESCE(SUM(tax_amount), 0) AS tax_collected, COALESCE(SUM(total_amount), 0) AS total_amount").
+		Where("shop_id = ? AND status <> ? AND bill_date >= ? AND bill_date < ?", shopID, "cancelled", fromDate, end).
+		Scan(&sales).Error; err != nil {
+		return nil, err
+	}
+	report.BillCount = sales.BillCount
+	report.GrossSales = roundAmount(sales.GrossSales)
+	report.Discounts = roundAmount(sales.Discounts)
+	report.TaxCollected = roundAmount(sales.TaxCollected)
+	report.NetSales = roundAmount(sales.TotalAmount - sales.TaxCollected)
+
+	// Cost of goods sold
+	var cogs struct {
+		Cost     float64
+		Uncosted int64
+	}
+	if err := s.db.Table("bill_items").
+		Select("COALESCE(SUM(bill_items.quantity * bill_items.unit_cost), 0) AS cost, "+
+			"COALESCE(SUM(CASE WHEN bill_items.unit_cost = 0 THEN bill_items.quantity ELSE 0 END), 0) AS uncosted").
+		Joins("JOIN bills ON bills.id = bill_items.bill_id").
+		Where("bills.shop_id = ? AND bills.deleted_at IS NULL AND bills.status <> ? AND bills.bill_date >= ? AND bills.bill_date < ?",
+			shopID, "cancelled", fromDate, end).
+		Scan(&cogs).Error; err != nil {
+		return nil, err
+	}
+	report.CostOfGoodsSold = roundAmount(cogs.Cost)
+	report.UncostedQuantity = cogs.Uncosted
+	report.GrossProfit = roundAmount(report.NetSales - report.CostOfGoodsSold)
+
+	// Expenses
+	if err := s.db.Model(&models.Expense{}).
+		Select("category, COUNT(*) AS count, COALESCE(SUM(amount), 0) AS amount, COALESCE(SUM(tax_amount), 0) AS tax_amount").
+		Where("shop_id = ? AND expense_date >= ? AND expense_date <= ?", shopID, fromDate, toDate).
+		Group("category").Order("amount DESC").
+		Scan(&report.Expenses).Error; err != nil {
+		return nil, err
+	}
+	for i := range report.Expenses {
+		report.Expenses[i].Amount = roundAmount(report.Expenses[i].Amount)
+		report.Expenses[i].TaxAmount = roundAmount(report.Expenses[i].TaxAmount)
+		report.TotalExpenses += report.Expenses[i].Amount
+		report.ExpenseTax += report.Expenses[i].TaxAmount
+	}
+	report.TotalExpenses = roundAmount(report.TotalExpenses)
+	report.ExpenseTax = roundAmount(report.ExpenseTax)
+	report.NetProfit = roundAmount(report.GrossProfit - report.TotalExpenses)
+
+	if report.NetSales != 0 {
+		report.GrossMargin = roundAmount(report.GrossProfit / report.NetSales * 100)
+		report.NetMargin = roundAmount(report.NetProfit / report.NetSales * 100)
+	}
+
+	return report, nil
+}
+
+// generateExpense records the expense of one period and advances the schedule.
+// It reports false when the period had already been recorded.
+func (s *ExpenseService) generateExpense(template *models.RecurringExpense, period time.Time) (bool, error) {
+	created := false
+	next := nextRecurringBillDate(recurringExpenseSchedule(*template), period.AddDate(0, 0, 1))
+
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		var count int64
+		if err := tx.Model(&models.Expense{}).Unscoped().
+			Where("recurring_expense_id = ? AND expense_date = ?", template.ID, period).
+			Count(&count).Error; err != nil {
+			return err
+		}
+
+		if count == 0 {
+			expense := models.Expense{
+				ShopID:             template.ShopID,
+				Category:           template.Category,
+				Description:        template.Description,
+				Vendor:             template.Vendor,
+				ExpenseDate:        period,
+				Amount:             template.Amount,
+				TaxAmount:          template.TaxAmount,
+				TotalAmount:        roundAmount(template.Amount + template.TaxAmount),
+				PaymentMode:        template.PaymentMode,
+				RecurringExpenseID: &template.ID,
+				CreatedBy:          template.CreatedBy,
+			}
+			if err := tx.Create(&expense).Error; err != nil {
+				return err
+			}
+			created = true
+		}
+
+		updates := map[string]interface{}{
+			"next_run_date": next,
+			"last_run_date": period,
+			"updated_at":    time.Now(),
+		}
+		if next == nil {
+			updates["status"] = "ended"
+		}
+		return tx.Model(&models.RecurringExpense{}).Where("id = ?", template.ID).Updates(updates).Error
+	})
+	if err != nil {
+		return false, err
+	}
+
+	template.NextRunDate = next
+	template.LastRunDate = &period
+	if next == nil {
+		template.Status = "ended"
+	}
+	return created, nil
+}
+
+// getExpense loads an expense of a shop
+func (s *ExpenseService) getExpense(expenseID, shopID uuid.UUID) (*models.Expense, error) {
+	var expense models.Expense
+	err := s.db.Preload("Attachments", func(db *gorm.DB) *gorm.DB {
+		return db.Omit("data")
+	}).Where("id = ? AND shop_id = ?", expenseID, shopID).First(&expense).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("expense not found")
+		}
+		return nil, err
+	}
+	return &expense, nil
+}
+
+// getExpenseResponse loads an expense and converts it to a response
+func (s *ExpenseService) getExpenseResponse(expenseID, shopID uuid.UUID) (*models.ExpenseResponse, error) {
+	expense, err := s.getExpense(expenseID, shopID)
+	if err != nil {
+		return nil, err
+	}
+
+	response := s.expenseToResponse(*expense)
+	return &response, nil
+}
+
+// getRecurringExpense loads a recurring expense of a shop
+func (s *ExpenseService) getRecurringExpense(recurringExpenseID, shopID uuid.UUID) (*models.RecurringExpense, error) {
+	var recurring models.RecurringExpense
+	if err := s.db.Where("id = ? AND shop_id = ?", recurringExpenseID, shopID).First(&recurring).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("recurring expense not found")
+		}
+		return nil, err
+	}
+	return &recurring, nil
+}
+
+// expenseToResponse converts an Expense model to ExpenseResponse
+func (s *ExpenseService) expenseToResponse(expense models.Expense) models.ExpenseResponse {
+	response := models.ExpenseResponse{
+		ID:                 expense.ID,
+		ShopID:             expense.ShopID,
+		Category:           expense.Category,
+		Description:        expense.Description,
+		Vendor:             expense.Vendor,
+		ExpenseDate:        expense.ExpenseDate,
+		Amount:             expense.Amount,
+		TaxAmount:          expense.TaxAmount,
+		TotalAmount:        expense.TotalAmount,
+		PaymentMode:        expense.PaymentMode,
+		Reference:          expense.Reference,
+		Notes:              expense.Notes,
+		RecurringExpenseID: expense.RecurringExpenseID,
+		Attachments:        []models.ExpenseAttachmentResponse{},
+		CreatedBy:          expense.CreatedBy,
+		CreatedAt:          expense.CreatedAt,
+		UpdatedAt:          expense.UpdatedAt,
+	}
+	for _, attachment := range expense.Attachments {
+		response.Attachments = append(response.Attachments, attachmentToResponse(attachment))
+	}
+	return response
+}
+
+// recurringExpenseToResponse converts a RecurringExpense model to RecurringExpenseResponse
+func (s *ExpenseService) recurringExpenseToResponse(recurring models.RecurringExpense) models.RecurringExpenseResponse {
+	return models.RecurringExpenseResponse{
+		ID:          recurring.ID,
+		ShopID:      recurring.ShopID,
+		Category:    recurring.Category,
+		Description: recurring.Description,
+		Vendor:      recurring.Vendor,
+		Amount:      recurring.Amount,
+		TaxAmount:   recurring.TaxAmount,
+		PaymentMode: recurring.PaymentMode,
+		Frequency:   recurring.Frequency,
+		StartDate:   recurring.StartDate,
+		EndDate:     recurring.EndDate,
+		Status:      recurring.Status,
+		NextRunDate: recurring.NextRunDate,
+		LastRunDate: recurring.LastRunDate,
+		CreatedAt:   recurring.CreatedAt,
+		UpdatedAt:   recurring.UpdatedAt,
+	}
+}
+
+// attachmentContentType sniffs the type of an attachment from its content, falling back to a
+// plain download for types outside attachmentContentTypes
+func attachmentContentType(data []byte) string {
+	contentType, _, _ := mime.ParseMediaType(http.DetectContentType(data))
+	if !attachmentContentTypes[contentType] {
+		return "application/octet-stream"
+	}
+	return contentType
+}
+
+// attachmentToResponse converts an ExpenseAttachment model to ExpenseAttachmentResponse
+func attachmentToResponse(attachment models.ExpenseAttachment) models.ExpenseAttachmentResponse {
+	return models.ExpenseAttachmentResponse{
+		ID:          attachment.ID,
+		FileName:    attachment.FileName,
+		ContentType: attachment.ContentType,
+		Size:        attachment.Size,
+		CreatedAt:   attachment.CreatedAt,
+	}
+}
+
+// applyExpenseRequest validates a request and copies it onto an expense
+func applyExpenseRequest(expense *models.Expense, req models.ExpenseRequest) error {
+	expenseDate, err := time.Parse("2006-01-02", req.ExpenseDate)
+	if err != nil {
+		return errors.New("invalid expense date format")
+	}
+
+	category := strings.TrimSpace(req.Category)
+	if category == "" {
+		return errors.New("category is required")
+	}
+
+	expense.Category = category
+	expense.Description = req.Description
+	expense.Vendor = req.Vendor
+	expense.ExpenseDate = expenseDate
+	expense.Amount = roundAmount(req.Amount)
+	expense.TaxAmount = roundAmount(req.TaxAmount)
+	expense.TotalAmount = roundAmount(req.Amount + req.TaxAmount)
+	expense.PaymentMode = req.PaymentMode
+	expense.Reference = req.Reference
+	expense.Notes = req.Notes
+
+	return nil
+}
+
+// applyRecurringExpenseRequest validates a request, copies it onto a recurring expense and works out
+// its next run. Changing the schedule never records a period that was already recorded, and
+// resuming a paused expense does not record the periods it was paused for.
+func applyRecurringExpenseRequest(recurring *models.RecurringExpense, req models.RecurringExpenseRequest) error {
+	startDate, err := time.Parse("2006-01-02", req.StartDate)
+	if err != nil {
+		return errors.New("invalid start date format")
+	}
+
+	var endDate *time.Time
+	if req.EndDate != nil && *req.EndDate != "" {
+		parsed, err := time.Parse("2006-01-02", *req.EndDate)
+		if err != nil {
+			return errors.New("invalid end date format")
+		}
+		if parsed.Before(startDate) {
+			return errors.New("end date must not be before the start date")
+		}
+		endDate = &parsed
+	}
+
+	category := strings.TrimSpace(req.Category)
+	if category == "" {
+		return errors.New("category is required")
+	}
+
+	recurring.Category = category
+	recurring.Description = req.Description
+	recurring.Vendor = req.Vendor
+	recurring.Amount = roundAmount(req.Amount)
+	recurring.TaxAmount = roundAmount(req.TaxAmount)
+	recurring.PaymentMode = req.PaymentMode
+	recurring.Frequency = req.Frequency
+	recurring.StartDate = startDate
+	recurring.EndDate = endDate
+	resuming := recurring.Status == "paused" && req.Status == "active"
+	if req.Status != "" {
+		recurring.Status = req.Status
+	}
+
+	from := startDate
+	if recurring.LastRunDate != nil && !recurring.LastRunDate.Before(from) {
+		from = recurring.LastRunDate.AddDate(0, 0, 1)
+	}
+	if today := recurringBillToday(); resuming && today.After(from) {
+		from = today
+	}
+	recurring.NextRunDate = nextRecurringBillDate(recurringExpenseSchedule(*recurring), from)
+	if recurring.NextRunDate == nil {
+		if recurring.LastRunDate == nil {
+			return errors.New("schedule has no dates before the end date")
+		}
+		recurring.Status = "ended"
+	}
+
+	return nil
+}
+
+// recurringExpenseSchedule describes a recurring expense as a recurring bill schedule so both
+// fall on the same dates, repeating on the day of month of the start date
+func recurringExpenseSchedule(recurring models.RecurringExpense) models.RecurringBill {
+	return models.RecurringBill{
+		Frequency:  recurring.Frequency,
+		StartDate:  recurring.StartDate,
+		EndDate:    recurring.EndDate,
+		DayOfMonth: recurring.StartDate.Day(),
+	}
+}
+
+// reportDateRange parses a report's from and to dates, defaulting to the current month to date
+func reportDateRange(from, to string) (time.Time, time.Time, error) {
+	today := recurringBillToday()
+	fromDate := time.Date(today.Year(), today.Month(), 1, 0, 0, 0, 0, time.UTC)
+	toDate := today
+
+	if from != "" {
+		parsed, err := time.Parse("2006-01-02", from)
+		if err != nil {
+			return fromDate, toDate, errors.New("invalid from date format")
+		}
+		fromDate = parsed
+	}
+	if to != "" {
+		parsed, err := time.Parse("2006-01-02", to)
+		if err != nil {
+			return fromDate, toDate, errors.New("invalid to date format")
+		}
+		toDate = parsed
+	}
+	if toDate.Before(fromDate) {
+		return fromDate, toDate, errors.New("to date must not be before the from date")
+	}
+
+	return fromDate, toDate, nil
+}
@@ -0,0 +1,723 @@
+package services
+
+import (
+	"archive/zip"
+	"billboard/backend/models"
+	"bytes"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// GSTService builds GST returns from a shop's bills
+type GSTService struct {
+	db *gorm.DB
+}
+
+// NewGSTService creates a new GSTService instance
+func NewGSTService(db *gorm.DB) *GSTService {
+	return &GSTService{db: db}
+}
+
+// gstB2CLLimit is the invoice value above which an inter-state sale to an unregistered customer
+// is reported invoice by invoice in B2CL rather than summed in B2CS
+const gstB2CLLimit = 100000
+
+// gstStateCodes maps Indian state and union territory names to their GST state codes
+var gstStateCodes = map[string]string{
+	"jammu and kashmir": "01",
+	"himachal pradesh":  "02",
+	"punjab":            "03",
+	"chandigarh":        "04",
+	"uttarakhand":       "05",
+	"haryana":           "06",
+	"delhi":             "07",
+	"rajasthan":         "08",
+	"uttar pradesh":     "09",
+	"bihar":             "10",
+	"sikkim":            "11",
+	"arunachal pradesh": "12",
+	"nagaland":          "13",
+	"manipur":           "14",
+	"mizoram":           "15",
+	"tripura":           "16",
+	"meghalaya":         "17",
+	"assam":             "18",
+	"west bengal":       "19",
+	"jharkhand":         "20",
+	"odisha":            "21",
+	"chhattisgarh":      "22",
+	"madhya pradesh":    "23",
+	"gujarat":           "24",
+	"dadra and nagar haveli and daman and diu": "26",
+	"karnataka":                   "29",
+	"goa":                         "30",
+	"lakshadweep":                 "31",
+	"kerala":                      "32",
+	"tamil nadu":                  "33",
+	"puducherry":                  "34",
+	"andaman and nicobar islands": "35",
+	"telangana":                   "36",
+	"andhra pradesh":              "37",
+	"ladakh":                      "38",
+}
+
+// gstUnitCodes maps common item units to GST unit quantity codes
+var gstUnitCodes = map[string]string{
+	"pcs": "PCS", "pc": "PCS", "piece": "PCS", "pieces": "PCS",
+	"nos": "NOS", "no": "NOS", "number": "NOS",
+	"kg": "KGS", "kgs": "KGS",
+	"g": "GMS", "gm": "GMS", "gms": "GMS",
+	"l": "LTR", "ltr": "LTR", "litre": "LTR",
+	"ml": "MLT",
+	"m":  "MTR", "mtr": "MTR", "meter": "MTR",
+	"box": "BOX", "pack": "PAC", "pac": "PAC",
+	"dozen": "DOZ", "doz": "DOZ", "set": "SET",
+	"bag": "BAG", "btl": "BTL", "bottle": "BTL",
+}
+
+// gstSupply is a bill line's taxable value and tax, split by the place of supply
+type gstSupply struct {
+	Rate, Taxable, IGST, CGST, SGST float64
+}
+
+// gstBill is a bill classified for GST with its lines summed by tax rate
+type gstBill struct {
+	Bill       models.Bill
+	CustomerID string // customer GSTIN, empty when unregistered
+	Pos        string
+	InterState bool
+	Rates      []gstSupply
+}
+
+// gstNote is a late fee debit note, or the credit note of a waived late fee, classified by the
+// bill it was charged on. Rates holds the note's own value split by tax rate.
+type gstNote struct {
+	gstBill
+	Number string
+	Date   time.Time
+	Type   string // D for debit, C for credit
+	Value  float64
+}
+
+// GetGSTR1 classifies the bills of a return period into the GSTR-1 sections. Period is YYYY-MM and
+// defaults to the previous month. Late fees are reported as debit notes, and waived late fees as
+// credit notes: in CDNR for registered customers, in CDNUR against large inter-state invoices,
+// and netted into B2CS otherwise. The notes carry no HSN code, so the HSN summary covers bills
+// only.
+func (s *GSTService) GetGSTR1(shopID, userID uuid.UUID, period string) (*models.GSTR1Return, error) {
+	// Check if user has access to the shop
+	var shopUser models.ShopUser
+	if err := s.db.Where("shop_id = ? AND user_id = ? AND is_active = ?", shopID, userID, true).First(&shopUser).Error; err != nil {
+		return nil, errors.New("access denied to shop")
+	}
+
+	gstin, start, bills, err := s.loadPeriodBills(shopID, period)
+	if err != nil {
+		return nil, err
+	}
+	notes, err := s.loadPeriodNotes(shopID, gstin, start)
+	if err != nil {
+		return nil, err
+	}
+
+	ret := &models.GSTR1Return{
+		GSTIN: gstin,
+		Fp:    start.Format("012006"),
+		B2B:   []models.GSTR1B2B{},
+		B2CL:  []models.GSTR1B2CL{},
+		B2CS:  []models.GSTR1B2CS{},
+		CDNR:  []models.GSTR1CDNR{},
+		CDNUR: []models.GSTR1CDNUR{},
+		HSN:   models.GSTR1HSNSummary{Data: []models.GSTR1HSNRow{}},
+	}
+
+	b2b := map[string]int{}
+	b2cl := map[string]int{}
+	b2cs := map[string]int{}
+	addB2CS := func(b gstBill, rates []gstSupply, sign float64) {
+		supplyType := "INTRA"
+		if b.InterState {
+			supplyType = "INTER"
+		}
+		for _, rate := range rates {
+			key := fmt.Sprintf("%s|%s|%.2f", supplyType, b.Pos, rate.Rate)
+			i, ok := b2cs[key]
+			if !ok {
+				i = len(ret.B2CS)
+				b2cs[key] = i
+				ret.B2CS = append(ret.B2CS, models.GSTR1B2CS{SplyTy: supplyType, Pos: b.Pos, Typ: "OE", Rt: rate.Rate})
+			}
+			row := &ret.B2CS[i]
+			row.Txval = roundAmount(row.Txval + sign*rate.Taxable)
+			row.Iamt = roundAmount(row.Iamt + sign*rate.IGST)
+			row.Camt = roundAmount(row.Camt + sign*rate.CGST)
+			row.Samt = roundAmount(row.Samt + sign*rate.SGST)
+		}
+	}
+	for _, b := range bills {
+		invoice := models.GSTR1Invoice{
+			Inum:  b.Bill.BillNumber,
+			Idt:   b.Bill.BillDate.Format("02-01-2006"),
+			Val:   roundAmount(b.Bill.TotalAmount),
+			Items: gstRateItems(b.Rates),
+		}
+
+		switch {
+		case b.CustomerID != "":
+			invoice.Pos = b.Pos
+			invoice.Rchrg = "N"
+			invoice.InvTyp = "R"
+			i, ok := b2b[b.CustomerID]
+			if !ok {
+				i = len(ret.B2B)
+				b2b[b.CustomerID] = i
+				ret.B2B = append(ret.B2B, models.GSTR1B2B{Ctin: b.CustomerID})
+			}
+			ret.B2B[i].Invoices = append(ret.B2B[i].Invoices, invoice)
+
+		case b.InterState && b.Bill.TotalAmount > gstB2CLLimit:
+			i, ok := b2cl[b.Pos]
+			if !ok {
+				i = len(ret.B2CL)
+				b2cl[b.Pos] = i
+				ret.B2CL = append(ret.B2CL, models.GSTR1B2CL{Pos: b.Pos})
+			}
+			ret.B2CL[i].Invoices = append(ret.B2CL[i].Invoices, invoice)
+
+		default:
+			addB2CS(b, b.Rates, 1)
+		}
+	}
+
+	cdnr := map[string]int{}
+	for _, n := range notes {
+		note := models.GSTR1Note{
+			NtNum: n.Number,
+			NtDt:  n.Date.Format("02-01-2006"),
+			NtTy:  n.Type,
+			Val:   n.Value,
+			Pos:   n.Pos,
+			Items: gstRateItems(n.Rates),
+		}
+
+		switch {
+		case n.CustomerID != "":
+			i, ok := cdnr[n.CustomerID]
+			if !ok {
+				i = len(ret.CDNR)
+				cdnr[n.CustomerID] = i
+				ret.CDNR = append(ret.CDNR, models.GSTR1CDNR{Ctin: n.CustomerID})
+			}
+			ret.CDNR[i].Notes = append(ret.CDNR[i].Notes, note)
+
+		case n.InterState && n.Bill.TotalAmount > gstB2CLLimit:
+			ret.CDNUR = append(ret.CDNUR, models.GSTR1CDNUR{GSTR1Note: note, Typ: "B2CL"})
+
+		default:
+			// Notes on small invoices to unregistered customers adjust the B2CS totals
+			sign := 1.0
+			if n.Type == "C" {
+				sign = -1
+			}
+			addB2CS(n.gstBill, n.Rates, sign)
+		}
+	}
+
+	hsn := map[string]int{}
+	for _, b := range bills {
+		for _, line := range b.Bill.Items {
+			code := line.HSNCode
+			if code == "" {
+				code = line.Item.HSNCode
+			}
+			unit := line.Unit
+			if unit == "" {
+				unit = line.Item.Unit
+			}
+			supply := gstSplitLine(line, b.InterState)
+
+			key := fmt.Sprintf("%s|%.2f", code, supply.Rate)
+			i, ok := hsn[key]
+			if !ok {
+				i = len(ret.HSN.Data)
+				hsn[key] = i
+				ret.HSN.Data = append(ret.HSN.Data, models.GSTR1HSNRow{
+					Num:   i + 1,
+					HsnSc: code,
+					Desc:  line.ItemName,
+					Uqc:   gstUnitCode(unit),
+					Rt:    supply.Rate,
+				})
+			}
+			row := &ret.HSN.Data[i]
+			row.Qty += float64(line.Quantity)
+			row.Txval = roundAmount(row.Txval + supply.Taxable)
+			row.Iamt = roundAmount(row.Iamt + supply.IGST)
+			row.Camt = roundAmount(row.Camt + supply.CGST)
+			row.Samt = roundAmount(row.Samt + supply.SGST)
+			row.Val = roundAmount(row.Txval + row.Iamt + row.Camt + row.Samt)
+		}
+	}
+
+	return ret, nil
+}
+
+// GetGSTR3B summarises the tax liability of a return period for GSTR-3B, net of late fee debit and
+// credit notes. Input tax credit is left at zero for the shop to fill in from GSTR-2B, as expenses
+// don't record the supplier's GSTIN.
+func (s *GSTService) GetGSTR3B(shopID, userID uuid.UUID, period string) (*models.GSTR3BReturn, error) {
+	// Check if user has access to the shop
+	var shopUser models.ShopUser
+	if err := s.db.Where("shop_id = ? AND user_id = ? AND is_active = ?", shopID, userID, true).First(&shopUser).Error; err != nil {
+		return nil, errors.New("access denied to shop")
+	}
+
+	gstin, start, bills, err := s.loadPeriodBills(shopID, period)
+	if err != nil {
+		return nil, err
+	}
+	notes, err := s.loadPeriodNotes(shopID, gstin, start)
+	if err != nil {
+		return nil, err
+	}
+
+	ret := &models.GSTR3BReturn{
+		GSTIN:     gstin,
+		RetPeriod: start.Format("012006"),
+		InterSup: models.GSTR3BInterState{
+			UnregDetails: []models.GSTR3BPlaceOfSupply{},
+			CompDetails:  []models.GSTR3BPlaceOfSupply{},
+			UinDetails:   []models.GSTR3BPlaceOfSupply{},
+		},
+		ITCElg: models.GSTR3BITC{
+			ITCAvl: []models.GSTR3BITCLine{
+				{Ty: "IMPG"}, {Ty: "IMPS"}, {Ty: "ISRC"}, {Ty: "ISD"}, {Ty: "OTH"},
+			},
+			ITCRev:   []models.GSTR3BITCLine{{Ty: "RUL"}, {Ty: "OTH"}},
+			ITCInelg: []models.GSTR3BITCLine{{Ty: "RUL"}, {Ty: "OTH"}},
+		},
+		InwardSup: models.GSTR3BInwardSupply{
+			IsupDetails: []models.GSTR3BInwardLine{{Ty: "GST"}, {Ty: "NONGST"}},
+		},
+	}
+
+	unregistered := map[string]int{}
+	add := func(b gstBill, rates []gstSupply, sign float64) {
+		for _, rate := range rates {
+			total := &ret.SupDetail.OsupDet
+			if rate.Rate == 0 {
+				total = &ret.SupDetail.OsupNilExmp
+			}
+			total.Txval = roundAmount(total.Txval + sign*rate.Taxable)
+			total.Iamt = roundAmount(total.Iamt + sign*rate.IGST)
+			total.Camt = roundAmount(total.Camt + sign*rate.CGST)
+			total.Samt = roundAmount(total.Samt + sign*rate.SGST)
+
+			if b.InterState && b.CustomerID == "" {
+				i, ok := unregistered[b.Pos]
+				if !ok {
+					i = len(ret.InterSup.UnregDetails)
+					unregistered[b.Pos] = i
+					ret.InterSup.UnregDetails = append(ret.InterSup.UnregDetails, models.GSTR3BPlaceOfSupply{Pos: b.Pos})
+				}
+				row := &ret.InterSup.UnregDetails[i]
+				row.Txval = roundAmount(row.Txval + sign*rate.Taxable)
+				row.Iamt = roundAmount(row.Iamt + sign*rate.IGST)
+			}
+		}
+	}
+	for _, b := range bills {
+		add(b, b.Rates, 1)
+	}
+	// Late fee debit notes add to the period's supplies and credit notes of waivers take away
+	for _, n := range notes {
+		sign := 1.0
+		if n.Type == "C" {
+			sign = -1
+		}
+		add(n.gstBill, n.Rates, sign)
+	}
+
+	return ret, nil
+}
+
+// ExportGSTR1CSV renders each GSTR-1 section as a CSV in the offline tool's layout, zipped together
+func (s *GSTService) ExportGSTR1CSV(shopID uuid.UUID, ret *models.GSTR1Return) ([]byte, error) {
+	customerNames := map[string]string{}
+	var customers []models.Customer
+	if err := s.db.Select("name", "tax_number").Where("shop_id = ? AND tax_number <> ''", shopID).Find(&customers).Error; err != nil {
+		return nil, err
+	}
+	for _, customer := range customers {
+		customerNames[strings.ToUpper(strings.TrimSpace(customer.TaxNumber))] = customer.Name
+	}
+
+	sections := []struct {
+		Name    string
+		Records [][]string
+	}{
+		{"b2b", [][]string{{"GSTIN/UIN of Recipient", "Receiver Name", "Invoice Number", "Invoice date", "Invoice Value", "Place Of Supply", "Reverse Charge", "Applicable % of Tax Rate", "Invoice Type", "E-Commerce GSTIN", "Rate", "Taxable Value", "Cess Amount"}}},
+		{"b2cl", [][]string{{"Invoice Number", "Invoice date", "Invoice Value", "Place Of Supply", "Applicable % of Tax Rate", "Rate", "Taxable Value", "Cess Amount", "E-Commerce GSTIN"}}},
+		{"b2cs", [][]string{{"Type", "Place Of Supply", "Applicable % of Tax Rate", "Rate", "Taxable Value", "Cess Amount", "E-Commerce GSTIN"}}},
+		{"cdnr", [][]string{{"GSTIN/UIN of Recipient", "Receiver Name", "Note Number", "Note Date", "Note Type", "Place Of Supply", "Reverse Charge", "Note Supply Type", "Note Value", "Applicable % of Tax Rate", "Rate", "Taxable Value", "Cess Amount"}}},
+		{"cdnur", [][]string{{"UR Type", "Note Number", "Note Date", "Note Type", "Place Of Supply", "Note Value", "Applicable % of Tax Rate", "Rate", "Taxable Value", "Cess Amount"}}},
+		{"hsn", [][]string{{"HSN", "Description", "UQC", "Total Quantity", "Total Value", "Rate", "Taxable Value", "Integrated Tax Amount", "Central Tax Amount", "State/UT Tax Amount", "Cess Amount"}}},
+	}
+
+	for _, party := range ret.B2B {
+		for _, inv := range party.Invoices {
+			for _, item := range inv.Items {
+				sections[0].Records = append(sections[0].Records, []string{
+					party.Ctin, customerNames[party.Ctin], inv.Inum, gstCSVDate(inv.Idt), gstCSVAmount(inv.Val),
+					gstPlaceOfSupply(inv.Pos), inv.Rchrg, "", "Regular B2B", "",
+					gstCSVAmount(item.Detail.Rt), gstCSVAmount(item.Detail.Txval), gstCSVAmount(item.Detail.Csamt),
+				})
+			}
+		}
+	}
+	for _, group := range ret.B2CL {
+		for _, inv := range group.Invoices {
+			for _, item := range inv.Items {
+				sections[1].Records = append(sections[1].Records, []string{
+					inv.Inum, gstCSVDate(inv.Idt), gstCSVAmount(inv.Val), gstPlaceOfSupply(group.Pos), "",
+					gstCSVAmount(item.Detail.Rt), gstCSVAmount(item.Detail.Txval), gstCSVAmount(item.Detail.Csamt), "",
+				})
+			}
+		}
+	}
+	for _, row := range ret.B2CS {
+		sections[2].Records = append(sections[2].Records, []string{
+			row.Typ, gstPlaceOfSupply(row.Pos), "", gstCSVAmount(row.Rt), gstCSVAmount(row.Txval), gstCSVAmount(row.Csamt), "",
+		})
+	}
+	for _, party := range ret.CDNR {
+		for _, note := range party.Notes {
+			for _, item := range note.Items {
+				sections[3].Records = append(sections[3].Records, []string{
+					party.Ctin, customerNames[party.Ctin], note.NtNum, gstCSVDate(note.NtDt), note.NtTy,
+					gstPlaceOfSupply(note.Pos), "N", "Regular B2B", gstCSVAmount(note.Val), "",
+					gstCSVAmount(item.Detail.Rt), gstCSVAmount(item.Detail.Txval), gstCSVAmount(item.Detail.Csamt),
+				})
+			}
+		}
+	}
+	for _, note := range ret.CDNUR {
+		for _, item := range note.Items {
+			sections[4].Records = append(sections[4].Records, []string{
+				note.Typ, note.NtNum, gstCSVDate(note.NtDt), note.NtTy, gstPlaceOfSupply(note.Pos), gstCSVAmount(note.Val), "",
+				gstCSVAmount(item.Detail.Rt), gstCSVAmount(item.Detail.Txval), gstCSVAmount(item.Detail.Csamt),
+			})
+		}
+	}
+	for _, row := range ret.HSN.Data {
+		sections[5].Records = append(sections[5].Records, []string{
+			row.HsnSc, row.Desc, row.Uqc, fmt.Sprintf("%.2f", row.Qty), gstCSVAmount(row.Val), gstCSVAmount(row.Rt),
+			gstCSVAmount(row.Txval), gstCSVAmount(row.Iamt), gstCSVAmount(row.Camt), gstCSVAmount(row.Samt), gstCSVAmount(row.Csamt),
+		})
+	}
+
+	var buf bytes.Buffer
+	archive := zip.NewWriter(&buf)
+	for _, section := range sections {
+		file, err := archive.Create(section.Name + ".csv")
+		if err != nil {
+			return nil, err
+		}
+		writer := csv.NewWriter(file)
+		if err := writer.WriteAll(section.Records); err != nil {
+			return nil, err
+		}
+	}
+	if err := archive.Close(); err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
+
+// ExportGSTR3BCSV renders the outward supply tables of a GSTR-3B summary as CSV
+func (s *GSTService) ExportGSTR3BCSV(ret *models.GSTR3BReturn) ([]byte, error) {
+	var buf bytes.Buffer
+	writer := csv.NewWriter(&buf)
+
+	records := [][]string{{"Table", "Nature of supplies", "Place Of Supply", "Taxable Value", "Integrated Tax", "Central Tax", "State/UT Tax", "Cess"}}
+	for _, row := range []struct {
+		Table, Nature string
+		Tax           models.GSTR3BTax
+	}{
+		{"3.1(a)", "Outward taxable supplies (other than zero rated, nil rated and exempted)", ret.SupDetail.OsupDet},
+		{"3.1(b)", "Outward taxable supplies (zero rated)", ret.SupDetail.OsupZero},
+		{"3.1(c)", "Other outward supplies (nil rated, exempted)", ret.SupDetail.OsupNilExmp},
+		{"3.1(d)", "Inward supplies (liable to reverse charge)", ret.SupDetail.IsupRev},
+		{"3.1(e)", "Non-GST outward supplies", ret.SupDetail.OsupNongst},
+	} {
+		records = append(records, []string{row.Table, row.Nature, "",
+			gstCSVAmount(row.Tax.Txval), gstCSVAmount(row.Tax.Iamt), gstCSVAmount(row.Tax.Camt), gstCSVAmount(row.Tax.Samt), gstCSVAmount(row.Tax.Csamt)})
+	}
+	for _, row := range ret.InterSup.UnregDetails {
+		records = append(records, []string{"3.2", "Supplies made to unregistered persons", gstPlaceOfSupply(row.Pos),
+			gstCSVAmount(row.Txval), gstCSVAmount(row.Iamt), "", "", ""})
+	}
+
+	if err := writer.WriteAll(records); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// loadPeriodBills loads the shop's GSTIN and the bills of a return period classified for GST
+func (s *GSTService) loadPeriodBills(shopID uuid.UUID, period string) (string, time.Time, []gstBill, error) {
+	start, err := gstReturnPeriod(period)
+	if err != nil {
+		return "", time.Time{}, nil, err
+	}
+
+	var shop models.Shop
+	if err := s.db.Select("id", "gst_number").Where("id = ?", shopID).First(&shop).Error; err != nil {
+		return "", time.Time{}, nil, errors.New("shop not found")
+	}
+	gstin := strings.ToUpper(strings.TrimSpace(shop.GSTNumber))
+	if !validGSTIN(gstin) {
+		return "", time.Time{}, nil, errors.New("shop GST number must be a valid GSTIN to file GST returns")
+	}
+	shopState := gstin[:2]
+
+	var bills []models.Bill
+	if err := s.db.Preload("Customer").Preload("Items").Preload("Items.Item").
+		Where("shop_id = ? AND status <> ? AND bill_date >= ? AND bill_date < ?",
+			shopID, "cancelled", start, start.AddDate(0, 1, 0)).
+		Order("bill_date ASC, bill_number ASC").
+		Find(&bills).Error; err != nil {
+		return "", time.Time{}, nil, err
+	}
+
+	classified := make([]gstBill, 0, len(bills))
+	for _, bill := range bills {
+		classified = append(classified, classifyGSTBill(bill, shopState))
+	}
+
+	return gstin, start, classified, nil
+}
+
+// loadPeriodNotes loads the late fee debit notes charged in a return period and the credit notes
+// of late fees waived in it, classified by the bill they were charged on
+func (s *GSTService) loadPeriodNotes(shopID uuid.UUID, gstin string, start time.Time) ([]gstNote, error) {
+	end := start.AddDate(0, 1, 0)
+	var charges []models.LateFeeCharge
+	if err := s.db.Preload("Bill").Preload("Bill.Customer").Preload("Bill.Items").
+		Where("shop_id = ? AND ((charge_date >= ? AND charge_date < ?) OR (status = ? AND waived_at >= ? AND waived_at < ?))",
+			shopID, start, end, "waived", start, end).
+		Order("charge_date ASC, note_number ASC").
+		Find(&charges).Error; err != nil {
+		return nil, err
+	}
+
+	var notes []gstNote
+	for _, charge := range charges {
+		b := classifyGSTBill(charge.Bill, gstin[:2])
+		note := gstNote{gstBill: b, Number: charge.NoteNumber, Value: roundAmount(charge.Amount)}
+		note.Rates = gstLateFeeSupplies(charge.Amount, b)
+		if !charge.ChargeDate.Before(start) && charge.ChargeDate.Before(end) {
+			note.Date = charge.ChargeDate
+			note.Type = "D"
+			notes = append(notes, note)
+		}
+		if charge.Status == "waived" && charge.WaivedAt != nil && !charge.WaivedAt.Before(start) && charge.WaivedAt.Before(end) {
+			note.Date = lateFeeDate(*charge.WaivedAt)
+			note.Type = "C"
+			notes = append(notes, note)
+		}
+	}
+	return notes, nil
+}
+
+// classifyGSTBill works out a bill's place of supply from the customer's GSTIN or state and sums
+// its lines by tax rate
+func classifyGSTBill(bill models.Bill, shopState string) gstBill {
+	b := gstBill{Bill: bill, Pos: shopState}
+	if bill.Customer != nil {
+		if customerGSTIN := strings.ToUpper(strings.TrimSpace(bill.Customer.TaxNumber)); validGSTIN(customerGSTIN) {
+			b.CustomerID = customerGSTIN
+			b.Pos = customerGSTIN[:2]
+		} else if code, ok := gstStateCodes[strings.ToLower(strings.TrimSpace(bill.Customer.State))]; ok {
+			b.Pos = code
+		}
+	}
+	b.InterState = b.Pos != shopState
+
+	rates := map[float64]int{}
+	for _, line := range bill.Items {
+		supply := gstSplitLine(line, b.InterState)
+		i, ok := rates[supply.Rate]
+		if !ok {
+			i = len(b.Rates)
+			rates[supply.Rate] = i
+			b.Rates = append(b.Rates, gstSupply{Rate: supply.Rate})
+		}
+		b.Rates[i].Taxable = roundAmount(b.Rates[i].Taxable + supply.Taxable)
+		b.Rates[i].IGST = roundAmount(b.Rates[i].IGST + supply.IGST)
+		b.Rates[i].CGST = roundAmount(b.Rates[i].CGST + supply.CGST)
+		b.Rates[i].SGST = roundAmount(b.Rates[i].SGST + supply.SGST)
+	}
+	sort.Slice(b.Rates, func(i, j int) bool { return b.Rates[i].Rate < b.Rates[j].Rate })
+	return b
+}
+
+// gstLateFeeSupplies splits a late fee over the tax rates of its bill in proportion to their
+// taxable value. Fees and interest for paying late are part of the value of the supply they are
+// charged on, and the fee is taken to include the tax on it.
+func gstLateFeeSupplies(amount float64, b gstBill) []gstSupply {
+	var rates []gstSupply
+	var total float64
+	for _, rate := range b.Rates {
+		if rate.Taxable > 0 {
+			rates = append(rates, rate)
+			total += rate.Taxable
+		}
+	}
+	if total == 0 {
+		return []gstSupply{{Taxable: roundAmount(amount)}}
+	}
+
+	supplies := make([]gstSupply, 0, len(rates))
+	remaining := roundAmount(amount)
+	for i, rate := range rates {
+		// The last rate takes what is left, so the parts add up to the fee
+		value := remaining
+		if i < len(rates)-1 {
+			value = roundAmount(amount * rate.Taxable / total)
+		}
+		remaining = roundAmount(remaining - value)
+
+		supply := gstSupply{Rate: rate.Rate, Taxable: roundAmount(value / (1 + rate.Rate/100))}
+		tax := roundAmount(value - supply.Taxable)
+		if b.InterState {
+			supply.IGST = tax
+		} else {
+			supply.CGST = roundAmount(tax / 2)
+			supply.SGST = roundAmount(tax - supply.CGST)
+		}
+		supplies = append(supplies, supply)
+	}
+	return supplies
+}
+
+// gstSplitLine works out a bill line's taxable value and splits its tax into integrated tax for
+// inter-state supplies or central and state tax for supplies within the shop's state. Lines from
+// before taxable values were recorded fall back to the line total.
+func gstSplitLine(line models.BillItem, interState bool) gstSupply {
+	supply := gstSupply{Rate: line.TaxRate, Taxable: line.TaxableValue}
+	if supply.Taxable == 0 && line.TaxAmount == 0 {
+		supply.Taxable = line.TotalPrice
+	}
+	if interState {
+		supply.IGST = line.TaxAmount
+	} else {
+		supply.CGST = roundAmount(line.TaxAmount / 2)
+		supply.SGST = roundAmount(line.TaxAmount - supply.CGST)
+	}
+	return supply
+}
+
+// gstRateItems numbers a bill's per-rate totals as GSTR-1 invoice items
+func gstRateItems(rates []gstSupply) []models.GSTR1RateItem {
+	items := make([]models.GSTR1RateItem, 0, len(rates))
+	for i, rate := range rates {
+		items = append(items, models.GSTR1RateItem{
+			Num: i + 1,
+			Detail: models.GSTR1ItemTotal{
+				Rt:    rate.Rate,
+				Txval: rate.Taxable,
+				Iamt:  rate.IGST,
+				Camt:  rate.CGST,
+				Samt:  rate.SGST,
+			},
+		})
+	}
+	return items
+}
+
+// gstReturnPeriod parses a YYYY-MM return period into its first day, defaulting to last month
+func gstReturnPeriod(period string) (time.Time, error) {
+	if period == "" {
+		now := time.Now()
+		return time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.UTC).AddDate(0, -1, 0), nil
+	}
+	start, err := time.Parse("2006-01", period)
+	if err != nil {
+		return time.Time{}, errors.New("period must be in YYYY-MM format")
+	}
+	return start, nil
+}
+
+// validGSTIN reports whether a value has the shape of a GSTIN: a two digit state code, a PAN,
+// an entity number, a Z and a check character
+func validGSTIN(gstin string) bool {
+	if len(gstin) != 15 || gstin[13] != 'Z' {
+		return false
+	}
+	for i, r := range gstin {
+		isDigit := r >= '0' && r <= '9'
+		isLetter := r >= 'A' && r <= 'Z'
+		switch {
+		case i < 2 || (i >= 7 && i < 11):
+			if !isDigit {
+				return false
+			}
+		case i < 7 || i == 11:
+			if !isLetter {
+				return false
+			}
+		default:
+			if !isDigit && !isLetter {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// gstUnitCode maps an item unit to a GST unit quantity code, using OTH for units it doesn't know
+func gstUnitCode(unit string) string {
+	if code, ok := gstUnitCodes[strings.ToLower(strings.TrimSpace(unit))]; ok {
+		return code
+	}
+	return "OTH"
+}
+
+// gstPlaceOfSupply formats a state code as the offline tool's "29-Karnataka" place of supply
+func gstPlaceOfSupply(code string) string {
+	for name, c := range gstStateCodes {
+		if c == code {
+			words := strings.Fields(name)
+			for i, word := range words {
+				if word != "and" {
+					words[i] = strings.ToUpper(word[:1]) + word[1:]
+				}
+			}
+			return code + "-" + strings.Join(words, " ")
+		}
+	}
+	return code
+}
+
+// gstCSVDate converts a DD-MM-YYYY return date to the offline tool's DD-Mon-YY CSV format
+func gstCSVDate(date string) string {
+	parsed, err := time.Parse("02-01-2006", date)
+	if err != nil {
+		return date
+	}
+	return parsed.Format("02-Jan-06")
+}
+
+// gstCSVAmount formats an amount for the offline tool's CSV templates
+func gstCSVAmount(amount float64) string {
+	return fmt.Sprintf("%.2f", amount)
+}
@@ -0,0 +1,158 @@
+package services
+
+import (
+	"billboard/backend/models"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// IRPClient registers e-invoices with an Invoice Registration Portal. Implementations talk to
+// the IRP directly or through a GST Suvidha Provider.
+type IRPClient interface {
+	// GenerateIRN registers an invoice and returns its IRN, acknowledgement and signed QR code
+	GenerateIRN(gstin string, invoice models.EInvoice) (*IRPRegistration, error)
+	// CancelIRN cancels a registered invoice. Reason is the IRP's code: 1 duplicate,
+	// 2 data entry mistake, 3 order cancelled, 4 others.
+	CancelIRN(gstin, irn, reason, remark string) (*IRPCancellation, error)
+}
+
+// IRPRegistration is the IRP's response to a registered invoice
+type IRPRegistration struct {
+	IRN          string
+	AckNo        string
+	AckDate      time.Time
+	SignedQRCode string
+}
+
+// IRPCancellation is the IRP's response to a cancelled invoice
+type IRPCancellation struct {
+	IRN        string
+	CancelDate time.Time
+}
+
+// MockIRPClient is an in-memory IRP for development and testing. It computes IRNs the way the
+// portal does and signs QR codes with a key generated at startup.
+type MockIRPClient struct {
+	key        []byte
+	mu         sync.Mutex
+	registered map[string]IRPRegistration
+	cancelled  map[string]bool
+	lastAckNo  int64
+}
+
+// NewMockIRPClient creates a new MockIRPClient instance
+func NewMockIRPClient() *MockIRPClient {
+	key := make([]byte, 32)
+	if _, err := rand.Read(key); err != nil {
+		panic(fmt.Sprintf("failed to generate mock IRP key: %v", err))
+	}
+	return &MockIRPClient{
+		key:        key,
+		registered: map[string]IRPRegistration{},
+		cancelled:  map[string]bool{},
+	}
+}
+
+// GenerateIRN registers an invoice, rejecting documents that were already registered
+func (c *MockIRPClient) GenerateIRN(gstin string, invoice models.EInvoice) (*IRPRegistration, error) {
+	docDate, err := time.Parse("02/01/2006", invoice.DocDtls.Dt)
+	if err != nil {
+		return nil, errors.New("invalid document date")
+	}
+	financialYear := docDate.Year()
+	if docDate.Month() < time.April {
+		financialYear--
+	}
+
+	// The IRN is the SHA-256 of the seller GSTIN, financial year, document type and number
+	sum := sha256.Sum256([]byte(fmt.Sprintf("%s%d-%02d%s%s", gstin, financialYear, (financialYear+1)%100, invoice.DocDtls.Typ, invoice.DocDtls.No)))
+	irn := hex.EncodeToString(sum[:])
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if _, ok := c.registered[irn]; ok {
+		return nil, errors.New("duplicate IRN: invoice is already registered")
+	}
+
+	now := time.Now()
+	ackNo := now.UnixNano() / int64(time.Microsecond)
+	if ackNo <= c.lastAckNo {
+		ackNo = c.lastAckNo + 1
+	}
+	c.lastAckNo = ackNo
+
+	mainHSN := ""
+	if len(invoice.ItemList) > 0 {
+		mainHSN = invoice.ItemList[0].HsnCd
+	}
+	qrData, err := json.Marshal(map[string]interface{}{
+		"SellerGstin": gstin,
+		"BuyerGstin":  invoice.BuyerDtls.Gstin,
+		"DocNo":       invoice.DocDtls.No,
+		"DocTyp":      invoice.DocDtls.Typ,
+		"DocDt":       invoice.DocDtls.Dt,
+		"TotInvVal":   invoice.ValDtls.TotInvVal,
+		"ItemCnt":     len(invoice.ItemList),
+		"MainHsnCode": mainHSN,
+		"Irn":         irn,
+		"IrnDt":       now.Format("2006-01-02 15:04:05"),
+	})
+	if err != nil {
+		return nil, err
+	}
+	signedQR, err := c.sign(map[string]interface{}{"data": string(qrData), "iss": "NIC"})
+	if err != nil {
+		return nil, err
+	}
+
+	registration := IRPRegistration{
+		IRN:          irn,
+		AckNo:        fmt.Sprintf("%d", ackNo),
+		AckDate:      now,
+		SignedQRCode: signedQR,
+	}
+	c.registered[irn] = registration
+	return &registration, nil
+}
+
+// CancelIRN cancels an invoice registered with this mock
+func (c *MockIRPClient) CancelIRN(gstin, irn, reason, remark string) (*IRPCancellation, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if _, ok := c.registered[irn]; !ok {
+		return nil, errors.New("invalid IRN")
+	}
+	if c.cancelled[irn] {
+		return nil, errors.New("IRN is already cancelled")
+	}
+	c.cancelled[irn] = true
+
+	return &IRPCancellation{IRN: irn, CancelDate: time.Now()}, nil
+}
+
+// sign encodes claims as a JWT signed with the mock's key
+func (c *MockIRPClient) sign(claims map[string]interface{}) (string, error) {
+	header, err := json.Marshal(map[string]string{"alg": "HS256", "typ": "JWT"})
+	if err != nil {
+		return "", err
+	}
+	payload, err := json.Marshal(claims)
+	if err != nil {
+		return "", err
+	}
+
+	unsigned := base64.RawURLEncoding.EncodeToString(header) + "." + base64.RawURLEncoding.EncodeToString(payload)
+	mac := hmac.New(sha256.New, c.key)
+	mac.Write([]byte(unsigned))
+	return unsigned + "." + base64.RawURLEncoding.EncodeToString(mac.Sum(nil)), nil
+}
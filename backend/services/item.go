@@ -89,21 +89,29 @@ func (s *ItemService) CreateItem(shopID uuid.UUID, req models.ItemRequest) (*mod
 		}
 	}
 
+	barcode, barcodeType, err := s.prepareBarcode(s.db, shopID, nil, req.Barcode, req.BarcodeType)
+	if err != nil {
+		return nil, err
+	}
+
 	// Create item
 	item := models.Item{
-		ShopID:      shopID,
-		Name:        req.Name,
-		Description: req.Description,
-		SKU:         req.SKU,
-		Price:       req.Price,
-		CostPrice:   req.CostPrice,
-		TaxRate:     req.TaxRate,
-		Category:    req.Category,
-		Quantity:    req.Quantity,
-		MinQuantity: req.MinQuantity,
-		Unit:        req.Unit,
-		Barcode:     req.Barcode,
-		IsActive:    req.IsActive,
+		ShopID:       shopID,
+		Name:         req.Name,
+		Description:  req.Description,
+		SKU:          req.SKU,
+		Price:        req.Price,
+		CostPrice:    req.CostPrice,
+		TaxRate:      req.TaxRate,
+		TaxInclusive: req.TaxInclusive,
+		HSNCode:      strings.TrimSpace(req.HSNCode),
+		Category:     req.Category,
+		Quantity:     req.Quantity,
+		MinQuantity:  req.MinQuantity,
+		Unit:         req.Unit,
+		Barcode:      barcode,
+		BarcodeType:  barcodeType,
+		IsActive:     req.IsActive,
 	}
 
 	if item.Unit == "" {
@@ -111,7 +119,7 @@ func (s *ItemService) CreateItem(shopID uuid.UUID, req models.ItemRequest) (*mod
 	}
 
 	if err := s.db.Create(&item).Error; err != nil {
-		return nil, err
+		return nil, barcodeError(err)
 	}
 
 	response := s.itemToResponse(item)
@@ -136,6 +144,17 @@ func (s *ItemService) UpdateItem(shopID, itemID uuid.UUID, req models.ItemReques
 		}
 	}
 
+	// Keep the existing barcode when the request leaves it empty
+	if req.Barcode == "" && item.Barcode != "" {
+		req.Barcode = item.Barcode
+		req.BarcodeType = item.BarcodeType
+	}
+
+	barcode, barcodeType, err := s.prepareBarcode(s.db, shopID, &itemID, req.Barcode, req.BarcodeType)
+	if err != nil {
+		return nil, err
+	}
+
 	// Update fields
 	item.Name = req.Name
 	item.Description = req.Description
@@ -143,11 +162,14 @@ func (s *ItemService) UpdateItem(shopID, itemID uuid.UUID, req models.ItemReques
 	item.Price = req.Price
 	item.CostPrice = req.CostPrice
 	item.TaxRate = req.TaxRate
+	item.TaxInclusive = req.TaxInclusive
+	item.HSNCode = strings.TrimSpace(req.HSNCode)
 	item.Category = req.Category
 	item.Quantity = req.Quantity
 	item.MinQuantity = req.MinQuantity
 	item.Unit = req.Unit
-	item.Barcode = req.Barcode
+	item.Barcode = barcode
+	item.BarcodeType = barcodeType
 	item.IsActive = req.IsActive
 
 	if item.Unit == "" {
@@ -155,7 +177,7 @@ func (s *ItemService) UpdateItem(shopID, itemID uuid.UUID, req models.ItemReques
 	}
 
 	if err := s.db.Save(&item).Error; err != nil {
-		return nil, err
+		return nil, barcodeError(err)
 	}
 
 	response := s.itemToResponse(item)
@@ -245,20 +267,29 @@ func (s *ItemService) BulkCreateItems(shopID uuid.UUID, items []models.ItemReque
 			return nil, errors.New("price must be greater than 0 for all items")
 		}
 
+		barcode, barcodeType, err := s.prepareBarcode(tx, shopID, nil, req.Barcode, req.BarcodeType)
+		if err != nil {
+			tx.Rollback()
+			return nil, fmt.Errorf("invalid barcode for item %s: %v", req.Name, err)
+		}
+
 		item := models.Item{
-			ShopID:      shopID,
-			Name:        req.Name,
-			Description: req.Description,
-			SKU:         req.SKU,
-			Price:       req.Price,
-			CostPrice:   req.CostPrice,
-			TaxRate:     req.TaxRate,
-			Category:    req.Category,
-			Quantity:    req.Quantity,
-			MinQuantity: req.MinQuantity,
-			Unit:        req.Unit,
-			Barcode:     req.Barcode,
-			IsActive:    req.IsActive,
+			ShopID:       shopID,
+			Name:         req.Name,
+			Description:  req.Description,
+			SKU:          req.SKU,
+			Price:        req.Price,
+			CostPrice:    req.CostPrice,
+			TaxRate:      req.TaxRate,
+			TaxInclusive: req.TaxInclusive,
+			HSNCode:      strings.TrimSpace(req.HSNCode),
+			Category:     req.Category,
+			Quantity:     req.Quantity,
+			MinQuantity:  req.MinQuantity,
+			Unit:         req.Unit,
+			Barcode:      barcode,
+			BarcodeType:  barcodeType,
+			IsActive:     req.IsActive,
 		}
 
 		if item.Unit == "" {
@@ -267,7 +298,7 @@ func (s *ItemService) BulkCreateItems(shopID uuid.UUID, items []models.ItemReque
 
 		if err := tx.Create(&item).Error; err != nil {
 			tx.Rollback()
-			return nil, fmt.Errorf("failed to create item %s: %v", req.Name, err)
+			return nil, fmt.Errorf("failed to create item %s: %v", req.Name, barcodeError(err))
 		}
 
 		createdItems = append(createdItems, item)
@@ -289,22 +320,25 @@ func (s *ItemService) BulkCreateItems(shopID uuid.UUID, items []models.ItemReque
 // itemToResponse converts Item model to ItemResponse
 func (s *ItemService) itemToResponse(item models.Item) models.ItemResponse {
 	return models.ItemResponse{
-		ID:          item.ID,
-		ShopID:      item.ShopID,
-		Name:        item.Name,
-		Description: item.Description,
-		SKU:         item.SKU,
-		Price:       item.Price,
-		CostPrice:   item.CostPrice,
-		TaxRate:     item.TaxRate,
-		Category:    item.Category,
-		Quantity:    item.Quantity,
-		MinQuantity: item.MinQuantity,
-		Unit:        item.Unit,
-		Barcode:     item.Barcode,
-		IsActive:    item.IsActive,
-		IsLowStock:  item.Quantity <= item.MinQuantity,
-		CreatedAt:   item.CreatedAt,
-		UpdatedAt:   item.UpdatedAt,
+		ID:           item.ID,
+		ShopID:       item.ShopID,
+		Name:         item.Name,
+		Description:  item.Description,
+		SKU:          item.SKU,
+		Price:        item.Price,
+		CostPrice:    item.CostPrice,
+		TaxRate:      item.TaxRate,
+		TaxInclusive: item.TaxInclusive,
+		HSNCode:      item.HSNCode,
+		Category:     item.Category,
+		Quantity:     item.Quantity,
+		MinQuantity:  item.MinQuantity,
+		Unit:         item.Unit,
+		Barcode:      item.Barcode,
+		BarcodeType:  item.BarcodeType,
+		IsActive:     item.IsActive,
+		IsLowStock:   item.Quantity <= item.MinQuantity,
+		CreatedAt:    item.CreatedAt,
+		UpdatedAt:    item.UpdatedAt,
 	}
 }
@@ -0,0 +1,351 @@
+package services
+
+import (
+	"billboard/backend/models"
+	"bytes"
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/png"
+	"math"
+
+	"github.com/boombuler/barcode"
+	"github.com/boombuler/barcode/code128"
+	"github.com/boombuler/barcode/ean"
+	"github.com/boombuler/barcode/qr"
+	"github.com/google/uuid"
+	"github.com/jung-kurt/gofpdf"
+	"golang.org/x/image/font"
+	"golang.org/x/image/font/basicfont"
+	"golang.org/x/image/math/fixed"
+	"gorm.io/gorm"
+)
+
+// LabelLayout describes a label stock, in millimetres
+type LabelLayout struct {
+	Name         string  `json:"name"`
+	Description  string  `json:"description"`
+	PageWidth    float64 `json:"page_width"`
+	PageHeight   float64 `json:"page_height"`
+	LabelWidth   float64 `json:"label_width"`
+	LabelHeight  float64 `json:"label_height"`
+	Columns      int     `json:"columns"`
+	Rows         int     `json:"rows"`
+	MarginLeft   float64 `json:"margin_left"`
+	MarginTop    float64 `json:"margin_top"`
+	GapX         float64 `json:"gap_x"`
+	GapY         float64 `json:"gap_y"`
+	IsRoll       bool    `json:"is_roll"`
+	DotsPerInch  int     `json:"dots_per_inch"`
+	LabelsOnPage int     `json:"labels_on_page"`
+}
+
+const defaultLabelSize = "a4-65"
+
+// maxLabelsPerRequest caps the labels rendered at once, over all items and copies
+const maxLabelsPerRequest = 2000
+
+// maxPNGPixels caps the size of a PNG of labels, which is drawn in memory at once, to one A4
+// page at 300 dpi. Larger jobs are printed from PDF.
+const maxPNGPixels = 2480 * 3508
+
+// labelLayouts lists the supported A4 sheets and roll label sizes
+var labelLayouts = map[string]LabelLayout{
+	"a4-65":       {Description: "A4 sheet, 65 labels of 38.1 x 21.2 mm", PageWidth: 210, PageHeight: 297, LabelWidth: 38.1, LabelHeight: 21.2, Columns: 5, Rows: 13, MarginLeft: 4.75, MarginTop: 10.7, GapX: 2.5, DotsPerInch: 300},
+	"a4-40":       {Description: "A4 sheet, 40 labels of 52.5 x 29.7 mm", PageWidth: 210, PageHeight: 297, LabelWidth: 52.5, LabelHeight: 29.7, Columns: 4, Rows: 10, DotsPerInch: 300},
+	"a4-24":       {Description: "A4 sheet, 24 labels of 70 x 37 mm", PageWidth: 210, PageHeight: 297, LabelWidth: 70, LabelHeight: 37, Columns: 3, Rows: 8, MarginTop: 0.5, DotsPerInch: 300},
+	"a4-21":       {Description: "A4 sheet, 21 labels of 70 x 42.3 mm", PageWidth: 210, PageHeight: 297, LabelWidth: 70, LabelHeight: 42.3, Columns: 3, Rows: 7, MarginTop: 0.45, DotsPerInch: 300},
+	"roll-38x25":  {Description: "Roll, 38 x 25 mm labels", PageWidth: 38, PageHeight: 25, LabelWidth: 38, LabelHeight: 25, Columns: 1, Rows: 1, IsRoll: true, DotsPerInch: 203},
+	"roll-50x25":  {Description: "Roll, 50 x 25 mm labels", PageWidth: 50, PageHeight: 25, LabelWidth: 50, LabelHeight: 25, Columns: 1, Rows: 1, IsRoll: true, DotsPerInch: 203},
+	"roll-58x40":  {Description: "Roll, 58 x 40 mm labels", PageWidth: 58, PageHeight: 40, LabelWidth: 58, LabelHeight: 40, Columns: 1, Rows: 1, IsRoll: true, DotsPerInch: 203},
+	"roll-100x50": {Description: "Roll, 100 x 50 mm labels", PageWidth: 100, PageHeight: 50, LabelWidth: 100, LabelHeight: 50, Columns: 1, Rows: 1, IsRoll: true, DotsPerInch: 203},
+}
+
+// LabelService renders printable barcode labels for items
+type LabelService struct {
+	db *gorm.DB
+}
+
+// NewLabelService creates a new LabelService instance
+func NewLabelService(db *gorm.DB) *LabelService {
+	return &LabelService{db: db}
+}
+
+// GetLabelSizes returns the supported label sizes
+func (s *LabelService) GetLabelSizes() []LabelLayout {
+	names := []string{"a4-65", "a4-40", "a4-24", "a4-21", "roll-38x25", "roll-50x25", "roll-58x40", "roll-100x50"}
+
+	var layouts []LabelLayout
+	for _, name := range names {
+		layout := labelLayouts[name]
+		layout.Name = name
+		layout.LabelsOnPage = layout.Columns * layout.Rows
+		layouts = append(layouts, layout)
+	}
+	return layouts
+}
+
+// GenerateLabels renders labels for the requested items and returns the file content and MIME type
+func (s *LabelService) GenerateLabels(shopID, userID uuid.UUID, req models.LabelRequest) ([]byte, string, error) {
+	// Check if user has access to the shop
+	var shopUser models.ShopUser
+	if err := s.db.Where("shop_id = ? AND user_id = ? AND is_active = ?", shopID, userID, true).First(&shopUser).Error; err != nil {
+		return nil, "", errors.New("access denied to shop")
+	}
+
+	if len(req.ItemIDs) == 0 {
+		return nil, "", errors.New("at least one item is required")
+	}
+
+	if req.LabelSize == "" {
+		req.LabelSize = defaultLabelSize
+	}
+	layout, ok := labelLayouts[req.LabelSize]
+	if !ok {
+		return nil, "", errors.New("unsupported label size")
+	}
+
+	if req.Copies <= 0 {
+		req.Copies = 1
+	}
+	if len(req.ItemIDs)*req.Copies > maxLabelsPerRequest {
+		return nil, "", fmt.Errorf("at most %d labels can be printed at once", maxLabelsPerRequest)
+	}
+
+	var items []models.Item
+	if err := s.db.Where("shop_id = ? AND id IN ?", shopID, req.ItemIDs).Find(&items).Error; err != nil {
+		return nil, "", err
+	}
+	if len(items) == 0 {
+		return nil, "", errors.New("item not found")
+	}
+
+	// Keep the order in which items were requested
+	itemsByID := make(map[uuid.UUID]models.Item)
+	for _, item := range items {
+		itemsByID[item.ID] = item
+	}
+
+	var labels []models.Item
+	for _, itemID := range req.ItemIDs {
+		item, ok := itemsByID[itemID]
+		if !ok {
+			continue
+		}
+		if item.Barcode == "" {
+			return nil, "", fmt.Errorf("item %s has no barcode", item.Name)
+		}
+		for i := 0; i < req.Copies; i++ {
+			labels = append(labels, item)
+		}
+	}
+
+	switch req.Format {
+	case "", "pdf":
+		content, err := renderLabelsPDF(layout, labels)
+		return content, "application/pdf", err
+	case "png":
+		content, err := renderLabelsPNG(layout, labels)
+		return content, "image/png", err
+	default:
+		return nil, "", errors.New("unsupported label format")
+	}
+}
+
+// encodeItemBarcode encodes an item's barcode in its symbology
+func encodeItemBarcode(item models.Item) (barcode.Barcode, error) {
+	barcodeType := item.BarcodeType
+	if barcodeType == "" {
+		barcodeType = detectBarcodeType(item.Barcode)
+	}
+
+	if barcodeType == BarcodeTypeEAN13 {
+		return ean.Encode(item.Barcode)
+	}
+	return code128.Encode(item.Barcode)
+}
+
+// labelPosition returns the top-left corner of the n-th label on a page, in millimetres
+func labelPosition(layout LabelLayout, n int) (float64, float64) {
+	col := n % layout.Columns
+	row := n / layout.Columns
+	x := layout.MarginLeft + float64(col)*(layout.LabelWidth+layout.GapX)
+	y := layout.MarginTop + float64(row)*(layout.LabelHeight+layout.GapY)
+	return x, y
+}
+
+// renderLabelsPDF lays the labels out over as many pages as needed
+func renderLabelsPDF(layout LabelLayout, labels []models.Item) ([]byte, error) {
+	orientation := "P"
+	if layout.PageWidth > layout.PageHeight {
+		orientation = "L"
+	}
+
+	pdf := gofpdf.NewCustom(&gofpdf.InitType{
+		OrientationStr: orientation,
+		UnitStr:        "mm",
+		Size:           gofpdf.SizeType{Wd: layout.PageWidth, Ht: layout.PageHeight},
+	})
+	pdf.SetMargins(0, 0, 0)
+	pdf.SetAutoPageBreak(false, 0)
+
+	perPage := layout.Columns * layout.Rows
+	padding := math.Min(layout.LabelWidth, layout.LabelHeight) * 0.06
+	fontSize := math.Max(5, math.Min(10, layout.LabelHeight/3.2))
+	lineHeight := fontSize * 0.3528 * 1.15
+
+	for i, item := range labels {
+		if i%perPage == 0 {
+			pdf.AddPage()
+		}
+		x, y := labelPosition(layout, i%perPage)
+		innerWidth := layout.LabelWidth - 2*padding
+
+		// Item name
+		pdf.SetFont("Helvetica", "B", fontSize)
+		pdf.SetXY(x+padding, y+padding)
+		pdf.CellFormat(innerWidth, lineHeight, fitPDFText(pdf, pdfText(item.Name), innerWidth), "", 0, "C", false, 0, "")
+
+		// Price
+		pdf.SetFont("Helvetica", "", fontSize)
+		pdf.SetXY(x+padding, y+padding+lineHeight)
+		pdf.CellFormat(innerWidth, lineHeight, fmt.Sprintf("MRP %.2f", item.Price), "", 0, "C", false, 0, "")
+
+		// Barcode with the human readable code underneath
+		bc, err := encodeItemBarcode(item)
+		if err != nil {
+			return nil, fmt.Errorf("failed to encode barcode for item %s: %v", item.Name, err)
+		}
+		barcodeTop := y + padding + 2*lineHeight
+		barcodeHeight := layout.LabelHeight - 2*padding - 3*lineHeight
+		if barcodeHeight <= 0 {
+			return nil, errors.New("label size is too small")
+		}
+
+		buf, err := barcodePNG(bc, bc.Bounds().Dx()*4, 80)
+		if err != nil {
+			return nil, err
+		}
+		imageName := fmt.Sprintf("barcode-%s-%s", item.BarcodeType, item.Barcode)
+		pdf.RegisterImageOptionsReader(imageName, gofpdf.ImageOptions{ImageType: "PNG"}, bytes.NewReader(buf))
+		pdf.ImageOptions(imageName, x+padding, barcodeTop, innerWidth, barcodeHeight, false, gofpdf.ImageOptions{ImageType: "PNG"}, 0, "")
+
+		pdf.SetFont("Courier", "", fontSize)
+		pdf.SetXY(x+padding, barcodeTop+barcodeHeight)
+		pdf.CellFormat(innerWidth, lineHeight, item.Barcode, "", 0, "C", false, 0, "")
+	}
+
+	var out bytes.Buffer
+	if err := pdf.Output(&out); err != nil {
+		return nil, err
+	}
+	return out.Bytes(), nil
+}
+
+// barcodePNG scales a barcode to the given size and encodes it as an 8-bit grayscale PNG
+func barcodePNG(bc barcode.Barcode, width, height int) ([]byte, error) {
+	scaled, err := barcode.Scale(bc, width, height)
+	if err != nil {
+		return nil, err
+	}
+
+	gray := image.NewGray(scaled.Bounds())
+	draw.Draw(gray, gray.Bounds(), scaled, scaled.Bounds().Min, draw.Src)
+
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, gray); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// qrCodePNG encodes content as a square QR code PNG of the given size in pixels
+func qrCodePNG(content string, size int) ([]byte, error) {
+	bc, err := qr.Encode(content, qr.M, qr.Auto)
+	if err != nil {
+		return nil, err
+	}
+	return barcodePNG(bc, size, size)
+}
+
+// fitPDFText truncates text so that it fits into the given width
+func fitPDFText(pdf *gofpdf.Fpdf, text string, width float64) string {
+	if pdf.GetStringWidth(text) <= width {
+		return text
+	}
+	runes := []rune(text)
+	for len(runes) > 0 && pdf.GetStringWidth(string(runes)+"...") > width {
+		runes = runes[:len(runes)-1]
+	}
+	return string(runes) + "..."
+}
+
+// renderLabelsPNG draws all pages of labels into a single grayscale image, stacked vertically.
+// Only as many pages as fit in maxPNGPixels are drawn.
+func renderLabelsPNG(layout LabelLayout, labels []models.Item) ([]byte, error) {
+	pxPerMM := float64(layout.DotsPerInch) / 25.4
+	toPx := func(mm float64) int { return int(math.Round(mm * pxPerMM)) }
+
+	perPage := layout.Columns * layout.Rows
+	pages := (len(labels) + perPage - 1) / perPage
+	pageWidth, pageHeight := toPx(layout.PageWidth), toPx(layout.PageHeight)
+
+	if maxPages := max(maxPNGPixels/(pageWidth*pageHeight), 1); pages > maxPages {
+		return nil, fmt.Errorf("at most %d labels of this size can be printed as PNG; use PDF for more", maxPages*perPage)
+	}
+
+	img := image.NewGray(image.Rect(0, 0, pageWidth, pageHeight*pages))
+	draw.Draw(img, img.Bounds(), image.White, image.Point{}, draw.Src)
+
+	face := basicfont.Face7x13
+	lineHeight := face.Metrics().Height.Ceil() + 2
+
+	for i, item := range labels {
+		page := i / perPage
+		xMM, yMM := labelPosition(layout, i%perPage)
+		x, y := toPx(xMM), toPx(yMM)+page*pageHeight
+		width, height := toPx(layout.LabelWidth), toPx(layout.LabelHeight)
+		padding := toPx(math.Min(layout.LabelWidth, layout.LabelHeight) * 0.06)
+
+		drawCenteredText(img, face, item.Name, x, width, y+padding+lineHeight)
+		drawCenteredText(img, face, fmt.Sprintf("MRP %.2f", item.Price), x, width, y+padding+2*lineHeight)
+
+		bc, err := encodeItemBarcode(item)
+		if err != nil {
+			return nil, fmt.Errorf("failed to encode barcode for item %s: %v", item.Name, err)
+		}
+		barcodeTop := y + padding + 2*lineHeight + 4
+		barcodeHeight := height - 2*padding - 3*lineHeight - 4
+		barcodeWidth := width - 2*padding
+		if barcodeHeight <= 0 || barcodeWidth < bc.Bounds().Dx() {
+			return nil, errors.New("label size is too small")
+		}
+
+		scaled, err := barcode.Scale(bc, barcodeWidth, barcodeHeight)
+		if err != nil {
+			return nil, err
+		}
+		draw.Draw(img, image.Rect(x+padding, barcodeTop, x+padding+barcodeWidth, barcodeTop+barcodeHeight), scaled, image.Point{}, draw.Src)
+
+		drawCenteredText(img, face, item.Barcode, x, width, barcodeTop+barcodeHeight+lineHeight)
+	}
+
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// drawCenteredText draws a single line of text centred horizontally within [x, x+width)
+func drawCenteredText(img draw.Image, face font.Face, text string, x, width, baseline int) {
+	drawer := &font.Drawer{Dst: img, Src: image.NewUniform(color.Black), Face: face}
+	for len(text) > 0 && drawer.MeasureString(text).Ceil() > width {
+		text = text[:len(text)-1]
+	}
+	offset := (width - drawer.MeasureString(text).Ceil()) / 2
+	drawer.Dot = fixed.P(x+offset, baseline)
+	drawer.DrawString(text)
+}
@@ -0,0 +1,676 @@
+package services
+
+import (
+	"billboard/backend/models"
+	"errors"
+	"fmt"
+	"log"
+	"strconv"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgconn"
+	"gorm.io/gorm"
+)
+
+// LateFeeService charges late fees and interest on overdue bills following each shop's late fee
+// policies. Charges are posted as debit notes that raise the bill's balance without touching
+// its invoiced total, so tax returns and e-invoices of the bill stay as they were.
+type LateFeeService struct {
+	db *gorm.DB
+}
+
+// NewLateFeeService creates a new LateFeeService instance
+func NewLateFeeService(db *gorm.DB) *LateFeeService {
+	return &LateFeeService{db: db}
+}
+
+// lateFeePaidLookback is how long after being paid a bill is still looked at, so interest for
+// the days it was paid late is charged by the next monthly run
+const lateFeePaidLookback = 92 * 24 * time.Hour
+
+// GetPolicies lists a shop's late fee policies, the shop's own policy first
+func (s *LateFeeService) GetPolicies(shopID, userID uuid.UUID) ([]models.LateFeePolicyResponse, error) {
+	// Check if user has access to the shop
+	var shopUser models.ShopUser
+	if err := s.db.Where("shop_id = ? AND user_id = ? AND is_active = ?", shopID, userID, true).First(&shopUser).Error; err != nil {
+		return nil, errors.New("access denied to shop")
+	}
+
+	var policies []models.LateFeePolicy
+	if err := s.db.Preload("Customer").Where("shop_id = ?", shopID).
+		Order("customer_id IS NOT NULL, created_at").Find(&policies).Error; err != nil {
+		return nil, err
+	}
+
+	responses := make([]models.LateFeePolicyResponse, len(policies))
+	for i, policy := range policies {
+		responses[i] = lateFeePolicyToResponse(policy)
+	}
+	return responses, nil
+}
+
+// CreatePolicy adds a late fee policy for the shop or one of its customers
+func (s *LateFeeService) CreatePolicy(shopID, userID uuid.UUID, req models.LateFeePolicyRequest) (*models.LateFeePolicyResponse, error) {
+	// Check if user has access to the shop
+	var shopUser models.ShopUser
+	if err := s.db.Where("shop_id = ? AND user_id = ? AND is_active = ?", shopID, userID, true).First(&shopUser).Error; err != nil {
+		return nil, errors.New("access denied to shop")
+	}
+
+	policy := models.LateFeePolicy{ShopID: shopID, CreatedBy: userID.String()}
+	if err := s.applyPolicyRequest(&policy, req); err != nil {
+		return nil, err
+	}
+	if err := s.db.Create(&policy).Error; err != nil {
+		return nil, err
+	}
+
+	return s.getPolicy(policy.ID)
+}
+
+// UpdatePolicy changes a late fee policy. Charges already posted keep the terms they were
+// worked out with.
+func (s *LateFeeService) UpdatePolicy(policyID, shopID, userID uuid.UUID, req models.LateFeePolicyRequest) (*models.LateFeePolicyResponse, error) {
+	// Check if user has access to the shop
+	var shopUser models.ShopUser
+	if err := s.db.Where("shop_id = ? AND user_id = ? AND is_active = ?", shopID, userID, true).First(&shopUser).Error; err != nil {
+		return nil, errors.New("access denied to shop")
+	}
+
+	var policy models.LateFeePolicy
+	if err := s.db.Where("id = ? AND shop_id = ?", policyID, shopID).First(&policy).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("late fee policy not found")
+		}
+		return nil, err
+	}
+
+	if err := s.applyPolicyRequest(&policy, req); err != nil {
+		return nil, err
+	}
+	if err := s.db.Save(&policy).Error; err != nil {
+		return nil, err
+	}
+
+	return s.getPolicy(policy.ID)
+}
+
+// DeletePolicy removes a late fee policy that has not charged anything yet. Policies with
+// charges are kept for their history and can be deactivated instead.
+func (s *LateFeeService) DeletePolicy(policyID, shopID, userID uuid.UUID) error {
+	// Check if user has access to the shop
+	var shopUser models.ShopUser
+	if err := s.db.Where("shop_id = ? AND user_id = ? AND is_active = ?", shopID, userID, true).First(&shopUser).Error; err != nil {
+		return errors.New("access denied to shop")
+	}
+
+	var charges int64
+	if err := s.db.Model(&models.LateFeeCharge{}).Where("policy_id = ? AND shop_id = ?", policyID, shopID).Count(&charges).Error; err != nil {
+		return err
+	}
+	if charges > 0 {
+		return errors.New("late fee policy has charges; deactivate it instead")
+	}
+
+	result := s.db.Where("id = ? AND shop_id = ?", policyID, shopID).Delete(&models.LateFeePolicy{})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return errors.New("late fee policy not found")
+	}
+	return nil
+}
+
+// GetCharges lists a shop's late fee charges, optionally by status, most recent first
+func (s *LateFeeService) GetCharges(shopID, userID uuid.UUID, status string) ([]models.LateFeeChargeResponse, error) {
+	// Check if user has access to the shop
+	var shopUser models.ShopUser
+	if err := s.db.Where("shop_id = ? AND user_id = ? AND is_active = ?", shopID, userID, true).First(&shopUser).Error; err != nil {
+		return nil, errors.New("access denied to shop")
+	}
+
+	query := s.db.Preload("Bill", func(db *gorm.DB) *gorm.DB { return db.Select("id", "bill_number") }).
+		Where("shop_id = ?", shopID)
+	if status != "" {
+		query = query.Where("status = ?", status)
+	}
+	var charges []models.LateFeeCharge
+	if err := query.Order("charge_date DESC, created_at DESC").Limit(500).Find(&charges).Error; err != nil {
+		return nil, err
+	}
+
+	responses := make([]models.LateFeeChargeResponse, len(charges))
+	for i, charge := range charges {
+		responses[i] = lateFeeChargeToResponse(charge)
+	}
+	return responses, nil
+}
+
+// GetBillCharges lists the late fees charged on a bill in the order they were charged
+func (s *LateFeeService) GetBillCharges(billID, shopID, userID uuid.UUID) ([]models.LateFeeChargeResponse, error) {
+	// Check if user has access to the shop
+	var shopUser models.ShopUser
+	if err := s.db.Where("shop_id = ? AND user_id = ? AND is_active = ?", shopID, userID, true).First(&shopUser).Error; err != nil {
+		return nil, errors.New("access denied to shop")
+	}
+
+	var charges []models.LateFeeCharge
+	if err := s.db.Preload("Bill", func(db *gorm.DB) *gorm.DB { return db.Select("id", "bill_number") }).
+		Where("bill_id = ? AND shop_id = ?", billID, shopID).
+		Order("period_start, created_at").Find(&charges).Error; err != nil {
+		return nil, err
+	}
+
+	responses := make([]models.LateFeeChargeResponse, len(charges))
+	for i, charge := range charges {
+		responses[i] = lateFeeChargeToResponse(charge)
+	}
+	return responses, nil
+}
+
+// WaiveCharge cancels a late fee that has not been paid yet, taking it off the bill's balance.
+// The charge is kept, marked as waived with the reason.
+func (s *LateFeeService) WaiveCharge(chargeID, shopID, userID uuid.UUID, reason string) (*models.LateFeeChargeResponse, error) {
+	// Check if user has access to the shop
+	var shopUser models.ShopUser
+	if err := s.db.Where("shop_id = ? AND user_id = ? AND is_active = ?", shopID, userID, true).First(&shopUser).Error; err != nil {
+		return nil, errors.New("access denied to shop")
+	}
+
+	var charge models.LateFeeCharge
+	if err := s.db.Preload("Bill").Where("id = ? AND shop_id = ?", chargeID, shopID).First(&charge).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("late fee not found")
+		}
+		return nil, err
+	}
+	if charge.Status == "waived" {
+		return nil, errors.New("late fee is already waived")
+	}
+	if roundAmount(charge.Bill.Balance) < roundAmount(charge.Amount) {
+		return nil, errors.New("late fee has already been paid")
+	}
+
+	status := charge.Bill.Status
+	if roundAmount(charge.Bill.Balance-charge.Amount) <= 0 {
+		status = "paid"
+	}
+
+	now := time.Now()
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		result := tx.Model(&models.LateFeeCharge{}).Where("id = ? AND status = ?", charge.ID, "posted").
+			Updates(map[string]interface{}{
+				"status":       "waived",
+				"waive_reason": reason,
+				"waived_by":    userID.String(),
+				"waived_at":    now,
+				"updated_at":   now,
+			})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return errors.New("late fee is already waived")
+		}
+
+		return tx.Model(&models.Bill{}).Where("id = ?", charge.BillID).Updates(map[string]interface{}{
+			"late_fee_amount": gorm.Expr("late_fee_amount - ?", charge.Amount),
+			"pending_amount":  gorm.Expr("pending_amount - ?", charge.Amount),
+			"balance":         gorm.Expr("balance - ?", charge.Amount),
+			"status":          status,
+			"updated_at":      now,
+		}).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	charge.Status = "waived"
+	charge.WaiveReason = reason
+	charge.WaivedBy = userID.String()
+	charge.WaivedAt = &now
+	response := lateFeeChargeToResponse(charge)
+	return &response, nil
+}
+
+// RunShopLateFees charges a shop's late fees straight away. Interest is charged up to the end
+// of the last month, or up to the given date.
+func (s *LateFeeService) RunShopLateFees(shopID, userID uuid.UUID, req models.LateFeeRunRequest) (*models.LateFeeRunResult, error) {
+	// Check if user has access to the shop
+	var shopUser models.ShopUser
+	if err := s.db.Where("shop_id = ? AND user_id = ? AND is_active = ?", shopID, userID, true).First(&shopUser).Error; err != nil {
+		return nil, errors.New("access denied to shop")
+	}
+
+	through := lateFeeDefaultThrough()
+	if req.Through != "" {
+		parsed, err := time.Parse("2006-01-02", req.Through)
+		if err != nil {
+			return nil, errors.New("invalid through date format")
+		}
+		if !parsed.Before(recurringBillToday()) {
+			return nil, errors.New("late fees can only be charged up to yesterday")
+		}
+		through = parsed
+	}
+
+	result, err := s.runShop(shopID, through, userID.String())
+	if err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+// ChargeDueLateFees charges the late fees of every shop up to the end of the last month and
+// returns how many charges were posted
+func (s *LateFeeService) ChargeDueLateFees() (int, error) {
+	var shopIDs []uuid.UUID
+	if err := s.db.Model(&models.LateFeePolicy{}).Where("is_active = ?", true).Distinct("shop_id").Pluck("shop_id", &shopIDs).Error; err != nil {
+		return 0, err
+	}
+
+	through := lateFeeDefaultThrough()
+	charged := 0
+	for _, shopID := range shopIDs {
+		result, err := s.runShop(shopID, through, "system")
+		if err != nil {
+			log.Printf("Failed to charge late fees for shop %s: %v", shopID, err)
+			continue
+		}
+		charged += result.Charged
+	}
+	return charged, nil
+}
+
+// StartScheduler periodically charges due late fees in the background
+func (s *LateFeeService) StartScheduler(interval time.Duration) {
+	if s.db == nil {
+		return
+	}
+
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for range ticker.C {
+			if count, err := s.ChargeDueLateFees(); err != nil {
+				log.Printf("Failed to charge late fees: %v", err)
+			} else if count > 0 {
+				log.Printf("Posted %d late fee charges", count)
+			}
+		}
+	}()
+}
+
+// runShop works out and posts the late fees of a shop's overdue bills up to a date. Open bills
+// are looked at, and so are bills paid recently, since they may owe interest for the days
+// before they were paid.
+func (s *LateFeeService) runShop(shopID uuid.UUID, through time.Time, createdBy string) (models.LateFeeRunResult, error) {
+	result := models.LateFeeRunResult{Through: through}
+
+	var policies []models.LateFeePolicy
+	if err := s.db.Where("shop_id = ? AND is_active = ? AND effective_from <= ?", shopID, true, through).Find(&policies).Error; err != nil {
+		return result, err
+	}
+	if len(policies) == 0 {
+		return result, nil
+	}
+	var shopPolicy *models.LateFeePolicy
+	customerPolicies := map[uuid.UUID]*models.LateFeePolicy{}
+	for i := range policies {
+		if policies[i].CustomerID == nil {
+			shopPolicy = &policies[i]
+		} else {
+			customerPolicies[*policies[i].CustomerID] = &policies[i]
+		}
+	}
+
+	var bills []models.Bill
+	if err := s.db.Preload("Payments").
+		Where("shop_id = ? AND deleted_at IS NULL AND due_date IS NOT NULL AND due_date < ?", shopID, through).
+		Where("((status NOT IN ? AND balance > 0) OR (status = ? AND updated_at >= ?))",
+			[]string{"paid", "cancelled"}, "paid", time.Now().Add(-lateFeePaidLookback)).
+		Order("due_date, bill_number").
+		Find(&bills).Error; err != nil {
+		return result, err
+	}
+
+	for _, bill := range bills {
+		policy := shopPolicy
+		if bill.CustomerID != nil {
+			if customerPolicy, ok := customerPolicies[*bill.CustomerID]; ok {
+				policy = customerPolicy
+			}
+		}
+		if policy == nil {
+			continue
+		}
+		result.BillsChecked++
+
+		var charges []models.LateFeeCharge
+		if err := s.db.Where("bill_id = ?", bill.ID).Order("period_start").Find(&charges).Error; err != nil {
+			return result, err
+		}
+		charge := lateFeeChargeFor(*policy, bill, charges, through)
+		if charge == nil {
+			continue
+		}
+		charge.CreatedBy = createdBy
+		if err := s.post(charge, bill, len(charges)); err != nil {
+			// Another run posted this period first, or the bill was cancelled meanwhile
+			if isUniqueViolation(err) || errors.Is(err, errLateFeeBillCancelled) {
+				continue
+			}
+			return result, err
+		}
+		result.Charged++
+		result.TotalAmount += charge.Amount
+	}
+	result.TotalAmount = roundAmount(result.TotalAmount)
+
+	return result, nil
+}
+
+// errLateFeeBillCancelled is returned when a bill is cancelled while a late fee is posted on it
+var errLateFeeBillCancelled = errors.New("bill has been cancelled")
+
+// post records a charge as the bill's next debit note and adds it to the bill's balance
+func (s *LateFeeService) post(charge *models.LateFeeCharge, bill models.Bill, previous int) error {
+	charge.NoteNumber = fmt.Sprintf("LF-%s-%d", bill.BillNumber, previous+1)
+
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(charge).Error; err != nil {
+			return err
+		}
+		// The bill may have been paid or cancelled since it was read. Only a bill still unpaid
+		// becomes overdue, and a cancelled one is not charged at all.
+		result := tx.Model(&models.Bill{}).Where("id = ? AND status <> ?", bill.ID, "cancelled").Updates(map[string]interface{}{
+			"late_fee_amount": gorm.Expr("late_fee_amount + ?", charge.Amount),
+			"pending_amount":  gorm.Expr("pending_amount + ?", charge.Amount),
+			"balance":         gorm.Expr("balance + ?", charge.Amount),
+			"status":          gorm.Expr("CASE WHEN balance > 0 AND status NOT IN ? THEN 'overdue' ELSE status END", []string{"paid", "cancelled"}),
+			"updated_at":      time.Now(),
+		})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return errLateFeeBillCancelled
+		}
+		return nil
+	})
+}
+
+// isUniqueViolation reports whether a database error is a unique constraint violation
+func isUniqueViolation(err error) bool {
+	var pgErr *pgconn.PgError
+	return errors.As(err, &pgErr) && pgErr.Code == "23505"
+}
+
+// lateFeeChargeFor works out the next charge a policy makes on a bill up to a date, or nil when
+// nothing is due. Nothing is charged before the grace period is over; interest then runs from
+// the day after the due date, or from where the last interest charge ended, on the part of the
+// bill total still unpaid each day. Late fees themselves earn no interest. Waived charges
+// count as charged, but not towards the cap.
+func lateFeeChargeFor(policy models.LateFeePolicy, bill models.Bill, charges []models.LateFeeCharge, through time.Time) *models.LateFeeCharge {
+	if bill.DueDate == nil {
+		return nil
+	}
+	dueDate := lateFeeDate(*bill.DueDate)
+	graceEnd := dueDate.AddDate(0, 0, policy.GraceDays)
+	if !through.After(graceEnd) {
+		return nil
+	}
+
+	// unpaid returns the part of the bill total still unpaid at the end of a day
+	unpaid := func(day time.Time) float64 {
+		amount := bill.TotalAmount
+		for _, payment := range bill.Payments {
+			if !lateFeeDate(payment.PaymentDate).After(day) {
+				amount -= payment.Amount
+			}
+		}
+		return roundAmount(amount)
+	}
+
+	charge := models.LateFeeCharge{
+		ShopID:     bill.ShopID,
+		BillID:     bill.ID,
+		CustomerID: bill.CustomerID,
+		PolicyID:   policy.ID,
+		FeeType:    policy.FeeType,
+		ChargeDate: recurringBillToday(),
+		Status:     "posted",
+	}
+
+	switch policy.FeeType {
+	case "flat":
+		for _, previous := range charges {
+			if previous.FeeType == "flat" {
+				return nil
+			}
+		}
+		start := graceEnd.AddDate(0, 0, 1)
+		if start.Before(policy.EffectiveFrom) {
+			return nil
+		}
+		base := unpaid(graceEnd)
+		if base <= 0 {
+			return nil
+		}
+		charge.PeriodStart = start
+		charge.PeriodEnd = start
+		charge.BaseAmount = base
+		charge.Amount = policy.FlatAmount
+	case "interest":
+		start := dueDate.AddDate(0, 0, 1)
+		for _, previous := range charges {
+			if previous.FeeType == "interest" && !lateFeeDate(previous.PeriodEnd).Before(start) {
+				start = lateFeeDate(previous.PeriodEnd).AddDate(0, 0, 1)
+			}
+		}
+		if start.Before(policy.EffectiveFrom) {
+			start = lateFeeDate(policy.EffectiveFrom)
+		}
+		if start.After(through) {
+			return nil
+		}
+
+		var days int
+		var total float64
+		for day := start; !day.After(through); day = day.AddDate(0, 0, 1) {
+			days++
+			if amount := unpaid(day); amount > 0 {
+				total += amount
+			}
+		}
+		charge.PeriodStart = start
+		charge.PeriodEnd = through
+		charge.Days = days
+		charge.BaseAmount = roundAmount(total / float64(days))
+		charge.Rate = policy.AnnualRate
+		charge.Amount = total * policy.AnnualRate / 100 / 365
+	default:
+		return nil
+	}
+
+	// Keep the bill's fees within the policy's cap
+	limit := -1.0
+	if policy.MaxAmount > 0 {
+		limit = policy.MaxAmount
+	}
+	if policy.MaxPercent > 0 {
+		percentLimit := bill.TotalAmount * policy.MaxPercent / 100
+		if limit < 0 || percentLimit < limit {
+			limit = percentLimit
+		}
+	}
+	if limit >= 0 {
+		remaining := limit
+		for _, previous := range charges {
+			if previous.Status == "posted" {
+				remaining -= previous.Amount
+			}
+		}
+		if charge.Amount > remaining {
+			charge.Amount = remaining
+			charge.Capped = true
+		}
+	}
+
+	charge.Amount = roundAmount(charge.Amount)
+	if charge.Amount <= 0 {
+		return nil
+	}
+	return &charge
+}
+
+// lateFeeChargeDescription describes a charge for statements and journal entries
+func lateFeeChargeDescription(charge models.LateFeeCharge) string {
+	if charge.FeeType == "interest" {
+		return fmt.Sprintf("Interest at %s%% p.a. on bill %s, average overdue %s, %s to %s (%d days)",
+			strconv.FormatFloat(charge.Rate, 'f', -1, 64), charge.Bill.BillNumber, formatAmount(charge.BaseAmount),
+			charge.PeriodStart.Format("02 Jan 2006"), charge.PeriodEnd.Format("02 Jan 2006"), charge.Days)
+	}
+	return fmt.Sprintf("Late fee on bill %s, unpaid after %s", charge.Bill.BillNumber,
+		charge.PeriodStart.AddDate(0, 0, -1).Format("02 Jan 2006"))
+}
+
+// lateFeeDefaultThrough returns the last day of the previous month, up to which interest is
+// charged on each monthly run
+func lateFeeDefaultThrough() time.Time {
+	today := recurringBillToday()
+	return time.Date(today.Year(), today.Month(), 1, 0, 0, 0, 0, time.UTC).AddDate(0, 0, -1)
+}
+
+// lateFeeDate drops the time of day from a date
+func lateFeeDate(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+}
+
+// getPolicy loads a policy with its customer for the response
+func (s *LateFeeService) getPolicy(policyID uuid.UUID) (*models.LateFeePolicyResponse, error) {
+	var policy models.LateFeePolicy
+	if err := s.db.Preload("Customer").Where("id = ?", policyID).First(&policy).Error; err != nil {
+		return nil, err
+	}
+	response := lateFeePolicyToResponse(policy)
+	return &response, nil
+}
+
+// applyPolicyRequest validates a policy request and copies it onto the policy. A shop has at
+// most one policy of its own and one per customer.
+func (s *LateFeeService) applyPolicyRequest(policy *models.LateFeePolicy, req models.LateFeePolicyRequest) error {
+	switch req.FeeType {
+	case "flat":
+		if req.FlatAmount <= 0 {
+			return errors.New("flat late fees need a flat amount")
+		}
+	case "interest":
+		if req.AnnualRate <= 0 {
+			return errors.New("interest late fees need an annual rate")
+		}
+	}
+
+	effectiveFrom := recurringBillToday()
+	if req.EffectiveFrom != "" {
+		parsed, err := time.Parse("2006-01-02", req.EffectiveFrom)
+		if err != nil {
+			return errors.New("invalid effective from date format")
+		}
+		effectiveFrom = parsed
+	} else if policy.ID != uuid.Nil {
+		effectiveFrom = policy.EffectiveFrom
+	}
+
+	existing := s.db.Model(&models.LateFeePolicy{}).Where("shop_id = ? AND id <> ?", policy.ShopID, policy.ID)
+	if req.CustomerID != nil {
+		var customer models.Customer
+		if err := s.db.Select("id").Where("id = ? AND shop_id = ?", *req.CustomerID, policy.ShopID).First(&customer).Error; err != nil {
+			return errors.New("customer not found")
+		}
+		existing = existing.Where("customer_id = ?", *req.CustomerID)
+	} else {
+		existing = existing.Where("customer_id IS NULL")
+	}
+	var count int64
+	if err := existing.Count(&count).Error; err != nil {
+		return err
+	}
+	if count > 0 {
+		if req.CustomerID != nil {
+			return errors.New("customer already has a late fee policy")
+		}
+		return errors.New("shop already has a late fee policy")
+	}
+
+	policy.CustomerID = req.CustomerID
+	policy.Name = req.Name
+	policy.FeeType = req.FeeType
+	policy.FlatAmount = 0
+	policy.AnnualRate = 0
+	if req.FeeType == "flat" {
+		policy.FlatAmount = req.FlatAmount
+	} else {
+		policy.AnnualRate = req.AnnualRate
+	}
+	policy.GraceDays = req.GraceDays
+	policy.MaxAmount = req.MaxAmount
+	policy.MaxPercent = req.MaxPercent
+	policy.EffectiveFrom = effectiveFrom
+	policy.IsActive = req.IsActive == nil || *req.IsActive
+	return nil
+}
+
+// lateFeePolicyToResponse converts a late fee policy to its response
+func lateFeePolicyToResponse(policy models.LateFeePolicy) models.LateFeePolicyResponse {
+	response := models.LateFeePolicyResponse{
+		ID:            policy.ID,
+		CustomerID:    policy.CustomerID,
+		Name:          policy.Name,
+		FeeType:       policy.FeeType,
+		FlatAmount:    policy.FlatAmount,
+		AnnualRate:    policy.AnnualRate,
+		GraceDays:     policy.GraceDays,
+		MaxAmount:     policy.MaxAmount,
+		MaxPercent:    policy.MaxPercent,
+		EffectiveFrom: policy.EffectiveFrom,
+		IsActive:      policy.IsActive,
+		CreatedBy:     policy.CreatedBy,
+		CreatedAt:     policy.CreatedAt,
+		UpdatedAt:     policy.UpdatedAt,
+	}
+	if policy.Customer != nil {
+		response.CustomerName = policy.Customer.Name
+	}
+	return response
+}
+
+// lateFeeChargeToResponse converts a late fee charge to its response
+func lateFeeChargeToResponse(charge models.LateFeeCharge) models.LateFeeChargeResponse {
+	return models.LateFeeChargeResponse{
+		ID:          charge.ID,
+		BillID:      charge.BillID,
+		BillNumber:  charge.Bill.BillNumber,
+		CustomerID:  charge.CustomerID,
+		PolicyID:    charge.PolicyID,
+		NoteNumber:  charge.NoteNumber,
+		FeeType:     charge.FeeType,
+		ChargeDate:  charge.ChargeDate,
+		PeriodStart: charge.PeriodStart,
+		PeriodEnd:   charge.PeriodEnd,
+		Days:        charge.Days,
+		BaseAmount:  charge.BaseAmount,
+		Rate:        charge.Rate,
+		Amount:      charge.Amount,
+		Capped:      charge.Capped,
+		Status:      charge.Status,
+		WaiveReason: charge.WaiveReason,
+		WaivedBy:    charge.WaivedBy,
+		WaivedAt:    charge.WaivedAt,
+		CreatedBy:   charge.CreatedBy,
+		CreatedAt:   charge.CreatedAt,
+	}
+}
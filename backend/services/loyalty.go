@@ -0,0 +1,642 @@
+package services
+
+import (
+	"billboard/backend/models"
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// LoyaltyService manages the loyalty program and customer points
+type LoyaltyService struct {
+	db *gorm.DB
+}
+
+// NewLoyaltyService creates a new LoyaltyService instance
+func NewLoyaltyService(db *gorm.DB) *LoyaltyService {
+	return &LoyaltyService{db: db}
+}
+
+// GetProgram retrieves the loyalty program of a shop
+func (s *LoyaltyService) GetProgram(shopID, userID uuid.UUID) (*models.LoyaltyProgramResponse, error) {
+	// Check if user has access to the shop
+	var shopUser models.ShopUser
+	if err := s.db.Where("shop_id = ? AND user_id = ? AND is_active = ?", shopID, userID, true).First(&shopUser).Error; err != nil {
+		return nil, errors.New("access denied to shop")
+	}
+
+	program, err := loadLoyaltyProgram(s.db, shopID)
+	if err != nil {
+		return nil, err
+	}
+	if program == nil {
+		return nil, errors.New("loyalty program not configured")
+	}
+
+	response := s.programToResponse(*program)
+	return &response, nil
+}
+
+// UpdateProgram creates or updates the loyalty program of a shop and replaces its tiers.
+// Points already earned keep their expiry date.
+func (s *LoyaltyService) UpdateProgram(shopID, userID uuid.UUID, req models.LoyaltyProgramRequest) (*models.LoyaltyProgramResponse, error) {
+	// Check if user has access to the shop
+	var shopUser models.ShopUser
+	if err := s.db.Where("shop_id = ? AND user_id = ? AND is_active = ?", shopID, userID, true).First(&shopUser).Error; err != nil {
+		return nil, errors.New("access denied to shop")
+	}
+
+	tierNames := map[string]bool{}
+	tierPoints := map[int]bool{}
+	for _, tier := range req.Tiers {
+		if tierNames[tier.Name] || tierPoints[tier.MinPoints] {
+			return nil, errors.New("tier names and minimum points must be unique")
+		}
+		tierNames[tier.Name] = true
+		tierPoints[tier.MinPoints] = true
+	}
+
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		var program models.LoyaltyProgram
+		if err := tx.Where("shop_id = ?", shopID).First(&program).Error; err != nil {
+			if !errors.Is(err, gorm.ErrRecordNotFound) {
+				return err
+			}
+			program = models.LoyaltyProgram{ShopID: shopID, IsActive: true}
+		}
+
+		program.PointsPerUnit = req.PointsPerUnit
+		program.PointValue = req.PointValue
+		program.MinRedeemPoints = req.MinRedeemPoints
+		program.ExpiryDays = req.ExpiryDays
+		if req.IsActive != nil {
+			program.IsActive = *req.IsActive
+		}
+
+		if err := tx.Omit("Tiers").Save(&program).Error; err != nil {
+			return err
+		}
+
+		if err := tx.Where("program_id = ?", program.ID).Delete(&models.LoyaltyTier{}).Error; err != nil {
+			return err
+		}
+		for _, tierReq := range req.Tiers {
+			multiplier := tierReq.Multiplier
+			if multiplier == 0 {
+				multiplier = 1
+			}
+			tier := models.LoyaltyTier{
+				ProgramID:  program.ID,
+				Name:       tierReq.Name,
+				MinPoints:  tierReq.MinPoints,
+				Multiplier: multiplier,
+			}
+			if err := tx.Create(&tier).Error; err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	program, err := loadLoyaltyProgram(s.db, shopID)
+	if err != nil {
+		return nil, err
+	}
+
+	response := s.programToResponse(*program)
+	return &response, nil
+}
+
+// GetCustomerLoyalty retrieves a customer's points balance, tier and points ledger
+func (s *LoyaltyService) GetCustomerLoyalty(customerID, shopID, userID uuid.UUID) (*models.CustomerLoyaltyResponse, error) {
+	// Check if user has access to the shop
+	var shopUser models.ShopUser
+	if err := s.db.Where("shop_id = ? AND user_id = ? AND is_active = ?", shopID, userID, true).First(&shopUser).Error; err != nil {
+		return nil, errors.New("access denied to shop")
+	}
+
+	if err := expireLoyaltyPoints(s.db, shopID, customerID, time.Now()); err != nil {
+		return nil, err
+	}
+
+	return s.getCustomerLoyalty(customerID, shopID)
+}
+
+// AdjustPoints adds or removes points from a customer by hand, recording the reason in the ledger
+func (s *LoyaltyService) AdjustPoints(customerID, shopID, userID uuid.UUID, req models.LoyaltyAdjustmentRequest) (*models.CustomerLoyaltyResponse, error) {
+	// Check if user has access to the shop
+	var shopUser models.ShopUser
+	if err := s.db.Where("shop_id = ? AND user_id = ? AND is_active = ?", shopID, userID, true).First(&shopUser).Error; err != nil {
+		return nil, errors.New("access denied to shop")
+	}
+
+	program, err := loadLoyaltyProgram(s.db, shopID)
+	if err != nil {
+		return nil, err
+	}
+	if program == nil {
+		return nil, errors.New("loyalty program not configured")
+	}
+
+	err = s.db.Transaction(func(tx *gorm.DB) error {
+		customer, err := lockLoyaltyCustomer(tx, shopID, customerID, time.Now())
+		if err != nil {
+			return err
+		}
+
+		entry := models.LoyaltyTransaction{
+			ShopID:      shopID,
+			CustomerID:  customerID,
+			Type:        "adjust",
+			Points:      req.Points,
+			Description: req.Description,
+			CreatedBy:   userID.String(),
+		}
+		if req.Points > 0 {
+			entry.Remaining = req.Points
+			entry.ExpiresAt = loyaltyExpiryDate(program, time.Now())
+		} else {
+			if customer.LoyaltyPoints < -req.Points {
+				return fmt.Errorf("customer only has %d points", customer.LoyaltyPoints)
+			}
+			if _, err := consumeLoyaltyPoints(tx, customerID, -req.Points, nil); err != nil {
+				return err
+			}
+		}
+
+		if err := tx.Create(&entry).Error; err != nil {
+			return err
+		}
+		return tx.Model(&models.Customer{}).Where("id = ?", customerID).
+			Update("loyalty_points", gorm.Expr("loyalty_points + ?", req.Points)).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return s.getCustomerLoyalty(customerID, shopID)
+}
+
+// getCustomerLoyalty builds the loyalty summary of a customer
+func (s *LoyaltyService) getCustomerLoyalty(customerID, shopID uuid.UUID) (*models.CustomerLoyaltyResponse, error) {
+	var customer models.Customer
+	if err := s.db.Where("id = ? AND shop_id = ?", customerID, shopID).First(&customer).Error; err != nil {
+		return nil, errors.New("customer not found")
+	}
+
+	program, err := loadLoyaltyProgram(s.db, shopID)
+	if err != nil {
+		return nil, err
+	}
+
+	response := &models.CustomerLoyaltyResponse{
+		CustomerID:     customer.ID,
+		Points:         customer.LoyaltyPoints,
+		LifetimePoints: customer.LifetimePoints,
+		Transactions:   []models.LoyaltyTransactionResponse{},
+	}
+
+	if program != nil {
+		response.PointsValue = roundAmount(float64(customer.LoyaltyPoints) * program.PointValue)
+		if tier := loyaltyTier(program, customer.LifetimePoints); tier != nil {
+			response.Tier = tier.Name
+		}
+		for _, tier := range program.Tiers {
+			if tier.MinPoints > customer.LifetimePoints {
+				response.NextTier = tier.Name
+				response.PointsToNext = tier.MinPoints - customer.LifetimePoints
+				break
+			}
+		}
+	}
+
+	// Points expiring in the next 30 days
+	var lots []models.LoyaltyTransaction
+	if err := s.db.Where("customer_id = ? AND remaining > 0 AND expires_at IS NOT NULL", customerID).
+		Order("expires_at ASC").Find(&lots).Error; err != nil {
+		return nil, err
+	}
+	soon := time.Now().AddDate(0, 0, 30)
+	for i, lot := range lots {
+		if i == 0 {
+			response.NextExpiryDate = lot.ExpiresAt
+		}
+		if lot.ExpiresAt.Before(soon) {
+			response.ExpiringPoints += lot.Remaining
+		}
+	}
+
+	var entries []models.LoyaltyTransaction
+	if err := s.db.Where("customer_id = ?", customerID).Order("created_at DESC").Find(&entries).Error; err != nil {
+		return nil, err
+	}
+	for _, entry := range entries {
+		response.Transactions = append(response.Transactions, models.LoyaltyTransactionResponse{
+			ID:          entry.ID,
+			BillID:      entry.BillID,
+			PaymentID:   entry.PaymentID,
+			Type:        entry.Type,
+			Points:      entry.Points,
+			Remaining:   entry.Remaining,
+			ExpiresAt:   entry.ExpiresAt,
+			Description: entry.Description,
+			CreatedBy:   entry.CreatedBy,
+			CreatedAt:   entry.CreatedAt,
+		})
+	}
+
+	return response, nil
+}
+
+// programToResponse converts a LoyaltyProgram model to LoyaltyProgramResponse
+func (s *LoyaltyService) programToResponse(program models.LoyaltyProgram) models.LoyaltyProgramResponse {
+	response := models.LoyaltyProgramResponse{
+		ID:              program.ID,
+		ShopID:          program.ShopID,
+		IsActive:        program.IsActive,
+		PointsPerUnit:   program.PointsPerUnit,
+		PointValue:      program.PointValue,
+		MinRedeemPoints: program.MinRedeemPoints,
+		ExpiryDays:      program.ExpiryDays,
+		Tiers:           []models.LoyaltyTierResponse{},
+		CreatedAt:       program.CreatedAt,
+		UpdatedAt:       program.UpdatedAt,
+	}
+	for _, tier := range program.Tiers {
+		response.Tiers = append(response.Tiers, models.LoyaltyTierResponse{
+			ID:         tier.ID,
+			Name:       tier.Name,
+			MinPoints:  tier.MinPoints,
+			Multiplier: tier.Multiplier,
+		})
+	}
+	return response
+}
+
+// loadLoyaltyProgram loads the loyalty program of a shop with its tiers in ascending order,
+// or nil when the shop has none
+func loadLoyaltyProgram(db *gorm.DB, shopID uuid.UUID) (*models.LoyaltyProgram, error) {
+	var program models.LoyaltyProgram
+	err := db.Preload("Tiers", func(db *gorm.DB) *gorm.DB {
+		return db.Order("min_points ASC")
+	}).Where("shop_id = ?", shopID).First(&program).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &program, nil
+}
+
+// loyaltyTier returns the highest tier reached with the given lifetime points
+func loyaltyTier(program *models.LoyaltyProgram, lifetimePoints int) *models.LoyaltyTier {
+	var reached *models.LoyaltyTier
+	for i := range program.Tiers {
+		if program.Tiers[i].MinPoints <= lifetimePoints {
+			reached = &program.Tiers[i]
+		}
+	}
+	return reached
+}
+
+// loyaltyExpiryDate returns when points earned at the given time expire, or nil if they never do
+func loyaltyExpiryDate(program *models.LoyaltyProgram, from time.Time) *time.Time {
+	if program.ExpiryDays == 0 {
+		return nil
+	}
+	expiresAt := from.AddDate(0, 0, program.ExpiryDays)
+	return &expiresAt
+}
+
+// applyPaymentLoyalty earns points for a payment on a customer's bill, or redeems points when
+// the payment is made with them. Bills without a customer earn nothing.
+func applyPaymentLoyalty(tx *gorm.DB, bill models.Bill, payment models.Payment, userID uuid.UUID) error {
+	redeeming := payment.PaymentMethod == "loyalty_points"
+
+	if bill.CustomerID == nil {
+		if redeeming {
+			return errors.New("loyalty points can only be redeemed on a customer's bill")
+		}
+		return nil
+	}
+
+	program, err := loadLoyaltyProgram(tx, bill.ShopID)
+	if err != nil {
+		return err
+	}
+	if program == nil || !program.IsActive {
+		if redeeming {
+			return errors.New("loyalty program is not active")
+		}
+		return nil
+	}
+
+	now := time.Now()
+	customer, err := lockLoyaltyCustomer(tx, bill.ShopID, *bill.CustomerID, now)
+	if err != nil {
+		return err
+	}
+
+	if redeeming {
+		if program.PointValue <= 0 {
+			return errors.New("loyalty points have no redemption value")
+		}
+		points := int(math.Ceil(payment.Amount/program.PointValue - 1e-9))
+		if points < program.MinRedeemPoints {
+			return fmt.Errorf("at least %d points must be redeemed", program.MinRedeemPoints)
+		}
+		if points > customer.LoyaltyPoints {
+			return fmt.Errorf("customer only has %d points", customer.LoyaltyPoints)
+		}
+		if _, err := consumeLoyaltyPoints(tx, customer.ID, points, nil); err != nil {
+			return err
+		}
+
+		entry := models.LoyaltyTransaction{
+			ShopID:      bill.ShopID,
+			CustomerID:  customer.ID,
+			BillID:      &bill.ID,
+			PaymentID:   &payment.ID,
+			Type:        "redeem",
+			Points:      -points,
+			Description: fmt.Sprintf("Redeemed on bill %s", bill.BillNumber),
+			CreatedBy:   userID.String(),
+		}
+		if err := tx.Create(&entry).Error; err != nil {
+			return err
+		}
+		return tx.Model(&models.Customer{}).Where("id = ?", customer.ID).
+			Update("loyalty_points", gorm.Expr("loyalty_points - ?", points)).Error
+	}
+
+	multiplier := 1.0
+	if tier := loyaltyTier(program, customer.LifetimePoints); tier != nil {
+		multiplier = tier.Multiplier
+	}
+	points := int(math.Floor(payment.Amount*program.PointsPerUnit*multiplier + 1e-9))
+	if points <= 0 {
+		return nil
+	}
+
+	entry := models.LoyaltyTransaction{
+		ShopID:      bill.ShopID,
+		CustomerID:  customer.ID,
+		BillID:      &bill.ID,
+		PaymentID:   &payment.ID,
+		Type:        "earn",
+		Points:      points,
+		Remaining:   points,
+		ExpiresAt:   loyaltyExpiryDate(program, now),
+		Description: fmt.Sprintf("Earned on bill %s", bill.BillNumber),
+		CreatedBy:   userID.String(),
+	}
+	if err := tx.Create(&entry).Error; err != nil {
+		return err
+	}
+	return tx.Model(&models.Customer{}).Where("id = ?", customer.ID).Updates(map[string]interface{}{
+		"loyalty_points":  gorm.Expr("loyalty_points + ?", points),
+		"lifetime_points": gorm.Expr("lifetime_points + ?", points),
+	}).Error
+}
+
+// reverseBillLoyalty undoes the loyalty activity of a cancelled bill: points earned on it are
+// taken back, as far as the customer still has them, and points redeemed on it are restored
+func reverseBillLoyalty(tx *gorm.DB, bill models.Bill, userID uuid.UUID) error {
+	var entries []models.LoyaltyTransaction
+	if err := tx.Where("bill_id = ? AND type IN ?", bill.ID, []string{"earn", "redeem"}).Find(&entries).Error; err != nil {
+		return err
+	}
+	if len(entries) == 0 {
+		return nil
+	}
+
+	program, err := loadLoyaltyProgram(tx, bill.ShopID)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	for _, entry := range entries {
+		reversal := models.LoyaltyTransaction{
+			ShopID:      entry.ShopID,
+			CustomerID:  entry.CustomerID,
+			BillID:      entry.BillID,
+			PaymentID:   entry.PaymentID,
+			Type:        "reverse",
+			Description: fmt.Sprintf("Reversed for cancelled bill %s", bill.BillNumber),
+			CreatedBy:   userID.String(),
+		}
+
+		if entry.Type == "earn" {
+			// Points already taken back for refunds on the bill are not taken back again
+			reversed, err := reversedEarnPoints(tx, entry)
+			if err != nil {
+				return err
+			}
+			if reversal.Points, err = takeBackEarnedPoints(tx, entry, entry.Points-reversed); err != nil {
+				return err
+			}
+		} else {
+			reversal.Points = -entry.Points
+			reversal.Remaining = reversal.Points
+			if program != nil {
+				reversal.ExpiresAt = loyaltyExpiryDate(program, now)
+			}
+			if err := tx.Model(&models.Customer{}).Where("id = ?", entry.CustomerID).
+				Update("loyalty_points", gorm.Expr("loyalty_points + ?", reversal.Points)).Error; err != nil {
+				return err
+			}
+		}
+
+		if reversal.Points == 0 {
+			continue
+		}
+		if err := tx.Create(&reversal).Error; err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// reverseRefundLoyalty takes back the points earned on a bill in proportion to an amount
+// refunded on it. Points redeemed on the bill are not restored, since the refund is paid out.
+func reverseRefundLoyalty(tx *gorm.DB, bill models.Bill, amount float64, userID uuid.UUID) error {
+	if bill.TotalAmount <= 0 {
+		return nil
+	}
+	var entries []models.LoyaltyTransaction
+	if err := tx.Where("bill_id = ? AND type = ?", bill.ID, "earn").Find(&entries).Error; err != nil {
+		return err
+	}
+
+	share := math.Min(amount/bill.TotalAmount, 1)
+	for _, entry := range entries {
+		reversed, err := reversedEarnPoints(tx, entry)
+		if err != nil {
+			return err
+		}
+		points := min(int(math.Round(float64(entry.Points)*share)), entry.Points-reversed)
+		if points <= 0 {
+			continue
+		}
+
+		taken, err := takeBackEarnedPoints(tx, entry, points)
+		if err != nil {
+			return err
+		}
+		if taken == 0 {
+			continue
+		}
+		reversal := models.LoyaltyTransaction{
+			ShopID:      entry.ShopID,
+			CustomerID:  entry.CustomerID,
+			BillID:      entry.BillID,
+			PaymentID:   entry.PaymentID,
+			Type:        "reverse",
+			Points:      taken,
+			Description: fmt.Sprintf("Reversed for refund of %.2f on bill %s", amount, bill.BillNumber),
+			CreatedBy:   userID.String(),
+		}
+		if err := tx.Create(&reversal).Error; err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// reversedEarnPoints returns how many of the points of an earn entry have already been taken
+// back. Reversals of earned points are the negative reverse entries for the same payment.
+func reversedEarnPoints(tx *gorm.DB, entry models.LoyaltyTransaction) (int, error) {
+	query := tx.Model(&models.LoyaltyTransaction{}).
+		Where("bill_id = ? AND type = ? AND points < 0", entry.BillID, "reverse")
+	if entry.PaymentID != nil {
+		query = query.Where("payment_id = ?", *entry.PaymentID)
+	} else {
+		query = query.Where("payment_id IS NULL")
+	}
+
+	var reversed int
+	if err := query.Select("COALESCE(-SUM(points), 0)").Scan(&reversed).Error; err != nil {
+		return 0, err
+	}
+	return reversed, nil
+}
+
+// takeBackEarnedPoints removes up to points of an earn entry from the customer, as far as they
+// still have them, and returns the negative number of points taken for the reversal entry
+func takeBackEarnedPoints(tx *gorm.DB, entry models.LoyaltyTransaction, points int) (int, error) {
+	if points <= 0 {
+		return 0, nil
+	}
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Select("id").
+		Where("id = ?", entry.CustomerID).First(&models.Customer{}).Error; err != nil {
+		return 0, err
+	}
+	taken, err := consumeLoyaltyPoints(tx, entry.CustomerID, points, &entry.ID)
+	if err != nil {
+		return 0, err
+	}
+	if err := tx.Model(&models.Customer{}).Where("id = ?", entry.CustomerID).Updates(map[string]interface{}{
+		"loyalty_points":  gorm.Expr("loyalty_points - ?", taken),
+		"lifetime_points": gorm.Expr("GREATEST(lifetime_points - ?, 0)", points),
+	}).Error; err != nil {
+		return 0, err
+	}
+	return -taken, nil
+}
+
+// lockLoyaltyCustomer locks a customer's row, so that redemptions and adjustments of their points
+// wait for each other instead of both spending the same balance, writes off their expired points
+// and returns them with the balance that is left
+func lockLoyaltyCustomer(tx *gorm.DB, shopID, customerID uuid.UUID, now time.Time) (*models.Customer, error) {
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Select("id").
+		Where("id = ? AND shop_id = ?", customerID, shopID).First(&models.Customer{}).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("customer not found")
+		}
+		return nil, err
+	}
+	if err := expireLoyaltyPoints(tx, shopID, customerID, now); err != nil {
+		return nil, err
+	}
+
+	var customer models.Customer
+	if err := tx.Where("id = ?", customerID).First(&customer).Error; err != nil {
+		return nil, err
+	}
+	return &customer, nil
+}
+
+// consumeLoyaltyPoints uses up to points from a customer's point lots, starting with the given
+// lot and then the lots expiring first, and returns how many points were used
+func consumeLoyaltyPoints(tx *gorm.DB, customerID uuid.UUID, points int, firstLotID *uuid.UUID) (int, error) {
+	var lots []models.LoyaltyTransaction
+	if err := tx.Where("customer_id = ? AND remaining > 0", customerID).
+		Order("expires_at ASC NULLS LAST, created_at ASC").Find(&lots).Error; err != nil {
+		return 0, err
+	}
+	if firstLotID != nil {
+		sort.SliceStable(lots, func(i, j int) bool {
+			return lots[i].ID == *firstLotID && lots[j].ID != *firstLotID
+		})
+	}
+
+	used := 0
+	for _, lot := range lots {
+		if used == points {
+			break
+		}
+		take := min(lot.Remaining, points-used)
+		if err := tx.Model(&models.LoyaltyTransaction{}).Where("id = ?", lot.ID).
+			Update("remaining", lot.Remaining-take).Error; err != nil {
+			return 0, err
+		}
+		used += take
+	}
+
+	return used, nil
+}
+
+// expireLoyaltyPoints writes off the unused points of a customer's lots that have expired
+func expireLoyaltyPoints(tx *gorm.DB, shopID, customerID uuid.UUID, now time.Time) error {
+	var lots []models.LoyaltyTransaction
+	if err := tx.Where("customer_id = ? AND remaining > 0 AND expires_at <= ?", customerID, now).Find(&lots).Error; err != nil {
+		return err
+	}
+
+	for _, lot := range lots {
+		entry := models.LoyaltyTransaction{
+			ShopID:      shopID,
+			CustomerID:  customerID,
+			Type:        "expire",
+			Points:      -lot.Remaining,
+			Description: fmt.Sprintf("Expired points earned %s", lot.CreatedAt.Format("2006-01-02")),
+			CreatedBy:   "system",
+		}
+		if err := tx.Create(&entry).Error; err != nil {
+			return err
+		}
+		if err := tx.Model(&models.LoyaltyTransaction{}).Where("id = ?", lot.ID).Update("remaining", 0).Error; err != nil {
+			return err
+		}
+		if err := tx.Model(&models.Customer{}).Where("id = ?", customerID).
+			Update("loyalty_points", gorm.Expr("GREATEST(loyalty_points - ?, 0)", lot.Remaining)).Error; err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,223 @@
+package services
+
+import (
+	"bytes"
+	"crypto/tls"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net"
+	"net/mail"
+	"net/smtp"
+	"net/textproto"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// SMTPConfig describes an SMTP server to send through
+type SMTPConfig struct {
+	Host     string
+	Port     int
+	Username string
+	Password string
+	Security string // none, starttls, tls
+	From     string // envelope and header sender address
+}
+
+// EmailMessage is an email ready to be sent
+type EmailMessage struct {
+	From        string
+	FromName    string
+	ReplyTo     string
+	To          []string
+	CC          []string
+	Subject     string
+	Body        string // plain text
+	Attachments []EmailAttachment
+}
+
+// EmailAttachment is a file attached to an email
+type EmailAttachment struct {
+	FileName    string
+	ContentType string
+	Content     []byte
+}
+
+// Mailer sends emails
+type Mailer interface {
+	// Send sends a message and returns its Message-ID
+	Send(msg EmailMessage) (string, error)
+}
+
+// SMTPMailer sends emails through an SMTP server. A local catch-all such as MailHog or
+// Mailpit works with security none and no username.
+type SMTPMailer struct {
+	config  SMTPConfig
+	timeout time.Duration
+}
+
+// NewSMTPMailer creates a new SMTPMailer instance
+func NewSMTPMailer(config SMTPConfig) *SMTPMailer {
+	return &SMTPMailer{config: config, timeout: 30 * time.Second}
+}
+
+// Send delivers a message to the SMTP server
+func (m *SMTPMailer) Send(msg EmailMessage) (string, error) {
+	if m.config.Host == "" {
+		return "", errors.New("no SMTP server configured")
+	}
+	recipients := append(append([]string{}, msg.To...), msg.CC...)
+	if len(recipients) == 0 {
+		return "", errors.New("email has no recipients")
+	}
+
+	messageID, content, err := buildEmailMessage(msg)
+	if err != nil {
+		return "", err
+	}
+
+	address := net.JoinHostPort(m.config.Host, strconv.Itoa(m.config.Port))
+	dialer := &net.Dialer{Timeout: m.timeout}
+	var conn net.Conn
+	if m.config.Security == "tls" {
+		conn, err = tls.DialWithDialer(dialer, "tcp", address, &tls.Config{ServerName: m.config.Host})
+	} else {
+		conn, err = dialer.Dial("tcp", address)
+	}
+	if err != nil {
+		return "", fmt.Errorf("cannot connect to SMTP server: %w", err)
+	}
+	conn.SetDeadline(time.Now().Add(m.timeout))
+
+	client, err := smtp.NewClient(conn, m.config.Host)
+	if err != nil {
+		conn.Close()
+		return "", fmt.Errorf("SMTP greeting failed: %w", err)
+	}
+	defer client.Close()
+
+	if m.config.Security == "starttls" {
+		if ok, _ := client.Extension("STARTTLS"); !ok {
+			return "", errors.New("SMTP server does not support STARTTLS")
+		}
+		if err := client.StartTLS(&tls.Config{ServerName: m.config.Host}); err != nil {
+			return "", fmt.Errorf("STARTTLS failed: %w", err)
+		}
+	}
+	if m.config.Username != "" {
+		if err := client.Auth(smtp.PlainAuth("", m.config.Username, m.config.Password, m.config.Host)); err != nil {
+			return "", fmt.Errorf("SMTP authentication failed: %w", err)
+		}
+	}
+
+	if err := client.Mail(msg.From); err != nil {
+		return "", fmt.Errorf("SMTP server rejected the sender: %w", err)
+	}
+	for _, recipient := range recipients {
+		if err := client.Rcpt(recipient); err != nil {
+			return "", fmt.Errorf("SMTP server rejected %s: %w", recipient, err)
+		}
+	}
+	writer, err := client.Data()
+	if err != nil {
+		return "", fmt.Errorf("SMTP server rejected the message: %w", err)
+	}
+	if _, err := writer.Write(content); err != nil {
+		return "", fmt.Errorf("sending the message failed: %w", err)
+	}
+	if err := writer.Close(); err != nil {
+		return "", fmt.Errorf("SMTP server rejected the message: %w", err)
+	}
+	client.Quit()
+
+	return messageID, nil
+}
+
+// buildEmailMessage encodes a message as MIME: a quoted-printable text part followed by base64
+// attachments. It returns the generated Message-ID with the content.
+func buildEmailMessage(msg EmailMessage) (string, []byte, error) {
+	domain := "localhost"
+	if at := strings.LastIndex(msg.From, "@"); at >= 0 {
+		domain = msg.From[at+1:]
+	}
+	messageID := fmt.Sprintf("<%s@%s>", uuid.New().String(), domain)
+
+	var buf bytes.Buffer
+	header := func(name, value string) {
+		fmt.Fprintf(&buf, "%s: %s\r\n", name, value)
+	}
+	header("From", (&mail.Address{Name: msg.FromName, Address: msg.From}).String())
+	header("To", strings.Join(msg.To, ", "))
+	if len(msg.CC) > 0 {
+		header("Cc", strings.Join(msg.CC, ", "))
+	}
+	if msg.ReplyTo != "" {
+		header("Reply-To", msg.ReplyTo)
+	}
+	header("Subject", mime.QEncoding.Encode("utf-8", msg.Subject))
+	header("Date", time.Now().Format(time.RFC1123Z))
+	header("Message-ID", messageID)
+	header("MIME-Version", "1.0")
+
+	parts := multipart.NewWriter(&buf)
+	header("Content-Type", fmt.Sprintf("multipart/mixed; boundary=%q", parts.Boundary()))
+	buf.WriteString("\r\n")
+
+	text, err := parts.CreatePart(textproto.MIMEHeader{
+		"Content-Type":              {"text/plain; charset=utf-8"},
+		"Content-Transfer-Encoding": {"quoted-printable"},
+	})
+	if err != nil {
+		return "", nil, err
+	}
+	encoder := quotedprintable.NewWriter(text)
+	if _, err := io.WriteString(encoder, strings.ReplaceAll(msg.Body, "\n", "\r\n")); err != nil {
+		return "", nil, err
+	}
+	if err := encoder.Close(); err != nil {
+		return "", nil, err
+	}
+
+	for _, attachment := range msg.Attachments {
+		part, err := parts.CreatePart(textproto.MIMEHeader{
+			"Content-Type":              {mime.FormatMediaType(attachment.ContentType, map[string]string{"name": attachment.FileName})},
+			"Content-Disposition":       {mime.FormatMediaType("attachment", map[string]string{"filename": attachment.FileName})},
+			"Content-Transfer-Encoding": {"base64"},
+		})
+		if err != nil {
+			return "", nil, err
+		}
+		encoded := base64.StdEncoding.EncodeToString(attachment.Content)
+		for len(encoded) > 76 {
+			io.WriteString(part, encoded[:76]+"\r\n")
+			encoded = encoded[76:]
+		}
+		io.WriteString(part, encoded+"\r\n")
+	}
+	if err := parts.Close(); err != nil {
+		return "", nil, err
+	}
+
+	return messageID, buf.Bytes(), nil
+}
+
+// transientMailError reports whether sending may succeed if tried again later: the server
+// could not be reached, dropped the connection, or answered with a 4xx reply
+func transientMailError(err error) bool {
+	var reply *textproto.Error
+	if errors.As(err, &reply) {
+		return reply.Code >= 400 && reply.Code < 500
+	}
+	var netErr net.Error
+	if errors.As(err, &netErr) {
+		return true
+	}
+	return errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF)
+}
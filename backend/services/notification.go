@@ -0,0 +1,863 @@
+package services
+
+import (
+	"billboard/backend/models"
+	"bytes"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"regexp"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// notificationRetryDelays is how long to wait before retrying a message after each transient
+// failure. A message is given up once they run out.
+var notificationRetryDelays = []time.Duration{time.Minute, 5 * time.Minute, 30 * time.Minute, 2 * time.Hour}
+
+// notificationClaimTimeout is how long a message being sent is hidden from the retry worker
+const notificationClaimTimeout = 10 * time.Minute
+
+// notificationAutoSendWindow is how old an event can be and still be notified automatically,
+// so a worker that was stopped for a while does not send stale messages
+const notificationAutoSendWindow = 24 * time.Hour
+
+// notificationChannels and notificationEvents list the templates a shop can customize, in
+// display order
+var notificationChannels = []string{"sms", "whatsapp"}
+var notificationEvents = []string{"bill_issued", "payment_received", "reminder"}
+
+// defaultNotificationTemplates are the messages sent on both channels until a shop customizes
+// them. They are kept short enough for a single SMS where possible.
+var defaultNotificationTemplates = map[string]string{
+	"bill_issued": `{{.ShopName}}: bill {{.BillNumber}} for Rs {{.TotalAmount}} dated {{.BillDate}}
+{{- if .DueDate}}, due {{.DueDate}}{{end}}.
+{{- if ne .Balance "0.00"}} Balance Rs {{.Balance}}.{{if .UPIID}} Pay by UPI to {{.UPIID}}.{{end}}{{end}} Thank you!`,
+	"payment_received": `{{.ShopName}}: received Rs {{.PaymentAmount}} for bill {{.BillNumber}} on {{.PaymentDate}}.
+{{- if ne .Balance "0.00"}} Balance due Rs {{.Balance}}.{{else}} The bill is fully paid.{{end}} Thank you!`,
+	"reminder": `{{.ShopName}}: Rs {{.Balance}} on bill {{.BillNumber}}
+{{- if gt .DaysOverdue 0}} was due on {{.DueDate}}, {{.DaysOverdue}} days ago.
+{{- else if eq .DaysUntilDue 0}} is due today.
+{{- else}} is due on {{.DueDate}}.{{end}}
+{{- if .UPIID}} Pay by UPI to {{.UPIID}}.{{end}} Please ignore if paid.`,
+}
+
+// billNotificationData is what notification templates are filled with: the bill, and the
+// payment for payment received messages
+type billNotificationData struct {
+	billEmailData
+	PaymentAmount string
+	PaymentDate   string
+	PaymentMethod string
+}
+
+// sampleBillNotificationData fills templates when checking them before they are saved
+var sampleBillNotificationData = billNotificationData{
+	billEmailData: sampleBillEmailData,
+	PaymentAmount: "50.00",
+	PaymentDate:   "15 Jan 2024",
+	PaymentMethod: "upi",
+}
+
+// notificationStatusRank orders delivery statuses, so a late delivery report never moves a
+// message back
+var notificationStatusRank = map[string]int{"queued": 0, "sent": 1, "delivered": 2, "read": 3}
+
+// phoneCleaner strips the separators people write phone numbers with
+var phoneCleaner = regexp.MustCompile(`[\s\-().]`)
+
+// NotificationService sends customers SMS and WhatsApp messages about their bills through the
+// installation's providers, automatically for the events a shop turns on, and keeps a log of
+// every message and its delivery status
+type NotificationService struct {
+	db          *gorm.DB
+	notifiers   map[string]Notifier // by channel
+	countryCode string
+}
+
+// NewNotificationService creates a new NotificationService instance. Phone numbers without a
+// country code are taken to be in countryCode, such as 91.
+func NewNotificationService(db *gorm.DB, sms, whatsApp Notifier, countryCode string) *NotificationService {
+	return &NotificationService{
+		db:          db,
+		notifiers:   map[string]Notifier{"sms": sms, "whatsapp": whatsApp},
+		countryCode: strings.TrimPrefix(countryCode, "+"),
+	}
+}
+
+// GetTemplates returns the shop's message for every channel and event, built-in or customized
+func (s *NotificationService) GetTemplates(shopID, userID uuid.UUID) ([]models.NotificationTemplateResponse, error) {
+	// Check if user has access to the shop
+	var shopUser models.ShopUser
+	if err := s.db.Where("shop_id = ? AND user_id = ? AND is_active = ?", shopID, userID, true).First(&shopUser).Error; err != nil {
+		return nil, errors.New("access denied to shop")
+	}
+
+	var custom []models.NotificationTemplate
+	if err := s.db.Where("shop_id = ?", shopID).Find(&custom).Error; err != nil {
+		return nil, err
+	}
+	byKey := map[string]models.NotificationTemplate{}
+	for _, tmpl := range custom {
+		byKey[tmpl.Channel+":"+tmpl.Event] = tmpl
+	}
+
+	responses := make([]models.NotificationTemplateResponse, 0, len(notificationChannels)*len(notificationEvents))
+	for _, channel := range notificationChannels {
+		for _, event := range notificationEvents {
+			tmpl, ok := byKey[channel+":"+event]
+			if !ok {
+				tmpl = models.NotificationTemplate{Channel: channel, Event: event, Body: defaultNotificationTemplates[event]}
+			}
+			response := s.templateToResponse(tmpl)
+			response.IsDefault = !ok
+			responses = append(responses, response)
+		}
+	}
+	return responses, nil
+}
+
+// UpdateTemplate customizes the shop's message for a channel and event. Turning on automatic
+// sending only affects events from then on.
+func (s *NotificationService) UpdateTemplate(shopID, userID uuid.UUID, channel, event string, req models.NotificationTemplateRequest) (*models.NotificationTemplateResponse, error) {
+	// Check if user has access to the shop
+	var shopUser models.ShopUser
+	if err := s.db.Where("shop_id = ? AND user_id = ? AND is_active = ?", shopID, userID, true).First(&shopUser).Error; err != nil {
+		return nil, errors.New("access denied to shop")
+	}
+
+	if err := validNotificationTemplate(channel, event); err != nil {
+		return nil, err
+	}
+	if event == "reminder" && req.AutoSend != nil && *req.AutoSend {
+		return nil, errors.New("reminders are sent by the payment reminder schedule")
+	}
+
+	var tmpl models.NotificationTemplate
+	if err := s.db.Where("shop_id = ? AND channel = ? AND event = ?", shopID, channel, event).Find(&tmpl).Error; err != nil {
+		return nil, err
+	}
+	tmpl.ShopID = shopID
+	tmpl.Channel = channel
+	tmpl.Event = event
+	tmpl.Body = req.Body
+	tmpl.ProviderTemplate = strings.TrimSpace(req.ProviderTemplate)
+	tmpl.Language = strings.TrimSpace(req.Language)
+	tmpl.Params = req.Params
+	if req.AutoSend != nil && *req.AutoSend != tmpl.AutoSend {
+		tmpl.AutoSend = *req.AutoSend
+		tmpl.AutoSendSince = nil
+		if tmpl.AutoSend {
+			now := time.Now()
+			tmpl.AutoSendSince = &now
+		}
+	}
+	if _, _, err := renderNotificationTemplate(tmpl, sampleBillNotificationData); err != nil {
+		return nil, err
+	}
+	if err := s.db.Save(&tmpl).Error; err != nil {
+		return nil, err
+	}
+
+	response := s.templateToResponse(tmpl)
+	return &response, nil
+}
+
+// ResetTemplate goes back to the built-in message for a channel and event, which also turns
+// off automatic sending
+func (s *NotificationService) ResetTemplate(shopID, userID uuid.UUID, channel, event string) error {
+	// Check if user has access to the shop
+	var shopUser models.ShopUser
+	if err := s.db.Where("shop_id = ? AND user_id = ? AND is_active = ?", shopID, userID, true).First(&shopUser).Error; err != nil {
+		return errors.New("access denied to shop")
+	}
+
+	if err := validNotificationTemplate(channel, event); err != nil {
+		return err
+	}
+	return s.db.Where("shop_id = ? AND channel = ? AND event = ?", shopID, channel, event).Delete(&models.NotificationTemplate{}).Error
+}
+
+// SendTestMessage sends a test message to check a channel's provider
+func (s *NotificationService) SendTestMessage(shopID, userID uuid.UUID, req models.TestNotificationRequest) (*models.NotificationDeliveryResponse, error) {
+	// Check if user has access to the shop
+	var shopUser models.ShopUser
+	if err := s.db.Where("shop_id = ? AND user_id = ? AND is_active = ?", shopID, userID, true).First(&shopUser).Error; err != nil {
+		return nil, errors.New("access denied to shop")
+	}
+
+	to, err := normalizePhone(req.Phone, s.countryCode)
+	if err != nil {
+		return nil, err
+	}
+	var shop models.Shop
+	if err := s.db.Select("id", "name").Where("id = ?", shopID).First(&shop).Error; err != nil {
+		return nil, errors.New("shop not found")
+	}
+
+	delivery := models.NotificationDelivery{
+		ShopID:    shopID,
+		Event:     "test",
+		Channel:   req.Channel,
+		Recipient: to,
+		Body:      fmt.Sprintf("%s: this is a test message. Your %s notifications are working.", shop.Name, notificationChannelName(req.Channel)),
+		CreatedBy: userID.String(),
+	}
+	if err := s.createAndSend(&delivery); err != nil {
+		return nil, err
+	}
+	response := notificationDeliveryToResponse(delivery)
+	return &response, nil
+}
+
+// SendBill messages a bill's customer about it. Payment received messages are about the
+// bill's latest payment.
+func (s *NotificationService) SendBill(billID, shopID, userID uuid.UUID, req models.SendBillNotificationRequest) (*models.NotificationDeliveryResponse, error) {
+	// Check if user has access to the shop
+	var shopUser models.ShopUser
+	if err := s.db.Where("shop_id = ? AND user_id = ? AND is_active = ?", shopID, userID, true).First(&shopUser).Error; err != nil {
+		return nil, errors.New("access denied to shop")
+	}
+
+	bill, err := s.loadBill(billID, shopID)
+	if err != nil {
+		return nil, err
+	}
+	if bill.Status == "cancelled" {
+		return nil, errors.New("cannot send a notification for a cancelled bill")
+	}
+
+	phone := req.Phone
+	if phone == "" {
+		if bill.Customer == nil || bill.Customer.Phone == "" {
+			return nil, errors.New("customer has no phone number")
+		}
+		phone = bill.Customer.Phone
+	}
+
+	var payment *models.Payment
+	if req.Event == "payment_received" {
+		for i := range bill.Payments {
+			if payment == nil || bill.Payments[i].CreatedAt.After(payment.CreatedAt) {
+				payment = &bill.Payments[i]
+			}
+		}
+		if payment == nil {
+			return nil, errors.New("bill has no payments")
+		}
+	}
+
+	delivery, err := s.sendBillNotification(*bill, payment, req.Event, req.Channel, phone, req.Message, userID.String(), nil)
+	if err != nil {
+		return nil, err
+	}
+	response := notificationDeliveryToResponse(*delivery)
+	return &response, nil
+}
+
+// GetBillDeliveries lists the messages sent about a bill, most recent first
+func (s *NotificationService) GetBillDeliveries(billID, shopID, userID uuid.UUID) ([]models.NotificationDeliveryResponse, error) {
+	// Check if user has access to the shop
+	var shopUser models.ShopUser
+	if err := s.db.Where("shop_id = ? AND user_id = ? AND is_active = ?", shopID, userID, true).First(&shopUser).Error; err != nil {
+		return nil, errors.New("access denied to shop")
+	}
+
+	var deliveries []models.NotificationDelivery
+	if err := s.db.Where("bill_id = ? AND shop_id = ?", billID, shopID).Order("created_at DESC").Find(&deliveries).Error; err != nil {
+		return nil, err
+	}
+	return notificationDeliveriesToResponse(deliveries), nil
+}
+
+// GetDeliveries lists a shop's messages, optionally by channel and status, most recent first
+func (s *NotificationService) GetDeliveries(shopID, userID uuid.UUID, channel, status string) ([]models.NotificationDeliveryResponse, error) {
+	// Check if user has access to the shop
+	var shopUser models.ShopUser
+	if err := s.db.Where("shop_id = ? AND user_id = ? AND is_active = ?", shopID, userID, true).First(&shopUser).Error; err != nil {
+		return nil, errors.New("access denied to shop")
+	}
+
+	query := s.db.Where("shop_id = ?", shopID)
+	if channel != "" {
+		query = query.Where("channel = ?", channel)
+	}
+	if status != "" {
+		query = query.Where("status = ?", status)
+	}
+	var deliveries []models.NotificationDelivery
+	if err := query.Order("created_at DESC").Limit(200).Find(&deliveries).Error; err != nil {
+		return nil, err
+	}
+	return notificationDeliveriesToResponse(deliveries), nil
+}
+
+// RetryDelivery sends a failed or queued message again straight away
+func (s *NotificationService) RetryDelivery(deliveryID, shopID, userID uuid.UUID) (*models.NotificationDeliveryResponse, error) {
+	// Check if user has access to the shop
+	var shopUser models.ShopUser
+	if err := s.db.Where("shop_id = ? AND user_id = ? AND is_active = ?", shopID, userID, true).First(&shopUser).Error; err != nil {
+		return nil, errors.New("access denied to shop")
+	}
+
+	var delivery models.NotificationDelivery
+	if err := s.db.Where("id = ? AND shop_id = ?", deliveryID, shopID).First(&delivery).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("notification not found")
+		}
+		return nil, err
+	}
+	if delivery.Status != "queued" && delivery.Status != "failed" {
+		return nil, errors.New("notification has already been sent")
+	}
+
+	// Claim the delivery so the retry worker does not send it at the same time
+	now := time.Now()
+	claimUntil := now.Add(notificationClaimTimeout)
+	claim := s.db.Model(&models.NotificationDelivery{}).
+		Where("id = ? AND (status = ? OR (status = ? AND next_attempt_at <= ?))", delivery.ID, "failed", "queued", now).
+		Updates(map[string]interface{}{"status": "queued", "next_attempt_at": claimUntil, "updated_at": now})
+	if claim.Error != nil {
+		return nil, claim.Error
+	}
+	if claim.RowsAffected == 0 {
+		return nil, errors.New("notification is being sent")
+	}
+	delivery.Status = "queued"
+	delivery.NextAttemptAt = &claimUntil
+
+	s.attempt(&delivery)
+	response := notificationDeliveryToResponse(delivery)
+	return &response, nil
+}
+
+// HandleDeliveryReport records the delivery statuses a provider reports for sent messages
+func (s *NotificationService) HandleDeliveryReport(provider string, payload []byte, headers http.Header) error {
+	notifier := s.notifierNamed(provider)
+	if notifier == nil {
+		return errors.New("unknown notification provider")
+	}
+
+	statuses, err := notifier.ParseDeliveryReport(payload, headers)
+	if err != nil {
+		return err
+	}
+
+	for _, report := range statuses {
+		var delivery models.NotificationDelivery
+		if err := s.db.Where("provider = ? AND provider_message_id = ?", provider, report.ProviderMessageID).Find(&delivery).Error; err != nil {
+			return err
+		}
+		if delivery.ID == uuid.Nil {
+			continue
+		}
+
+		now := time.Now()
+		updates := map[string]interface{}{"updated_at": now}
+		switch report.Status {
+		case "failed":
+			// A message already delivered cannot fail afterwards
+			if notificationStatusRank[delivery.Status] > notificationStatusRank["sent"] || delivery.Status == "failed" {
+				continue
+			}
+			updates["status"] = "failed"
+			updates["last_error"] = report.Error
+		default:
+			if delivery.Status == "failed" || notificationStatusRank[report.Status] <= notificationStatusRank[delivery.Status] {
+				continue
+			}
+			updates["status"] = report.Status
+			if delivery.DeliveredAt == nil {
+				updates["delivered_at"] = now
+			}
+			if report.Status == "read" {
+				updates["read_at"] = now
+			}
+		}
+		if err := s.db.Model(&models.NotificationDelivery{}).Where("id = ?", delivery.ID).Updates(updates).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// VerifyWebhookSubscription answers the challenge a provider sends when its delivery report
+// webhook is set up. It returns the challenge to echo back.
+func (s *NotificationService) VerifyWebhookSubscription(provider, mode, token, challenge string) (string, error) {
+	challenger, ok := s.notifierNamed(provider).(interface {
+		VerifySubscription(mode, token string) bool
+	})
+	if !ok {
+		return "", errors.New("unknown notification provider")
+	}
+	if !challenger.VerifySubscription(mode, token) {
+		return "", ErrInvalidWebhookSignature
+	}
+	return challenge, nil
+}
+
+// ProcessQueue sends the automatic messages of new bills and payments, then retries queued
+// messages that are due. It returns how many were sent.
+func (s *NotificationService) ProcessQueue() (int, error) {
+	if err := s.queueAutomaticNotifications(); err != nil {
+		return 0, err
+	}
+
+	var due []models.NotificationDelivery
+	if err := s.db.Where("status = ? AND next_attempt_at <= ?", "queued", time.Now()).
+		Order("next_attempt_at").Limit(100).Find(&due).Error; err != nil {
+		return 0, err
+	}
+
+	sent := 0
+	for i := range due {
+		delivery := &due[i]
+		now := time.Now()
+		claimUntil := now.Add(notificationClaimTimeout)
+		claim := s.db.Model(&models.NotificationDelivery{}).
+			Where("id = ? AND status = ? AND next_attempt_at <= ?", delivery.ID, "queued", now).
+			Update("next_attempt_at", claimUntil)
+		if claim.Error != nil {
+			return sent, claim.Error
+		}
+		if claim.RowsAffected == 0 {
+			continue
+		}
+		delivery.NextAttemptAt = &claimUntil
+
+		s.attempt(delivery)
+		if delivery.Status == "sent" {
+			sent++
+		}
+	}
+	return sent, nil
+}
+
+// StartWorker periodically sends automatic and queued messages in the background
+func (s *NotificationService) StartWorker(interval time.Duration) {
+	if s.db == nil {
+		return
+	}
+
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for range ticker.C {
+			if count, err := s.ProcessQueue(); err != nil {
+				log.Printf("Failed to process notification queue: %v", err)
+			} else if count > 0 {
+				log.Printf("Sent %d queued notifications", count)
+			}
+		}
+	}()
+}
+
+// queueAutomaticNotifications sends the bill issued and payment received messages shops have
+// turned on, for bills and payments of customers with a phone number. Each event is sent once
+// per channel: the unique automatic key rejects a message another worker already sent.
+func (s *NotificationService) queueAutomaticNotifications() error {
+	var templates []models.NotificationTemplate
+	if err := s.db.Where("auto_send = ? AND auto_send_since IS NOT NULL AND event IN ?", true, []string{"bill_issued", "payment_received"}).
+		Find(&templates).Error; err != nil {
+		return err
+	}
+
+	windowStart := time.Now().Add(-notificationAutoSendWindow)
+	for _, tmpl := range templates {
+		var bills []models.Bill
+		var payments []models.Payment
+		switch tmpl.Event {
+		case "bill_issued":
+			if err := s.db.Preload("Shop").Preload("Customer").
+				Joins("JOIN customers ON customers.id = bills.customer_id").
+				Where("bills.shop_id = ? AND bills.deleted_at IS NULL AND bills.status <> ? AND bills.created_at >= ? AND bills.updated_at >= ? AND customers.phone <> ''",
+					tmpl.ShopID, "cancelled", *tmpl.AutoSendSince, windowStart).
+				Order("bills.created_at").Limit(100).
+				Find(&bills).Error; err != nil {
+				return err
+			}
+		case "payment_received":
+			if err := s.db.Preload("Bill.Shop").Preload("Bill.Customer").
+				Joins("JOIN bills ON bills.id = payments.bill_id").
+				Joins("JOIN customers ON customers.id = bills.customer_id").
+				Where("bills.shop_id = ? AND bills.deleted_at IS NULL AND bills.status <> ? AND payments.created_at >= ? AND payments.created_at >= ? AND customers.phone <> ''",
+					tmpl.ShopID, "cancelled", *tmpl.AutoSendSince, windowStart).
+				Order("payments.created_at").Limit(100).
+				Find(&payments).Error; err != nil {
+				return err
+			}
+		}
+
+		keys := make([]string, 0, len(bills)+len(payments))
+		for _, bill := range bills {
+			keys = append(keys, notificationAutoKey(tmpl.Event, tmpl.Channel, bill.ID))
+		}
+		for _, payment := range payments {
+			keys = append(keys, notificationAutoKey(tmpl.Event, tmpl.Channel, payment.ID))
+		}
+		if len(keys) == 0 {
+			continue
+		}
+		var sentKeys []string
+		if err := s.db.Model(&models.NotificationDelivery{}).Where("auto_key IN ?", keys).Pluck("auto_key", &sentKeys).Error; err != nil {
+			return err
+		}
+		sent := make(map[string]bool, len(sentKeys))
+		for _, key := range sentKeys {
+			sent[key] = true
+		}
+
+		for _, bill := range bills {
+			key := notificationAutoKey(tmpl.Event, tmpl.Channel, bill.ID)
+			if sent[key] {
+				continue
+			}
+			if _, err := s.sendBillNotification(bill, nil, tmpl.Event, tmpl.Channel, bill.Customer.Phone, "", "system", &key); err != nil {
+				log.Printf("Cannot notify bill %s by %s: %v", bill.ID, tmpl.Channel, err)
+			}
+		}
+		for i := range payments {
+			payment := &payments[i]
+			key := notificationAutoKey(tmpl.Event, tmpl.Channel, payment.ID)
+			if sent[key] {
+				continue
+			}
+			if _, err := s.sendBillNotification(payment.Bill, payment, tmpl.Event, tmpl.Channel, payment.Bill.Customer.Phone, "", "system", &key); err != nil {
+				log.Printf("Cannot notify payment %s by %s: %v", payment.ID, tmpl.Channel, err)
+			}
+		}
+	}
+	return nil
+}
+
+// sendBillNotification fills the shop's template for an event with a bill, and the payment
+// for payment received messages, and sends it. A message given in place of the template is
+// sent as plain text. A draft bill the customer has been told about is issued.
+func (s *NotificationService) sendBillNotification(bill models.Bill, payment *models.Payment, event, channel, phone, message, createdBy string, autoKey *string) (*models.NotificationDelivery, error) {
+	to, err := normalizePhone(phone, s.countryCode)
+	if err != nil {
+		return nil, err
+	}
+
+	tmpl, err := s.shopTemplate(bill.ShopID, channel, event)
+	if err != nil {
+		return nil, err
+	}
+	if message != "" {
+		tmpl = models.NotificationTemplate{Channel: channel, Event: event, Body: message}
+	}
+	body, params, err := renderNotificationTemplate(tmpl, billNotificationDataFor(bill, payment))
+	if err != nil {
+		return nil, err
+	}
+
+	delivery := models.NotificationDelivery{
+		ShopID:           bill.ShopID,
+		BillID:           &bill.ID,
+		CustomerID:       bill.CustomerID,
+		AutoKey:          autoKey,
+		Event:            event,
+		Channel:          channel,
+		Recipient:        to,
+		Body:             body,
+		ProviderTemplate: tmpl.ProviderTemplate,
+		Language:         tmpl.Language,
+		Params:           strings.Join(params, "\n"),
+		CreatedBy:        createdBy,
+	}
+	if payment != nil {
+		delivery.PaymentID = &payment.ID
+	}
+	if err := s.createAndSend(&delivery); err != nil {
+		return nil, err
+	}
+	if event == "bill_issued" && delivery.Status != "failed" {
+		if err := issueBill(s.db, &bill); err != nil {
+			return nil, err
+		}
+	}
+	return &delivery, nil
+}
+
+// createAndSend logs a new delivery and makes its first attempt
+func (s *NotificationService) createAndSend(delivery *models.NotificationDelivery) error {
+	notifier := s.notifiers[delivery.Channel]
+	if notifier == nil {
+		return fmt.Errorf("%s notifications are not available", notificationChannelName(delivery.Channel))
+	}
+
+	claimUntil := time.Now().Add(notificationClaimTimeout)
+	delivery.Provider = notifier.Name()
+	delivery.Status = "queued"
+	delivery.NextAttemptAt = &claimUntil
+	if err := s.db.Create(delivery).Error; err != nil {
+		return err
+	}
+	s.attempt(delivery)
+	return nil
+}
+
+// attempt sends a claimed delivery once and records the outcome: sent, queued for a retry
+// after a transient failure, or failed
+func (s *NotificationService) attempt(delivery *models.NotificationDelivery) {
+	var messageID string
+	var err error
+	notifier := s.notifierNamed(delivery.Provider)
+	if notifier == nil {
+		err = fmt.Errorf("notification provider %s is no longer configured", delivery.Provider)
+	} else {
+		msg := NotifierMessage{
+			To:               delivery.Recipient,
+			Body:             delivery.Body,
+			ProviderTemplate: delivery.ProviderTemplate,
+			Language:         delivery.Language,
+			Reference:        delivery.ID.String(),
+		}
+		if delivery.Params != "" {
+			msg.Params = strings.Split(delivery.Params, "\n")
+		}
+		messageID, err = notifier.Send(msg)
+	}
+
+	now := time.Now()
+	delivery.Attempts++
+	delivery.LastError = ""
+	switch {
+	case err == nil:
+		delivery.Status = "sent"
+		delivery.SentAt = &now
+		delivery.NextAttemptAt = nil
+		if messageID != "" {
+			delivery.ProviderMessageID = &messageID
+		}
+	case errors.Is(err, errNotifierUnavailable) && delivery.Attempts <= len(notificationRetryDelays):
+		next := now.Add(notificationRetryDelays[delivery.Attempts-1])
+		delivery.Status = "queued"
+		delivery.NextAttemptAt = &next
+		delivery.LastError = err.Error()
+	default:
+		delivery.Status = "failed"
+		delivery.NextAttemptAt = nil
+		delivery.LastError = err.Error()
+	}
+
+	if err := s.db.Model(&models.NotificationDelivery{}).Where("id = ?", delivery.ID).Updates(map[string]interface{}{
+		"status":              delivery.Status,
+		"attempts":            delivery.Attempts,
+		"next_attempt_at":     delivery.NextAttemptAt,
+		"last_error":          delivery.LastError,
+		"provider_message_id": delivery.ProviderMessageID,
+		"sent_at":             delivery.SentAt,
+		"updated_at":          now,
+	}).Error; err != nil {
+		log.Printf("Failed to record notification %s: %v", delivery.ID, err)
+	}
+}
+
+// channelAvailable reports whether a provider is configured to deliver a channel's messages
+func (s *NotificationService) channelAvailable(channel string) bool {
+	return s != nil && s.notifiers[channel] != nil
+}
+
+// notifierNamed returns the configured provider with a name, or nil
+func (s *NotificationService) notifierNamed(name string) Notifier {
+	for _, notifier := range s.notifiers {
+		if notifier != nil && notifier.Name() == name {
+			return notifier
+		}
+	}
+	return nil
+}
+
+// shopTemplate returns a shop's template for a channel and event, or the built-in one
+func (s *NotificationService) shopTemplate(shopID uuid.UUID, channel, event string) (models.NotificationTemplate, error) {
+	var custom models.NotificationTemplate
+	if err := s.db.Where("shop_id = ? AND channel = ? AND event = ?", shopID, channel, event).Find(&custom).Error; err != nil {
+		return custom, err
+	}
+	if custom.ID != uuid.Nil {
+		return custom, nil
+	}
+	return models.NotificationTemplate{ShopID: shopID, Channel: channel, Event: event, Body: defaultNotificationTemplates[event]}, nil
+}
+
+// loadBill loads a bill with everything notification templates need
+func (s *NotificationService) loadBill(billID, shopID uuid.UUID) (*models.Bill, error) {
+	var bill models.Bill
+	if err := s.db.Preload("Shop").Preload("Customer").Preload("Payments").
+		Where("id = ? AND shop_id = ? AND deleted_at IS NULL", billID, shopID).First(&bill).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("bill not found")
+		}
+		return nil, err
+	}
+	return &bill, nil
+}
+
+// templateToResponse converts a template to its response, naming the channel's provider
+func (s *NotificationService) templateToResponse(tmpl models.NotificationTemplate) models.NotificationTemplateResponse {
+	response := models.NotificationTemplateResponse{
+		Channel:          tmpl.Channel,
+		Event:            tmpl.Event,
+		Body:             tmpl.Body,
+		ProviderTemplate: tmpl.ProviderTemplate,
+		Language:         tmpl.Language,
+		Params:           tmpl.Params,
+		AutoSend:         tmpl.AutoSend,
+		AutoSendSince:    tmpl.AutoSendSince,
+	}
+	if notifier := s.notifiers[tmpl.Channel]; notifier != nil {
+		response.Provider = notifier.Name()
+	}
+	return response
+}
+
+// billNotificationDataFor collects the template data of a bill and, optionally, a payment
+func billNotificationDataFor(bill models.Bill, payment *models.Payment) billNotificationData {
+	data := billNotificationData{billEmailData: billEmailDataFor(bill)}
+	if payment != nil {
+		data.PaymentAmount = formatAmount(payment.Amount)
+		data.PaymentDate = payment.PaymentDate.Format("02 Jan 2006")
+		data.PaymentMethod = payment.PaymentMethod
+	}
+	return data
+}
+
+// renderNotificationTemplate fills a template's message and WhatsApp template parameters.
+// Blank lines are dropped from messages, since texts are read on small screens.
+func renderNotificationTemplate(tmpl models.NotificationTemplate, data interface{}) (string, []string, error) {
+	render := func(name, text string) (string, error) {
+		parsed, err := template.New(name).Parse(text)
+		if err != nil {
+			return "", fmt.Errorf("invalid %s template: %v", name, err)
+		}
+		var buf bytes.Buffer
+		if err := parsed.Execute(&buf, data); err != nil {
+			return "", fmt.Errorf("invalid %s template: %v", name, err)
+		}
+		return buf.String(), nil
+	}
+
+	rendered, err := render("message", tmpl.Body)
+	if err != nil {
+		return "", nil, err
+	}
+	var lines []string
+	for _, line := range strings.Split(rendered, "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			lines = append(lines, line)
+		}
+	}
+	if len(lines) == 0 {
+		return "", nil, errors.New("notification message is empty")
+	}
+
+	var params []string
+	for _, line := range strings.Split(tmpl.Params, "\n") {
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+		param, err := render("params", line)
+		if err != nil {
+			return "", nil, err
+		}
+		// WhatsApp rejects parameters with new lines or left empty
+		param = strings.Join(strings.Fields(param), " ")
+		if param == "" {
+			param = "-"
+		}
+		params = append(params, param)
+	}
+	return strings.Join(lines, "\n"), params, nil
+}
+
+// normalizePhone turns a phone number as customers write it into E.164. Ten digit numbers, or
+// eleven with a leading zero, are taken to be in the default country.
+func normalizePhone(phone, countryCode string) (string, error) {
+	cleaned := phoneCleaner.ReplaceAllString(strings.TrimSpace(phone), "")
+	digits := strings.TrimPrefix(cleaned, "+")
+	for _, r := range digits {
+		if r < '0' || r > '9' {
+			return "", errors.New("invalid phone number")
+		}
+	}
+
+	switch {
+	case strings.HasPrefix(cleaned, "+"):
+	case strings.HasPrefix(digits, "00"):
+		digits = digits[2:]
+	case len(digits) == 11 && digits[0] == '0':
+		digits = countryCode + digits[1:]
+	case len(digits) == 10:
+		digits = countryCode + digits
+	}
+	if len(digits) < 8 || len(digits) > 15 || digits[0] == '0' {
+		return "", errors.New("invalid phone number")
+	}
+	return "+" + digits, nil
+}
+
+// validNotificationTemplate checks a channel and event name a template is customized for
+func validNotificationTemplate(channel, event string) error {
+	if channel != "sms" && channel != "whatsapp" {
+		return errors.New("unknown notification channel")
+	}
+	if _, ok := defaultNotificationTemplates[event]; !ok {
+		return errors.New("unknown notification event")
+	}
+	return nil
+}
+
+// notificationAutoKey identifies the automatic message of an event for a bill or payment
+func notificationAutoKey(event, channel string, id uuid.UUID) string {
+	return event + ":" + channel + ":" + id.String()
+}
+
+// notificationChannelName returns a channel's name for messages
+func notificationChannelName(channel string) string {
+	if channel == "whatsapp" {
+		return "WhatsApp"
+	}
+	return "SMS"
+}
+
+// notificationDeliveriesToResponse converts deliveries to their responses
+func notificationDeliveriesToResponse(deliveries []models.NotificationDelivery) []models.NotificationDeliveryResponse {
+	responses := make([]models.NotificationDeliveryResponse, len(deliveries))
+	for i, delivery := range deliveries {
+		responses[i] = notificationDeliveryToResponse(delivery)
+	}
+	return responses
+}
+
+// notificationDeliveryToResponse converts a delivery to its response
+func notificationDeliveryToResponse(delivery models.NotificationDelivery) models.NotificationDeliveryResponse {
+	return models.NotificationDeliveryResponse{
+		ID:                delivery.ID,
+		BillID:            delivery.BillID,
+		PaymentID:         delivery.PaymentID,
+		CustomerID:        delivery.CustomerID,
+		Event:             delivery.Event,
+		Channel:           delivery.Channel,
+		Provider:          delivery.Provider,
+		Recipient:         delivery.Recipient,
+		Body:              delivery.Body,
+		Automatic:         delivery.AutoKey != nil,
+		Status:            delivery.Status,
+		Attempts:          delivery.Attempts,
+		NextAttemptAt:     delivery.NextAttemptAt,
+		LastError:         delivery.LastError,
+		ProviderMessageID: delivery.ProviderMessageID,
+		SentAt:            delivery.SentAt,
+		DeliveredAt:       delivery.DeliveredAt,
+		ReadAt:            delivery.ReadAt,
+		CreatedBy:         delivery.CreatedBy,
+		CreatedAt:         delivery.CreatedAt,
+	}
+}
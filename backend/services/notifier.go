@@ -0,0 +1,348 @@
+package services
+
+import (
+	"bytes"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// errNotifierUnavailable marks send failures that may pass, such as the provider being
+// unreachable or rate limiting, so the message is retried later
+var errNotifierUnavailable = errors.New("notification provider unavailable")
+
+// Notifier sends text messages to phones through an SMS or WhatsApp provider and reads the
+// delivery reports it sends back
+type Notifier interface {
+	// Name identifies the provider in stored deliveries and delivery report URLs
+	Name() string
+	// Send sends a message and returns the provider's message ID
+	Send(msg NotifierMessage) (string, error)
+	// ParseDeliveryReport verifies a delivery report's signature and returns the statuses it
+	// carries. It returns ErrInvalidWebhookSignature when the signature does not verify.
+	ParseDeliveryReport(payload []byte, headers http.Header) ([]NotifierStatus, error)
+}
+
+// NotifierMessage is a text message ready to be sent
+type NotifierMessage struct {
+	To               string // E.164 number, such as +919876543210
+	Body             string
+	ProviderTemplate string   // DLT template ID for SMS, approved template name for WhatsApp
+	Language         string   // WhatsApp template language code
+	Params           []string // WhatsApp template body parameters
+	Reference        string   // our delivery ID
+}
+
+// NotifierStatus is a delivery report for a message
+type NotifierStatus struct {
+	ProviderMessageID string
+	Status            string // delivered, read, failed
+	Error             string
+}
+
+// HTTPSMSNotifier sends SMS through a generic HTTP gateway. Messages are posted as JSON with a
+// bearer API key, and delivery reports are expected as JSON signed with an X-Signature
+// HMAC-SHA256 of the body.
+type HTTPSMSNotifier struct {
+	url           string
+	apiKey        string
+	senderID      string
+	webhookSecret string
+	client        *http.Client
+}
+
+// NewHTTPSMSNotifier creates a new HTTPSMSNotifier instance
+func NewHTTPSMSNotifier(url, apiKey, senderID, webhookSecret string) *HTTPSMSNotifier {
+	return &HTTPSMSNotifier{
+		url:           url,
+		apiKey:        apiKey,
+		senderID:      senderID,
+		webhookSecret: webhookSecret,
+		client:        &http.Client{Timeout: 15 * time.Second},
+	}
+}
+
+// Name returns the provider name
+func (n *HTTPSMSNotifier) Name() string {
+	return "http_sms"
+}
+
+// Send posts a message to the SMS gateway
+func (n *HTTPSMSNotifier) Send(msg NotifierMessage) (string, error) {
+	if n.url == "" {
+		return "", errors.New("no SMS gateway configured")
+	}
+	body := map[string]string{
+		"to":        msg.To,
+		"from":      n.senderID,
+		"message":   msg.Body,
+		"reference": msg.Reference,
+	}
+	if msg.ProviderTemplate != "" {
+		body["template_id"] = msg.ProviderTemplate
+	}
+
+	var response struct {
+		ID        string `json:"id"`
+		MessageID string `json:"message_id"`
+	}
+	if err := notifierCall(n.client, n.url, n.apiKey, body, &response); err != nil {
+		return "", err
+	}
+	if response.MessageID != "" {
+		return response.MessageID, nil
+	}
+	return response.ID, nil
+}
+
+// ParseDeliveryReport reads a delivery report of the form
+// {"message_id": "...", "status": "delivered", "error": ""}
+func (n *HTTPSMSNotifier) ParseDeliveryReport(payload []byte, headers http.Header) ([]NotifierStatus, error) {
+	if !verifyHMACSignature(payload, headers.Get("X-Signature"), n.webhookSecret) {
+		return nil, ErrInvalidWebhookSignature
+	}
+
+	var report struct {
+		MessageID string `json:"message_id"`
+		Status    string `json:"status"`
+		Error     string `json:"error"`
+	}
+	if err := json.Unmarshal(payload, &report); err != nil || report.MessageID == "" {
+		return nil, errors.New("invalid delivery report")
+	}
+
+	status := strings.ToLower(report.Status)
+	switch status {
+	case "delivered", "read":
+	case "failed", "undelivered", "rejected", "expired":
+		status = "failed"
+	default:
+		return nil, nil
+	}
+	return []NotifierStatus{{ProviderMessageID: report.MessageID, Status: status, Error: report.Error}}, nil
+}
+
+// WhatsAppNotifier sends WhatsApp messages through the WhatsApp Business Cloud API. Messages
+// with a provider template are sent as that approved template, which WhatsApp requires to
+// start a conversation; others are sent as plain text.
+type WhatsAppNotifier struct {
+	baseURL       string
+	phoneNumberID string
+	accessToken   string
+	appSecret     string
+	verifyToken   string
+	client        *http.Client
+}
+
+// NewWhatsAppNotifier creates a new WhatsAppNotifier instance. The verify token is the one
+// entered when subscribing to the app's webhooks.
+func NewWhatsAppNotifier(baseURL, phoneNumberID, accessToken, appSecret, verifyToken string) *WhatsAppNotifier {
+	return &WhatsAppNotifier{
+		baseURL:       strings.TrimSuffix(baseURL, "/"),
+		phoneNumberID: phoneNumberID,
+		accessToken:   accessToken,
+		appSecret:     appSecret,
+		verifyToken:   verifyToken,
+		client:        &http.Client{Timeout: 15 * time.Second},
+	}
+}
+
+// Name returns the provider name
+func (n *WhatsAppNotifier) Name() string {
+	return "whatsapp_cloud"
+}
+
+// Send posts a message to the WhatsApp Cloud API
+func (n *WhatsAppNotifier) Send(msg NotifierMessage) (string, error) {
+	if n.phoneNumberID == "" {
+		return "", errors.New("no WhatsApp phone number configured")
+	}
+	body := map[string]interface{}{
+		"messaging_product": "whatsapp",
+		"recipient_type":    "individual",
+		"to":                strings.TrimPrefix(msg.To, "+"),
+	}
+	if msg.ProviderTemplate != "" {
+		language := msg.Language
+		if language == "" {
+			language = "en"
+		}
+		template := map[string]interface{}{
+			"name":     msg.ProviderTemplate,
+			"language": map[string]string{"code": language},
+		}
+		if len(msg.Params) > 0 {
+			parameters := make([]map[string]string, len(msg.Params))
+			for i, param := range msg.Params {
+				parameters[i] = map[string]string{"type": "text", "text": param}
+			}
+			template["components"] = []map[string]interface{}{{"type": "body", "parameters": parameters}}
+		}
+		body["type"] = "template"
+		body["template"] = template
+	} else {
+		body["type"] = "text"
+		body["text"] = map[string]interface{}{"preview_url": false, "body": msg.Body}
+	}
+
+	var response struct {
+		Messages []struct {
+			ID string `json:"id"`
+		} `json:"messages"`
+	}
+	if err := notifierCall(n.client, n.baseURL+"/"+n.phoneNumberID+"/messages", n.accessToken, body, &response); err != nil {
+		return "", err
+	}
+	if len(response.Messages) == 0 {
+		return "", errors.New("WhatsApp accepted the message without an ID")
+	}
+	return response.Messages[0].ID, nil
+}
+
+// ParseDeliveryReport verifies the X-Hub-Signature-256 header, an HMAC-SHA256 of the body with
+// the app secret, and reads the message statuses of a webhook
+func (n *WhatsAppNotifier) ParseDeliveryReport(payload []byte, headers http.Header) ([]NotifierStatus, error) {
+	if !verifyHMACSignature(payload, strings.TrimPrefix(headers.Get("X-Hub-Signature-256"), "sha256="), n.appSecret) {
+		return nil, ErrInvalidWebhookSignature
+	}
+
+	var webhook struct {
+		Entry []struct {
+			Changes []struct {
+				Value struct {
+					Statuses []struct {
+						ID     string `json:"id"`
+						Status string `json:"status"`
+						Errors []struct {
+							Title   string `json:"title"`
+							Message string `json:"message"`
+						} `json:"errors"`
+					} `json:"statuses"`
+				} `json:"value"`
+			} `json:"changes"`
+		} `json:"entry"`
+	}
+	if err := json.Unmarshal(payload, &webhook); err != nil {
+		return nil, errors.New("invalid delivery report")
+	}
+
+	var statuses []NotifierStatus
+	for _, entry := range webhook.Entry {
+		for _, change := range entry.Changes {
+			for _, status := range change.Value.Statuses {
+				if status.Status != "delivered" && status.Status != "read" && status.Status != "failed" {
+					continue
+				}
+				report := NotifierStatus{ProviderMessageID: status.ID, Status: status.Status}
+				if len(status.Errors) > 0 {
+					report.Error = status.Errors[0].Title
+					if status.Errors[0].Message != "" {
+						report.Error = status.Errors[0].Message
+					}
+				}
+				statuses = append(statuses, report)
+			}
+		}
+	}
+	return statuses, nil
+}
+
+// VerifySubscription checks the verify token Meta sends when the webhook is subscribed
+func (n *WhatsAppNotifier) VerifySubscription(mode, token string) bool {
+	return mode == "subscribe" && n.verifyToken != "" &&
+		subtle.ConstantTimeCompare([]byte(token), []byte(n.verifyToken)) == 1
+}
+
+// LogNotifier writes messages to the server log instead of sending them, for development
+type LogNotifier struct {
+	channel string
+}
+
+// NewLogNotifier creates a new LogNotifier instance for a channel
+func NewLogNotifier(channel string) *LogNotifier {
+	return &LogNotifier{channel: channel}
+}
+
+// Name returns the provider name
+func (n *LogNotifier) Name() string {
+	return "log"
+}
+
+// Send logs the message and returns a made-up message ID
+func (n *LogNotifier) Send(msg NotifierMessage) (string, error) {
+	if msg.ProviderTemplate != "" {
+		log.Printf("%s to %s (template %s %q): %s", strings.ToUpper(n.channel), msg.To, msg.ProviderTemplate, msg.Params, msg.Body)
+	} else {
+		log.Printf("%s to %s: %s", strings.ToUpper(n.channel), msg.To, msg.Body)
+	}
+	return "log-" + uuid.New().String(), nil
+}
+
+// ParseDeliveryReport is not supported, since logged messages go nowhere
+func (n *LogNotifier) ParseDeliveryReport(payload []byte, headers http.Header) ([]NotifierStatus, error) {
+	return nil, errors.New("the log provider sends no delivery reports")
+}
+
+// notifierCall posts JSON to a provider with a bearer token and decodes its response. Network
+// failures, rate limiting and server errors are reported as errNotifierUnavailable.
+func notifierCall(client *http.Client, url, token string, body, out interface{}) error {
+	content, err := json.Marshal(body)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequest(http.MethodPost, url, bytes.NewReader(content))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("%w: %v", errNotifierUnavailable, err)
+	}
+	defer resp.Body.Close()
+
+	content, err = io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return fmt.Errorf("%w: %v", errNotifierUnavailable, err)
+	}
+	if resp.StatusCode >= 300 {
+		var failure struct {
+			Error json.RawMessage `json:"error"`
+		}
+		message := fmt.Sprintf("provider returned status %d", resp.StatusCode)
+		if json.Unmarshal(content, &failure) == nil && len(failure.Error) > 0 {
+			var detail struct {
+				Message string `json:"message"`
+			}
+			var text string
+			if json.Unmarshal(failure.Error, &detail) == nil && detail.Message != "" {
+				message = detail.Message
+			} else if json.Unmarshal(failure.Error, &text) == nil && text != "" {
+				message = text
+			}
+		}
+		if resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500 {
+			return fmt.Errorf("%w: %s", errNotifierUnavailable, message)
+		}
+		return errors.New(message)
+	}
+	if out == nil {
+		return nil
+	}
+	if err := json.Unmarshal(content, out); err != nil {
+		return errors.New("invalid response from notification provider")
+	}
+	return nil
+}
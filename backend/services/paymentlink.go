@@ -0,0 +1,332 @@
+package services
+
+import (
+	"billboard/backend/models"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// defaultPaymentLinkExpiry is how long payment links stay payable unless asked otherwise
+const defaultPaymentLinkExpiry = 7 * 24 * time.Hour
+
+// errPaymentLinksDisabled is returned when no payment gateway has been configured
+var errPaymentLinksDisabled = errors.New("payment links are not configured; set PAYMENT_GATEWAY to enable them")
+
+// PaymentLinkService creates payment links for bills and records the payments their provider
+// reports through webhooks
+type PaymentLinkService struct {
+	db      *gorm.DB
+	gateway PaymentGateway
+}
+
+// NewPaymentLinkService creates a new PaymentLinkService instance
+func NewPaymentLinkService(db *gorm.DB, gateway PaymentGateway) *PaymentLinkService {
+	return &PaymentLinkService{db: db, gateway: gateway}
+}
+
+// CreatePaymentLink creates a link for the bill's balance. An unexpired link for the same
+// amount is returned as it is; older links for other amounts are cancelled. A link for a draft
+// bill issues it, since its amount must not change once the customer can pay it.
+func (s *PaymentLinkService) CreatePaymentLink(billID, shopID, userID uuid.UUID, req models.PaymentLinkRequest) (*models.PaymentLinkResponse, error) {
+	// Check if user has access to the shop
+	var shopUser models.ShopUser
+	if err := s.db.Where("shop_id = ? AND user_id = ? AND is_active = ?", shopID, userID, true).First(&shopUser).Error; err != nil {
+		return nil, errors.New("access denied to shop")
+	}
+	if s.gateway == nil {
+		return nil, errPaymentLinksDisabled
+	}
+
+	var bill models.Bill
+	if err := s.db.Preload("Shop").Preload("Customer").
+		Where("id = ? AND shop_id = ? AND deleted_at IS NULL", billID, shopID).First(&bill).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("bill not found")
+		}
+		return nil, err
+	}
+	if bill.Status == "cancelled" {
+		return nil, errors.New("cannot create a payment link for a cancelled bill")
+	}
+	amount := roundAmount(bill.Balance)
+	if amount <= 0 {
+		return nil, errors.New("bill has no balance to pay")
+	}
+
+	var active []models.PaymentLink
+	if err := s.db.Where("bill_id = ? AND status = ?", bill.ID, "created").Find(&active).Error; err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	for _, link := range active {
+		if link.Amount == amount && link.Provider == s.gateway.Name() && (link.ExpiresAt == nil || link.ExpiresAt.After(now)) {
+			response := paymentLinkToResponse(link)
+			return &response, nil
+		}
+	}
+	for _, link := range active {
+		if err := s.cancelLink(link); err != nil {
+			return nil, err
+		}
+	}
+
+	expiry := defaultPaymentLinkExpiry
+	if req.ExpiresInHours > 0 {
+		expiry = time.Duration(req.ExpiresInHours) * time.Hour
+	}
+	expiresAt := now.Add(expiry)
+
+	// The ID is chosen up front so the provider can echo it back as the reference
+	link := models.PaymentLink{
+		ID:        uuid.New(),
+		ShopID:    shopID,
+		BillID:    bill.ID,
+		Provider:  s.gateway.Name(),
+		Amount:    amount,
+		Status:    "created",
+		ExpiresAt: &expiresAt,
+		CreatedBy: userID.String(),
+	}
+	gatewayReq := GatewayLinkRequest{
+		Amount:      amount,
+		Reference:   link.ID.String(),
+		Description: fmt.Sprintf("%s bill %s", bill.Shop.Name, bill.BillNumber),
+		ExpiresAt:   expiresAt,
+	}
+	if bill.Customer != nil {
+		gatewayReq.CustomerName = bill.Customer.Name
+		gatewayReq.CustomerEmail = bill.Customer.Email
+		gatewayReq.CustomerPhone = bill.Customer.Phone
+	}
+
+	created, err := s.gateway.CreatePaymentLink(gatewayReq)
+	if err != nil {
+		return nil, err
+	}
+	link.ProviderLinkID = created.ProviderLinkID
+	link.URL = created.URL
+
+	if err := s.db.Create(&link).Error; err != nil {
+		return nil, err
+	}
+	if err := issueBill(s.db, &bill); err != nil {
+		return nil, err
+	}
+
+	response := paymentLinkToResponse(link)
+	return &response, nil
+}
+
+// GetPaymentLinks lists the payment links of a bill, most recent first
+func (s *PaymentLinkService) GetPaymentLinks(billID, shopID, userID uuid.UUID) ([]models.PaymentLinkResponse, error) {
+	// Check if user has access to the shop
+	var shopUser models.ShopUser
+	if err := s.db.Where("shop_id = ? AND user_id = ? AND is_active = ?", shopID, userID, true).First(&shopUser).Error; err != nil {
+		return nil, errors.New("access denied to shop")
+	}
+
+	var links []models.PaymentLink
+	if err := s.db.Where("bill_id = ? AND shop_id = ?", billID, shopID).Order("created_at DESC").Find(&links).Error; err != nil {
+		return nil, err
+	}
+
+	responses := make([]models.PaymentLinkResponse, len(links))
+	for i, link := range links {
+		responses[i] = paymentLinkToResponse(link)
+	}
+	return responses, nil
+}
+
+// CancelPaymentLink cancels an unpaid payment link with the provider
+func (s *PaymentLinkService) CancelPaymentLink(linkID, billID, shopID, userID uuid.UUID) (*models.PaymentLinkResponse, error) {
+	// Check if user has access to the shop
+	var shopUser models.ShopUser
+	if err := s.db.Where("shop_id = ? AND user_id = ? AND is_active = ?", shopID, userID, true).First(&shopUser).Error; err != nil {
+		return nil, errors.New("access denied to shop")
+	}
+
+	var link models.PaymentLink
+	if err := s.db.Where("id = ? AND bill_id = ? AND shop_id = ?", linkID, billID, shopID).First(&link).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("payment link not found")
+		}
+		return nil, err
+	}
+	if link.Status != "created" {
+		return nil, fmt.Errorf("cannot cancel a %s payment link", link.Status)
+	}
+
+	if err := s.cancelLink(link); err != nil {
+		return nil, err
+	}
+	link.Status = "cancelled"
+
+	response := paymentLinkToResponse(link)
+	return &response, nil
+}
+
+// HandleWebhook verifies and records a webhook from a payment provider. Events are recorded by
+// their ID, so redelivered events are acknowledged without being acted on again. Paid links
+// record a payment on their bill.
+func (s *PaymentLinkService) HandleWebhook(provider string, payload []byte, headers http.Header) error {
+	if s.gateway == nil || provider != s.gateway.Name() {
+		return errors.New("unknown payment provider")
+	}
+
+	event, err := s.gateway.ParseWebhook(payload, headers)
+	if err != nil {
+		return err
+	}
+	if event.EventID == "" {
+		return errors.New("webhook event has no ID")
+	}
+
+	record := models.PaymentWebhookEvent{
+		Provider:       provider,
+		EventID:        event.EventID,
+		EventType:      event.Type,
+		ProviderLinkID: event.ProviderLinkID,
+		Status:         "received",
+	}
+	if err := s.db.Create(&record).Error; err != nil {
+		// The unique index on provider and event ID rejects redeliveries
+		var existing models.PaymentWebhookEvent
+		if findErr := s.db.Where("provider = ? AND event_id = ?", provider, event.EventID).First(&existing).Error; findErr != nil {
+			return err
+		}
+		if existing.Status != "failed" {
+			return nil
+		}
+		record = existing
+	}
+
+	status, processErr := s.applyEvent(*event)
+	updates := map[string]interface{}{"status": status, "error": "", "updated_at": time.Now()}
+	if processErr != nil {
+		updates["error"] = processErr.Error()
+	}
+	if err := s.db.Model(&record).Updates(updates).Error; err != nil {
+		return err
+	}
+	return processErr
+}
+
+// applyEvent acts on a webhook event and returns the status to record for it
+func (s *PaymentLinkService) applyEvent(event GatewayEvent) (string, error) {
+	var link models.PaymentLink
+	if err := s.db.Where("provider = ? AND provider_link_id = ?", s.gateway.Name(), event.ProviderLinkID).First(&link).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return "ignored", nil
+		}
+		return "failed", err
+	}
+
+	switch event.Type {
+	case "paid":
+		return s.applyPaid(link, event)
+	case "expired", "cancelled":
+		result := s.db.Model(&models.PaymentLink{}).Where("id = ? AND status = ?", link.ID, "created").
+			Updates(map[string]interface{}{"status": event.Type, "updated_at": time.Now()})
+		if result.Error != nil {
+			return "failed", result.Error
+		}
+		return "processed", nil
+	}
+	return "ignored", nil
+}
+
+// applyPaid records the payment of a paid link on its bill. Links are paid in full, so the
+// amount recorded is the link's own rather than the one the webhook carries.
+func (s *PaymentLinkService) applyPaid(link models.PaymentLink, event GatewayEvent) (string, error) {
+	if event.Amount != 0 && roundAmount(event.Amount) != link.Amount {
+		log.Printf("Payment link %s reported paid %.2f, expected %.2f", link.ID, event.Amount, link.Amount)
+	}
+
+	createdBy, err := uuid.Parse(link.CreatedBy)
+	if err != nil {
+		return "failed", errors.New("payment link has no creator")
+	}
+	method := event.Method
+	if method == "" {
+		method = "other"
+	}
+	reference := event.ProviderPaymentID
+	if reference == "" {
+		reference = link.ProviderLinkID
+	}
+
+	// Claim the link so two events about the same payment cannot both record it. A link can be
+	// paid after it was cancelled or expired on our side; the money was still received, so it
+	// is recorded all the same.
+	now := time.Now()
+	claimed := true
+	err = s.db.Transaction(func(tx *gorm.DB) error {
+		claim := tx.Model(&models.PaymentLink{}).Where("id = ? AND status <> ?", link.ID, "paid").
+			Updates(map[string]interface{}{"status": "paid", "paid_at": now, "updated_at": now})
+		if claim.Error != nil {
+			return claim.Error
+		}
+		if claim.RowsAffected == 0 {
+			claimed = false
+			return nil
+		}
+
+		payment, err := (&BillService{db: tx}).recordPayment(link.BillID, link.ShopID, createdBy, models.PaymentRequest{
+			Amount:        link.Amount,
+			PaymentDate:   now.Format("2006-01-02"),
+			PaymentMethod: method,
+			Reference:     reference,
+			Notes:         fmt.Sprintf("Paid online through %s payment link", link.Provider),
+		}, false)
+		if err != nil {
+			return err
+		}
+
+		return tx.Model(&models.PaymentLink{}).Where("id = ?", link.ID).Updates(map[string]interface{}{
+			"provider_payment_id": event.ProviderPaymentID,
+			"payment_id":          payment.ID,
+		}).Error
+	})
+	if err != nil {
+		return "failed", err
+	}
+	if !claimed {
+		return "ignored", nil
+	}
+	return "processed", nil
+}
+
+// cancelLink cancels a link with its provider and marks it cancelled
+func (s *PaymentLinkService) cancelLink(link models.PaymentLink) error {
+	if s.gateway != nil && link.Provider == s.gateway.Name() {
+		if err := s.gateway.CancelPaymentLink(link.ProviderLinkID); err != nil {
+			return err
+		}
+	}
+	return s.db.Model(&models.PaymentLink{}).Where("id = ?", link.ID).
+		Updates(map[string]interface{}{"status": "cancelled", "updated_at": time.Now()}).Error
+}
+
+// paymentLinkToResponse converts a payment link to its response
+func paymentLinkToResponse(link models.PaymentLink) models.PaymentLinkResponse {
+	return models.PaymentLinkResponse{
+		ID:                link.ID,
+		BillID:            link.BillID,
+		Provider:          link.Provider,
+		URL:               link.URL,
+		Amount:            link.Amount,
+		Status:            link.Status,
+		ExpiresAt:         link.ExpiresAt,
+		PaidAt:            link.PaidAt,
+		ProviderPaymentID: link.ProviderPaymentID,
+		PaymentID:         link.PaymentID,
+		CreatedBy:         link.CreatedBy,
+		CreatedAt:         link.CreatedAt,
+	}
+}
@@ -0,0 +1,214 @@
+package services
+
+import (
+	"billboard/backend/database"
+	"billboard/backend/models"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+const testWebhookSecret = "test-webhook-secret"
+
+// openTestDB connects to the database in TEST_DATABASE_URL, migrating it as the server does.
+// Tests that need a database are skipped without one.
+func openTestDB(t *testing.T) *gorm.DB {
+	t.Helper()
+	url := os.Getenv("TEST_DATABASE_URL")
+	if url == "" {
+		t.Skip("TEST_DATABASE_URL is not set")
+	}
+	db, err := database.Initialize(url)
+	if err != nil {
+		t.Fatalf("connect to test database: %v", err)
+	}
+	return db
+}
+
+// createTestBill creates a user, a shop they own and a sent bill of 1000 with nothing paid
+func createTestBill(t *testing.T, db *gorm.DB) (models.Bill, uuid.UUID) {
+	t.Helper()
+	suffix := uuid.NewString()
+
+	user := models.User{Email: suffix + "@example.com", Password: "x", FirstName: "Test", LastName: "User"}
+	if err := db.Create(&user).Error; err != nil {
+		t.Fatal(err)
+	}
+	shop := models.Shop{Name: "Test Shop"}
+	if err := db.Create(&shop).Error; err != nil {
+		t.Fatal(err)
+	}
+	if err := db.Create(&models.ShopUser{ShopID: shop.ID, UserID: user.ID, Role: "owner", IsActive: true}).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	bill := models.Bill{
+		ShopID:        shop.ID,
+		BillNumber:    "TEST-" + suffix,
+		BillDate:      time.Now(),
+		SubTotal:      1000,
+		TaxableValue:  1000,
+		TotalAmount:   1000,
+		PendingAmount: 1000,
+		Balance:       1000,
+		Status:        "sent",
+		CreatedBy:     user.ID.String(),
+	}
+	if err := db.Create(&bill).Error; err != nil {
+		t.Fatal(err)
+	}
+	return bill, user.ID
+}
+
+// signedFakeEvent signs an event the way the fake provider does
+func signedFakeEvent(t *testing.T, event GatewayEvent, secret string) ([]byte, http.Header) {
+	t.Helper()
+	payload, err := json.Marshal(event)
+	if err != nil {
+		t.Fatal(err)
+	}
+	headers := http.Header{}
+	headers.Set("X-Signature", hmacSHA256Hex(payload, secret))
+	return payload, headers
+}
+
+func TestPaymentWebhookRejectsBadSignature(t *testing.T) {
+	gateway := NewFakePaymentGateway(testWebhookSecret)
+	link, err := gateway.CreatePaymentLink(GatewayLinkRequest{Amount: 1000, ExpiresAt: time.Now().Add(time.Hour)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	payload, headers, err := gateway.PaidEvent("evt_1", link.ProviderLinkID, "upi")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Signatures are checked before anything is read or written, so no database is needed
+	service := NewPaymentLinkService(nil, gateway)
+
+	forged, forgedHeaders := signedFakeEvent(t, GatewayEvent{EventID: "evt_2", Type: "paid", ProviderLinkID: link.ProviderLinkID}, "guessed-secret")
+	tampered := []byte(string(payload[:len(payload)-1]) + " ")
+
+	cases := []struct {
+		name    string
+		payload []byte
+		headers http.Header
+	}{
+		{"wrong secret", forged, forgedHeaders},
+		{"tampered payload", tampered, headers},
+		{"missing signature", payload, http.Header{}},
+	}
+	for _, c := range cases {
+		if err := service.HandleWebhook("fake", c.payload, c.headers); !errors.Is(err, ErrInvalidWebhookSignature) {
+			t.Errorf("%s: got %v, want %v", c.name, err, ErrInvalidWebhookSignature)
+		}
+	}
+
+	if err := service.HandleWebhook("razorpay", payload, headers); err == nil {
+		t.Error("webhook for another provider was accepted")
+	}
+	if err := NewPaymentLinkService(nil, nil).HandleWebhook("fake", payload, headers); err == nil {
+		t.Error("webhook was accepted with payment links disabled")
+	}
+}
+
+func TestPaymentWebhookRecordsPaymentOnce(t *testing.T) {
+	db := openTestDB(t)
+	bill, userID := createTestBill(t, db)
+	gateway := NewFakePaymentGateway(testWebhookSecret)
+	service := NewPaymentLinkService(db, gateway)
+
+	link, err := service.CreatePaymentLink(bill.ID, bill.ShopID, userID, models.PaymentLinkRequest{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var providerLinkID string
+	if err := db.Model(&models.PaymentLink{}).Where("id = ?", link.ID).Select("provider_link_id").Scan(&providerLinkID).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	eventID := "evt_" + uuid.NewString()
+	payload, headers, err := gateway.PaidEvent(eventID, providerLinkID, "upi")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The provider redelivers the same event
+	for i := 0; i < 2; i++ {
+		if err := service.HandleWebhook("fake", payload, headers); err != nil {
+			t.Fatalf("delivery %d: %v", i+1, err)
+		}
+	}
+	// A second event about the same payment must not record it again
+	payload, headers, err = gateway.PaidEvent("evt_"+uuid.NewString(), providerLinkID, "upi")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := service.HandleWebhook("fake", payload, headers); err != nil {
+		t.Fatal(err)
+	}
+
+	var payments []models.Payment
+	if err := db.Where("bill_id = ?", bill.ID).Find(&payments).Error; err != nil {
+		t.Fatal(err)
+	}
+	if len(payments) != 1 {
+		t.Fatalf("got %d payments, want 1", len(payments))
+	}
+	if payments[0].Amount != 1000 || payments[0].PaymentMethod != "upi" {
+		t.Errorf("got payment of %.2f by %s, want 1000.00 by upi", payments[0].Amount, payments[0].PaymentMethod)
+	}
+
+	var events int64
+	db.Model(&models.PaymentWebhookEvent{}).Where("provider = ? AND event_id = ?", "fake", eventID).Count(&events)
+	if events != 1 {
+		t.Errorf("got %d records of the event, want 1", events)
+	}
+
+	if err := db.First(&bill, "id = ?", bill.ID).Error; err != nil {
+		t.Fatal(err)
+	}
+	if bill.Status != "paid" || bill.Balance != 0 {
+		t.Errorf("bill is %s with balance %.2f, want paid with balance 0", bill.Status, bill.Balance)
+	}
+}
+
+func TestPaymentWebhookRecordsLinkAmount(t *testing.T) {
+	db := openTestDB(t)
+	bill, userID := createTestBill(t, db)
+	gateway := NewFakePaymentGateway(testWebhookSecret)
+	service := NewPaymentLinkService(db, gateway)
+
+	link, err := service.CreatePaymentLink(bill.ID, bill.ShopID, userID, models.PaymentLinkRequest{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var providerLinkID string
+	if err := db.Model(&models.PaymentLink{}).Where("id = ?", link.ID).Select("provider_link_id").Scan(&providerLinkID).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	payload, headers := signedFakeEvent(t, GatewayEvent{
+		EventID:        "evt_" + uuid.NewString(),
+		Type:           "paid",
+		ProviderLinkID: providerLinkID,
+		Amount:         5000,
+		Method:         "card",
+	}, testWebhookSecret)
+	if err := service.HandleWebhook("fake", payload, headers); err != nil {
+		t.Fatal(err)
+	}
+
+	var payment models.Payment
+	if err := db.Where("bill_id = ?", bill.ID).First(&payment).Error; err != nil {
+		t.Fatal(err)
+	}
+	if payment.Amount != link.Amount {
+		t.Errorf("recorded %.2f, want the link amount %.2f", payment.Amount, link.Amount)
+	}
+}
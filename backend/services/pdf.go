@@ -0,0 +1,512 @@
+package services
+
+import (
+	"billboard/backend/models"
+	"bytes"
+	"fmt"
+	"sort"
+
+	"github.com/jung-kurt/gofpdf"
+)
+
+// PDFService renders printable documents
+type PDFService struct{}
+
+// NewPDFService creates a new PDFService instance
+func NewPDFService() *PDFService {
+	return &PDFService{}
+}
+
+const (
+	pdfPageWidth = 210.0
+	pdfMargin    = 15.0
+	pdfBodyWidth = pdfPageWidth - 2*pdfMargin
+)
+
+// newDocument starts an A4 document with the shop name and document title as header
+func (s *PDFService) newDocument(shopName, title string) *gofpdf.Fpdf {
+	pdf := gofpdf.New("P", "mm", "A4", "")
+	pdf.SetMargins(pdfMargin, pdfMargin, pdfMargin)
+	pdf.SetAutoPageBreak(true, pdfMargin)
+	pdf.AliasNbPages("")
+	pdf.SetFooterFunc(func() {
+		pdf.SetY(-pdfMargin + 5)
+		pdf.SetFont("Helvetica", "I", 8)
+		pdf.CellFormat(0, 5, fmt.Sprintf("Page %d of {nb}", pdf.PageNo()), "", 0, "C", false, 0, "")
+	})
+	pdf.AddPage()
+
+	pdf.SetFont("Helvetica", "B", 16)
+	pdf.CellFormat(pdfBodyWidth, 8, pdfText(shopName), "", 1, "L", false, 0, "")
+	pdf.SetFont("Helvetica", "B", 12)
+	pdf.CellFormat(pdfBodyWidth, 7, pdfText(title), "", 1, "L", false, 0, "")
+	pdf.Ln(3)
+
+	return pdf
+}
+
+// keyValueRows prints label/value pairs as a two column list
+func (s *PDFService) keyValueRows(pdf *gofpdf.Fpdf, rows [][2]string) {
+	for _, row := range rows {
+		pdf.SetFont("Helvetica", "", 10)
+		pdf.CellFormat(pdfBodyWidth*0.6, 6, pdfText(row[0]), "", 0, "L", false, 0, "")
+		pdf.SetFont("Helvetica", "B", 10)
+		pdf.CellFormat(pdfBodyWidth*0.4, 6, pdfText(row[1]), "", 1, "R", false, 0, "")
+	}
+}
+
+// table prints a table with a shaded header row; widths are fractions of the body width
+func (s *PDFService) table(pdf *gofpdf.Fpdf, headers []string, widths []float64, aligns []string, rows [][]string) {
+	pdf.SetFont("Helvetica", "B", 9)
+	pdf.SetFillColor(230, 230, 230)
+	for i, header := range headers {
+		pdf.CellFormat(pdfBodyWidth*widths[i], 7, pdfText(header), "1", 0, aligns[i], true, 0, "")
+	}
+	pdf.Ln(-1)
+
+	pdf.SetFont("Helvetica", "", 9)
+	for _, row := range rows {
+		for i, value := range row {
+			pdf.CellFormat(pdfBodyWidth*widths[i], 6, pdfText(value), "1", 0, aligns[i], false, 0, "")
+		}
+		pdf.Ln(-1)
+	}
+}
+
+// sectionTitle prints a bold heading followed by a little spacing
+func (s *PDFService) sectionTitle(pdf *gofpdf.Fpdf, title string) {
+	pdf.Ln(3)
+	pdf.SetFont("Helvetica", "B", 11)
+	pdf.CellFormat(pdfBodyWidth, 7, pdfText(title), "B", 1, "L", false, 0, "")
+	pdf.Ln(1)
+}
+
+// output serializes the document
+func (s *PDFService) output(pdf *gofpdf.Fpdf) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := pdf.Output(&buf); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// pdfText converts UTF-8 text to the single-byte encoding used by the core PDF fonts,
+// replacing characters that cannot be represented
+func pdfText(text string) string {
+	encoded := make([]byte, 0, len(text))
+	for _, r := range text {
+		if r < 256 {
+			encoded = append(encoded, byte(r))
+		} else {
+			encoded = append(encoded, '?')
+		}
+	}
+	return string(encoded)
+}
+
+// formatAmount formats a currency amount with two decimals
+func formatAmount(amount float64) string {
+	return fmt.Sprintf("%.2f", amount)
+}
+
+// RenderRegisterReport renders an X or Z report for a register session
+func (s *PDFService) RenderRegisterReport(report models.RegisterReport) ([]byte, error) {
+	title := "X Report (mid-shift)"
+	if report.ReportType == "Z" {
+		title = "Z Report (end of day)"
+	}
+	pdf := s.newDocument(report.ShopName, title)
+
+	session := report.Session
+	closedAt := "-"
+	if session.ClosedAt != nil {
+		closedAt = session.ClosedAt.Format("2006-01-02 15:04")
+	}
+	s.keyValueRows(pdf, [][2]string{
+		{"Register", session.RegisterName},
+		{"Opened at", session.OpenedAt.Format("2006-01-02 15:04")},
+		{"Closed at", closedAt},
+		{"Generated at", report.GeneratedAt.Format("2006-01-02 15:04")},
+	})
+
+	s.sectionTitle(pdf, "Sales by payment method")
+	var rows [][]string
+	for _, total := range report.SalesByMethod {
+		rows = append(rows, []string{total.PaymentMethod, fmt.Sprintf("%d", total.Count), formatAmount(total.Amount)})
+	}
+	rows = append(rows, []string{"Total", fmt.Sprintf("%d", report.PaymentCount), formatAmount(report.TotalSales)})
+	s.table(pdf, []string{"Method", "Payments", "Amount"}, []float64{0.5, 0.2, 0.3}, []string{"L", "R", "R"}, rows)
+	if report.PointsRedeemed > 0 {
+		s.keyValueRows(pdf, [][2]string{{"Loyalty points redeemed", formatAmount(report.PointsRedeemed)}})
+	}
+
+	s.sectionTitle(pdf, "Refunds")
+	rows = nil
+	for _, total := range report.RefundsByMethod {
+		rows = append(rows, []string{total.PaymentMethod, fmt.Sprintf("%d", total.Count), formatAmount(total.Amount)})
+	}
+	rows = append(rows, []string{"Total", "", formatAmount(report.TotalRefunds)})
+	s.table(pdf, []string{"Method", "Refunds", "Amount"}, []float64{0.5, 0.2, 0.3}, []string{"L", "R", "R"}, rows)
+
+	s.sectionTitle(pdf, "Cash drawer")
+	counted := "-"
+	if report.CountedCash != nil {
+		counted = formatAmount(*report.CountedCash)
+	}
+	s.keyValueRows(pdf, [][2]string{
+		{"Opening float", formatAmount(report.OpeningFloat)},
+		{"Cash sales", formatAmount(report.CashSales)},
+		{"Cash in", formatAmount(report.CashIn)},
+		{"Cash out", formatAmount(-report.CashOut)},
+		{"Cash refunds", formatAmount(-report.CashRefunds)},
+		{"Expected cash", formatAmount(report.ExpectedCash)},
+		{"Counted cash", counted},
+		{"Variance", formatAmount(report.Variance)},
+	})
+
+	return s.output(pdf)
+}
+
+// documentLine is a line item on an invoice or quotation
+type documentLine struct {
+	Name        string
+	Description string
+	Quantity    int
+	UnitPrice   float64
+	Total       float64
+
+	// Tax breakdown, used on invoices
+	Discount     float64
+	TaxableValue float64
+	TaxRate      float64
+	TaxAmount    float64
+}
+
+// commercialDocument holds the parts shared by invoices and quotations
+type commercialDocument struct {
+	Title      string
+	Shop       models.Shop
+	Customer   *models.Customer
+	Details    [][2]string
+	Lines      []documentLine
+	SubTotal   float64
+	Deductions [][2]string
+	// TaxBreakdown shows discounts, taxable value and tax per line and rate instead of a single bill rate
+	TaxBreakdown bool
+	TaxableValue float64
+	TaxRate      float64
+	TaxAmount    float64
+	Discount     float64
+	Total        float64
+	Summary      [][2]string
+	Notes        string
+	Terms        string
+	// QRCode is a PNG printed in the top right corner, such as the e-invoice signed QR code
+	QRCode []byte
+	// PaymentQRCode is a PNG printed below the totals with PaymentCaption beside it, such as
+	// a UPI QR code for the balance
+	PaymentQRCode  []byte
+	PaymentCaption string
+}
+
+// renderCommercialDocument lays out an invoice or quotation with parties, lines and totals
+func (s *PDFService) renderCommercialDocument(doc commercialDocument) *gofpdf.Fpdf {
+	pdf := s.newDocument(doc.Shop.Name, doc.Title)
+
+	if doc.QRCode != nil {
+		const qrSize = 32.0
+		pdf.RegisterImageOptionsReader("document-qr", gofpdf.ImageOptions{ImageType: "PNG"}, bytes.NewReader(doc.QRCode))
+		pdf.ImageOptions("document-qr", pdfMargin+pdfBodyWidth-qrSize, pdfMargin, qrSize, qrSize, false, gofpdf.ImageOptions{ImageType: "PNG"}, 0, "")
+	}
+
+	// Seller and buyer
+	pdf.SetFont("Helvetica", "", 9)
+	seller := []string{doc.Shop.Address, doc.Shop.Phone, doc.Shop.Email}
+	if doc.Shop.GSTNumber != "" {
+		seller = append(seller, "GSTIN: "+doc.Shop.GSTNumber)
+	}
+	for _, line := range seller {
+		if line != "" {
+			pdf.CellFormat(pdfBodyWidth, 5, pdfText(line), "", 1, "L", false, 0, "")
+		}
+	}
+
+	if doc.Customer != nil {
+		s.sectionTitle(pdf, "Bill to")
+		pdf.SetFont("Helvetica", "B", 10)
+		pdf.CellFormat(pdfBodyWidth, 5, pdfText(doc.Customer.Name), "", 1, "L", false, 0, "")
+		pdf.SetFont("Helvetica", "", 9)
+		for _, line := range []string{doc.Customer.Address, doc.Customer.Phone, doc.Customer.Email} {
+			if line != "" {
+				pdf.CellFormat(pdfBodyWidth, 5, pdfText(line), "", 1, "L", false, 0, "")
+			}
+		}
+	}
+
+	pdf.Ln(2)
+	s.keyValueRows(pdf, doc.Details)
+
+	// Line items
+	s.sectionTitle(pdf, "Items")
+	var rows [][]string
+	for i, line := range doc.Lines {
+		name := line.Name
+		if line.Description != "" {
+			name += " - " + line.Description
+		}
+		if doc.TaxBreakdown {
+			rows = append(rows, []string{
+				fmt.Sprintf("%d", i+1),
+				name,
+				fmt.Sprintf("%d", line.Quantity),
+				formatAmount(line.UnitPrice),
+				formatAmount(line.Discount),
+				formatAmount(line.TaxableValue),
+				fmt.Sprintf("%.2f%%", line.TaxRate),
+				formatAmount(line.TaxAmount),
+				formatAmount(line.TaxableValue + line.TaxAmount),
+			})
+		} else {
+			rows = append(rows, []string{
+				fmt.Sprintf("%d", i+1),
+				name,
+				fmt.Sprintf("%d", line.Quantity),
+				formatAmount(line.UnitPrice),
+				formatAmount(line.Total),
+			})
+		}
+	}
+	if doc.TaxBreakdown {
+		s.table(pdf, []string{"#", "Item", "Qty", "Rate", "Disc.", "Taxable", "Tax %", "Tax", "Amount"},
+			[]float64{0.05, 0.27, 0.07, 0.1, 0.09, 0.12, 0.08, 0.1, 0.12},
+			[]string{"C", "L", "R", "R", "R", "R", "R", "R", "R"}, rows)
+	} else {
+		s.table(pdf, []string{"#", "Item", "Qty", "Unit price", "Amount"},
+			[]float64{0.06, 0.5, 0.1, 0.16, 0.18}, []string{"C", "L", "R", "R", "R"}, rows)
+	}
+
+	// Totals
+	pdf.Ln(3)
+	totals := [][2]string{{"Subtotal", formatAmount(doc.SubTotal)}}
+	totals = append(totals, doc.Deductions...)
+	if doc.TaxBreakdown {
+		totals = append(totals, [2]string{"Taxable value", formatAmount(doc.TaxableValue)})
+		totals = append(totals, [2]string{"Tax", formatAmount(doc.TaxAmount)})
+	} else if doc.TaxAmount > 0 {
+		totals = append(totals, [2]string{fmt.Sprintf("Tax (%.2f%%)", doc.TaxRate), formatAmount(doc.TaxAmount)})
+	}
+	if doc.Discount > 0 {
+		totals = append(totals, [2]string{"Discount", "-" + formatAmount(doc.Discount)})
+	}
+	totals = append(totals, [2]string{"Total", formatAmount(doc.Total)})
+	totals = append(totals, doc.Summary...)
+	s.keyValueRows(pdf, totals)
+
+	if doc.PaymentQRCode != nil {
+		const qrSize = 30.0
+		pdf.Ln(3)
+		_, pageHeight := pdf.GetPageSize()
+		if pdf.GetY()+qrSize > pageHeight-pdfMargin {
+			pdf.AddPage()
+		}
+		top := pdf.GetY()
+		pdf.RegisterImageOptionsReader("payment-qr", gofpdf.ImageOptions{ImageType: "PNG"}, bytes.NewReader(doc.PaymentQRCode))
+		pdf.ImageOptions("payment-qr", pdfMargin, top, qrSize, qrSize, false, gofpdf.ImageOptions{ImageType: "PNG"}, 0, "")
+		pdf.SetXY(pdfMargin+qrSize+4, top+qrSize/2-5)
+		pdf.SetFont("Helvetica", "", 9)
+		pdf.MultiCell(pdfBodyWidth-qrSize-4, 5, pdfText(doc.PaymentCaption), "", "L", false)
+		pdf.SetXY(pdfMargin, top+qrSize)
+	}
+
+	if doc.TaxBreakdown && doc.TaxAmount > 0 {
+		s.sectionTitle(pdf, "Tax summary")
+		s.table(pdf, []string{"Tax rate", "Taxable value", "Tax"}, []float64{0.4, 0.3, 0.3}, []string{"L", "R", "R"}, taxSummaryRows(doc.Lines))
+	}
+
+	if doc.Notes != "" {
+		s.sectionTitle(pdf, "Notes")
+		pdf.SetFont("Helvetica", "", 9)
+		pdf.MultiCell(pdfBodyWidth, 5, pdfText(doc.Notes), "", "L", false)
+	}
+	if doc.Terms != "" {
+		s.sectionTitle(pdf, "Terms")
+		pdf.SetFont("Helvetica", "", 9)
+		pdf.MultiCell(pdfBodyWidth, 5, pdfText(doc.Terms), "", "L", false)
+	}
+
+	return pdf
+}
+
+// RenderBill renders a bill as an A4 tax invoice
+func (s *PDFService) RenderBill(bill models.Bill) ([]byte, error) {
+	details := [][2]string{
+		{"Invoice number", bill.BillNumber},
+		{"Invoice date", bill.BillDate.Format("2006-01-02")},
+	}
+	if bill.DueDate != nil {
+		details = append(details, [2]string{"Due date", bill.DueDate.Format("2006-01-02")})
+	}
+
+	// Registered e-invoices carry the IRN, acknowledgement and the IRP's signed QR code
+	var qrCode []byte
+	switch bill.IRNStatus {
+	case "generated":
+		details = append(details, [2]string{"IRN", bill.IRN}, [2]string{"Ack no", bill.IRNAckNo})
+		if bill.IRNAckDate != nil {
+			details = append(details, [2]string{"Ack date", bill.IRNAckDate.Format("2006-01-02 15:04")})
+		}
+		if bill.IRNSignedQR != "" {
+			png, err := qrCodePNG(bill.IRNSignedQR, 400)
+			if err != nil {
+				return nil, err
+			}
+			qrCode = png
+		}
+	case "cancelled":
+		details = append(details, [2]string{"E-invoice", "Cancelled"})
+	}
+
+	// An unpaid balance can be paid by scanning a UPI QR code
+	var paymentQRCode []byte
+	var paymentCaption string
+	if intent, ok := billUPIIntent(bill); ok {
+		png, err := qrCodePNG(intent, 400)
+		if err != nil {
+			return nil, err
+		}
+		paymentQRCode = png
+		paymentCaption = fmt.Sprintf("Scan with any UPI app to pay the balance of %s to %s", formatAmount(bill.Balance), bill.Shop.UPIID)
+	}
+
+	var lines []documentLine
+	for _, item := range bill.Items {
+		lines = append(lines, documentLine{
+			Name:        item.ItemName,
+			Description: item.Description,
+			Quantity:    item.Quantity,
+			UnitPrice:   item.UnitPrice,
+			Total:       item.TotalPrice,
+
+			Discount:     item.DiscountAmount + item.PromotionDiscount,
+			TaxableValue: item.TaxableValue,
+			TaxRate:      item.TaxRate,
+			TaxAmount:    item.TaxAmount,
+		})
+	}
+
+	var deductions [][2]string
+	if bill.LineDiscount > 0 {
+		deductions = append(deductions, [2]string{"Line discounts", "-" + formatAmount(bill.LineDiscount)})
+	}
+	for _, promotion := range bill.Promotions {
+		label := "Promotion: " + promotion.PromotionName
+		if promotion.CouponCode != "" {
+			label += " (" + promotion.CouponCode + ")"
+		}
+		deductions = append(deductions, [2]string{label, "-" + formatAmount(promotion.Amount)})
+	}
+
+	// Late fees are charged on top of the invoice total
+	var summary [][2]string
+	if bill.LateFeeAmount > 0 {
+		summary = append(summary, [2]string{"Late fees", formatAmount(bill.LateFeeAmount)})
+	}
+	summary = append(summary,
+		[2]string{"Paid", formatAmount(bill.PaidAmount)},
+		[2]string{"Balance due", formatAmount(bill.Balance)},
+	)
+
+	pdf := s.renderCommercialDocument(commercialDocument{
+		Title:          "Tax Invoice",
+		Shop:           bill.Shop,
+		Customer:       bill.Customer,
+		Details:        details,
+		Lines:          lines,
+		SubTotal:       bill.SubTotal,
+		Deductions:     deductions,
+		TaxBreakdown:   true,
+		TaxableValue:   bill.TaxableValue,
+		TaxAmount:      bill.TaxAmount,
+		Discount:       bill.Discount,
+		Total:          bill.TotalAmount,
+		Summary:        summary,
+		Notes:          bill.Notes,
+		Terms:          bill.Terms,
+		QRCode:         qrCode,
+		PaymentQRCode:  paymentQRCode,
+		PaymentCaption: paymentCaption,
+	})
+
+	return s.output(pdf)
+}
+
+// RenderQuotation renders a quotation as an A4 document
+func (s *PDFService) RenderQuotation(quotation models.Quotation) ([]byte, error) {
+	var lines []documentLine
+	for _, item := range quotation.Items {
+		lines = append(lines, documentLine{
+			Name:        item.ItemName,
+			Description: item.Description,
+			Quantity:    item.Quantity,
+			UnitPrice:   item.UnitPrice,
+			Total:       item.TotalPrice,
+
+			Discount:     item.DiscountAmount + item.PromotionDiscount,
+			TaxableValue: item.TaxableValue,
+			TaxRate:      item.TaxRate,
+			TaxAmount:    item.TaxAmount,
+		})
+	}
+
+	var deductions [][2]string
+	if quotation.LineDiscount > 0 {
+		deductions = append(deductions, [2]string{"Line discounts", "-" + formatAmount(quotation.LineDiscount)})
+	}
+	if quotation.PromotionDiscount > 0 {
+		deductions = append(deductions, [2]string{"Promotions", "-" + formatAmount(quotation.PromotionDiscount)})
+	}
+
+	pdf := s.renderCommercialDocument(commercialDocument{
+		Title:    "Quotation",
+		Shop:     quotation.Shop,
+		Customer: quotation.Customer,
+		Details: [][2]string{
+			{"Quotation number", quotation.QuotationNumber},
+			{"Quotation date", quotation.QuotationDate.Format("2006-01-02")},
+			{"Valid until", quotation.ValidUntil.Format("2006-01-02")},
+		},
+		Lines:        lines,
+		SubTotal:     quotation.SubTotal,
+		Deductions:   deductions,
+		TaxBreakdown: true,
+		TaxableValue: quotation.TaxableValue,
+		TaxAmount:    quotation.TaxAmount,
+		Discount:     quotation.Discount,
+		Total:        quotation.TotalAmount,
+		Notes:        quotation.Notes,
+		Terms:        quotation.Terms,
+	})
+
+	return s.output(pdf)
+}
+
+// taxSummaryRows totals taxable value and tax per tax rate
+func taxSummaryRows(lines []documentLine) [][]string {
+	var rates []float64
+	taxable := map[float64]float64{}
+	tax := map[float64]float64{}
+	for _, line := range lines {
+		if _, ok := taxable[line.TaxRate]; !ok {
+			rates = append(rates, line.TaxRate)
+		}
+		taxable[line.TaxRate] += line.TaxableValue
+		tax[line.TaxRate] += line.TaxAmount
+	}
+	sort.Float64s(rates)
+
+	var rows [][]string
+	for _, rate := range rates {
+		rows = append(rows, []string{fmt.Sprintf("%.2f%%", rate), formatAmount(taxable[rate]), formatAmount(tax[rate])})
+	}
+	return rows
+}
@@ -0,0 +1,172 @@
+package services
+
+import (
+	"billboard/backend/models"
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// POSService handles counter sales built from barcode scans
+type POSService struct {
+	db *gorm.DB
+}
+
+// NewPOSService creates a new POSService instance
+func NewPOSService(db *gorm.DB) *POSService {
+	return &POSService{db: db}
+}
+
+// QuickSale creates a paid bill and its payment from a list of scans in one call
+func (s *POSService) QuickSale(shopID, userID uuid.UUID, req models.POSSaleRequest) (*models.POSSaleResponse, error) {
+	// Check if user has access to the shop
+	var shopUser models.ShopUser
+	if err := s.db.Where("shop_id = ? AND user_id = ? AND is_active = ?", shopID, userID, true).First(&shopUser).Error; err != nil {
+		return nil, errors.New("access denied to shop")
+	}
+
+	tx := s.db.Begin()
+	defer func() {
+		if r := recover(); r != nil {
+			tx.Rollback()
+		}
+	}()
+
+	itemService := &ItemService{db: tx}
+	billService := &BillService{db: tx}
+
+	billItems, err := s.scansToBillItems(itemService, shopID, req.Scans)
+	if err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+
+	today := time.Now().Format("2006-01-02")
+	bill, err := billService.CreateBill(shopID, userID, models.BillRequest{
+		CustomerID:  req.CustomerID,
+		BillDate:    today,
+		Items:       billItems,
+		Discount:    req.Discount,
+		TaxRate:     req.TaxRate,
+		Notes:       req.Notes,
+		CouponCodes: req.CouponCodes,
+	})
+	if err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+
+	tendered, change, err := settleTender(req.Tender, bill.TotalAmount)
+	if err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+
+	payment, err := billService.AddPayment(bill.ID, shopID, userID, models.PaymentRequest{
+		Amount:         bill.TotalAmount,
+		PaymentDate:    today,
+		PaymentMethod:  req.Tender.Method,
+		Reference:      req.Tender.Reference,
+		Notes:          "POS quick sale",
+		TenderedAmount: tendered,
+	})
+	if err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+
+	if err := tx.Commit().Error; err != nil {
+		return nil, err
+	}
+
+	paidBill, err := (&BillService{db: s.db}).getBillWithRelations(bill.ID, shopID)
+	if err != nil {
+		return nil, err
+	}
+
+	return &models.POSSaleResponse{
+		Bill:           *paidBill,
+		Payment:        *payment,
+		AmountTendered: tendered,
+		ChangeDue:      change,
+	}, nil
+}
+
+// scansToBillItems resolves scans to bill lines, merging repeated scans of the same code
+func (s *POSService) scansToBillItems(itemService *ItemService, shopID uuid.UUID, scans []models.POSScanRequest) ([]models.BillItemRequest, error) {
+	var billItems []models.BillItemRequest
+	lineByCode := make(map[string]int)
+
+	for _, scan := range scans {
+		code := strings.TrimSpace(scan.Code)
+		quantity := scan.Quantity
+		if quantity == 0 {
+			quantity = 1
+		}
+		if quantity < 0 {
+			return nil, fmt.Errorf("invalid quantity for %s", code)
+		}
+
+		lookup, err := itemService.LookupItem(shopID, code)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %v", code, err)
+		}
+
+		if index, ok := lineByCode[code]; ok {
+			billItems[index].Quantity += quantity * lookup.UnitQuantity
+			continue
+		}
+
+		description := lookup.Variant
+		if lookup.UnitQuantity > 1 {
+			description = strings.TrimSpace(fmt.Sprintf("%s %s of %d", description, lookup.Unit, lookup.UnitQuantity))
+		}
+
+		// Lines are priced from the customer's price list or the item price, unless the code is
+		// a pack barcode with a price of its own
+		var unitPrice *float64
+		if lookup.BarcodeID != nil && lookup.UnitPrice != lookup.Item.Price {
+			unitPrice = &lookup.UnitPrice
+		}
+
+		lineByCode[code] = len(billItems)
+		billItems = append(billItems, models.BillItemRequest{
+			ItemID:      lookup.Item.ID,
+			Quantity:    quantity * lookup.UnitQuantity,
+			UnitPrice:   unitPrice,
+			Description: description,
+		})
+	}
+
+	return billItems, nil
+}
+
+// settleTender validates the tender against the bill total and calculates change due.
+// Only cash can be over-tendered; other methods are charged the exact total.
+func settleTender(tender models.POSTenderRequest, total float64) (float64, float64, error) {
+	total = roundAmount(total)
+
+	if tender.Method != "cash" {
+		if tender.Amount != 0 && roundAmount(tender.Amount) != total {
+			return 0, 0, errors.New("non-cash tender must match the bill total")
+		}
+		return total, 0, nil
+	}
+
+	tendered := roundAmount(tender.Amount)
+	if tendered < total {
+		return 0, 0, fmt.Errorf("tendered amount %.2f is less than bill total %.2f", tendered, total)
+	}
+
+	return tendered, roundAmount(tendered - total), nil
+}
+
+// roundAmount rounds a currency amount to two decimal places
+func roundAmount(amount float64) float64 {
+	return math.Round(amount*100) / 100
+}
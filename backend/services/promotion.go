@@ -0,0 +1,576 @@
+package services
+
+import (
+	"billboard/backend/models"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// PromotionService manages promotions and coupon codes
+type PromotionService struct {
+	db *gorm.DB
+}
+
+// NewPromotionService creates a new PromotionService instance
+func NewPromotionService(db *gorm.DB) *PromotionService {
+	return &PromotionService{db: db}
+}
+
+// CreatePromotion creates a new promotion
+func (s *PromotionService) CreatePromotion(shopID, userID uuid.UUID, req models.PromotionRequest) (*models.PromotionResponse, error) {
+	// Check if user has access to the shop
+	var shopUser models.ShopUser
+	if err := s.db.Where("shop_id = ? AND user_id = ? AND is_active = ?", shopID, userID, true).First(&shopUser).Error; err != nil {
+		return nil, errors.New("access denied to shop")
+	}
+
+	promotion := models.Promotion{ShopID: shopID, IsActive: true}
+	if err := s.applyPromotionRequest(&promotion, req); err != nil {
+		return nil, err
+	}
+
+	if err := s.db.Create(&promotion).Error; err != nil {
+		return nil, err
+	}
+
+	response := s.promotionToResponse(promotion)
+	return &response, nil
+}
+
+// GetPromotions retrieves all promotions for a shop
+func (s *PromotionService) GetPromotions(shopID, userID uuid.UUID, activeOnly bool) ([]models.PromotionResponse, error) {
+	// Check if user has access to the shop
+	var shopUser models.ShopUser
+	if err := s.db.Where("shop_id = ? AND user_id = ? AND is_active = ?", shopID, userID, true).First(&shopUser).Error; err != nil {
+		return nil, errors.New("access denied to shop")
+	}
+
+	query := s.db.Where("shop_id = ?", shopID)
+	if activeOnly {
+		query = query.Where("is_active = ?", true)
+	}
+
+	var promotions []models.Promotion
+	if err := query.Order("created_at DESC").Find(&promotions).Error; err != nil {
+		return nil, err
+	}
+
+	var responses []models.PromotionResponse
+	for _, promotion := range promotions {
+		responses = append(responses, s.promotionToResponse(promotion))
+	}
+
+	return responses, nil
+}
+
+// GetPromotion retrieves a specific promotion
+func (s *PromotionService) GetPromotion(promotionID, shopID, userID uuid.UUID) (*models.PromotionResponse, error) {
+	// Check if user has access to the shop
+	var shopUser models.ShopUser
+	if err := s.db.Where("shop_id = ? AND user_id = ? AND is_active = ?", shopID, userID, true).First(&shopUser).Error; err != nil {
+		return nil, errors.New("access denied to shop")
+	}
+
+	promotion, err := s.getPromotion(promotionID, shopID)
+	if err != nil {
+		return nil, err
+	}
+
+	response := s.promotionToResponse(*promotion)
+	return &response, nil
+}
+
+// UpdatePromotion updates a promotion; bills it was already applied to keep their discount
+func (s *PromotionService) UpdatePromotion(promotionID, shopID, userID uuid.UUID, req models.PromotionRequest) (*models.PromotionResponse, error) {
+	// Check if user has access to the shop
+	var shopUser models.ShopUser
+	if err := s.db.Where("shop_id = ? AND user_id = ? AND is_active = ?", shopID, userID, true).First(&shopUser).Error; err != nil {
+		return nil, errors.New("access denied to shop")
+	}
+
+	promotion, err := s.getPromotion(promotionID, shopID)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.applyPromotionRequest(promotion, req); err != nil {
+		return nil, err
+	}
+
+	if err := s.db.Save(promotion).Error; err != nil {
+		return nil, err
+	}
+
+	response := s.promotionToResponse(*promotion)
+	return &response, nil
+}
+
+// DeletePromotion soft deletes a promotion
+func (s *PromotionService) DeletePromotion(promotionID, shopID, userID uuid.UUID) error {
+	// Check if user has access to the shop
+	var shopUser models.ShopUser
+	if err := s.db.Where("shop_id = ? AND user_id = ? AND is_active = ?", shopID, userID, true).First(&shopUser).Error; err != nil {
+		return errors.New("access denied to shop")
+	}
+
+	promotion, err := s.getPromotion(promotionID, shopID)
+	if err != nil {
+		return err
+	}
+
+	return s.db.Delete(promotion).Error
+}
+
+// EvaluatePromotions previews the promotions a bill request would receive without recording anything
+func (s *PromotionService) EvaluatePromotions(shopID, userID uuid.UUID, req models.BillRequest) (*models.PromotionEvaluation, error) {
+	// Check if user has access to the shop
+	var shopUser models.ShopUser
+	if err := s.db.Where("shop_id = ? AND user_id = ? AND is_active = ?", shopID, userID, true).First(&shopUser).Error; err != nil {
+		return nil, errors.New("access denied to shop")
+	}
+
+	billDate, err := time.Parse("2006-01-02", req.BillDate)
+	if err != nil {
+		return nil, errors.New("invalid bill date format")
+	}
+
+	calc, err := calculateBill(s.db, shopID, billDate, req)
+	if err != nil {
+		return nil, err
+	}
+	result := calc.Promotions
+
+	evaluation := &models.PromotionEvaluation{
+		SubTotal:          calc.SubTotal,
+		PromotionDiscount: result.Total,
+		Lines:             []models.PromotionLineResult{},
+		Promotions:        []models.BillPromotionResponse{},
+	}
+	for i, itemReq := range req.Items {
+		line := models.PromotionLineResult{ItemID: itemReq.ItemID, PromotionDiscount: result.LineDiscounts[i]}
+		if result.LinePromotions[i] != nil {
+			line.PromotionID = &result.LinePromotions[i].ID
+		}
+		evaluation.Lines = append(evaluation.Lines, line)
+	}
+	for _, applied := range result.Applied {
+		evaluation.Promotions = append(evaluation.Promotions, billPromotionToResponse(applied))
+	}
+
+	return evaluation, nil
+}
+
+// applyPromotionRequest validates a request and copies it onto a promotion
+func (s *PromotionService) applyPromotionRequest(promotion *models.Promotion, req models.PromotionRequest) error {
+	switch req.Scope {
+	case "item":
+		if req.ItemID == nil {
+			return errors.New("item promotions require an item")
+		}
+		var count int64
+		if err := s.db.Model(&models.Item{}).Where("id = ? AND shop_id = ?", *req.ItemID, promotion.ShopID).Count(&count).Error; err != nil {
+			return err
+		}
+		if count == 0 {
+			return errors.New("item not found")
+		}
+	case "category":
+		if strings.TrimSpace(req.Category) == "" {
+			return errors.New("category promotions require a category")
+		}
+	case "bill":
+		if req.Type == "buy_x_get_y" {
+			return errors.New("buy-x-get-y promotions must target an item or category")
+		}
+	}
+
+	switch req.Type {
+	case "percentage":
+		if req.Value <= 0 || req.Value > 100 {
+			return errors.New("percentage must be between 0 and 100")
+		}
+	case "fixed":
+		if req.Value <= 0 {
+			return errors.New("fixed discount must be greater than zero")
+		}
+	case "buy_x_get_y":
+		if req.BuyQuantity <= 0 || req.GetQuantity <= 0 {
+			return errors.New("buy-x-get-y promotions require buy and get quantities")
+		}
+		if req.Value == 0 {
+			req.Value = 100
+		}
+		if req.Value > 100 {
+			return errors.New("percentage off the free units must not exceed 100")
+		}
+	}
+
+	var startsAt, endsAt *time.Time
+	if req.StartsAt != nil && *req.StartsAt != "" {
+		parsed, err := time.Parse("2006-01-02", *req.StartsAt)
+		if err != nil {
+			return errors.New("invalid start date format")
+		}
+		startsAt = &parsed
+	}
+	if req.EndsAt != nil && *req.EndsAt != "" {
+		parsed, err := time.Parse("2006-01-02", *req.EndsAt)
+		if err != nil {
+			return errors.New("invalid end date format")
+		}
+		endsAt = &parsed
+	}
+	if startsAt != nil && endsAt != nil && endsAt.Before(*startsAt) {
+		return errors.New("end date must not be before the start date")
+	}
+
+	couponCode := normalizeCouponCode(req.CouponCode)
+	if couponCode != "" {
+		query := s.db.Model(&models.Promotion{}).Where("shop_id = ? AND coupon_code = ?", promotion.ShopID, couponCode)
+		if promotion.ID != uuid.Nil {
+			query = query.Where("id != ?", promotion.ID)
+		}
+		var count int64
+		if err := query.Count(&count).Error; err != nil {
+			return err
+		}
+		if count > 0 {
+			return errors.New("coupon code already exists")
+		}
+	}
+
+	promotion.Name = req.Name
+	promotion.Description = req.Description
+	promotion.Type = req.Type
+	promotion.Scope = req.Scope
+	promotion.ItemID = nil
+	promotion.Category = ""
+	if req.Scope == "item" {
+		promotion.ItemID = req.ItemID
+	}
+	if req.Scope == "category" {
+		promotion.Category = strings.TrimSpace(req.Category)
+	}
+	promotion.Value = req.Value
+	promotion.BuyQuantity = req.BuyQuantity
+	promotion.GetQuantity = req.GetQuantity
+	promotion.MinBillValue = req.MinBillValue
+	promotion.StartsAt = startsAt
+	promotion.EndsAt = endsAt
+	promotion.CouponCode = couponCode
+	promotion.UsageLimit = req.UsageLimit
+	if req.IsActive != nil {
+		promotion.IsActive = *req.IsActive
+	}
+
+	return nil
+}
+
+// getPromotion loads a promotion of a shop
+func (s *PromotionService) getPromotion(promotionID, shopID uuid.UUID) (*models.Promotion, error) {
+	var promotion models.Promotion
+	if err := s.db.Where("id = ? AND shop_id = ?", promotionID, shopID).First(&promotion).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("promotion not found")
+		}
+		return nil, err
+	}
+	return &promotion, nil
+}
+
+// promotionToResponse converts a Promotion model to PromotionResponse
+func (s *PromotionService) promotionToResponse(promotion models.Promotion) models.PromotionResponse {
+	return models.PromotionResponse{
+		ID:           promotion.ID,
+		ShopID:       promotion.ShopID,
+		Name:         promotion.Name,
+		Description:  promotion.Description,
+		Type:         promotion.Type,
+		Scope:        promotion.Scope,
+		ItemID:       promotion.ItemID,
+		Category:     promotion.Category,
+		Value:        promotion.Value,
+		BuyQuantity:  promotion.BuyQuantity,
+		GetQuantity:  promotion.GetQuantity,
+		MinBillValue: promotion.MinBillValue,
+		StartsAt:     promotion.StartsAt,
+		EndsAt:       promotion.EndsAt,
+		CouponCode:   promotion.CouponCode,
+		UsageLimit:   promotion.UsageLimit,
+		UsageCount:   promotion.UsageCount,
+		IsActive:     promotion.IsActive,
+		CreatedAt:    promotion.CreatedAt,
+		UpdatedAt:    promotion.UpdatedAt,
+	}
+}
+
+// promotionLine is a bill line as seen by the promotion engine
+type promotionLine struct {
+	ItemID    uuid.UUID
+	Category  string
+	Quantity  int
+	UnitPrice float64
+}
+
+// promotionResult is the outcome of evaluating promotions against the lines of a bill
+type promotionResult struct {
+	LineDiscounts  []float64
+	LinePromotions []*models.Promotion
+	Applied        []models.BillPromotion
+	Total          float64
+}
+
+// promotionLines pairs bill line requests with the category of their catalogue items,
+// pricing each unit at the line amount left after the line discount
+func promotionLines(reqs []models.BillItemRequest, items []models.Item, netAmounts []float64) []promotionLine {
+	lines := make([]promotionLine, len(reqs))
+	for i, req := range reqs {
+		lines[i] = promotionLine{
+			ItemID:    req.ItemID,
+			Category:  items[i].Category,
+			Quantity:  req.Quantity,
+			UnitPrice: netAmounts[i] / float64(req.Quantity),
+		}
+	}
+	return lines
+}
+
+// evaluatePromotions applies the shop's automatic promotions and the given coupons to bill lines.
+// Each line receives its single best item or category promotion, then the best bill promotion
+// is applied to what remains. Coupons that cannot be used are reported as errors.
+func evaluatePromotions(db *gorm.DB, shopID uuid.UUID, billDate time.Time, lines []promotionLine, couponCodes []string) (*promotionResult, error) {
+	result := &promotionResult{
+		LineDiscounts:  make([]float64, len(lines)),
+		LinePromotions: make([]*models.Promotion, len(lines)),
+	}
+
+	codes := []string{}
+	seen := map[string]bool{}
+	for _, code := range couponCodes {
+		code = normalizeCouponCode(code)
+		if code != "" && !seen[code] {
+			seen[code] = true
+			codes = append(codes, code)
+		}
+	}
+
+	var candidates []models.Promotion
+	if err := db.Where("shop_id = ? AND is_active = ? AND (coupon_code IS NULL OR coupon_code IN ?)", shopID, true, append(codes, "")).
+		Order("created_at").Find(&candidates).Error; err != nil {
+		return nil, err
+	}
+
+	subTotal := 0.0
+	for _, line := range lines {
+		subTotal += float64(line.Quantity) * line.UnitPrice
+	}
+
+	// Keep promotions that are usable for this bill, rejecting coupons that are not
+	var promotions []models.Promotion
+	found := map[string]bool{}
+	for _, promotion := range candidates {
+		if promotion.CouponCode != "" {
+			found[promotion.CouponCode] = true
+		}
+
+		var reason string
+		switch {
+		case promotion.StartsAt != nil && billDate.Before(*promotion.StartsAt):
+			reason = "is not active yet"
+		case promotion.EndsAt != nil && billDate.After(*promotion.EndsAt):
+			reason = "has expired"
+		case promotion.UsageLimit > 0 && promotion.UsageCount >= promotion.UsageLimit:
+			reason = "has reached its usage limit"
+		case subTotal < promotion.MinBillValue:
+			reason = fmt.Sprintf("requires a minimum bill value of %.2f", promotion.MinBillValue)
+		}
+
+		if reason == "" {
+			promotions = append(promotions, promotion)
+		} else if promotion.CouponCode != "" {
+			return nil, fmt.Errorf("coupon %s %s", promotion.CouponCode, reason)
+		}
+	}
+	for _, code := range codes {
+		if !found[code] {
+			return nil, fmt.Errorf("invalid coupon code %s", code)
+		}
+	}
+
+	// Best item or category promotion per line
+	applied := map[uuid.UUID]*models.BillPromotion{}
+	var order []uuid.UUID
+	record := func(promotion *models.Promotion, amount float64) {
+		if _, ok := applied[promotion.ID]; !ok {
+			applied[promotion.ID] = &models.BillPromotion{
+				PromotionID:   promotion.ID,
+				PromotionName: promotion.Name,
+				CouponCode:    promotion.CouponCode,
+				Scope:         promotion.Scope,
+			}
+			order = append(order, promotion.ID)
+		}
+		applied[promotion.ID].Amount = roundAmount(applied[promotion.ID].Amount + amount)
+		result.Total = roundAmount(result.Total + amount)
+	}
+
+	remaining := subTotal
+	for i, line := range lines {
+		for j := range promotions {
+			discount := promotionLineDiscount(promotions[j], line)
+			if discount > result.LineDiscounts[i] {
+				result.LineDiscounts[i] = discount
+				result.LinePromotions[i] = &promotions[j]
+			}
+		}
+		if result.LinePromotions[i] != nil {
+			record(result.LinePromotions[i], result.LineDiscounts[i])
+			remaining -= result.LineDiscounts[i]
+		}
+	}
+
+	// Best bill promotion on the remaining amount
+	var best *models.Promotion
+	bestDiscount := 0.0
+	for j := range promotions {
+		discount := promotionBillDiscount(promotions[j], remaining)
+		if discount > bestDiscount {
+			best = &promotions[j]
+			bestDiscount = discount
+		}
+	}
+	if best != nil {
+		record(best, bestDiscount)
+	}
+
+	for _, id := range order {
+		result.Applied = append(result.Applied, *applied[id])
+	}
+
+	return result, nil
+}
+
+// promotionLineDiscount calculates the discount an item or category promotion gives on a line
+func promotionLineDiscount(promotion models.Promotion, line promotionLine) float64 {
+	switch promotion.Scope {
+	case "item":
+		if promotion.ItemID == nil || *promotion.ItemID != line.ItemID {
+			return 0
+		}
+	case "category":
+		if line.Category == "" || !strings.EqualFold(promotion.Category, line.Category) {
+			return 0
+		}
+	default:
+		return 0
+	}
+
+	gross := float64(line.Quantity) * line.UnitPrice
+	discount := 0.0
+	switch promotion.Type {
+	case "percentage":
+		discount = gross * promotion.Value / 100
+	case "fixed":
+		discount = promotion.Value * float64(line.Quantity)
+	case "buy_x_get_y":
+		setSize := promotion.BuyQuantity + promotion.GetQuantity
+		if setSize > 0 {
+			freeUnits := line.Quantity / setSize * promotion.GetQuantity
+			discount = float64(freeUnits) * line.UnitPrice * promotion.Value / 100
+		}
+	}
+
+	if discount > gross {
+		discount = gross
+	}
+	return roundAmount(discount)
+}
+
+// promotionBillDiscount calculates the discount a bill promotion gives on the given amount
+func promotionBillDiscount(promotion models.Promotion, amount float64) float64 {
+	if promotion.Scope != "bill" || amount <= 0 {
+		return 0
+	}
+
+	discount := 0.0
+	switch promotion.Type {
+	case "percentage":
+		discount = amount * promotion.Value / 100
+	case "fixed":
+		discount = promotion.Value
+	}
+
+	if discount > amount {
+		discount = amount
+	}
+	return roundAmount(discount)
+}
+
+// redeemCoupons counts a use of every coupon promotion applied to a bill
+func redeemCoupons(tx *gorm.DB, applied []models.BillPromotion) error {
+	for _, promotion := range applied {
+		if promotion.CouponCode == "" {
+			continue
+		}
+		result := tx.Model(&models.Promotion{}).
+			Where("id = ? AND (usage_limit = 0 OR usage_count < usage_limit)", promotion.PromotionID).
+			Update("usage_count", gorm.Expr("usage_count + 1"))
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return fmt.Errorf("coupon %s has reached its usage limit", promotion.CouponCode)
+		}
+	}
+	return nil
+}
+
+// releaseBillPromotions removes the promotions recorded on a bill and gives back their coupon uses
+func releaseBillPromotions(tx *gorm.DB, billID uuid.UUID) error {
+	if err := returnBillCoupons(tx, billID); err != nil {
+		return err
+	}
+
+	return tx.Where("bill_id = ?", billID).Delete(&models.BillPromotion{}).Error
+}
+
+// returnBillCoupons gives back the coupon uses of a bill, keeping the record of its promotions
+func returnBillCoupons(tx *gorm.DB, billID uuid.UUID) error {
+	var applied []models.BillPromotion
+	if err := tx.Where("bill_id = ?", billID).Find(&applied).Error; err != nil {
+		return err
+	}
+
+	for _, promotion := range applied {
+		if promotion.CouponCode == "" {
+			continue
+		}
+		if err := tx.Model(&models.Promotion{}).Where("id = ? AND usage_count > 0", promotion.PromotionID).
+			Update("usage_count", gorm.Expr("usage_count - 1")).Error; err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// normalizeCouponCode trims and upper-cases a coupon code so lookups are case-insensitive
+func normalizeCouponCode(code string) string {
+	return strings.ToUpper(strings.TrimSpace(code))
+}
+
+// billPromotionToResponse converts a BillPromotion model to BillPromotionResponse
+func billPromotionToResponse(promotion models.BillPromotion) models.BillPromotionResponse {
+	return models.BillPromotionResponse{
+		PromotionID:   promotion.PromotionID,
+		PromotionName: promotion.PromotionName,
+		CouponCode:    promotion.CouponCode,
+		Scope:         promotion.Scope,
+		Amount:        promotion.Amount,
+	}
+}
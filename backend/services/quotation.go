@@ -0,0 +1,516 @@
+package services
+
+import (
+	"billboard/backend/models"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// QuotationService handles quotations/estimates and their conversion into bills
+type QuotationService struct {
+	db *gorm.DB
+}
+
+// NewQuotationService creates a new QuotationService instance
+func NewQuotationService(db *gorm.DB) *QuotationService {
+	return &QuotationService{db: db}
+}
+
+// CreateQuotation creates a new quotation; it neither uses a bill number nor touches stock
+func (s *QuotationService) CreateQuotation(shopID, userID uuid.UUID, req models.QuotationRequest) (*models.QuotationResponse, error) {
+	// Check if user has access to the shop
+	var shopUser models.ShopUser
+	if err := s.db.Where("shop_id = ? AND user_id = ? AND is_active = ?", shopID, userID, true).First(&shopUser).Error; err != nil {
+		return nil, errors.New("access denied to shop")
+	}
+
+	quotationDate, validUntil, err := parseQuotationDates(req)
+	if err != nil {
+		return nil, err
+	}
+
+	quotationNumber, err := s.generateQuotationNumber(shopID)
+	if err != nil {
+		return nil, err
+	}
+
+	var quotation models.Quotation
+	err = s.db.Transaction(func(tx *gorm.DB) error {
+		// Totals are worked out the way the bill the quotation converts into will be
+		calc, err := calculateBill(tx, shopID, quotationDate, quotationBillRequest(req))
+		if err != nil {
+			return err
+		}
+
+		quotation = models.Quotation{
+			ShopID:            shopID,
+			CustomerID:        req.CustomerID,
+			QuotationNumber:   quotationNumber,
+			QuotationDate:     quotationDate,
+			ValidUntil:        validUntil,
+			SubTotal:          calc.SubTotal,
+			TaxRate:           req.TaxRate,
+			TaxAmount:         calc.TaxAmount,
+			Discount:          req.Discount,
+			LineDiscount:      calc.LineDiscount,
+			PromotionDiscount: calc.PromotionDiscount,
+			TaxableValue:      calc.TaxableValue,
+			TotalAmount:       calc.TotalAmount,
+			Status:            "draft",
+			Notes:             req.Notes,
+			Terms:             req.Terms,
+			CreatedBy:         userID.String(),
+		}
+		if err := tx.Create(&quotation).Error; err != nil {
+			return err
+		}
+		return createQuotationItems(tx, quotation.ID, req.Items, calc)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return s.getQuotationWithRelations(quotation.ID, shopID)
+}
+
+// GetQuotations retrieves all quotations for a shop
+func (s *QuotationService) GetQuotations(shopID, userID uuid.UUID, filters map[string]interface{}) ([]models.QuotationResponse, error) {
+	// Check if user has access to the shop
+	var shopUser models.ShopUser
+	if err := s.db.Where("shop_id = ? AND user_id = ? AND is_active = ?", shopID, userID, true).First(&shopUser).Error; err != nil {
+		return nil, errors.New("access denied to shop")
+	}
+
+	if err := s.expireQuotations(shopID); err != nil {
+		return nil, err
+	}
+
+	query := s.db.Preload("Customer").Preload("Items").Where("shop_id = ?", shopID)
+
+	if search, ok := filters["search"].(string); ok && search != "" {
+		query = query.Where("quotation_number ILIKE ? OR notes ILIKE ?", "%"+search+"%", "%"+search+"%")
+	}
+
+	if status, ok := filters["status"].(string); ok && status != "" {
+		query = query.Where("status = ?", status)
+	}
+
+	if customerID, ok := filters["customer_id"].(string); ok && customerID != "" {
+		query = query.Where("customer_id = ?", customerID)
+	}
+
+	var quotations []models.Quotation
+	if err := query.Order("created_at DESC").Find(&quotations).Error; err != nil {
+		return nil, err
+	}
+
+	var responses []models.QuotationResponse
+	for _, quotation := range quotations {
+		responses = append(responses, s.quotationToResponse(quotation))
+	}
+
+	return responses, nil
+}
+
+// GetQuotation retrieves a specific quotation
+func (s *QuotationService) GetQuotation(quotationID, shopID, userID uuid.UUID) (*models.QuotationResponse, error) {
+	// Check if user has access to the shop
+	var shopUser models.ShopUser
+	if err := s.db.Where("shop_id = ? AND user_id = ? AND is_active = ?", shopID, userID, true).First(&shopUser).Error; err != nil {
+		return nil, errors.New("access denied to shop")
+	}
+
+	if err := s.expireQuotations(shopID); err != nil {
+		return nil, err
+	}
+
+	return s.getQuotationWithRelations(quotationID, shopID)
+}
+
+// GetQuotationDocument retrieves a quotation with its shop, customer and items for rendering
+func (s *QuotationService) GetQuotationDocument(quotationID, shopID, userID uuid.UUID) (*models.Quotation, error) {
+	// Check if user has access to the shop
+	var shopUser models.ShopUser
+	if err := s.db.Where("shop_id = ? AND user_id = ? AND is_active = ?", shopID, userID, true).First(&shopUser).Error; err != nil {
+		return nil, errors.New("access denied to shop")
+	}
+
+	var quotation models.Quotation
+	if err := s.db.Preload("Shop").Preload("Customer").Preload("Items").
+		Where("id = ? AND shop_id = ?", quotationID, shopID).First(&quotation).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("quotation not found")
+		}
+		return nil, err
+	}
+
+	return &quotation, nil
+}
+
+// UpdateQuotation updates a quotation that has not been decided on yet
+func (s *QuotationService) UpdateQuotation(quotationID, shopID, userID uuid.UUID, req models.QuotationRequest) (*models.QuotationResponse, error) {
+	// Check if user has access to the shop
+	var shopUser models.ShopUser
+	if err := s.db.Where("shop_id = ? AND user_id = ? AND is_active = ?", shopID, userID, true).First(&shopUser).Error; err != nil {
+		return nil, errors.New("access denied to shop")
+	}
+
+	quotation, err := s.getQuotation(quotationID, shopID)
+	if err != nil {
+		return nil, err
+	}
+
+	if quotation.Status != "draft" && quotation.Status != "sent" {
+		return nil, fmt.Errorf("%s quotations cannot be updated", quotation.Status)
+	}
+
+	quotationDate, validUntil, err := parseQuotationDates(req)
+	if err != nil {
+		return nil, err
+	}
+
+	err = s.db.Transaction(func(tx *gorm.DB) error {
+		calc, err := calculateBill(tx, shopID, quotationDate, quotationBillRequest(req))
+		if err != nil {
+			return err
+		}
+
+		updates := map[string]interface{}{
+			"customer_id":        req.CustomerID,
+			"quotation_date":     quotationDate,
+			"valid_until":        validUntil,
+			"sub_total":          calc.SubTotal,
+			"tax_rate":           req.TaxRate,
+			"tax_amount":         calc.TaxAmount,
+			"discount":           req.Discount,
+			"line_discount":      calc.LineDiscount,
+			"promotion_discount": calc.PromotionDiscount,
+			"taxable_value":      calc.TaxableValue,
+			"total_amount":       calc.TotalAmount,
+			"notes":              req.Notes,
+			"terms":              req.Terms,
+			"updated_at":         time.Now(),
+		}
+		if err := tx.Model(&models.Quotation{}).Where("id = ?", quotationID).Updates(updates).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("quotation_id = ?", quotationID).Delete(&models.QuotationItem{}).Error; err != nil {
+			return err
+		}
+		return createQuotationItems(tx, quotationID, req.Items, calc)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return s.getQuotationWithRelations(quotationID, shopID)
+}
+
+// UpdateQuotationStatus marks a quotation as sent, accepted or rejected
+func (s *QuotationService) UpdateQuotationStatus(quotationID, shopID, userID uuid.UUID, status string) (*models.QuotationResponse, error) {
+	// Check if user has access to the shop
+	var shopUser models.ShopUser
+	if err := s.db.Where("shop_id = ? AND user_id = ? AND is_active = ?", shopID, userID, true).First(&shopUser).Error; err != nil {
+		return nil, errors.New("access denied to shop")
+	}
+
+	if err := s.expireQuotations(shopID); err != nil {
+		return nil, err
+	}
+
+	quotation, err := s.getQuotation(quotationID, shopID)
+	if err != nil {
+		return nil, err
+	}
+
+	if quotation.Status == "converted" || quotation.Status == "expired" {
+		return nil, fmt.Errorf("%s quotations cannot change status", quotation.Status)
+	}
+
+	if err := s.db.Model(&models.Quotation{}).Where("id = ?", quotationID).Updates(map[string]interface{}{
+		"status":     status,
+		"updated_at": time.Now(),
+	}).Error; err != nil {
+		return nil, err
+	}
+
+	return s.getQuotationWithRelations(quotationID, shopID)
+}
+
+// DeleteQuotation soft deletes a quotation that has not been converted
+func (s *QuotationService) DeleteQuotation(quotationID, shopID, userID uuid.UUID) error {
+	// Check if user has access to the shop
+	var shopUser models.ShopUser
+	if err := s.db.Where("shop_id = ? AND user_id = ? AND is_active = ?", shopID, userID, true).First(&shopUser).Error; err != nil {
+		return errors.New("access denied to shop")
+	}
+
+	quotation, err := s.getQuotation(quotationID, shopID)
+	if err != nil {
+		return err
+	}
+
+	if quotation.Status == "converted" {
+		return errors.New("converted quotations cannot be deleted")
+	}
+
+	return s.db.Delete(quotation).Error
+}
+
+// ConvertToBill creates a bill from a quotation, preserving its lines and customer
+func (s *QuotationService) ConvertToBill(quotationID, shopID, userID uuid.UUID, req models.QuotationConvertRequest) (*models.BillResponse, error) {
+	// Check if user has access to the shop
+	var shopUser models.ShopUser
+	if err := s.db.Where("shop_id = ? AND user_id = ? AND is_active = ?", shopID, userID, true).First(&shopUser).Error; err != nil {
+		return nil, errors.New("access denied to shop")
+	}
+
+	if err := s.expireQuotations(shopID); err != nil {
+		return nil, err
+	}
+
+	if req.BillDate == "" {
+		req.BillDate = time.Now().Format("2006-01-02")
+	}
+
+	var billID uuid.UUID
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		// The lock makes a second conversion of the same quotation wait, then find it converted
+		var quotation models.Quotation
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Preload("Items").
+			Where("id = ? AND shop_id = ?", quotationID, shopID).First(&quotation).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return errors.New("quotation not found")
+			}
+			return err
+		}
+
+		switch quotation.Status {
+		case "converted":
+			return errors.New("quotation has already been converted")
+		case "rejected", "expired":
+			return fmt.Errorf("%s quotations cannot be converted", quotation.Status)
+		}
+
+		billReq := models.BillRequest{
+			CustomerID: quotation.CustomerID,
+			BillDate:   req.BillDate,
+			DueDate:    req.DueDate,
+			Discount:   quotation.Discount,
+			TaxRate:    quotation.TaxRate,
+			Notes:      quotation.Notes,
+			Terms:      quotation.Terms,
+		}
+		for _, item := range quotation.Items {
+			unitPrice := item.UnitPrice // stored lines hold the price already resolved
+			billReq.Items = append(billReq.Items, models.BillItemRequest{
+				ItemID:       item.ItemID,
+				Quantity:     item.Quantity,
+				UnitPrice:    &unitPrice,
+				Description:  item.Description,
+				Discount:     item.Discount,
+				DiscountType: item.DiscountType,
+				TaxInclusive: item.TaxInclusive,
+			})
+		}
+
+		bill, err := (&BillService{db: tx}).CreateBill(shopID, userID, billReq)
+		if err != nil {
+			return err
+		}
+		billID = bill.ID
+
+		result := tx.Model(&models.Quotation{}).Where("id = ? AND status = ?", quotationID, quotation.Status).Updates(map[string]interface{}{
+			"status":     "converted",
+			"bill_id":    bill.ID,
+			"updated_at": time.Now(),
+		})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return errors.New("quotation has already been converted")
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return (&BillService{db: s.db}).getBillWithRelations(billID, shopID)
+}
+
+// expireQuotations marks open quotations past their validity date as expired
+func (s *QuotationService) expireQuotations(shopID uuid.UUID) error {
+	today := time.Now().Format("2006-01-02")
+	return s.db.Model(&models.Quotation{}).
+		Where("shop_id = ? AND status IN ? AND valid_until < ?", shopID, []string{"draft", "sent"}, today).
+		Updates(map[string]interface{}{"status": "expired", "updated_at": time.Now()}).Error
+}
+
+// createQuotationItems creates the lines of a quotation from their calculation. Lines without a
+// unit price keep the price list or item price they were quoted at.
+func createQuotationItems(tx *gorm.DB, quotationID uuid.UUID, items []models.BillItemRequest, calc *billCalculation) error {
+	for i, itemReq := range items {
+		quotationItem := models.QuotationItem{
+			QuotationID:       quotationID,
+			ItemID:            itemReq.ItemID,
+			ItemName:          calc.Items[i].Name,
+			Description:       itemReq.Description,
+			Quantity:          itemReq.Quantity,
+			UnitPrice:         calc.Lines[i].UnitPrice,
+			TotalPrice:        float64(itemReq.Quantity) * calc.Lines[i].UnitPrice,
+			Discount:          itemReq.Discount,
+			DiscountType:      calc.Lines[i].DiscountType,
+			DiscountAmount:    calc.Lines[i].DiscountAmount,
+			PromotionDiscount: calc.Lines[i].PromotionDiscount,
+			TaxInclusive:      itemReq.TaxInclusive,
+			TaxRate:           calc.Lines[i].TaxRate,
+			TaxableValue:      calc.Lines[i].TaxableValue,
+			TaxAmount:         calc.Lines[i].TaxAmount,
+		}
+
+		if err := tx.Create(&quotationItem).Error; err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// parseQuotationDates parses and checks the quotation and validity dates
+func parseQuotationDates(req models.QuotationRequest) (time.Time, time.Time, error) {
+	quotationDate, err := time.Parse("2006-01-02", req.QuotationDate)
+	if err != nil {
+		return time.Time{}, time.Time{}, errors.New("invalid quotation date format")
+	}
+
+	validUntil, err := time.Parse("2006-01-02", req.ValidUntil)
+	if err != nil {
+		return time.Time{}, time.Time{}, errors.New("invalid validity date format")
+	}
+
+	if validUntil.Before(quotationDate) {
+		return time.Time{}, time.Time{}, errors.New("validity date must not be before the quotation date")
+	}
+
+	return quotationDate, validUntil, nil
+}
+
+// quotationBillRequest turns a quotation request into the bill request its totals are worked
+// out from
+func quotationBillRequest(req models.QuotationRequest) models.BillRequest {
+	return models.BillRequest{
+		CustomerID: req.CustomerID,
+		Items:      req.Items,
+		Discount:   req.Discount,
+		TaxRate:    req.TaxRate,
+	}
+}
+
+// generateQuotationNumber generates a unique quotation number
+func (s *QuotationService) generateQuotationNumber(shopID uuid.UUID) (string, error) {
+	var count int64
+	s.db.Unscoped().Model(&models.Quotation{}).Where("shop_id = ?", shopID).Count(&count)
+
+	// Generate quotation number: QUO-YYYY-NNNNNN
+	year := time.Now().Year()
+	for sequence := count + 1; ; sequence++ {
+		quotationNumber := fmt.Sprintf("QUO-%d-%06d", year, sequence)
+
+		var existing int64
+		if err := s.db.Unscoped().Model(&models.Quotation{}).Where("quotation_number = ?", quotationNumber).Count(&existing).Error; err != nil {
+			return "", err
+		}
+		if existing == 0 {
+			return quotationNumber, nil
+		}
+	}
+}
+
+// getQuotation loads a quotation without relationships
+func (s *QuotationService) getQuotation(quotationID, shopID uuid.UUID) (*models.Quotation, error) {
+	var quotation models.Quotation
+	if err := s.db.Where("id = ? AND shop_id = ?", quotationID, shopID).First(&quotation).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("quotation not found")
+		}
+		return nil, err
+	}
+	return &quotation, nil
+}
+
+// getQuotationWithRelations fetches a quotation with its customer and items
+func (s *QuotationService) getQuotationWithRelations(quotationID, shopID uuid.UUID) (*models.QuotationResponse, error) {
+	var quotation models.Quotation
+	if err := s.db.Preload("Customer").Preload("Items").Where("id = ? AND shop_id = ?", quotationID, shopID).First(&quotation).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("quotation not found")
+		}
+		return nil, err
+	}
+
+	response := s.quotationToResponse(quotation)
+	return &response, nil
+}
+
+// quotationToResponse converts a Quotation model to QuotationResponse
+func (s *QuotationService) quotationToResponse(quotation models.Quotation) models.QuotationResponse {
+	var customer *models.CustomerResponse
+	if quotation.Customer != nil {
+		customerResp := (&BillService{}).customerToResponse(*quotation.Customer)
+		customer = &customerResp
+	}
+
+	items := []models.QuotationItemResponse{}
+	for _, item := range quotation.Items {
+		items = append(items, models.QuotationItemResponse{
+			ID:                item.ID,
+			QuotationID:       item.QuotationID,
+			ItemID:            item.ItemID,
+			ItemName:          item.ItemName,
+			Description:       item.Description,
+			Quantity:          item.Quantity,
+			UnitPrice:         item.UnitPrice,
+			TotalPrice:        item.TotalPrice,
+			Discount:          item.Discount,
+			DiscountType:      item.DiscountType,
+			DiscountAmount:    item.DiscountAmount,
+			PromotionDiscount: item.PromotionDiscount,
+			TaxInclusive:      item.TaxInclusive,
+			TaxRate:           item.TaxRate,
+			TaxableValue:      item.TaxableValue,
+			TaxAmount:         item.TaxAmount,
+		})
+	}
+
+	return models.QuotationResponse{
+		ID:                quotation.ID,
+		ShopID:            quotation.ShopID,
+		CustomerID:        quotation.CustomerID,
+		QuotationNumber:   quotation.QuotationNumber,
+		QuotationDate:     quotation.QuotationDate,
+		ValidUntil:        quotation.ValidUntil,
+		SubTotal:          quotation.SubTotal,
+		TaxRate:           quotation.TaxRate,
+		TaxAmount:         quotation.TaxAmount,
+		Discount:          quotation.Discount,
+		LineDiscount:      quotation.LineDiscount,
+		PromotionDiscount: quotation.PromotionDiscount,
+		TaxableValue:      quotation.TaxableValue,
+		TotalAmount:       quotation.TotalAmount,
+		Status:            quotation.Status,
+		Notes:             quotation.Notes,
+		Terms:             quotation.Terms,
+		BillID:            quotation.BillID,
+		Customer:          customer,
+		Items:             items,
+		CreatedAt:         quotation.CreatedAt,
+		UpdatedAt:         quotation.UpdatedAt,
+	}
+}
@@ -0,0 +1,429 @@
+package services
+
+import (
+	"billboard/backend/models"
+	"bytes"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// ReceiptOptions controls how a thermal receipt is laid out
+type ReceiptOptions struct {
+	PaperWidth int    // paper width in millimetres, 58 or 80
+	Code       string // qr, barcode, none
+	Footer     string // overrides the shop's receipt footer
+}
+
+// ReceiptService renders bills as ESC/POS byte streams for thermal printers
+type ReceiptService struct {
+	db *gorm.DB
+}
+
+// NewReceiptService creates a new ReceiptService instance
+func NewReceiptService(db *gorm.DB) *ReceiptService {
+	return &ReceiptService{db: db}
+}
+
+// RenderBillReceipt renders a bill as an ESC/POS receipt
+func (s *ReceiptService) RenderBillReceipt(billID, shopID, userID uuid.UUID, opts ReceiptOptions) ([]byte, error) {
+	// Check if user has access to the shop
+	var shopUser models.ShopUser
+	if err := s.db.Where("shop_id = ? AND user_id = ? AND is_active = ?", shopID, userID, true).First(&shopUser).Error; err != nil {
+		return nil, errors.New("access denied to shop")
+	}
+
+	var bill models.Bill
+	if err := s.db.Preload("Shop").Preload("Customer").Preload("Items").Preload("Payments").Preload("Promotions").
+		Where("id = ? AND shop_id = ? AND deleted_at IS NULL", billID, shopID).First(&bill).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("bill not found")
+		}
+		return nil, err
+	}
+
+	var columns int
+	switch opts.PaperWidth {
+	case 0, 80:
+		columns = 48
+	case 58:
+		columns = 32
+	default:
+		return nil, errors.New("unsupported paper width, use 58 or 80")
+	}
+
+	switch opts.Code {
+	case "":
+		opts.Code = "qr"
+	case "qr", "barcode", "none":
+	default:
+		return nil, errors.New("unsupported receipt code, use qr, barcode or none")
+	}
+
+	if opts.Footer == "" {
+		opts.Footer = bill.Shop.ReceiptFooter
+	}
+
+	return renderReceipt(bill, columns, opts), nil
+}
+
+// RenderBillUPIQR renders the UPI QR code that pays a bill's balance as a PNG of the given
+// size in pixels, for the point of sale screen to show to the customer
+func (s *ReceiptService) RenderBillUPIQR(billID, shopID, userID uuid.UUID, size int) ([]byte, error) {
+	// Check if user has access to the shop
+	var shopUser models.ShopUser
+	if err := s.db.Where("shop_id = ? AND user_id = ? AND is_active = ?", shopID, userID, true).First(&shopUser).Error; err != nil {
+		return nil, errors.New("access denied to shop")
+	}
+
+	var bill models.Bill
+	if err := s.db.Preload("Shop").Where("id = ? AND shop_id = ? AND deleted_at IS NULL", billID, shopID).First(&bill).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("bill not found")
+		}
+		return nil, err
+	}
+
+	if size == 0 {
+		size = 300
+	}
+	if size < 100 || size > 1000 {
+		return nil, errors.New("size must be between 100 and 1000 pixels")
+	}
+	if bill.Shop.UPIID == "" {
+		return nil, errors.New("shop has no UPI ID configured")
+	}
+
+	if bill.Status == "cancelled" {
+		return nil, errors.New("bill is cancelled")
+	}
+	intent, ok := billUPIIntent(bill)
+	if !ok {
+		return nil, errors.New("bill has no balance to pay")
+	}
+	return qrCodePNG(intent, size)
+}
+
+// renderReceipt lays out a bill on a receipt with the given number of columns
+func renderReceipt(bill models.Bill, columns int, opts ReceiptOptions) []byte {
+	r := &escposWriter{columns: columns}
+	r.init()
+
+	// Shop header
+	r.align(escposCenter)
+	r.size(true)
+	r.bold(true)
+	r.wrapped(bill.Shop.Name)
+	r.size(false)
+	r.bold(false)
+	r.wrapped(bill.Shop.Address)
+	if bill.Shop.Phone != "" {
+		r.wrapped("Ph: " + bill.Shop.Phone)
+	}
+	if bill.Shop.GSTNumber != "" {
+		r.wrapped("GSTIN: " + bill.Shop.GSTNumber)
+	}
+	r.separator()
+
+	// Bill details
+	r.align(escposLeft)
+	r.columnsLine("Bill No: "+bill.BillNumber, bill.BillDate.Format("02-01-2006"))
+	if bill.Customer != nil {
+		r.wrapped("Customer: " + bill.Customer.Name)
+	}
+	r.separator()
+
+	// Item lines
+	r.bold(true)
+	r.columnsLine("Item", "Amount")
+	r.bold(false)
+	for _, item := range bill.Items {
+		r.wrapped(item.ItemName)
+		if item.Description != "" {
+			r.wrapped("  " + item.Description)
+		}
+		r.columnsLine(fmt.Sprintf("  %d x %s", item.Quantity, formatAmount(item.UnitPrice)), formatAmount(item.TotalPrice))
+		if item.DiscountAmount > 0 {
+			label := "  Discount"
+			if item.DiscountType == "percentage" {
+				label = fmt.Sprintf("  Discount %.2f%%", item.DiscountValue)
+			}
+			r.columnsLine(label, "-"+formatAmount(item.DiscountAmount))
+		}
+	}
+	r.separator()
+
+	// Totals and tax summary
+	r.columnsLine("Subtotal", formatAmount(bill.SubTotal))
+	if bill.LineDiscount > 0 {
+		r.columnsLine("Line discounts", "-"+formatAmount(bill.LineDiscount))
+	}
+	for _, promotion := range bill.Promotions {
+		label := "Promo: " + promotion.PromotionName
+		if promotion.CouponCode != "" {
+			label = "Coupon " + promotion.CouponCode
+		}
+		r.columnsLine(label, "-"+formatAmount(promotion.Amount))
+	}
+	if bill.TaxAmount > 0 {
+		r.columnsLine("Taxable value", formatAmount(bill.TaxableValue))
+		var rates []float64
+		taxable := map[float64]float64{}
+		tax := map[float64]float64{}
+		for _, item := range bill.Items {
+			if _, ok := taxable[item.TaxRate]; !ok {
+				rates = append(rates, item.TaxRate)
+			}
+			taxable[item.TaxRate] += item.TaxableValue
+			tax[item.TaxRate] += item.TaxAmount
+		}
+		sort.Float64s(rates)
+		for _, rate := range rates {
+			if tax[rate] > 0 {
+				r.columnsLine(fmt.Sprintf("Tax @ %.2f%% on %s", rate, formatAmount(taxable[rate])), formatAmount(tax[rate]))
+			}
+		}
+	}
+	if bill.Discount > 0 {
+		r.columnsLine("Discount", "-"+formatAmount(bill.Discount))
+	}
+	r.bold(true)
+	r.size(true)
+	r.columnsLineWidth("TOTAL", formatAmount(bill.TotalAmount), columns/2)
+	r.size(false)
+	r.bold(false)
+	r.separator()
+	if bill.LateFeeAmount > 0 {
+		r.columnsLine("Late fees", formatAmount(bill.LateFeeAmount))
+		r.separator()
+	}
+
+	// Payments and change due
+	changeDue := 0.0
+	for _, payment := range bill.Payments {
+		r.columnsLine(strings.ToUpper(payment.PaymentMethod), formatAmount(payment.Amount))
+		if payment.TenderedAmount > payment.Amount {
+			r.columnsLine("  Tendered", formatAmount(payment.TenderedAmount))
+			changeDue += payment.TenderedAmount - payment.Amount
+		}
+	}
+	if len(bill.Payments) > 0 {
+		r.columnsLine("Paid", formatAmount(bill.PaidAmount))
+	}
+	if changeDue > 0 {
+		r.bold(true)
+		r.columnsLine("Change due", formatAmount(changeDue))
+		r.bold(false)
+	}
+	if bill.Balance > 0 {
+		r.columnsLine("Balance due", formatAmount(bill.Balance))
+	}
+	if intent, ok := billUPIIntent(bill); ok {
+		r.align(escposCenter)
+		r.feed(1)
+		r.wrapped("Scan to pay " + formatAmount(bill.Balance) + " by UPI")
+		r.qrCode(intent)
+		r.wrapped(bill.Shop.UPIID)
+		r.align(escposLeft)
+	}
+
+	// Footer and bill number code
+	r.align(escposCenter)
+	if opts.Footer != "" {
+		r.feed(1)
+		r.wrapped(opts.Footer)
+	}
+	switch opts.Code {
+	case "qr":
+		r.feed(1)
+		r.qrCode(bill.BillNumber)
+	case "barcode":
+		r.feed(1)
+		r.code128(bill.BillNumber)
+	}
+
+	r.feed(3)
+	r.cut()
+	return r.buf.Bytes()
+}
+
+const (
+	escposLeft   = 0
+	escposCenter = 1
+	escposRight  = 2
+)
+
+// escposWriter builds an ESC/POS byte stream for a receipt of a fixed column width
+type escposWriter struct {
+	buf     bytes.Buffer
+	columns int
+}
+
+// init resets the printer to its default settings
+func (w *escposWriter) init() {
+	w.buf.Write([]byte{0x1B, 0x40})
+}
+
+// align sets the justification of the following lines
+func (w *escposWriter) align(mode byte) {
+	w.buf.Write([]byte{0x1B, 0x61, mode})
+}
+
+// bold turns emphasized printing on or off
+func (w *escposWriter) bold(on bool) {
+	w.buf.Write([]byte{0x1B, 0x45, escposFlag(on)})
+}
+
+// size switches between normal and double width and height characters
+func (w *escposWriter) size(double bool) {
+	var n byte
+	if double {
+		n = 0x11
+	}
+	w.buf.Write([]byte{0x1D, 0x21, n})
+}
+
+// line writes a single line of text
+func (w *escposWriter) line(text string) {
+	w.buf.WriteString(escposText(text))
+	w.buf.WriteByte(0x0A)
+}
+
+// wrapped writes text wrapped at word boundaries to the column width
+func (w *escposWriter) wrapped(text string) {
+	if strings.TrimSpace(text) == "" {
+		return
+	}
+	for _, line := range wrapText(text, w.columns) {
+		w.line(line)
+	}
+}
+
+// columnsLine writes left and right aligned text on one line, wrapping the left text if needed
+func (w *escposWriter) columnsLine(left, right string) {
+	w.columnsLineWidth(left, right, w.columns)
+}
+
+// columnsLineWidth is columnsLine for a custom number of columns, used with double width text
+func (w *escposWriter) columnsLineWidth(left, right string, columns int) {
+	space := columns - len(right) - 1
+	if space < 1 {
+		space = 1
+	}
+	lines := wrapText(left, space)
+	for _, line := range lines[:len(lines)-1] {
+		w.line(line)
+	}
+	last := lines[len(lines)-1]
+	padding := columns - len(last) - len(right)
+	if padding < 1 {
+		padding = 1
+	}
+	w.line(last + strings.Repeat(" ", padding) + right)
+}
+
+// separator writes a dashed line across the receipt
+func (w *escposWriter) separator() {
+	w.line(strings.Repeat("-", w.columns))
+}
+
+// feed prints and feeds n lines
+func (w *escposWriter) feed(n int) {
+	w.buf.Write([]byte{0x1B, 0x64, byte(n)})
+}
+
+// cut feeds the paper and performs a partial cut
+func (w *escposWriter) cut() {
+	w.buf.Write([]byte{0x1D, 0x56, 0x42, 0x00})
+}
+
+// qrCode prints data as a QR code using the printer's native QR support
+func (w *escposWriter) qrCode(data string) {
+	payload := escposText(data)
+	storeLen := len(payload) + 3
+
+	w.buf.Write([]byte{0x1D, 0x28, 0x6B, 0x04, 0x00, 0x31, 0x41, 0x32, 0x00}) // model 2
+	w.buf.Write([]byte{0x1D, 0x28, 0x6B, 0x03, 0x00, 0x31, 0x43, 0x06})       // module size
+	w.buf.Write([]byte{0x1D, 0x28, 0x6B, 0x03, 0x00, 0x31, 0x45, 0x31})       // error correction M
+	w.buf.Write([]byte{0x1D, 0x28, 0x6B, byte(storeLen % 256), byte(storeLen / 256), 0x31, 0x50, 0x30})
+	w.buf.WriteString(payload)
+	w.buf.Write([]byte{0x1D, 0x28, 0x6B, 0x03, 0x00, 0x31, 0x51, 0x30}) // print
+	w.buf.WriteByte(0x0A)
+}
+
+// code128 prints data as a Code128 barcode with the text printed below
+func (w *escposWriter) code128(data string) {
+	payload := "{B" + escposText(data)
+
+	w.buf.Write([]byte{0x1D, 0x68, 0x50}) // height
+	w.buf.Write([]byte{0x1D, 0x77, 0x02}) // module width
+	w.buf.Write([]byte{0x1D, 0x48, 0x02}) // text below
+	w.buf.Write([]byte{0x1D, 0x6B, 0x49, byte(len(payload))})
+	w.buf.WriteString(payload)
+	w.buf.WriteByte(0x0A)
+}
+
+// escposFlag converts a boolean to the 0/1 argument used by ESC/POS commands
+func escposFlag(on bool) byte {
+	if on {
+		return 1
+	}
+	return 0
+}
+
+// escposText replaces characters the printer's default code page cannot print
+func escposText(text string) string {
+	return strings.Map(func(r rune) rune {
+		if r == '\n' || r == '\r' || r == '\t' {
+			return ' '
+		}
+		if r < 32 || r > 126 {
+			return '?'
+		}
+		return r
+	}, text)
+}
+
+// wrapText splits text into lines of at most width characters, breaking at spaces where possible
+func wrapText(text string, width int) []string {
+	text = escposText(text)
+	indent := text[:len(text)-len(strings.TrimLeft(text, " "))]
+	words := strings.Fields(text)
+	if len(words) == 0 {
+		return []string{""}
+	}
+	if len(indent) < width {
+		words[0] = indent + words[0]
+	}
+
+	var lines []string
+	current := ""
+	for _, word := range words {
+		for len(word) > width {
+			if current != "" {
+				lines = append(lines, current)
+				current = ""
+			}
+			lines = append(lines, word[:width])
+			word = word[width:]
+		}
+		switch {
+		case current == "":
+			current = word
+		case len(current)+1+len(word) <= width:
+			current += " " + word
+		default:
+			lines = append(lines, current)
+			current = word
+		}
+	}
+	if current != "" || len(lines) == 0 {
+		lines = append(lines, current)
+	}
+
+	return lines
+}
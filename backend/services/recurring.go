@@ -0,0 +1,677 @@
+package services
+
+import (
+	"billboard/backend/models"
+	"errors"
+	"log"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// RecurringBillService manages recurring bill templates and generates their bills
+type RecurringBillService struct {
+	db *gorm.DB
+}
+
+// NewRecurringBillService creates a new RecurringBillService instance
+func NewRecurringBillService(db *gorm.DB) *RecurringBillService {
+	return &RecurringBillService{db: db}
+}
+
+// CreateRecurringBill creates a new recurring bill template
+func (s *RecurringBillService) CreateRecurringBill(shopID, userID uuid.UUID, req models.RecurringBillRequest) (*models.RecurringBillResponse, error) {
+	// Check if user has access to the shop
+	var shopUser models.ShopUser
+	if err := s.db.Where("shop_id = ? AND user_id = ? AND is_active = ?", shopID, userID, true).First(&shopUser).Error; err != nil {
+		return nil, errors.New("access denied to shop")
+	}
+
+	template := models.RecurringBill{
+		ShopID:    shopID,
+		Status:    "active",
+		CreatedBy: userID.String(),
+	}
+	if err := applyRecurringBillRequest(&template, req); err != nil {
+		return nil, err
+	}
+	scheduleRecurringBill(&template)
+
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&template).Error; err != nil {
+			return err
+		}
+		return s.createRecurringBillItems(tx, &template, req.Items)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return s.getRecurringBillWithRelations(template.ID, shopID)
+}
+
+// GetRecurringBills retrieves all recurring bill templates for a shop
+func (s *RecurringBillService) GetRecurringBills(shopID, userID uuid.UUID, status string) ([]models.RecurringBillResponse, error) {
+	// Check if user has access to the shop
+	var shopUser models.ShopUser
+	if err := s.db.Where("shop_id = ? AND user_id = ? AND is_active = ?", shopID, userID, true).First(&shopUser).Error; err != nil {
+		return nil, errors.New("access denied to shop")
+	}
+
+	query := s.db.Preload("Customer").Preload("Items").Where("shop_id = ?", shopID)
+	if status != "" {
+		query = query.Where("status = ?", status)
+	}
+
+	var templates []models.RecurringBill
+	if err := query.Order("created_at DESC").Find(&templates).Error; err != nil {
+		return nil, err
+	}
+
+	var responses []models.RecurringBillResponse
+	for _, template := range templates {
+		responses = append(responses, s.recurringBillToResponse(template))
+	}
+
+	return responses, nil
+}
+
+// GetRecurringBill retrieves a specific recurring bill template
+func (s *RecurringBillService) GetRecurringBill(templateID, shopID, userID uuid.UUID) (*models.RecurringBillResponse, error) {
+	// Check if user has access to the shop
+	var shopUser models.ShopUser
+	if err := s.db.Where("shop_id = ? AND user_id = ? AND is_active = ?", shopID, userID, true).First(&shopUser).Error; err != nil {
+		return nil, errors.New("access denied to shop")
+	}
+
+	return s.getRecurringBillWithRelations(templateID, shopID)
+}
+
+// UpdateRecurringBill updates a recurring bill template; bills already generated are not changed
+func (s *RecurringBillService) UpdateRecurringBill(templateID, shopID, userID uuid.UUID, req models.RecurringBillRequest) (*models.RecurringBillResponse, error) {
+	// Check if user has access to the shop
+	var shopUser models.ShopUser
+	if err := s.db.Where("shop_id = ? AND user_id = ? AND is_active = ?", shopID, userID, true).First(&shopUser).Error; err != nil {
+		return nil, errors.New("access denied to shop")
+	}
+
+	template, err := s.getRecurringBill(templateID, shopID)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := applyRecurringBillRequest(template, req); err != nil {
+		return nil, err
+	}
+	if template.Status == "ended" {
+		template.Status = "active"
+	}
+	scheduleRecurringBill(template)
+
+	err = s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Omit("Customer", "Items").Save(template).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("recurring_bill_id = ?", templateID).Delete(&models.RecurringBillItem{}).Error; err != nil {
+			return err
+		}
+		return s.createRecurringBillItems(tx, template, req.Items)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return s.getRecurringBillWithRelations(templateID, shopID)
+}
+
+// DeleteRecurringBill soft deletes a recurring bill template
+func (s *RecurringBillService) DeleteRecurringBill(templateID, shopID, userID uuid.UUID) error {
+	// Check if user has access to the shop
+	var shopUser models.ShopUser
+	if err := s.db.Where("shop_id = ? AND user_id = ? AND is_active = ?", shopID, userID, true).First(&shopUser).Error; err != nil {
+		return errors.New("access denied to shop")
+	}
+
+	template, err := s.getRecurringBill(templateID, shopID)
+	if err != nil {
+		return err
+	}
+
+	return s.db.Delete(template).Error
+}
+
+// PauseRecurringBill stops a template from generating bills until it is resumed
+func (s *RecurringBillService) PauseRecurringBill(templateID, shopID, userID uuid.UUID) (*models.RecurringBillResponse, error) {
+	// Check if user has access to the shop
+	var shopUser models.ShopUser
+	if err := s.db.Where("shop_id = ? AND user_id = ? AND is_active = ?", shopID, userID, true).First(&shopUser).Error; err != nil {
+		return nil, errors.New("access denied to shop")
+	}
+
+	template, err := s.getRecurringBill(templateID, shopID)
+	if err != nil {
+		return nil, err
+	}
+
+	if template.Status != "active" {
+		return nil, errors.New("only active recurring bills can be paused")
+	}
+
+	if err := s.db.Model(template).Updates(map[string]interface{}{
+		"status":     "paused",
+		"updated_at": time.Now(),
+	}).Error; err != nil {
+		return nil, err
+	}
+
+	return s.getRecurringBillWithRelations(templateID, shopID)
+}
+
+// ResumeRecurringBill reactivates a paused template; periods missed while paused are skipped
+func (s *RecurringBillService) ResumeRecurringBill(templateID, shopID, userID uuid.UUID) (*models.RecurringBillResponse, error) {
+	// Check if user has access to the shop
+	var shopUser models.ShopUser
+	if err := s.db.Where("shop_id = ? AND user_id = ? AND is_active = ?", shopID, userID, true).First(&shopUser).Error; err != nil {
+		return nil, errors.New("access denied to shop")
+	}
+
+	template, err := s.getRecurringBill(templateID, shopID)
+	if err != nil {
+		return nil, err
+	}
+
+	if template.Status != "paused" {
+		return nil, errors.New("only paused recurring bills can be resumed")
+	}
+
+	template.Status = "active"
+	scheduleRecurringBill(template)
+
+	if err := s.db.Model(template).Updates(map[string]interface{}{
+		"status":        template.Status,
+		"next_run_date": template.NextRunDate,
+		"updated_at":    time.Now(),
+	}).Error; err != nil {
+		return nil, err
+	}
+
+	return s.getRecurringBillWithRelations(templateID, shopID)
+}
+
+// PreviewRecurringBill lists the next runs of a template without generating anything
+func (s *RecurringBillService) PreviewRecurringBill(templateID, shopID, userID uuid.UUID, count int) ([]models.RecurringBillPreview, error) {
+	// Check if user has access to the shop
+	var shopUser models.ShopUser
+	if err := s.db.Where("shop_id = ? AND user_id = ? AND is_active = ?", shopID, userID, true).First(&shopUser).Error; err != nil {
+		return nil, errors.New("access denied to shop")
+	}
+
+	var template models.RecurringBill
+	if err := s.db.Preload("Items").Where("id = ? AND shop_id = ?", templateID, shopID).First(&template).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("recurring bill not found")
+		}
+		return nil, err
+	}
+
+	if count <= 0 {
+		count = 6
+	}
+	if count > 36 {
+		count = 36
+	}
+
+	billReq := recurringBillRequest(template, recurringBillItemRequests(template.Items))
+	status := "draft"
+	if template.AutoIssue {
+		status = "sent"
+	}
+
+	previews := []models.RecurringBillPreview{}
+	from := recurringBillResumeDate(template)
+	if template.Status == "active" && template.NextRunDate != nil {
+		from = *template.NextRunDate
+	}
+	for len(previews) < count {
+		billDate := nextRecurringBillDate(template, from)
+		if billDate == nil {
+			break
+		}
+
+		// Promotions depend on the bill date, so each run is worked out on its own
+		calc, err := calculateBill(s.db, shopID, *billDate, billReq)
+		if err != nil {
+			return nil, err
+		}
+		previews = append(previews, models.RecurringBillPreview{
+			BillDate:          *billDate,
+			DueDate:           recurringBillDueDate(template, *billDate),
+			Status:            status,
+			SubTotal:          calc.SubTotal,
+			LineDiscount:      calc.LineDiscount,
+			PromotionDiscount: calc.PromotionDiscount,
+			TaxAmount:         calc.TaxAmount,
+			TotalAmount:       calc.TotalAmount,
+		})
+		from = billDate.AddDate(0, 0, 1)
+	}
+
+	return previews, nil
+}
+
+// GetRecurringBillRuns retrieves the bills generated by a template
+func (s *RecurringBillService) GetRecurringBillRuns(templateID, shopID, userID uuid.UUID) ([]models.RecurringBillRunResponse, error) {
+	// Check if user has access to the shop
+	var shopUser models.ShopUser
+	if err := s.db.Where("shop_id = ? AND user_id = ? AND is_active = ?", shopID, userID, true).First(&shopUser).Error; err != nil {
+		return nil, errors.New("access denied to shop")
+	}
+
+	if _, err := s.getRecurringBill(templateID, shopID); err != nil {
+		return nil, err
+	}
+
+	var runs []models.RecurringBillRun
+	if err := s.db.Where("recurring_bill_id = ?", templateID).Order("period_date DESC").Find(&runs).Error; err != nil {
+		return nil, err
+	}
+
+	var responses []models.RecurringBillRunResponse
+	for _, run := range runs {
+		responses = append(responses, models.RecurringBillRunResponse{
+			ID:          run.ID,
+			PeriodDate:  run.PeriodDate,
+			BillID:      run.BillID,
+			EmailStatus: run.EmailStatus,
+			CreatedAt:   run.CreatedAt,
+		})
+	}
+
+	return responses, nil
+}
+
+// GenerateDueBills generates the bills of all active templates that are due, catching up missed periods.
+// Each period is generated at most once, so running it repeatedly or concurrently is safe.
+func (s *RecurringBillService) GenerateDueBills() (int, error) {
+	today := recurringBillToday()
+
+	var templates []models.RecurringBill
+	if err := s.db.Preload("Items").
+		Where("status = ? AND next_run_date <= ?", "active", today).
+		Find(&templates).Error; err != nil {
+		return 0, err
+	}
+
+	generated := 0
+	for _, template := range templates {
+		for template.NextRunDate != nil && !template.NextRunDate.After(today) {
+			created, err := s.generateRun(&template, *template.NextRunDate)
+			if err != nil {
+				log.Printf("Failed to generate recurring bill %s for %s: %v", template.ID, template.NextRunDate.Format("2006-01-02"), err)
+				break
+			}
+			if created {
+				generated++
+			}
+		}
+	}
+
+	return generated, nil
+}
+
+// StartGenerator periodically generates due recurring bills in the background
+func (s *RecurringBillService) StartGenerator(interval time.Duration) {
+	if s.db == nil {
+		return
+	}
+
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for range ticker.C {
+			if count, err := s.GenerateDueBills(); err != nil {
+				log.Printf("Failed to generate recurring bills: %v", err)
+			} else if count > 0 {
+				log.Printf("Generated %d recurring bills", count)
+			}
+		}
+	}()
+}
+
+// generateRun creates the bill for one period and advances the template's schedule.
+// It reports false when the period had already been generated.
+func (s *RecurringBillService) generateRun(template *models.RecurringBill, period time.Time) (bool, error) {
+	created := false
+	next := nextRecurringBillDate(*template, period.AddDate(0, 0, 1))
+
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		var existing int64
+		if err := tx.Model(&models.RecurringBillRun{}).
+			Where("recurring_bill_id = ? AND period_date = ?", template.ID, period).
+			Count(&existing).Error; err != nil {
+			return err
+		}
+
+		if existing == 0 {
+			userID, err := uuid.Parse(template.CreatedBy)
+			if err != nil {
+				return err
+			}
+
+			billReq := recurringBillRequest(*template, recurringBillItemRequests(template.Items))
+			billReq.BillDate = period.Format("2006-01-02")
+			if dueDate := recurringBillDueDate(*template, period); dueDate != nil {
+				formatted := dueDate.Format("2006-01-02")
+				billReq.DueDate = &formatted
+			}
+
+			bill, err := (&BillService{db: tx}).CreateBill(template.ShopID, userID, billReq)
+			if err != nil {
+				return err
+			}
+
+			emailStatus := "not_required"
+			if template.AutoIssue {
+				if err := tx.Model(&models.Bill{}).Where("id = ?", bill.ID).Update("status", "sent").Error; err != nil {
+					return err
+				}
+				if template.AutoEmail {
+					emailStatus = "pending"
+				}
+			}
+
+			// The unique period index rejects a run generated concurrently for the same period
+			run := models.RecurringBillRun{
+				RecurringBillID: template.ID,
+				PeriodDate:      period,
+				BillID:          &bill.ID,
+				EmailStatus:     emailStatus,
+			}
+			if err := tx.Create(&run).Error; err != nil {
+				return err
+			}
+			created = true
+		}
+
+		updates := map[string]interface{}{
+			"next_run_date": next,
+			"last_run_date": period,
+			"updated_at":    time.Now(),
+		}
+		if next == nil {
+			updates["status"] = "ended"
+		}
+		return tx.Model(&models.RecurringBill{}).Where("id = ?", template.ID).Updates(updates).Error
+	})
+	if err != nil {
+		return false, err
+	}
+
+	template.NextRunDate = next
+	template.LastRunDate = &period
+	return created, nil
+}
+
+// createRecurringBillItems creates the lines of a recurring bill template, checking them the
+// way the bills it generates will be. Lines without a unit price are priced from the
+// customer's price list, or the item price, as of the start date.
+func (s *RecurringBillService) createRecurringBillItems(tx *gorm.DB, template *models.RecurringBill, items []models.BillItemRequest) error {
+	calc, err := calculateBill(tx, template.ShopID, template.StartDate, recurringBillRequest(*template, items))
+	if err != nil {
+		return err
+	}
+
+	for i, itemReq := range items {
+		templateItem := models.RecurringBillItem{
+			RecurringBillID: template.ID,
+			ItemID:          itemReq.ItemID,
+			ItemName:        calc.Items[i].Name,
+			Description:     itemReq.Description,
+			Quantity:        itemReq.Quantity,
+			UnitPrice:       calc.Lines[i].UnitPrice,
+			Discount:        itemReq.Discount,
+			DiscountType:    calc.Lines[i].DiscountType,
+			TaxInclusive:    itemReq.TaxInclusive,
+		}
+
+		if err := tx.Create(&templateItem).Error; err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// applyRecurringBillRequest validates a request and copies it onto a template
+func applyRecurringBillRequest(template *models.RecurringBill, req models.RecurringBillRequest) error {
+	startDate, err := time.Parse("2006-01-02", req.StartDate)
+	if err != nil {
+		return errors.New("invalid start date format")
+	}
+
+	var endDate *time.Time
+	if req.EndDate != nil && *req.EndDate != "" {
+		parsedEndDate, err := time.Parse("2006-01-02", *req.EndDate)
+		if err != nil {
+			return errors.New("invalid end date format")
+		}
+		if parsedEndDate.Before(startDate) {
+			return errors.New("end date must not be before the start date")
+		}
+		endDate = &parsedEndDate
+	}
+
+	if req.AutoEmail && req.CustomerID == nil {
+		return errors.New("auto-email requires a customer")
+	}
+	if req.AutoEmail && !req.AutoIssue {
+		return errors.New("auto-email requires auto-issue, draft bills are not emailed")
+	}
+
+	dayOfMonth := req.DayOfMonth
+	if dayOfMonth == 0 {
+		dayOfMonth = startDate.Day()
+	}
+
+	template.CustomerID = req.CustomerID
+	template.Name = req.Name
+	template.Frequency = req.Frequency
+	template.StartDate = startDate
+	template.EndDate = endDate
+	template.DayOfMonth = dayOfMonth
+	template.DueInDays = req.DueInDays
+	template.AutoIssue = req.AutoIssue
+	template.AutoEmail = req.AutoEmail
+	template.Discount = req.Discount
+	template.TaxRate = req.TaxRate
+	template.Notes = req.Notes
+	template.Terms = req.Terms
+
+	return nil
+}
+
+// scheduleRecurringBill sets the next run date of a template from today, ending it when no runs remain
+func scheduleRecurringBill(template *models.RecurringBill) {
+	template.NextRunDate = nextRecurringBillDate(*template, recurringBillResumeDate(*template))
+	if template.NextRunDate == nil && template.Status == "active" {
+		template.Status = "ended"
+	}
+}
+
+// recurringBillResumeDate returns the earliest date the next run may fall on:
+// not before today, the start date, or the day after the last generated period
+func recurringBillResumeDate(template models.RecurringBill) time.Time {
+	from := recurringBillToday()
+	if template.StartDate.After(from) {
+		from = template.StartDate
+	}
+	if template.LastRunDate != nil && !template.LastRunDate.Before(from) {
+		from = template.LastRunDate.AddDate(0, 0, 1)
+	}
+	return from
+}
+
+// nextRecurringBillDate returns the first scheduled bill date on or after from, or nil past the end date
+func nextRecurringBillDate(template models.RecurringBill, from time.Time) *time.Time {
+	for n := 0; ; n++ {
+		date := recurringBillOccurrence(template, n)
+		if template.EndDate != nil && date.After(*template.EndDate) {
+			return nil
+		}
+		if !date.Before(from) {
+			return &date
+		}
+	}
+}
+
+// recurringBillOccurrence returns the bill date of the nth period of a template.
+// Monthly schedules use the day of month, clamped to the last day of short months.
+func recurringBillOccurrence(template models.RecurringBill, n int) time.Time {
+	start := template.StartDate
+	if template.Frequency == "weekly" {
+		return start.AddDate(0, 0, 7*n)
+	}
+
+	stepMonths := 1
+	switch template.Frequency {
+	case "quarterly":
+		stepMonths = 3
+	case "yearly":
+		stepMonths = 12
+	}
+
+	month := time.Date(start.Year(), start.Month(), 1, 0, 0, 0, 0, time.UTC)
+	if template.DayOfMonth < start.Day() {
+		month = month.AddDate(0, 1, 0)
+	}
+	month = month.AddDate(0, n*stepMonths, 0)
+
+	day := template.DayOfMonth
+	if lastDay := month.AddDate(0, 1, -1).Day(); day > lastDay {
+		day = lastDay
+	}
+	return time.Date(month.Year(), month.Month(), day, 0, 0, 0, 0, time.UTC)
+}
+
+// recurringBillDueDate returns the due date of a bill generated on billDate, if the template sets one
+func recurringBillDueDate(template models.RecurringBill, billDate time.Time) *time.Time {
+	if template.DueInDays <= 0 {
+		return nil
+	}
+	dueDate := billDate.AddDate(0, 0, template.DueInDays)
+	return &dueDate
+}
+
+// recurringBillToday returns today's date at midnight UTC, matching how bill dates are stored
+func recurringBillToday() time.Time {
+	now := time.Now()
+	return time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+}
+
+// recurringBillItemRequests converts template lines back into bill item requests
+func recurringBillItemRequests(items []models.RecurringBillItem) []models.BillItemRequest {
+	var requests []models.BillItemRequest
+	for _, item := range items {
+		unitPrice := item.UnitPrice // stored lines hold the price already resolved
+		requests = append(requests, models.BillItemRequest{
+			ItemID:       item.ItemID,
+			Quantity:     item.Quantity,
+			UnitPrice:    &unitPrice,
+			Description:  item.Description,
+			Discount:     item.Discount,
+			DiscountType: item.DiscountType,
+			TaxInclusive: item.TaxInclusive,
+		})
+	}
+	return requests
+}
+
+// recurringBillRequest builds the bill request a template generates, less its dates
+func recurringBillRequest(template models.RecurringBill, items []models.BillItemRequest) models.BillRequest {
+	return models.BillRequest{
+		CustomerID: template.CustomerID,
+		Items:      items,
+		Discount:   template.Discount,
+		TaxRate:    template.TaxRate,
+		Notes:      template.Notes,
+		Terms:      template.Terms,
+	}
+}
+
+// getRecurringBill loads a recurring bill template without relationships
+func (s *RecurringBillService) getRecurringBill(templateID, shopID uuid.UUID) (*models.RecurringBill, error) {
+	var template models.RecurringBill
+	if err := s.db.Where("id = ? AND shop_id = ?", templateID, shopID).First(&template).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("recurring bill not found")
+		}
+		return nil, err
+	}
+	return &template, nil
+}
+
+// getRecurringBillWithRelations fetches a recurring bill template with its customer and items
+func (s *RecurringBillService) getRecurringBillWithRelations(templateID, shopID uuid.UUID) (*models.RecurringBillResponse, error) {
+	var template models.RecurringBill
+	if err := s.db.Preload("Customer").Preload("Items").Where("id = ? AND shop_id = ?", templateID, shopID).First(&template).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("recurring bill not found")
+		}
+		return nil, err
+	}
+
+	response := s.recurringBillToResponse(template)
+	return &response, nil
+}
+
+// recurringBillToResponse converts a RecurringBill model to RecurringBillResponse
+func (s *RecurringBillService) recurringBillToResponse(template models.RecurringBill) models.RecurringBillResponse {
+	var customer *models.CustomerResponse
+	if template.Customer != nil {
+		customerResp := (&BillService{}).customerToResponse(*template.Customer)
+		customer = &customerResp
+	}
+
+	items := []models.RecurringBillItemResponse{}
+	for _, item := range template.Items {
+		items = append(items, models.RecurringBillItemResponse{
+			ID:           item.ID,
+			ItemID:       item.ItemID,
+			ItemName:     item.ItemName,
+			Description:  item.Description,
+			Quantity:     item.Quantity,
+			UnitPrice:    item.UnitPrice,
+			TotalPrice:   float64(item.Quantity) * item.UnitPrice,
+			Discount:     item.Discount,
+			DiscountType: item.DiscountType,
+			TaxInclusive: item.TaxInclusive,
+		})
+	}
+
+	return models.RecurringBillResponse{
+		ID:          template.ID,
+		ShopID:      template.ShopID,
+		CustomerID:  template.CustomerID,
+		Name:        template.Name,
+		Frequency:   template.Frequency,
+		StartDate:   template.StartDate,
+		EndDate:     template.EndDate,
+		DayOfMonth:  template.DayOfMonth,
+		DueInDays:   template.DueInDays,
+		AutoIssue:   template.AutoIssue,
+		AutoEmail:   template.AutoEmail,
+		Status:      template.Status,
+		NextRunDate: template.NextRunDate,
+		LastRunDate: template.LastRunDate,
+		Discount:    template.Discount,
+		TaxRate:     template.TaxRate,
+		Notes:       template.Notes,
+		Terms:       template.Terms,
+		Customer:    customer,
+		Items:       items,
+		CreatedAt:   template.CreatedAt,
+		UpdatedAt:   template.UpdatedAt,
+	}
+}
@@ -0,0 +1,411 @@
+package services
+
+import (
+	"billboard/backend/models"
+	"errors"
+	"sort"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// RegisterService handles cash register sessions and their reports
+type RegisterService struct {
+	db *gorm.DB
+}
+
+// NewRegisterService creates a new RegisterService instance
+func NewRegisterService(db *gorm.DB) *RegisterService {
+	return &RegisterService{db: db}
+}
+
+// OpenSession opens a register session with an opening float
+func (s *RegisterService) OpenSession(shopID, userID uuid.UUID, req models.OpenRegisterRequest) (*models.RegisterSessionResponse, error) {
+	// Check if user has access to the shop
+	var shopUser models.ShopUser
+	if err := s.db.Where("shop_id = ? AND user_id = ? AND is_active = ?", shopID, userID, true).First(&shopUser).Error; err != nil {
+		return nil, errors.New("access denied to shop")
+	}
+
+	var count int64
+	s.db.Model(&models.RegisterSession{}).Where("shop_id = ? AND status = ? AND opened_by = ?", shopID, "open", userID.String()).Count(&count)
+	if count > 0 {
+		return nil, errors.New("you already have an open register session")
+	}
+
+	s.db.Model(&models.RegisterSession{}).Where("shop_id = ? AND status = ? AND register_name = ?", shopID, "open", req.RegisterName).Count(&count)
+	if count > 0 {
+		return nil, errors.New("this register already has an open session")
+	}
+
+	session := models.RegisterSession{
+		ShopID:       shopID,
+		RegisterName: req.RegisterName,
+		Status:       "open",
+		OpeningFloat: req.OpeningFloat,
+		ExpectedCash: req.OpeningFloat,
+		OpenedBy:     userID.String(),
+		OpenedAt:     time.Now(),
+		Notes:        req.Notes,
+	}
+
+	if err := s.db.Create(&session).Error; err != nil {
+		return nil, err
+	}
+
+	response := s.sessionToResponse(session)
+	return &response, nil
+}
+
+// GetSessions retrieves register sessions for a shop
+func (s *RegisterService) GetSessions(shopID, userID uuid.UUID, filters map[string]interface{}) ([]models.RegisterSessionResponse, error) {
+	// Check if user has access to the shop
+	var shopUser models.ShopUser
+	if err := s.db.Where("shop_id = ? AND user_id = ? AND is_active = ?", shopID, userID, true).First(&shopUser).Error; err != nil {
+		return nil, errors.New("access denied to shop")
+	}
+
+	query := s.db.Where("shop_id = ?", shopID)
+
+	if status, ok := filters["status"].(string); ok && status != "" {
+		query = query.Where("status = ?", status)
+	}
+
+	if startDate, ok := filters["start_date"].(string); ok && startDate != "" {
+		query = query.Where("opened_at >= ?", startDate)
+	}
+
+	if endDate, ok := filters["end_date"].(string); ok && endDate != "" {
+		query = query.Where("opened_at::date <= ?", endDate)
+	}
+
+	var sessions []models.RegisterSession
+	if err := query.Order("opened_at DESC").Find(&sessions).Error; err != nil {
+		return nil, err
+	}
+
+	var responses []models.RegisterSessionResponse
+	for _, session := range sessions {
+		responses = append(responses, s.sessionToResponse(session))
+	}
+
+	return responses, nil
+}
+
+// GetCurrentSession retrieves the open register session of the user
+func (s *RegisterService) GetCurrentSession(shopID, userID uuid.UUID) (*models.RegisterSessionResponse, error) {
+	// Check if user has access to the shop
+	var shopUser models.ShopUser
+	if err := s.db.Where("shop_id = ? AND user_id = ? AND is_active = ?", shopID, userID, true).First(&shopUser).Error; err != nil {
+		return nil, errors.New("access denied to shop")
+	}
+
+	var session models.RegisterSession
+	if err := s.db.Preload("Movements").Where("shop_id = ? AND opened_by = ? AND status = ?", shopID, userID.String(), "open").First(&session).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("no open register session")
+		}
+		return nil, err
+	}
+
+	response := s.sessionToResponse(session)
+	return &response, nil
+}
+
+// GetSession retrieves a specific register session
+func (s *RegisterService) GetSession(sessionID, shopID, userID uuid.UUID) (*models.RegisterSessionResponse, error) {
+	// Check if user has access to the shop
+	var shopUser models.ShopUser
+	if err := s.db.Where("shop_id = ? AND user_id = ? AND is_active = ?", shopID, userID, true).First(&shopUser).Error; err != nil {
+		return nil, errors.New("access denied to shop")
+	}
+
+	session, err := s.getSession(sessionID, shopID)
+	if err != nil {
+		return nil, err
+	}
+
+	response := s.sessionToResponse(*session)
+	return &response, nil
+}
+
+// AddMovement records cash put into or taken out of the drawer, or a refund paid out
+func (s *RegisterService) AddMovement(sessionID, shopID, userID uuid.UUID, req models.CashMovementRequest) (*models.CashMovementResponse, error) {
+	// Check if user has access to the shop
+	var shopUser models.ShopUser
+	if err := s.db.Where("shop_id = ? AND user_id = ? AND is_active = ?", shopID, userID, true).First(&shopUser).Error; err != nil {
+		return nil, errors.New("access denied to shop")
+	}
+
+	session, err := s.getSession(sessionID, shopID)
+	if err != nil {
+		return nil, err
+	}
+	if session.Status != "open" {
+		return nil, errors.New("register session is closed")
+	}
+
+	if req.PaymentMethod == "" || req.Type != "refund" {
+		req.PaymentMethod = "cash"
+	}
+
+	if req.BillID != nil {
+		var count int64
+		s.db.Model(&models.Bill{}).Where("id = ? AND shop_id = ?", *req.BillID, shopID).Count(&count)
+		if count == 0 {
+			return nil, errors.New("bill not found")
+		}
+	}
+
+	movement := models.CashMovement{
+		SessionID:     sessionID,
+		ShopID:        shopID,
+		Type:          req.Type,
+		Amount:        req.Amount,
+		PaymentMethod: req.PaymentMethod,
+		BillID:        req.BillID,
+		Reason:        req.Reason,
+		CreatedBy:     userID.String(),
+	}
+
+	err = s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&movement).Error; err != nil {
+			return err
+		}
+		// A refund against a bill comes off what was paid on it, and takes back the loyalty
+		// points earned on the refunded part
+		if req.Type == "refund" && req.BillID != nil {
+			bill, err := refundBillPayment(tx, *req.BillID, req.Amount)
+			if err != nil {
+				return err
+			}
+			return reverseRefundLoyalty(tx, *bill, req.Amount, userID)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	response := s.movementToResponse(movement)
+	return &response, nil
+}
+
+// CloseSession closes a register session with the counted cash and returns its Z report
+func (s *RegisterService) CloseSession(sessionID, shopID, userID uuid.UUID, req models.CloseRegisterRequest) (*models.RegisterReport, error) {
+	// Check if user has access to the shop
+	var shopUser models.ShopUser
+	if err := s.db.Where("shop_id = ? AND user_id = ? AND is_active = ?", shopID, userID, true).First(&shopUser).Error; err != nil {
+		return nil, errors.New("access denied to shop")
+	}
+
+	session, err := s.getSession(sessionID, shopID)
+	if err != nil {
+		return nil, err
+	}
+	if session.Status != "open" {
+		return nil, errors.New("register session is already closed")
+	}
+
+	report, err := s.buildReport(*session)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	counted := req.CountedCash
+	notes := session.Notes
+	if req.Notes != "" {
+		notes = req.Notes
+	}
+
+	updates := map[string]interface{}{
+		"status":        "closed",
+		"expected_cash": report.ExpectedCash,
+		"counted_cash":  counted,
+		"variance":      roundAmount(counted - report.ExpectedCash),
+		"closed_by":     userID.String(),
+		"closed_at":     now,
+		"notes":         notes,
+		"updated_at":    now,
+	}
+
+	// Only the first of two concurrent closes writes the Z report
+	result := s.db.Model(&models.RegisterSession{}).Where("id = ? AND status = ?", sessionID, "open").Updates(updates)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	if result.RowsAffected == 0 {
+		return nil, errors.New("register session is already closed")
+	}
+
+	return s.GetReport(sessionID, shopID, userID)
+}
+
+// GetReport builds the X report of an open session or the Z report of a closed one
+func (s *RegisterService) GetReport(sessionID, shopID, userID uuid.UUID) (*models.RegisterReport, error) {
+	// Check if user has access to the shop
+	var shopUser models.ShopUser
+	if err := s.db.Where("shop_id = ? AND user_id = ? AND is_active = ?", shopID, userID, true).First(&shopUser).Error; err != nil {
+		return nil, errors.New("access denied to shop")
+	}
+
+	session, err := s.getSession(sessionID, shopID)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.buildReport(*session)
+}
+
+// buildReport aggregates the payments and cash movements of a session
+func (s *RegisterService) buildReport(session models.RegisterSession) (*models.RegisterReport, error) {
+	var shop models.Shop
+	if err := s.db.Where("id = ?", session.ShopID).First(&shop).Error; err != nil {
+		return nil, err
+	}
+
+	var payments []models.Payment
+	if err := s.db.Where("register_session_id = ?", session.ID).Find(&payments).Error; err != nil {
+		return nil, err
+	}
+
+	report := &models.RegisterReport{
+		ReportType:   "X",
+		ShopName:     shop.Name,
+		OpeningFloat: session.OpeningFloat,
+		GeneratedAt:  time.Now(),
+	}
+	if session.Status == "closed" {
+		report.ReportType = "Z"
+	}
+
+	sales := make(map[string]*models.PaymentMethodTotal)
+	for _, payment := range payments {
+		// Loyalty points are a discount on the bill, not money taken at the till
+		if payment.PaymentMethod == "loyalty_points" {
+			report.PointsRedeemed += payment.Amount
+			continue
+		}
+		total, ok := sales[payment.PaymentMethod]
+		if !ok {
+			total = &models.PaymentMethodTotal{PaymentMethod: payment.PaymentMethod}
+			sales[payment.PaymentMethod] = total
+		}
+		total.Count++
+		total.Amount += payment.Amount
+		report.TotalSales += payment.Amount
+		report.PaymentCount++
+		if payment.PaymentMethod == "cash" {
+			report.CashSales += payment.Amount
+		}
+	}
+
+	refunds := make(map[string]*models.PaymentMethodTotal)
+	for _, movement := range session.Movements {
+		switch movement.Type {
+		case "cash_in":
+			report.CashIn += movement.Amount
+		case "cash_out":
+			report.CashOut += movement.Amount
+		case "refund":
+			total, ok := refunds[movement.PaymentMethod]
+			if !ok {
+				total = &models.PaymentMethodTotal{PaymentMethod: movement.PaymentMethod}
+				refunds[movement.PaymentMethod] = total
+			}
+			total.Count++
+			total.Amount += movement.Amount
+			report.TotalRefunds += movement.Amount
+			if movement.PaymentMethod == "cash" {
+				report.CashRefunds += movement.Amount
+			}
+		}
+	}
+
+	report.PointsRedeemed = roundAmount(report.PointsRedeemed)
+	report.SalesByMethod = sortedMethodTotals(sales)
+	report.RefundsByMethod = sortedMethodTotals(refunds)
+	report.ExpectedCash = roundAmount(session.OpeningFloat + report.CashSales + report.CashIn - report.CashOut - report.CashRefunds)
+
+	if session.Status == "closed" {
+		report.CountedCash = session.CountedCash
+		report.Variance = session.Variance
+		report.ExpectedCash = session.ExpectedCash
+	} else {
+		session.ExpectedCash = report.ExpectedCash
+	}
+	report.Session = s.sessionToResponse(session)
+
+	return report, nil
+}
+
+// sortedMethodTotals returns payment method totals ordered by method name
+func sortedMethodTotals(totals map[string]*models.PaymentMethodTotal) []models.PaymentMethodTotal {
+	result := []models.PaymentMethodTotal{}
+	for _, total := range totals {
+		total.Amount = roundAmount(total.Amount)
+		result = append(result, *total)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].PaymentMethod < result[j].PaymentMethod
+	})
+	return result
+}
+
+// getSession loads a register session with its cash movements
+func (s *RegisterService) getSession(sessionID, shopID uuid.UUID) (*models.RegisterSession, error) {
+	var session models.RegisterSession
+	if err := s.db.Preload("Movements", func(db *gorm.DB) *gorm.DB {
+		return db.Order("created_at")
+	}).Where("id = ? AND shop_id = ?", sessionID, shopID).First(&session).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("register session not found")
+		}
+		return nil, err
+	}
+	return &session, nil
+}
+
+// sessionToResponse converts a RegisterSession model to RegisterSessionResponse
+func (s *RegisterService) sessionToResponse(session models.RegisterSession) models.RegisterSessionResponse {
+	movements := []models.CashMovementResponse{}
+	for _, movement := range session.Movements {
+		movements = append(movements, s.movementToResponse(movement))
+	}
+
+	return models.RegisterSessionResponse{
+		ID:           session.ID,
+		ShopID:       session.ShopID,
+		RegisterName: session.RegisterName,
+		Status:       session.Status,
+		OpeningFloat: session.OpeningFloat,
+		ExpectedCash: session.ExpectedCash,
+		CountedCash:  session.CountedCash,
+		Variance:     session.Variance,
+		OpenedBy:     session.OpenedBy,
+		OpenedAt:     session.OpenedAt,
+		ClosedBy:     session.ClosedBy,
+		ClosedAt:     session.ClosedAt,
+		Notes:        session.Notes,
+		Movements:    movements,
+		CreatedAt:    session.CreatedAt,
+		UpdatedAt:    session.UpdatedAt,
+	}
+}
+
+// movementToResponse converts a CashMovement model to CashMovementResponse
+func (s *RegisterService) movementToResponse(movement models.CashMovement) models.CashMovementResponse {
+	return models.CashMovementResponse{
+		ID:            movement.ID,
+		SessionID:     movement.SessionID,
+		Type:          movement.Type,
+		Amount:        movement.Amount,
+		PaymentMethod: movement.PaymentMethod,
+		BillID:        movement.BillID,
+		Reason:        movement.Reason,
+		CreatedBy:     movement.CreatedBy,
+		CreatedAt:     movement.CreatedAt,
+	}
+}
@@ -0,0 +1,256 @@
+package services
+
+import (
+	"billboard/backend/models"
+	"bytes"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// ReportService builds reports over a shop's sales
+type ReportService struct {
+	db *gorm.DB
+}
+
+// NewReportService creates a new ReportService instance
+func NewReportService(db *gorm.DB) *ReportService {
+	return &ReportService{db: db}
+}
+
+// profitabilityGroups maps each grouping to its key, name and category columns and the joins it needs
+var profitabilityGroups = map[string]struct {
+	Key, Name, Category, GroupBy string
+	Joins                        []string
+}{
+	"item": {
+		Key:      "bill_items.item_id::text",
+		Name:     "MAX(bill_items.item_name)",
+		Category: "MAX(COALESCE(items.category, ''))",
+		GroupBy:  "bill_items.item_id",
+	},
+	"category": {
+		Key:      "''",
+		Name:     "COALESCE(NULLIF(items.category, ''), 'Uncategorised')",
+		Category: "''",
+		GroupBy:  "COALESCE(NULLIF(items.category, ''), 'Uncategorised')",
+	},
+	"customer": {
+		Key:      "COALESCE(bills.customer_id::text, '')",
+		Name:     "COALESCE(MAX(customers.name), 'Walk-in')",
+		Category: "''",
+		GroupBy:  "bills.customer_id",
+		Joins:    []string{"LEFT JOIN customers ON customers.id = bills.customer_id"},
+	},
+	"staff": {
+		Key:      "bills.created_by",
+		Name:     "COALESCE(MAX(users.first_name || ' ' || users.last_name), bills.created_by)",
+		Category: "''",
+		GroupBy:  "bills.created_by",
+		Joins:    []string{"LEFT JOIN users ON users.id::text = bills.created_by"},
+	},
+}
+
+// profitabilityRevenue is the revenue of a bill line excluding tax, with the bill discount spread
+// over the lines by taxable value. Bills from before taxable values were recorded fall back to
+// spreading the bill's net sales over the line totals.
+const profitabilityRevenue = `CASE
+	WHEN bills.taxable_value > 0 THEN bill_items.taxable_value * (bills.taxable_value - bills.discount_amount) / bills.taxable_value
+	WHEN bills.subtotal > 0 THEN bill_items.total_price * (bills.total_amount - bills.tax_amount) / bills.subtotal
+	ELSE 0 END`
+
+// GetProfitability reports revenue, cost and gross margin for a date range grouped by item, category,
+// customer or staff member. Cancelled bills are left out; unpaid bills count, since their stock
+// has gone.
+func (s *ReportService) GetProfitability(shopID, userID uuid.UUID, groupBy, from, to, sortBy, order string) (*models.ProfitabilityReport, error) {
+	// Check if user has access to the shop
+	var shopUser models.ShopUser
+	if err := s.db.Where("shop_id = ? AND user_id = ? AND is_active = ?", shopID, userID, true).First(&shopUser).Error; err != nil {
+		return nil, errors.New("access denied to shop")
+	}
+
+	if groupBy == "" {
+		groupBy = "item"
+	}
+	group, ok := profitabilityGroups[groupBy]
+	if !ok {
+		return nil, errors.New("group by must be item, category, customer or staff")
+	}
+
+	if sortBy == "" {
+		sortBy = "revenue"
+	}
+	if order == "" {
+		order = "desc"
+	}
+	if order != "asc" && order != "desc" {
+		return nil, errors.New("order must be asc or desc")
+	}
+
+	fromDate, toDate, err := reportDateRange(from, to)
+	if err != nil {
+		return nil, err
+	}
+
+	query := s.db.Table("bill_items").
+		Select(fmt.Sprintf("%s AS group_key, %s AS name, %s AS category, "+
+			"COUNT(DISTINCT bills.id) AS bill_count, COALESCE(SUM(bill_items.quantity), 0) AS quantity, "+
+			"COALESCE(SUM(%s), 0) AS revenue, COALESCE(SUM(bill_items.quantity * bill_items.unit_cost), 0) AS cost, "+
+			"COALESCE(SUM(CASE WHEN bill_items.unit_cost = 0 THEN bill_items.quantity ELSE 0 END), 0) AS uncosted_quantity",
+			group.Key, group.Name, group.Category, profitabilityRevenue)).
+		Joins("JOIN bills ON bills.id = bill_items.bill_id").
+		Joins("LEFT JOIN items ON items.id = bill_items.item_id")
+	for _, join := range group.Joins {
+		query = query.Joins(join)
+	}
+
+	var groups []struct {
+		GroupKey         string
+		Name             string
+		Category         string
+		BillCount        int64
+		Quantity         int64
+		Revenue          float64
+		Cost             float64
+		UncostedQuantity int64
+	}
+	if err := query.
+		Where("bills.shop_id = ? AND bills.deleted_at IS NULL AND bills.status <> ? AND bills.bill_date >= ? AND bills.bill_date < ?",
+			shopID, "cancelled", fromDate, toDate.AddDate(0, 0, 1)).
+		Group(group.GroupBy).
+		Scan(&groups).Error; err != nil {
+		return nil, err
+	}
+
+	report := &models.ProfitabilityReport{
+		From:    fromDate,
+		To:      toDate,
+		GroupBy: groupBy,
+		SortBy:  sortBy,
+		Order:   order,
+		Rows:    []models.ProfitabilityRow{},
+		Totals:  models.ProfitabilityRow{Name: "Total"},
+	}
+
+	for _, g := range groups {
+		row := models.ProfitabilityRow{
+			Name:             g.Name,
+			Category:         g.Category,
+			BillCount:        g.BillCount,
+			Quantity:         g.Quantity,
+			Revenue:          roundAmount(g.Revenue),
+			Cost:             roundAmount(g.Cost),
+			UncostedQuantity: g.UncostedQuantity,
+		}
+		if key, err := uuid.Parse(g.GroupKey); err == nil {
+			row.Key = &key
+		}
+		row.GrossMargin = roundAmount(row.Revenue - row.Cost)
+		row.MarginPercent = marginPercent(row.GrossMargin, row.Revenue)
+		report.Rows = append(report.Rows, row)
+
+		report.Totals.Quantity += row.Quantity
+		report.Totals.Revenue += row.Revenue
+		report.Totals.Cost += row.Cost
+		report.Totals.UncostedQuantity += row.UncostedQuantity
+	}
+
+	// Bills are counted once for the whole range, not once per group
+	if err := s.db.Model(&models.Bill{}).
+		Where("shop_id = ? AND status <> ? AND bill_date >= ? AND bill_date < ?",
+			shopID, "cancelled", fromDate, toDate.AddDate(0, 0, 1)).
+		Count(&report.Totals.BillCount).Error; err != nil {
+		return nil, err
+	}
+	report.Totals.Revenue = roundAmount(report.Totals.Revenue)
+	report.Totals.Cost = roundAmount(report.Totals.Cost)
+	report.Totals.GrossMargin = roundAmount(report.Totals.Revenue - report.Totals.Cost)
+	report.Totals.MarginPercent = marginPercent(report.Totals.GrossMargin, report.Totals.Revenue)
+
+	if err := sortProfitabilityRows(report.Rows, sortBy, order == "desc"); err != nil {
+		return nil, err
+	}
+
+	return report, nil
+}
+
+// ExportProfitabilityCSV renders a profitability report as CSV with a totals row
+func (s *ReportService) ExportProfitabilityCSV(report *models.ProfitabilityReport) ([]byte, error) {
+	var buf bytes.Buffer
+	writer := csv.NewWriter(&buf)
+
+	header := []string{strings.ToUpper(report.GroupBy[:1]) + report.GroupBy[1:]}
+	if report.GroupBy == "item" {
+		header = append(header, "Category")
+	}
+	header = append(header, "Bills", "Quantity", "Revenue", "Cost", "Gross margin", "Margin %", "Uncosted quantity")
+	if err := writer.Write(header); err != nil {
+		return nil, err
+	}
+
+	for _, row := range append(report.Rows, report.Totals) {
+		record := []string{row.Name}
+		if report.GroupBy == "item" {
+			record = append(record, row.Category)
+		}
+		record = append(record,
+			fmt.Sprintf("%d", row.BillCount),
+			fmt.Sprintf("%d", row.Quantity),
+			fmt.Sprintf("%.2f", row.Revenue),
+			fmt.Sprintf("%.2f", row.Cost),
+			fmt.Sprintf("%.2f", row.GrossMargin),
+			fmt.Sprintf("%.2f", row.MarginPercent),
+			fmt.Sprintf("%d", row.UncostedQuantity),
+		)
+		if err := writer.Write(record); err != nil {
+			return nil, err
+		}
+	}
+
+	writer.Flush()
+	return buf.Bytes(), writer.Error()
+}
+
+// sortProfitabilityRows orders report rows by the given column, breaking ties by name
+func sortProfitabilityRows(rows []models.ProfitabilityRow, sortBy string, descending bool) error {
+	var value func(row models.ProfitabilityRow) float64
+	switch sortBy {
+	case "name":
+	case "quantity":
+		value = func(row models.ProfitabilityRow) float64 { return float64(row.Quantity) }
+	case "revenue":
+		value = func(row models.ProfitabilityRow) float64 { return row.Revenue }
+	case "cost":
+		value = func(row models.ProfitabilityRow) float64 { return row.Cost }
+	case "gross_margin":
+		value = func(row models.ProfitabilityRow) float64 { return row.GrossMargin }
+	case "margin_percent":
+		value = func(row models.ProfitabilityRow) float64 { return row.MarginPercent }
+	default:
+		return errors.New("sort by must be name, quantity, revenue, cost, gross_margin or margin_percent")
+	}
+
+	sort.SliceStable(rows, func(i, j int) bool {
+		if value != nil && value(rows[i]) != value(rows[j]) {
+			return (value(rows[i]) < value(rows[j])) != descending
+		}
+		if value == nil {
+			return (strings.ToLower(rows[i].Name) < strings.ToLower(rows[j].Name)) != descending
+		}
+		return strings.ToLower(rows[i].Name) < strings.ToLower(rows[j].Name)
+	})
+	return nil
+}
+
+// marginPercent returns a margin as a percentage of revenue
+func marginPercent(margin, revenue float64) float64 {
+	if revenue == 0 {
+		return 0
+	}
+	return roundAmount(margin / revenue * 100)
+}
@@ -1,16 +1,33 @@
 package services
 
 type Services struct {
-	Auth     *AuthService
-	Bill     *BillService
-	Item     *ItemService
-	Customer *CustomerService
-	Shop     *ShopService
-	PDF      *PDFService
-}
-
-type PDFService struct{}
-
-func NewPDFService() *PDFService {
-	return &PDFService{}
+	Auth               *AuthService
+	Bill               *BillService
+	Item               *ItemService
+	Customer           *CustomerService
+	Shop               *ShopService
+	PDF                *PDFService
+	Receipt            *ReceiptService
+	Label              *LabelService
+	POS                *POSService
+	Cart               *CartService
+	Register           *RegisterService
+	Quotation          *QuotationService
+	Recurring          *RecurringBillService
+	Promotion          *PromotionService
+	PriceList          *PriceListService
+	Loyalty            *LoyaltyService
+	Expense            *ExpenseService
+	Report             *ReportService
+	GST                *GSTService
+	EInvoice           *EInvoiceService
+	EWayBill           *EWayBillService
+	UBL                *UBLService
+	Accounting         *AccountingService
+	BankReconciliation *BankReconciliationService
+	PaymentLink        *PaymentLinkService
+	Email              *EmailService
+	Notification       *NotificationService
+	Dunning            *DunningService
+	LateFee            *LateFeeService
 }
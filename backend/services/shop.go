@@ -38,6 +38,9 @@ func (s *ShopService) CreateShop(userID uuid.UUID, req models.ShopRequest) (*mod
 	if req.Name == "" {
 		return nil, errors.New("name is required")
 	}
+	if req.UPIID != "" && !validUPIID(req.UPIID) {
+		return nil, errors.New("invalid UPI ID")
+	}
 
 	// Check for duplicate shop name for this user
 	var existingShop models.Shop
@@ -64,14 +67,18 @@ func (s *ShopService) CreateShop(userID uuid.UUID, req models.ShopRequest) (*mod
 
 	// Create shop
 	shop := models.Shop{
-		Name:      req.Name,
-		Address:   req.Address,
-		Phone:     req.Phone,
-		Email:     req.Email,
-		GSTNumber: req.GSTNumber,
-		LogoURL:   req.LogoURL,
-		Settings:  "{}", // Default empty JSON object
-		IsActive:  req.IsActive,
+		Name:             req.Name,
+		Address:          req.Address,
+		Phone:            req.Phone,
+		Email:            req.Email,
+		GSTNumber:        req.GSTNumber,
+		LogoURL:          req.LogoURL,
+		Settings:         "{}", // Default empty JSON object
+		ReceiptFooter:    req.ReceiptFooter,
+		PricesIncludeTax: req.PricesIncludeTax,
+		UPIID:            req.UPIID,
+		UPIPayeeName:     req.UPIPayeeName,
+		IsActive:         req.IsActive,
 	}
 
 	if err := s.db.Create(&shop).Error; err != nil {
@@ -121,18 +128,25 @@ func (s *ShopService) UpdateShop(shopID, userID uuid.UUID, req models.ShopReques
 		}
 		return nil, err
 	}
+	if req.UPIID != "" && !validUPIID(req.UPIID) {
+		return nil, errors.New("invalid UPI ID")
+	}
 
 	// Update shop
 	updates := map[string]interface{}{
-		"name":       req.Name,
-		"address":    req.Address,
-		"phone":      req.Phone,
-		"email":      req.Email,
-		"gst_number": req.GSTNumber,
-		"logo_url":   req.LogoURL,
-		"settings":   "{}", // Default empty JSON object
-		"is_active":  req.IsActive,
-		"updated_at": time.Now(),
+		"name":               req.Name,
+		"address":            req.Address,
+		"phone":              req.Phone,
+		"email":              req.Email,
+		"gst_number":         req.GSTNumber,
+		"logo_url":           req.LogoURL,
+		"settings":           "{}", // Default empty JSON object
+		"receipt_footer":     req.ReceiptFooter,
+		"prices_include_tax": req.PricesIncludeTax,
+		"upi_id":             req.UPIID,
+		"upi_payee_name":     req.UPIPayeeName,
+		"is_active":          req.IsActive,
+		"updated_at":         time.Now(),
 	}
 
 	if err := s.db.Model(&models.Shop{}).Where("id = ?", shopID).Updates(updates).Error; err != nil {
@@ -312,16 +326,20 @@ func (s *ShopService) GetPendingAmounts(shopID, userID uuid.UUID, limit int) ([]
 // shopToResponse converts a Shop model to ShopResponse
 func (s *ShopService) shopToResponse(shop models.Shop) models.ShopResponse {
 	return models.ShopResponse{
-		ID:        shop.ID,
-		Name:      shop.Name,
-		Address:   shop.Address,
-		Phone:     shop.Phone,
-		Email:     shop.Email,
-		GSTNumber: shop.GSTNumber,
-		LogoURL:   shop.LogoURL,
-		Settings:  shop.Settings,
-		IsActive:  shop.IsActive,
-		CreatedAt: shop.CreatedAt,
-		UpdatedAt: shop.UpdatedAt,
+		ID:               shop.ID,
+		Name:             shop.Name,
+		Address:          shop.Address,
+		Phone:            shop.Phone,
+		Email:            shop.Email,
+		GSTNumber:        shop.GSTNumber,
+		LogoURL:          shop.LogoURL,
+		Settings:         shop.Settings,
+		ReceiptFooter:    shop.ReceiptFooter,
+		PricesIncludeTax: shop.PricesIncludeTax,
+		UPIID:            shop.UPIID,
+		UPIPayeeName:     shop.UPIPayeeName,
+		IsActive:         shop.IsActive,
+		CreatedAt:        shop.CreatedAt,
+		UpdatedAt:        shop.UpdatedAt,
 	}
 }
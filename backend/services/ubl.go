@@ -0,0 +1,370 @@
+package services
+
+import (
+	"billboard/backend/models"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+)
+
+// UBLService renders bills as UBL 2.1 invoices following Peppol BIS Billing 3.0
+type UBLService struct{}
+
+// NewUBLService creates a new UBLService instance
+func NewUBLService() *UBLService {
+	return &UBLService{}
+}
+
+const (
+	ublCustomizationID = "urn:cen.eu:en16931:2017#compliant#urn:fdc:peppol.eu:2017:poacc:billing:3.0"
+	ublProfileID       = "urn:fdc:peppol.eu:2017:poacc:billing:01:1.0"
+	// ublCurrency is the currency of all amounts; bills are kept in rupees
+	ublCurrency = "INR"
+)
+
+// ublCountryCodes maps country names to ISO 3166-1 alpha-2 codes for the countries our customers
+// most often invoice
+var ublCountryCodes = map[string]string{
+	"india": "IN", "singapore": "SG", "united kingdom": "GB", "uk": "GB", "ireland": "IE",
+	"germany": "DE", "france": "FR", "netherlands": "NL", "belgium": "BE", "luxembourg": "LU",
+	"italy": "IT", "spain": "ES", "portugal": "PT", "austria": "AT", "switzerland": "CH",
+	"sweden": "SE", "norway": "NO", "denmark": "DK", "finland": "FI", "iceland": "IS",
+	"poland": "PL", "czech republic": "CZ", "czechia": "CZ", "greece": "GR", "estonia": "EE",
+	"latvia": "LV", "lithuania": "LT", "croatia": "HR", "slovenia": "SI", "slovakia": "SK",
+	"hungary": "HU", "romania": "RO", "bulgaria": "BG", "cyprus": "CY", "malta": "MT",
+	"australia": "AU", "new zealand": "NZ", "japan": "JP", "malaysia": "MY",
+	"united arab emirates": "AE", "uae": "AE", "united states": "US", "usa": "US", "canada": "CA",
+}
+
+// ublUnitCodes maps common item units to UN/ECE recommendation 20 unit codes
+var ublUnitCodes = map[string]string{
+	"pcs": "H87", "pc": "H87", "piece": "H87", "pieces": "H87",
+	"nos": "C62", "no": "C62", "number": "C62",
+	"kg": "KGM", "kgs": "KGM", "g": "GRM", "gm": "GRM", "gms": "GRM",
+	"l": "LTR", "ltr": "LTR", "litre": "LTR", "ml": "MLT",
+	"m": "MTR", "mtr": "MTR", "meter": "MTR",
+	"box": "XBX", "pack": "XPK", "pac": "XPK", "dozen": "DZN", "doz": "DZN",
+	"set": "SET", "bag": "XBG", "btl": "XBO", "bottle": "XBO",
+	"hour": "HUR", "hr": "HUR", "day": "DAY",
+}
+
+// RenderBill serialises a bill with its shop, customer, lines, tax and payments as Peppol BIS
+// Billing 3.0 UBL XML. The seller and buyer are addressed by email, which Peppol accepts as an
+// electronic address scheme.
+func (s *UBLService) RenderBill(bill models.Bill) ([]byte, error) {
+	invoice, err := buildUBLInvoice(bill)
+	if err != nil {
+		return nil, err
+	}
+	if problems := validateUBLInvoice(*invoice); len(problems) > 0 {
+		return nil, fmt.Errorf("invoice does not meet Peppol BIS Billing 3.0: %s", strings.Join(problems, "; "))
+	}
+
+	content, err := xml.MarshalIndent(invoice, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return append([]byte(xml.Header), content...), nil
+}
+
+// buildUBLInvoice maps a bill to a UBL invoice
+func buildUBLInvoice(bill models.Bill) (*models.UBLInvoice, error) {
+	if bill.Status == "cancelled" {
+		return nil, errors.New("cancelled bills cannot be exported as e-invoices")
+	}
+	if bill.Customer == nil {
+		return nil, errors.New("UBL invoices need a customer")
+	}
+	// A bill discount is taken off after tax, which EN 16931 has no way to express
+	if bill.Discount > 0 {
+		return nil, errors.New("bills with a bill discount cannot be exported as UBL; use line discounts instead")
+	}
+	if bill.Shop.Email == "" {
+		return nil, errors.New("shop needs an email address to use as its Peppol endpoint")
+	}
+	if bill.Customer.Email == "" {
+		return nil, errors.New("customer needs an email address to use as their Peppol endpoint")
+	}
+	buyerCountry, ok := ublCountryCode(bill.Customer.Country)
+	if !ok {
+		return nil, errors.New("customer country must be an ISO country code or a known country name")
+	}
+
+	sellerStreet, sellerCity, sellerPin := splitShopAddress(bill.Shop.Address)
+	seller := models.UBLParty{
+		EndpointID: models.UBLIdentifier{SchemeID: "EM", Value: bill.Shop.Email},
+		PartyName:  &models.UBLPartyName{Name: bill.Shop.Name},
+		PostalAddress: models.UBLAddress{
+			StreetName: sellerStreet,
+			CityName:   sellerCity,
+			Country:    models.UBLCountry{IdentificationCode: "IN"},
+		},
+		PartyLegalEntity: models.UBLLegalEntity{RegistrationName: bill.Shop.Name},
+		Contact:          &models.UBLContact{Telephone: bill.Shop.Phone, ElectronicMail: bill.Shop.Email},
+	}
+	if sellerPin != 0 {
+		seller.PostalAddress.PostalZone = fmt.Sprintf("%d", sellerPin)
+	}
+	if gstin := strings.ToUpper(strings.TrimSpace(bill.Shop.GSTNumber)); gstin != "" {
+		seller.PartyTaxScheme = &models.UBLPartyTaxScheme{CompanyID: "IN" + gstin, TaxScheme: models.UBLTaxScheme{ID: "VAT"}}
+	}
+
+	buyer := models.UBLParty{
+		EndpointID: models.UBLIdentifier{SchemeID: "EM", Value: bill.Customer.Email},
+		PartyName:  &models.UBLPartyName{Name: bill.Customer.Name},
+		PostalAddress: models.UBLAddress{
+			StreetName: bill.Customer.Address,
+			CityName:   bill.Customer.City,
+			PostalZone: bill.Customer.PostalCode,
+			Country:    models.UBLCountry{IdentificationCode: buyerCountry},
+		},
+		PartyLegalEntity: models.UBLLegalEntity{RegistrationName: bill.Customer.Name},
+		Contact:          &models.UBLContact{Telephone: bill.Customer.Phone, ElectronicMail: bill.Customer.Email},
+	}
+	if taxNumber := strings.ToUpper(strings.ReplaceAll(bill.Customer.TaxNumber, " ", "")); taxNumber != "" {
+		// VAT identifiers carry the ISO prefix of the issuing country
+		if len(taxNumber) < 2 || taxNumber[0] < 'A' || taxNumber[0] > 'Z' || taxNumber[1] < 'A' || taxNumber[1] > 'Z' {
+			taxNumber = buyerCountry + taxNumber
+		}
+		buyer.PartyTaxScheme = &models.UBLPartyTaxScheme{CompanyID: taxNumber, TaxScheme: models.UBLTaxScheme{ID: "VAT"}}
+	}
+
+	invoice := &models.UBLInvoice{
+		Xmlns:                   "urn:oasis:names:specification:ubl:schema:xsd:Invoice-2",
+		XmlnsCac:                "urn:oasis:names:specification:ubl:schema:xsd:CommonAggregateComponents-2",
+		XmlnsCbc:                "urn:oasis:names:specification:ubl:schema:xsd:CommonBasicComponents-2",
+		CustomizationID:         ublCustomizationID,
+		ProfileID:               ublProfileID,
+		ID:                      bill.BillNumber,
+		IssueDate:               bill.BillDate.Format("2006-01-02"),
+		InvoiceTypeCode:         "380",
+		Note:                    bill.Notes,
+		DocumentCurrencyCode:    ublCurrency,
+		BuyerReference:          bill.BillNumber,
+		AccountingSupplierParty: models.UBLPartyWrapper{Party: seller},
+		AccountingCustomerParty: models.UBLPartyWrapper{Party: buyer},
+	}
+	if bill.DueDate != nil {
+		invoice.DueDate = bill.DueDate.Format("2006-01-02")
+	}
+	if bill.Terms != "" {
+		invoice.PaymentTerms = &models.UBLPaymentTerms{Note: bill.Terms}
+	}
+
+	type taxGroup struct {
+		Category     string
+		Rate         float64
+		Taxable, Tax float64
+	}
+	groups := map[string]*taxGroup{}
+	var lineTotal, taxTotal float64
+
+	for i, line := range bill.Items {
+		taxable := line.TaxableValue
+		if taxable == 0 && line.TaxAmount == 0 {
+			taxable = line.TotalPrice
+		}
+
+		// Prices are net of tax; tax-inclusive lines have the tax taken back out
+		unitPrice := line.UnitPrice
+		if line.TaxInclusive {
+			unitPrice = line.UnitPrice / (1 + line.TaxRate/100)
+		}
+		unitPrice = roundAmount(unitPrice)
+		gross := roundAmount(unitPrice * float64(line.Quantity))
+
+		category := "S"
+		if line.TaxRate == 0 {
+			category = "Z"
+		}
+		taxCategory := models.UBLTaxCategory{ID: category, Percent: ublNumber(line.TaxRate), TaxScheme: models.UBLTaxScheme{ID: "VAT"}}
+
+		ublLine := models.UBLInvoiceLine{
+			ID:                  fmt.Sprintf("%d", i+1),
+			InvoicedQuantity:    models.UBLQuantity{UnitCode: ublUnitCode(line.Unit), Value: fmt.Sprintf("%d", line.Quantity)},
+			LineExtensionAmount: ublAmount(taxable),
+			Item: models.UBLItem{
+				Description:           line.Description,
+				Name:                  line.ItemName,
+				ClassifiedTaxCategory: taxCategory,
+			},
+			Price: models.UBLPrice{PriceAmount: ublAmount(unitPrice)},
+		}
+		if line.Item.SKU != "" {
+			ublLine.Item.SellersItemIdentification = &models.UBLItemIdentification{ID: line.Item.SKU}
+		}
+		hsn := line.HSNCode
+		if hsn == "" {
+			hsn = line.Item.HSNCode
+		}
+		if hsn != "" {
+			ublLine.Item.CommodityClassification = &models.UBLCommodityClassification{
+				ItemClassificationCode: models.UBLClassificationCode{ListID: "HS", Value: hsn},
+			}
+		}
+
+		// Line and promotion discounts become a line allowance; rounding the net price can
+		// leave a few paise over, which becomes a charge
+		if diff := roundAmount(gross - taxable); diff > 0 {
+			ublLine.AllowanceCharges = append(ublLine.AllowanceCharges, models.UBLAllowanceCharge{
+				ChargeIndicator: false, AllowanceChargeReason: "Discount", Amount: ublAmount(diff),
+			})
+		} else if diff < 0 {
+			ublLine.AllowanceCharges = append(ublLine.AllowanceCharges, models.UBLAllowanceCharge{
+				ChargeIndicator: true, AllowanceChargeReason: "Rounding", Amount: ublAmount(-diff),
+			})
+		}
+		invoice.InvoiceLines = append(invoice.InvoiceLines, ublLine)
+
+		key := fmt.Sprintf("%s|%.2f", category, line.TaxRate)
+		group, ok := groups[key]
+		if !ok {
+			group = &taxGroup{Category: category, Rate: line.TaxRate}
+			groups[key] = group
+		}
+		group.Taxable = roundAmount(group.Taxable + taxable)
+		group.Tax = roundAmount(group.Tax + line.TaxAmount)
+		lineTotal = roundAmount(lineTotal + taxable)
+		taxTotal = roundAmount(taxTotal + line.TaxAmount)
+	}
+	if len(invoice.InvoiceLines) == 0 {
+		return nil, errors.New("bill has no items")
+	}
+
+	keys := make([]string, 0, len(groups))
+	for key := range groups {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	invoice.TaxTotal.TaxAmount = ublAmount(taxTotal)
+	for _, key := range keys {
+		group := groups[key]
+		invoice.TaxTotal.TaxSubtotals = append(invoice.TaxTotal.TaxSubtotals, models.UBLTaxSubtotal{
+			TaxableAmount: ublAmount(group.Taxable),
+			TaxAmount:     ublAmount(group.Tax),
+			TaxCategory:   models.UBLTaxCategory{ID: group.Category, Percent: ublNumber(group.Rate), TaxScheme: models.UBLTaxScheme{ID: "VAT"}},
+		})
+	}
+
+	taxInclusive := roundAmount(lineTotal + taxTotal)
+	totals := models.UBLMonetaryTotal{
+		LineExtensionAmount: ublAmount(lineTotal),
+		TaxExclusiveAmount:  ublAmount(lineTotal),
+		TaxInclusiveAmount:  ublAmount(taxInclusive),
+		PayableAmount:       ublAmount(math.Max(0, roundAmount(bill.TotalAmount-bill.PaidAmount))),
+	}
+	if bill.PaidAmount > 0 {
+		prepaid := ublAmount(math.Min(bill.PaidAmount, bill.TotalAmount))
+		totals.PrepaidAmount = &prepaid
+	}
+	if rounding := roundAmount(bill.TotalAmount - taxInclusive); rounding != 0 {
+		amount := ublAmount(rounding)
+		totals.PayableRoundingAmount = &amount
+	}
+	invoice.LegalMonetaryTotal = totals
+
+	return invoice, nil
+}
+
+// validateUBLInvoice checks the business rules of EN 16931 that a bill could break and returns
+// every problem found
+func validateUBLInvoice(invoice models.UBLInvoice) []string {
+	var problems []string
+	check := func(ok bool, problem string) {
+		if !ok {
+			problems = append(problems, problem)
+		}
+	}
+	amount := func(a models.UBLAmount) float64 {
+		var value float64
+		fmt.Sscanf(a.Value, "%f", &value)
+		return value
+	}
+	near := func(a, b float64) bool { return math.Abs(a-b) < 0.005 }
+
+	check(invoice.ID != "", "BR-02 invoice number is required")
+	check(invoice.IssueDate != "", "BR-03 issue date is required")
+	check(invoice.AccountingSupplierParty.Party.PartyLegalEntity.RegistrationName != "", "BR-06 seller name is required")
+	check(invoice.AccountingCustomerParty.Party.PartyLegalEntity.RegistrationName != "", "BR-07 buyer name is required")
+	check(invoice.AccountingSupplierParty.Party.PostalAddress.Country.IdentificationCode != "", "BR-09 seller country is required")
+	check(invoice.AccountingCustomerParty.Party.PostalAddress.Country.IdentificationCode != "", "BR-11 buyer country is required")
+	check(len(invoice.InvoiceLines) > 0, "BR-16 at least one invoice line is required")
+
+	var lineTotal float64
+	for _, line := range invoice.InvoiceLines {
+		check(line.Item.Name != "", fmt.Sprintf("BR-25 line %s needs an item name", line.ID))
+		check(amount(line.Price.PriceAmount) >= 0, fmt.Sprintf("BR-27 line %s has a negative price", line.ID))
+
+		var quantity float64
+		fmt.Sscanf(line.InvoicedQuantity.Value, "%f", &quantity)
+		net := quantity * amount(line.Price.PriceAmount)
+		for _, ac := range line.AllowanceCharges {
+			if ac.ChargeIndicator {
+				net += amount(ac.Amount)
+			} else {
+				net -= amount(ac.Amount)
+			}
+		}
+		check(near(roundAmount(net), amount(line.LineExtensionAmount)), fmt.Sprintf("line %s net amount does not match quantity, price and allowances", line.ID))
+		lineTotal += amount(line.LineExtensionAmount)
+	}
+
+	totals := invoice.LegalMonetaryTotal
+	check(near(roundAmount(lineTotal), amount(totals.LineExtensionAmount)), "BR-CO-10 sum of line net amounts must equal the line total")
+	check(near(amount(totals.TaxExclusiveAmount), amount(totals.LineExtensionAmount)), "BR-CO-13 tax exclusive amount must equal the line total")
+
+	var subtotalTax float64
+	for _, subtotal := range invoice.TaxTotal.TaxSubtotals {
+		subtotalTax += amount(subtotal.TaxAmount)
+		check(subtotal.TaxCategory.ID != "Z" || amount(subtotal.TaxAmount) == 0, "BR-Z-09 zero rated tax amount must be 0")
+	}
+	check(near(roundAmount(subtotalTax), amount(invoice.TaxTotal.TaxAmount)), "BR-CO-14 tax total must equal the sum of the tax subtotals")
+	check(near(amount(totals.TaxInclusiveAmount), roundAmount(amount(totals.TaxExclusiveAmount)+amount(invoice.TaxTotal.TaxAmount))), "BR-CO-15 tax inclusive amount must equal the tax exclusive amount plus tax")
+
+	payable := amount(totals.TaxInclusiveAmount)
+	if totals.PrepaidAmount != nil {
+		payable -= amount(*totals.PrepaidAmount)
+	}
+	if totals.PayableRoundingAmount != nil {
+		payable += amount(*totals.PayableRoundingAmount)
+	}
+	check(near(roundAmount(payable), amount(totals.PayableAmount)), "BR-CO-16 amount due must equal the tax inclusive amount less prepaid amounts plus rounding")
+
+	return problems
+}
+
+// ublCountryCode resolves a country code or name to its ISO 3166-1 alpha-2 code, defaulting
+// an empty country to India
+func ublCountryCode(country string) (string, bool) {
+	country = strings.TrimSpace(country)
+	if country == "" {
+		return "IN", true
+	}
+	if len(country) == 2 {
+		return strings.ToUpper(country), true
+	}
+	code, ok := ublCountryCodes[strings.ToLower(country)]
+	return code, ok
+}
+
+// ublUnitCode maps an item unit to a UN/ECE recommendation 20 code, using C62 (one) for units
+// it doesn't know
+func ublUnitCode(unit string) string {
+	if code, ok := ublUnitCodes[strings.ToLower(strings.TrimSpace(unit))]; ok {
+		return code
+	}
+	return "C62"
+}
+
+// ublAmount formats an amount in the invoice currency
+func ublAmount(amount float64) models.UBLAmount {
+	return models.UBLAmount{CurrencyID: ublCurrency, Value: fmt.Sprintf("%.2f", roundAmount(amount))}
+}
+
+// ublNumber formats a rate without trailing zeros
+func ublNumber(value float64) string {
+	return strings.TrimSuffix(strings.TrimRight(fmt.Sprintf("%.2f", value), "0"), ".")
+}
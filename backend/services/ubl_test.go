@@ -0,0 +1,148 @@
+package services
+
+import (
+	"billboard/backend/models"
+	"bytes"
+	"encoding/xml"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// The UBL 2.1 schemas and the compiled Peppol BIS Billing 3.0 rules are not checked in; run
+// scripts/fetch-ubl-schemas.sh to place them under testdata/ubl. Tests that need them are
+// skipped until they are there.
+var (
+	ublSchemaPath     = filepath.Join("testdata", "ubl", "xsd", "maindoc", "UBL-Invoice-2.1.xsd")
+	ublSchematronGlob = filepath.Join("testdata", "ubl", "schematron", "*.xslt")
+)
+
+// ublTestBill is a bill with a tax-exclusive line, a tax-inclusive discounted line, a zero
+// rated line and a part payment
+func ublTestBill(status string) models.Bill {
+	due := time.Date(2026, 5, 15, 0, 0, 0, 0, time.UTC)
+	return models.Bill{
+		BillNumber:  "INV-2026-0042",
+		BillDate:    time.Date(2026, 4, 15, 0, 0, 0, 0, time.UTC),
+		DueDate:     &due,
+		Status:      status,
+		Terms:       "Payment due in 30 days",
+		TotalAmount: 2448,
+		PaidAmount:  500,
+		Shop: models.Shop{
+			Name:      "Sharma Stores",
+			Address:   "12 MG Road, Bengaluru 560001",
+			Phone:     "+91 80 4000 1234",
+			Email:     "billing@sharmastores.example",
+			GSTNumber: "29ABCDE1234F1Z5",
+		},
+		Customer: &models.Customer{
+			Name:       "Acme Trading BV",
+			Email:      "ap@acme.example",
+			Address:    "Keizersgracht 1",
+			City:       "Amsterdam",
+			PostalCode: "1015 CJ",
+			Country:    "Netherlands",
+			TaxNumber:  "NL123456789B01",
+		},
+		Items: []models.BillItem{
+			{ItemName: "Basmati rice 5kg", Unit: "pcs", HSNCode: "1006", Quantity: 2, UnitPrice: 500, TotalPrice: 1000, TaxRate: 5, TaxableValue: 1000, TaxAmount: 50},
+			{ItemName: "Steel tiffin", Unit: "pcs", HSNCode: "7323", Quantity: 1, UnitPrice: 1180, TotalPrice: 1080, DiscountAmount: 100, TaxInclusive: true, TaxRate: 18, TaxableValue: 915.25, TaxAmount: 164.75},
+			{ItemName: "Fresh vegetables", Unit: "kg", Quantity: 3, UnitPrice: 106, TotalPrice: 318, TaxableValue: 318},
+		},
+	}
+}
+
+func TestRenderBillDraft(t *testing.T) {
+	if _, err := NewUBLService().RenderBill(ublTestBill("draft")); err != nil {
+		t.Fatalf("draft bill was not rendered: %v", err)
+	}
+	if _, err := NewUBLService().RenderBill(ublTestBill("cancelled")); err == nil {
+		t.Fatal("cancelled bill was rendered")
+	}
+}
+
+func TestRenderBillMatchesUBLSchema(t *testing.T) {
+	if _, err := os.Stat(ublSchemaPath); err != nil {
+		t.Skipf("UBL 2.1 schemas not found at %s; run scripts/fetch-ubl-schemas.sh", ublSchemaPath)
+	}
+	xmllint, err := exec.LookPath("xmllint")
+	if err != nil {
+		t.Skip("xmllint is not installed")
+	}
+
+	path := renderUBLTestInvoice(t)
+	if output, err := exec.Command(xmllint, "--noout", "--schema", ublSchemaPath, path).CombinedOutput(); err != nil {
+		t.Fatalf("invoice does not match the UBL 2.1 schema:\n%s", output)
+	}
+}
+
+func TestRenderBillMeetsPeppolRules(t *testing.T) {
+	stylesheets, _ := filepath.Glob(ublSchematronGlob)
+	if len(stylesheets) == 0 {
+		t.Skipf("compiled Peppol BIS Billing 3.0 rules not found at %s; see scripts/fetch-ubl-schemas.sh", ublSchematronGlob)
+	}
+	// The rules use XPath 2.0, which needs an XSLT 2.0 processor such as Saxon HE
+	saxon, err := exec.LookPath("saxon")
+	if err != nil {
+		t.Skip("saxon is not installed")
+	}
+
+	path := renderUBLTestInvoice(t)
+	for _, stylesheet := range stylesheets {
+		output, err := exec.Command(saxon, "-s:"+path, "-xsl:"+stylesheet).Output()
+		if err != nil {
+			t.Fatalf("%s: %v", filepath.Base(stylesheet), err)
+		}
+		for _, failure := range fatalSchematronFailures(t, output) {
+			t.Errorf("%s: %s", filepath.Base(stylesheet), failure)
+		}
+	}
+}
+
+// renderUBLTestInvoice renders the test bill to a file and returns its path
+func renderUBLTestInvoice(t *testing.T) string {
+	t.Helper()
+	content, err := NewUBLService().RenderBill(ublTestBill("sent"))
+	if err != nil {
+		t.Fatalf("render: %v", err)
+	}
+	path := filepath.Join(t.TempDir(), "invoice.xml")
+	if err := os.WriteFile(path, content, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+// fatalSchematronFailures reads the failed assertions flagged fatal from an SVRL report
+func fatalSchematronFailures(t *testing.T, report []byte) []string {
+	t.Helper()
+	var failures []string
+	decoder := xml.NewDecoder(bytes.NewReader(report))
+	for {
+		token, err := decoder.Token()
+		if err != nil {
+			break
+		}
+		start, ok := token.(xml.StartElement)
+		if !ok || start.Name.Local != "failed-assert" {
+			continue
+		}
+		var failed struct {
+			ID       string `xml:"id,attr"`
+			Flag     string `xml:"flag,attr"`
+			Location string `xml:"location,attr"`
+			Text     string `xml:"text"`
+		}
+		if err := decoder.DecodeElement(&failed, &start); err != nil {
+			t.Fatalf("read SVRL report: %v", err)
+		}
+		if failed.Flag == "fatal" {
+			failures = append(failures, failed.ID+" at "+failed.Location+": "+strings.TrimSpace(failed.Text))
+		}
+	}
+	return failures
+}
@@ -0,0 +1,45 @@
+package services
+
+import (
+	"billboard/backend/models"
+	"fmt"
+	"net/url"
+	"regexp"
+	"strings"
+)
+
+// upiIDPattern matches a UPI virtual payment address such as shopname@okhdfcbank
+var upiIDPattern = regexp.MustCompile(`^[A-Za-z0-9._-]{2,256}@[A-Za-z][A-Za-z0-9]{1,63}$`)
+
+// validUPIID reports whether id looks like a UPI virtual payment address
+func validUPIID(id string) bool {
+	return upiIDPattern.MatchString(id)
+}
+
+// billUPIIntent returns the UPI intent URI that pays a bill's balance to the shop, and false
+// when the bill cannot be paid by UPI: the shop has no UPI ID, the bill is cancelled, or nothing
+// is left to pay.
+// Payer apps open the URI with the payee, amount and note filled in.
+func billUPIIntent(bill models.Bill) (string, bool) {
+	if bill.Shop.UPIID == "" || bill.Status == "cancelled" {
+		return "", false
+	}
+	amount := roundAmount(bill.Balance)
+	if amount <= 0 {
+		return "", false
+	}
+
+	payee := bill.Shop.UPIPayeeName
+	if payee == "" {
+		payee = bill.Shop.Name
+	}
+
+	// The UPI linking specification wants spaces as %20 rather than the query string +. The
+	// payee address is validated on the shop and left as it is, since some payer apps do not
+	// decode an escaped @.
+	escape := func(value string) string {
+		return strings.ReplaceAll(url.QueryEscape(value), "+", "%20")
+	}
+	return fmt.Sprintf("upi://pay?pa=%s&pn=%s&am=%.2f&cu=INR&tn=%s",
+		bill.Shop.UPIID, escape(truncateRunes(payee, 50)), amount, escape(truncateRunes(bill.BillNumber, 50))), true
+}
@@ -0,0 +1,38 @@
+#!/bin/bash
+
+# Fetches the artefacts the UBL export tests validate against into
+# backend/services/testdata/ubl, where they are committed with the tests. The
+# tests skip whatever is missing.
+#
+#   xsd/         UBL 2.1 schemas, from the OASIS standard release
+#   schematron/  compiled (XSLT) Peppol BIS Billing 3.0 and CEN EN 16931 rules
+#
+# The Peppol rules are published with each Peppol BIS Billing 3.0 release at
+# https://github.com/OpenPEPPOL/peppol-bis-invoice-3. Download the release you
+# validate against and point PEPPOL_RULES_DIR at the directory holding its
+# compiled .xslt files. Running them needs Saxon HE on the PATH as "saxon".
+set -e
+
+UBL_ZIP_URL="https://docs.oasis-open.org/ubl/os-UBL-2.1/UBL-2.1.zip"
+
+DEST="$(dirname "$0")/../backend/services/testdata/ubl"
+mkdir -p "$DEST"
+cd "$DEST"
+
+echo "Fetching UBL 2.1 schemas..."
+TMP=$(mktemp -d)
+trap 'rm -rf "$TMP"' EXIT
+curl -fsSL -o "$TMP/UBL-2.1.zip" "$UBL_ZIP_URL"
+unzip -q "$TMP/UBL-2.1.zip" 'xsd/*' -d "$TMP"
+rm -rf xsd
+mv "$TMP/xsd" xsd
+
+if [ -n "$PEPPOL_RULES_DIR" ]; then
+    echo "Copying Peppol BIS Billing 3.0 rules from $PEPPOL_RULES_DIR..."
+    mkdir -p schematron
+    cp "$PEPPOL_RULES_DIR"/*.xslt schematron/
+else
+    echo "PEPPOL_RULES_DIR is not set; the Peppol rules test will be skipped."
+fi
+
+echo "Done. Run: cd backend && go test ./services -run RenderBill"